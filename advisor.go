@@ -0,0 +1,43 @@
+package sqlextractor
+
+import (
+	"fmt"
+
+	"github.com/kydance/sql-extractor/internal/advisor"
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// IndexRecommendations suggests candidate composite indexes per table from the
+// equality, range and order by columns of each statement, keyed by the digest
+// (TemplatizedSQLHash) of the statement. It should be called after Extract.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE age > 18 AND name = 'kyden'")
+//	if err := extractor.Extract(); err != nil {
+//	  // handle error
+//	}
+//	recommendations, err := extractor.IndexRecommendations()
+//	// users: (name, age)
+func (e *Extractor) IndexRecommendations() (map[string][]*models.IndexRecommendation, error) {
+	predicates, err := e.internal().ExtractPredicates(e.rawSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(predicates) != len(e.hash) {
+		return nil, fmt.Errorf("statement count mismatch: %d vs %d, Extract should be called first",
+			len(predicates), len(e.hash))
+	}
+
+	recommendations := make(map[string][]*models.IndexRecommendation, len(predicates))
+	for idx := range predicates {
+		if _, ok := recommendations[e.hash[idx]]; ok {
+			continue
+		}
+
+		recommendations[e.hash[idx]] = advisor.Recommend(predicates[idx])
+	}
+
+	return recommendations, nil
+}
@@ -0,0 +1,39 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestExtractor_IndexRecommendations(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users WHERE age > 18 AND name = 'kyden'; SELECT * FROM users WHERE age > 20 AND name = 'kk'")
+	_, err := extractor.IndexRecommendations()
+	as.NotNil(err) // Extract not called
+
+	as.Nil(extractor.Extract())
+	recommendations, err := extractor.IndexRecommendations()
+	as.Nil(err)
+	as.Equal(map[string][]*models.IndexRecommendation{
+		extractor.TemplatizedSQLHash()[0]: {models.NewIndexRecommendation("", "users", []string{"name", "age"})},
+	}, recommendations)
+}
+
+func TestExtractor_IndexRecommendations_Dialect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 使用 Extractor 的方言选项解析
+	extractor := NewExtractor("SELECT TOP 5 * FROM users WHERE age > 18 AND name = 'kyden'", WithSQLServer())
+	as.Nil(extractor.Extract())
+	recommendations, err := extractor.IndexRecommendations()
+	as.Nil(err)
+	as.Equal(map[string][]*models.IndexRecommendation{
+		extractor.TemplatizedSQLHash()[0]: {models.NewIndexRecommendation("", "users", []string{"name", "age"})},
+	}, recommendations)
+}
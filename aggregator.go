@@ -0,0 +1,422 @@
+package sqlextractor
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+	"github.com/kydance/sql-extractor/internal/models"
+	"github.com/kydance/sql-extractor/internal/obfuscate"
+)
+
+// Default example retention of the Aggregator.
+const (
+	DefaultMaxExamples     = 3
+	DefaultMaxExampleBytes = 1024
+)
+
+// DigestStats is the aggregate of the statements with the same digest and
+// tags, within the same time bucket.
+type DigestStats struct {
+	Digest         string              // sha256 of the templatized SQL, hex encoded
+	TemplatizedSQL string              // templatized SQL
+	OpType         models.SQLOpType    // operation type
+	TableInfos     []*models.TableInfo // tables of the first statement
+	ParamsCount    int                 // number of params of the first statement
+	Tags           Tags                // tags of the statements, see SetTags
+	Bucket         time.Time           // start of the time bucket, zero without WithTimeBucket
+	Count          int64               // number of statements
+
+	// Examples are distinct raw statements with the literals redacted (e.g.
+	// WHERE id = ?), in order of appearance.
+	Examples []string
+
+	// Params are the statistics of the values of each placeholder, nil
+	// without WithParamStats.
+	Params []ParamStats
+
+	// InListSizes and ValuesRows are the distributions of the IN list lengths
+	// and of the INSERT VALUES row counts, nil without WithListSizeHistograms
+	// or lists.
+	InListSizes *SizeHistogram
+	ValuesRows  *SizeHistogram
+}
+
+// NPlusOne is a likely N+1 query pattern: a burst of single-row lookups of the
+// same digest and tags, e.g. of the same connection, issued in a loop instead
+// of a single batched query.
+type NPlusOne struct {
+	Digest          string    // digest of the lookups
+	TemplatizedSQL  string    // templatized SQL of the lookups
+	Tags            Tags      // tags of the lookups
+	Column          string    // column of the lookup equality
+	Batched         string    // suggested batched alternative, with an IN-list of the column
+	BatchedTemplate string    // template of the batched alternative, see BatchedTemplate
+	Start, End      time.Time // time of the first and the last lookup of the burst
+	Count           int64     // number of lookups of the burst
+}
+
+// Aggregator aggregates statement results by digest and tags, and retains a
+// few redacted examples of each digest, so dashboards can show a
+// representative query without storing sensitive values. It is safe for
+// concurrent use.
+type Aggregator struct {
+	mu sync.Mutex
+
+	maxExamples     int
+	maxExampleBytes int
+	bucket          time.Duration
+	nPlusOneWindow  time.Duration
+	nPlusOneMin     int64
+	paramTopK       int
+	paramMask       func(param any) string
+	listBounds      []int
+	now             func() time.Time
+
+	stats      map[string]*DigestStats       // digest + tags + bucket
+	order      []*DigestStats                // in order of first appearance
+	paramSlots map[*DigestStats][]*paramSlot // see WithParamStats
+
+	lookups   map[string]*batchLookup // digest -> 批量形式，nil 表示不是单行查询
+	bursts    map[string]*lookupBurst // digest + tags
+	nPlusOnes []*NPlusOne             // in order of detection
+}
+
+// nPlusOneBatchSize is the number of placeholders of the IN-list of the
+// suggested batched queries.
+const nPlusOneBatchSize = 3
+
+// batchLookup is the batched form of a single-row lookup digest.
+type batchLookup struct {
+	column   string
+	batched  string
+	template string
+}
+
+// lookupBurst is the current burst of the lookups of a digest and tags.
+type lookupBurst struct {
+	start, last time.Time
+	count       int64
+	found       *NPlusOne // nil until the burst reaches the threshold
+}
+
+// AggregatorOption configures the Aggregator.
+type AggregatorOption func(*Aggregator)
+
+// WithMaxExamples retains up to n examples per digest, 0 disables examples.
+// Default is DefaultMaxExamples.
+func WithMaxExamples(n int) AggregatorOption {
+	return func(a *Aggregator) { a.maxExamples = max(n, 0) }
+}
+
+// WithMaxExampleBytes truncates the examples to n bytes, on a character
+// boundary. A n <= 0 means unlimited, default is DefaultMaxExampleBytes.
+func WithMaxExampleBytes(n int) AggregatorOption {
+	return func(a *Aggregator) { a.maxExampleBytes = max(n, 0) }
+}
+
+// WithTimeBucket aggregates the statements separately per time bucket of
+// duration d, e.g. a minute, by the time they are added. A d <= 0 disables
+// buckets, which is the default.
+func WithTimeBucket(d time.Duration) AggregatorOption {
+	return func(a *Aggregator) { a.bucket = max(d, 0) }
+}
+
+// WithNPlusOneDetection flags the bursts of at least threshold single-row
+// lookups of the same digest and tags, each within window of the previous one,
+// as likely N+1 patterns, see NPlusOnes. The tags should identify the caller,
+// e.g. a connection or a request. A single-row lookup is a SELECT from one
+// table whose WHERE clause is the equality of a column with a value. A
+// window <= 0 or a threshold < 2 disables the detection, which is the default.
+func WithNPlusOneDetection(window time.Duration, threshold int) AggregatorOption {
+	return func(a *Aggregator) {
+		a.nPlusOneWindow, a.nPlusOneMin = 0, 0
+		if window > 0 && threshold >= 2 {
+			a.nPlusOneWindow, a.nPlusOneMin = window, int64(threshold)
+		}
+	}
+}
+
+// NewAggregator creates a new Aggregator.
+func NewAggregator(opts ...AggregatorOption) *Aggregator {
+	a := &Aggregator{
+		maxExamples:     DefaultMaxExamples,
+		maxExampleBytes: DefaultMaxExampleBytes,
+		now:             time.Now,
+		stats:           make(map[string]*DigestStats),
+		paramSlots:      make(map[*DigestStats][]*paramSlot),
+		lookups:         make(map[string]*batchLookup),
+		bursts:          make(map[string]*lookupBurst),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Add aggregates a statement result, e.g. of ExtractStream. Results with Err
+// set are ignored. The example is the redacted RawSQL, if set.
+func (a *Aggregator) Add(r StatementResult) {
+	if r.Err != nil {
+		return
+	}
+
+	var now, bucket time.Time
+	if a.bucket > 0 || a.nPlusOneWindow > 0 {
+		now = a.now()
+	}
+	if a.bucket > 0 {
+		bucket = now.Truncate(a.bucket)
+	}
+
+	digest := defaultHash([]byte(r.TemplatizedSQL))
+	tags := tagsKey(r.Tags)
+	key := digest + tags + "\x00" + bucket.String()
+
+	var lookup *batchLookup
+	if a.nPlusOneWindow > 0 && r.OpType == models.SQLOperationSelect {
+		lookup = a.lookupOf(digest, r.TemplatizedSQL)
+	}
+
+	// 脱敏在锁外进行
+	var example string
+	if r.RawSQL != "" && a.maxExamples > 0 {
+		example = truncateExample(obfuscate.Obfuscate(r.RawSQL), a.maxExampleBytes)
+	}
+
+	var (
+		sizes      extract.ListSizes
+		knownSizes bool
+	)
+	if a.listBounds != nil {
+		sizes, knownSizes = listSizesOf(r)
+	}
+
+	var masked []string
+	if a.paramTopK > 0 {
+		masked = make([]string, len(r.Params))
+		for idx, param := range r.Params {
+			masked[idx] = a.paramMask(param)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.stats[key]
+	if !ok {
+		s = &DigestStats{
+			Digest:         digest,
+			TemplatizedSQL: r.TemplatizedSQL,
+			OpType:         r.OpType,
+			TableInfos:     r.TableInfos,
+			ParamsCount:    len(r.Params),
+			Tags:           r.Tags,
+			Bucket:         bucket,
+		}
+		a.stats[key] = s
+		a.order = append(a.order, s)
+	}
+
+	s.Count++
+	if example != "" && len(s.Examples) < a.maxExamples && !slices.Contains(s.Examples, example) {
+		s.Examples = append(s.Examples, example)
+	}
+	if masked != nil {
+		a.addParams(s, masked)
+	}
+	if knownSizes {
+		a.addListSizes(s, sizes)
+	}
+
+	if lookup != nil {
+		a.addLookup(digest+tags, r, digest, lookup, now)
+	}
+}
+
+// lookupOf returns the batched form of the digest, nil if it is not a
+// single-row lookup. It is computed once per digest, outside the lock.
+func (a *Aggregator) lookupOf(digest, template string) *batchLookup {
+	a.mu.Lock()
+	lookup, ok := a.lookups[digest]
+	a.mu.Unlock()
+	if ok {
+		return lookup
+	}
+
+	if sql, _, err := Translate(template, DialectMySQL, DialectMySQL); err == nil {
+		batched, column, isLookup, err := defaultExtractor.BatchLookup(sql, nPlusOneBatchSize)
+		if err == nil && isLookup {
+			lookup = &batchLookup{column: column, batched: batched}
+			lookup.template, _, _, _ = batchedTemplate(sql)
+		}
+	}
+
+	a.mu.Lock()
+	a.lookups[digest] = lookup
+	a.mu.Unlock()
+
+	return lookup
+}
+
+// addLookup adds the lookup to the burst of its digest and tags, and flags the
+// burst once it reaches the threshold. The caller holds the lock.
+func (a *Aggregator) addLookup(key string, r StatementResult, digest string, lookup *batchLookup, now time.Time) {
+	b := a.bursts[key]
+	if b == nil || now.Sub(b.last) > a.nPlusOneWindow {
+		b = &lookupBurst{start: now}
+		a.bursts[key] = b
+	}
+	b.count++
+	b.last = now
+
+	if b.count < a.nPlusOneMin {
+		return
+	}
+	if b.found == nil {
+		b.found = &NPlusOne{
+			Digest:          digest,
+			TemplatizedSQL:  r.TemplatizedSQL,
+			Tags:            r.Tags,
+			Column:          lookup.column,
+			Batched:         lookup.batched,
+			BatchedTemplate: lookup.template,
+			Start:           b.start,
+		}
+		a.nPlusOnes = append(a.nPlusOnes, b.found)
+	}
+	b.found.End, b.found.Count = now, b.count
+}
+
+// AddSQL extracts the SQL with the tags, and aggregates its statements.
+func (a *Aggregator) AddSQL(sql string, tags Tags) error {
+	return a.AddSQLWith(sql, tags)
+}
+
+// AddSQLWith is AddSQL with the middleware run on each statement before it is
+// aggregated, e.g. DefaultSchema.
+func (a *Aggregator) AddSQLWith(sql string, tags Tags, mw ...Middleware) error {
+	stmts, err := defaultExtractor.Split(sql)
+	if err != nil {
+		return err
+	}
+
+	e := NewExtractor(sql)
+	e.Use(mw...)
+	if err := e.Extract(); err != nil {
+		return err
+	}
+
+	for idx := range e.templatedSQL {
+		r := StatementResult{
+			Index:          idx,
+			TemplatizedSQL: e.templatedSQL[idx],
+			TableInfos:     e.tableInfos[idx],
+			Params:         e.params[idx],
+			OpType:         e.opType[idx],
+			Tags:           tags,
+		}
+		if len(stmts) == len(e.templatedSQL) {
+			r.RawSQL = stmts[idx]
+		}
+
+		a.Add(r)
+	}
+
+	return nil
+}
+
+// Stats returns a snapshot of the aggregates, in order of first appearance.
+func (a *Aggregator) Stats() []DigestStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := make([]DigestStats, len(a.order))
+	for idx, s := range a.order {
+		stats[idx] = *s
+		stats[idx].Examples = slices.Clone(s.Examples)
+		stats[idx].Params = a.paramStats(s)
+		stats[idx].InListSizes = s.InListSizes.clone()
+		stats[idx].ValuesRows = s.ValuesRows.clone()
+	}
+
+	return stats
+}
+
+// NPlusOnes returns a snapshot of the likely N+1 patterns, in order of
+// detection, see WithNPlusOneDetection. A burst is reported once, with the
+// count of its lookups so far.
+//
+// Example:
+//
+//	a := NewAggregator(WithNPlusOneDetection(50*time.Millisecond, 10))
+//	// ... a.AddSQL(sql, Tags{"conn": connID})
+//	for _, p := range a.NPlusOnes() {
+//	  log.Printf("N+1: %d x %s, use %s", p.Count, p.TemplatizedSQL, p.Batched)
+//	}
+func (a *Aggregator) NPlusOnes() []NPlusOne {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	patterns := make([]NPlusOne, len(a.nPlusOnes))
+	for idx, p := range a.nPlusOnes {
+		patterns[idx] = *p
+	}
+
+	return patterns
+}
+
+// Reset removes all aggregates and N+1 patterns, e.g. after they are
+// exported.
+func (a *Aggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats = make(map[string]*DigestStats)
+	a.order = nil
+	a.paramSlots = make(map[*DigestStats][]*paramSlot)
+	a.bursts = make(map[string]*lookupBurst)
+	a.nPlusOnes = nil
+}
+
+// tagsKey returns a canonical representation of the tags.
+func tagsKey(tags Tags) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var builder strings.Builder
+	for _, k := range keys {
+		builder.WriteByte(0)
+		builder.WriteString(k)
+		builder.WriteByte(0)
+		builder.WriteString(tags[k])
+	}
+
+	return builder.String()
+}
+
+// truncateExample truncates the example to maxBytes, without splitting a
+// character.
+func truncateExample(s string, maxBytes int) string {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s
+	}
+
+	n := maxBytes
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
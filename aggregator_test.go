@@ -0,0 +1,239 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestAggregator(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	a := NewAggregator(WithMaxExamples(2))
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE id = 1; SELECT * FROM users WHERE id = 2", nil))
+	as.Nil(a.AddSQL("select * from users where id = 3", nil))
+	as.Nil(a.AddSQL("SELECT *\n  FROM users WHERE id = 4", nil))
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE id = 5", Tags{"service": "billing"}))
+	as.NotNil(a.AddSQL("SELEC 1", nil))
+	a.Add(StatementResult{TemplatizedSQL: "DELETE FROM users WHERE id eq ?", OpType: models.SQLOperationDelete})
+	a.Add(StatementResult{Err: assert.AnError})
+
+	stats := a.Stats()
+	as.Equal(3, len(stats))
+
+	as.Equal("SELECT * FROM users WHERE id eq ?", stats[0].TemplatizedSQL)
+	as.Equal(defaultHash([]byte(stats[0].TemplatizedSQL)), stats[0].Digest)
+	as.Equal(models.SQLOperationSelect, stats[0].OpType)
+	as.Equal("users", stats[0].TableInfos[0].TableName())
+	as.Nil(stats[0].Tags)
+	as.Equal(int64(4), stats[0].Count)
+	// literals are redacted, duplicates are retained once, up to 2 examples
+	as.Equal([]string{"SELECT * FROM users WHERE id = ?", "select * from users where id = ?"}, stats[0].Examples)
+
+	// same digest, other tags
+	as.Equal(stats[0].Digest, stats[1].Digest)
+	as.Equal(Tags{"service": "billing"}, stats[1].Tags)
+	as.Equal(int64(1), stats[1].Count)
+
+	// no raw SQL, no example
+	as.Equal(int64(1), stats[2].Count)
+	as.Empty(stats[2].Examples)
+
+	a.Reset()
+	as.Empty(a.Stats())
+}
+
+func TestAggregator_ExampleLimits(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	a := NewAggregator(WithMaxExampleBytes(30))
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE name = 'kyden' AND city = '北京'", nil))
+	as.Equal([]string{"SELECT * FROM users WHERE name"}, a.Stats()[0].Examples)
+
+	a = NewAggregator(WithMaxExamples(0))
+	as.Nil(a.AddSQL("SELECT 1", nil))
+	as.Empty(a.Stats()[0].Examples)
+
+	as.Equal("SELECT ", truncateExample("SELECT 北京", 8))
+	as.Equal("SELECT 北", truncateExample("SELECT 北京", 10))
+	as.Equal(strings.Repeat("a", 5), truncateExample(strings.Repeat("a", 5), 0))
+}
+
+func TestAggregator_TimeBucket(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	now := time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+	a := NewAggregator(WithTimeBucket(time.Minute))
+	a.now = func() time.Time { return now }
+
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE id = 1", nil))
+	now = now.Add(20 * time.Second)
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE id = 2", nil))
+	now = now.Add(20 * time.Second)
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE id = 3", nil))
+
+	stats := a.Stats()
+	as.Equal(2, len(stats))
+	as.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), stats[0].Bucket)
+	as.Equal(int64(2), stats[0].Count)
+	as.Equal(1, stats[0].ParamsCount)
+	as.Equal(time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC), stats[1].Bucket)
+	as.Equal(int64(1), stats[1].Count)
+
+}
+
+func TestAggregator_NPlusOne(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAggregator(WithNPlusOneDetection(50*time.Millisecond, 3))
+	a.now = func() time.Time { return now }
+
+	lookup := func(id int, tags Tags) {
+		now = now.Add(10 * time.Millisecond)
+		as.Nil(a.AddSQL("SELECT * FROM orders WHERE user_id = "+strconv.Itoa(id), tags))
+	}
+
+	// below the threshold, interleaved connections
+	lookup(1, Tags{"conn": "1"})
+	lookup(2, Tags{"conn": "2"})
+	lookup(3, Tags{"conn": "1"})
+	as.Empty(a.NPlusOnes())
+
+	// burst of 4 lookups of connection 1, not a lookup, then a pause
+	lookup(4, Tags{"conn": "1"})
+	as.Nil(a.AddSQL("SELECT * FROM orders WHERE amount > 10", Tags{"conn": "1"}))
+	lookup(5, Tags{"conn": "1"})
+	now = now.Add(time.Second)
+	lookup(6, Tags{"conn": "1"})
+
+	patterns := a.NPlusOnes()
+	as.Equal(1, len(patterns))
+	as.Equal("SELECT * FROM orders WHERE user_id eq ?", patterns[0].TemplatizedSQL)
+	as.Equal(defaultHash([]byte(patterns[0].TemplatizedSQL)), patterns[0].Digest)
+	as.Equal(Tags{"conn": "1"}, patterns[0].Tags)
+	as.Equal("user_id", patterns[0].Column)
+	as.Equal("SELECT * FROM `orders` WHERE `user_id` IN (?,?,?)", patterns[0].Batched)
+	as.Equal("SELECT * FROM orders WHERE user_id IN (?)", patterns[0].BatchedTemplate)
+	as.Equal(int64(4), patterns[0].Count)
+	as.Equal(40*time.Millisecond, patterns[0].End.Sub(patterns[0].Start))
+
+	a.Reset()
+	as.Empty(a.NPlusOnes())
+
+	// disabled by default
+	a = NewAggregator()
+	for id := range 10 {
+		as.Nil(a.AddSQL("SELECT * FROM orders WHERE user_id = "+strconv.Itoa(id), nil))
+	}
+	as.Empty(a.NPlusOnes())
+}
+
+func TestAggregator_ParamStats(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	a := NewAggregator(WithParamStats(2, func(param any) string { return fmt.Sprint(param) }))
+	for idx := range 5 {
+		tenant := 42
+		if idx == 4 {
+			tenant = 7
+		}
+		as.Nil(a.AddSQL(fmt.Sprintf("SELECT * FROM orders WHERE tenant_id = %d AND id = %d", tenant, idx), nil))
+	}
+	as.Nil(a.AddSQL("SELECT * FROM orders WHERE tenant_id = 9 AND id = 0", nil))
+
+	params := a.Stats()[0].Params
+	as.Equal([]ParamStats{
+		{Index: 0, Distinct: 3, Top: []ParamValue{{Value: "42", Count: 4}, {Value: "7", Count: 1}}},
+		{Index: 1, Distinct: 5, Top: []ParamValue{{Value: "0", Count: 2}, {Value: "1", Count: 1}}},
+	}, params)
+
+	// masked by a hash by default
+	a = NewAggregator(WithParamStats(1, nil))
+	as.Nil(a.AddSQL("SELECT * FROM users WHERE email = 'kyden@example.com' OR email IS NULL OR id = NULL", nil))
+	params = a.Stats()[0].Params
+	as.Len(params, 2)
+	as.Equal(hashParam("kyden@example.com"), params[0].Top[0].Value)
+	as.Len(params[0].Top[0].Value, 16)
+	as.Equal("NULL", params[1].Top[0].Value)
+
+	// the distinct values are bounded
+	a = NewAggregator(WithParamStats(1, func(param any) string { return fmt.Sprint(param) }))
+	for idx := range maxParamValues + 10 {
+		a.Add(StatementResult{TemplatizedSQL: "SELECT * FROM t WHERE id eq ?", Params: []any{int64(idx)}})
+	}
+	params = a.Stats()[0].Params
+	as.Equal(maxParamValues, params[0].Distinct)
+	as.True(params[0].Truncated)
+
+	a = NewAggregator()
+	as.Nil(a.AddSQL("SELECT 1", nil))
+	as.Nil(a.Stats()[0].Params, "disabled by default")
+}
+
+func TestAggregator_ListSizeHistograms(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	a := NewAggregator(WithListSizeHistograms(10, 1, 5))
+	extractor := NewExtractor("", WithPreset(PresetMySQLDigest))
+	for _, sql := range []string{
+		"SELECT * FROM t WHERE id IN (1)",
+		"SELECT * FROM t WHERE id IN (1, 2, 3)",
+		"SELECT * FROM t WHERE id IN (1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)",
+		"INSERT INTO t (a) VALUES (1), (2)",
+	} {
+		extractor.SetRawSQL(sql)
+		as.Nil(extractor.Extract())
+		for _, r := range extractor.Results() {
+			r.RawSQL = sql
+			a.Add(r)
+		}
+	}
+
+	stats := a.Stats()
+	as.Len(stats, 2)
+	as.Equal(&SizeHistogram{Bounds: []int{1, 5, 10}, Counts: []int64{1, 1, 0, 1}, Count: 3, Sum: 16, Max: 12},
+		stats[0].InListSizes)
+	as.Nil(stats[0].ValuesRows)
+	as.Nil(stats[1].InListSizes)
+	as.Equal(&SizeHistogram{Bounds: []int{1, 5, 10}, Counts: []int64{0, 1, 0, 0}, Count: 1, Sum: 2, Max: 2},
+		stats[1].ValuesRows)
+
+	var b strings.Builder
+	as.Nil(a.WriteMetrics(&b))
+	digest := stats[0].Digest
+	as.Contains(b.String(), "# TYPE sql_extractor_in_list_size histogram\n")
+	as.Contains(b.String(), `sql_extractor_in_list_size_bucket{digest="`+digest+`",le="5"} 2`+"\n"+
+		`sql_extractor_in_list_size_bucket{digest="`+digest+`",le="10"} 2`+"\n"+
+		`sql_extractor_in_list_size_bucket{digest="`+digest+`",le="+Inf"} 3`+"\n"+
+		`sql_extractor_in_list_size_sum{digest="`+digest+`"} 16`+"\n")
+	as.Contains(b.String(), `sql_extractor_values_rows_count{digest="`+stats[1].Digest+`"} 1`)
+	as.True(strings.HasSuffix(b.String(), "# EOF\n"))
+
+	// the snapshot is a copy
+	stats[0].InListSizes.Counts[0] = 100
+	as.Equal(int64(1), a.Stats()[0].InListSizes.Counts[0])
+
+	a = NewAggregator(WithListSizeHistograms())
+	as.Nil(a.AddSQL("SELECT * FROM t WHERE id IN (1, 2)", nil))
+	as.Equal(DefaultListSizeBounds, a.Stats()[0].InListSizes.Bounds)
+
+	a = NewAggregator()
+	as.Nil(a.AddSQL("SELECT * FROM t WHERE id IN (1, 2)", nil))
+	as.Nil(a.Stats()[0].InListSizes)
+	b.Reset()
+	as.Nil(a.WriteMetrics(&b))
+	as.Equal("# EOF\n", b.String())
+}
@@ -0,0 +1,244 @@
+package sqlextractor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+type (
+	// AnomalyKind is the kind of an anomaly of the OnAnomaly hook.
+	AnomalyKind = extract.AnomalyKind
+	// AnomalyInfo is the payload of the OnAnomaly hook: kind, digest, new table,
+	// and the window count and baseline of spikes.
+	AnomalyInfo = extract.AnomalyInfo
+)
+
+// Anomaly kinds of AnomalyDetector.
+const (
+	AnomalyNewDigest = extract.AnomalyNewDigest
+	AnomalySpike     = extract.AnomalySpike
+	AnomalyNewTable  = extract.AnomalyNewTable
+)
+
+// Default spike detection of the AnomalyDetector.
+const (
+	DefaultSpikeWindow   = time.Minute
+	DefaultSpikeFactor   = 10
+	DefaultSpikeMinCount = 100
+)
+
+// spikeSmoothing is the weight of the last window in the baseline, an
+// exponentially weighted moving average.
+const spikeSmoothing = 0.2
+
+// AnomalyDetector flags never-before-seen digests, sudden volume spikes per
+// digest and new tables being accessed in a stream of statements, and emits
+// them to the OnAnomaly hook, a lightweight database activity monitoring
+// primitive. It is safe for concurrent use.
+//
+// A spike is a window of a digest with more statements than the spike factor
+// times the baseline, the average statements of the digest per window; it is
+// emitted once per window.
+type AnomalyDetector struct {
+	mu sync.Mutex
+
+	hooks    *Hooks
+	window   time.Duration
+	factor   float64
+	minCount int64
+	learning time.Duration
+	learnEnd time.Time // 学习期结束时间，之前不发出异常
+	now      func() time.Time
+
+	digests map[string]*digestActivity
+	tables  map[string]struct{}
+}
+
+// digestActivity is the volume of a digest.
+type digestActivity struct {
+	start    time.Time // start of the current window
+	count    int64     // statements in the current window
+	baseline float64   // average statements per window
+	windows  int       // complete windows in the baseline
+	spiked   bool      // spike emitted in the current window
+}
+
+// AnomalyOption configures the AnomalyDetector.
+type AnomalyOption func(*AnomalyDetector)
+
+// WithSpikeWindow sets the window of the spike detection, default is
+// DefaultSpikeWindow.
+func WithSpikeWindow(d time.Duration) AnomalyOption {
+	return func(a *AnomalyDetector) {
+		if d > 0 {
+			a.window = d
+		}
+	}
+}
+
+// WithSpikeThreshold flags the windows with more than factor times the
+// baseline statements, and at least minCount statements so quiet digests do
+// not spike. Defaults are DefaultSpikeFactor and DefaultSpikeMinCount.
+func WithSpikeThreshold(factor float64, minCount int64) AnomalyOption {
+	return func(a *AnomalyDetector) {
+		a.factor = max(factor, 1)
+		a.minCount = max(minCount, 1)
+	}
+}
+
+// WithLearningPeriod learns the digests, tables and baselines without emitting
+// anomalies for the duration d after the detector is created, so the known
+// activity is not flagged after a restart.
+func WithLearningPeriod(d time.Duration) AnomalyOption {
+	return func(a *AnomalyDetector) { a.learning = max(d, 0) }
+}
+
+// NewAnomalyDetector creates a new AnomalyDetector emitting the anomalies to
+// the OnAnomaly hook of hooks.
+//
+// Example:
+//
+//	detector := NewAnomalyDetector(&Hooks{
+//	  OnAnomaly: func(info AnomalyInfo) { log.Printf("%s %s %s", info.Kind, info.Digest, info.Table) },
+//	}, WithLearningPeriod(time.Hour))
+//
+//	extractor := NewExtractor(sql)
+//	extractor.Use(detector.Middleware())
+func NewAnomalyDetector(hooks *Hooks, opts ...AnomalyOption) *AnomalyDetector {
+	a := &AnomalyDetector{
+		hooks:    hooks,
+		window:   DefaultSpikeWindow,
+		factor:   DefaultSpikeFactor,
+		minCount: DefaultSpikeMinCount,
+		now:      time.Now,
+		digests:  make(map[string]*digestActivity),
+		tables:   make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.learning > 0 {
+		a.learnEnd = a.now().Add(a.learning)
+	}
+
+	return a
+}
+
+// Add checks a statement result, e.g. of ExtractStream, for anomalies. Results
+// with Err set are ignored.
+func (a *AnomalyDetector) Add(r StatementResult) {
+	if r.Err != nil {
+		return
+	}
+
+	digest := defaultHash([]byte(r.TemplatizedSQL))
+	anomalies := a.check(r, digest)
+
+	// 钩子在锁外调用
+	if a.hooks == nil || a.hooks.OnAnomaly == nil {
+		return
+	}
+	for _, info := range anomalies {
+		a.hooks.OnAnomaly(info)
+	}
+}
+
+// AddSQL extracts the SQL with the tags, and checks its statements for
+// anomalies.
+func (a *AnomalyDetector) AddSQL(sql string, tags Tags) error {
+	e := NewExtractor(sql)
+	if err := e.Extract(); err != nil {
+		return err
+	}
+
+	for idx := range e.templatedSQL {
+		a.Add(StatementResult{
+			Index:          idx,
+			TemplatizedSQL: e.templatedSQL[idx],
+			Params:         e.params[idx],
+			TableInfos:     e.tableInfos[idx],
+			OpType:         e.opType[idx],
+			Tags:           tags,
+		})
+	}
+
+	return nil
+}
+
+// Middleware returns a Middleware checking the statements of an Extractor for
+// anomalies, the results are not changed.
+func (a *AnomalyDetector) Middleware() Middleware {
+	return func(r StatementResult) StatementResult {
+		a.Add(r)
+		return r
+	}
+}
+
+// check records the statement and returns its anomalies.
+func (a *AnomalyDetector) check(r StatementResult, digest string) []AnomalyInfo {
+	now := a.now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var anomalies []AnomalyInfo
+	emit := !now.Before(a.learnEnd)
+	anomaly := func(kind AnomalyKind) *AnomalyInfo {
+		anomalies = append(anomalies, AnomalyInfo{
+			Kind:           kind,
+			Time:           now,
+			Digest:         digest,
+			TemplatizedSQL: r.TemplatizedSQL,
+			Tags:           r.Tags,
+		})
+
+		return &anomalies[len(anomalies)-1]
+	}
+
+	activity, ok := a.digests[digest]
+	if !ok {
+		activity = &digestActivity{start: now.Truncate(a.window)}
+		a.digests[digest] = activity
+		if emit {
+			anomaly(AnomalyNewDigest)
+		}
+	}
+
+	for _, ti := range r.TableInfos {
+		name, _ := ti.TableNameWithSchema()
+		if _, ok := a.tables[name]; ok {
+			continue
+		}
+
+		a.tables[name] = struct{}{}
+		if emit {
+			anomaly(AnomalyNewTable).Table = name
+		}
+	}
+
+	// 进入新窗口时，将之前的窗口（包括没有语句的窗口）计入 baseline
+	if start := now.Truncate(a.window); start.After(activity.start) {
+		windows := int(start.Sub(activity.start) / a.window)
+		if activity.windows == 0 {
+			activity.baseline = float64(activity.count) // 第一个窗口作为初始 baseline
+		} else {
+			activity.baseline = spikeSmoothing*float64(activity.count) + (1-spikeSmoothing)*activity.baseline
+		}
+		activity.baseline *= math.Pow(1-spikeSmoothing, float64(windows-1))
+		activity.windows += windows
+		activity.start, activity.count, activity.spiked = start, 0, false
+	}
+
+	activity.count++
+	if emit && activity.windows > 0 && !activity.spiked && activity.count >= a.minCount &&
+		float64(activity.count) > a.factor*activity.baseline {
+		activity.spiked = true
+		info := anomaly(AnomalySpike)
+		info.Count, info.Baseline = activity.count, activity.baseline
+	}
+
+	return anomalies
+}
@@ -0,0 +1,116 @@
+package sqlextractor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var (
+		mu        sync.Mutex
+		anomalies []AnomalyInfo
+	)
+	hooks := &Hooks{OnAnomaly: func(info AnomalyInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		anomalies = append(anomalies, info)
+	}}
+	take := func() []AnomalyInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		taken := anomalies
+		anomalies = nil
+		return taken
+	}
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	d := NewAnomalyDetector(hooks, WithSpikeWindow(time.Minute), WithSpikeThreshold(5, 10))
+	d.now = func() time.Time { return now }
+
+	// new digest and new tables
+	as.Nil(d.AddSQL("SELECT * FROM shop.users u JOIN orders o ON u.id = o.uid WHERE u.id = 1", Tags{"service": "api"}))
+	got := take()
+	as.Equal(3, len(got))
+	as.Equal(AnomalyNewDigest, got[0].Kind)
+	as.Equal(defaultHash([]byte(got[0].TemplatizedSQL)), got[0].Digest)
+	as.Equal(map[string]string{"service": "api"}, got[0].Tags)
+	as.Equal(AnomalyNewTable, got[1].Kind)
+	as.Equal("shop.users", got[1].Table)
+	as.Equal("orders", got[2].Table)
+
+	// known digest and tables
+	as.Nil(d.AddSQL("SELECT * FROM shop.users u JOIN orders o ON u.id = o.uid WHERE u.id = 2", nil))
+	as.Nil(d.AddSQL("DELETE FROM orders WHERE id = 1", nil))
+	got = take()
+	as.Equal(1, len(got))
+	as.Equal(AnomalyNewDigest, got[0].Kind)
+	as.Equal("DELETE FROM orders WHERE id eq ?", got[0].TemplatizedSQL)
+
+	// baseline of 2 per minute, a spike is more than 10 and at least 10
+	for range 3 {
+		now = now.Add(time.Minute)
+		for range 2 {
+			as.Nil(d.AddSQL("SELECT * FROM shop.users u JOIN orders o ON u.id = o.uid WHERE u.id = 3", nil))
+		}
+	}
+	as.Empty(take())
+
+	now = now.Add(time.Minute)
+	for range 20 {
+		as.Nil(d.AddSQL("SELECT * FROM shop.users u JOIN orders o ON u.id = o.uid WHERE u.id = 3", nil))
+	}
+	got = take()
+	as.Equal(1, len(got))
+	as.Equal(AnomalySpike, got[0].Kind)
+	as.Equal(int64(11), got[0].Count)
+	as.InDelta(2.0, got[0].Baseline, 0.01)
+
+	// the baseline decays in quiet windows
+	now = now.Add(time.Hour)
+	for range 10 {
+		as.Nil(d.AddSQL("DELETE FROM orders WHERE id = 1", nil))
+	}
+	got = take()
+	as.Equal(1, len(got))
+	as.Equal(AnomalySpike, got[0].Kind)
+	as.Equal(int64(10), got[0].Count)
+
+	// errors are ignored
+	as.NotNil(d.AddSQL("SELEC", nil))
+	d.Add(StatementResult{TemplatizedSQL: "SELECT ?", Err: assert.AnError})
+	as.Empty(take())
+}
+
+func TestAnomalyDetector_Learning(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var anomalies []AnomalyInfo
+	d := NewAnomalyDetector(&Hooks{OnAnomaly: func(info AnomalyInfo) { anomalies = append(anomalies, info) }},
+		WithLearningPeriod(time.Hour))
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	e := NewExtractor("SELECT * FROM users WHERE id = 1")
+	e.Use(d.Middleware())
+	as.Nil(e.Extract())
+	as.Empty(anomalies)
+
+	now = now.Add(2 * time.Hour)
+	e = NewExtractor("SELECT * FROM users WHERE id = 2; SELECT * FROM orders")
+	e.Use(d.Middleware())
+	as.Nil(e.Extract())
+	as.Equal(2, len(anomalies))
+	as.Equal(AnomalyNewDigest, anomalies[0].Kind)
+	as.Equal(AnomalyNewTable, anomalies[1].Kind)
+	as.Equal("orders", anomalies[1].Table)
+
+	// without hooks
+	NewAnomalyDetector(nil).Add(StatementResult{TemplatizedSQL: "SELECT ?"})
+}
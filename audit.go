@@ -0,0 +1,203 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// AuditRecord is the data access audit record of a statement, see
+// AuditRecords.
+type AuditRecord struct {
+	User   string       `json:"user,omitempty"` // the user tag, see WithAuditUserTag
+	Action string       `json:"action"`         // read, insert, update, delete, or the lower case op type
+	Tables []AuditTable `json:"tables"`
+	Params []string     `json:"params"` // masked params, see WithAuditMask
+	Digest string       `json:"digest"`
+	Tags   Tags         `json:"tags,omitempty"`
+}
+
+// AuditTable is the access to a table of an AuditRecord.
+type AuditTable struct {
+	Table   string   `json:"table"`   // table name with schema
+	Columns []string `json:"columns"` // columns read (projected) or written, empty for all the rows
+	Filters []string `json:"filters"` // columns filtered on
+}
+
+// auditVerbs are the verbs of the actions in the audit messages.
+var auditVerbs = map[string]string{
+	"read":   "read",
+	"insert": "inserted",
+	"update": "updated",
+	"delete": "deleted",
+}
+
+// String returns the record as a message for compliance logs, e.g.
+// alice read columns name, email of table users with filter on tenant_id.
+func (r *AuditRecord) String() string {
+	var b strings.Builder
+
+	user := r.User
+	if user == "" {
+		user = "unknown user"
+	}
+	verb, ok := auditVerbs[r.Action]
+	if !ok {
+		verb = "ran " + r.Action + " on"
+	}
+	fmt.Fprintf(&b, "%s %s ", user, verb)
+
+	for idx, t := range r.Tables {
+		if idx > 0 {
+			b.WriteString(" and ")
+		}
+
+		if len(t.Columns) == 0 {
+			fmt.Fprintf(&b, "rows of table %s", t.Table)
+		} else {
+			fmt.Fprintf(&b, "columns %s of table %s", strings.Join(t.Columns, ", "), t.Table)
+		}
+
+		if len(t.Filters) > 0 {
+			fmt.Fprintf(&b, " with filter on %s", strings.Join(t.Filters, ", "))
+		}
+	}
+
+	if len(r.Tables) == 0 {
+		b.WriteString("no table")
+	}
+
+	return b.String()
+}
+
+// AuditOption configures AuditRecords.
+type AuditOption func(*auditConfig)
+
+type auditConfig struct {
+	userTag string
+	mask    func(param any) string
+}
+
+// DefaultAuditUserTag is the tag of the user of the audit records.
+const DefaultAuditUserTag = "user"
+
+// WithAuditUserTag sets the tag of the user of the audit records,
+// DefaultAuditUserTag by default.
+func WithAuditUserTag(key string) AuditOption {
+	return func(c *auditConfig) { c.userTag = key }
+}
+
+// WithAuditMask sets the function masking the params of the audit records, by
+// default all the values but NULL are masked as ***, e.g. to keep the tenant
+// ids.
+func WithAuditMask(mask func(param any) string) AuditOption {
+	return func(c *auditConfig) { c.mask = mask }
+}
+
+// maskParam masks all the values but NULL.
+func maskParam(param any) string {
+	if param == nil {
+		return "NULL"
+	}
+
+	return "***"
+}
+
+// AuditRecords returns the data access audit record of each statement, for
+// compliance logs: the user (from the tags), the action, the columns read or
+// written and filtered on of each table, and the masked params. It should be
+// called after Extract.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT name, email FROM users WHERE tenant_id = 42")
+//	extractor.SetTags(Tags{"user": "alice"})
+//	_ = extractor.Extract()
+//	records, err := extractor.AuditRecords()
+//	// alice read columns name, email of table users with filter on tenant_id
+func (e *Extractor) AuditRecords(opts ...AuditOption) ([]AuditRecord, error) {
+	cfg := auditConfig{userTag: DefaultAuditUserTag, mask: maskParam}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	columns, err := e.internal().ExtractColumns(e.rawSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(columns) != len(e.hash) {
+		return nil, fmt.Errorf("statement count mismatch: %d vs %d, Extract should be called first",
+			len(columns), len(e.hash))
+	}
+
+	records := make([]AuditRecord, len(e.hash))
+	for idx := range records {
+		r := &records[idx]
+		r.User = e.tags[cfg.userTag]
+		r.Action = auditAction(e.opType[idx])
+		r.Digest = e.hash[idx]
+		r.Tags = e.tags
+		r.Tables = auditTables(e.tableInfos[idx], columns[idx], e.opType[idx] == models.SQLOperationSelect)
+
+		r.Params = make([]string, len(e.params[idx]))
+		for jdx := range e.params[idx] {
+			r.Params[jdx] = cfg.mask(e.params[idx][jdx])
+		}
+	}
+
+	return records, nil
+}
+
+// auditAction returns the action of the op type.
+func auditAction(op models.SQLOpType) string {
+	if op == models.SQLOperationSelect {
+		return "read"
+	}
+
+	return strings.ToLower(op.String())
+}
+
+// auditTables returns the accesses to the tables, in order of appearance. The
+// columns are the projected columns of reads, and the written columns of the
+// other statements.
+func auditTables(tables []*models.TableInfo, columns []*models.ColumnUsage, read bool) []AuditTable {
+	access := models.ColumnWrite
+	if read {
+		access = models.ColumnProjection
+	}
+
+	var (
+		result = make([]AuditTable, 0, len(tables))
+		index  = make(map[string]int, len(tables))
+	)
+	for _, ti := range tables {
+		name, _ := ti.TableNameWithSchema()
+		if _, ok := index[name]; ok {
+			continue
+		}
+
+		index[name] = len(result)
+		result = append(result, AuditTable{Table: name, Columns: []string{}, Filters: []string{}})
+	}
+
+	for _, c := range columns {
+		name, _ := models.NewTableInfo(c.Schema(), c.Table()).TableNameWithSchema()
+		idx, ok := index[name]
+		if !ok {
+			continue
+		}
+
+		t := &result[idx]
+		switch {
+		case c.Usage() == access && !slices.Contains(t.Columns, c.Column()):
+			t.Columns = append(t.Columns, c.Column())
+		case c.Usage() == models.ColumnFilter && !slices.Contains(t.Filters, c.Column()):
+			t.Filters = append(t.Filters, c.Column())
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,60 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_AuditRecords(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor("SELECT name, email FROM users WHERE tenant_id = 42; " +
+		"UPDATE users SET email = 'a@b.c' WHERE id = 1; DELETE FROM orders WHERE state = 'new'")
+	e.SetTags(Tags{"user": "alice"})
+	as.Nil(e.Extract())
+
+	records, err := e.AuditRecords()
+	as.Nil(err)
+	as.Len(records, 3)
+
+	as.Equal("alice", records[0].User)
+	as.Equal("read", records[0].Action)
+	as.Equal([]AuditTable{{Table: "users", Columns: []string{"name", "email"}, Filters: []string{"tenant_id"}}},
+		records[0].Tables)
+	as.Equal([]string{"***"}, records[0].Params)
+	as.Equal("alice read columns name, email of table users with filter on tenant_id", records[0].String())
+
+	as.Equal("alice updated columns email of table users with filter on id", records[1].String())
+	as.Equal("alice deleted rows of table orders with filter on state", records[2].String())
+
+	// 自定义用户标签与脱敏函数
+	records, err = e.AuditRecords(WithAuditUserTag("principal"),
+		WithAuditMask(func(param any) string { return fmt.Sprint(param) }))
+	as.Nil(err)
+	as.Equal("", records[0].User)
+	as.Equal([]string{"42"}, records[0].Params)
+	as.Equal("unknown user read columns name, email of table users with filter on tenant_id", records[0].String())
+
+	// 未调用 Extract
+	_, err = NewExtractor("SELECT 1").AuditRecords()
+	as.NotNil(err)
+}
+
+func TestExtractor_AuditRecords_Dialect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 使用 Extractor 的方言选项解析
+	e := NewExtractor("INSERT INTO users (id, email) VALUES (1, 'a@b.c') ON CONFLICT (id) DO UPDATE SET email = 'd@e.f'",
+		WithPostgres())
+	e.SetTags(Tags{"user": "alice"})
+	as.Nil(e.Extract())
+
+	records, err := e.AuditRecords()
+	as.Nil(err)
+	as.Len(records, 1)
+	as.Equal("alice", records[0].User)
+}
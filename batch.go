@@ -0,0 +1,40 @@
+package sqlextractor
+
+// BatchedTemplate returns the batched equivalent of a single-row lookup
+// template, e.g. a TemplatizedSQL or the template of an NPlusOne: the equality
+// of the lookup column with a value is rewritten as an IN-list of the column,
+// so the rows of a loop of lookups can be fetched with a single query. The
+// batched template is templatized like any other statement, and the column is
+// returned with it. ok is false if the template is not a single-row lookup, a
+// SELECT from one table whose WHERE clause is the equality of a column with a
+// value.
+//
+// Example:
+//
+//	batched, column, ok, err := sqlextractor.BatchedTemplate("SELECT * FROM orders WHERE user_id eq ?")
+//	// batched: SELECT * FROM orders WHERE user_id IN (?)
+//	// column:  user_id
+func BatchedTemplate(template string) (batched, column string, ok bool, err error) {
+	sql, _, err := Translate(template, DialectMySQL, DialectMySQL)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	return batchedTemplate(sql)
+}
+
+// batchedTemplate returns the batched template of the executable single-row
+// lookup.
+func batchedTemplate(sql string) (string, string, bool, error) {
+	rewritten, column, ok, err := defaultExtractor.BatchLookup(sql, 1)
+	if err != nil || !ok {
+		return "", "", false, err
+	}
+
+	templates, _, _, _, err := defaultExtractor.Extract(rewritten)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	return templates[0], column, true, nil
+}
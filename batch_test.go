@@ -0,0 +1,43 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchedTemplate(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	batched, column, ok, err := BatchedTemplate("SELECT * FROM orders WHERE user_id eq ?")
+	as.Nil(err)
+	as.True(ok)
+	as.Equal("SELECT * FROM orders WHERE user_id IN (?)", batched)
+	as.Equal("user_id", column)
+
+	batched, column, ok, err = BatchedTemplate("SELECT name, age FROM shop.users AS u WHERE ? eq u.id LIMIT ?")
+	as.Nil(err)
+	as.True(ok)
+	as.Equal("SELECT name, age FROM shop.users AS u WHERE u.id IN (?) LIMIT ?", batched)
+	as.Equal("id", column)
+
+	// the template of an N+1 pattern
+	e := NewExtractor("SELECT * FROM users WHERE email = 'kyden@example.com'")
+	as.Nil(e.Extract())
+	batched, _, ok, err = BatchedTemplate(e.TemplatizedSQL()[0])
+	as.Nil(err)
+	as.True(ok)
+	as.Equal("SELECT * FROM users WHERE email IN (?)", batched)
+
+	_, _, ok, err = BatchedTemplate("SELECT * FROM users WHERE id gt ?")
+	as.Nil(err)
+	as.False(ok)
+
+	_, _, ok, err = BatchedTemplate("UPDATE users SET name eq ? WHERE id eq ?")
+	as.Nil(err)
+	as.False(ok)
+
+	_, _, _, err = BatchedTemplate("SELEC")
+	as.NotNil(err)
+}
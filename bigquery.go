@@ -0,0 +1,13 @@
+package sqlextractor
+
+// WithBigQuery supports the BigQuery standard SQL syntax the MySQL parser
+// rejects, e.g. for a Dialect wrapping the Extractor: the project.dataset.table
+// names, quoted by backticks as a whole or not, UNNEST, STRUCT and array
+// literals, QUALIFY and the wildcard modifiers. The project is reported by
+// TableInfo.Catalog and the catalog field of the Envelope tables. The named
+// parameters @param are kept in the template.
+//
+// e.g. SELECT x FROM `p.d.t`, UNNEST(arr) AS x WHERE id = @id -> SELECT x FROM p.d.t CROSS JOIN UNNEST(arr) AS x WHERE id eq @id
+func WithBigQuery() ExtractorOption {
+	return func(e *Extractor) { e.options.bigquery = true }
+}
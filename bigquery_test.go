@@ -0,0 +1,29 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithBigQuery(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT e.user_id, tag FROM `my-project.analytics.events` AS e, UNNEST(e.tags) AS tag " +
+		"WHERE e.day = @day AND e.country IN UNNEST(@countries)"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithBigQuery())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT e.user_id, tag FROM my-project.analytics.events AS e CROSS JOIN UNNEST(e.tags) AS tag " +
+		"WHERE e.day eq @day and e.country IN UNNEST(@countries)"}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{}}, extractor.Params())
+
+	ti := extractor.TableInfos()[0][0]
+	as.Equal("my-project", ti.Catalog())
+	as.Equal("analytics", ti.Schema())
+	as.Equal("events", ti.TableName())
+	as.Equal("my-project.analytics.events", extractor.Envelope().Statements[0].Tables[0].String())
+}
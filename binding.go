@@ -0,0 +1,174 @@
+package sqlextractor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldBinding binds a param to a field of a struct.
+type FieldBinding struct {
+	Param string // name of the param, e.g. of ParamNames
+	Field string // path of the field, e.g. Address.City
+	Index []int  // index of the field for reflect.Value.FieldByIndex
+	// Shared is true when the param of a repeated column, e.g. id_2 of id IN
+	// (?, ?), is bound to the field of the column.
+	Shared bool
+}
+
+// BindingPlan binds the params of a statement to the fields of a struct, in
+// positional binding order.
+type BindingPlan []FieldBinding
+
+// NewBindingPlan returns the binding plan of the named params, e.g. of
+// ParamNames, to the fields of the struct v, a struct or a pointer to a
+// struct, so code generators can emit the data access code of captured SQL.
+// The fields are named as with sqlx: by their db tag, or their lowercase
+// name, db:"-" fields are skipped and embedded structs are flattened. A param
+// suffixed with _2, _3, ... without field of its own is bound to the field of
+// its column. It returns an error if a param has no field.
+//
+// Example:
+//
+//	type User struct {
+//	  ID   int64 `db:"id"`
+//	  Name string
+//	}
+//
+//	plan, err := sqlextractor.NewBindingPlan(User{}, []string{"name", "id"})
+//	// plan: [{name Name [1]} {id ID [0]}]
+//	args, err := plan.Args(&user)
+func NewBindingPlan(v any, names []string) (BindingPlan, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", v)
+	}
+
+	fields := make(map[string]FieldBinding)
+	collectFields(t, nil, "", fields)
+
+	plan := make(BindingPlan, 0, len(names))
+	for idx, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			if base, suffixed := trimParamSuffix(name); suffixed {
+				field, ok = fields[base]
+				field.Shared = true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("no field of param %s (%d) in %s", name, idx+1, t)
+		}
+
+		field.Param = name
+		plan = append(plan, field)
+	}
+
+	return plan, nil
+}
+
+// BindingPlans returns the binding plan of the params of each statement, see
+// ParamNames, to the fields of the struct v.
+func (e *Extractor) BindingPlans(v any) ([]BindingPlan, error) {
+	names, err := e.ParamNames()
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]BindingPlan, 0, len(names))
+	for idx := range names {
+		plan, err := NewBindingPlan(v, names[idx])
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", idx+1, err)
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+// Args returns the values of the fields of the struct v, a struct or a
+// pointer to a struct of the type of the plan, in positional binding order.
+func (p BindingPlan) Args(v any) ([]any, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, errors.New("nil struct pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", v)
+	}
+
+	args := make([]any, len(p))
+	for idx := range p {
+		field, err := rv.FieldByIndexErr(p[idx].Index)
+		if err != nil {
+			return nil, fmt.Errorf("field %s of param %s: %w", p[idx].Field, p[idx].Param, err)
+		}
+		args[idx] = field.Interface()
+	}
+
+	return args, nil
+}
+
+// collectFields collects the fields of the struct by name, the fields of the
+// outer structs take precedence over those of the embedded ones.
+func collectFields(t reflect.Type, index []int, prefix string, fields map[string]FieldBinding) {
+	var embedded []reflect.StructField
+	for idx := range t.NumField() {
+		f := t.Field(idx)
+		tag, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+		if tag == "-" {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && tag == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, f) // 展开匿名结构体
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = FieldBinding{Field: prefix + f.Name, Index: append(append([]int{}, index...), idx)}
+		}
+	}
+
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		collectFields(ft, append(append([]int{}, index...), f.Index...), prefix+f.Name+".", fields)
+	}
+}
+
+// trimParamSuffix trims the _2, _3, ... suffix of the param of a repeated
+// column, e.g. id_2 -> id.
+func trimParamSuffix(name string) (string, bool) {
+	i := strings.LastIndexByte(name, '_')
+	if i <= 0 {
+		return name, false
+	}
+	if n, err := strconv.Atoi(name[i+1:]); err != nil || n < 2 {
+		return name, false
+	}
+
+	return name[:i], true
+}
@@ -0,0 +1,61 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bindingBase struct {
+	ID      int64 `db:"id"`
+	Created string
+}
+
+type bindingUser struct {
+	bindingBase
+	Name   string `db:"user_name"`
+	Age    int
+	Secret string `db:"-"`
+	Limit  int    `db:"limit,omitempty"`
+}
+
+func TestNewBindingPlan(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	plan, err := NewBindingPlan(&bindingUser{}, []string{"user_name", "age", "id", "id_2", "limit"})
+	as.Nil(err)
+	as.Equal(BindingPlan{
+		{Param: "user_name", Field: "Name", Index: []int{1}},
+		{Param: "age", Field: "Age", Index: []int{2}},
+		{Param: "id", Field: "bindingBase.ID", Index: []int{0, 0}},
+		{Param: "id_2", Field: "bindingBase.ID", Index: []int{0, 0}, Shared: true},
+		{Param: "limit", Field: "Limit", Index: []int{4}},
+	}, plan)
+
+	args, err := plan.Args(bindingUser{bindingBase: bindingBase{ID: 7}, Name: "bob", Age: 18, Limit: 10})
+	as.Nil(err)
+	as.Equal([]any{"bob", 18, int64(7), int64(7), 10}, args)
+
+	_, err = NewBindingPlan(bindingUser{}, []string{"secret"})
+	as.EqualError(err, "no field of param secret (1) in sqlextractor.bindingUser")
+	_, err = NewBindingPlan(1, nil)
+	as.EqualError(err, "int is not a struct")
+}
+
+func TestExtractor_BindingPlans(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("UPDATE users SET age = 18 WHERE id = 1 AND created = '2024-01-01'")
+	plans, err := extractor.BindingPlans(bindingUser{})
+	as.Nil(err)
+	as.Len(plans, 1)
+
+	args, err := plans[0].Args(&bindingUser{bindingBase: bindingBase{ID: 1, Created: "2024-01-01"}, Age: 18})
+	as.Nil(err)
+	as.Equal([]any{18, int64(1), "2024-01-01"}, args)
+
+	_, err = NewExtractor("SELECT * FROM t WHERE missing = 1").BindingPlans(bindingUser{})
+	as.EqualError(err, "statement 1: no field of param missing (1) in sqlextractor.bindingUser")
+}
@@ -0,0 +1,286 @@
+package sqlextractor
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// BinlogRowsType is the type of a binlog rows event.
+type BinlogRowsType int
+
+const (
+	BinlogWriteRows  BinlogRowsType = iota // WRITE_ROWS_EVENT, INSERT
+	BinlogUpdateRows                       // UPDATE_ROWS_EVENT, UPDATE
+	BinlogDeleteRows                       // DELETE_ROWS_EVENT, DELETE
+)
+
+// BinlogQueryEvent is a statement format binlog event (QUERY_EVENT), e.g.
+// from go-mysql replication.QueryEvent.
+type BinlogQueryEvent struct {
+	Schema string // default schema of the statement, set on unqualified tables
+	Query  string
+}
+
+// BinlogRowsEvent is a row format binlog event, e.g. from go-mysql
+// replication.RowsEvent.
+type BinlogRowsEvent struct {
+	Type   BinlogRowsType
+	Schema string
+	Table  string
+
+	// Columns are the column names, available with binlog_row_metadata=FULL.
+	// If empty, the columns are named by their 1-based position, @1, @2, ...
+	Columns []string
+
+	// Rows are the row images. Update events have two images per row, before
+	// and after the update, as in go-mysql.
+	Rows [][]any
+}
+
+// BinlogAdapter produces extraction results from parsed binlog events.
+// Statement events are templatized directly, row events are synthesized into
+// canonical INSERT, UPDATE and DELETE templates per table, e.g. an update of
+// (id, name) gives:
+//
+//	UPDATE shop.users SET id eq ?, name eq ? WHERE id eq ? and name eq ?
+//
+// with the after image then the before image as parameters. Templates of row
+// events are cached per table and columns. It is safe for concurrent use.
+type BinlogAdapter struct {
+	mu    sync.RWMutex
+	cache map[string]*binlogTemplate // synthesized SQL -> template
+}
+
+// binlogTemplate is the template of a synthesized row event statement.
+type binlogTemplate struct {
+	templatizedSQL string
+	tableInfos     []*models.TableInfo
+	opType         models.SQLOpType
+}
+
+// NewBinlogAdapter creates a new BinlogAdapter.
+func NewBinlogAdapter() *BinlogAdapter {
+	return &BinlogAdapter{cache: make(map[string]*binlogTemplate)}
+}
+
+// QueryEvent extracts the statements of a statement format event.
+// Transaction control statements (BEGIN, COMMIT, ...) give no results.
+func (a *BinlogAdapter) QueryEvent(ev BinlogQueryEvent) ([]StatementResult, error) {
+	if isTxnControl(ev.Query) {
+		return nil, nil
+	}
+
+	e := NewExtractor(ev.Query)
+	if err := e.Extract(); err != nil {
+		return nil, err
+	}
+
+	results := make([]StatementResult, len(e.templatedSQL))
+	for idx := range e.templatedSQL {
+		results[idx] = StatementResult{
+			Index:          idx,
+			RawSQL:         ev.Query,
+			TemplatizedSQL: e.templatedSQL[idx],
+			TableInfos:     withDefaultSchema(e.tableInfos[idx], ev.Schema),
+			Params:         e.params[idx],
+			OpType:         e.opType[idx],
+		}
+	}
+
+	return results, nil
+}
+
+// withDefaultSchema sets the schema of the unqualified tables, the templatized
+// schema is kept empty as the template has no schema.
+func withDefaultSchema(tableInfos []*models.TableInfo, schema string) []*models.TableInfo {
+	if schema == "" {
+		return tableInfos
+	}
+
+	for _, ti := range tableInfos {
+		if ti.Schema() == "" {
+			ti.SetSchema(schema)
+		}
+	}
+
+	return tableInfos
+}
+
+// RowsEvent synthesizes a statement per row of a row format event.
+func (a *BinlogAdapter) RowsEvent(ev BinlogRowsEvent) ([]StatementResult, error) {
+	step := 1
+	if ev.Type == BinlogUpdateRows {
+		step = 2
+	}
+
+	results := make([]StatementResult, 0, len(ev.Rows)/step)
+	for idx := 0; idx+step <= len(ev.Rows); idx += step {
+		if len(ev.Rows[idx]) == 0 {
+			continue
+		}
+
+		var (
+			sql    string
+			params []any
+		)
+
+		switch ev.Type {
+		case BinlogWriteRows:
+			sql = insertSQL(ev.Schema, ev.Table, columnNames(ev.Columns, len(ev.Rows[idx])))
+			params = ev.Rows[idx]
+		case BinlogUpdateRows:
+			before, after := ev.Rows[idx], ev.Rows[idx+1]
+			sql = updateSQL(ev.Schema, ev.Table,
+				columnNames(ev.Columns, len(after)), columnNames(ev.Columns, len(before)))
+			params = append(append(make([]any, 0, len(after)+len(before)), after...), before...)
+		case BinlogDeleteRows:
+			sql = deleteSQL(ev.Schema, ev.Table, columnNames(ev.Columns, len(ev.Rows[idx])))
+			params = ev.Rows[idx]
+		}
+
+		tmpl, err := a.template(sql)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, StatementResult{
+			Index:          len(results),
+			TemplatizedSQL: tmpl.templatizedSQL,
+			TableInfos:     tmpl.tableInfos,
+			Params:         params,
+			OpType:         tmpl.opType,
+		})
+	}
+
+	return results, nil
+}
+
+// template returns the cached template of the synthesized SQL.
+func (a *BinlogAdapter) template(sql string) (*binlogTemplate, error) {
+	a.mu.RLock()
+	tmpl, ok := a.cache[sql]
+	a.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	e := NewExtractor(sql)
+	if err := e.Extract(); err != nil {
+		return nil, err
+	}
+
+	tmpl = &binlogTemplate{
+		templatizedSQL: e.templatedSQL[0],
+		tableInfos:     e.tableInfos[0],
+		opType:         e.opType[0],
+	}
+
+	a.mu.Lock()
+	a.cache[sql] = tmpl
+	a.mu.Unlock()
+
+	return tmpl, nil
+}
+
+// columnNames returns the n column names, named by position if unknown.
+func columnNames(columns []string, n int) []string {
+	if len(columns) == n {
+		return columns
+	}
+
+	names := make([]string, n)
+	for idx := range names {
+		names[idx] = "@" + strconv.Itoa(idx+1)
+	}
+
+	return names
+}
+
+// quoteIdent quotes the identifier with backticks.
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func qualifiedTable(schema, table string) string {
+	if schema == "" {
+		return quoteIdent(table)
+	}
+
+	return quoteIdent(schema) + "." + quoteIdent(table)
+}
+
+// insertSQL returns INSERT INTO t (a, b) VALUES (?, ?).
+func insertSQL(schema, table string, columns []string) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(qualifiedTable(schema, table))
+	b.WriteString(" (")
+	for idx := range columns {
+		if idx > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteIdent(columns[idx]))
+	}
+	b.WriteString(") VALUES (")
+	b.WriteString(strings.Repeat("?, ", len(columns)-1))
+	b.WriteString("?)")
+
+	return b.String()
+}
+
+// updateSQL returns UPDATE t SET a = ?, b = ? WHERE a = ? AND b = ?.
+func updateSQL(schema, table string, set, where []string) string {
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(qualifiedTable(schema, table))
+	b.WriteString(" SET ")
+	for idx := range set {
+		if idx > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteIdent(set[idx]))
+		b.WriteString(" = ?")
+	}
+	writeWhere(&b, where)
+
+	return b.String()
+}
+
+// deleteSQL returns DELETE FROM t WHERE a = ? AND b = ?.
+func deleteSQL(schema, table string, where []string) string {
+	var b strings.Builder
+	b.WriteString("DELETE FROM ")
+	b.WriteString(qualifiedTable(schema, table))
+	writeWhere(&b, where)
+
+	return b.String()
+}
+
+func writeWhere(b *strings.Builder, columns []string) {
+	b.WriteString(" WHERE ")
+	for idx := range columns {
+		if idx > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(quoteIdent(columns[idx]))
+		b.WriteString(" = ?")
+	}
+}
+
+// txnControl are the transaction control statements of statement events.
+var txnControl = []string{"BEGIN", "COMMIT", "ROLLBACK", "XA "}
+
+// isTxnControl reports whether the query is a transaction control statement.
+func isTxnControl(query string) bool {
+	query = strings.TrimSpace(query)
+	for _, prefix := range txnControl {
+		if len(query) >= len(prefix) && strings.EqualFold(query[:len(prefix)], prefix) {
+			return true
+		}
+	}
+
+	return false
+}
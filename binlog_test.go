@@ -0,0 +1,71 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestBinlogAdapter_QueryEvent(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	a := NewBinlogAdapter()
+
+	results, err := a.QueryEvent(BinlogQueryEvent{Schema: "shop", Query: "BEGIN"})
+	as.Nil(err)
+	as.Empty(results)
+
+	results, err = a.QueryEvent(BinlogQueryEvent{Schema: "shop", Query: "UPDATE users SET name = 'a' WHERE id = 1"})
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.Equal("UPDATE users SET name eq ? WHERE id eq ?", results[0].TemplatizedSQL)
+	as.Equal([]any{"a", int64(1)}, results[0].Params)
+	as.Equal("shop", results[0].TableInfos[0].Schema())
+	as.Equal(models.SQLOperationUpdate, results[0].OpType)
+
+	_, err = a.QueryEvent(BinlogQueryEvent{Query: "UPDAT users"})
+	as.NotNil(err)
+}
+
+func TestBinlogAdapter_RowsEvent(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	a := NewBinlogAdapter()
+
+	results, err := a.RowsEvent(BinlogRowsEvent{
+		Type: BinlogWriteRows, Schema: "shop", Table: "users", Columns: []string{"id", "name"},
+		Rows: [][]any{{int64(1), "a"}, {int64(2), "b"}},
+	})
+	as.Nil(err)
+	as.Equal(2, len(results))
+	as.Equal("INSERT INTO shop.users (id, name) VALUES (?, ?)", results[0].TemplatizedSQL)
+	as.Equal([]any{int64(2), "b"}, results[1].Params)
+	as.Equal(1, results[1].Index)
+	as.Equal(models.SQLOperationInsert, results[1].OpType)
+	as.Equal("users", results[1].TableInfos[0].TableName())
+
+	// before and after images
+	results, err = a.RowsEvent(BinlogRowsEvent{
+		Type: BinlogUpdateRows, Schema: "shop", Table: "users", Columns: []string{"id", "name"},
+		Rows: [][]any{{int64(1), "a"}, {int64(1), "b"}},
+	})
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.Equal("UPDATE shop.users SET id eq ?, name eq ? WHERE id eq ? and name eq ?", results[0].TemplatizedSQL)
+	as.Equal([]any{int64(1), "b", int64(1), "a"}, results[0].Params)
+
+	// without column metadata, sharded table
+	results, err = a.RowsEvent(BinlogRowsEvent{
+		Type: BinlogDeleteRows, Schema: "db_1", Table: "orders_12",
+		Rows: [][]any{{int64(9), nil}},
+	})
+	as.Nil(err)
+	as.Equal("DELETE FROM db_?.orders_? WHERE @1 eq ? and @2 eq ?", results[0].TemplatizedSQL)
+	as.Equal([]any{int64(9), nil}, results[0].Params)
+	as.Equal(models.SQLOperationDelete, results[0].OpType)
+
+	// cached
+	as.Equal(3, len(a.cache))
+}
@@ -0,0 +1,10 @@
+package main
+
+import sqlextractor "github.com/kydance/sql-extractor"
+
+// extractJSON extracts the SQL and encodes the result as a JSON envelope.
+// Errors are reported in the error field, so the caller always gets a JSON
+// document.
+func extractJSON(sql string) []byte {
+	return sqlextractor.ExtractEnvelope(sql).JSON()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+func TestExtractJSON(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var env sqlextractor.Envelope
+	as.Nil(json.Unmarshal(extractJSON("SELECT * FROM db_1.users WHERE id = 1; SHOW TABLES"), &env))
+	as.Equal(sqlextractor.SchemaVersion, env.SchemaVersion)
+	as.Equal(2, len(env.Statements))
+	as.Equal("SELECT * FROM db_?.users WHERE id eq ?", env.Statements[0].TemplatizedSQL)
+	as.Equal([]sqlextractor.EnvelopeTable{{Schema: "db_1", Table: "users"}}, env.Statements[0].Tables)
+	as.Equal([]any{float64(1)}, env.Statements[0].Params)
+	as.Equal("SHOW", env.Statements[1].OpType)
+
+	as.JSONEq(`{"schema_version":1,"dialect":"mysql","statements":[],"warnings":[],"error":"empty SQL statement"}`,
+		string(extractJSON("")))
+}
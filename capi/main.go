@@ -0,0 +1,32 @@
+// Command capi builds the extractor as a C shared library, so Python, Ruby and
+// other non-Go agents can call it in-process:
+//
+//	go build -buildmode=c-shared -o libsqlextractor.so ./capi
+//
+// The library exports:
+//
+//	char *extract_sql(const char *sql); // returns the result as JSON
+//	void extract_sql_free(char *result); // frees the result of extract_sql
+//
+// The JSON document is the versioned envelope described by
+// schema/envelope.v1.json.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+//export extract_sql
+func extract_sql(sql *C.char) *C.char {
+	return C.CString(string(extractJSON(C.GoString(sql))))
+}
+
+//export extract_sql_free
+func extract_sql_free(result *C.char) {
+	C.free(unsafe.Pointer(result))
+}
+
+func main() {}
@@ -0,0 +1,34 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+type (
+	// Clause is a clause of a SELECT statement.
+	Clause = models.Clause
+	// ClauseSegment is the segment of a template written for a clause.
+	ClauseSegment = models.ClauseSegment
+)
+
+const (
+	ClauseSelect  = models.ClauseSelect
+	ClauseFrom    = models.ClauseFrom
+	ClauseWhere   = models.ClauseWhere
+	ClauseGroupBy = models.ClauseGroupBy
+	ClauseHaving  = models.ClauseHaving
+	ClauseOrderBy = models.ClauseOrderBy
+	ClauseLimit   = models.ClauseLimit
+)
+
+// Clauses returns the template of each statement split into the clauses of
+// its outermost query block, keywords included, so that tools can display or
+// diff the individual clauses without parsing the templates. The statements
+// other than SELECT have no segment.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT a FROM t WHERE id = 1 ORDER BY a LIMIT 10")
+//	clauses, err := extractor.Clauses()
+//	// clauses[0]: [SELECT a] [FROM t] [WHERE id eq ?] [ORDER BY a] [LIMIT ?]
+func (e *Extractor) Clauses() ([][]*ClauseSegment, error) {
+	return e.internal().ExtractClauses(e.rawSQL)
+}
@@ -0,0 +1,47 @@
+package sqlextractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_Clauses(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT DISTINCT a, count(*) FROM t JOIN s ON t.id = s.tid " +
+		"WHERE t.b IN (SELECT b FROM u WHERE c > 1) GROUP BY a HAVING count(*) > 2 ORDER BY a DESC LIMIT 10; " +
+		"SELECT 1; UPDATE t SET a = 1")
+	as.Nil(extractor.Extract())
+
+	clauses, err := extractor.Clauses()
+	as.Nil(err)
+	as.Len(clauses, 3)
+
+	want := []struct {
+		clause Clause
+		text   string
+	}{
+		{ClauseSelect, "SELECT DISTINCT a, count(1)"},
+		{ClauseFrom, "FROM t INNER JOIN s ON t.id eq s.tid"},
+		{ClauseWhere, "WHERE t.b IN ((SELECT b FROM u WHERE c gt ?))"},
+		{ClauseGroupBy, "GROUP BY a"},
+		{ClauseHaving, "HAVING count(1) gt ?"},
+		{ClauseOrderBy, "ORDER BY a DESC"},
+		{ClauseLimit, "LIMIT ?"},
+	}
+	as.Len(clauses[0], len(want))
+	texts := make([]string, 0, len(want))
+	for idx, w := range want {
+		as.Equal(w.clause, clauses[0][idx].Clause())
+		as.Equal(w.text, clauses[0][idx].Text())
+		texts = append(texts, clauses[0][idx].Text())
+	}
+	as.Equal(extractor.TemplatizedSQL()[0], strings.Join(texts, " "))
+
+	as.Len(clauses[1], 1)
+	as.Equal(ClauseSelect, clauses[1][0].Clause())
+	as.Nil(clauses[2])
+}
@@ -0,0 +1,48 @@
+package sqlextractor
+
+import (
+	"fmt"
+
+	"github.com/kydance/sql-extractor/internal/cluster"
+)
+
+// ClusterTemplates groups similar statements beyond exact digest matching, so
+// workload summaries are not fragmented by trivial variations. Statements are
+// clustered together when they have the same operation type and tables, and
+// the Jaccard similarity of their predicate column sets is not less than
+// threshold, e.g. 0.6.
+//
+// It returns the cluster ID of each statement of sqls, in order. Each element
+// of sqls may contain multiple statements separated by semicolons.
+func ClusterTemplates(sqls []string, threshold float64) ([]string, error) {
+	features := make([]*cluster.Feature, 0, len(sqls))
+
+	for idx := range sqls {
+		_, tableInfos, _, opType, err := defaultExtractor.Extract(sqls[idx])
+		if err != nil {
+			return nil, fmt.Errorf("error processing sql %d: %w", idx+1, err)
+		}
+
+		predicates, err := defaultExtractor.ExtractPredicates(sqls[idx])
+		if err != nil {
+			return nil, fmt.Errorf("error processing sql %d: %w", idx+1, err)
+		}
+
+		for jdx := range opType {
+			tables := make([]string, 0, len(tableInfos[jdx]))
+			for _, ti := range tableInfos[jdx] {
+				name, _ := ti.TemplatizedTableNameWithSchema()
+				tables = append(tables, name)
+			}
+
+			columns := make([]string, 0, len(predicates[jdx]))
+			for _, p := range predicates[jdx] {
+				columns = append(columns, p.Column())
+			}
+
+			features = append(features, cluster.NewFeature(opType[jdx], tables, columns))
+		}
+	}
+
+	return cluster.Cluster(features, threshold), nil
+}
@@ -0,0 +1,26 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterTemplates(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	ids, err := ClusterTemplates([]string{
+		"SELECT * FROM users_01 WHERE id = 1 AND name = 'a' AND age > 1",
+		"SELECT * FROM users_02 WHERE name = 'b' AND id = 2 AND age > 2 AND status = 1; SELECT * FROM users_03 WHERE email = 'x'",
+		"DELETE FROM users_01 WHERE id = 1 AND name = 'a' AND age > 1",
+	}, 0.6)
+	as.Nil(err)
+	as.Equal(4, len(ids))
+	as.Equal(ids[0], ids[1])
+	as.NotEqual(ids[0], ids[2])
+	as.NotEqual(ids[0], ids[3])
+
+	_, err = ClusterTemplates([]string{"SELECT * FROM"}, 0.6)
+	as.NotNil(err)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// fileResult is the extraction result of an input file: the envelope of its
+// queries, see schema/envelope.v1.json.
+type fileResult struct {
+	File string `json:"file"`
+	sqlextractor.Envelope
+	Errors []queryError `json:"errors,omitempty"` // queries which can not be extracted
+}
+
+// queryError is the extraction error of a query, queries which can not be
+// extracted do not stop the processing of the file.
+type queryError struct {
+	Query string `json:"query"`
+	Error string `json:"error"`
+}
+
+// newFileResult creates an empty result of the file.
+func newFileResult(file string) *fileResult {
+	return &fileResult{File: file, Envelope: *sqlextractor.NewEnvelope()}
+}
+
+// processFiles processes the files with workers goroutines, the results are
+// in the order of files.
+func processFiles(files []string, workers int) []*fileResult {
+	results := make([]*fileResult, len(files))
+
+	var (
+		wg  sync.WaitGroup
+		idx = make(chan int)
+	)
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				results[i] = processFile(files[i])
+			}
+		}()
+	}
+
+	for i := range files {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+
+	return results
+}
+
+// processFile extracts the queries of a file, the error of the envelope is set
+// if the file can not be read.
+func processFile(file string) *fileResult {
+	res := newFileResult(file)
+
+	queries, err := readQueries(file)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	for _, query := range queries {
+		env := query.envelope()
+		if env.Error != "" {
+			res.Errors = append(res.Errors, queryError{Query: query.sql, Error: env.Error})
+			continue
+		}
+
+		res.Statements = append(res.Statements, env.Statements...)
+		res.Warnings = append(res.Warnings, env.Warnings...)
+	}
+
+	return res
+}
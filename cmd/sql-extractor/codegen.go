@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// query is a statement of the generated code.
+type query struct {
+	name     string // Go name of the statement, e.g. GetUser
+	file     string
+	template string // executable template with ? placeholders
+	rows     bool   // the statement returns rows, e.g. SELECT
+	fields   []param
+}
+
+// param is a field of the params struct of a query.
+type param struct {
+	name  string // Go name of the field, e.g. UserID
+	param string // name of the param, e.g. user_id
+	typ   string // Go type of the field, from the captured value
+}
+
+// codegen returns the Go source of package pkg with, for each statement of the
+// files, the executable template as a constant, the struct of its params, and
+// the function running it. The statements are named after their files, e.g.
+// get_user.sql -> GetUser, GetUser2, ... for the next statements, and the
+// param types are those of the captured values.
+func codegen(pkg string, files []string) ([]byte, error) {
+	var (
+		queries []query
+		names   = make(map[string]int)
+	)
+	for _, file := range files {
+		logged, err := readQueries(file)
+		if err != nil {
+			return nil, err
+		}
+
+		base := goName(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		for _, lq := range logged {
+			extracted, err := codegenQueries(lq.sql)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+
+			for _, q := range extracted {
+				q.name, q.file = base, file
+				if n := names[base]; n > 0 { // 同名文件或多条语句，追加序号
+					q.name += strconv.Itoa(n + 1)
+				}
+				names[base]++
+				queries = append(queries, q)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	writeCode(&b, pkg, queries)
+
+	return format.Source(b.Bytes())
+}
+
+// codegenQueries returns the queries of the statements of sql, without name.
+func codegenQueries(sql string) ([]query, error) {
+	extractor := sqlextractor.NewExtractor(sql, sqlextractor.WithPreset(sqlextractor.PresetExecutable))
+	if err := extractor.Extract(); err != nil {
+		return nil, err
+	}
+
+	names, err := extractor.ParamNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		templates = extractor.TemplatizedSQL()
+		params    = extractor.Params()
+		ops       = extractor.OpType()
+		queries   = make([]query, 0, len(templates))
+	)
+	for idx := range templates {
+		if len(names[idx]) != len(params[idx]) {
+			return nil, fmt.Errorf("statement %d: %d param names for %d params", idx+1, len(names[idx]), len(params[idx]))
+		}
+
+		op := ops[idx].String()
+		q := query{template: templates[idx], rows: op == "SELECT" || op == "SHOW"}
+		for jdx, name := range names[idx] {
+			q.fields = append(q.fields, param{name: goName(name), param: name, typ: goType(params[idx][jdx])})
+		}
+		queries = append(queries, q)
+	}
+
+	return queries, nil
+}
+
+// writeCode writes the unformatted Go source of the queries.
+func writeCode(b *bytes.Buffer, pkg string, queries []query) {
+	fmt.Fprintf(b, "// Code generated by sql-extractor -codegen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	b.WriteString("import (\n\"context\"\n\"database/sql\"\n)\n\n")
+	b.WriteString("// DBTX is implemented by *sql.DB, *sql.Conn and *sql.Tx.\n")
+	b.WriteString("type DBTX interface {\n")
+	b.WriteString("ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)\n")
+	b.WriteString("QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)\n}\n")
+
+	for _, q := range queries {
+		fmt.Fprintf(b, "\n// %s is the template of %s.\nconst %s = %s\n", q.name, filepath.ToSlash(q.file), q.name,
+			strconv.Quote(q.template))
+
+		args := ""
+		if len(q.fields) > 0 {
+			fmt.Fprintf(b, "\n// %sParams are the params of %s.\ntype %sParams struct {\n", q.name, q.name, q.name)
+			for _, f := range q.fields {
+				fmt.Fprintf(b, "%s %s `db:%q`\n", f.name, f.typ, f.param)
+			}
+			b.WriteString("}\n")
+
+			fmt.Fprintf(b, "\n// Args returns the args of %s in binding order.\nfunc (p %sParams) Args() []any {\n", q.name, q.name)
+			b.WriteString("return []any{")
+			for idx, f := range q.fields {
+				if idx > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString("p." + f.name)
+			}
+			b.WriteString("}\n}\n")
+
+			args = ", p.Args()..."
+		}
+
+		signature := "ctx context.Context, db DBTX"
+		if len(q.fields) > 0 {
+			signature += ", p " + q.name + "Params"
+		}
+		if q.rows {
+			fmt.Fprintf(b, "\n// Query%s runs %s and returns its rows.\n", q.name, q.name)
+			fmt.Fprintf(b, "func Query%s(%s) (*sql.Rows, error) {\nreturn db.QueryContext(ctx, %s%s)\n}\n",
+				q.name, signature, q.name, args)
+		} else {
+			fmt.Fprintf(b, "\n// Exec%s runs %s.\n", q.name, q.name)
+			fmt.Fprintf(b, "func Exec%s(%s) (sql.Result, error) {\nreturn db.ExecContext(ctx, %s%s)\n}\n",
+				q.name, signature, q.name, args)
+		}
+	}
+}
+
+// goName returns the exported Go name of a snake case name, e.g. user_id ->
+// UserID, get-orders -> GetOrders.
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if strings.EqualFold(word, "id") {
+			b.WriteString("ID")
+			continue
+		}
+
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	if b.Len() == 0 || !unicode.IsLetter([]rune(b.String())[0]) {
+		return "Q" + b.String()
+	}
+
+	return b.String()
+}
+
+// goType returns the Go type of a captured param value, any if unknown.
+func goType(v any) string {
+	switch v.(type) {
+	case int64, uint64, float64, string, bool:
+		return reflect.TypeOf(v).String()
+	case fmt.Stringer: // e.g. DECIMAL
+		if reflect.TypeOf(v).Kind() != reflect.Slice {
+			return "string"
+		}
+	}
+
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return "[]byte" // e.g. x'01'
+	}
+
+	return "any"
+}
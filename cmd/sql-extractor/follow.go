@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// followIdleFlush is how long a follow waits for more lines before the query
+// being parsed is considered complete, general log entries have no terminator.
+const followIdleFlush = time.Second
+
+// follow tails the growing log file at path, like tail -F, and writes the
+// envelope of each new query allowed by the sampler, with the tags, to w as
+// JSON lines until ctx is done.
+//
+// It starts at the end of the file, and reopens it from the start when it is
+// rotated (recreated) or truncated.
+func follow(ctx context.Context, path string, sampler *sqlextractor.Sampler, tags sqlextractor.Tags, w io.Writer) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// 监听目录而不是文件，才能感知日志轮转
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	t := &tailer{path: path, sampler: sampler, tags: tags, enc: json.NewEncoder(w)}
+	if err := t.open(io.SeekEnd); err != nil {
+		return err
+	}
+	defer t.close()
+
+	idle := time.NewTicker(followIdleFlush)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.parser.flush()
+			return t.emit()
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) != filepath.Clean(path) {
+				continue
+			}
+
+			switch {
+			case ev.Has(fsnotify.Create):
+				// 日志轮转：读完旧文件剩余的内容后，从头读取新文件
+				if err := t.read(); err != nil {
+					return err
+				}
+				if err := t.open(io.SeekStart); err != nil {
+					return err
+				}
+				err = t.read()
+			case ev.Has(fsnotify.Write):
+				err = t.read()
+			}
+			if err != nil {
+				return err
+			}
+			idle.Reset(followIdleFlush)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+
+		case <-idle.C:
+			t.parser.flush()
+			if err := t.emit(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// tailer reads the lines appended to a file.
+type tailer struct {
+	path    string
+	f       *os.File
+	r       *bufio.Reader
+	offset  int64  // offset of the next byte to read
+	partial string // last line, not terminated yet
+	parser  logParser
+	sampler *sqlextractor.Sampler
+	tags    sqlextractor.Tags
+	enc     *json.Encoder
+}
+
+// open (re)opens the file at the start or the end.
+func (t *tailer) open(whence int) error {
+	t.close()
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+
+	if t.offset, err = f.Seek(0, whence); err != nil {
+		f.Close()
+		return err
+	}
+
+	t.f, t.r, t.partial = f, bufio.NewReader(f), ""
+
+	return nil
+}
+
+func (t *tailer) close() {
+	if t.f != nil {
+		t.f.Close()
+		t.f = nil
+	}
+}
+
+// read parses the lines appended since the last read, and emits the queries.
+func (t *tailer) read() error {
+	if t.f == nil {
+		return nil
+	}
+
+	// 文件被截断，从头读取
+	if info, err := t.f.Stat(); err == nil && info.Size() < t.offset {
+		if err := t.open(io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	for {
+		line, err := t.r.ReadString('\n')
+		t.offset += int64(len(line))
+		if err != nil {
+			t.partial += line
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+
+		t.parser.line(trimNewline(t.partial + line))
+		t.partial = ""
+	}
+
+	return t.emit()
+}
+
+// emit writes the envelopes of the parsed queries.
+func (t *tailer) emit() error {
+	for _, query := range t.parser.take() {
+		if !t.sampler.Allow() {
+			continue
+		}
+
+		env := query.envelope()
+		env.Tags = query.sessionTags(t.tags)
+		if err := t.enc.Encode(env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func trimNewline(line string) string {
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+
+	return line
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.Nil(t, err)
+	_, err = f.WriteString(content)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+}
+
+func TestFollow(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "slow.log")
+	as.Nil(os.WriteFile(path, []byte("SELECT * FROM old WHERE id = 1;\n"), 0o644))
+
+	var (
+		out         syncBuffer
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan error)
+	)
+	go func() { done <- follow(ctx, path, sqlextractor.NewSampler(), sqlextractor.Tags{"host": "db-1"}, &out) }()
+
+	// existing content is skipped, a query may be written in several chunks
+	waitFor := func(n int) {
+		as.Eventually(func() bool { return len(out.lines()) == n }, 5*time.Second, 10*time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	appendFile(t, path, "# Query_time: 1.5\nSELECT * FROM users\nWHERE id ")
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, "= 1;\nSELEC x;\n")
+	waitFor(2)
+	as.Contains(out.lines()[0], `"templatized_sql":"SELECT * FROM users WHERE id eq ?"`)
+	as.Contains(out.lines()[0], `"tags":{"host":"db-1"}`)
+	as.Contains(out.lines()[1], `"error"`)
+
+	// truncated
+	as.Nil(os.WriteFile(path, []byte("DELETE FROM a WHERE id = 2;\n"), 0o644))
+	waitFor(3)
+	as.Contains(out.lines()[2], `"templatized_sql":"DELETE FROM a WHERE id eq ?"`)
+
+	// rotated
+	as.Nil(os.Rename(path, path+".1"))
+	as.Nil(os.WriteFile(path, []byte("UPDATE b SET c = 3;\n"), 0o644))
+	waitFor(4)
+	as.Contains(out.lines()[3], `"op_type":"UPDATE"`)
+
+	cancel()
+	as.Nil(<-done)
+}
+
+func TestFollow_Sampled(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "slow.log")
+	as.Nil(os.WriteFile(path, nil, 0o644))
+
+	var (
+		out         syncBuffer
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan error)
+		sampler     = sqlextractor.NewSampler(sqlextractor.WithRateLimit(0.001, 2))
+	)
+	go func() { done <- follow(ctx, path, sampler, nil, &out) }()
+
+	time.Sleep(100 * time.Millisecond)
+	appendFile(t, path, "SELECT 1;\nSELECT 2;\nSELECT 3;\nSELECT 4;\n")
+	as.Eventually(func() bool { return len(out.lines()) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	as.Nil(<-done)
+	as.Equal(2, len(out.lines())) // burst of 2, then limited
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// inputExts are the extensions of the files processed in directories.
+var inputExts = []string{".sql", ".log"}
+
+// expandPaths expands directories (recursively) and globs to the list of
+// input files, sorted and without duplicates.
+func expandPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			matches = []string{path} // reported as not found when it is read
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				files = append(files, match)
+				continue
+			}
+
+			err = filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && slices.Contains(inputExts, strings.ToLower(filepath.Ext(p))) {
+					files = append(files, p)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	slices.Sort(files)
+
+	return slices.Compact(files), nil
+}
+
+// logQuery is a query of an input file, with the session of its connection
+// when it is read from a log.
+type logQuery struct {
+	sql      string
+	database string // current database of the connection, e.g. USE shop
+	charset  string // character set of the connection, e.g. SET NAMES utf8mb4
+	timeZone string // time zone of the connection, e.g. SET time_zone = '+08:00'
+}
+
+// middleware returns the middleware applying the session of the query: the
+// current database to the unqualified tables, and the character set to the
+// string params.
+func (q logQuery) middleware() []sqlextractor.Middleware {
+	var mw []sqlextractor.Middleware
+	if q.database != "" {
+		mw = append(mw, sqlextractor.DefaultSchema(q.database))
+	}
+	if q.charset != "" {
+		mw = append(mw, sqlextractor.ConnectionCharset(q.charset))
+	}
+
+	return mw
+}
+
+// envelope extracts the query with its session, the extraction error is
+// reported in the Error field.
+func (q logQuery) envelope() *sqlextractor.Envelope {
+	e := sqlextractor.NewExtractor(q.sql)
+	e.Use(q.middleware()...)
+	if err := e.Extract(); err != nil {
+		env := e.Envelope()
+		env.Error = err.Error()
+
+		return env
+	}
+
+	env := e.Envelope()
+	if q.timeZone == "" {
+		return env
+	}
+
+	// 会话时区已知时，DATE、TIMESTAMP 字面量的参数按该时区解析为时间，
+	// 其它参数保持 Envelope 的 JSON 表示
+	loc, err := sqlextractor.ParseTimeZone(q.timeZone)
+	if err == nil {
+		var params [][]any
+		if params, err = e.TemporalParams(loc); err == nil {
+			for idx := range env.Statements {
+				for jdx, param := range params[idx] {
+					if t, ok := param.(time.Time); ok {
+						env.Statements[idx].Params[jdx] = t
+					}
+				}
+			}
+		}
+	}
+	if err != nil {
+		env.Warnings = append(env.Warnings, fmt.Sprintf("time zone %s: %v", q.timeZone, err))
+	}
+
+	return env
+}
+
+// sessionTags returns the tags with the session of the query, tags if the
+// session is unknown.
+func (q logQuery) sessionTags(tags sqlextractor.Tags) sqlextractor.Tags {
+	if q.database == "" && q.charset == "" && q.timeZone == "" {
+		return tags
+	}
+
+	merged := make(sqlextractor.Tags, len(tags)+3)
+	for k, v := range tags {
+		merged[k] = v
+	}
+	if q.database != "" {
+		merged["database"] = q.database
+	}
+	if q.charset != "" {
+		merged["charset"] = q.charset
+	}
+	if q.timeZone != "" {
+		merged["time_zone"] = q.timeZone
+	}
+
+	return merged
+}
+
+// readQueries reads the queries of a file: .log files are parsed as MySQL slow
+// or general logs, other files are read as a whole.
+func readQueries(path string) ([]logQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".log") {
+		return parseLog(f)
+	}
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(string(b)) == "" {
+		return nil, nil
+	}
+
+	return []logQuery{{sql: string(b)}}, nil
+}
+
+var (
+	// generalLogLine matches a general log entry, e.g.
+	// "2024-05-01T10:00:00.123456Z\t   42 Query\tSELECT 1"
+	generalLogLine = regexp.MustCompile(`^\S*\t\s*(\d+) ([A-Za-z ]+)\t(.*)$`)
+
+	// slowLogUser matches the user line of a slow log entry, with the
+	// connection id, e.g. "# User@Host: app[app] @ localhost []  Id:    42"
+	slowLogUser = regexp.MustCompile(`^# User@Host: .*\sId:\s*(\d+)`)
+
+	// logNoise matches the log lines which are not part of a query.
+	logNoise = regexp.MustCompile(`(?i)^(#|SET timestamp=|use \S+;$|\S+, Version: |Tcp port: |Time\s+Id\s+Command)`)
+
+	// useDatabase, setNames and setTimeZone match the queries changing the
+	// session.
+	useDatabase = regexp.MustCompile("(?i)^use\\s+`?([^`;\\s]+)`?\\s*;?$")
+	setNames    = regexp.MustCompile(`(?i)^set\s+names\s+['"]?(\w+)`)
+	setTimeZone = regexp.MustCompile(`(?i)^set\s+(?:session\s+|@@(?:session\.)?)?time_zone\s*=\s*['"]?([^'";\s]+)`)
+
+	// connectDatabase matches the database of a general log Connect entry,
+	// e.g. "app@localhost on shop using TCP/IP".
+	connectDatabase = regexp.MustCompile(`\son (\S+) using `)
+)
+
+// parseLog parses the queries of a MySQL slow log or general log. Queries may
+// span multiple lines, slow log queries end with a semicolon.
+func parseLog(r io.Reader) ([]logQuery, error) {
+	var p logParser
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for scanner.Scan() {
+		p.line(scanner.Text())
+	}
+	p.flush()
+
+	return p.take(), scanner.Err()
+}
+
+// session is the state of a connection which applies to its next queries.
+type session struct {
+	database string
+	charset  string
+	timeZone string
+}
+
+// logParser parses the queries of a log line by line, and tracks the session
+// of each connection: USE, Init DB, SET NAMES and SET time_zone apply to the next queries of
+// the same connection.
+type logParser struct {
+	query    strings.Builder     // query being parsed
+	conn     string              // connection of the entry being parsed
+	sessions map[string]*session // connection id -> session
+	queries  []logQuery          // parsed queries, not taken yet
+}
+
+// line parses a log line, without the trailing newline.
+func (p *logParser) line(line string) {
+	if m := generalLogLine.FindStringSubmatch(line); m != nil {
+		p.flush()
+		p.conn = m[1]
+
+		switch m[2] {
+		case "Query", "Execute":
+			p.query.WriteString(m[3])
+		case "Connect":
+			if db := connectDatabase.FindStringSubmatch(m[3]); db != nil {
+				p.session().database = db[1]
+			}
+		case "Init DB":
+			p.session().database = strings.TrimSpace(m[3])
+		case "Quit":
+			delete(p.sessions, p.conn)
+		}
+
+		return
+	}
+
+	if logNoise.MatchString(line) {
+		p.flush()
+
+		if m := slowLogUser.FindStringSubmatch(line); m != nil {
+			p.conn = m[1]
+		} else if m := useDatabase.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			p.session().database = m[1]
+		}
+
+		return
+	}
+
+	if p.query.Len() > 0 {
+		p.query.WriteByte('\n')
+	}
+	p.query.WriteString(line)
+
+	if strings.HasSuffix(strings.TrimSpace(line), ";") {
+		p.flush()
+	}
+}
+
+// session returns the session of the current connection.
+func (p *logParser) session() *session {
+	if p.sessions == nil {
+		p.sessions = make(map[string]*session)
+	}
+
+	s, ok := p.sessions[p.conn]
+	if !ok {
+		s = &session{}
+		p.sessions[p.conn] = s
+	}
+
+	return s
+}
+
+// flush ends the query being parsed.
+func (p *logParser) flush() {
+	q := strings.TrimSpace(p.query.String())
+	p.query.Reset()
+	if q == "" {
+		return
+	}
+
+	s := p.session()
+	p.queries = append(p.queries, logQuery{sql: q, database: s.database, charset: s.charset, timeZone: s.timeZone})
+
+	// 会话语句作用于同一连接的后续语句
+	if m := useDatabase.FindStringSubmatch(q); m != nil {
+		s.database = m[1]
+	} else if m := setNames.FindStringSubmatch(q); m != nil {
+		s.charset = strings.ToLower(m[1])
+	} else if m := setTimeZone.FindStringSubmatch(q); m != nil {
+		s.timeZone = m[1]
+	}
+}
+
+// take returns and removes the parsed queries.
+func (p *logParser) take() []logQuery {
+	queries := p.queries
+	p.queries = nil
+
+	return queries
+}
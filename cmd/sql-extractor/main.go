@@ -0,0 +1,371 @@
+// Command sql-extractor templatizes SQL files and MySQL slow/general logs, and
+// writes the results as JSON.
+//
+// Usage:
+//
+//	sql-extractor [flags] [file|dir|glob ...]
+//
+// Directories are walked recursively for .sql and .log files. Without
+// arguments, the SQL is read from stdin. The session of each connection of the
+// logs is tracked: the current database (USE, Init DB) is the schema of the
+// unqualified tables of its next queries.
+//
+// The results are JSON envelopes (see schema/envelope.v1.json) with the input
+// file name, and the tags given by -tag. With -follow, a single growing slow log or general log is tailed,
+// and the envelope of each new query is streamed as a JSON line to stdout, or
+// appended to the -o file, until interrupted.
+//
+// With -table-report, the queries of all the inputs are aggregated instead, and
+// the access pattern of each table is written as JSON: the read/write ratio,
+// the most common predicates, the WHERE and ORDER BY columns and the digests.
+//
+// With -template-diff, the templates of the inputs are compared with those of
+// a baseline corpus, e.g. the integration test logs of the previous release,
+// and the added, removed and changed templates are written as JSON.
+//
+// With -codegen, a Go file of the given package is generated from the SQL
+// files instead, a lightweight sqlc: for each statement, the executable
+// template as a constant, the struct of its params typed after the captured
+// values, and the function running it on a *sql.DB or *sql.Tx. Statements are
+// named after their files, e.g. get_user.sql -> GetUser.
+//
+// With -serve, it runs as an HTTP service instead: POST /extract returns the
+// envelope of the SQL in the request body, ?fields=digest,tables selects the
+// fields of its statements, and GET /metrics exposes request
+// counts, parse errors, templatization latency and cache hit ratio in the
+// OpenMetrics text format. /healthz and /readyz are the liveness and readiness
+// probes; on SIGTERM, /readyz fails, requests are still served for -drain-delay,
+// then in-flight requests are drained within -shutdown-timeout.
+//
+// Examples:
+//
+//	sql-extractor -workers 8 -out results/ /var/log/mysql/ 'dumps/*.sql'
+//	echo "SELECT * FROM users WHERE id = 1" | sql-extractor
+//	sql-extractor -follow -sample-rate 0.1 -rate-limit 1000 /var/log/mysql/slow.log
+//	sql-extractor -table-report /var/log/mysql/slow.log
+//	sql-extractor -codegen queries -o queries/queries.go sql/
+//	sql-extractor -template-diff logs/v1.4/ logs/v1.5/
+//	sql-extractor -tag service=billing -tag host=db-1 /var/log/mysql/slow.log
+//	sql-extractor -serve :8080 -cache-size 10000
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sql-extractor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		workers = flags.Int("workers", runtime.NumCPU(), "number of files processed in parallel")
+		outDir  = flags.String("out", "", "write a JSON result per file into `dir`, instead of merged results")
+		output  = flags.String("o", "", "write the merged JSON results to `file` instead of stdout")
+		follow  = flags.Bool("follow", false, "tail a growing log file and stream JSON lines")
+		report  = flags.Bool("table-report", false, "write the access pattern of each table of all the queries instead of the results")
+		pkg     = flags.String("codegen", "", "generate Go code of package `pkg` from the SQL files instead of the results")
+		base    = flags.String("template-diff", "", "write the template differences of the inputs with the `baseline` corpus instead of the results")
+		tags    = tagsFlag{}
+
+		serveAddr = flags.String("serve", "", "serve the extraction over HTTP on `addr`, e.g. :8080")
+		cacheSize = flags.Int("cache-size", 10000, "number of results cached by SQL in server mode, 0 disables the cache")
+
+		drainDelay      = flags.Duration("drain-delay", 5*time.Second, "how long the server keeps serving after /readyz fails on shutdown")
+		shutdownTimeout = flags.Duration("shutdown-timeout", 30*time.Second, "how long in-flight requests are waited for on shutdown")
+
+		sampleRate = flags.Float64("sample-rate", 1, "fraction of the queries extracted in follow mode")
+		rateLimit  = flags.Float64("rate-limit", 0, "max queries extracted per second in follow mode, 0 means unlimited")
+	)
+	flags.Var(tags, "tag", "attach `key=value` metadata to the results, can be repeated")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: sql-extractor [flags] [file|dir|glob ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *serveAddr != "" {
+		ln, err := net.Listen("tcp", *serveAddr)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		fmt.Fprintln(stderr, "serving on", ln.Addr())
+		opts := shutdownOptions{drainDelay: *drainDelay, timeout: *shutdownTimeout}
+		if err := serve(ctx, ln, newServer(*cacheSize), opts); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	if *follow {
+		if flags.NArg() != 1 {
+			fmt.Fprintln(stderr, "-follow requires a single log file")
+			return 2
+		}
+
+		sampler := sqlextractor.NewSampler(
+			sqlextractor.WithSampleRate(*sampleRate),
+			sqlextractor.WithRateLimit(*rateLimit, max(int(*rateLimit), 1)),
+		)
+		if err := runFollow(ctx, flags.Arg(0), sampler, sqlextractor.Tags(tags), *output, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	if *report {
+		if err := runTableReport(flags.Args(), stdin, sqlextractor.Tags(tags), *output, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	if *base != "" {
+		if err := runTemplateDiff(*base, flags.Args(), *output, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	if *pkg != "" {
+		if err := runCodegen(*pkg, flags.Args(), *output, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	var results []*fileResult
+	if flags.NArg() == 0 {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		results = []*fileResult{{File: "-", Envelope: *sqlextractor.ExtractEnvelope(string(b))}}
+	} else {
+		files, err := expandPaths(flags.Args())
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		results = processFiles(files, *workers)
+	}
+
+	if len(tags) > 0 {
+		for _, res := range results {
+			res.Tags = sqlextractor.Tags(tags)
+		}
+	}
+
+	if err := writeResults(results, *outDir, *output, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// runFollow follows the log file, and writes the JSON lines to output (stdout
+// if empty).
+func runFollow(ctx context.Context, path string, sampler *sqlextractor.Sampler, tags sqlextractor.Tags,
+	output string, stdout io.Writer,
+) error {
+	if output == "" {
+		return follow(ctx, path, sampler, tags, stdout)
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return follow(ctx, path, sampler, tags, f)
+}
+
+// runTableReport writes the table report of the inputs to output (stdout if
+// empty).
+func runTableReport(paths []string, stdin io.Reader, tags sqlextractor.Tags, output string, stdout io.Writer) error {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	report, err := tableReport(files, stdin, tags)
+	if err != nil {
+		return err
+	}
+
+	if output != "" {
+		return writeJSON(output, report)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(report)
+}
+
+// runTemplateDiff writes the template differences of the inputs with the
+// baseline corpus to output (stdout if empty).
+func runTemplateDiff(base string, paths []string, output string, stdout io.Writer) error {
+	if len(paths) == 0 {
+		return errors.New("-template-diff requires the files of the new corpus")
+	}
+
+	oldFiles, err := expandPaths([]string{base})
+	if err != nil {
+		return err
+	}
+
+	newFiles, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	diff, err := templateDiff(oldFiles, newFiles)
+	if err != nil {
+		return err
+	}
+
+	if output != "" {
+		return writeJSON(output, diff)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(diff)
+}
+
+// runCodegen writes the Go code generated from the files to output (stdout if
+// empty).
+func runCodegen(pkg string, paths []string, output string, stdout io.Writer) error {
+	if len(paths) == 0 {
+		return errors.New("-codegen requires SQL files")
+	}
+
+	files, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	code, err := codegen(pkg, files)
+	if err != nil {
+		return err
+	}
+
+	if output != "" {
+		return os.WriteFile(output, code, 0o644)
+	}
+
+	_, err = stdout.Write(code)
+
+	return err
+}
+
+// writeResults writes a JSON file per result into outDir, or the merged
+// results to output (stdout if empty).
+func writeResults(results []*fileResult, outDir, output string, stdout io.Writer) error {
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return err
+		}
+
+		for _, res := range results {
+			if err := writeJSON(filepath.Join(outDir, resultName(res.File)), res); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if output != "" {
+		return writeJSON(output, results)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(results)
+}
+
+// resultName returns the name of the result file of an input file, the path
+// separators are replaced so files of different directories do not collide.
+func resultName(file string) string {
+	name := filepath.ToSlash(filepath.Clean(file))
+	name = strings.TrimLeft(strings.ReplaceAll(name, "../", ""), "/")
+
+	return strings.ReplaceAll(name, "/", "_") + ".json"
+}
+
+func writeJSON(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// tagsFlag collects the repeated -tag key=value flags.
+type tagsFlag map[string]string
+
+func (f tagsFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (f tagsFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("invalid tag %q, want key=value", s)
+	}
+	f[k] = v
+
+	return nil
+}
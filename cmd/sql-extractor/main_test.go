@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+const slowLog = `/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:
+Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock
+Time                 Id Command    Argument
+# Time: 2024-05-01T10:00:00.123456Z
+# User@Host: app[app] @ localhost []  Id:    42
+# Query_time: 1.500000  Lock_time: 0.000010 Rows_sent: 1  Rows_examined: 100000
+use shop;
+SET timestamp=1714557600;
+SELECT *
+FROM orders
+WHERE user_id = 7;
+# Time: 2024-05-01T10:00:01.123456Z
+# Query_time: 2.000000  Lock_time: 0.000010 Rows_sent: 0  Rows_examined: 5
+SET timestamp=1714557601;
+DELETE FROM orders WHERE id = 9;
+`
+
+const generalLog = "2024-05-01T10:00:00.123456Z\t   42 Connect\tapp@localhost on shop using TCP/IP\n" +
+	"2024-05-01T10:00:00.223456Z\t   42 Query\tSELECT * FROM users\n" +
+	"WHERE id = 1\n" +
+	"2024-05-01T10:00:00.323456Z\t   42 Query\tUPDATE users SET name = 'a' WHERE id = 2\n" +
+	"2024-05-01T10:00:00.423456Z\t   42 Quit\t\n"
+
+func TestParseLog(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	queries, err := parseLog(strings.NewReader(slowLog))
+	as.Nil(err)
+	as.Equal([]logQuery{
+		{sql: "SELECT *\nFROM orders\nWHERE user_id = 7;", database: "shop"},
+		{sql: "DELETE FROM orders WHERE id = 9;", database: "shop"},
+	}, queries)
+
+	queries, err = parseLog(strings.NewReader(generalLog))
+	as.Nil(err)
+	as.Equal([]logQuery{
+		{sql: "SELECT * FROM users\nWHERE id = 1", database: "shop"},
+		{sql: "UPDATE users SET name = 'a' WHERE id = 2", database: "shop"},
+	}, queries)
+}
+
+func TestParseLog_Sessions(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	log := "2024-05-01T10:00:00.1Z\t    7 Connect\tapp@localhost on  using TCP/IP\n" +
+		"2024-05-01T10:00:00.2Z\t    8 Connect\tapp@localhost on shop using TCP/IP\n" +
+		"2024-05-01T10:00:00.3Z\t    7 Init DB\tbilling\n" +
+		"2024-05-01T10:00:00.4Z\t    7 Query\tSET NAMES utf8mb4\n" +
+		"2024-05-01T10:00:00.5Z\t    8 Query\tSELECT * FROM orders\n" +
+		"2024-05-01T10:00:00.6Z\t    7 Query\tSELECT * FROM invoices\n" +
+		"2024-05-01T10:00:00.7Z\t    8 Query\tUSE `archive`\n" +
+		"2024-05-01T10:00:00.8Z\t    8 Query\tSELECT * FROM orders\n" +
+		"2024-05-01T10:00:00.9Z\t    8 Quit\t\n"
+
+	queries, err := parseLog(strings.NewReader(log))
+	as.Nil(err)
+	as.Equal([]logQuery{
+		{sql: "SET NAMES utf8mb4", database: "billing"},
+		{sql: "SELECT * FROM orders", database: "shop"},
+		{sql: "SELECT * FROM invoices", database: "billing", charset: "utf8mb4"},
+		{sql: "USE `archive`", database: "shop"},
+		{sql: "SELECT * FROM orders", database: "archive"},
+	}, queries)
+
+	env := queries[4].envelope()
+	as.Empty(env.Error)
+	as.Equal("archive.orders", env.Statements[0].Tables[0].String())
+	as.Equal(sqlextractor.Tags{"database": "billing", "charset": "utf8mb4", "env": "prod"},
+		queries[2].sessionTags(sqlextractor.Tags{"env": "prod"}))
+}
+
+func TestProcessFile_Sessions(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 批处理模式与 follow 模式一样应用连接的数据库和字符集
+	path := filepath.Join(t.TempDir(), "general.log")
+	log := "2024-05-01T10:00:00.1Z\t    7 Init DB\tshop\n" +
+		"2024-05-01T10:00:00.2Z\t    7 Query\tSET NAMES gbk\n" +
+		"2024-05-01T10:00:00.3Z\t    7 Query\tSELECT * FROM users WHERE name = '\xd6\xd0\xce\xc4'\n" +
+		"2024-05-01T10:00:00.4Z\t    8 Query\tSELECT * FROM users WHERE name = 'kyden'\n"
+	as.Nil(os.WriteFile(path, []byte(log), 0o644))
+
+	res := processFile(path)
+	as.Empty(res.Error)
+	as.Empty(res.Errors)
+	as.Len(res.Statements, 3)
+	as.Equal("shop.users", res.Statements[1].Tables[0].String())
+	as.Equal([]any{"中文"}, res.Statements[1].Params)
+	as.Equal("users", res.Statements[2].Tables[0].String())
+	as.Equal([]any{"kyden"}, res.Statements[2].Params)
+}
+
+func TestParseLog_TimeZone(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	log := "2024-05-01T10:00:00.1Z\t    7 Query\tSET time_zone = '+08:00'\n" +
+		"2024-05-01T10:00:00.2Z\t    7 Query\tSELECT * FROM orders WHERE created_at > TIMESTAMP '2024-05-01 08:00:00'\n" +
+		"2024-05-01T10:00:00.3Z\t    8 Query\tSET @@session.time_zone = 'Mars/Olympus'\n" +
+		"2024-05-01T10:00:00.4Z\t    8 Query\tSELECT * FROM orders WHERE day = DATE '2024-05-01'\n"
+
+	queries, err := parseLog(strings.NewReader(log))
+	as.Nil(err)
+	as.Equal("+08:00", queries[1].timeZone)
+	as.Equal(sqlextractor.Tags{"time_zone": "+08:00"}, queries[1].sessionTags(nil))
+
+	env := queries[1].envelope()
+	as.Empty(env.Warnings)
+	as.Equal("2024-05-01T00:00:00Z", env.Statements[0].Params[0].(time.Time).UTC().Format(time.RFC3339))
+
+	env = queries[3].envelope()
+	as.Equal("2024-05-01", env.Statements[0].Params[0], "the params are kept for an unknown time zone")
+	as.Len(env.Warnings, 1)
+}
+
+// writeInputs writes the input files into a temporary directory.
+func writeInputs(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.sql":           "SELECT * FROM users WHERE id = 1; SELEC x",
+		"logs/slow.log":   slowLog,
+		"logs/readme.txt": "not an input",
+		"z.sql":           "INSERT INTO t (a) VALUES (1)",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	return dir
+}
+
+func TestExpandPaths(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	dir := writeInputs(t)
+
+	files, err := expandPaths([]string{dir, filepath.Join(dir, "*.sql")})
+	as.Nil(err)
+	as.Equal([]string{
+		filepath.Join(dir, "a.sql"),
+		filepath.Join(dir, "logs", "slow.log"),
+		filepath.Join(dir, "z.sql"),
+	}, files)
+
+	files, err = expandPaths([]string{filepath.Join(dir, "missing.sql")})
+	as.Nil(err)
+	as.Equal([]string{filepath.Join(dir, "missing.sql")}, files)
+}
+
+func TestRun_Merged(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	dir := writeInputs(t)
+
+	var stdout, stderr bytes.Buffer
+	as.Equal(0, run(context.Background(), []string{"-workers", "3", dir, filepath.Join(dir, "missing.sql")}, nil, &stdout, &stderr))
+	as.Empty(stderr.String())
+
+	var results []*fileResult
+	as.Nil(json.Unmarshal(stdout.Bytes(), &results))
+	as.Equal(4, len(results))
+
+	// a.sql fails as a whole, since it is a single query
+	as.Equal(filepath.Join(dir, "a.sql"), results[0].File)
+	as.Empty(results[0].Statements)
+	as.Equal(1, len(results[0].Errors))
+
+	as.Equal(filepath.Join(dir, "logs", "slow.log"), results[1].File)
+	as.Equal(sqlextractor.SchemaVersion, results[1].SchemaVersion)
+	as.Equal(2, len(results[1].Statements))
+	as.Equal("SELECT * FROM orders WHERE user_id eq ?", results[1].Statements[0].TemplatizedSQL)
+	as.Equal([]any{float64(7)}, results[1].Statements[0].Params)
+	as.Equal("DELETE", results[1].Statements[1].OpType)
+
+	as.Equal(filepath.Join(dir, "missing.sql"), results[2].File)
+	as.NotEmpty(results[2].Error)
+
+	as.Equal("INSERT", results[3].Statements[0].OpType)
+}
+
+func TestRun_PerFile(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	dir := writeInputs(t)
+	out := filepath.Join(t.TempDir(), "results")
+
+	var stdout, stderr bytes.Buffer
+	as.Equal(0, run(context.Background(), []string{"-out", out, filepath.Join(dir, "*.sql")}, nil, &stdout, &stderr))
+	as.Empty(stdout.String())
+
+	entries, err := os.ReadDir(out)
+	as.Nil(err)
+	as.Equal(2, len(entries))
+
+	b, err := os.ReadFile(filepath.Join(out, resultName(filepath.Join(dir, "z.sql"))))
+	as.Nil(err)
+
+	var res fileResult
+	as.Nil(json.Unmarshal(b, &res))
+	as.Equal("INSERT INTO t (a) VALUES (?)", res.Statements[0].TemplatizedSQL)
+}
+
+func TestRun_Stdin(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var stdout, stderr bytes.Buffer
+	as.Equal(0, run(context.Background(), nil, strings.NewReader("SELECT * FROM t WHERE a = 'x'"), &stdout, &stderr))
+
+	var results []*fileResult
+	as.Nil(json.Unmarshal(stdout.Bytes(), &results))
+	as.Equal("-", results[0].File)
+	as.Equal([]any{"x"}, results[0].Statements[0].Params)
+	as.Nil(results[0].Tags)
+
+	stdout.Reset()
+	as.Equal(0, run(context.Background(), []string{"-tag", "service=billing", "-tag", "user=app"},
+		strings.NewReader("SELECT 1"), &stdout, &stderr))
+	as.Nil(json.Unmarshal(stdout.Bytes(), &results))
+	as.Equal(sqlextractor.Tags{"service": "billing", "user": "app"}, results[0].Tags)
+
+	as.Equal(2, run(context.Background(), []string{"-tag", "service"}, nil, &stdout, &stderr))
+	as.Equal(2, run(context.Background(), []string{"-unknown"}, nil, &stdout, &stderr))
+}
+
+func TestRun_TableReport(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	dir := writeInputs(t)
+
+	var stdout, stderr bytes.Buffer
+	as.Equal(0, run(context.Background(), []string{"-table-report", dir}, nil, &stdout, &stderr))
+	as.Empty(stderr.String())
+
+	var report []sqlextractor.TableAccess
+	as.Nil(json.Unmarshal(stdout.Bytes(), &report))
+	as.Equal(2, len(report)) // a.sql can not be extracted
+
+	as.Equal("orders", report[0].Table)
+	as.Equal(int64(1), report[0].Reads)
+	as.Equal(int64(1), report[0].Writes)
+	as.Equal(0.5, report[0].ReadRatio)
+	as.Equal([]string{"id", "user_id"}, report[0].WhereColumns)
+	as.Equal(2, len(report[0].Digests))
+
+	stdout.Reset()
+	as.Equal(0, run(context.Background(), []string{"-table-report"},
+		strings.NewReader("SELECT * FROM t WHERE a = 1 ORDER BY b"), &stdout, &stderr))
+	as.Nil(json.Unmarshal(stdout.Bytes(), &report))
+	as.Equal([]string{"b"}, report[0].OrderColumns)
+
+	as.Equal(1, run(context.Background(), []string{"-table-report", filepath.Join(dir, "missing.sql")}, nil, &stdout, &stderr))
+}
+
+func TestRun_Codegen(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	dir := t.TempDir()
+	as.Nil(os.WriteFile(filepath.Join(dir, "get_user.sql"), []byte("SELECT * FROM users WHERE id = 1 AND name = 'a'"), 0o644))
+	as.Nil(os.WriteFile(filepath.Join(dir, "touch-user.sql"), []byte("UPDATE users SET seen = 1.5 WHERE id = 2; DELETE FROM t"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	as.Equal(0, run(context.Background(), []string{"-codegen", "queries", dir}, nil, &stdout, &stderr))
+	as.Empty(stderr.String())
+
+	code := stdout.String()
+	as.True(strings.HasPrefix(code, "// Code generated by sql-extractor -codegen. DO NOT EDIT.\n\npackage queries\n"))
+	as.Contains(code, `const GetUser = "SELECT * FROM users WHERE id = ? AND name = ?"`)
+	as.Contains(code, "type GetUserParams struct {\n\tID   int64  `db:\"id\"`\n\tName string `db:\"name\"`\n}")
+	as.Contains(code, "func QueryGetUser(ctx context.Context, db DBTX, p GetUserParams) (*sql.Rows, error) {\n"+
+		"\treturn db.QueryContext(ctx, GetUser, p.Args()...)\n}")
+	as.Contains(code, "Seen string `db:\"seen\"`")
+	as.Contains(code, "func ExecTouchUser(ctx context.Context, db DBTX, p TouchUserParams) (sql.Result, error) {")
+	as.Contains(code, "func ExecTouchUser2(ctx context.Context, db DBTX) (sql.Result, error) {\n"+
+		"\treturn db.ExecContext(ctx, TouchUser2)\n}")
+
+	as.Equal(1, run(context.Background(), []string{"-codegen", "queries"}, nil, &stdout, &stderr))
+	as.Equal(1, run(context.Background(), []string{"-codegen", "queries", filepath.Join(dir, "missing.sql")}, nil, &stdout, &stderr))
+}
+
+func TestRun_TemplateDiff(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	dir := t.TempDir()
+	as.Nil(os.MkdirAll(filepath.Join(dir, "v1"), 0o755))
+	as.Nil(os.MkdirAll(filepath.Join(dir, "v2"), 0o755))
+	as.Nil(os.WriteFile(filepath.Join(dir, "v1", "slow.log"), []byte(slowLog), 0o644))
+	as.Nil(os.WriteFile(filepath.Join(dir, "v2", "a.sql"), []byte("SELECT * FROM orders WHERE user_id = 1"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	as.Equal(0, run(context.Background(), []string{"-template-diff", filepath.Join(dir, "v1"), filepath.Join(dir, "v2")},
+		nil, &stdout, &stderr))
+	as.Empty(stderr.String())
+
+	var diff sqlextractor.TemplateDiff
+	as.Nil(json.Unmarshal(stdout.Bytes(), &diff))
+	as.Empty(diff.Added)
+	as.Empty(diff.Changed)
+	as.Len(diff.Removed, 1)
+	as.Equal("DELETE FROM orders WHERE id eq ?", diff.Removed[0].TemplatizedSQL)
+
+	as.Equal(1, run(context.Background(), []string{"-template-diff", filepath.Join(dir, "v1")}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is the number of latest observations the latency quantiles
+// are computed from.
+const latencyWindow = 4096
+
+// metrics are the metrics of the server, exposed in the OpenMetrics text
+// format.
+type metrics struct {
+	mu sync.Mutex
+
+	requests    map[requestKey]uint64
+	parseErrors uint64
+	cacheHits   uint64
+	cacheMisses uint64
+
+	// templatization latency: ring of the latest observations, sum and count
+	// of all of them
+	latencies []float64
+	next      int
+	sum       float64
+	count     uint64
+}
+
+// requestKey labels the request counter.
+type requestKey struct {
+	path string
+	code int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]uint64),
+		latencies: make([]float64, 0, latencyWindow),
+	}
+}
+
+func (m *metrics) request(path string, code int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{path: path, code: code}]++
+}
+
+func (m *metrics) parseError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.parseErrors++
+}
+
+func (m *metrics) cache(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+func (m *metrics) templatized(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := d.Seconds()
+	if len(m.latencies) < latencyWindow {
+		m.latencies = append(m.latencies, s)
+	} else {
+		m.latencies[m.next] = s
+		m.next = (m.next + 1) % latencyWindow
+	}
+	m.sum += s
+	m.count++
+}
+
+// quantile returns the q-quantile of the sorted values, NaN if empty.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+
+	return sorted[int(math.Ceil(q*float64(len(sorted))))-1]
+}
+
+// write writes the metrics in the OpenMetrics text format.
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	requests := make(map[requestKey]uint64, len(m.requests))
+	for k, v := range m.requests {
+		requests[k] = v
+	}
+	var (
+		parseErrors, hits, misses = m.parseErrors, m.cacheHits, m.cacheMisses
+		latencies                 = slices.Clone(m.latencies)
+		sum, count                = m.sum, m.count
+	)
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+
+		return keys[i].code < keys[j].code
+	})
+	slices.Sort(latencies)
+
+	ratio := math.NaN()
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
+
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("# TYPE sql_extractor_requests counter\n")
+	printf("# HELP sql_extractor_requests HTTP requests served.\n")
+	for _, k := range keys {
+		printf("sql_extractor_requests_total{path=%q,code=\"%d\"} %d\n", k.path, k.code, requests[k])
+	}
+
+	printf("# TYPE sql_extractor_parse_errors counter\n")
+	printf("# HELP sql_extractor_parse_errors SQL which can not be extracted.\n")
+	printf("sql_extractor_parse_errors_total %d\n", parseErrors)
+
+	printf("# TYPE sql_extractor_templatize_duration_seconds summary\n")
+	printf("# UNIT sql_extractor_templatize_duration_seconds seconds\n")
+	printf("# HELP sql_extractor_templatize_duration_seconds Templatization latency, quantiles of the latest %d requests.\n", latencyWindow)
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		printf("sql_extractor_templatize_duration_seconds{quantile=\"%g\"} %g\n", q, quantile(latencies, q))
+	}
+	printf("sql_extractor_templatize_duration_seconds_sum %g\n", sum)
+	printf("sql_extractor_templatize_duration_seconds_count %d\n", count)
+
+	printf("# TYPE sql_extractor_cache_hits counter\n")
+	printf("# HELP sql_extractor_cache_hits Results served from the cache.\n")
+	printf("sql_extractor_cache_hits_total %d\n", hits)
+	printf("# TYPE sql_extractor_cache_misses counter\n")
+	printf("# HELP sql_extractor_cache_misses Results not in the cache.\n")
+	printf("sql_extractor_cache_misses_total %d\n", misses)
+	printf("# TYPE sql_extractor_cache_hit_ratio gauge\n")
+	printf("# HELP sql_extractor_cache_hit_ratio Ratio of the results served from the cache.\n")
+	printf("sql_extractor_cache_hit_ratio %g\n", ratio)
+
+	printf("# EOF\n")
+
+	return err
+}
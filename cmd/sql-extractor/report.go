@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// tableReport aggregates the queries of the files, or of stdin without files,
+// and returns the access pattern of each table. Queries which can not be
+// extracted are skipped.
+func tableReport(files []string, stdin io.Reader, tags sqlextractor.Tags) ([]sqlextractor.TableAccess, error) {
+	stats, err := aggregate(files, stdin, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlextractor.TableReport(stats), nil
+}
+
+// templateDiff aggregates the queries of the old and the new files, and
+// returns the differences of their templates. Queries which can not be
+// extracted are skipped.
+func templateDiff(oldFiles, newFiles []string) (sqlextractor.TemplateDiff, error) {
+	oldStats, err := aggregate(oldFiles, nil, nil)
+	if err != nil {
+		return sqlextractor.TemplateDiff{}, err
+	}
+
+	newStats, err := aggregate(newFiles, nil, nil)
+	if err != nil {
+		return sqlextractor.TemplateDiff{}, err
+	}
+
+	return sqlextractor.DiffTemplates(oldStats, newStats), nil
+}
+
+// aggregate aggregates the queries of the files, or of stdin without files.
+func aggregate(files []string, stdin io.Reader, tags sqlextractor.Tags) ([]sqlextractor.DigestStats, error) {
+	aggregator := sqlextractor.NewAggregator(sqlextractor.WithMaxExamples(1), sqlextractor.WithMaxExampleBytes(0))
+
+	if len(files) == 0 {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+
+		_ = aggregator.AddSQL(string(b), tags)
+		return aggregator.Stats(), nil
+	}
+
+	for _, file := range files {
+		queries, err := readQueries(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, query := range queries {
+			_ = aggregator.AddSQLWith(query.sql, tags, query.middleware()...)
+		}
+	}
+
+	return aggregator.Stats(), nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// maxRequestBytes limits the size of the SQL of a request.
+const maxRequestBytes = 16 << 20
+
+// server serves the extraction over HTTP:
+//
+//	POST /extract  the SQL in the body, returns the envelope, ?fields= selects
+//	               the fields of its statements, e.g. ?fields=digest
+//	GET  /metrics  metrics in the OpenMetrics text format
+//	GET  /healthz  liveness, 200 while the process is running
+//	GET  /readyz   readiness, 503 once the server is shutting down
+type server struct {
+	cache   *resultCache
+	metrics *metrics
+	ready   atomic.Bool
+}
+
+func newServer(cacheSize int) *server {
+	s := &server{cache: newResultCache(cacheSize), metrics: newMetrics()}
+	s.ready.Store(true)
+
+	return s
+}
+
+// handler returns the HTTP handler of the server.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /extract", s.extract)
+	mux.HandleFunc("GET /metrics", s.serveMetrics)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !s.ready.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok\n")
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		mux.ServeHTTP(rec, r)
+
+		// 只统计已知路径，避免标签基数无限增长
+		path := r.URL.Path
+		if _, pattern := mux.Handler(r); pattern == "" {
+			path = "other"
+		}
+		s.metrics.request(path, rec.code)
+	})
+}
+
+// extract returns the envelope of the SQL, with status 422 if it can not be
+// extracted.
+func (s *server) extract(w http.ResponseWriter, r *http.Request) {
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	sql := string(b)
+
+	// 缓存按字段选择区分，已裁剪的结果不需要重复编码
+	key := sql
+	if fields != nil {
+		key = strings.Join(fields, ",") + "\x00" + sql
+	}
+
+	body, failed, ok := s.cache.get(key)
+	s.metrics.cache(ok)
+	if !ok {
+		start := time.Now()
+		env := sqlextractor.ExtractEnvelope(sql)
+		s.metrics.templatized(time.Since(start))
+
+		body, failed = projectEnvelope(env, fields), env.Error != ""
+		s.cache.add(key, body, failed)
+	}
+	if failed {
+		s.metrics.parseError()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if failed {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	_, _ = w.Write(body)
+}
+
+// statementFields are the fields of the statements of the envelope, which can
+// be selected with ?fields=.
+var statementFields = []string{"templatized_sql", "digest", "op_type", "tables", "params"}
+
+// parseFields returns the sorted statement fields of the comma separated
+// list, nil for all the fields if the list is empty.
+func parseFields(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(statementFields, field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(statementFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	return slices.Compact(fields), nil
+}
+
+// projectEnvelope returns the JSON of the envelope with only the fields of
+// its statements, the whole envelope if fields is nil. The envelope fields
+// other than the statements are always returned.
+func projectEnvelope(env *sqlextractor.Envelope, fields []string) []byte {
+	if fields == nil {
+		return env.JSON()
+	}
+
+	statements := make([]map[string]any, len(env.Statements))
+	for idx, stmt := range env.Statements {
+		projected := make(map[string]any, len(fields))
+		for _, field := range fields {
+			switch field {
+			case "templatized_sql":
+				projected[field] = stmt.TemplatizedSQL
+			case "digest":
+				projected[field] = stmt.Digest
+			case "op_type":
+				projected[field] = stmt.OpType
+			case "tables":
+				projected[field] = stmt.Tables
+			case "params":
+				projected[field] = stmt.Params
+			}
+		}
+		statements[idx] = projected
+	}
+
+	// 外层的 Statements 字段覆盖 Envelope 中的同名字段
+	b, err := json.Marshal(struct {
+		*sqlextractor.Envelope
+		Statements []map[string]any `json:"statements"`
+	}{env, statements})
+	if err != nil {
+		return env.JSON()
+	}
+
+	return b
+}
+
+func (s *server) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	_ = s.metrics.write(w)
+}
+
+// statusRecorder records the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// resultCache is a LRU cache of the envelopes by SQL, ORMs send the same SQL
+// over and over. A size <= 0 disables the cache.
+type resultCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	sql    string
+	body   []byte
+	failed bool
+}
+
+func newResultCache(size int) *resultCache {
+	return &resultCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *resultCache) get(sql string) ([]byte, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[sql]
+	if !ok {
+		return nil, false, false
+	}
+	c.ll.MoveToFront(el)
+	entry := el.Value.(*cacheEntry)
+
+	return entry.body, entry.failed, true
+}
+
+func (c *resultCache) add(sql string, body []byte, failed bool) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[sql]; ok {
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[sql] = c.ll.PushFront(&cacheEntry{sql: sql, body: body, failed: failed})
+	if c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*cacheEntry).sql)
+	}
+}
+
+// shutdownOptions configure the graceful shutdown of the server.
+type shutdownOptions struct {
+	// drainDelay is how long the server keeps serving after it reports not
+	// ready, so load balancers (e.g. Kubernetes endpoints) stop sending new
+	// requests before the listener is closed.
+	drainDelay time.Duration
+
+	// timeout bounds how long in-flight requests are waited for, the
+	// remaining connections are closed after it.
+	timeout time.Duration
+}
+
+// serve serves the server on the listener until ctx is done, then shuts down
+// gracefully: /readyz reports not ready, new requests are still served during
+// the drain delay, then the listener is closed and in-flight requests are
+// drained within the shutdown timeout.
+func serve(ctx context.Context, ln net.Listener, s *server, opts shutdownOptions) error {
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+
+		s.ready.Store(false)
+		srv.SetKeepAlivesEnabled(false)
+		time.Sleep(opts.drainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+		defer cancel()
+
+		err := srv.Shutdown(shutdownCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = errors.Join(err, srv.Close())
+		}
+		done <- err
+	}()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return <-done
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	ts := httptest.NewServer(newServer(10).handler())
+	defer ts.Close()
+
+	post := func(sql string) (int, string) {
+		resp, err := http.Post(ts.URL+"/extract", "text/plain", strings.NewReader(sql))
+		as.Nil(err)
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+
+		return resp.StatusCode, string(b)
+	}
+
+	code, body := post("SELECT * FROM users WHERE id = 1")
+	as.Equal(http.StatusOK, code)
+	as.Contains(body, `"templatized_sql":"SELECT * FROM users WHERE id eq ?"`)
+
+	code, cached := post("SELECT * FROM users WHERE id = 1")
+	as.Equal(http.StatusOK, code)
+	as.Equal(body, cached)
+
+	code, body = post("SELEC 1")
+	as.Equal(http.StatusUnprocessableEntity, code)
+	as.Contains(body, `"error"`)
+	code, _ = post("SELEC 1")
+	as.Equal(http.StatusUnprocessableEntity, code)
+
+	resp, err := http.Get(ts.URL + "/extract")
+	as.Nil(err)
+	resp.Body.Close()
+	as.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/metrics")
+	as.Nil(err)
+	defer resp.Body.Close()
+	as.Equal("application/openmetrics-text; version=1.0.0; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	b, _ := io.ReadAll(resp.Body)
+	metrics := string(b)
+	for _, line := range []string{
+		`sql_extractor_requests_total{path="/extract",code="200"} 2`,
+		`sql_extractor_requests_total{path="/extract",code="422"} 2`,
+		`sql_extractor_requests_total{path="other",code="405"} 1`,
+		`sql_extractor_parse_errors_total 2`,
+		`sql_extractor_templatize_duration_seconds_count 2`,
+		`sql_extractor_cache_hits_total 2`,
+		`sql_extractor_cache_misses_total 2`,
+		`sql_extractor_cache_hit_ratio 0.5`,
+	} {
+		as.Contains(metrics, line+"\n")
+	}
+	as.Contains(metrics, `sql_extractor_templatize_duration_seconds{quantile="0.99"} `)
+	as.True(strings.HasSuffix(metrics, "# EOF\n"))
+}
+
+func TestServer_Fields(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	ts := httptest.NewServer(newServer(10).handler())
+	defer ts.Close()
+
+	post := func(query, sql string) (int, string) {
+		resp, err := http.Post(ts.URL+"/extract"+query, "text/plain", strings.NewReader(sql))
+		as.Nil(err)
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+
+		return resp.StatusCode, string(b)
+	}
+
+	const sql = "SELECT * FROM users WHERE id = 1"
+	code, body := post("?fields=digest", sql)
+	as.Equal(http.StatusOK, code)
+	as.JSONEq(`{"schema_version": 1, "dialect": "mysql", "warnings": [],
+		"statements": [{"digest": "`+sqlextractor.ExtractEnvelope(sql).Statements[0].Digest+`"}]}`, body)
+
+	code, body = post("?fields=tables,%20templatized_sql,tables", sql)
+	as.Equal(http.StatusOK, code)
+	as.JSONEq(`{"schema_version": 1, "dialect": "mysql", "warnings": [], "statements": [{
+		"templatized_sql": "SELECT * FROM users WHERE id eq ?", "tables": [{"schema": "", "table": "users"}]}]}`, body)
+
+	// the full result is cached separately
+	code, body = post("", sql)
+	as.Equal(http.StatusOK, code)
+	as.Contains(body, `"params":[1]`)
+
+	code, body = post("?fields=digest", "SELEC 1")
+	as.Equal(http.StatusUnprocessableEntity, code)
+	as.Contains(body, `"error"`)
+
+	code, body = post("?fields=digest,plan", sql)
+	as.Equal(http.StatusBadRequest, code)
+	as.Contains(body, `unknown field "plan"`)
+}
+
+func TestResultCache(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	c := newResultCache(2)
+	c.add("a", []byte("1"), false)
+	c.add("b", []byte("2"), true)
+	_, _, ok := c.get("a")
+	as.True(ok)
+	c.add("c", []byte("3"), false) // evicts b, the least recently used
+
+	_, _, ok = c.get("b")
+	as.False(ok)
+	body, failed, ok := c.get("a")
+	as.True(ok)
+	as.False(failed)
+	as.Equal([]byte("1"), body)
+
+	c = newResultCache(0)
+	c.add("a", []byte("1"), false)
+	_, _, ok = c.get("a")
+	as.False(ok)
+}
+
+func TestQuantile(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	values := make([]float64, 100)
+	for idx := range values {
+		values[idx] = float64(idx + 1)
+	}
+	as.Equal(50.0, quantile(values, 0.5))
+	as.Equal(99.0, quantile(values, 0.99))
+	as.Equal(1.0, quantile(values[:1], 0.99))
+	as.True(math.IsNaN(quantile(nil, 0.5)))
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	as.Nil(err)
+	url := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- serve(ctx, ln, newServer(0), shutdownOptions{drainDelay: 300 * time.Millisecond, timeout: 5 * time.Second})
+	}()
+
+	get := func(path string) int {
+		resp, err := http.Get(url + path)
+		if !as.Nil(err) {
+			return 0
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+	as.Equal(http.StatusOK, get("/healthz"))
+	as.Equal(http.StatusOK, get("/readyz"))
+
+	// a request in flight when the shutdown starts, on its own connection as
+	// idle connections are closed
+	var (
+		body, writer = io.Pipe()
+		inflight     = make(chan int)
+		client       = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	)
+	go func() {
+		resp, err := client.Post(url+"/extract", "text/plain", body)
+		if err != nil {
+			t.Log(err)
+			inflight <- 0
+			return
+		}
+		resp.Body.Close()
+		inflight <- resp.StatusCode
+	}()
+	_, err = writer.Write([]byte("SELECT * FROM users "))
+	as.Nil(err)
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+
+	// not ready, but still serving during the drain delay
+	as.Eventually(func() bool { return get("/readyz") == http.StatusServiceUnavailable }, time.Second, 10*time.Millisecond)
+	as.Equal(http.StatusOK, get("/healthz"))
+
+	time.Sleep(500 * time.Millisecond)
+	_, err = writer.Write([]byte("WHERE id = 1"))
+	as.Nil(err)
+	as.Nil(writer.Close())
+	as.Equal(http.StatusOK, <-inflight)
+
+	select {
+	case err := <-done:
+		as.Nil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not shut down")
+	}
+}
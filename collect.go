@@ -0,0 +1,233 @@
+package sqlextractor
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// ExtractorOption configures the Extractor.
+type ExtractorOption func(*Extractor)
+
+// WithCollectParams sets whether the parameters are collected, true by default.
+// Without collecting, the literals are still replaced by placeholders, but
+// their values are neither boxed nor stored and Params is empty, which reduces
+// CPU and GC pressure for monitoring only callers.
+func WithCollectParams(collect bool) ExtractorOption {
+	return func(e *Extractor) { e.options.noParams = !collect }
+}
+
+// WithCollectTables sets whether the table infos are collected, true by
+// default. Without collecting, TableInfos is empty.
+func WithCollectTables(collect bool) ExtractorOption {
+	return func(e *Extractor) { e.options.noTables = !collect }
+}
+
+// ParamsOverflow is the policy applied when a statement has more parameters
+// than the limit of WithMaxParams.
+type ParamsOverflow = extract.ParamsOverflow
+
+const (
+	// ParamsTruncate keeps the first parameters up to the limit, the template
+	// is unchanged, and a warning is reported.
+	ParamsTruncate = extract.ParamsTruncate
+	// ParamsCollapse templatizes the statement again with IN lists collapsed
+	// to their first item and INSERT VALUES to their first row, truncating the
+	// parameters if they still exceed the limit.
+	ParamsCollapse = extract.ParamsCollapse
+	// ParamsError fails the statement with ErrTooManyParams.
+	ParamsError = extract.ParamsError
+)
+
+// ErrTooManyParams is returned by Extract when a statement has more parameters
+// than the limit of WithMaxParams, with the ParamsError policy.
+var ErrTooManyParams = extract.ErrTooManyParams
+
+// WithMaxParams limits the parameters collected per statement to n, instead of
+// growing the memory unbounded for statements with tens of thousands of
+// literals, e.g. giant IN lists or bulk INSERTs. The policy decides what
+// happens to the statements over the limit, truncations are reported by
+// Warnings. n <= 0 means no limit, the default.
+func WithMaxParams(n int, policy ParamsOverflow) ExtractorOption {
+	return func(e *Extractor) {
+		e.options.maxParams = max(n, 0)
+		e.options.overflow = policy
+	}
+}
+
+// TruncatedParam replaces a string parameter larger than the limit of
+// WithMaxParamBytes, or a blob-like literal skipped by WithSkipBlobParams.
+type TruncatedParam = extract.TruncatedParam
+
+// WithMaxParamBytes truncates the string parameters larger than n bytes, e.g.
+// the payloads of huge INSERTs, to a TruncatedParam of their first n bytes,
+// which keeps the size of the value. n <= 0 means no limit, the default.
+func WithMaxParamBytes(n int) ExtractorOption {
+	return func(e *Extractor) { e.options.maxParamBytes = max(n, 0) }
+}
+
+// WithSkipBlobParams replaces the blob-like literals of at least minBytes
+// bytes by a TruncatedParam without their value. Hex and binary literals,
+// strings that are not valid UTF-8 and base64 strings are blob-like.
+// minBytes <= 0 disables it, the default.
+//
+// e.g. WithSkipBlobParams(1024): INSERT INTO files VALUES (1, 'iVBORw0KGgo...') -> params [1, {Size: 4096, Blob: true}]
+func WithSkipBlobParams(minBytes int) ExtractorOption {
+	return func(e *Extractor) { e.options.blobParamBytes = max(minBytes, 0) }
+}
+
+// WithStandardOperators renders the standard SQL operators (=, >, <=, <>,
+// AND, OR, ...) instead of the eq, gt, le, ne, and, or tokens, and quotes the
+// reserved keywords and the names with special characters with backticks, so
+// the templates are valid SQL and can be executed directly as prepared
+// statements with the params. The templates, and so the digests, differ from
+// the default ones. The presets set it, see WithPreset.
+//
+// e.g. SELECT * FROM users WHERE name = 'kyden' AND `order` > 18 -> SELECT * FROM users WHERE name = ? AND `order` > ?
+func WithStandardOperators() ExtractorOption {
+	return func(e *Extractor) {
+		e.options.standardOps = true
+		// 保留预设的引用方式，如 PresetMySQLDigest 的 QuoteAlways
+		if e.options.quoting == extract.QuoteDefault {
+			e.options.quoting = extract.QuoteWhenNeeded
+		}
+	}
+}
+
+// AssignmentStyle is the operator of the assignments in SET clauses.
+type AssignmentStyle = extract.AssignmentStyle
+
+const (
+	// AssignmentFollowOps follows the operator style of the comparisons: eq.
+	AssignmentFollowOps = extract.AssignmentFollowOps
+	// AssignmentSymbolic renders =, so the SET clauses of UPDATE and INSERT
+	// templates are executable SQL.
+	AssignmentSymbolic = extract.AssignmentSymbolic
+	// AssignmentWord renders eq.
+	AssignmentWord = extract.AssignmentWord
+)
+
+// WithAssignmentStyle sets the operator of the assignments in SET clauses,
+// AssignmentFollowOps by default.
+//
+// e.g. AssignmentSymbolic: UPDATE t SET a = 1 WHERE b = 2 -> UPDATE t SET a = ? WHERE b eq ?
+func WithAssignmentStyle(style AssignmentStyle) ExtractorOption {
+	return func(e *Extractor) { e.options.assignment = style }
+}
+
+// extractorOptions are the options of the internal extractor.
+type extractorOptions struct {
+	noParams   bool
+	noTables   bool
+	maxParams  int
+	overflow   ParamsOverflow
+	assignment AssignmentStyle
+
+	maxParamBytes  int
+	blobParamBytes int
+
+	wildcardModifiers bool
+	qualify           bool
+	optimizerHints    bool
+	snowflake         bool
+	bigquery          bool
+	postgres          bool
+	sqlserver         bool
+	oracle            bool
+	spark             bool
+
+	tenantPattern string
+	tenantSchema  string
+
+	standardOps   bool
+	quoting       extract.IdentifierQuoting
+	collapseIn    bool
+	rawTableNames bool
+	named         bool
+}
+
+// internalOptions returns the options of the internal extractor.
+func (o extractorOptions) internalOptions() []extract.Option {
+	opts := []extract.Option{
+		extract.WithCollectParams(!o.noParams),
+		extract.WithCollectTables(!o.noTables),
+		extract.WithMaxParams(o.maxParams, o.overflow),
+		extract.WithAssignmentStyle(o.assignment),
+	}
+	if o.maxParamBytes > 0 {
+		opts = append(opts, extract.WithMaxParamBytes(o.maxParamBytes))
+	}
+	if o.blobParamBytes > 0 {
+		opts = append(opts, extract.WithSkipBlobParams(o.blobParamBytes))
+	}
+	if o.wildcardModifiers {
+		opts = append(opts, extract.WithWildcardModifiers())
+	}
+	if o.qualify {
+		opts = append(opts, extract.WithQualify())
+	}
+	if o.optimizerHints {
+		opts = append(opts, extract.WithOptimizerHints())
+	}
+	if o.snowflake {
+		opts = append(opts, extract.WithSnowflake())
+	}
+	if o.bigquery {
+		opts = append(opts, extract.WithBigQuery())
+	}
+	if o.postgres {
+		opts = append(opts, extract.WithPostgres())
+	}
+	if o.sqlserver {
+		opts = append(opts, extract.WithSQLServer())
+	}
+	if o.oracle {
+		opts = append(opts, extract.WithOracle())
+	}
+	if o.spark {
+		opts = append(opts, extract.WithSpark())
+	}
+	if o.tenantPattern != "" {
+		opts = append(opts, extract.WithTenantSchema(regexp.MustCompile(o.tenantPattern), o.tenantSchema))
+	}
+	if o.standardOps {
+		opts = append(opts, extract.WithStandardOperators())
+	}
+	if o.quoting != extract.QuoteDefault {
+		opts = append(opts, extract.WithIdentifierQuoting(o.quoting))
+	}
+	if o.collapseIn {
+		opts = append(opts, extract.WithCollapsedIn())
+	}
+	if o.rawTableNames {
+		opts = append(opts, extract.WithRawTableNames())
+	}
+	if o.named {
+		opts = append(opts, extract.WithNamedParams())
+	}
+
+	return opts
+}
+
+// sharedExtractors are the internal extractors shared by the Extractors with
+// the same options, created on first use.
+var sharedExtractors sync.Map // extractorOptions -> *extract.Extractor
+
+// sharedExtractor returns the shared internal extractor of the options.
+func sharedExtractor(o extractorOptions) *extract.Extractor {
+	if o == (extractorOptions{}) {
+		return defaultExtractor
+	}
+
+	if e, ok := sharedExtractors.Load(o); ok {
+		return e.(*extract.Extractor)
+	}
+
+	e, loaded := sharedExtractors.LoadOrStore(o, newExtractor(o.internalOptions()...))
+	if !loaded {
+		registerExtractor(e.(*extract.Extractor))
+	}
+
+	return e.(*extract.Extractor)
+}
@@ -0,0 +1,130 @@
+package sqlextractor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithCollect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT * FROM shop.users WHERE id = 1 AND name = 'kyden'"
+	full := NewExtractor(sql)
+	as.Nil(full.Extract())
+
+	bare := NewExtractor(sql, WithCollectParams(false), WithCollectTables(false))
+	as.Nil(bare.Extract())
+	as.Equal(full.TemplatizedSQL(), bare.TemplatizedSQL())
+	as.Equal(full.TemplatizedSQLHash(), bare.TemplatizedSQLHash())
+	as.Equal(full.OpType(), bare.OpType())
+	as.Empty(bare.Params()[0])
+	as.Empty(bare.TableInfos()[0])
+
+	params := NewExtractor(sql, WithCollectTables(false))
+	as.Nil(params.Extract())
+	as.Equal(full.Params(), params.Params())
+	as.Empty(params.TableInfos()[0])
+
+	// hooks 使用单独的内部 extractor，同样不收集参数
+	var statements int
+	hooked := NewExtractor(sql, WithCollectParams(false))
+	hooked.SetHooks(&Hooks{OnStatementDone: func(StatementInfo) { statements++ }})
+	as.Nil(hooked.Extract())
+	as.Equal(1, statements)
+	as.Empty(hooked.Params()[0])
+	as.Equal(full.TableInfos(), hooked.TableInfos())
+}
+
+func TestExtractor_WithMaxParams(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT * FROM t WHERE a IN (1, 2, 3, 4) AND b = 5"
+
+	truncate := NewExtractor(sql, WithMaxParams(2, ParamsTruncate))
+	as.Nil(truncate.Extract())
+	as.Equal([]string{"SELECT * FROM t WHERE a IN (?, ?, ?, ?) and b eq ?"}, truncate.TemplatizedSQL())
+	as.Equal([][]any{{int64(1), int64(2)}}, truncate.Params())
+	as.Equal([]string{"statement 1: 5 parameters truncated to 2 (truncate)"}, truncate.Warnings())
+
+	collapse := NewExtractor(sql, WithMaxParams(2, ParamsCollapse))
+	as.Nil(collapse.Extract())
+	as.Equal([]string{"SELECT * FROM t WHERE a IN (?) and b eq ?"}, collapse.TemplatizedSQL())
+	as.Equal([][]any{{int64(1), int64(5)}}, collapse.Params())
+
+	as.ErrorIs(NewExtractor(sql, WithMaxParams(2, ParamsError)).Extract(), ErrTooManyParams)
+
+	// 相同选项共享内部 extractor
+	as.Same(truncate.internal(), NewExtractor("SELECT 1", WithMaxParams(2, ParamsTruncate)).internal())
+	as.Same(defaultExtractor, NewExtractor(sql).internal())
+}
+
+func TestExtractor_WithParamBytes(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	payload := strings.Repeat("QUJD", 64)
+	sql := "INSERT INTO files (id, name, note, data, raw) VALUES (1, 'a.png', '你好世界', '" + payload + "', X'" +
+		strings.Repeat("ff", 300) + "')"
+
+	extractor := NewExtractor(sql, WithMaxParamBytes(8), WithSkipBlobParams(200))
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"INSERT INTO files (id, name, note, data, raw) VALUES (?, ?, ?, ?, ?)"}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{
+		int64(1),
+		"a.png",
+		TruncatedParam{Prefix: "你好", Size: 12, Truncated: true},
+		TruncatedParam{Size: 256, Blob: true, Truncated: true},
+		TruncatedParam{Size: 300, Blob: true, Truncated: true},
+	}}, extractor.Params())
+
+	// 小于阈值的二进制字面量不跳过，也不按字节数截断
+	extractor = NewExtractor("SELECT * FROM t WHERE a = X'ffff' AND b = 'QUJDRA=='", WithMaxParamBytes(1), WithSkipBlobParams(16))
+	as.Nil(extractor.Extract())
+	as.Equal([][]any{{test_driver.BinaryLiteral{0xff, 0xff}, TruncatedParam{Prefix: "Q", Size: 8, Truncated: true}}},
+		extractor.Params())
+
+	b, err := json.Marshal(TruncatedParam{Prefix: "ab", Size: 10, Truncated: true})
+	as.Nil(err)
+	as.JSONEq(`{"prefix": "ab", "size": 10, "truncated": true}`, string(b))
+}
+
+func TestExtractor_WithAssignmentStyle(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("UPDATE t SET a = 1, b = 'x' WHERE c = 2", WithAssignmentStyle(AssignmentSymbolic))
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"UPDATE t SET a = ?, b = ? WHERE c eq ?"}, extractor.TemplatizedSQL())
+
+	extractor = NewExtractor("UPDATE t SET a = 1 WHERE c = 2")
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"UPDATE t SET a eq ? WHERE c eq ?"}, extractor.TemplatizedSQL())
+}
+
+func TestExtractor_WithStandardOperators(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users WHERE name = 'kyden' AND age > 18 OR (score <= 5 AND id != 3); "+
+		"UPDATE t SET a = 1 WHERE c >= 2", WithStandardOperators())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{
+		"SELECT * FROM users WHERE name = ? AND age > ? OR (score <= ? AND id <> ?)",
+		"UPDATE t SET a = ? WHERE c >= ?",
+	}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{"kyden", int64(18), int64(5), int64(3)}, {int64(1), int64(2)}}, extractor.Params())
+
+	extractor = NewExtractor("UPDATE t SET a = 1 WHERE c = 2", WithStandardOperators(), WithAssignmentStyle(AssignmentWord))
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"UPDATE t SET a eq ? WHERE c = ?"}, extractor.TemplatizedSQL())
+	// 模板可以直接执行：保留字和含空格的标识符需要引用
+	extractor = NewExtractor("SELECT `order`, `my col` FROM `my table` WHERE `select` = 1 AND id > 2", WithStandardOperators())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT `order`, `my col` FROM `my table` WHERE `select` = ? AND id > ?"}, extractor.TemplatizedSQL())
+}
@@ -0,0 +1,119 @@
+package sqlextractor
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// Columns returns the columns used by each statement, resolved to their
+// original tables, with the clause they are used in: filter (WHERE, HAVING),
+// join (ON, USING), projection, order (ORDER BY) or write (UPDATE SET, INSERT
+// columns).
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT name FROM users WHERE age > 18 ORDER BY id")
+//	columns, err := extractor.Columns()
+//	// users.name PROJECTION, users.age FILTER, users.id ORDER
+func (e *Extractor) Columns() ([][]*models.ColumnUsage, error) {
+	return e.internal().ExtractColumns(e.rawSQL)
+}
+
+// ColumnStat is the usage counters of a column, see ColumnStats. The counters
+// are the numbers of statements using the column in each kind of clause.
+type ColumnStat struct {
+	Table      string `json:"table"` // templatized table name with schema, e.g. db_?.orders
+	Column     string `json:"column"`
+	Filter     int64  `json:"filter"`     // WHERE, HAVING
+	Join       int64  `json:"join"`       // ON, USING
+	Projection int64  `json:"projection"` // select fields
+	Order      int64  `json:"order"`      // ORDER BY
+	Write      int64  `json:"write"`      // UPDATE SET, INSERT columns
+}
+
+// Total returns the number of usages of the column in all the clauses.
+func (c *ColumnStat) Total() int64 { return c.Filter + c.Join + c.Projection + c.Order + c.Write }
+
+// ColumnStats returns the usage counters of each column of the aggregates, to
+// guide index cleanup (e.g. indexed columns never filtered on) and
+// denormalization (e.g. columns always joined). The columns are sorted by
+// usages, most used first.
+//
+// Like TableReport, the columns are resolved from the first example of each
+// aggregate, so they are missing for the aggregates without examples.
+func ColumnStats(stats []DigestStats) []ColumnStat {
+	type key struct{ table, column string }
+
+	var (
+		columns = make(map[key]*ColumnStat)
+		order   []*ColumnStat
+	)
+	for idx := range stats {
+		s := &stats[idx]
+
+		usages := exampleColumns(s)
+		if len(usages) == 0 {
+			continue
+		}
+
+		names := templatizedNames(s)
+		for _, u := range usages {
+			original, _ := models.NewTableInfo(u.Schema(), u.Table()).TableNameWithSchema()
+			name, ok := names[original]
+			if !ok {
+				continue
+			}
+
+			c, ok := columns[key{table: name, column: u.Column()}]
+			if !ok {
+				c = &ColumnStat{Table: name, Column: u.Column()}
+				columns[key{table: name, column: u.Column()}] = c
+				order = append(order, c)
+			}
+
+			switch u.Usage() {
+			case models.ColumnFilter:
+				c.Filter += s.Count
+			case models.ColumnJoin:
+				c.Join += s.Count
+			case models.ColumnProjection:
+				c.Projection += s.Count
+			case models.ColumnOrder:
+				c.Order += s.Count
+			case models.ColumnWrite:
+				c.Write += s.Count
+			}
+		}
+	}
+
+	result := make([]ColumnStat, len(order))
+	for idx, c := range order {
+		result[idx] = *c
+	}
+	slices.SortStableFunc(result, func(a, b ColumnStat) int { return cmp.Compare(b.Total(), a.Total()) })
+
+	return result
+}
+
+// ColumnStats returns the usage counters of each column of the aggregates, see
+// ColumnStats.
+func (a *Aggregator) ColumnStats() []ColumnStat {
+	return ColumnStats(a.Stats())
+}
+
+// exampleColumns returns the columns of the first example of the aggregate,
+// nil if it has none or can not be parsed, e.g. truncated.
+func exampleColumns(s *DigestStats) []*models.ColumnUsage {
+	if len(s.Examples) == 0 {
+		return nil
+	}
+
+	columns, err := defaultExtractor.ExtractColumns(s.Examples[0])
+	if err != nil || len(columns) != 1 {
+		return nil
+	}
+
+	return columns[0]
+}
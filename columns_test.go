@@ -0,0 +1,65 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestExtractor_Columns(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	columns, err := NewExtractor("SELECT name FROM users WHERE age > 18 ORDER BY id").Columns()
+	as.Nil(err)
+	as.Equal([][]*models.ColumnUsage{{
+		models.NewColumnUsage("", "users", "name", models.ColumnProjection),
+		models.NewColumnUsage("", "users", "age", models.ColumnFilter),
+		models.NewColumnUsage("", "users", "id", models.ColumnOrder),
+	}}, columns)
+}
+
+func TestColumnStats(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	aggregator := NewAggregator()
+	for _, sql := range []string{
+		"SELECT u.name FROM users u JOIN orders o ON o.user_id = u.id WHERE o.state = 'paid'",
+		"SELECT u.name FROM users u JOIN orders o ON o.user_id = u.id WHERE o.state = 'new'",
+		"SELECT name, email FROM users WHERE id = 1 ORDER BY name",
+		"SELECT * FROM db_1.tb_2 WHERE k = 1; SELECT * FROM db_3.tb_4 WHERE k = 2",
+	} {
+		as.Nil(aggregator.AddSQL(sql, nil))
+	}
+
+	stats := aggregator.ColumnStats()
+	as.Equal([]ColumnStat{
+		{Table: "users", Column: "name", Projection: 3, Order: 1},
+		{Table: "users", Column: "id", Filter: 1, Join: 2},
+		{Table: "orders", Column: "user_id", Join: 2},
+		{Table: "orders", Column: "state", Filter: 2},
+		{Table: "db_?.tb_?", Column: "k", Filter: 2},
+		{Table: "users", Column: "email", Projection: 1},
+	}, stats)
+	as.Equal(int64(4), stats[0].Total())
+
+	as.Empty(ColumnStats(nil))
+	as.Empty(NewAggregator(WithMaxExamples(0)).ColumnStats())
+}
+
+func TestExtractor_Columns_Dialect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 使用 Extractor 的方言选项解析
+	columns, err := NewExtractor("SELECT TOP 5 name FROM users WHERE age > 18 ORDER BY id", WithSQLServer()).Columns()
+	as.Nil(err)
+	as.Equal([][]*models.ColumnUsage{{
+		models.NewColumnUsage("", "users", "name", models.ColumnProjection),
+		models.NewColumnUsage("", "users", "age", models.ColumnFilter),
+		models.NewColumnUsage("", "users", "id", models.ColumnOrder),
+	}}, columns)
+}
@@ -0,0 +1,126 @@
+package sqlextractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Diff fields, see Change.
+const (
+	DiffError          = "error"
+	DiffStatements     = "statements"
+	DiffTemplatizedSQL = "templatized_sql"
+	DiffParams         = "params"
+	DiffTables         = "tables"
+	DiffOpType         = "op_type"
+)
+
+// Change is a difference between two results.
+type Change struct {
+	// Statement is the 0-based index of the statement, -1 for the changes of
+	// the whole result: its error or statement count.
+	Statement int
+
+	// Field is the changed field, e.g. DiffTemplatizedSQL or DiffParams.
+	Field string
+
+	// Old and New are the values of the field in the compared results.
+	Old, New any
+}
+
+// String returns the change in a human readable form.
+func (c Change) String() string {
+	if c.Statement < 0 {
+		return fmt.Sprintf("%s: %v -> %v", c.Field, c.Old, c.New)
+	}
+
+	return fmt.Sprintf("statement %d: %s: %v -> %v", c.Statement+1, c.Field, c.Old, c.New)
+}
+
+// Diff is the differences between two results of the same SQL, reported by
+// CompareResults.
+type Diff struct {
+	Changes []Change
+}
+
+// Empty reports whether the results are equivalent.
+func (d Diff) Empty() bool { return len(d.Changes) == 0 }
+
+// String returns the changes, one per line.
+func (d Diff) String() string {
+	lines := make([]string, len(d.Changes))
+	for idx := range d.Changes {
+		lines[idx] = d.Changes[idx].String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// CompareResults reports the differences in templates, params, tables and op
+// types between the results a and b of the same SQL, e.g. extracted by the
+// current and an upgraded version of the extractor, so the changes can be
+// reviewed on production samples before rollout. The statements are compared
+// in order, up to the shorter result.
+//
+// Params are compared by their JSON encoding, so results decoded from JSON
+// (where numbers are float64) can be compared with fresh ones. Digests, which
+// follow the templates, and warnings are not compared.
+//
+// Example:
+//
+//	diff := sqlextractor.CompareResults(stored, sqlextractor.ExtractEnvelope(sql))
+//	if !diff.Empty() {
+//	  log.Println(diff)
+//	}
+func CompareResults(a, b *Envelope) Diff {
+	var diff Diff
+	add := func(stmt int, field string, o, n any) {
+		diff.Changes = append(diff.Changes, Change{Statement: stmt, Field: field, Old: o, New: n})
+	}
+
+	if a.Error != b.Error {
+		add(-1, DiffError, a.Error, b.Error)
+	}
+	if len(a.Statements) != len(b.Statements) {
+		add(-1, DiffStatements, len(a.Statements), len(b.Statements))
+	}
+
+	for idx := range min(len(a.Statements), len(b.Statements)) {
+		sa, sb := &a.Statements[idx], &b.Statements[idx]
+
+		if sa.TemplatizedSQL != sb.TemplatizedSQL {
+			add(idx, DiffTemplatizedSQL, sa.TemplatizedSQL, sb.TemplatizedSQL)
+		}
+
+		if pa, pb := paramsJSON(sa.Params), paramsJSON(sb.Params); pa != pb {
+			add(idx, DiffParams, pa, pb)
+		}
+
+		if !slices.Equal(sa.Tables, sb.Tables) {
+			add(idx, DiffTables, sa.Tables, sb.Tables)
+		}
+
+		if sa.OpType != sb.OpType {
+			add(idx, DiffOpType, sa.OpType, sb.OpType)
+		}
+	}
+
+	return diff
+}
+
+// paramsJSON returns the JSON encoding of the params, nil and empty params are
+// both encoded as [].
+func paramsJSON(params []any) string {
+	if len(params) == 0 {
+		return "[]"
+	}
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Sprint(params)
+	}
+
+	return string(b)
+}
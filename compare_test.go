@@ -0,0 +1,48 @@
+package sqlextractor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareResults(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT * FROM users WHERE id = 1; UPDATE orders SET state = 'done' WHERE id IN (1, 2)"
+	current := ExtractEnvelope(sql)
+
+	// 从 JSON 解码的结果与新提取的结果相同
+	var stored Envelope
+	as.Nil(json.Unmarshal(current.JSON(), &stored))
+	diff := CompareResults(&stored, current)
+	as.True(diff.Empty(), diff.String())
+
+	upgraded := ExtractEnvelope(sql)
+	upgraded.Statements[0].TemplatizedSQL = "SELECT * FROM users WHERE id = ?"
+	upgraded.Statements[1].Params = []any{"done", int64(1)}
+	upgraded.Statements[1].Tables = []EnvelopeTable{{Table: "orders_?"}}
+	upgraded.Statements[1].OpType = "UNKNOWN"
+
+	diff = CompareResults(current, upgraded)
+	as.False(diff.Empty())
+	as.Equal([]Change{
+		{Statement: 0, Field: DiffTemplatizedSQL, Old: "SELECT * FROM users WHERE id eq ?", New: "SELECT * FROM users WHERE id = ?"},
+		{Statement: 1, Field: DiffParams, Old: `["done",1,2]`, New: `["done",1]`},
+		{Statement: 1, Field: DiffTables, Old: []EnvelopeTable{{Table: "orders"}}, New: []EnvelopeTable{{Table: "orders_?"}}},
+		{Statement: 1, Field: DiffOpType, Old: "UPDATE", New: "UNKNOWN"},
+	}, diff.Changes)
+	as.Equal("statement 1: templatized_sql: SELECT * FROM users WHERE id eq ? -> SELECT * FROM users WHERE id = ?\n"+
+		`statement 2: params: ["done",1,2] -> ["done",1]`+"\n"+
+		"statement 2: tables: [orders] -> [orders_?]\n"+
+		"statement 2: op_type: UPDATE -> UNKNOWN", diff.String())
+
+	failed := ExtractEnvelope("SELECT * FROM")
+	diff = CompareResults(current, failed)
+	as.Equal([]Change{
+		{Statement: -1, Field: DiffError, Old: "", New: failed.Error},
+		{Statement: -1, Field: DiffStatements, Old: 2, New: 0},
+	}, diff.Changes)
+}
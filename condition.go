@@ -0,0 +1,53 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/extract"
+
+type (
+	// Condition is a node of the simplified expression tree of a WHERE
+	// clause, see Extractor.Conditions.
+	Condition = extract.Condition
+	// ConditionOp is the operator of a Condition.
+	ConditionOp = extract.ConditionOp
+)
+
+const (
+	CondAnd = extract.CondAnd
+	CondOr  = extract.CondOr
+	CondNot = extract.CondNot
+
+	CondEQ     = extract.CondEQ
+	CondNE     = extract.CondNE
+	CondLT     = extract.CondLT
+	CondLE     = extract.CondLE
+	CondGT     = extract.CondGT
+	CondGE     = extract.CondGE
+	CondNullEQ = extract.CondNullEQ
+
+	CondIn         = extract.CondIn
+	CondNotIn      = extract.CondNotIn
+	CondLike       = extract.CondLike
+	CondNotLike    = extract.CondNotLike
+	CondBetween    = extract.CondBetween
+	CondNotBetween = extract.CondNotBetween
+	CondIsNull     = extract.CondIsNull
+	CondIsNotNull  = extract.CondIsNotNull
+
+	CondExpr = extract.CondExpr
+)
+
+// Conditions returns the WHERE clause of each statement as a serializable
+// Condition tree: flattened AND and OR nodes, NOT nodes, and comparisons of a
+// column with the indexes of their placeholders in Params, so that rule
+// engines can evaluate the conditions without the TiDB AST. The predicates
+// which are not a comparison of a column with values, e.g. a = b or
+// length(a) > 1, are CondExpr with their templates. The statements without
+// WHERE clause have a nil tree.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM t WHERE a = 1 AND (b > 2 OR c IS NULL)")
+//	conditions, err := extractor.Conditions()
+//	// conditions[0]: and(eq a [0], or(gt b [1], is null c))
+func (e *Extractor) Conditions() ([]*Condition, error) {
+	return e.internal().ExtractConditions(e.rawSQL)
+}
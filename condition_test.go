@@ -0,0 +1,40 @@
+package sqlextractor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_Conditions(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM t WHERE a = 1 AND (b > 2 OR c IS NULL) AND 10 >= d; " +
+		"DELETE FROM t WHERE id IN (1, 2, 3) AND NOT name LIKE 'x%'; SELECT 1")
+	as.Nil(extractor.Extract())
+
+	conditions, err := extractor.Conditions()
+	as.Nil(err)
+	as.Equal([]*Condition{
+		{Op: CondAnd, Children: []*Condition{
+			{Op: CondEQ, Column: "a", Params: []int{0}},
+			{Op: CondOr, Children: []*Condition{
+				{Op: CondGT, Column: "b", Params: []int{1}},
+				{Op: CondIsNull, Column: "c"},
+			}},
+			{Op: CondLE, Column: "d", Params: []int{2}},
+		}},
+		{Op: CondAnd, Children: []*Condition{
+			{Op: CondIn, Column: "id", Params: []int{0, 1, 2}},
+			{Op: CondNot, Children: []*Condition{{Op: CondLike, Column: "name", Params: []int{3}}}},
+		}},
+		nil,
+	}, conditions)
+	as.Equal([]any{int64(1), int64(2), int64(10)}, extractor.Params()[0])
+
+	data, err := json.Marshal(conditions[1].Children[0])
+	as.Nil(err)
+	as.JSONEq(`{"op":"in","column":"id","params":[0,1,2]}`, string(data))
+}
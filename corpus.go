@@ -0,0 +1,171 @@
+package sqlextractor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CorpusCase is the result of a corpus file, see RunCorpus.
+type CorpusCase struct {
+	File    string // path of the SQL file
+	Diff    Diff   // differences between the expected and the actual result
+	Err     error  // error reading the files or decoding the expected result
+	Updated bool   // the expected result was written, see WithCorpusUpdate
+}
+
+// Failed reports whether the case failed.
+func (c *CorpusCase) Failed() bool { return c.Err != nil || !c.Diff.Empty() }
+
+// CorpusReport is the result of RunCorpus.
+type CorpusReport struct {
+	Cases []CorpusCase
+}
+
+// OK reports whether all the cases passed.
+func (r *CorpusReport) OK() bool { return len(r.Failures()) == 0 }
+
+// Failures returns the failed cases.
+func (r *CorpusReport) Failures() []CorpusCase {
+	var failures []CorpusCase
+	for idx := range r.Cases {
+		if r.Cases[idx].Failed() {
+			failures = append(failures, r.Cases[idx])
+		}
+	}
+
+	return failures
+}
+
+// String returns the failed cases with their differences.
+func (r *CorpusReport) String() string {
+	var b strings.Builder
+	for _, c := range r.Failures() {
+		if c.Err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", c.File, c.Err)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:\n", c.File)
+		for _, change := range c.Diff.Changes {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+
+	return b.String()
+}
+
+// CorpusOption configures RunCorpus.
+type CorpusOption func(*corpusConfig)
+
+type corpusConfig struct {
+	update  bool
+	extract func(sql string) *Envelope
+}
+
+// WithCorpusUpdate writes the actual results as the expected results, e.g.
+// after reviewing the differences of an upgrade.
+func WithCorpusUpdate() CorpusOption {
+	return func(c *corpusConfig) { c.update = true }
+}
+
+// WithCorpusExtract sets the function extracting the SQL of the corpus files,
+// ExtractEnvelope by default, e.g. to run the corpus with extractor options.
+func WithCorpusExtract(extract func(sql string) *Envelope) CorpusOption {
+	return func(c *corpusConfig) { c.extract = extract }
+}
+
+// RunCorpus validates a golden corpus, so downstream users can maintain their
+// own regression corpora against the library. Each .sql file of dir, searched
+// recursively, is extracted and compared with the expected result, the
+// Envelope JSON of the file of the same name with the .json extension, by
+// CompareResults. A missing expected result fails the case, unless it is
+// written by WithCorpusUpdate.
+//
+// Example:
+//
+//	func TestCorpus(t *testing.T) {
+//	  report, err := sqlextractor.RunCorpus("testdata/corpus")
+//	  if err != nil {
+//	    t.Fatal(err)
+//	  }
+//	  if !report.OK() {
+//	    t.Error(report)
+//	  }
+//	}
+func RunCorpus(dir string, opts ...CorpusOption) (*CorpusReport, error) {
+	cfg := corpusConfig{extract: ExtractEnvelope}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	report := &CorpusReport{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".sql" {
+			return nil
+		}
+
+		report.Cases = append(report.Cases, runCorpusCase(path, &cfg))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// runCorpusCase validates a corpus file.
+func runCorpusCase(path string, cfg *corpusConfig) CorpusCase {
+	c := CorpusCase{File: path}
+
+	sql, err := os.ReadFile(path)
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	actual := cfg.extract(string(sql))
+
+	expectedPath := strings.TrimSuffix(path, ".sql") + ".json"
+	if cfg.update {
+		c.Err = writeEnvelope(expectedPath, actual)
+		c.Updated = c.Err == nil
+		return c
+	}
+
+	b, err := os.ReadFile(expectedPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.Err = fmt.Errorf("missing expected result %s", expectedPath)
+		return c
+	} else if err != nil {
+		c.Err = err
+		return c
+	}
+
+	var expected Envelope
+	if err := json.Unmarshal(b, &expected); err != nil {
+		c.Err = fmt.Errorf("decode %s: %w", expectedPath, err)
+		return c
+	}
+
+	c.Diff = CompareResults(&expected, actual)
+	return c
+}
+
+// writeEnvelope writes the indented JSON of the Envelope, so the expected
+// results are easy to review.
+func writeEnvelope(path string, env *Envelope) error {
+	b, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
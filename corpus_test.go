@@ -0,0 +1,70 @@
+package sqlextractor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCorpus(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	dir := t.TempDir()
+	as.Nil(os.MkdirAll(filepath.Join(dir, "orders"), 0o755))
+	as.Nil(os.WriteFile(filepath.Join(dir, "users.sql"), []byte("SELECT * FROM users WHERE id = 1"), 0o644))
+	as.Nil(os.WriteFile(filepath.Join(dir, "orders", "update.sql"),
+		[]byte("UPDATE orders SET state = 'done' WHERE id = 2; DELETE FROM orders WHERE id = 3"), 0o644))
+	as.Nil(os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a case"), 0o644))
+
+	// 缺少期望结果
+	report, err := RunCorpus(dir)
+	as.Nil(err)
+	as.Len(report.Cases, 2)
+	as.False(report.OK())
+	as.Len(report.Failures(), 2)
+
+	// 写入期望结果
+	report, err = RunCorpus(dir, WithCorpusUpdate())
+	as.Nil(err)
+	as.True(report.OK())
+	for _, c := range report.Cases {
+		as.True(c.Updated, c.File)
+	}
+	as.FileExists(filepath.Join(dir, "users.json"))
+	as.FileExists(filepath.Join(dir, "orders", "update.json"))
+
+	report, err = RunCorpus(dir)
+	as.Nil(err)
+	as.True(report.OK(), report.String())
+	as.Empty(report.String())
+
+	// 行为变化时报告差异
+	report, err = RunCorpus(dir, WithCorpusExtract(func(sql string) *Envelope {
+		e := NewExtractor(sql, WithCollectParams(false))
+		env := e.Envelope()
+		if err := e.Extract(); err == nil {
+			env = e.Envelope()
+		}
+
+		return env
+	}))
+	as.Nil(err)
+	as.False(report.OK())
+	as.Equal([]string{filepath.Join(dir, "orders", "update.sql"), filepath.Join(dir, "users.sql")},
+		[]string{report.Failures()[0].File, report.Failures()[1].File})
+	as.Equal(filepath.Join(dir, "users.sql")+":\n"+`  statement 1: params: [1] -> []`+"\n",
+		(&CorpusReport{Cases: report.Failures()[1:]}).String())
+
+	// 无法解码的期望结果
+	as.Nil(os.WriteFile(filepath.Join(dir, "users.json"), []byte("{"), 0o644))
+	report, err = RunCorpus(dir)
+	as.Nil(err)
+	as.Len(report.Failures(), 1)
+	as.ErrorContains(report.Failures()[0].Err, "decode")
+
+	_, err = RunCorpus(filepath.Join(dir, "missing"))
+	as.NotNil(err)
+}
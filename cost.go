@@ -0,0 +1,41 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+type (
+	// CostEstimate is the static cost estimate of a statement.
+	CostEstimate = models.CostEstimate
+	// CostTier is the coarse cost tier of a statement.
+	CostTier = models.CostTier
+	// Selectivity is the coarse selectivity class of the predicates of a
+	// statement.
+	Selectivity = models.Selectivity
+)
+
+const (
+	CostLow      = models.CostLow
+	CostMedium   = models.CostMedium
+	CostHigh     = models.CostHigh
+	CostCritical = models.CostCritical
+
+	SelectivityPoint = models.SelectivityPoint
+	SelectivityRange = models.SelectivityRange
+	SelectivityScan  = models.SelectivityScan
+)
+
+// CostEstimates returns the static cost estimate of each statement, computed
+// from its shape without a database: the table count, the selectivity class
+// of its predicates, and the leading wildcards, functions on columns and
+// ORDER BY without LIMIT, so that gateways can reject or throttle the
+// expensive statements by their tier.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE name LIKE '%bob%' ORDER BY created_at")
+//	estimates, err := extractor.CostEstimates()
+//	if err == nil && estimates[0].Tier() >= CostHigh {
+//	  // reject
+//	}
+func (e *Extractor) CostEstimates() ([]*CostEstimate, error) {
+	return e.internal().EstimateCost(e.rawSQL)
+}
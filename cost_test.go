@@ -0,0 +1,46 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_CostEstimates(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; " +
+		"SELECT * FROM users WHERE name LIKE '%bob%' ORDER BY created_at")
+
+	estimates, err := extractor.CostEstimates()
+	as.Nil(err)
+	as.Len(estimates, 2)
+
+	as.Equal(SelectivityPoint, estimates[0].Selectivity())
+	as.Equal(1, estimates[0].Score())
+	as.Equal(CostLow, estimates[0].Tier())
+
+	as.Equal(SelectivityScan, estimates[1].Selectivity())
+	as.True(estimates[1].LeadingWildcard())
+	as.True(estimates[1].UnboundedSort())
+	as.Equal(64, estimates[1].Score())
+	as.Equal(CostCritical, estimates[1].Tier())
+	as.Equal("CRITICAL", estimates[1].Tier().String())
+
+	_, err = NewExtractor("SELEC").CostEstimates()
+	as.NotNil(err)
+}
+
+func TestExtractor_CostEstimates_Dialect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 使用 Extractor 的方言选项解析
+	estimates, err := NewExtractor("SELECT * FROM users WHERE id = 1 OFFSET 0 ROWS FETCH NEXT 1 ROWS ONLY",
+		WithPostgres()).CostEstimates()
+	as.Nil(err)
+	as.Len(estimates, 1)
+	as.Equal(SelectivityPoint, estimates[0].Selectivity())
+	as.Equal(CostLow, estimates[0].Tier())
+}
@@ -0,0 +1,134 @@
+package sqlextractor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// Dialect is a SQL dialect tried by ExtractAutoDialect, e.g. a wrapper of a
+// PostgreSQL parser.
+type Dialect struct {
+	// Name is reported in the result metadata, e.g. "postgresql".
+	Name string
+
+	// Extract extracts the statements of the SQL, as Extract does. Nil means
+	// the built-in MySQL parser, with the hooks and warnings of the Extractor.
+	Extract func(sql string) ([]string, [][]*models.TableInfo, [][]any, []models.SQLOpType, error)
+
+	// Match reports whether the SQL has syntax specific to the dialect, e.g.
+	// $1 placeholders or :: casts for PostgreSQL. Matching dialects are tried
+	// first. It is optional.
+	Match func(sql string) bool
+}
+
+// mysqlSyntax matches syntax specific to MySQL: backtick quoted identifiers,
+// LIMIT offset, count and ON DUPLICATE KEY UPDATE.
+var mysqlSyntax = regexp.MustCompile("`|(?i)\\bLIMIT\\s+\\d+\\s*,|\\bON\\s+DUPLICATE\\s+KEY\\b")
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = []Dialect{{
+		Name:  DialectMySQL,
+		Match: mysqlSyntax.MatchString,
+	}}
+)
+
+// RegisterDialect registers the dialect, which is tried by ExtractAutoDialect
+// in registration order after the built-in MySQL dialect. A dialect with the
+// same name is replaced. It is usually called at init.
+func RegisterDialect(d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+
+	if idx := slices.IndexFunc(dialects, func(r Dialect) bool { return r.Name == d.Name }); idx >= 0 {
+		dialects[idx] = d
+		return
+	}
+	dialects = append(dialects, d)
+}
+
+// Dialects returns the names of the registered dialects, in order.
+func Dialects() []string {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	names := make([]string, len(dialects))
+	for idx := range dialects {
+		names[idx] = dialects[idx].Name
+	}
+
+	return names
+}
+
+// candidateDialects returns the dialects in the order they are tried: the
+// dialects matching the SQL first, then the others, in registration order.
+func candidateDialects(sql string) []Dialect {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	var matched, others []Dialect
+	for _, d := range dialects {
+		if d.Match != nil && d.Match(sql) {
+			matched = append(matched, d)
+		} else {
+			others = append(others, d)
+		}
+	}
+
+	return append(matched, others...)
+}
+
+// Dialect returns the dialect the SQL was extracted with, DialectMySQL unless
+// detected otherwise by ExtractAutoDialect.
+func (e *Extractor) Dialect() string {
+	if e.dialect == "" {
+		return DialectMySQL
+	}
+
+	return e.dialect
+}
+
+// ExtractAutoDialect extracts the SQL when its dialect is not known: the
+// registered dialects are tried in order, those whose syntax heuristics match
+// the SQL first, and the first one which succeeds is reported by Dialect. The
+// error of each dialect is returned if none succeeds.
+//
+// Example:
+//
+//	sqlextractor.RegisterDialect(sqlextractor.Dialect{Name: "postgresql", Extract: pgExtract, Match: pgSyntax})
+//	extractor := NewExtractor(`SELECT * FROM "users" WHERE id = $1::int`)
+//	if err := extractor.ExtractAutoDialect(); err != nil {
+//	  // handle error
+//	}
+//	fmt.Println(extractor.Dialect()) // postgresql
+func (e *Extractor) ExtractAutoDialect() error {
+	var errs []error
+	for _, d := range candidateDialects(e.rawSQL) {
+		var err error
+		if d.Extract == nil {
+			err = e.Extract()
+		} else {
+			e.warnings = nil
+			e.templatedSQL, e.tableInfos, e.params, e.opType, err = d.Extract(e.rawSQL)
+			if err == nil {
+				e.plans = nil
+				e.applyMiddleware()
+				e.doHash()
+			}
+		}
+
+		// Extract 会将 dialect 设置为 MySQL
+		if err == nil {
+			e.dialect = d.Name
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", d.Name, err))
+	}
+
+	return errors.Join(errs...)
+}
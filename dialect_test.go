@@ -0,0 +1,75 @@
+package sqlextractor
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+var (
+	pgSyntax      = regexp.MustCompile(`\$\d+|::`)
+	pgPlaceholder = regexp.MustCompile(`\$\d+`)
+	pgCast        = regexp.MustCompile(`::\w+`)
+)
+
+// pgExtract is a toy PostgreSQL dialect: $N placeholders and :: casts are
+// rewritten, backticks are rejected.
+func pgExtract(sql string) ([]string, [][]*models.TableInfo, [][]any, []models.SQLOpType, error) {
+	if strings.Contains(sql, "`") {
+		return nil, nil, nil, nil, assert.AnError
+	}
+
+	sql = pgCast.ReplaceAllString(pgPlaceholder.ReplaceAllString(sql, "?"), "")
+
+	return defaultExtractor.Extract(sql)
+}
+
+// Not parallel: the dialects are global.
+func TestExtractor_ExtractAutoDialect(t *testing.T) {
+	as := assert.New(t)
+
+	saved := append([]Dialect(nil), dialects...)
+	t.Cleanup(func() { dialects = saved })
+
+	RegisterDialect(Dialect{Name: "postgresql", Extract: pgExtract, Match: pgSyntax.MatchString})
+	as.Equal([]string{DialectMySQL, "postgresql"}, Dialects())
+
+	e := NewExtractor("SELECT * FROM users WHERE id = 1")
+	as.Equal(DialectMySQL, e.Dialect())
+	as.Nil(e.ExtractAutoDialect())
+	as.Equal(DialectMySQL, e.Dialect())
+
+	// tried first as it matches, the MySQL parser does not support :: casts
+	e = NewExtractor("SELECT * FROM users WHERE id = $1::int AND name = 'kyden'")
+	as.Nil(e.ExtractAutoDialect())
+	as.Equal("postgresql", e.Dialect())
+	as.Equal([]string{"SELECT * FROM users WHERE id eq ? and name eq ?"}, e.TemplatizedSQL())
+	as.Equal([][]any{{"kyden"}}, e.Params())
+	as.Equal("postgresql", e.Envelope().Dialect)
+	as.Empty(e.Warnings())
+
+	// both match, MySQL is registered first
+	e = NewExtractor("SELECT `a` FROM t WHERE b = '$1'")
+	as.Nil(e.ExtractAutoDialect())
+	as.Equal(DialectMySQL, e.Dialect())
+
+	// both fail, the errors of each dialect are reported
+	e = NewExtractor("SELECT * FROM `t` WHERE b = $1::int")
+	err := e.ExtractAutoDialect()
+	as.NotNil(err)
+	as.Contains(err.Error(), "postgresql: ")
+	as.Contains(err.Error(), "mysql: ")
+
+	// replaced by name
+	RegisterDialect(Dialect{Name: "postgresql", Extract: pgExtract})
+	as.Equal([]string{DialectMySQL, "postgresql"}, Dialects())
+	e = NewExtractor("SELECT * FROM users WHERE id = $1::int")
+	as.Nil(e.ExtractAutoDialect())
+	as.Equal("postgresql", e.Dialect())
+
+	as.Equal(DialectMySQL, ExtractEnvelope("SELECT 1").Dialect)
+}
@@ -0,0 +1,287 @@
+package sqlextractor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DigestClass is a query class of a pt-query-digest report, joined with the
+// extraction of its example query.
+//
+// The embedded StatementResult is the first statement of the example, RawSQL
+// is the example query and Err is set if it can not be extracted.
+type DigestClass struct {
+	StatementResult
+
+	Checksum    string  // query ID, e.g. 0x3A99CC42AEDCCFCD
+	Fingerprint string  // pt-query-digest fingerprint, JSON reports only
+	Database    string  // database of the example
+	Count       int64   // number of queries
+	QueryTime   float64 // total query time in seconds
+	Digest      string  // sha256 of the templatized SQL, hex encoded
+}
+
+// DigestGroup is a group of pt-query-digest classes with the same templatized
+// SQL.
+type DigestGroup struct {
+	Digest         string
+	TemplatizedSQL string
+	Checksums      []string // query IDs of the classes, in order of appearance
+	Count          int64
+	QueryTime      float64
+}
+
+// ReadDigestReport reads a pt-query-digest report from r, either the default
+// report or the output of --output json, and extracts the example query of
+// each class. Classes without example (e.g. --no-report examples) are kept,
+// with Err set.
+func ReadDigestReport(r io.Reader) ([]*DigestClass, error) {
+	br := bufio.NewReader(r)
+
+	// JSON 报告以 { 开头
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+			break
+		}
+		_, _ = br.ReadByte()
+	}
+
+	var (
+		classes []*DigestClass
+		err     error
+	)
+	if b, _ := br.Peek(1); b[0] == '{' {
+		classes, err = readDigestJSON(br)
+	} else {
+		classes, err = readDigestText(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range classes {
+		c.extract()
+	}
+
+	return classes, nil
+}
+
+// GroupDigestClasses groups the classes by their templatized SQL, as the
+// normalization of this package differs from pt-query-digest fingerprints.
+// Classes which can not be extracted are ignored.
+func GroupDigestClasses(classes []*DigestClass) []*DigestGroup {
+	var (
+		groups []*DigestGroup
+		index  = make(map[string]*DigestGroup)
+	)
+
+	for _, c := range classes {
+		if c.Err != nil {
+			continue
+		}
+
+		g, ok := index[c.Digest]
+		if !ok {
+			g = &DigestGroup{Digest: c.Digest, TemplatizedSQL: c.TemplatizedSQL}
+			index[c.Digest] = g
+			groups = append(groups, g)
+		}
+
+		g.Checksums = append(g.Checksums, c.Checksum)
+		g.Count += c.Count
+		g.QueryTime += c.QueryTime
+	}
+
+	return groups
+}
+
+// extract extracts the example query of the class.
+func (c *DigestClass) extract() {
+	if c.RawSQL == "" {
+		c.Err = errors.New("class has no example query")
+		return
+	}
+
+	e := NewExtractor(c.RawSQL)
+	if c.Err = e.Extract(); c.Err != nil {
+		return
+	}
+
+	c.TemplatizedSQL = e.templatedSQL[0]
+	c.TableInfos = withDefaultSchema(e.tableInfos[0], c.Database)
+	c.Params = e.params[0]
+	c.OpType = e.opType[0]
+	c.Digest = e.hash[0]
+}
+
+// ptDigestReport is the output of pt-query-digest --output json. Metrics are
+// numbers or strings depending on the version.
+type ptDigestReport struct {
+	Classes []struct {
+		Checksum    string      `json:"checksum"`
+		Fingerprint string      `json:"fingerprint"`
+		QueryCount  json.Number `json:"query_count"`
+		Example     struct {
+			Query string `json:"query"`
+		} `json:"example"`
+		Metrics struct {
+			QueryTime struct {
+				Sum json.Number `json:"sum"`
+			} `json:"Query_time"`
+			DB struct {
+				Value string `json:"value"`
+			} `json:"db"`
+		} `json:"metrics"`
+	} `json:"classes"`
+}
+
+func readDigestJSON(r io.Reader) ([]*DigestClass, error) {
+	var report ptDigestReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	classes := make([]*DigestClass, len(report.Classes))
+	for idx, pc := range report.Classes {
+		c := &DigestClass{
+			Checksum:    pc.Checksum,
+			Fingerprint: pc.Fingerprint,
+			Database:    pc.Metrics.DB.Value,
+		}
+		c.Index = idx
+		c.RawSQL = strings.TrimSpace(pc.Example.Query)
+		c.Count, _ = strconv.ParseInt(pc.QueryCount.String(), 10, 64)
+		c.QueryTime, _ = strconv.ParseFloat(pc.Metrics.QueryTime.Sum.String(), 64)
+
+		classes[idx] = c
+	}
+
+	return classes, nil
+}
+
+var (
+	// # Query 1: 0.00 QPS, 0.00x concurrency, ID 0x3A99CC42AEDCCFCD at byte 1234 ___
+	ptQueryHeader = regexp.MustCompile(`^# Query \d+:.*\bID (0x[0-9A-Fa-f]+)`)
+	// # Count         50       2
+	// # Exec time     66    10ms     2ms ...
+	ptAttribute = regexp.MustCompile(`^#\s+(Count|Exec time|Databases)\s+(.*)$`)
+)
+
+// readDigestText reads the default report: a header per class, followed by
+// the attribute table and the example query, terminated by \G.
+func readDigestText(r io.Reader) ([]*DigestClass, error) {
+	var (
+		classes []*DigestClass
+		c       *DigestClass    // current class
+		example strings.Builder // example being read
+		done    bool            // example of the class read
+	)
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+
+		if m := ptQueryHeader.FindStringSubmatch(line); m != nil {
+			c = &DigestClass{Checksum: m[1]}
+			c.Index = len(classes)
+			classes = append(classes, c)
+			example.Reset()
+			done = false
+
+			continue
+		}
+		if c == nil || done {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if m := ptAttribute.FindStringSubmatch(line); m != nil {
+				c.setAttribute(m[1], strings.Fields(m[2]))
+			}
+
+			continue
+		}
+		if example.Len() == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if example.Len() > 0 {
+			example.WriteByte('\n')
+		}
+		example.WriteString(line)
+
+		query, ok := strings.CutSuffix(strings.TrimSpace(example.String()), `\G`)
+		if !ok {
+			continue
+		}
+		example.Reset()
+
+		// 示例前可能有 USE 语句
+		if db, ok := useSchema(query); ok {
+			if c.Database == "" {
+				c.Database = db
+			}
+
+			continue
+		}
+
+		c.RawSQL, done = query, true
+	}
+
+	return classes, s.Err()
+}
+
+// setAttribute sets an attribute of the class from the attribute table.
+func (c *DigestClass) setAttribute(name string, fields []string) {
+	switch name {
+	case "Count":
+		// pct total
+		if len(fields) >= 2 {
+			c.Count, _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	case "Exec time":
+		if len(fields) >= 2 {
+			c.QueryTime = parseDuration(fields[1])
+		}
+	case "Databases":
+		// shop (2/50%), other... 或 shop
+		if len(fields) >= 1 {
+			c.Database = fields[0]
+		}
+	}
+}
+
+// parseDuration parses a pt-query-digest duration, e.g. 10ms, 2s, 500us, in
+// seconds.
+func parseDuration(s string) float64 {
+	units := []struct {
+		suffix string
+		scale  float64
+	}{{"us", 1e-6}, {"ms", 1e-3}, {"s", 1}}
+
+	for _, u := range units {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			f, _ := strconv.ParseFloat(v, 64)
+			return f * u.scale
+		}
+	}
+
+	f, _ := strconv.ParseFloat(s, 64)
+
+	return f
+}
@@ -0,0 +1,145 @@
+package sqlextractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+const ptDigestText = `
+# 150ms user time, 20ms system time, 27.50M rss, 210.42M vsz
+# Overall: 5 total, 2 unique, 0.01 QPS, 0.00x concurrency ________________
+
+# Profile
+# Rank Response time Calls R/Call V/M   Item
+# ==== ============= ===== ====== ===== ===========
+#    1 0.0120 80.0%      3 0.0040  0.00 SELECT users
+#    2 0.0030 20.0%      2 0.0015  0.00 UPDATE users
+
+# Query 1: 0.00 QPS, 0.00x concurrency, ID 0x3A99CC42AEDCCFCD at byte 1234
+# Scores: V/M = 0.00
+# Time range: 2024-02-29T13:05:09 to 2024-02-29T13:15:09
+# Attribute    pct   total     min     max     avg     95%  stddev  median
+# ============ === ======= ======= ======= ======= ======= ======= =======
+# Count         60       3
+# Exec time     80    12ms     3ms     5ms     4ms     5ms     1ms     4ms
+# Databases    shop
+# Query_time distribution
+#   1us
+#   1ms  ################################################################
+# Tables
+#    SHOW TABLE STATUS FROM ` + "`shop`" + ` LIKE 'users'\G
+#    SHOW CREATE TABLE ` + "`shop`.`users`" + `\G
+# EXPLAIN /*!50100 PARTITIONS*/
+SELECT *
+FROM users
+WHERE id = 1\G
+
+# Query 2: 0.00 QPS, 0.00x concurrency, ID 0x813031B8BBC3B329 at byte 5678
+# Attribute    pct   total     min     max     avg     95%  stddev  median
+# ============ === ======= ======= ======= ======= ======= ======= =======
+# Count         40       2
+# Exec time     20     3ms     1ms     2ms     2ms     2ms   500us     2ms
+# Databases    shop (1/50%), crm (1/50%)
+USE ` + "`crm`" + `\G
+UPDATE users SET name = 'a' WHERE id = 2\G
+# Converted for EXPLAIN
+# EXPLAIN /*!50100 PARTITIONS*/
+select  name = 'a' from users where  id = 2\G
+`
+
+func TestReadDigestReport_Text(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	classes, err := ReadDigestReport(strings.NewReader(ptDigestText))
+	as.Nil(err)
+	as.Equal(2, len(classes))
+
+	as.Equal("0x3A99CC42AEDCCFCD", classes[0].Checksum)
+	as.Equal(int64(3), classes[0].Count)
+	as.InDelta(0.012, classes[0].QueryTime, 1e-9)
+	as.Equal("shop", classes[0].Database)
+	as.Equal("SELECT *\nFROM users\nWHERE id = 1", classes[0].RawSQL)
+	as.Nil(classes[0].Err)
+	as.Equal("SELECT * FROM users WHERE id eq ?", classes[0].TemplatizedSQL)
+	as.Equal([]any{int64(1)}, classes[0].Params)
+	as.Equal("shop", classes[0].TableInfos[0].Schema())
+	as.Equal(models.SQLOperationSelect, classes[0].OpType)
+	as.Equal(defaultHash([]byte(classes[0].TemplatizedSQL)), classes[0].Digest)
+
+	as.Equal(1, classes[1].Index)
+	as.Equal(int64(2), classes[1].Count)
+	as.InDelta(0.003, classes[1].QueryTime, 1e-9)
+	as.Equal("shop", classes[1].Database)
+	as.Equal("UPDATE users SET name = 'a' WHERE id = 2", classes[1].RawSQL)
+	as.Equal("UPDATE users SET name eq ? WHERE id eq ?", classes[1].TemplatizedSQL)
+	as.Equal(models.SQLOperationUpdate, classes[1].OpType)
+}
+
+const ptDigestJSON = `{
+   "classes" : [
+      {
+         "checksum" : "3A99CC42AEDCCFCD",
+         "fingerprint" : "select * from users where id = ?",
+         "query_count" : 3,
+         "example" : { "query" : "SELECT * FROM users WHERE id = 1", "ts" : "2024-02-29 13:05:09" },
+         "metrics" : {
+            "Query_time" : { "sum" : "0.012000", "avg" : "0.004000" },
+            "db" : { "value" : "shop" }
+         }
+      },
+      {
+         "checksum" : "5F4D1A0C77B93E21",
+         "fingerprint" : "select * from users where id=?",
+         "query_count" : 2,
+         "example" : { "query" : "select * from users where id=7" },
+         "metrics" : { "Query_time" : { "sum" : 0.5 } }
+      },
+      {
+         "checksum" : "0D0E0A0D0B0E0E0F",
+         "fingerprint" : "selec ?",
+         "query_count" : 1,
+         "example" : { "query" : "selec 1" }
+      }
+   ],
+   "global" : { "query_count" : 6 }
+}`
+
+func TestReadDigestReport_JSON(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	classes, err := ReadDigestReport(strings.NewReader("\n" + ptDigestJSON))
+	as.Nil(err)
+	as.Equal(3, len(classes))
+
+	as.Equal("3A99CC42AEDCCFCD", classes[0].Checksum)
+	as.Equal("select * from users where id = ?", classes[0].Fingerprint)
+	as.Equal(int64(3), classes[0].Count)
+	as.InDelta(0.012, classes[0].QueryTime, 1e-9)
+	as.Equal("shop", classes[0].TableInfos[0].Schema())
+	as.Equal("SELECT * FROM users WHERE id eq ?", classes[0].TemplatizedSQL)
+
+	as.InDelta(0.5, classes[1].QueryTime, 1e-9)
+	as.Equal("", classes[1].TableInfos[0].Schema())
+	as.NotNil(classes[2].Err)
+
+	// both fingerprints have the same template
+	groups := GroupDigestClasses(classes)
+	as.Equal(1, len(groups))
+	as.Equal("SELECT * FROM users WHERE id eq ?", groups[0].TemplatizedSQL)
+	as.Equal([]string{"3A99CC42AEDCCFCD", "5F4D1A0C77B93E21"}, groups[0].Checksums)
+	as.Equal(int64(5), groups[0].Count)
+	as.InDelta(0.512, groups[0].QueryTime, 1e-9)
+
+	classes, err = ReadDigestReport(strings.NewReader(""))
+	as.Nil(err)
+	as.Empty(classes)
+
+	_, err = ReadDigestReport(strings.NewReader(`{"classes": [`))
+	as.NotNil(err)
+}
@@ -0,0 +1,235 @@
+//go:build !js
+
+package sqlextractor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DigestListMode is whether a DigestList lists the allowed or the denied
+// digests.
+type DigestListMode int
+
+const (
+	// AllowList allows only the listed digests, e.g. the known-good statements
+	// of prepared statement allowlisting.
+	AllowList DigestListMode = iota
+	// DenyList denies the listed digests, e.g. blocked statements.
+	DenyList
+)
+
+// Verdict is the decision of a DigestList on a SQL.
+type Verdict struct {
+	Allowed bool     // all the statements are allowed
+	Digests []string // digests of the statements, in order
+	Denied  []string // digests of the denied statements, in order
+}
+
+// DigestList is a list of allowed or denied digests loaded from a file or an
+// HTTP endpoint, reloaded periodically by Run. It is safe for concurrent use,
+// a reload replaces the list atomically.
+//
+// The list has a digest per line, the first field of the line, so the lines
+// may have comments, e.g. the templatized SQL. Empty lines and lines starting
+// with # are ignored.
+//
+// DigestList is not available in js builds, e.g. the WebAssembly build.
+type DigestList struct {
+	mode     DigestListMode
+	source   string // file path or http(s) URL
+	interval time.Duration
+	client   *http.Client
+	opts     []ExtractorOption
+	onError  func(error)
+
+	digests atomic.Pointer[map[string]struct{}]
+}
+
+// DigestListOption configures the DigestList.
+type DigestListOption func(*DigestList)
+
+// WithReloadInterval sets the interval of the reloads of Run, default is a
+// minute.
+func WithReloadInterval(d time.Duration) DigestListOption {
+	return func(l *DigestList) {
+		if d > 0 {
+			l.interval = d
+		}
+	}
+}
+
+// WithDigestListClient sets the HTTP client loading the list from an HTTP
+// endpoint, http.DefaultClient by default.
+func WithDigestListClient(client *http.Client) DigestListOption {
+	return func(l *DigestList) { l.client = client }
+}
+
+// WithDigestListExtractor sets the options of the extractor digesting the SQL
+// of Verdict, e.g. a dialect, so the digests match those of the list.
+func WithDigestListExtractor(opts ...ExtractorOption) DigestListOption {
+	return func(l *DigestList) { l.opts = opts }
+}
+
+// WithReloadError sets the function called with the errors of the reloads of
+// Run, the previous list is kept on errors.
+func WithReloadError(fn func(error)) DigestListOption {
+	return func(l *DigestList) { l.onError = fn }
+}
+
+// NewDigestList creates a DigestList of the source, a file path or an http(s)
+// URL, and loads it.
+//
+// Example:
+//
+//	list, err := NewDigestList("/etc/sql/allowlist.txt", AllowList)
+//	if err != nil {
+//	  // handle error
+//	}
+//	go list.Run(ctx)
+//
+//	if v, err := list.Verdict(sql); err != nil || !v.Allowed {
+//	  // reject the query
+//	}
+func NewDigestList(source string, mode DigestListMode, opts ...DigestListOption) (*DigestList, error) {
+	l := &DigestList{
+		mode:     mode,
+		source:   source,
+		interval: time.Minute,
+		client:   http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if err := l.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Mode returns the mode of the list.
+func (l *DigestList) Mode() DigestListMode { return l.mode }
+
+// Len returns the number of digests of the list.
+func (l *DigestList) Len() int { return len(*l.digests.Load()) }
+
+// Contains reports whether the digest is in the list.
+func (l *DigestList) Contains(digest string) bool {
+	_, ok := (*l.digests.Load())[digest]
+	return ok
+}
+
+// Allowed reports whether the statement of the digest is allowed.
+func (l *DigestList) Allowed(digest string) bool {
+	return l.Contains(digest) == (l.mode == AllowList)
+}
+
+// Verdict parses the SQL, digests its statements and checks them against the
+// list. The SQL is allowed if all its statements are allowed; a SQL which can
+// not be parsed returns the error.
+func (l *DigestList) Verdict(sql string) (Verdict, error) {
+	var (
+		digests []string
+		err     error
+	)
+	if len(l.opts) == 0 {
+		digests, err = DigestOnly(sql)
+	} else {
+		e := NewExtractor(sql, l.opts...)
+		if err = e.Extract(); err == nil {
+			digests = e.TemplatizedSQLHash()
+		}
+	}
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	v := Verdict{Allowed: true, Digests: digests}
+	for _, digest := range digests {
+		if !l.Allowed(digest) {
+			v.Allowed = false
+			v.Denied = append(v.Denied, digest)
+		}
+	}
+
+	return v, nil
+}
+
+// Reload loads the list from its source, and replaces the list if it is
+// loaded.
+func (l *DigestList) Reload(ctx context.Context) error {
+	var r io.ReadCloser
+	if strings.HasPrefix(l.source, "http://") || strings.HasPrefix(l.source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.source, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := l.client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("load digest list %s: %s", l.source, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(l.source)
+		if err != nil {
+			return err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	digests, err := readDigests(r)
+	if err != nil {
+		return fmt.Errorf("load digest list %s: %w", l.source, err)
+	}
+	l.digests.Store(&digests)
+
+	return nil
+}
+
+// Run reloads the list at the reload interval until ctx is done.
+func (l *DigestList) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Reload(ctx); err != nil && l.onError != nil && ctx.Err() == nil {
+				l.onError(err)
+			}
+		}
+	}
+}
+
+// readDigests reads the digests of a list, the first field of each line.
+func readDigests(r io.Reader) (map[string]struct{}, error) {
+	digests := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		digests[strings.ToLower(fields[0])] = struct{}{}
+	}
+
+	return digests, scanner.Err()
+}
@@ -0,0 +1,114 @@
+//go:build !js
+
+package sqlextractor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestList(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	digests, err := DigestOnly("SELECT * FROM users WHERE id = 1; DELETE FROM users")
+	as.Nil(err)
+
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	as.Nil(os.WriteFile(path, []byte("# known-good statements\n\n"+digests[0]+" SELECT * FROM users WHERE id eq ?\n"), 0o644))
+
+	allow, err := NewDigestList(path, AllowList)
+	as.Nil(err)
+	as.Equal(AllowList, allow.Mode())
+	as.Equal(1, allow.Len())
+	as.True(allow.Contains(digests[0]))
+
+	v, err := allow.Verdict("select * from users where id = 42")
+	as.Nil(err)
+	as.Equal(Verdict{Allowed: true, Digests: digests[:1]}, v)
+
+	v, err = allow.Verdict("SELECT * FROM users WHERE id = 2; DELETE FROM users")
+	as.Nil(err)
+	as.Equal(Verdict{Digests: digests, Denied: digests[1:]}, v)
+
+	_, err = allow.Verdict("SELEC")
+	as.NotNil(err)
+
+	deny, err := NewDigestList(path, DenyList)
+	as.Nil(err)
+	v, err = deny.Verdict("SELECT * FROM users WHERE id = 2; DELETE FROM users")
+	as.Nil(err)
+	as.Equal(Verdict{Digests: digests, Denied: digests[:1]}, v)
+
+	// reload replaces the list
+	as.Nil(os.WriteFile(path, []byte(digests[1]+"\n"), 0o644))
+	as.Nil(allow.Reload(context.Background()))
+	as.False(allow.Allowed(digests[0]))
+	as.True(allow.Allowed(digests[1]))
+
+	// the dialect of the list
+	e := NewExtractor("SELECT x FROM t, UNNEST(t.arr) AS x", WithBigQuery())
+	as.Nil(e.Extract())
+	as.Nil(os.WriteFile(path, []byte(e.TemplatizedSQLHash()[0]+"\n"), 0o644))
+	bigquery, err := NewDigestList(path, AllowList, WithDigestListExtractor(WithBigQuery()))
+	as.Nil(err)
+	v, err = bigquery.Verdict("SELECT x FROM t, UNNEST(t.arr) AS x")
+	as.Nil(err)
+	as.True(v.Allowed)
+
+	_, err = NewDigestList(filepath.Join(t.TempDir(), "missing.txt"), AllowList)
+	as.NotNil(err)
+}
+
+func TestDigestList_HTTP(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	digests, err := DigestOnly("SELECT 1; SELECT * FROM `p.d.t`")
+	as.Nil(err)
+
+	var (
+		requests atomic.Int32
+		fail     atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(digests[requests.Add(1)%2] + "\n"))
+	}))
+	defer srv.Close()
+
+	errs := make(chan error, 10)
+	list, err := NewDigestList(srv.URL, DenyList, WithReloadInterval(10*time.Millisecond),
+		WithReloadError(func(err error) { errs <- err }))
+	as.Nil(err)
+	as.True(list.Contains(digests[1]))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		list.Run(ctx)
+		close(done)
+	}()
+
+	as.Eventually(func() bool { return list.Contains(digests[0]) }, time.Second, 5*time.Millisecond)
+
+	// failed reloads keep the list
+	fail.Store(true)
+	err = <-errs
+	as.ErrorContains(err, "503")
+	as.Equal(1, list.Len())
+
+	cancel()
+	<-done
+}
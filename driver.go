@@ -0,0 +1,71 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+// DriverParams returns the params of each statement converted to the values
+// accepted by database/sql drivers (int64, float64, string, []byte, time.Time
+// and nil) instead of the parser types, so they can be passed to Exec or Query
+// along with a template rendered with ? placeholders and standard operators.
+// It should be called after Extract.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM orders WHERE amount > 12.50")
+//	_ = extractor.Extract()
+//	params, err := extractor.DriverParams()
+//	// params: [["12.50"]], the decimal keeps its precision
+func (e *Extractor) DriverParams() ([][]any, error) {
+	params := make([][]any, len(e.params))
+	for idx := range e.params {
+		params[idx] = make([]any, len(e.params[idx]))
+		for jdx := range e.params[idx] {
+			val, ok := driverValue(e.params[idx][jdx])
+			if !ok {
+				return nil, fmt.Errorf("statement %d: param %d: unsupported type %T",
+					idx+1, jdx+1, e.params[idx][jdx])
+			}
+			params[idx][jdx] = val
+		}
+	}
+
+	return params, nil
+}
+
+// driverValue converts the parameter to a database/sql driver value:
+//   - uint64 -> int64 if it fits, otherwise the decimal string
+//   - float32 -> float64
+//   - decimal -> string, keeping its precision
+//   - bit / hex literal -> []byte
+//
+// ok is false if the type can not be converted.
+func driverValue(param any) (any, bool) {
+	switch val := param.(type) {
+	case nil, int64, float64, string, []byte, time.Time:
+		return val, true
+
+	case uint64:
+		if val <= math.MaxInt64 {
+			return int64(val), true
+		}
+
+		return strconv.FormatUint(val, 10), true
+
+	case float32:
+		return float64(val), true
+
+	case *test_driver.MyDecimal:
+		return val.String(), true
+
+	case test_driver.BinaryLiteral:
+		return []byte(val), true
+	}
+
+	return param, false
+}
@@ -0,0 +1,74 @@
+package sqlextractor
+
+import (
+	"database/sql/driver"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_DriverParams(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM orders WHERE amount > 12.50 AND flags = b'101' AND id IN (1, 18446744073709551615) " +
+		"AND note = 'x' AND rate < 1.5e3 AND deleted_at IS NULL LIMIT 10; INSERT INTO t (a) VALUES (NULL)")
+	as.Nil(extractor.Extract())
+
+	params, err := extractor.DriverParams()
+	as.Nil(err)
+	as.Equal([][]any{
+		{"12.50", []byte{0x05}, int64(1), "18446744073709551615", "x", float64(1500), int64(10)},
+		{nil},
+	}, params)
+	for idx := range params {
+		for jdx := range params[idx] {
+			as.True(driver.IsValue(params[idx][jdx]), "%T", params[idx][jdx])
+		}
+	}
+
+	params, err = NewExtractor("SELECT 1").DriverParams()
+	as.Nil(err)
+	as.Empty(params)
+
+	extractor = NewExtractor("")
+	extractor.params = [][]any{{struct{}{}}}
+	_, err = extractor.DriverParams()
+	as.EqualError(err, "statement 1: param 1: unsupported type struct {}")
+}
+
+func TestDriverValue(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	now := time.Now()
+	dec := new(test_driver.MyDecimal)
+	as.Nil(dec.FromString([]byte("3.14")))
+
+	tests := []struct {
+		param any
+		want  any
+	}{
+		{int64(1), int64(1)},
+		{uint64(1), int64(1)},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{float32(1.5), float64(1.5)},
+		{dec, "3.14"},
+		{test_driver.BinaryLiteral{0x1f}, []byte{0x1f}},
+		{"kyden", "kyden"},
+		{[]byte("kyden"), []byte("kyden")},
+		{now, now},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		val, ok := driverValue(tt.param)
+		as.True(ok, "%T", tt.param)
+		as.Equal(tt.want, val)
+	}
+
+	_, ok := driverValue(struct{}{})
+	as.False(ok)
+}
@@ -0,0 +1,124 @@
+package sqlextractor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// dumpExtractor collapses extended INSERTs, so their template does not depend
+// on the number of rows, and only their first row is visited.
+var dumpExtractor = packageExtractor(extract.WithCollapsedValues())
+
+// DumpTable is the summary of a table in a dump.
+type DumpTable struct {
+	Schema     string
+	Table      string
+	Rows       int64    // rows inserted by INSERT ... VALUES
+	Statements int      // statements using the table
+	Templates  []string // distinct templates, in order of appearance
+}
+
+// DumpReport is the summary of a dump.
+type DumpReport struct {
+	Tables     []*DumpTable // tables in order of appearance
+	Statements int          // statements analyzed
+	Skipped    int          // header statements: conditional comments, SET, USE, LOCK and UNLOCK TABLES
+	Errors     []string     // statements which can not be parsed, e.g. stored routines
+}
+
+// AnalyzeDump reads a mysqldump output from r, and reports the row count and
+// templates of each table. The statements are read one at a time, see
+// ExtractStream.
+//
+// Conditional comments (/*!40101 ... */), SET, USE and LOCK/UNLOCK TABLES
+// headers are skipped, USE changes the schema of unqualified tables. Extended
+// INSERTs are collapsed to their first row, e.g. INSERT INTO t VALUES (?, ?).
+func AnalyzeDump(r io.Reader) (*DumpReport, error) {
+	var (
+		s      = newStmtScanner(r)
+		report = &DumpReport{}
+		tables = make(map[string]*DumpTable)
+		seen   = make(map[string]struct{}) // table + template
+		schema string                      // current schema, set by USE
+	)
+
+	for idx := 0; ; idx++ {
+		stmt, err := s.next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if db, ok := useSchema(stmt); ok {
+			schema = db
+			report.Skipped++
+
+			continue
+		}
+		if isDumpHeader(stmt) {
+			report.Skipped++
+			continue
+		}
+
+		results, err := dumpExtractor.ExtractDump(stmt)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("statement %d: %v", idx+1, err))
+			continue
+		}
+
+		for _, res := range results {
+			report.Statements++
+
+			for _, ti := range res.TableInfos {
+				db := ti.Schema()
+				if db == "" {
+					db = schema
+				}
+
+				key := db + "." + ti.TableName()
+				t, ok := tables[key]
+				if !ok {
+					t = &DumpTable{Schema: db, Table: ti.TableName()}
+					tables[key] = t
+					report.Tables = append(report.Tables, t)
+				}
+
+				t.Statements++
+				t.Rows += int64(res.Rows)
+				if _, ok := seen[key+"\x00"+res.TemplatizedSQL]; !ok {
+					seen[key+"\x00"+res.TemplatizedSQL] = struct{}{}
+					t.Templates = append(t.Templates, res.TemplatizedSQL)
+				}
+			}
+		}
+	}
+}
+
+// dumpHeaders are the prefixes of the header statements of a dump.
+var dumpHeaders = []string{"/*!", "SET ", "LOCK TABLES ", "UNLOCK TABLES"}
+
+// isDumpHeader reports whether the statement is a header of a dump.
+func isDumpHeader(stmt string) bool {
+	for _, prefix := range dumpHeaders {
+		if len(stmt) >= len(prefix) && strings.EqualFold(stmt[:len(prefix)], prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// useSchema returns the schema of a USE statement.
+func useSchema(stmt string) (string, bool) {
+	if len(stmt) < 4 || !strings.EqualFold(stmt[:4], "USE ") {
+		return "", false
+	}
+
+	return strings.Trim(strings.TrimSpace(stmt[4:]), "`"), true
+}
@@ -0,0 +1,66 @@
+package sqlextractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mysqldump = `-- MySQL dump 10.13  Distrib 8.0.36, for Linux (x86_64)
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!50503 SET NAMES utf8mb4 */;
+SET @@SESSION.SQL_LOG_BIN= 0;
+
+USE ` + "`shop`" + `;
+
+DROP TABLE IF EXISTS ` + "`users`" + `;
+CREATE TABLE ` + "`users`" + ` (
+  ` + "`id`" + ` int NOT NULL,
+  ` + "`name`" + ` varchar(64) DEFAULT NULL,
+  PRIMARY KEY (` + "`id`" + `)
+) ENGINE=InnoDB;
+
+LOCK TABLES ` + "`users`" + ` WRITE;
+/*!40000 ALTER TABLE ` + "`users`" + ` DISABLE KEYS */;
+INSERT INTO ` + "`users`" + ` VALUES (1,'a'),(2,'b;c'),(3,'d');
+INSERT INTO ` + "`users`" + ` VALUES (4,'e');
+/*!40000 ALTER TABLE ` + "`users`" + ` ENABLE KEYS */;
+UNLOCK TABLES;
+
+INSERT INTO ` + "`log`.`events`" + ` VALUES (1);
+
+DELIMITER ;;
+CREATE PROCEDURE p() BEGIN SELECT 1; END ;;
+DELIMITER ;
+NOT SQL AT ALL;
+`
+
+func TestAnalyzeDump(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	report, err := AnalyzeDump(strings.NewReader(mysqldump))
+	as.Nil(err)
+	as.Equal(8, report.Skipped)
+	as.Equal(6, report.Statements) // DDL and stored routines are not templatized
+
+	as.Equal([]*DumpTable{
+		{
+			Schema:     "shop",
+			Table:      "users",
+			Rows:       4,
+			Statements: 2,
+			Templates:  []string{"INSERT INTO users VALUES (?, ?)"},
+		},
+		{
+			Schema:     "log",
+			Table:      "events",
+			Rows:       1,
+			Statements: 1,
+			Templates:  []string{"INSERT INTO log.events VALUES (?)"},
+		},
+	}, report.Tables)
+	as.Equal(1, len(report.Errors))
+	as.Contains(report.Errors[0], "statement 15:")
+}
@@ -0,0 +1,181 @@
+package sqlextractor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// SchemaVersion is the version of the Envelope JSON schema, described by
+// schema/envelope.v1.json.
+//
+// The schema evolves in a backward compatible way: fields may be added, but
+// are never removed, renamed or change their type or meaning within the same
+// version; such changes bump SchemaVersion. Consumers should ignore unknown
+// fields.
+const SchemaVersion = 1
+
+// DialectMySQL is the dialect of the built-in parser, MySQL compatible (TiDB).
+const DialectMySQL = "mysql"
+
+// Envelope is the canonical, versioned JSON result, so consumers in other
+// languages can build against a contract rather than Go struct shapes.
+type Envelope struct {
+	SchemaVersion int                 `json:"schema_version"`
+	Dialect       string              `json:"dialect"`
+	Statements    []EnvelopeStatement `json:"statements"`
+	Warnings      []string            `json:"warnings"`
+	Error         string              `json:"error,omitempty"` // set if the SQL can not be extracted
+	Tags          Tags                `json:"tags,omitempty"`  // caller metadata, see SetTags
+}
+
+// EnvelopeStatement is the result of a statement in the Envelope.
+type EnvelopeStatement struct {
+	TemplatizedSQL string          `json:"templatized_sql"`
+	Digest         string          `json:"digest"` // sha256 of the templatized SQL, hex encoded
+	OpType         string          `json:"op_type"`
+	Tables         []EnvelopeTable `json:"tables"`
+	Params         []any           `json:"params"` // see envelopeParams
+
+	// TruncatedParams are the params truncated by WithMaxParamBytes or skipped
+	// by WithSkipBlobParams, whose params are their prefix.
+	TruncatedParams []EnvelopeTruncatedParam `json:"truncated_params,omitempty"`
+}
+
+// EnvelopeTruncatedParam is a param of the Envelope replaced by a
+// TruncatedParam.
+type EnvelopeTruncatedParam struct {
+	Index int  `json:"index"`          // index of the param in params
+	Size  int  `json:"size"`           // size of the value in bytes
+	Blob  bool `json:"blob,omitempty"` // skipped blob-like literal
+}
+
+// EnvelopeTable is a table used by a statement in the Envelope.
+type EnvelopeTable struct {
+	Server    string `json:"server,omitempty"`  // linked server of a four-part name
+	Catalog   string `json:"catalog,omitempty"` // e.g. the BigQuery project, see WithBigQuery
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	Tenant    string `json:"tenant,omitempty"`    // see WithTenantSchema
+	Temporary bool   `json:"temporary,omitempty"` // created by CREATE TEMPORARY TABLE in the same input
+}
+
+// String returns the table name with schema, so the tables read well in Diff
+// whatever fields are added.
+func (t EnvelopeTable) String() string {
+	if t.Server != "" || t.Catalog != "" {
+		return QualifiedName{Server: t.Server, Catalog: t.Catalog, Schema: t.Schema, Table: t.Table}.String()
+	}
+	if t.Schema == "" {
+		return t.Table
+	}
+
+	return t.Schema + "." + t.Table
+}
+
+// NewEnvelope creates an empty Envelope of the current SchemaVersion.
+func NewEnvelope() *Envelope {
+	return &Envelope{
+		SchemaVersion: SchemaVersion,
+		Dialect:       DialectMySQL,
+		Statements:    []EnvelopeStatement{},
+		Warnings:      []string{},
+	}
+}
+
+// Envelope returns the results as an Envelope, it should be called after
+// Extract.
+func (e *Extractor) Envelope() *Envelope {
+	env := NewEnvelope()
+	env.Statements = make([]EnvelopeStatement, len(e.templatedSQL))
+	env.Warnings = append(env.Warnings, e.warnings...)
+	env.Tags = e.tags
+	env.Dialect = e.Dialect()
+
+	hash := e.TemplatizedSQLHash()
+	for idx := range e.templatedSQL {
+		tables := make([]EnvelopeTable, len(e.tableInfos[idx]))
+		for i, ti := range e.tableInfos[idx] {
+			tables[i] = EnvelopeTable{
+				Server:    ti.Server(),
+				Catalog:   ti.Catalog(),
+				Schema:    ti.Schema(),
+				Table:     ti.TableName(),
+				Tenant:    ti.Tenant(),
+				Temporary: ti.Temporary(),
+			}
+		}
+
+		params, truncated := envelopeParams(e.params[idx])
+		env.Statements[idx] = EnvelopeStatement{
+			TemplatizedSQL:  e.templatedSQL[idx],
+			Digest:          hash[idx],
+			OpType:          e.opType[idx].String(),
+			Tables:          tables,
+			Params:          params,
+			TruncatedParams: truncated,
+		}
+	}
+
+	return env
+}
+
+// envelopeParams converts the params to the JSON values of the schema, the
+// parser types are converted by driverValue:
+//   - decimal -> string, keeping its precision, e.g. "1.50"
+//   - bit / hex literal -> hex string, e.g. "0x0a"
+//   - TruncatedParam -> its prefix, reported in the truncated params
+//
+// The other values are kept as is.
+func envelopeParams(params []any) ([]any, []EnvelopeTruncatedParam) {
+	var (
+		converted = make([]any, len(params))
+		truncated []EnvelopeTruncatedParam
+	)
+	for idx := range params {
+		if tp, ok := params[idx].(TruncatedParam); ok {
+			converted[idx] = tp.Prefix
+			truncated = append(truncated, EnvelopeTruncatedParam{Index: idx, Size: tp.Size, Blob: tp.Blob})
+			continue
+		}
+
+		val, ok := driverValue(params[idx])
+		if !ok {
+			converted[idx] = params[idx]
+			continue
+		}
+
+		if b, ok := val.([]byte); ok {
+			val = "0x" + hex.EncodeToString(b)
+		}
+		converted[idx] = val
+	}
+
+	return converted, truncated
+}
+
+// ExtractEnvelope extracts the SQL and returns the results as an Envelope,
+// the extraction error is reported in its Error field.
+func ExtractEnvelope(sql string) *Envelope {
+	e := NewExtractor(sql)
+	if err := e.Extract(); err != nil {
+		env := e.Envelope()
+		env.Error = err.Error()
+
+		return env
+	}
+
+	return e.Envelope()
+}
+
+// JSON returns the JSON encoding of the Envelope.
+func (env *Envelope) JSON() []byte {
+	b, err := json.Marshal(env)
+	if err != nil {
+		// 参数无法编码时（例如 NaN），仍然返回合法的 JSON
+		failed := NewEnvelope()
+		failed.Error = err.Error()
+		b, _ = json.Marshal(failed)
+	}
+
+	return b
+}
@@ -0,0 +1,222 @@
+package sqlextractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractEnvelope(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	env := ExtractEnvelope("SELECT * FROM db_1.users /*+ USE_INDEX(users, a) */ WHERE id = 1; SHOW TABLES")
+	as.JSONEq(`{
+		"schema_version": 1,
+		"dialect": "mysql",
+		"statements": [
+			{
+				"templatized_sql": "SELECT * FROM db_?.users WHERE id eq ?",
+				"digest": "`+defaultHash([]byte("SELECT * FROM db_?.users WHERE id eq ?"))+`",
+				"op_type": "SELECT",
+				"tables": [{"schema": "db_1", "table": "users"}],
+				"params": [1]
+			},
+			{
+				"templatized_sql": "SHOW TABLES",
+				"digest": "`+defaultHash([]byte("SHOW TABLES"))+`",
+				"op_type": "SHOW",
+				"tables": [],
+				"params": []
+			}
+		],
+		"warnings": ["`+env.Warnings[0]+`"]
+	}`, string(env.JSON()))
+
+	as.JSONEq(`{"schema_version": 1, "dialect": "mysql", "statements": [], "warnings": [], "error": "empty SQL statement"}`,
+		string(ExtractEnvelope("").JSON()))
+}
+
+func TestEnvelope_Params(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	env := ExtractEnvelope("SELECT * FROM t WHERE price = 1.50 AND flags = b'1010' AND id = 0x0AFF AND name = 'x'")
+	as.Empty(env.Error)
+
+	var decoded struct {
+		Statements []struct {
+			Params []any `json:"params"`
+		} `json:"statements"`
+	}
+	as.Nil(json.Unmarshal(env.JSON(), &decoded))
+	as.Equal([]any{"1.50", "0x0a", "0x0aff", "x"}, decoded.Statements[0].Params)
+}
+
+// TestEnvelope_Schema guards the contract: the JSON fields of the envelope
+// must match the properties of the published schema.
+func TestEnvelope_Schema(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	b, err := os.ReadFile("schema/envelope.v1.json")
+	as.Nil(err)
+
+	type object struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]object          `json:"$defs"`
+	}
+	var schema object
+	as.Nil(json.Unmarshal(b, &schema))
+
+	var version struct {
+		Const int `json:"const"`
+	}
+	as.Nil(json.Unmarshal(schema.Properties["schema_version"], &version))
+	as.Equal(SchemaVersion, version.Const)
+
+	check := func(v any, required []string, properties []string) {
+		var fields, requiredFields []string
+		for _, f := range reflect.VisibleFields(reflect.TypeOf(v)) {
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			fields = append(fields, name)
+			if opts != "omitempty" {
+				requiredFields = append(requiredFields, name)
+			}
+		}
+
+		slices.Sort(fields)
+		slices.Sort(properties)
+		slices.Sort(requiredFields)
+		slices.Sort(required)
+		as.Equal(properties, fields, "%T", v)
+		as.Equal(required, requiredFields, "%T", v)
+	}
+
+	keys := func(m map[string]json.RawMessage) []string {
+		var ks []string
+		for k := range m {
+			ks = append(ks, k)
+		}
+		return ks
+	}
+
+	check(Envelope{}, schema.Required, keys(schema.Properties))
+	check(EnvelopeStatement{}, schema.Defs["statement"].Required, keys(schema.Defs["statement"].Properties))
+	check(EnvelopeTable{}, schema.Defs["table"].Required, keys(schema.Defs["table"].Properties))
+	check(EnvelopeTruncatedParam{}, schema.Defs["truncated_param"].Required,
+		keys(schema.Defs["truncated_param"].Properties))
+}
+
+// TestEnvelope_Validate validates envelopes, with truncated params, against the
+// published schema.
+func TestEnvelope_Validate(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	b, err := os.ReadFile("schema/envelope.v1.json")
+	as.Nil(err)
+	var schema map[string]any
+	as.Nil(json.Unmarshal(b, &schema))
+
+	e := NewExtractor("INSERT INTO files VALUES (1.50, 'kyden sql', X'0AFF0AFF', NULL, true)",
+		WithMaxParamBytes(3), WithSkipBlobParams(4))
+	as.Nil(e.Extract())
+	env := e.Envelope()
+	as.Equal([]any{"1.50", "kyd", "", nil, int64(1)}, env.Statements[0].Params)
+	as.Equal([]EnvelopeTruncatedParam{{Index: 1, Size: 9}, {Index: 2, Size: 4, Blob: true}},
+		env.Statements[0].TruncatedParams)
+
+	for _, env := range []*Envelope{env, ExtractEnvelope("SELECT * FROM t WHERE a = 1"), ExtractEnvelope("")} {
+		var doc any
+		as.Nil(json.Unmarshal(env.JSON(), &doc))
+		as.Empty(validateSchema(schema, schema, doc, "$"))
+	}
+}
+
+// validateSchema returns the violations of the JSON document against the JSON
+// schema keywords used by the envelope schema.
+func validateSchema(root, schema map[string]any, doc any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		defs, _ := root["$defs"].(map[string]any)
+		def, _ := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		return validateSchema(root, def, doc, path)
+	}
+	if c, ok := schema["const"]; ok && c != doc {
+		return []string{path + ": not the const"}
+	}
+
+	if typ, ok := schema["type"]; ok {
+		types, isList := typ.([]any)
+		if !isList {
+			types = []any{typ}
+		}
+		if !slices.ContainsFunc(types, func(typ any) bool { return jsonType(doc, typ.(string)) }) {
+			return []string{path + ": not of type " + fmt.Sprint(typ)}
+		}
+	}
+
+	var violations []string
+	switch doc := doc.(type) {
+	case map[string]any:
+		for _, name := range schema["required"].([]any) {
+			if _, ok := doc[name.(string)]; !ok {
+				violations = append(violations, path+": missing "+name.(string))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, value := range doc {
+			if property, ok := properties[name].(map[string]any); ok {
+				violations = append(violations, validateSchema(root, property, value, path+"."+name)...)
+			} else if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+				violations = append(violations, validateSchema(root, additional, value, path+"."+name)...)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for idx, value := range doc {
+				violations = append(violations, validateSchema(root, items, value, fmt.Sprintf("%s[%d]", path, idx))...)
+			}
+		}
+	}
+
+	return violations
+}
+
+// jsonType reports whether the decoded JSON value is of the JSON schema type.
+func jsonType(v any, typ string) bool {
+	switch v := v.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || (typ == "integer" && v == float64(int64(v)))
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	}
+
+	return false
+}
+
+func TestEnvelope_TemporaryTables(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	env := ExtractEnvelope("CREATE TEMPORARY TABLE tmp LIKE users; SELECT * FROM tmp WHERE id = 1")
+	as.Equal([]EnvelopeTable{{Table: "tmp", Temporary: true}, {Table: "users"}}, env.Statements[0].Tables)
+	as.Equal([]EnvelopeTable{{Table: "tmp", Temporary: true}}, env.Statements[1].Tables)
+	as.Equal("CREATE", env.Statements[0].OpType)
+	as.Contains(string(env.JSON()), `"tables":[{"schema":"","table":"tmp","temporary":true},{"schema":"","table":"users"}]`)
+}
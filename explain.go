@@ -0,0 +1,62 @@
+package sqlextractor
+
+import (
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// explainable is the set of operation types which can be explained.
+var explainable = map[models.SQLOpType]struct{}{
+	models.SQLOperationSelect: {},
+	models.SQLOperationInsert: {},
+	models.SQLOperationUpdate: {},
+	models.SQLOperationDelete: {},
+}
+
+// Plans returns the plan summaries attached by Explain. The plan of a statement
+// which can not be explained is nil.
+func (e *Extractor) Plans() [][]*models.PlanSummary { return e.plans }
+
+// planShape returns the normalized plan shape, which only keeps the table,
+// access type and key used of each plan row, rows estimate is ignored.
+//
+// e.g. users:const:PRIMARY|orders:ALL:
+func planShape(plans []*models.PlanSummary) string {
+	var builder strings.Builder
+	for idx := range plans {
+		if idx > 0 {
+			builder.WriteString("|")
+		}
+
+		builder.WriteString(plans[idx].Table())
+		builder.WriteString(":")
+		builder.WriteString(plans[idx].AccessType())
+		builder.WriteString(":")
+		builder.WriteString(plans[idx].Key())
+	}
+
+	return builder.String()
+}
+
+// PlanHash returns the hash of the normalized plan shape of each statement, so
+// plan regressions (e.g. index no longer used) can be detected even when the
+// templatized SQL hash is unchanged. The hash of a statement which was not
+// explained is empty.
+//
+// Default hash function is sha256.
+func (e *Extractor) PlanHash(fn ...func([]byte) string) []string {
+	hashFn := defaultHash
+	if len(fn) > 0 {
+		hashFn = fn[0]
+	}
+
+	hash := make([]string, len(e.plans))
+	for idx := range e.plans {
+		if e.plans[idx] != nil {
+			hash[idx] = hashFn([]byte(planShape(e.plans[idx])))
+		}
+	}
+
+	return hash
+}
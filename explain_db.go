@@ -0,0 +1,114 @@
+//go:build !js
+
+package sqlextractor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// Explain runs EXPLAIN on each original statement through db, and attaches the
+// parsed plan summary (access type, key used, rows estimate) to the extractor.
+// It should be called after Extract, only SELECT, INSERT, UPDATE and DELETE
+// statements are explained, not the EXECUTE statements. Explain is not available in js builds, e.g. the
+// WebAssembly build.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id = 1")
+//	if err := extractor.Extract(); err != nil {
+//	  // handle error
+//	}
+//	if err := extractor.Explain(ctx, db); err != nil {
+//	  // handle error
+//	}
+//	fmt.Println(extractor.Plans())
+func (e *Extractor) Explain(ctx context.Context, db *sql.DB) error {
+	internal := e.internal()
+	stmts, err := internal.Split(e.rawSQL)
+	if err != nil {
+		return err
+	}
+	kinds, err := internal.Explainable(e.rawSQL)
+	if err != nil {
+		return err
+	}
+
+	if len(stmts) != len(e.opType) {
+		return fmt.Errorf("statement count mismatch: %d vs %d, Extract should be called first",
+			len(stmts), len(e.opType))
+	}
+
+	e.plans = make([][]*models.PlanSummary, len(stmts))
+	for idx := range stmts {
+		// EXECUTE 的操作类型是预处理语句的类型，但不能被 EXPLAIN
+		if _, ok := explainable[e.opType[idx]]; !ok || !kinds[idx] {
+			continue
+		}
+
+		if e.plans[idx], err = explainOne(ctx, db, stmts[idx]); err != nil {
+			return fmt.Errorf("error explaining statement %d: %w", idx+1, err)
+		}
+	}
+
+	return nil
+}
+
+// explainOne runs EXPLAIN on a single statement and parses the plan summary.
+//
+// Both MySQL (table, type, key, rows) and TiDB (access object, estRows) output
+// columns are recognized, unknown columns are ignored.
+func explainOne(ctx context.Context, db *sql.DB, stmt string) ([]*models.PlanSummary, error) {
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		plans  = make([]*models.PlanSummary, 0)
+		values = make([]sql.NullString, len(columns))
+		dest   = make([]any, len(columns))
+	)
+	for idx := range values {
+		dest[idx] = &values[idx]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		var table, accessType, key string
+		var estRows int64
+		for idx := range columns {
+			switch strings.ToLower(columns[idx]) {
+			case "table", "access object":
+				table = values[idx].String
+			case "type":
+				accessType = values[idx].String
+			case "key":
+				key = values[idx].String
+			case "rows", "estrows":
+				// TiDB estRows is a float, e.g. 10000.00
+				if f, err := strconv.ParseFloat(values[idx].String, 64); err == nil {
+					estRows = int64(f)
+				}
+			}
+		}
+
+		plans = append(plans, models.NewPlanSummary(table, accessType, key, estRows))
+	}
+
+	return plans, rows.Err()
+}
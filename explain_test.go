@@ -0,0 +1,136 @@
+//go:build !js
+
+package sqlextractor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// explainDriver is a fake driver which answers EXPLAIN queries with MySQL style rows.
+type explainDriver struct{}
+
+func (explainDriver) Open(string) (driver.Conn, error) { return explainConn{}, nil }
+
+type explainConn struct{}
+
+func (explainConn) Prepare(query string) (driver.Stmt, error) { return explainStmt{query}, nil }
+func (explainConn) Close() error                              { return nil }
+func (explainConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type explainStmt struct{ query string }
+
+func (explainStmt) Close() error  { return nil }
+func (explainStmt) NumInput() int { return -1 }
+func (explainStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s explainStmt) Query([]driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "EXPLAIN ") || strings.HasPrefix(s.query, "EXPLAIN EXECUTE") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+
+	if strings.Contains(s.query, "WHERE id = 1") {
+		return &explainRows{values: [][]driver.Value{{"users", "const", "PRIMARY", "1"}}}, nil
+	}
+
+	return &explainRows{values: [][]driver.Value{{"orders", "ALL", nil, "1024"}}}, nil
+}
+
+type explainRows struct {
+	values [][]driver.Value
+	idx    int
+}
+
+func (*explainRows) Columns() []string { return []string{"table", "type", "key", "rows"} }
+func (*explainRows) Close() error      { return nil }
+
+func (r *explainRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.values) {
+		return io.EOF
+	}
+
+	copy(dest, r.values[r.idx])
+	r.idx++
+
+	return nil
+}
+
+func init() { sql.Register("explain-fake", explainDriver{}) }
+
+func TestExtractor_Explain(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	db, err := sql.Open("explain-fake", "")
+	as.Nil(err)
+	defer db.Close()
+
+	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; SHOW TABLES; SELECT * FROM orders")
+	as.Nil(extractor.Extract())
+	as.Nil(extractor.Explain(context.Background(), db))
+	as.Equal([][]*models.PlanSummary{
+		{models.NewPlanSummary("users", "const", "PRIMARY", 1)},
+		nil,
+		{models.NewPlanSummary("orders", "ALL", "", 1024)},
+	}, extractor.Plans())
+
+	// EXECUTE 不被 EXPLAIN
+	extractor = NewExtractor("PREPARE s FROM 'SELECT * FROM users WHERE id = ?'; SET @a = 1; EXECUTE s USING @a; " +
+		"SELECT * FROM users WHERE id = 1")
+	as.Nil(extractor.Extract())
+	as.Equal(models.SQLOperationSelect, extractor.OpType()[2])
+	as.Nil(extractor.Explain(context.Background(), db))
+	as.Equal([][]*models.PlanSummary{nil, nil, nil, {models.NewPlanSummary("users", "const", "PRIMARY", 1)}},
+		extractor.Plans())
+
+	// 使用 Extractor 的方言选项切分
+	extractor = NewExtractor("SELECT * FROM users WHERE id = 1 OFFSET 0 ROWS FETCH NEXT 1 ROWS ONLY", WithPostgres())
+	as.Nil(extractor.Extract())
+	as.Nil(extractor.Explain(context.Background(), db))
+	as.Equal([][]*models.PlanSummary{{models.NewPlanSummary("users", "const", "PRIMARY", 1)}}, extractor.Plans())
+
+	// Extract not called
+	extractor = NewExtractor("SELECT * FROM users WHERE id = 1")
+	as.NotNil(extractor.Explain(context.Background(), db))
+}
+
+func TestExtractor_PlanHash(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	db, err := sql.Open("explain-fake", "")
+	as.Nil(err)
+	defer db.Close()
+
+	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; SHOW TABLES; SELECT * FROM orders")
+	as.Empty(extractor.PlanHash()) // not explained
+	as.Nil(extractor.Extract())
+	as.Nil(extractor.Explain(context.Background(), db))
+
+	hash := extractor.PlanHash()
+	as.Equal(3, len(hash))
+	as.Equal(defaultHash([]byte("users:const:PRIMARY")), hash[0])
+	as.Equal("", hash[1])
+	as.Equal(defaultHash([]byte("orders:ALL:")), hash[2])
+
+	// custom hash function
+	hash = extractor.PlanHash(func(b []byte) string { return string(b) })
+	as.Equal([]string{"users:const:PRIMARY", "", "orders:ALL:"}, hash)
+
+	// rows estimate is not part of the plan shape
+	as.Equal(
+		planShape([]*models.PlanSummary{models.NewPlanSummary("t", "ref", "idx_a", 1)}),
+		planShape([]*models.PlanSummary{models.NewPlanSummary("t", "ref", "idx_a", 1000)}),
+	)
+}
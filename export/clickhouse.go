@@ -0,0 +1,234 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// Defaults of the ClickHouseSink.
+const (
+	DefaultClickHouseDatabase  = "default"
+	DefaultClickHouseTable     = "sql_digests"
+	DefaultClickHouseBatchSize = 1000
+)
+
+// ClickHouseSink writes aggregates into a ClickHouse table through the HTTP
+// interface (port 8123), in batches of JSONEachRow inserts. It is safe for
+// concurrent use.
+//
+// Aggregates without time bucket are stamped with the time they are written.
+type ClickHouseSink struct {
+	mu sync.Mutex
+
+	endpoint  string
+	database  string
+	table     string
+	user      string
+	password  string
+	batchSize int
+	client    *http.Client
+	now       func() time.Time
+
+	buf  bytes.Buffer // pending rows, JSON lines
+	rows int
+}
+
+// ClickHouseOption configures the ClickHouseSink.
+type ClickHouseOption func(*ClickHouseSink)
+
+// WithClickHouseTable writes into database.table, default is
+// default.sql_digests.
+func WithClickHouseTable(database, table string) ClickHouseOption {
+	return func(s *ClickHouseSink) { s.database, s.table = database, table }
+}
+
+// WithClickHouseAuth authenticates as user.
+func WithClickHouseAuth(user, password string) ClickHouseOption {
+	return func(s *ClickHouseSink) { s.user, s.password = user, password }
+}
+
+// WithClickHouseBatchSize inserts once n rows are pending, default is
+// DefaultClickHouseBatchSize.
+func WithClickHouseBatchSize(n int) ClickHouseOption {
+	return func(s *ClickHouseSink) { s.batchSize = max(n, 1) }
+}
+
+// WithHTTPClient sends the requests with the client, default is
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) ClickHouseOption {
+	return func(s *ClickHouseSink) { s.client = client }
+}
+
+// NewClickHouseSink creates a new ClickHouseSink of the HTTP endpoint, e.g.
+// http://localhost:8123.
+func NewClickHouseSink(endpoint string, opts ...ClickHouseOption) *ClickHouseSink {
+	s := &ClickHouseSink{
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		database:  DefaultClickHouseDatabase,
+		table:     DefaultClickHouseTable,
+		batchSize: DefaultClickHouseBatchSize,
+		client:    http.DefaultClient,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// quoteIdent quotes a ClickHouse identifier.
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), "`", "\\`") + "`"
+}
+
+func (s *ClickHouseSink) tableName() string {
+	return quoteIdent(s.database) + "." + quoteIdent(s.table)
+}
+
+// Bootstrap creates the table if it does not exist.
+func (s *ClickHouseSink) Bootstrap(ctx context.Context) error {
+	ddl := "CREATE TABLE IF NOT EXISTS " + s.tableName() + ` (
+	bucket DateTime64(3, 'UTC'),
+	digest String,
+	templatized_sql String,
+	op_type LowCardinality(String),
+	tables Array(String),
+	params_count UInt32,
+	count UInt64,
+	tags Map(String, String),
+	examples Array(String)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMMDD(bucket)
+ORDER BY (digest, bucket)`
+
+	return s.do(ctx, ddl, nil)
+}
+
+// clickHouseRow is a row of the table, in JSONEachRow format.
+type clickHouseRow struct {
+	Bucket         string            `json:"bucket"`
+	Digest         string            `json:"digest"`
+	TemplatizedSQL string            `json:"templatized_sql"`
+	OpType         string            `json:"op_type"`
+	Tables         []string          `json:"tables"`
+	ParamsCount    int               `json:"params_count"`
+	Count          int64             `json:"count"`
+	Tags           map[string]string `json:"tags"`
+	Examples       []string          `json:"examples"`
+}
+
+// Write buffers the aggregates, and inserts them once the batch size is
+// reached.
+func (s *ClickHouseSink) Write(ctx context.Context, stats []sqlextractor.DigestStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enc := json.NewEncoder(&s.buf)
+	for idx := range stats {
+		st := &stats[idx]
+
+		bucket := st.Bucket
+		if bucket.IsZero() {
+			bucket = s.now()
+		}
+
+		row := clickHouseRow{
+			Bucket:         bucket.UTC().Format("2006-01-02 15:04:05.000"),
+			Digest:         st.Digest,
+			TemplatizedSQL: st.TemplatizedSQL,
+			OpType:         st.OpType.String(),
+			Tables:         tableNames(st),
+			ParamsCount:    st.ParamsCount,
+			Count:          st.Count,
+			Tags:           st.Tags,
+			Examples:       st.Examples,
+		}
+		if row.Tags == nil {
+			row.Tags = map[string]string{}
+		}
+		if row.Examples == nil {
+			row.Examples = []string{}
+		}
+
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+		s.rows++
+
+		if s.rows >= s.batchSize {
+			if err := s.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush inserts the pending rows. The rows are kept if the insert fails, so
+// it can be retried.
+func (s *ClickHouseSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush(ctx)
+}
+
+func (s *ClickHouseSink) flush(ctx context.Context) error {
+	if s.rows == 0 {
+		return nil
+	}
+
+	if err := s.do(ctx, "INSERT INTO "+s.tableName()+" FORMAT JSONEachRow", s.buf.Bytes()); err != nil {
+		return err
+	}
+
+	s.buf.Reset()
+	s.rows = 0
+
+	return nil
+}
+
+// do sends the query, with the data after it.
+func (s *ClickHouseSink) do(ctx context.Context, query string, data []byte) error {
+	body := io.Reader(strings.NewReader(query))
+	if data != nil {
+		body = io.MultiReader(strings.NewReader(query+"\n"), bytes.NewReader(data))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/?"+url.Values{
+		"database": {s.database},
+	}.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if s.user != "" {
+		req.Header.Set("X-ClickHouse-User", s.user)
+		req.Header.Set("X-ClickHouse-Key", s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("clickhouse: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
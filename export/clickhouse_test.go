@@ -0,0 +1,104 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clickHouseServer records the queries sent to it.
+type clickHouseServer struct {
+	mu      sync.Mutex
+	queries []string
+	fail    bool
+}
+
+func (c *clickHouseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.Header.Get("X-ClickHouse-User") != "app" || r.Header.Get("X-ClickHouse-Key") != "secret" ||
+		r.URL.Query().Get("database") != "analytics" {
+		http.Error(w, "Code: 516. Authentication failed", http.StatusUnauthorized)
+		return
+	}
+	if c.fail {
+		http.Error(w, "Code: 60. Unknown table", http.StatusNotFound)
+		return
+	}
+
+	b, _ := io.ReadAll(r.Body)
+	c.queries = append(c.queries, string(b))
+}
+
+func TestClickHouseSink(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	srv := &clickHouseServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx := context.Background()
+	sink := NewClickHouseSink(ts.URL+"/",
+		WithClickHouseTable("analytics", "digests"),
+		WithClickHouseAuth("app", "secret"),
+		WithClickHouseBatchSize(3),
+		WithHTTPClient(ts.Client()),
+	)
+	sink.now = func() time.Time { return time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC) }
+
+	as.Nil(sink.Bootstrap(ctx))
+	as.Equal(1, len(srv.queries))
+	as.True(strings.HasPrefix(srv.queries[0], "CREATE TABLE IF NOT EXISTS `analytics`.`digests` ("))
+
+	// batched once 3 rows are pending
+	stats := testStats(t)
+	as.Nil(sink.Write(ctx, stats))
+	as.Equal(1, len(srv.queries))
+	as.Nil(sink.Write(ctx, stats))
+	as.Equal(2, len(srv.queries))
+
+	lines := strings.Split(strings.TrimSpace(srv.queries[1]), "\n")
+	as.Equal("INSERT INTO `analytics`.`digests` FORMAT JSONEachRow", lines[0])
+	as.Equal(4, len(lines))
+
+	var row map[string]any
+	as.Nil(json.Unmarshal([]byte(lines[1]), &row))
+	as.Equal("2024-05-01 10:00:00.000", row["bucket"])
+	as.Equal(stats[0].Digest, row["digest"])
+	as.Equal("SELECT", row["op_type"])
+	as.Equal([]any{"shop.users", "orders"}, row["tables"])
+	as.Equal(float64(1), row["params_count"])
+	as.Equal(map[string]any{"service": "billing"}, row["tags"])
+
+	// no bucket, stamped with the write time
+	as.Nil(json.Unmarshal([]byte(lines[2]), &row))
+	as.Equal("2024-05-01 10:00:30.000", row["bucket"])
+	as.Equal(map[string]any{}, row["tags"])
+
+	// the pending row is kept if the insert fails
+	srv.fail = true
+	err := sink.Flush(ctx)
+	as.NotNil(err)
+	as.Contains(err.Error(), "Unknown table")
+
+	srv.fail = false
+	as.Nil(sink.Flush(ctx))
+	as.Equal(3, len(srv.queries))
+	as.Equal(2, len(strings.Split(strings.TrimSpace(srv.queries[2]), "\n")))
+
+	as.Nil(sink.Flush(ctx))
+	as.Equal(3, len(srv.queries))
+
+	as.NotNil(NewClickHouseSink(ts.URL, WithHTTPClient(ts.Client())).Bootstrap(ctx))
+	as.Equal("`a\\`b`", quoteIdent("a`b"))
+}
@@ -0,0 +1,135 @@
+// Package export writes the per-digest aggregates of sqlextractor.Aggregator
+// to files for loading into warehouses, as CSV or Parquet, or directly into
+// ClickHouse.
+//
+// It is a separate package so the Parquet dependency is not linked into
+// programs which only extract, e.g. the WebAssembly build.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// Columns are the names of the exported columns, in order.
+var Columns = []string{
+	"bucket", "digest", "templatized_sql", "op_type", "tables",
+	"params_count", "count", "tags", "examples",
+}
+
+// tableNames returns the qualified names of the tables, e.g. shop.users.
+func tableNames(s *sqlextractor.DigestStats) []string {
+	tables := make([]string, len(s.TableInfos))
+	for idx, ti := range s.TableInfos {
+		tables[idx], _ = ti.TableNameWithSchema()
+	}
+
+	return tables
+}
+
+// WriteCSV writes the aggregates to w as CSV with a header row. The bucket is
+// RFC 3339 in UTC, empty without time buckets; tables, tags and examples are
+// JSON encoded.
+func WriteCSV(w io.Writer, stats []sqlextractor.DigestStats) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return err
+	}
+
+	jsonString := func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+
+	for idx := range stats {
+		s := &stats[idx]
+
+		var bucket string
+		if !s.Bucket.IsZero() {
+			bucket = s.Bucket.UTC().Format(time.RFC3339)
+		}
+
+		tags := s.Tags
+		if tags == nil {
+			tags = sqlextractor.Tags{}
+		}
+		examples := s.Examples
+		if examples == nil {
+			examples = []string{}
+		}
+
+		record := []string{
+			bucket, s.Digest, s.TemplatizedSQL, s.OpType.String(), "",
+			strconv.Itoa(s.ParamsCount), strconv.FormatInt(s.Count, 10), "", "",
+		}
+		var err error
+		if record[4], err = jsonString(tableNames(s)); err != nil {
+			return err
+		}
+		if record[7], err = jsonString(tags); err != nil {
+			return err
+		}
+		if record[8], err = jsonString(examples); err != nil {
+			return err
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// parquetRow is the Parquet schema of the aggregates.
+type parquetRow struct {
+	Bucket         int64             `parquet:"bucket,optional,timestamp(millisecond)"` // 0 is null
+	Digest         string            `parquet:"digest"`
+	TemplatizedSQL string            `parquet:"templatized_sql"`
+	OpType         string            `parquet:"op_type,enum"`
+	Tables         []string          `parquet:"tables,list"`
+	ParamsCount    int32             `parquet:"params_count"`
+	Count          int64             `parquet:"count"`
+	Tags           map[string]string `parquet:"tags"`
+	Examples       []string          `parquet:"examples,list"`
+}
+
+// WriteParquet writes the aggregates to w as a Parquet file with typed
+// columns: bucket is a millisecond timestamp (null without time buckets), op
+// type an enum, tables and examples lists, tags a map and the counts integers.
+func WriteParquet(w io.Writer, stats []sqlextractor.DigestStats) error {
+	rows := make([]parquetRow, len(stats))
+	for idx := range stats {
+		s := &stats[idx]
+
+		rows[idx] = parquetRow{
+			Digest:         s.Digest,
+			TemplatizedSQL: s.TemplatizedSQL,
+			OpType:         s.OpType.String(),
+			Tables:         tableNames(s),
+			ParamsCount:    int32(s.ParamsCount),
+			Count:          s.Count,
+			Tags:           s.Tags,
+			Examples:       s.Examples,
+		}
+		if !s.Bucket.IsZero() {
+			rows[idx].Bucket = s.Bucket.UnixMilli()
+		}
+	}
+
+	pw := parquet.NewGenericWriter[parquetRow](w)
+	if _, err := pw.Write(rows); err != nil {
+		return err
+	}
+
+	return pw.Close()
+}
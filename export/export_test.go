@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+func testStats(t *testing.T) []sqlextractor.DigestStats {
+	t.Helper()
+
+	a := sqlextractor.NewAggregator()
+	assert.Nil(t, a.AddSQL("SELECT * FROM shop.users u JOIN orders o ON u.id = o.uid WHERE u.id = 1", sqlextractor.Tags{"service": "billing"}))
+	assert.Nil(t, a.AddSQL("SHOW TABLES", nil))
+
+	stats := a.Stats()
+	stats[0].Bucket = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	return stats
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	stats := testStats(t)
+
+	var buf bytes.Buffer
+	as.Nil(WriteCSV(&buf, stats))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	as.Nil(err)
+	as.Equal(3, len(records))
+	as.Equal(Columns, records[0])
+	as.Equal([]string{
+		"2024-05-01T10:00:00Z", stats[0].Digest, stats[0].TemplatizedSQL, "SELECT", `["shop.users","orders"]`,
+		"1", "1", `{"service":"billing"}`, `["SELECT * FROM shop.users u JOIN orders o ON u.id = o.uid WHERE u.id = ?"]`,
+	}, records[1])
+	as.Equal([]string{"", stats[1].Digest, "SHOW TABLES", "SHOW", `[]`, "0", "1", `{}`, `["SHOW TABLES"]`}, records[2])
+}
+
+func TestWriteParquet(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	stats := testStats(t)
+
+	var buf bytes.Buffer
+	as.Nil(WriteParquet(&buf, stats))
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	as.Nil(err)
+	as.Equal(int64(2), f.NumRows())
+
+	schema := f.Schema()
+	var names []string
+	for _, field := range schema.Fields() {
+		names = append(names, field.Name())
+	}
+	as.Equal(Columns, names)
+	leaf := func(name string) parquet.Type {
+		col, ok := schema.Lookup(name)
+		as.True(ok, name)
+		return col.Node.Type()
+	}
+	as.Equal("TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)", leaf("bucket").LogicalType().String())
+	as.Equal("ENUM", leaf("op_type").LogicalType().String())
+	as.Equal(parquet.Int32, leaf("params_count").Kind())
+	as.Equal(parquet.Int64, leaf("count").Kind())
+
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	as.Nil(err)
+	as.Equal(2, len(rows))
+	as.Equal(stats[0].Bucket.UnixMilli(), rows[0].Bucket)
+	as.Equal("SELECT", rows[0].OpType)
+	as.Equal([]string{"shop.users", "orders"}, rows[0].Tables)
+	as.Equal(int32(1), rows[0].ParamsCount)
+	as.Equal(map[string]string{"service": "billing"}, rows[0].Tags)
+	as.Equal(int64(0), rows[1].Bucket)
+
+	// no bucket is null
+	values := make([]parquet.Row, 2)
+	n, err := parquet.NewReader(bytes.NewReader(buf.Bytes())).ReadRows(values)
+	as.Equal(2, n)
+	as.False(values[0][0].IsNull())
+	as.True(values[1][0].IsNull())
+	as.Equal("SHOW TABLES", rows[1].TemplatizedSQL)
+}
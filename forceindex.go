@@ -0,0 +1,41 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/extract"
+
+// ForceIndexRule forces the indexes of a table in the statements of a digest,
+// the TemplatizedSQLHash of the statement, e.g. to pin the plan of a regressed
+// query.
+type ForceIndexRule = extract.ForceIndexRule
+
+// ForceIndexPolicy rewrites the statements of the digests of its rules with
+// FORCE INDEX hints, enabling emergency plan pinning in a SQL-rewriting proxy.
+// It is safe for concurrent use.
+type ForceIndexPolicy struct {
+	rules map[string][]ForceIndexRule // digest -> rules
+}
+
+// NewForceIndexPolicy creates a policy of the rules.
+//
+// Example:
+//
+//	policy := sqlextractor.NewForceIndexPolicy(sqlextractor.ForceIndexRule{
+//	  Digest: digest, Table: "orders", Indexes: []string{"idx_user_created"},
+//	})
+//	sql, rewritten, err := policy.Rewrite("SELECT * FROM orders WHERE user_id = 1 ORDER BY created_at")
+//	// SELECT * FROM `orders` FORCE INDEX (`idx_user_created`) WHERE `user_id`=1 ORDER BY `created_at`
+func NewForceIndexPolicy(rules ...ForceIndexRule) *ForceIndexPolicy {
+	p := &ForceIndexPolicy{rules: make(map[string][]ForceIndexRule, len(rules))}
+	for _, rule := range rules {
+		p.rules[rule.Digest] = append(p.rules[rule.Digest], rule)
+	}
+
+	return p
+}
+
+// Rewrite returns the SQL with the index hints of the tables of the matching
+// rules replaced with FORCE INDEX (idx, ...), and whether it was rewritten.
+// Only the matching statements are rewritten, the SQL is returned as is if no
+// rule matches.
+func (p *ForceIndexPolicy) Rewrite(sql string) (string, bool, error) {
+	return defaultExtractor.ForceIndex(sql, p.rules)
+}
@@ -0,0 +1,59 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForceIndexPolicy(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	digest := func(sql string) string {
+		extractor := NewExtractor(sql)
+		as.Nil(extractor.Extract())
+		return extractor.TemplatizedSQLHash()[0]
+	}
+
+	pinned := "SELECT * FROM shop.orders o JOIN users u ON u.id = o.user_id WHERE o.user_id = 1 ORDER BY o.created_at"
+	policy := NewForceIndexPolicy(
+		ForceIndexRule{Digest: digest(pinned), Table: "ORDERS", Indexes: []string{"idx_user_created"}},
+		ForceIndexRule{Digest: digest("UPDATE orders USE INDEX (idx_a) SET state = 'x' WHERE id = 1"),
+			Schema: "shop", Table: "orders", Indexes: []string{"PRIMARY"}},
+	)
+
+	// 相同 digest 的语句，参数不同
+	sql, rewritten, err := policy.Rewrite("SELECT * FROM shop.orders o JOIN users u ON u.id = o.user_id WHERE o.user_id = 42 ORDER BY o.created_at")
+	as.Nil(err)
+	as.True(rewritten)
+	as.Equal("SELECT * FROM `shop`.`orders` AS `o` FORCE INDEX (`idx_user_created`) JOIN `users` AS `u` ON `u`.`id`=`o`.`user_id` "+
+		"WHERE `o`.`user_id`=42 ORDER BY `o`.`created_at`", sql)
+
+	// 改写后 digest 不变，可以重复改写
+	as.Equal(digest(pinned), digest(sql))
+	again, rewritten, err := policy.Rewrite(sql)
+	as.Nil(err)
+	as.True(rewritten)
+	as.Equal(sql, again)
+
+	// 原有的索引提示被替换，schema 不匹配时不改写
+	sql, rewritten, err = policy.Rewrite("SELECT 1; UPDATE orders USE INDEX (idx_a) SET state = 'y' WHERE id = 2")
+	as.Nil(err)
+	as.False(rewritten)
+	as.Equal("SELECT 1; UPDATE orders USE INDEX (idx_a) SET state = 'y' WHERE id = 2", sql)
+
+	sql, rewritten, err = policy.Rewrite("SELECT 1; UPDATE shop.orders USE INDEX (idx_a) SET state = 'y' WHERE id = 2")
+	as.Nil(err)
+	as.False(rewritten, "different digest, the schema is part of the template")
+	as.Equal("SELECT 1; UPDATE shop.orders USE INDEX (idx_a) SET state = 'y' WHERE id = 2", sql)
+
+	// 没有匹配的规则时原样返回
+	sql, rewritten, err = policy.Rewrite("SELECT * FROM orders WHERE id = 1")
+	as.Nil(err)
+	as.False(rewritten)
+	as.Equal("SELECT * FROM orders WHERE id = 1", sql)
+
+	_, _, err = policy.Rewrite("SELECT * FROM")
+	as.NotNil(err)
+}
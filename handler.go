@@ -0,0 +1,82 @@
+package sqlextractor
+
+import (
+	"sync"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+type (
+	// NodeHandler handles an AST node during templatization. It reports whether
+	// the node is handled; if not, the node falls back to the builtin handling.
+	NodeHandler = extract.NodeHandler
+	// ExtractVisitor is the visitor passed to NodeHandler, it writes the
+	// templatized SQL and parameters.
+	ExtractVisitor = extract.ExtractVisitor
+)
+
+// nodeHandler is a registered NodeHandler.
+type nodeHandler struct {
+	nodeType ast.Node
+	fn       NodeHandler
+}
+
+// nodeHandlers are the registered handlers, in order of registration.
+var nodeHandlers []nodeHandler
+
+var (
+	extractorsMu sync.Mutex
+	// extractors are the package-level and shared internal extractors, which
+	// RegisterNodeHandler installs the handlers on.
+	extractors []*extract.Extractor
+)
+
+// RegisterNodeHandler adds or overrides the handling of the AST node type of
+// nodeType, e.g. vendor-specific expressions, without forking the visitor. A
+// nil fn removes the handler.
+//
+// It is not safe for concurrent use with Extract, handlers should be
+// registered at init.
+//
+// Example:
+//
+//	sqlextractor.RegisterNodeHandler((*ast.RowExpr)(nil), func(v *sqlextractor.ExtractVisitor, n ast.Node) bool {
+//	    v.WriteString("ROW(...)")
+//	    return true
+//	})
+func RegisterNodeHandler(nodeType ast.Node, fn NodeHandler) {
+	nodeHandlers = append(nodeHandlers, nodeHandler{nodeType: nodeType, fn: fn})
+
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	for _, e := range extractors {
+		e.RegisterNodeHandler(nodeType, fn)
+	}
+}
+
+// packageExtractor creates a package-level internal extractor, which the
+// registered handlers are installed on.
+func packageExtractor(opts ...extract.Option) *extract.Extractor {
+	return registerExtractor(newExtractor(opts...))
+}
+
+// registerExtractor adds the internal extractor to extractors.
+func registerExtractor(e *extract.Extractor) *extract.Extractor {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append(extractors, e)
+
+	return e
+}
+
+// newExtractor creates an internal extractor with the registered handlers.
+func newExtractor(opts ...extract.Option) *extract.Extractor {
+	e := extract.NewExtractor(opts...)
+	for _, h := range nodeHandlers {
+		e.RegisterNodeHandler(h.nodeType, h.fn)
+	}
+
+	return e
+}
@@ -0,0 +1,42 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRegisterNodeHandler is not parallel, since registration is not safe
+// for concurrent use with Extract.
+func TestRegisterNodeHandler(t *testing.T) {
+	as := assert.New(t)
+
+	RegisterNodeHandler((*ast.RowExpr)(nil), func(v *ExtractVisitor, n ast.Node) bool {
+		v.WriteString("ROW(")
+		for idx, expr := range n.(*ast.RowExpr).Values {
+			if idx > 0 {
+				v.WriteString(", ")
+			}
+			v.Visit(expr)
+		}
+		v.WriteString(")")
+
+		return true
+	})
+
+	extractor := NewExtractor("SELECT * FROM t WHERE (a, b) = (1, 2)")
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT * FROM t WHERE ROW(a, b) eq ROW(?, ?)"}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{int64(1), int64(2)}}, extractor.Params())
+
+	// extractors with hooks use the registered handlers too
+	extractor.SetHooks(&Hooks{})
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT * FROM t WHERE ROW(a, b) eq ROW(?, ?)"}, extractor.TemplatizedSQL())
+
+	// package-level extractors, e.g. of the profiles, use the registered handlers too
+	normalized, err := extractor.NormalizedSQL(ProfileSQLGlot)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE ROW(a, b) = ROW(?, ?)"}, normalized)
+}
@@ -0,0 +1,11 @@
+package sqlextractor
+
+// WithOptimizerHints keeps the optimizer hint comment following the leading
+// keyword of a statement, e.g. the Oracle hints of legacy application logs,
+// which are otherwise dropped from the templates. The spaces of the hint are
+// collapsed, so the statements with the same hints share a digest.
+//
+// e.g. SELECT /*+ INDEX(t idx_a) */ a FROM t WHERE ROWNUM <= 10 -> SELECT /*+ INDEX(t idx_a) */ a FROM t WHERE ROWNUM le ?
+func WithOptimizerHints() ExtractorOption {
+	return func(e *Extractor) { e.options.optimizerHints = true }
+}
@@ -0,0 +1,32 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithOptimizerHints(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT /*+ INDEX(o idx_status)  FIRST_ROWS(10) */ o.id, c.name FROM orders o, customers c " +
+		"WHERE o.cid = c.id(+) AND o.status = 'NEW' AND ROWNUM <= 10"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	// WithOracle 保留优化器提示
+	extractor := NewExtractor(sql, WithOracle())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT /*+ INDEX(o idx_status) FIRST_ROWS(10) */ o.id, c.name FROM orders AS o CROSS JOIN customers AS c " +
+		"WHERE o.cid eq c.id(+) and o.status eq ? and ROWNUM le ?"}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{"NEW", int64(10)}}, extractor.Params())
+
+	// 其它方言使用 WithOptimizerHints 保留，默认去掉
+	extractor = NewExtractor("SELECT /*+ INDEX(o idx_status) */ o.id FROM orders o WHERE o.status = 'NEW'", WithOptimizerHints())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT /*+ INDEX(o idx_status) */ o.id FROM orders AS o WHERE o.status eq ?"}, extractor.TemplatizedSQL())
+	extractor = NewExtractor("SELECT /*+ INDEX(o idx_status) */ o.id FROM orders o WHERE o.status = 'NEW'")
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT o.id FROM orders AS o WHERE o.status eq ?"}, extractor.TemplatizedSQL())
+}
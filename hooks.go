@@ -0,0 +1,34 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/extract"
+
+type (
+	// Hooks are callbacks invoked by Extract, so embedders can record parse and
+	// templatize latency and error rates without wrapping the whole call.
+	Hooks = extract.Hooks
+	// ParseInfo is the payload of the parse hooks: SQL byte size, latency and error.
+	ParseInfo = extract.ParseInfo
+	// StatementInfo is the payload of the OnStatementDone hook: statement index,
+	// byte size, AST node count, operation type and latency.
+	StatementInfo = extract.StatementInfo
+)
+
+// SetHooks sets the hooks invoked by Extract, nil removes them.
+func (e *Extractor) SetHooks(hooks *Hooks) {
+	if hooks == nil {
+		e.extractor = nil
+		return
+	}
+
+	e.extractor = newExtractor(append(e.options.internalOptions(), extract.WithHooks(hooks))...)
+}
+
+// internal returns the internal extractor: the one with hooks, or the shared
+// one of the options.
+func (e *Extractor) internal() *extract.Extractor {
+	if e.extractor != nil {
+		return e.extractor
+	}
+
+	return sharedExtractor(e.options)
+}
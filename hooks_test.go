@@ -0,0 +1,37 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestExtractor_SetHooks(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var stmts []StatementInfo
+	var errs []error
+	extractor := NewExtractor("SELECT * FROM users WHERE id = 1")
+	extractor.SetHooks(&Hooks{
+		OnStatementDone: func(info StatementInfo) { stmts = append(stmts, info) },
+		OnError:         func(err error) { errs = append(errs, err) },
+	})
+
+	as.Nil(extractor.Extract())
+	as.Equal(1, len(stmts))
+	as.Equal(models.SQLOperationSelect, stmts[0].OpType)
+	as.Equal(len("SELECT * FROM users WHERE id = 1"), stmts[0].Bytes)
+	as.Positive(stmts[0].NodeCount)
+
+	extractor.SetRawSQL("SELEC 1")
+	as.NotNil(extractor.Extract())
+	as.Equal(1, len(errs))
+
+	// hooks removed
+	extractor.SetHooks(nil)
+	as.NotNil(extractor.Extract())
+	as.Equal(1, len(errs))
+}
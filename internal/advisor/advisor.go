@@ -0,0 +1,81 @@
+// Package advisor suggests candidate composite indexes from the columns used
+// by equality, range and order by predicates.
+//
+// Index columns of a table are ordered by the usual selectivity heuristics of
+// B-tree indexes:
+//   - equality columns first, in order of appearance
+//   - then the first range column, since columns after a range column can not
+//     be used to seek
+//   - then the order by columns, only if there is no range column, so the index
+//     can avoid a filesort
+package advisor
+
+import (
+	"slices"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// Recommend returns the candidate composite indexes of each table used by the
+// predicates of a single statement, tables are in order of first appearance.
+func Recommend(predicates []*models.Predicate) []*models.IndexRecommendation {
+	type tableColumns struct {
+		schema, table string
+		equal         []string
+		rng           []string
+		order         []string
+	}
+
+	var (
+		tables = make(map[string]*tableColumns)
+		order  []string
+	)
+
+	for _, p := range predicates {
+		key := p.Schema() + "." + p.Table()
+		tc, ok := tables[key]
+		if !ok {
+			tc = &tableColumns{schema: p.Schema(), table: p.Table()}
+			tables[key] = tc
+			order = append(order, key)
+		}
+
+		switch p.Type() {
+		case models.PredicateEqual:
+			tc.equal = appendUniq(tc.equal, p.Column())
+		case models.PredicateRange:
+			tc.rng = appendUniq(tc.rng, p.Column())
+		case models.PredicateOrder:
+			tc.order = appendUniq(tc.order, p.Column())
+		}
+	}
+
+	recommendations := make([]*models.IndexRecommendation, 0, len(order))
+	for _, key := range order {
+		tc := tables[key]
+
+		columns := append([]string{}, tc.equal...)
+		if len(tc.rng) > 0 {
+			columns = appendUniq(columns, tc.rng[0])
+		} else {
+			for _, col := range tc.order {
+				columns = appendUniq(columns, col)
+			}
+		}
+
+		if len(columns) > 0 {
+			recommendations = append(recommendations,
+				models.NewIndexRecommendation(tc.schema, tc.table, columns))
+		}
+	}
+
+	return recommendations
+}
+
+func appendUniq(columns []string, column string) []string {
+	if slices.Contains(columns, column) {
+		return columns
+	}
+
+	return append(columns, column)
+}
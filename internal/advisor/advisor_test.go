@@ -0,0 +1,40 @@
+package advisor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestRecommend(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// no predicates
+	as.Equal([]*models.IndexRecommendation{}, Recommend(nil))
+
+	// equality first, then the first range column
+	as.Equal([]*models.IndexRecommendation{
+		models.NewIndexRecommendation("", "users", []string{"name", "status", "age"}),
+	}, Recommend([]*models.Predicate{
+		models.NewPredicate("", "users", "age", models.PredicateRange),
+		models.NewPredicate("", "users", "name", models.PredicateEqual),
+		models.NewPredicate("", "users", "created_at", models.PredicateRange),
+		models.NewPredicate("", "users", "status", models.PredicateEqual),
+		models.NewPredicate("", "users", "name", models.PredicateEqual),
+		models.NewPredicate("", "users", "id", models.PredicateOrder),
+	}))
+
+	// order by columns are used only without range columns
+	as.Equal([]*models.IndexRecommendation{
+		models.NewIndexRecommendation("db", "orders", []string{"user_id", "created_at", "id"}),
+		models.NewIndexRecommendation("", "users", []string{"name"}),
+	}, Recommend([]*models.Predicate{
+		models.NewPredicate("db", "orders", "user_id", models.PredicateEqual),
+		models.NewPredicate("", "users", "name", models.PredicateEqual),
+		models.NewPredicate("db", "orders", "created_at", models.PredicateOrder),
+		models.NewPredicate("db", "orders", "id", models.PredicateOrder),
+	}))
+}
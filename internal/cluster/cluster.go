@@ -0,0 +1,100 @@
+// Package cluster groups similar SQL templates beyond exact digest matching.
+//
+// Templates are grouped when they have the same operation type and the same
+// set of tables, and their predicate column sets are similar enough (Jaccard
+// similarity not less than the threshold). Clustering is greedy: a template
+// joins the first cluster whose representative (first member) is similar
+// enough, otherwise it starts a new cluster.
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// Feature is the feature of a template used for clustering.
+type Feature struct {
+	opType  models.SQLOpType
+	tables  []string // sorted, deduplicated
+	columns []string // sorted, deduplicated
+}
+
+// NewFeature creates a new Feature object.
+func NewFeature(opType models.SQLOpType, tables, columns []string) *Feature {
+	return &Feature{
+		opType:  opType,
+		tables:  sortUniq(tables),
+		columns: sortUniq(columns),
+	}
+}
+
+// group is the key of templates which may be clustered together.
+func (f *Feature) group() string {
+	return f.opType.String() + "|" + strings.Join(f.tables, ",")
+}
+
+// Cluster returns the cluster ID of each feature. Features of the same cluster
+// share the same ID, which is the hash of the representative's feature, so it
+// is stable for the same input.
+func Cluster(features []*Feature, threshold float64) []string {
+	var (
+		ids             = make([]string, len(features))
+		representatives = make(map[string][]int) // group -> indexes of representatives
+	)
+
+	for idx, f := range features {
+		group := f.group()
+
+		found := false
+		for _, rep := range representatives[group] {
+			if jaccard(features[rep].columns, f.columns) >= threshold {
+				ids[idx] = ids[rep]
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			hash := sha256.Sum256([]byte(group + "|" + strings.Join(f.columns, ",")))
+			ids[idx] = hex.EncodeToString(hash[:8])
+			representatives[group] = append(representatives[group], idx)
+		}
+	}
+
+	return ids
+}
+
+// jaccard returns the Jaccard similarity of two sorted sets, 1 if both are empty.
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	intersection := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			intersection++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+func sortUniq(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+
+	return slices.Compact(s)
+}
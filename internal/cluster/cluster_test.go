@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestCluster(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	ids := Cluster([]*Feature{
+		NewFeature(models.SQLOperationSelect, []string{"users"}, []string{"id", "name", "age"}),
+		NewFeature(models.SQLOperationSelect, []string{"users"}, []string{"name", "id", "age", "status"}),
+		NewFeature(models.SQLOperationSelect, []string{"users"}, []string{"email"}),
+		NewFeature(models.SQLOperationSelect, []string{"users", "orders"}, []string{"id", "name", "age"}),
+		NewFeature(models.SQLOperationDelete, []string{"users"}, []string{"id", "name", "age"}),
+		NewFeature(models.SQLOperationSelect, []string{"orders", "users", "users"}, []string{"id", "name"}),
+	}, 0.6)
+
+	as.Equal(6, len(ids))
+	as.Equal(ids[0], ids[1]) // 3/4 similar
+	as.NotEqual(ids[0], ids[2])
+	as.NotEqual(ids[0], ids[3]) // different tables
+	as.NotEqual(ids[0], ids[4]) // different operation type
+	as.Equal(ids[3], ids[5])    // 2/3 similar, same tables
+	as.Len(ids[0], 16)
+
+	// stable for the same input
+	as.Equal(ids[0], Cluster([]*Feature{
+		NewFeature(models.SQLOperationSelect, []string{"users"}, []string{"age", "id", "name"}),
+	}, 0.6)[0])
+
+	as.Equal([]string{}, Cluster(nil, 0.6))
+}
+
+func TestJaccard(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.Equal(1.0, jaccard(nil, nil))
+	as.Equal(0.0, jaccard([]string{"a"}, nil))
+	as.Equal(0.5, jaccard([]string{"a", "b"}, []string{"b"}))
+	as.Equal(1.0/3, jaccard([]string{"a", "b"}, []string{"b", "c"}))
+}
@@ -0,0 +1,215 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+// 数组语法改写后的函数名和标记列名，解析后由 visitor 还原
+const (
+	arrayMarker     = "sqlextractor_array"
+	anyMarker       = "sqlextractor_any"
+	allMarker       = "sqlextractor_all"
+	containsMarker  = "sqlextractor_contains"
+	containedMarker = "sqlextractor_contained"
+)
+
+// rewriteArrays rewrites the PostgreSQL array syntax, which the parser
+// rejects, to marker functions and operators:
+//
+//	ARRAY[1, 2]      -> sqlextractor_array(1, 2)
+//	a = ANY(expr)    -> a = sqlextractor_any(expr), the same for ALL
+//	a @> b, a <@ b   -> a | sqlextractor_contains | b, a | sqlextractor_contained | b
+//
+// ANY and ALL of a subquery are valid MySQL and kept. <@ is only rewritten
+// when followed by a space or a parenthesis, since a<@b is a comparison with
+// a user variable in MySQL. Quoted strings, identifiers and comments are kept
+// as is.
+func rewriteArrays(sql string) string {
+	if !strings.Contains(sql, "@") && !containsKeyword(sql, "ARRAY") &&
+		!containsKeyword(sql, "ANY") && !containsKeyword(sql, "ALL") {
+		return sql
+	}
+
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+	replace := func(from, to int, s string) {
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 32)
+		}
+		b.WriteString(sql[last:from])
+		b.WriteString(s)
+		last = to
+	}
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(sql[i:], "@>"):
+			replace(i, i+2, "| "+containsMarker+" |")
+			i++
+			continue
+		case strings.HasPrefix(sql[i:], "<@") && i+2 < len(sql) && (isSpace(sql[i+2]) || sql[i+2] == '('):
+			replace(i, i+2, "| "+containedMarker+" |")
+			i++
+			continue
+		case !isIdentChar(sql[i]) || (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')):
+			continue
+		}
+
+		switch {
+		case hasKeyword(sql[i:], "ARRAY"):
+			open := skipSpace(sql, i+len("ARRAY"))
+			if open >= len(sql) || sql[open] != '[' {
+				continue
+			}
+			if closing := matchBracket(sql, open); closing >= 0 {
+				// 嵌套的 ARRAY[...] 在后续的遍历中改写
+				replace(i, open+1, arrayMarker+"(")
+				sql = sql[:closing] + ")" + sql[closing+1:]
+				i = open
+			}
+
+		case hasKeyword(sql[i:], "ANY"), hasKeyword(sql[i:], "ALL"):
+			marker := anyMarker
+			if hasKeyword(sql[i:], "ALL") {
+				marker = allMarker
+			}
+
+			open := skipSpace(sql, i+3)
+			if open >= len(sql) || sql[open] != '(' || !afterComparison(sql, i) {
+				continue
+			}
+			inner := skipSpace(sql, open+1)
+			if hasKeyword(sql[inner:], "SELECT") || hasKeyword(sql[inner:], "WITH") ||
+				hasKeyword(sql[inner:], "VALUES") || hasKeyword(sql[inner:], "TABLE") {
+				continue
+			}
+			replace(i, open, marker)
+			i = open - 1
+		}
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// matchBracket returns the index of the bracket closing sql[open], or -1.
+func matchBracket(sql string, open int) int {
+	depth := 0
+	for i := open; i < len(sql); i++ {
+		switch sql[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(sql, i)
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// afterComparison reports whether sql[i] follows a comparison operator, e.g.
+// = ANY (...), spaces between them are ignored.
+func afterComparison(sql string, i int) bool {
+	j := i - 1
+	for j >= 0 && isSpace(sql[j]) {
+		j--
+	}
+
+	return j >= 0 && (sql[j] == '=' || sql[j] == '<' || sql[j] == '>')
+}
+
+// containmentOp returns the array operator of a rewritten a @> b or a <@ b,
+// whose left operand is a | marker, empty if the expression is not one.
+func containmentOp(node *ast.BinaryOperationExpr) (string, ast.ExprNode) {
+	l, ok := node.L.(*ast.BinaryOperationExpr)
+	if !ok || node.Op != opcode.Or || l.Op != opcode.Or {
+		return "", nil
+	}
+
+	col, ok := l.R.(*ast.ColumnNameExpr)
+	if !ok || col.Name.Table.L != "" {
+		return "", nil
+	}
+
+	switch col.Name.Name.L {
+	case containsMarker:
+		return "@>", l.L
+	case containedMarker:
+		return "<@", l.L
+	}
+
+	return "", nil
+}
+
+// isArrayMarker reports whether the column is a marker of a rewritten array
+// operator.
+func isArrayMarker(name *ast.ColumnName) bool {
+	return name.Table.L == "" && (name.Name.L == containsMarker || name.Name.L == containedMarker)
+}
+
+// writeArrayFunc 写入数组和 ANY、ALL 的标记函数，数组的元素与 IN 列表一样参数化，
+// 并且按 IN 列表的折叠规则只保留第一项，不是标记函数时返回 false
+//
+// e.g. id = ANY(ARRAY[1, 2, 3]) -> id eq ANY(ARRAY[?, ?, ?])
+func (v *ExtractVisitor) writeArrayFunc(node *ast.FuncCallExpr) bool {
+	switch node.FnName.L {
+	case arrayMarker:
+		args := node.Args
+		if v.collapseIn {
+			args = args[:min(len(args), 1)]
+		}
+
+		v.builder.WriteString("ARRAY[")
+		for idx := range args {
+			if idx > 0 {
+				v.builder.WriteString(", ")
+			}
+
+			if value, ok := args[idx].(*test_driver.ValueExpr); ok {
+				v.writeValue(value)
+			} else {
+				args[idx].Accept(v)
+			}
+		}
+		v.builder.WriteString("]")
+
+	case anyMarker, allMarker:
+		if node.FnName.L == anyMarker {
+			v.builder.WriteString("ANY(")
+		} else {
+			v.builder.WriteString("ALL(")
+		}
+		for idx := range node.Args {
+			if idx > 0 {
+				v.builder.WriteString(", ")
+			}
+			node.Args[idx].Accept(v)
+		}
+		v.builder.WriteString(")")
+
+	default:
+		return false
+	}
+
+	return true
+}
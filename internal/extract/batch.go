@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+)
+
+// BatchLookup returns the batched form of a single-row lookup, a SELECT from
+// one table whose WHERE clause is the equality of a column with a value, with
+// the equality replaced by an IN-list of size placeholders, and the column,
+// e.g. with a size of 3
+//
+//	SELECT * FROM users WHERE id = ? -> SELECT * FROM `users` WHERE `id` IN (?,?,?), id
+//
+// ok is false if the SQL is not a single statement or not a single-row lookup.
+func (e *Extractor) BatchLookup(sql string, size int) (batched, column string, ok bool, err error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(stmts) != 1 {
+		return "", "", false, nil
+	}
+
+	sel, isSelect := stmts[0].(*ast.SelectStmt)
+	if !isSelect || sel.From == nil || sel.From.TableRefs == nil || sel.From.TableRefs.Right != nil {
+		return "", "", false, nil
+	}
+	if ts, isTable := sel.From.TableRefs.Left.(*ast.TableSource); !isTable {
+		return "", "", false, nil
+	} else if _, isName := ts.Source.(*ast.TableName); !isName {
+		return "", "", false, nil
+	}
+
+	where := sel.Where
+	for {
+		paren, isParen := where.(*ast.ParenthesesExpr)
+		if !isParen {
+			break
+		}
+		where = paren.Expr
+	}
+
+	cmp, isCmp := where.(*ast.BinaryOperationExpr)
+	if !isCmp || cmp.Op != opcode.EQ {
+		return "", "", false, nil
+	}
+	col, isCol := cmp.L.(*ast.ColumnNameExpr)
+	if !isCol || !isConstant(cmp.R) {
+		if col, isCol = cmp.R.(*ast.ColumnNameExpr); !isCol || !isConstant(cmp.L) {
+			return "", "", false, nil
+		}
+	}
+
+	list := make([]ast.ExprNode, max(size, 1))
+	for idx := range list {
+		list[idx] = ast.NewParamMarkerExpr(idx)
+	}
+	sel.Where = &ast.PatternInExpr{Expr: col, List: list}
+
+	var builder strings.Builder
+	err = sel.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags|format.RestoreStringWithoutCharset, &builder))
+	if err != nil {
+		return "", "", false, err
+	}
+
+	return builder.String(), col.Name.Name.O, true, nil
+}
@@ -0,0 +1,355 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// BigQuery 语法改写后的函数名和别名，解析后由 visitor 还原
+const (
+	unnestMarker = "sqlextractor_unnest"
+	structMarker = "sqlextractor_struct"
+)
+
+// WithBigQuery supports the BigQuery standard SQL syntax the parser rejects:
+// the project.dataset.table names, quoted as a whole or not, UNNEST, STRUCT
+// and array literals, QUALIFY and the wildcard modifiers. The project is
+// reported by TableInfo.Catalog, the dataset by TableInfo.Schema, the column
+// usages qualify the dataset by the project. The named parameters @param are
+// kept in the template.
+//
+// e.g. SELECT x FROM `p.d.t`, UNNEST(arr) AS x WHERE id = @id -> SELECT x FROM p.d.t CROSS JOIN UNNEST(arr) AS x WHERE id eq @id
+func WithBigQuery() Option {
+	return func(e *Extractor) {
+		e.bigquery = true
+		e.qualify = true
+		e.wildcardModifiers = true
+	}
+}
+
+// rewriteBigQuery rewrites the BigQuery syntax to the syntax the parser
+// accepts, the table names are rewritten by rewriteQualifiedNames:
+//
+//	FROM UNNEST(a) AS x WITH OFFSET -> FROM (SELECT sqlextractor_unnest(a, 'WITH OFFSET')) AS x
+//	x IN UNNEST(@a)                 -> x IN (sqlextractor_unnest(@a))
+//	STRUCT<a INT64>(1 AS a)         -> sqlextractor_struct('<a INT64>', 1, 'a')
+//	[1, 2]                          -> ARRAY[1, 2]
+//
+// Quoted strings and comments are kept as is.
+func rewriteBigQuery(sql string) string {
+	if strings.Contains(sql, "[") {
+		sql = rewriteArrayLiterals(sql)
+	}
+	if containsKeyword(sql, "STRUCT") {
+		for {
+			rewritten, ok := rewriteFirstStruct(sql)
+			if !ok {
+				break
+			}
+			sql = rewritten
+		}
+	}
+	if containsKeyword(sql, "UNNEST") {
+		sql = rewriteUnnest(sql)
+	}
+
+	return sql
+}
+
+// rewriteArrayLiterals rewrites the array literals [1, 2] to ARRAY[1, 2],
+// which are rewritten by rewriteArrays. The brackets following an operand are
+// subscripts and kept.
+func rewriteArrayLiterals(sql string) string {
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if sql[i] != '[' {
+			continue
+		}
+
+		before := strings.TrimRight(sql[:i], " \t\r\n")
+		if before == "" || !strings.ContainsRune("(,=+-*/", rune(before[len(before)-1])) {
+			start := len(before)
+			for start > 0 && isIdentChar(before[start-1]) {
+				start--
+			}
+			if word := before[start:]; !hasKeyword(word, "SELECT") && !hasKeyword(word, "THEN") && !hasKeyword(word, "ELSE") {
+				continue
+			}
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 16)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString("ARRAY")
+		last = i
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// rewriteFirstStruct rewrites the first STRUCT literal of sql, the nested ones
+// are rewritten by the following calls.
+func rewriteFirstStruct(sql string) (string, bool) {
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "STRUCT") {
+			continue
+		}
+
+		// STRUCT [<field type, ...>] (expr [AS field], ...)
+		typ := ""
+		open := skipSpace(sql, i+len("STRUCT"))
+		if open < len(sql) && sql[open] == '<' {
+			closing := matchAngle(sql, open)
+			if closing < 0 {
+				continue
+			}
+			typ = sql[open : closing+1]
+			open = skipSpace(sql, closing+1)
+		}
+		if open >= len(sql) || sql[open] != '(' {
+			continue
+		}
+		closing := matchParen(sql, open)
+		if closing < 0 {
+			continue
+		}
+
+		args := []string{mysqlString(typ)}
+		if list := sql[open+1 : closing]; strings.TrimSpace(list) != "" {
+			for _, item := range splitTopLevel(list) {
+				name := ""
+				if idx := lastTopLevelAs(item); idx >= 0 {
+					item, name = item[:idx], strings.TrimSpace(item[idx+len(" AS "):])
+				}
+				args = append(args, strings.TrimSpace(item), mysqlString(name))
+			}
+		}
+
+		var b strings.Builder
+		b.Grow(len(sql) + len(structMarker) + 16)
+		b.WriteString(sql[:i])
+		b.WriteString(structMarker)
+		b.WriteByte('(')
+		b.WriteString(strings.Join(args, ", "))
+		b.WriteByte(')')
+		b.WriteString(sql[closing+1:])
+
+		return b.String(), true
+	}
+
+	return sql, false
+}
+
+// matchAngle returns the index of the angle bracket closing sql[open], or -1.
+func matchAngle(sql string, open int) int {
+	depth := 0
+	for i := open; i < len(sql); i++ {
+		switch sql[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(sql, i)
+		case '<':
+			depth++
+		case '>':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// rewriteUnnest rewrites the UNNEST operators, in the FROM clauses to derived
+// tables of a marker function, after IN to lists of a marker function.
+func rewriteUnnest(sql string) string {
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "UNNEST") {
+			continue
+		}
+
+		open := skipSpace(sql, i+len("UNNEST"))
+		if open >= len(sql) || sql[open] != '(' {
+			continue
+		}
+		closing := matchParen(sql, open)
+		if closing < 0 {
+			continue
+		}
+		expr := rewriteUnnest(sql[open+1 : closing]) // 参数中嵌套的 UNNEST
+
+		var clause string
+		end := closing + 1
+		if before := strings.TrimRight(sql[:i], " \t\r\n"); len(before) >= 2 && hasKeyword(before[len(before)-2:], "IN") &&
+			(len(before) == 2 || !isIdentChar(before[len(before)-3])) {
+			clause = "(" + unnestMarker + "(" + expr + "))"
+		} else {
+			// [AS] alias [WITH OFFSET [AS] offset]
+			alias := unnestMarker
+			j := skipSpace(sql, end)
+			if hasKeyword(sql[j:], "AS") {
+				j = skipSpace(sql, j+len("AS"))
+			}
+			if k := identEnd(sql, j); k > j && !hasKeyword(sql[j:], "WITH") && !isClauseKeyword(sql[j:]) {
+				alias, end = sql[j:k], k
+			}
+
+			options := ""
+			if j = skipSpace(sql, end); hasKeyword(sql[j:], "WITH") {
+				if k := skipSpace(sql, j+len("WITH")); hasKeyword(sql[k:], "OFFSET") {
+					end = k + len("OFFSET")
+					options = "WITH OFFSET"
+
+					k = skipSpace(sql, end)
+					if hasKeyword(sql[k:], "AS") {
+						k = skipSpace(sql, k+len("AS"))
+					}
+					if name := identEnd(sql, k); name > k && !isClauseKeyword(sql[k:]) {
+						options += " AS " + sql[k:name]
+						end = name
+					}
+				}
+			}
+
+			clause = "(SELECT " + unnestMarker + "(" + expr + ", " + mysqlString(options) + ")) AS " + alias
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 64)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString(clause)
+		last, i = end, end-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// unnestCall returns the marker function of a derived table rewritten from
+// UNNEST, nil if it is not.
+func unnestCall(node *ast.SelectStmt) *ast.FuncCallExpr {
+	if node.Fields == nil || len(node.Fields.Fields) != 1 || node.From != nil {
+		return nil
+	}
+
+	fn, ok := node.Fields.Fields[0].Expr.(*ast.FuncCallExpr)
+	if !ok || fn.FnName.L != unnestMarker || len(fn.Args) != 2 {
+		return nil
+	}
+
+	return fn
+}
+
+// writeUnnest 写入 FROM 子句中的 UNNEST，改写时生成的别名不写入
+//
+// e.g. FROM t, UNNEST(t.tags) AS tag WITH OFFSET AS pos -> FROM t, UNNEST(t.tags) AS tag WITH OFFSET AS pos
+func (v *ExtractVisitor) writeUnnest(fn *ast.FuncCallExpr, alias string) {
+	v.builder.WriteString("UNNEST(")
+	fn.Args[0].Accept(v)
+	v.builder.WriteString(")")
+
+	if !strings.EqualFold(alias, unnestMarker) {
+		v.builder.WriteString(" AS ")
+		v.writeIdent(alias)
+	}
+	if options := copyArg(fn, 1); options != "" {
+		v.builder.WriteString(" ")
+		v.builder.WriteString(options)
+	}
+}
+
+// inUnnest returns the argument of x IN UNNEST(arg), nil if the list is not
+// rewritten from UNNEST.
+func inUnnest(node *ast.PatternInExpr) ast.ExprNode {
+	if len(node.List) != 1 {
+		return nil
+	}
+
+	fn, ok := node.List[0].(*ast.FuncCallExpr)
+	if !ok || fn.FnName.L != unnestMarker || len(fn.Args) != 1 {
+		return nil
+	}
+
+	return fn.Args[0]
+}
+
+// writeStruct 写入 STRUCT 字面量，不是标记函数时返回 false
+//
+// e.g. STRUCT<a INT64>(1 AS a) -> STRUCT<a INT64>(? AS a)
+func (v *ExtractVisitor) writeStruct(node *ast.FuncCallExpr) bool {
+	if node.FnName.L != structMarker || len(node.Args)%2 != 1 {
+		return false
+	}
+
+	v.builder.WriteString("STRUCT")
+	v.builder.WriteString(copyArg(node, 0))
+	v.builder.WriteString("(")
+	for idx := 1; idx < len(node.Args); idx += 2 {
+		if idx > 1 {
+			v.builder.WriteString(", ")
+		}
+
+		node.Args[idx].Accept(v)
+		if name := copyArg(node, idx+1); name != "" {
+			v.builder.WriteString(" AS ")
+			v.builder.WriteString(name)
+		}
+	}
+	v.builder.WriteString(")")
+
+	return true
+}
+
+// handleVariableExpr 处理变量，e.g. @a、@@global.max_connections，也是 BigQuery 的命名参数
+func (v *ExtractVisitor) handleVariableExpr(node *ast.VariableExpr) {
+	if node.IsSystem {
+		v.builder.WriteString("@@")
+		if node.ExplicitScope {
+			if node.IsGlobal {
+				v.builder.WriteString("GLOBAL.")
+			} else {
+				v.builder.WriteString("SESSION.")
+			}
+		}
+	} else {
+		v.builder.WriteString("@")
+	}
+	v.builder.WriteString(node.Name)
+
+	if node.Value != nil {
+		v.builder.WriteString(" := ")
+		node.Value.Accept(v)
+	}
+}
@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// clauseOffset is the position of a clause in the template.
+type clauseOffset struct {
+	clause     models.Clause
+	start, end int
+}
+
+// segment records the clause written since start when node is the segmented
+// SELECT, and returns the end of the clause, the start of the next one.
+func (v *ExtractVisitor) segment(node *ast.SelectStmt, clause models.Clause, start int) int {
+	end := v.builder.Len()
+	if node == v.segmented && end > start {
+		v.segments = append(v.segments, clauseOffset{clause: clause, start: start, end: end})
+	}
+
+	return end
+}
+
+// ExtractClauses returns the template of each statement split into its clauses
+// (select list, FROM, WHERE, GROUP BY, HAVING, ORDER BY and LIMIT), in order
+// of position, nil for the statements other than SELECT, e.g. UNION. The
+// segments are those of the outermost query block, subqueries stay within
+// their clauses.
+//
+// e.g. SELECT a FROM t WHERE id = 1 LIMIT 1 -> [SELECT a] [FROM t] [WHERE id eq ?] [LIMIT ?]
+func (e *Extractor) ExtractClauses(sql string) ([][]*models.ClauseSegment, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := make([][]*models.ClauseSegment, 0, len(stmts))
+	for idx := range stmts {
+		var segments []*models.ClauseSegment
+		err := e.visit(stmts[idx], visitClauses, func(v *ExtractVisitor) {
+			template := v.builder.String()
+			for _, off := range v.segments {
+				text := strings.TrimSpace(template[off.start:off.end])
+				segments = append(segments, models.NewClauseSegment(off.clause, text))
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, segments)
+	}
+
+	return clauses, nil
+}
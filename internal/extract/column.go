@@ -0,0 +1,278 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ExtractColumns returns the columns used by each statement, resolved to their
+// original tables, with the clause they are used in: filters (WHERE, HAVING),
+// joins (ON, USING), projections (select fields), ordering (ORDER BY) and
+// writes (UPDATE SET, INSERT columns). A column used several times in the same
+// kind of clause is reported once.
+//
+// Unqualified columns of statements with more than one distinct table are
+// ignored, since they can not be resolved.
+func (e *Extractor) ExtractColumns(sql string) ([][]*models.ColumnUsage, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([][]*models.ColumnUsage, 0, len(stmts))
+	for idx := range stmts {
+		v := &columnVisitor{
+			tables:  make(map[string]*models.TableInfo),
+			clauses: make(map[ast.Node]models.ColumnUsageType),
+		}
+		stmts[idx].Accept(v)
+		columns = append(columns, v.resolve())
+	}
+
+	return columns, nil
+}
+
+// columnUsageRef is a column used by a clause, before it is resolved to a
+// table, table is set if it is already resolved.
+type columnUsageRef struct {
+	qualifier string // table name or alias, e.g. u in u.id
+	table     *models.TableInfo
+	column    string
+	usage     models.ColumnUsageType
+}
+
+// columnVisitor implements ast.Visitor, it collects the columns of the
+// clauses and the tables (and aliases) they may refer to.
+type columnVisitor struct {
+	tables map[string]*models.TableInfo // lower case table name or alias -> table
+	order  []*models.TableInfo          // tables in order of appearance
+
+	clauses map[ast.Node]models.ColumnUsageType // 子句的根节点 -> 使用方式
+	usages  []models.ColumnUsageType            // 当前所在子句的栈
+	refs    []columnUsageRef
+}
+
+// Enter implement ast.Visitor interface.
+func (v *columnVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	if usage, ok := v.clauses[n]; ok {
+		v.usages = append(v.usages, usage)
+	}
+
+	switch node := n.(type) {
+	case *ast.TableSource:
+		if tn, ok := node.Source.(*ast.TableName); ok {
+			ti := models.NewTableInfo(schemaName(tn), tn.Name.O)
+			v.order = append(v.order, ti)
+			v.tables[strings.ToLower(tn.Name.O)] = ti
+			if node.AsName.O != "" {
+				v.tables[strings.ToLower(node.AsName.O)] = ti
+			}
+		}
+
+	case *ast.SelectStmt:
+		if fn := copyCall(node); fn != nil {
+			v.addCopy(fn)
+			break
+		}
+
+		if node.Fields != nil {
+			for _, field := range node.Fields.Fields {
+				v.mark(field.Expr, models.ColumnProjection)
+			}
+		}
+		v.mark(node.Where, models.ColumnFilter)
+		if node.Having != nil {
+			v.mark(node.Having.Expr, models.ColumnFilter)
+		}
+		if node.OrderBy != nil {
+			v.mark(node.OrderBy, models.ColumnOrder)
+		}
+
+	case *ast.UpdateStmt:
+		v.mark(node.Where, models.ColumnFilter)
+		if node.Order != nil {
+			v.mark(node.Order, models.ColumnOrder)
+		}
+		for _, assignment := range node.List {
+			v.addWrite(assignment.Column)
+		}
+
+	case *ast.InsertStmt:
+		for _, column := range node.Columns {
+			v.addWrite(column)
+		}
+		for _, assignment := range node.OnDuplicate {
+			if !isConflictMarker(assignment) && !isSparkMarker(assignment.Column) {
+				v.addWrite(assignment.Column)
+			}
+		}
+		if fn := insertTarget(node); fn != nil {
+			v.addPartition(fn)
+		}
+
+	case *ast.DeleteStmt:
+		v.mark(node.Where, models.ColumnFilter)
+		if node.Order != nil {
+			v.mark(node.Order, models.ColumnOrder)
+		}
+
+	case *ast.Join:
+		if node.On != nil {
+			v.mark(node.On.Expr, models.ColumnJoin)
+		}
+		v.addUsing(node)
+
+	case *ast.BinaryOperationExpr:
+		// Oracle 的 a.id = b.aid(+) 是外连接的连接条件
+		if _, ok := v.clauses[n]; !ok && isOuterJoin(node) {
+			v.mark(node, models.ColumnJoin)
+			v.usages = append(v.usages, models.ColumnJoin)
+		}
+
+	case *ast.ColumnNameExpr:
+		if len(v.usages) > 0 && !isMarkerColumn(node.Name) && !isPseudoColumn(node.Name) {
+			v.refs = append(v.refs, columnUsageRef{
+				qualifier: strings.ToLower(node.Name.Table.O),
+				column:    node.Name.Name.O,
+				usage:     v.usages[len(v.usages)-1],
+			})
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *columnVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	if _, ok := v.clauses[n]; ok {
+		v.usages = v.usages[:len(v.usages)-1]
+	}
+
+	return n, true
+}
+
+// isMarkerColumn reports whether the column is a marker of a rewritten clause,
+// e.g. of @> or OFFSET FETCH, which is not a column of the tables.
+func isMarkerColumn(name *ast.ColumnName) bool {
+	return isArrayMarker(name) || isFetchMarker(name) || isSparkMarker(name)
+}
+
+// mark records the usage of the columns of the clause.
+func (v *columnVisitor) mark(clause ast.Node, usage models.ColumnUsageType) {
+	if clause != nil {
+		v.clauses[clause] = usage
+	}
+}
+
+// addWrite records a written column.
+func (v *columnVisitor) addWrite(column *ast.ColumnName) {
+	v.refs = append(v.refs, columnUsageRef{
+		qualifier: strings.ToLower(column.Table.O),
+		column:    column.Name.O,
+		usage:     models.ColumnWrite,
+	})
+}
+
+// addCopy records the columns of a COPY statement, written by COPY FROM and
+// projected by COPY TO.
+func (v *columnVisitor) addCopy(fn *ast.FuncCallExpr) {
+	usage := models.ColumnProjection
+	if copyArg(fn, 0) == "FROM" {
+		usage = models.ColumnWrite
+	}
+
+	for _, arg := range fn.Args[4:] {
+		if column, ok := arg.(*ast.ColumnNameExpr); ok {
+			v.refs = append(v.refs, columnUsageRef{
+				qualifier: strings.ToLower(column.Name.Table.O),
+				column:    column.Name.Name.O,
+				usage:     usage,
+			})
+		}
+	}
+}
+
+// addPartition records the partition columns of a rewritten INSERT OVERWRITE
+// statement, which are written.
+func (v *columnVisitor) addPartition(fn *ast.FuncCallExpr) {
+	for _, arg := range fn.Args {
+		if eq, ok := arg.(*ast.BinaryOperationExpr); ok {
+			arg = eq.L
+		}
+		if column, ok := arg.(*ast.ColumnNameExpr); ok {
+			v.addWrite(column.Name)
+		}
+	}
+}
+
+// addUsing records the columns of USING for the last table of the left
+// operand and the first table of the right operand.
+func (v *columnVisitor) addUsing(node *ast.Join) {
+	for _, tn := range []*ast.TableName{edgeTable(node.Left, true), edgeTable(node.Right, false)} {
+		if tn == nil {
+			continue
+		}
+
+		ti := models.NewTableInfo(schemaName(tn), tn.Name.O)
+		for idx := range node.Using {
+			v.refs = append(v.refs, columnUsageRef{table: ti, column: node.Using[idx].Name.O, usage: models.ColumnJoin})
+		}
+	}
+}
+
+// resolve resolves the collected columns to their tables, without duplicates.
+func (v *columnVisitor) resolve() []*models.ColumnUsage {
+	type key struct {
+		schema, table, column string
+		usage                 models.ColumnUsageType
+	}
+
+	var (
+		columns = make([]*models.ColumnUsage, 0, len(v.refs))
+		seen    = make(map[key]bool, len(v.refs))
+	)
+	for idx := range v.refs {
+		ref := &v.refs[idx]
+
+		ti := ref.table
+		if ti == nil && ref.qualifier != "" {
+			ti = v.tables[ref.qualifier]
+		} else if ti == nil {
+			ti = v.onlyTable()
+		}
+
+		if ti == nil {
+			continue
+		}
+
+		k := key{schema: ti.Schema(), table: ti.TableName(), column: ref.column, usage: ref.usage}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		columns = append(columns, models.NewColumnUsage(ti.Schema(), ti.TableName(), ref.column, ref.usage))
+	}
+
+	return columns
+}
+
+// onlyTable returns the table of the statement, nil if it has none or more
+// than one distinct table, e.g. a self join or a subquery on the same table
+// has only one.
+func (v *columnVisitor) onlyTable() *models.TableInfo {
+	if len(v.order) == 0 {
+		return nil
+	}
+
+	for _, ti := range v.order[1:] {
+		if !strings.EqualFold(ti.Schema(), v.order[0].Schema()) || !strings.EqualFold(ti.TableName(), v.order[0].TableName()) {
+			return nil
+		}
+	}
+
+	return v.order[0]
+}
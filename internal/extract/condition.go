@@ -0,0 +1,281 @@
+package extract
+
+import (
+	stdslices "slices"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+// ConditionOp is the operator of a Condition.
+type ConditionOp string
+
+const (
+	CondAnd ConditionOp = "and" // all the children hold
+	CondOr  ConditionOp = "or"  // any of the children holds
+	CondNot ConditionOp = "not" // the only child does not hold
+
+	CondEQ     ConditionOp = "eq"
+	CondNE     ConditionOp = "ne"
+	CondLT     ConditionOp = "lt"
+	CondLE     ConditionOp = "le"
+	CondGT     ConditionOp = "gt"
+	CondGE     ConditionOp = "ge"
+	CondNullEQ ConditionOp = "nulleq" // <=>
+
+	CondIn         ConditionOp = "in"
+	CondNotIn      ConditionOp = "not in"
+	CondLike       ConditionOp = "like"
+	CondNotLike    ConditionOp = "not like"
+	CondBetween    ConditionOp = "between"
+	CondNotBetween ConditionOp = "not between"
+	CondIsNull     ConditionOp = "is null"
+	CondIsNotNull  ConditionOp = "is not null"
+
+	// CondExpr is any other predicate, kept as its template in Expr.
+	CondExpr ConditionOp = "expr"
+)
+
+// Condition is a node of the simplified expression tree of a WHERE clause:
+// AND, OR and NOT nodes with children, comparisons of a column with
+// placeholders, and other predicates as their templates.
+type Condition struct {
+	Op       ConditionOp  `json:"op"`
+	Children []*Condition `json:"children,omitempty"` // operands of AND, OR and NOT
+	Column   string       `json:"column,omitempty"`   // compared column as in the template, e.g. u.id
+	Params   []int        `json:"params,omitempty"`   // indexes of the placeholders in the parameters
+	Expr     string       `json:"expr,omitempty"`     // template of the predicate of CondExpr
+}
+
+// comparisonOps are the comparison operators, and their mirrors when the
+// column is on the right hand side, e.g. 1 < a -> a > 1.
+var comparisonOps = map[opcode.Op]struct{ op, mirror ConditionOp }{
+	opcode.EQ:     {CondEQ, CondEQ},
+	opcode.NE:     {CondNE, CondNE},
+	opcode.LT:     {CondLT, CondGT},
+	opcode.LE:     {CondLE, CondGE},
+	opcode.GT:     {CondGT, CondLT},
+	opcode.GE:     {CondGE, CondLE},
+	opcode.NullEQ: {CondNullEQ, CondNullEQ},
+}
+
+// ExtractConditions returns the WHERE clause of each SELECT, UPDATE and DELETE
+// statement as a Condition tree, nil for the statements without WHERE clause.
+// Nested ANDs and ORs are flattened, and the placeholders are indexes in the
+// parameters of the statement, so rule engines can evaluate the conditions
+// with the parameters, without the TiDB AST.
+//
+// e.g. WHERE a = 1 AND (b > 2 OR c IS NULL) -> and(eq(a, 0), or(gt(b, 1), is null(c)))
+func (e *Extractor) ExtractConditions(sql string) ([]*Condition, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := make([]*Condition, 0, len(stmts))
+	for idx := range stmts {
+		var where ast.ExprNode
+		switch node := stmts[idx].(type) {
+		case *ast.SelectStmt:
+			where = node.Where
+		case *ast.UpdateStmt:
+			where = node.Where
+		case *ast.DeleteStmt:
+			where = node.Where
+		}
+		if where == nil {
+			conditions = append(conditions, nil)
+			continue
+		}
+
+		var cond *Condition
+		err := e.visit(stmts[idx], visitSpans, func(v *ExtractVisitor) {
+			cond = v.condition(where, v.builder.String())
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, cond)
+	}
+
+	return conditions, nil
+}
+
+// span is the position of a node in the template.
+type span struct {
+	start, end int
+}
+
+// indexParam records the index of the placeholder of the literal or parameter
+// marker just written.
+func (v *ExtractVisitor) indexParam(node ast.ExprNode) {
+	if v.paramIndex != nil && v.nparams > 0 {
+		v.paramIndex[node] = v.nparams - 1
+	}
+}
+
+// condition returns the Condition of the predicate.
+func (v *ExtractVisitor) condition(expr ast.ExprNode, template string) *Condition {
+	switch node := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return v.condition(node.Expr, template)
+
+	case *ast.BinaryOperationExpr:
+		switch node.Op {
+		case opcode.LogicAnd, opcode.LogicOr:
+			op := CondAnd
+			if node.Op == opcode.LogicOr {
+				op = CondOr
+			}
+
+			cond := &Condition{Op: op}
+			for _, operand := range []ast.ExprNode{node.L, node.R} {
+				child := v.condition(operand, template)
+				if child.Op == op { // 展开嵌套的 AND、OR
+					cond.Children = append(cond.Children, child.Children...)
+				} else {
+					cond.Children = append(cond.Children, child)
+				}
+			}
+			return cond
+		}
+
+		if ops, ok := comparisonOps[node.Op]; ok {
+			if col, idx, ok := v.columnParam(node.L, node.R, template); ok {
+				return &Condition{Op: ops.op, Column: col, Params: []int{idx}}
+			}
+			if col, idx, ok := v.columnParam(node.R, node.L, template); ok {
+				return &Condition{Op: ops.mirror, Column: col, Params: []int{idx}}
+			}
+		}
+
+	case *ast.UnaryOperationExpr:
+		if node.Op == opcode.Not {
+			return &Condition{Op: CondNot, Children: []*Condition{v.condition(node.V, template)}}
+		}
+
+	case *ast.PatternInExpr:
+		col, isCol := v.column(node.Expr, template)
+		if isCol && node.Sel == nil {
+			var params []int
+			for _, item := range node.List {
+				if idx, ok := v.paramIndex[item]; ok {
+					params = append(params, idx)
+				} else if !isParamNode(item) {
+					params = nil
+					break
+				}
+			}
+			if params != nil {
+				return &Condition{Op: pick(node.Not, CondNotIn, CondIn), Column: col, Params: params}
+			}
+		}
+
+	case *ast.PatternLikeOrIlikeExpr:
+		if col, idx, ok := v.columnParam(node.Expr, node.Pattern, template); ok {
+			return &Condition{Op: pick(node.Not, CondNotLike, CondLike), Column: col, Params: []int{idx}}
+		}
+
+	case *ast.BetweenExpr:
+		col, isCol := v.column(node.Expr, template)
+		left, lok := v.paramIndex[node.Left]
+		right, rok := v.paramIndex[node.Right]
+		if isCol && lok && rok {
+			return &Condition{Op: pick(node.Not, CondNotBetween, CondBetween), Column: col, Params: []int{left, right}}
+		}
+
+	case *ast.IsNullExpr:
+		if col, ok := v.column(node.Expr, template); ok {
+			return &Condition{Op: pick(node.Not, CondIsNotNull, CondIsNull), Column: col}
+		}
+	}
+
+	return v.exprCondition(expr, template)
+}
+
+// exprCondition returns the CondExpr of the predicate, with the placeholders
+// it contains.
+func (v *ExtractVisitor) exprCondition(expr ast.ExprNode, template string) *Condition {
+	cond := &Condition{Op: CondExpr}
+	if s, ok := v.spans[expr]; ok {
+		cond.Expr = template[s.start:s.end]
+	}
+
+	collector := &paramCollector{index: v.paramIndex}
+	expr.Accept(collector)
+	stdslices.Sort(collector.params)
+	cond.Params = collector.params
+
+	return cond
+}
+
+// column returns the column of the expression as in the template.
+func (v *ExtractVisitor) column(expr ast.ExprNode, template string) (string, bool) {
+	if _, ok := expr.(*ast.ColumnNameExpr); !ok {
+		return "", false
+	}
+
+	s, ok := v.spans[expr]
+	if !ok {
+		return "", false
+	}
+
+	return template[s.start:s.end], true
+}
+
+// columnParam returns the column of col and the placeholder index of param.
+func (v *ExtractVisitor) columnParam(col, param ast.ExprNode, template string) (string, int, bool) {
+	name, ok := v.column(col, template)
+	if !ok {
+		return "", 0, false
+	}
+
+	idx, ok := v.paramIndex[param]
+
+	return name, idx, ok
+}
+
+// isParamNode reports whether the node is a literal or a parameter marker,
+// which may have no placeholder when the IN list is collapsed.
+func isParamNode(node ast.ExprNode) bool {
+	switch node.(type) {
+	case *test_driver.ValueExpr, *test_driver.ParamMarkerExpr:
+		return true
+	}
+
+	return false
+}
+
+// pick returns not if negated, else op.
+func pick(negated bool, not, op ConditionOp) ConditionOp {
+	if negated {
+		return not
+	}
+
+	return op
+}
+
+// paramCollector implements ast.Visitor, it collects the placeholder indexes
+// of the literals and parameter markers of an expression.
+type paramCollector struct {
+	index  map[ast.ExprNode]int
+	params []int
+}
+
+// Enter implement ast.Visitor interface.
+func (c *paramCollector) Enter(n ast.Node) (ast.Node, bool) {
+	if expr, ok := n.(ast.ExprNode); ok {
+		if idx, found := c.index[expr]; found {
+			c.params = append(c.params, idx)
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (c *paramCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,228 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// ON CONFLICT 子句改写后的标记列和函数名，解析后由 visitor 还原
+const (
+	conflictMarker           = "sqlextractor_conflict"
+	conflictConstraintMarker = "sqlextractor_conflict_constraint"
+	conflictWhereMarker      = "sqlextractor_conflict_where"
+	updateWhereMarker        = "sqlextractor_update_where"
+)
+
+// rewriteOnConflict rewrites the ON CONFLICT clauses of PostgreSQL upserts,
+// which the parser rejects, to ON DUPLICATE KEY UPDATE clauses led by marker
+// assignments:
+//
+//	ON CONFLICT (a) DO NOTHING              -> ON DUPLICATE KEY UPDATE sqlextractor_conflict = sqlextractor_conflict(a)
+//	ON CONFLICT ON CONSTRAINT c DO NOTHING  -> ON DUPLICATE KEY UPDATE sqlextractor_conflict = sqlextractor_conflict_constraint(c)
+//	ON CONFLICT (a) WHERE p DO UPDATE SET b = EXCLUDED.b WHERE q
+//	  -> ON DUPLICATE KEY UPDATE sqlextractor_conflict = sqlextractor_conflict(a),
+//	     sqlextractor_conflict_where = (p), b = EXCLUDED.b, sqlextractor_update_where = (q)
+//
+// Quoted strings, identifiers and comments are kept as is. A clause which can
+// not be rewritten is kept, so the parser reports the error.
+func rewriteOnConflict(sql string) string {
+	if !containsKeyword(sql, "CONFLICT") {
+		return sql
+	}
+
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "ON") {
+			continue
+		}
+
+		start := skipSpace(sql, i+len("ON"))
+		if !hasKeyword(sql[start:], "CONFLICT") {
+			continue
+		}
+
+		clause, end, ok := onConflictClause(sql, start+len("CONFLICT"))
+		if !ok {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 64)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString(clause)
+		last, i = end, end-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// onConflictClause returns the ON DUPLICATE KEY UPDATE clause of the ON
+// CONFLICT clause whose target starts at sql[i], and the end of the clause.
+func onConflictClause(sql string, i int) (string, int, bool) {
+	var items []string
+
+	// conflict_target: (a, b) [WHERE p] | ON CONSTRAINT c
+	i = skipSpace(sql, i)
+	switch {
+	case i < len(sql) && sql[i] == '(':
+		closing := matchParen(sql, i)
+		if closing < 0 {
+			return "", 0, false
+		}
+		items = append(items, conflictMarker+" = "+conflictMarker+sql[i:closing+1])
+
+		i = skipSpace(sql, closing+1)
+		if hasKeyword(sql[i:], "WHERE") {
+			end := clauseEnd(sql, i+len("WHERE"), []string{"DO"})
+			items = append(items, conflictWhereMarker+" = ("+strings.TrimSpace(sql[i+len("WHERE"):end])+")")
+			i = end
+		}
+
+	case hasKeyword(sql[i:], "ON"):
+		j := skipSpace(sql, i+len("ON"))
+		if !hasKeyword(sql[j:], "CONSTRAINT") {
+			return "", 0, false
+		}
+
+		j = skipSpace(sql, j+len("CONSTRAINT"))
+		k := j
+		for k < len(sql) && isIdentChar(sql[k]) {
+			k++
+		}
+		if k == j {
+			return "", 0, false
+		}
+		items = append(items, conflictMarker+" = "+conflictConstraintMarker+"("+sql[j:k]+")")
+		i = skipSpace(sql, k)
+
+	default:
+		items = append(items, conflictMarker+" = "+conflictMarker+"()")
+	}
+
+	// conflict_action: DO NOTHING | DO UPDATE SET ... [WHERE q]
+	if !hasKeyword(sql[i:], "DO") {
+		return "", 0, false
+	}
+	i = skipSpace(sql, i+len("DO"))
+
+	switch {
+	case hasKeyword(sql[i:], "NOTHING"):
+		i += len("NOTHING")
+
+	case hasKeyword(sql[i:], "UPDATE"):
+		j := skipSpace(sql, i+len("UPDATE"))
+		if !hasKeyword(sql[j:], "SET") {
+			return "", 0, false
+		}
+
+		j += len("SET")
+		end := clauseEnd(sql, j, []string{"WHERE", "RETURNING"})
+		set := strings.TrimSpace(sql[j:end])
+		if set == "" {
+			return "", 0, false
+		}
+		items = append(items, set)
+
+		i = end
+		if hasKeyword(sql[i:], "WHERE") {
+			end = clauseEnd(sql, i+len("WHERE"), []string{"RETURNING"})
+			items = append(items, updateWhereMarker+" = ("+strings.TrimSpace(sql[i+len("WHERE"):end])+")")
+			i = end
+		}
+
+	default:
+		return "", 0, false
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(items, ", "), i, true
+}
+
+// isConflictMarker reports whether the assignment is a marker of a rewritten
+// ON CONFLICT clause.
+func isConflictMarker(assignment *ast.Assignment) bool {
+	switch assignment.Column.Name.L {
+	case conflictMarker, conflictWhereMarker, updateWhereMarker:
+		return true
+	}
+
+	return false
+}
+
+// writeOnConflict 写入 ON CONFLICT 子句，assignments 以冲突目标的标记开头
+//
+// e.g. ON CONFLICT (id) DO UPDATE SET hits = t.hits + 1 WHERE t.state = 'a' -> ON CONFLICT (id) DO UPDATE SET hits eq t.hits plus ? WHERE t.state eq ?
+func (v *ExtractVisitor) writeOnConflict(assignments []*ast.Assignment) {
+	v.builder.WriteString(" ON CONFLICT")
+
+	if target, ok := assignments[0].Expr.(*ast.FuncCallExpr); ok {
+		switch {
+		case target.FnName.L == conflictConstraintMarker:
+			v.builder.WriteString(" ON CONSTRAINT ")
+			target.Args[0].Accept(v)
+		case len(target.Args) > 0:
+			v.builder.WriteString(" (")
+			for idx := range target.Args {
+				if idx > 0 {
+					v.builder.WriteString(", ")
+				}
+				target.Args[idx].Accept(v)
+			}
+			v.builder.WriteString(")")
+		}
+	}
+
+	set := assignments[1:]
+	if len(set) > 0 && set[0].Column.Name.L == conflictWhereMarker {
+		v.builder.WriteString(" WHERE ")
+		unwrapParentheses(set[0].Expr).Accept(v)
+		set = set[1:]
+	}
+
+	var where ast.ExprNode
+	if n := len(set); n > 0 && set[n-1].Column.Name.L == updateWhereMarker {
+		where = set[n-1].Expr
+		set = set[:n-1]
+	}
+
+	if len(set) == 0 {
+		v.builder.WriteString(" DO NOTHING")
+		return
+	}
+
+	v.builder.WriteString(" DO UPDATE SET ")
+	for idx := range set {
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
+		set[idx].Accept(v)
+	}
+
+	if where != nil {
+		v.builder.WriteString(" WHERE ")
+		unwrapParentheses(where).Accept(v)
+	}
+}
+
+// unwrapParentheses 去掉改写时加上的括号
+func unwrapParentheses(expr ast.ExprNode) ast.ExprNode {
+	if p, ok := expr.(*ast.ParenthesesExpr); ok {
+		return p.Expr
+	}
+
+	return expr
+}
@@ -0,0 +1,289 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// COPY 语句改写后的函数名和派生表别名，解析后由 visitor 还原
+const (
+	copyMarker       = "sqlextractor_copy"
+	clientCopyMarker = "sqlextractor_client_copy"
+)
+
+// rewriteCopy rewrites the PostgreSQL COPY statements and psql \copy
+// meta-commands, which the parser rejects, to SELECT statements of a marker
+// function, whose arguments are the direction, the kind of source or target,
+// the file or program, the options and the columns:
+//
+//	COPY t (a, b) FROM STDIN WITH (FORMAT csv) -> SELECT sqlextractor_copy('FROM', 'STDIN', NULL, 'WITH (FORMAT csv)', a, b) FROM t
+//	COPY (SELECT ...) TO '/tmp/t.csv'          -> SELECT sqlextractor_copy('TO', 'FILE', '/tmp/t.csv', '') FROM (SELECT ...) AS sqlextractor_copy
+//	\copy t FROM 'data.csv' CSV                -> SELECT sqlextractor_client_copy('FROM', 'FILE', 'data.csv', 'CSV') FROM t
+//
+// A \copy ends at the end of its line. Quoted strings, identifiers and
+// comments are kept as is. A statement which can not be rewritten is kept, so
+// the parser reports the error.
+func rewriteCopy(sql string) string {
+	if !containsKeyword(sql, "COPY") {
+		return sql
+	}
+
+	var (
+		b     strings.Builder
+		last  int    // sql[:last] 已写入 b
+		start = true // sql[i] 位于语句开头
+	)
+
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case isSpace(c):
+			continue
+		case c == ';':
+			start = true
+			continue
+		}
+
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			if c := sql[i]; c == '\'' || c == '"' || c == '`' {
+				start = false
+			}
+			i = j
+			continue
+		}
+
+		if !start {
+			continue
+		}
+		start = false
+
+		client := sql[i] == '\\'
+		keyword := i
+		if client {
+			keyword++
+		}
+		if !hasKeyword(sql[keyword:], "COPY") {
+			continue
+		}
+
+		stmt, end, ok := copyStmt(sql, keyword+len("COPY"), client)
+		if !ok {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 64)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString(stmt)
+		last, i = end, end-1
+		start = client
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// copyStmt returns the marker SELECT statement of the COPY statement whose
+// table or query starts at sql[i], and the end of the statement.
+func copyStmt(sql string, i int, client bool) (string, int, bool) {
+	var from, columns string
+
+	// ( query ) | table [ (a, b) ]
+	i = skipSpace(sql, i)
+	if i < len(sql) && sql[i] == '(' {
+		closing := matchParen(sql, i)
+		if closing < 0 {
+			return "", 0, false
+		}
+		from = sql[i:closing+1] + " AS " + copyMarker
+		i = closing + 1
+	} else {
+		j := i
+		for j < len(sql) && (isIdentChar(sql[j]) || sql[j] == '.') {
+			j++
+		}
+		if j == i {
+			return "", 0, false
+		}
+		from = sql[i:j]
+
+		i = skipSpace(sql, j)
+		if i < len(sql) && sql[i] == '(' {
+			closing := matchParen(sql, i)
+			if closing < 0 {
+				return "", 0, false
+			}
+			columns = strings.TrimSpace(sql[i+1 : closing])
+			i = closing + 1
+		}
+	}
+
+	// FROM | TO
+	i = skipSpace(sql, i)
+	var direction string
+	for _, keyword := range []string{"FROM", "TO"} {
+		if hasKeyword(sql[i:], keyword) {
+			direction = keyword
+		}
+	}
+	if direction == "" {
+		return "", 0, false
+	}
+
+	// STDIN | STDOUT | PROGRAM 'command' | 'file'
+	i = skipSpace(sql, i+len(direction))
+	kind, source := "FILE", "NULL"
+	switch {
+	case hasKeyword(sql[i:], "STDIN"):
+		kind = "STDIN"
+		i += len(kind)
+	case hasKeyword(sql[i:], "STDOUT"):
+		kind = "STDOUT"
+		i += len(kind)
+	case hasKeyword(sql[i:], "PROGRAM"):
+		kind = "PROGRAM"
+		i = skipSpace(sql, i+len(kind))
+		fallthrough
+	default:
+		if i >= len(sql) || sql[i] != '\'' {
+			return "", 0, false
+		}
+		j := skipQuoted(sql, i)
+		if j >= len(sql) {
+			return "", 0, false
+		}
+		source = sql[i : j+1]
+		i = j + 1
+	}
+
+	// 选项直到语句结束，\copy 直到行尾，可以没有分号
+	end := len(sql)
+	if client {
+		if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+			end = i + j
+		}
+	} else {
+		end = clauseEnd(sql, i, nil)
+	}
+	options := strings.TrimSpace(strings.TrimRight(sql[i:end], "; \t\r"))
+
+	marker := copyMarker
+	if client {
+		marker = clientCopyMarker
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(marker)
+	b.WriteString("('")
+	b.WriteString(direction)
+	b.WriteString("', '")
+	b.WriteString(kind)
+	b.WriteString("', ")
+	b.WriteString(source)
+	b.WriteString(", '")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(options, `\`, `\\`), "'", "''"))
+	b.WriteString("'")
+	if columns != "" {
+		b.WriteString(", ")
+		b.WriteString(columns)
+	}
+	b.WriteString(") FROM ")
+	b.WriteString(from)
+	if client && end < len(sql) {
+		b.WriteString(";")
+	}
+
+	return b.String(), end, true
+}
+
+// copyCall returns the marker function of a rewritten COPY statement, nil if
+// the statement is not a COPY statement.
+func copyCall(node *ast.SelectStmt) *ast.FuncCallExpr {
+	if node.Fields == nil || len(node.Fields.Fields) != 1 || node.From == nil {
+		return nil
+	}
+
+	fn, ok := node.Fields.Fields[0].Expr.(*ast.FuncCallExpr)
+	if !ok || (fn.FnName.L != copyMarker && fn.FnName.L != clientCopyMarker) || len(fn.Args) < 4 {
+		return nil
+	}
+
+	return fn
+}
+
+// handleCopy 处理改写后的 COPY 语句，文件名和命令作为参数，选项保持原样
+//
+// e.g. COPY t (a, b) FROM '/tmp/t.csv' WITH (FORMAT csv) -> COPY t (a, b) FROM ? WITH (FORMAT csv)
+func (v *ExtractVisitor) handleCopy(node *ast.SelectStmt, fn *ast.FuncCallExpr) {
+	direction, kind, options := copyArg(fn, 0), copyArg(fn, 1), copyArg(fn, 3)
+
+	if v.opType == models.SQLOperationUnknown {
+		v.opType = models.SQLOperationBulkUnload
+		if direction == "FROM" {
+			v.opType = models.SQLOperationBulkLoad
+		}
+	}
+
+	if fn.FnName.L == clientCopyMarker {
+		v.builder.WriteString("\\copy ")
+	} else {
+		v.builder.WriteString("COPY ")
+	}
+
+	if ts, ok := node.From.TableRefs.Left.(*ast.TableSource); ok {
+		if tn, ok := ts.Source.(*ast.TableName); ok {
+			tn.Accept(v)
+		} else {
+			v.builder.WriteString("(")
+			ts.Source.Accept(v)
+			v.builder.WriteString(")")
+		}
+	}
+
+	if len(fn.Args) > 4 {
+		v.builder.WriteString(" (")
+		for idx, arg := range fn.Args[4:] {
+			if idx > 0 {
+				v.builder.WriteString(", ")
+			}
+			arg.Accept(v)
+		}
+		v.builder.WriteString(")")
+	}
+
+	v.builder.WriteString(" ")
+	v.builder.WriteString(direction)
+	v.builder.WriteString(" ")
+	switch kind {
+	case "FILE":
+		fn.Args[2].Accept(v)
+	case "PROGRAM":
+		v.builder.WriteString("PROGRAM ")
+		fn.Args[2].Accept(v)
+	default:
+		v.builder.WriteString(kind)
+	}
+
+	if options != "" {
+		v.builder.WriteString(" ")
+		v.builder.WriteString(options)
+	}
+}
+
+// copyArg 返回标记函数的第 idx 个字符串参数
+func copyArg(fn *ast.FuncCallExpr, idx int) string {
+	if value, ok := fn.Args[idx].(*test_driver.ValueExpr); ok {
+		return value.GetString()
+	}
+
+	return ""
+}
@@ -0,0 +1,236 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// Factors of the cost score: tables x selectivity x penalties.
+var selectivityFactors = map[models.Selectivity]int{
+	models.SelectivityPoint: 1,
+	models.SelectivityRange: 4,
+	models.SelectivityScan:  16,
+}
+
+// costPenalty multiplies the score of each of the leading wildcard, the
+// function on a column and the unbounded sort.
+const costPenalty = 2
+
+// costTiers are the minimum scores of the tiers above CostLow.
+var costTiers = []struct {
+	score int
+	tier  models.CostTier
+}{
+	{64, models.CostCritical},
+	{16, models.CostHigh},
+	{4, models.CostMedium},
+}
+
+// EstimateCost returns the static cost estimate of each statement, without a
+// database: the score is the table count, times the selectivity factor of its
+// predicates (point 1, range 4, scan 16), times 2 for each of LIKE '%...',
+// functions on columns in predicates and ORDER BY without LIMIT. The tier is
+// LOW below 4, MEDIUM below 16, HIGH below 64 and CRITICAL otherwise.
+//
+// e.g. SELECT * FROM a JOIN b ON a.id = b.aid ORDER BY b.t -> 2 tables x scan x unbounded sort = 64, CRITICAL
+func (e *Extractor) EstimateCost(sql string) ([]*models.CostEstimate, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := make([]*models.CostEstimate, 0, len(stmts))
+	for idx := range stmts {
+		v := &costVisitor{selectivity: models.SelectivityPoint}
+		stmts[idx].Accept(v)
+		estimates = append(estimates, v.estimate())
+	}
+
+	return estimates, nil
+}
+
+// costVisitor implements ast.Visitor, it collects the cost factors of a
+// statement.
+type costVisitor struct {
+	tables           int
+	selectivity      models.Selectivity // 各查询块中最差的选择性
+	leadingWildcard  bool
+	functionOnColumn bool
+	unboundedSort    bool
+}
+
+// Enter implement ast.Visitor interface.
+func (v *costVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.TableSource:
+		v.tables++
+
+	case *ast.SelectStmt:
+		if node.From != nil {
+			class := v.where(node.Where)
+			if class == models.SelectivityScan && node.Limit != nil && node.OrderBy == nil {
+				class = models.SelectivityRange // 读取到 LIMIT 行即停止
+			}
+			v.block(class)
+		}
+		if node.OrderBy != nil && node.Limit == nil && node.From != nil {
+			v.unboundedSort = true
+		}
+
+	case *ast.SetOprStmt:
+		if node.OrderBy != nil && node.Limit == nil {
+			v.unboundedSort = true
+		}
+
+	case *ast.UpdateStmt:
+		v.block(v.where(node.Where))
+
+	case *ast.DeleteStmt:
+		v.block(v.where(node.Where))
+
+	case *ast.Join:
+		if node.On != nil {
+			v.where(node.On.Expr) // 只检查惩罚项，连接条件不决定选择性
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *costVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// block merges the selectivity of a query block, keeping the worst.
+func (v *costVisitor) block(class models.Selectivity) {
+	if selectivityFactors[class] > selectivityFactors[v.selectivity] {
+		v.selectivity = class
+	}
+}
+
+// where returns the selectivity of the WHERE clause, a scan if it is nil.
+func (v *costVisitor) where(expr ast.ExprNode) models.Selectivity {
+	if expr == nil {
+		return models.SelectivityScan
+	}
+
+	return v.selectivityOf(expr)
+}
+
+// selectivityOf returns the selectivity of the predicate: the best of the
+// conjuncts of AND, the worst of the disjuncts of OR.
+func (v *costVisitor) selectivityOf(expr ast.ExprNode) models.Selectivity {
+	switch node := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return v.selectivityOf(node.Expr)
+
+	case *ast.BinaryOperationExpr:
+		switch node.Op {
+		case opcode.LogicAnd:
+			l, r := v.selectivityOf(node.L), v.selectivityOf(node.R)
+			if selectivityFactors[l] < selectivityFactors[r] {
+				return l
+			}
+			return r
+		case opcode.LogicOr:
+			l, r := v.selectivityOf(node.L), v.selectivityOf(node.R)
+			if selectivityFactors[l] > selectivityFactors[r] {
+				return l
+			}
+			return r
+		case opcode.EQ, opcode.NullEQ:
+			return v.comparison(node.L, node.R, models.SelectivityPoint)
+		case opcode.LT, opcode.LE, opcode.GT, opcode.GE:
+			return v.comparison(node.L, node.R, models.SelectivityRange)
+		}
+
+	case *ast.PatternInExpr:
+		if v.column(node.Expr) && !node.Not {
+			if node.Sel != nil {
+				return models.SelectivityRange
+			}
+			return models.SelectivityPoint
+		}
+
+	case *ast.BetweenExpr:
+		if v.column(node.Expr) && !node.Not {
+			return models.SelectivityRange
+		}
+
+	case *ast.IsNullExpr:
+		if v.column(node.Expr) && !node.Not {
+			return models.SelectivityRange
+		}
+
+	case *ast.PatternLikeOrIlikeExpr:
+		if !v.column(node.Expr) || node.Not {
+			break
+		}
+		if pattern, ok := node.Pattern.(*test_driver.ValueExpr); ok {
+			if s, isStr := pattern.GetValue().(string); isStr && s != "" && (s[0] == '%' || s[0] == '_') {
+				v.leadingWildcard = true
+				break
+			}
+		}
+		return models.SelectivityRange
+	}
+
+	return models.SelectivityScan
+}
+
+// comparison returns the selectivity of a comparison of a column with a value,
+// a scan if the column is wrapped in a function or compared with a column.
+func (v *costVisitor) comparison(l, r ast.ExprNode, class models.Selectivity) models.Selectivity {
+	lcol, rcol := v.column(l), v.column(r)
+	if lcol != rcol && (isConstant(l) || isConstant(r)) {
+		return class
+	}
+
+	return models.SelectivityScan
+}
+
+// column reports whether expr is a bare column, and flags the functions of
+// columns.
+func (v *costVisitor) column(expr ast.ExprNode) bool {
+	switch node := expr.(type) {
+	case *ast.ColumnNameExpr:
+		return true
+	case *ast.FuncCallExpr:
+		for _, arg := range node.Args {
+			if _, ok := arg.(*ast.ColumnNameExpr); ok {
+				v.functionOnColumn = true
+			}
+		}
+	case *ast.FuncCastExpr:
+		if _, ok := node.Expr.(*ast.ColumnNameExpr); ok {
+			v.functionOnColumn = true
+		}
+	}
+
+	return false
+}
+
+// estimate returns the cost estimate of the collected factors.
+func (v *costVisitor) estimate() *models.CostEstimate {
+	score := v.tables * selectivityFactors[v.selectivity]
+	for _, penalty := range []bool{v.leadingWildcard, v.functionOnColumn, v.unboundedSort} {
+		if penalty {
+			score *= costPenalty
+		}
+	}
+
+	tier := models.CostLow
+	for _, t := range costTiers {
+		if score >= t.score {
+			tier = t.tier
+			break
+		}
+	}
+
+	return models.NewCostEstimate(v.tables, v.selectivity, v.leadingWildcard, v.functionOnColumn, v.unboundedSort,
+		score, tier)
+}
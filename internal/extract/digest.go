@@ -0,0 +1,163 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// templateBuilder builds the template of a statement. When digesting, the
+// written bytes are fed to the digest hash as they are written, in chunks, so
+// the digest needs no second pass over the template. In digest only mode, the
+// template itself is not kept.
+type templateBuilder struct {
+	strings.Builder
+
+	digest  hash.Hash // 模板的摘要，为 nil 时不计算
+	discard bool      // 只计算摘要，不保留模板
+	buf     [512]byte // 待写入摘要的字节
+	n       int
+}
+
+// Write implements io.Writer.
+func (b *templateBuilder) Write(p []byte) (int, error) {
+	if b.digest != nil {
+		feed(b, p)
+		if b.discard {
+			return len(p), nil
+		}
+	}
+
+	return b.Builder.Write(p)
+}
+
+// WriteString implements io.StringWriter.
+func (b *templateBuilder) WriteString(s string) (int, error) {
+	if b.digest != nil {
+		feed(b, s)
+		if b.discard {
+			return len(s), nil
+		}
+	}
+
+	return b.Builder.WriteString(s)
+}
+
+// WriteByte implements io.ByteWriter.
+func (b *templateBuilder) WriteByte(c byte) error {
+	if b.digest != nil {
+		if b.n == len(b.buf) {
+			b.flush()
+		}
+		b.buf[b.n] = c
+		b.n++
+
+		if b.discard {
+			return nil
+		}
+	}
+
+	return b.Builder.WriteByte(c)
+}
+
+// feed copies the bytes to buf, and writes buf to the digest when it is full.
+func feed[T string | []byte](b *templateBuilder, s T) {
+	for len(s) > 0 {
+		if b.n == len(b.buf) {
+			b.flush()
+		}
+		n := copy(b.buf[b.n:], s)
+		b.n += n
+		s = s[n:]
+	}
+}
+
+func (b *templateBuilder) flush() {
+	_, _ = b.digest.Write(b.buf[:b.n])
+	b.n = 0
+}
+
+// Sum returns the hex encoded digest of the written template.
+func (b *templateBuilder) Sum() string {
+	b.flush()
+	return hex.EncodeToString(b.digest.Sum(nil))
+}
+
+// Reset resets the builder, and stops digesting.
+func (b *templateBuilder) Reset() {
+	b.Builder.Reset()
+	b.digest = nil
+	b.discard = false
+	b.n = 0
+}
+
+// Digests returns the digest, the hex encoded sha256 of the template, of each
+// statement of sql. It is the sha256 of the templates returned by Extract, but
+// the digest is computed while the statement is templatized and the templates
+// are not kept, so large statements take a single pass. Hooks are not invoked.
+func (e *Extractor) Digests(sql string) ([]string, error) {
+	if sql == "" {
+		return nil, errors.New("empty SQL statement")
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stmts) == 0 {
+		return nil, errors.New("no valid SQL statements found")
+	}
+
+	var (
+		digests = make([]string, 0, len(stmts))
+		st      = newExtractState()
+	)
+
+	for idx := range stmts {
+		digest, err := e.digestStmt(stmts[idx], st)
+		if err != nil {
+			return nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+		}
+
+		digests = append(digests, digest)
+	}
+
+	return digests, nil
+}
+
+// digestStmt returns the digest of a statement.
+func (e *Extractor) digestStmt(stmt ast.StmtNode, st *extractState) (string, error) {
+	switch stmt.(type) {
+	case *ast.PrepareStmt, *ast.ExecuteStmt, *ast.DeallocateStmt:
+		// 模板依赖同一输入中的其他语句，不经过 visitor
+		templatedSQL, _, _, _, err := e.extractStmt(stmt, st)
+		if err != nil {
+			return "", err
+		}
+
+		sum := sha256.Sum256([]byte(templatedSQL))
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	var (
+		digest   string
+		overflow int
+	)
+	err := e.visit(stmt, visitDigest, func(v *ExtractVisitor) {
+		digest = v.builder.Sum()
+		if v.overflowed() {
+			overflow = v.nparams
+		}
+	})
+	if err == nil {
+		err = e.checkOverflow(overflow, st)
+	}
+
+	return digest, err
+}
@@ -20,7 +20,7 @@
 //	// opType: models.SQLOperationSelect
 //
 // The package implements a visitor pattern through ExtractVisitor to traverse the AST (Abstract Syntax Tree)
-// generated by the SQL parser. It maintains a pool of visitors for better performance when processing
+// generated by the SQL parser. It maintains pools of parsers and visitors for better performance when processing
 // multiple SQL statements.
 //
 // Key Features:
@@ -29,7 +29,7 @@
 //   - Parameter extraction: Collects literal values from the SQL in order of appearance
 //   - Operation type detection: Identifies the type of SQL operation (SELECT/INSERT/UPDATE/DELETE)
 //   - Multi-statement support: Can process multiple SQL statements separated by semicolons
-//   - Thread-safe: Uses sync.Pool for visitor objects to handle concurrent processing
+//   - Thread-safe: Uses sync.Pool for parser and visitor objects to handle concurrent processing
 //
 // The package is designed to be used internally by the sql-extractor tool and provides
 // the core functionality for SQL analysis and transformation.
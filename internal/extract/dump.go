@@ -0,0 +1,48 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// DumpStmt is the result of a statement of a dump.
+type DumpStmt struct {
+	TemplatizedSQL string
+	TableInfos     []*models.TableInfo
+	OpType         models.SQLOpType
+	Rows           int // number of rows of INSERT ... VALUES, 0 otherwise
+}
+
+// ExtractDump extracts the statements of a dump, e.g. mysqldump output, with
+// the number of rows of INSERT ... VALUES statements. Parameters are not
+// collected, create the Extractor WithCollapsedValues to only visit the first
+// row of extended INSERTs.
+func (e *Extractor) ExtractDump(sql string) ([]DumpStmt, error) {
+	if sql == "" {
+		return nil, errors.New("empty SQL statement")
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]DumpStmt, 0, len(stmts))
+	for idx := range stmts {
+		err := e.visitStmt(stmts[idx], func(v *ExtractVisitor) {
+			results = append(results, DumpStmt{
+				TemplatizedSQL: v.builder.String(),
+				TableInfos:     uniqTableInfos(v.tableInfos),
+				OpType:         v.opType,
+				Rows:           v.rows,
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,27 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// Explainable reports whether each statement can be explained by EXPLAIN:
+// SELECT (and set operations), INSERT, REPLACE, UPDATE and DELETE. EXECUTE is
+// not, even if the prepared statement is.
+func (e *Extractor) Explainable(sql string) ([]bool, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	explainable := make([]bool, 0, len(stmts))
+	for idx := range stmts {
+		switch stmts[idx].(type) {
+		case *ast.SelectStmt, *ast.SetOprStmt, *ast.InsertStmt, *ast.UpdateStmt, *ast.DeleteStmt:
+			explainable = append(explainable, true)
+		default:
+			explainable = append(explainable, false)
+		}
+	}
+
+	return explainable, nil
+}
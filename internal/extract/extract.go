@@ -1,8 +1,12 @@
 package extract
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	stdslices "slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -10,6 +14,9 @@ import (
 	"github.com/kydance/ziwi/slices"
 	"github.com/pingcap/tidb/pkg/parser"
 	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
 	"github.com/pingcap/tidb/pkg/parser/test_driver"
 
 	"github.com/kydance/sql-extractor/internal/models"
@@ -18,46 +25,341 @@ import (
 const (
 	paramsMaxCount   = 64
 	tablePlaceholder = "?"
+
+	// largeStmtLen is the length from which a statement is considered large,
+	// e.g. bulk INSERTs, and is handled by the large visitor pool.
+	largeStmtLen = 16 << 10
+	// bytesPerParam is the estimated length of SQL text per parameter of large
+	// statements, used to pre-size params, e.g. "(1, 'abc'), " is about 6 per param.
+	bytesPerParam = 8
+	// paramsRetainFactor limits the params capacity retained by pooled visitors
+	// to paramsRetainFactor times the capacity of their pool tier.
+	paramsRetainFactor = 4
+	// largeParamsCapacity is the params capacity of the large visitor pool.
+	largeParamsCapacity = 4 << 10
 )
 
+// Extractor is safe for concurrent use: both parsers and visitors are pooled,
+// so each goroutine works on its own instances.
+//
+// Memory behavior: visitors are pooled in two tiers by statement length. Small
+// statements use visitors with WithParamsCapacity (default 64) params capacity.
+// Large statements (e.g. bulk INSERTs with 10k values) use the large tier, where
+// params are pre-sized from the statement length to avoid repeated growth. When
+// a visitor's params grew beyond paramsRetainFactor times the capacity of its
+// tier, the params are released instead of being retained by the pool, so a
+// single huge statement does not pin its memory.
 type Extractor struct {
-	parser *parser.Parser
+	parserPool sync.Pool
+	pools      [stmtTierCount]sync.Pool // visitor pools, by stmtTier
+
+	paramsCapacity int    // params capacity of the small visitor pool
+	hooks          *Hooks // callbacks invoked by Extract, may be nil
+
+	handlers map[reflect.Type]NodeHandler // 用户注册的节点处理函数
+
+	placeholder   func(n int) string // 参数占位符，为 nil 时使用 ?
+	listHolder    func(n int) string // 字面量 IN 列表的列表占位符，为 nil 时逐项写入占位符
+	standardOps   bool               // 使用标准 SQL 运算符，而不是 eq、gt 等
+	assignment    AssignmentStyle    // SET 子句中赋值运算符的样式
+	rawTableNames bool               // 不模板化表名
+	named         bool               // 使用命名参数占位符 :name
+	collapse      bool               // INSERT VALUES 只保留第一行
+	paramMarkers  bool               // 参数标记 ? 作为参数收集
+	foldIdents    bool               // 标识符转为小写，仅在必要时加引号
+	quoting       IdentifierQuoting  // 标识符的引号
+	collapseIn    bool               // IN 列表只保留第一项
+	limitOffset   bool               // LIMIT 写为 LIMIT count OFFSET offset
+	sqlglot       bool               // 与 sqlglot 的输出一致
+	noParams      bool               // 不收集参数
+	noTables      bool               // 不收集表信息
+	maxParams     int                // 每条语句的参数个数上限，为 0 时不限制
+	overflow      ParamsOverflow     // 参数个数超出上限时的策略
+
+	maxParamBytes  int // 字符串参数的字节数上限，为 0 时不限制
+	blobParamBytes int // 跳过不小于该字节数的二进制字面量，为 0 时不跳过
+
+	wildcardModifiers bool // 支持 * EXCEPT (...)、* REPLACE (...)
+	qualify           bool // 支持 QUALIFY 子句
+	optimizerHints    bool // 保留语句开头的优化器提示
+	snowflake         bool // 支持 Snowflake 语法和标识符大小写规则
+	bigquery          bool // 支持 BigQuery 语法和 project.dataset.table 表名
+	postgres          bool // 支持 PostgreSQL 语法
+	sqlserver         bool // 支持 SQL Server 语法
+	oracle            bool // 支持 Oracle 语法
+	spark             bool // 支持 Spark SQL / Hive 语法
+
+	tenantPattern *regexp.Regexp // 租户 schema 的匹配模式，为 nil 时不映射
+	tenantSchema  string         // 租户 schema 映射到的规范 schema
+}
+
+// ParamMarker is the parameter collected for the 0-based order-th parameter
+// marker ? of a prepared statement, see WithParamMarkers.
+type ParamMarker int
+
+// Option configures the Extractor.
+type Option func(*Extractor)
+
+// WithPlaceholder renders the parameters by fn instead of ?, n is the 1-based
+// index of the parameter in the statement.
+func WithPlaceholder(fn func(n int) string) Option {
+	return func(e *Extractor) { e.placeholder = fn }
+}
+
+// WithListPlaceholder renders an IN list of literals as the single list
+// placeholder fn(n), whose parameter is the []any of the values, n is the
+// 1-based index of the parameter in the statement. The lists with other
+// expressions are rendered item by item.
+//
+// e.g. a IN (1, 2) AND b = 3 -> a IN ::vtg1 AND b = :vtg2, params: [1 2], 3
+func WithListPlaceholder(fn func(n int) string) Option {
+	return func(e *Extractor) { e.listHolder = fn }
+}
+
+// WithLimitOffset renders LIMIT offset, count as LIMIT count OFFSET offset,
+// which the other dialects such as PostgreSQL accept.
+//
+// e.g. SELECT * FROM t LIMIT 10, 20 -> SELECT * FROM t LIMIT ? OFFSET ?, params: 20, 10
+func WithLimitOffset() Option {
+	return func(e *Extractor) { e.limitOffset = true }
+}
+
+// AssignmentStyle is the operator of the assignments in SET clauses (UPDATE,
+// INSERT ... SET and ON DUPLICATE KEY UPDATE).
+type AssignmentStyle int
+
+const (
+	// AssignmentFollowOps follows the operator style: eq by default, = with
+	// WithStandardOperators.
+	AssignmentFollowOps AssignmentStyle = iota
+
+	// AssignmentSymbolic renders =, so the SET clauses are executable SQL
+	// whatever the operator style.
+	AssignmentSymbolic
+
+	// AssignmentWord renders eq, as the comparisons by default.
+	AssignmentWord
+)
+
+// WithAssignmentStyle sets the operator of the assignments in SET clauses,
+// AssignmentFollowOps by default.
+//
+// e.g. AssignmentSymbolic: UPDATE t SET a = 1 WHERE b = 2 -> UPDATE t SET a = ? WHERE b eq ?
+func WithAssignmentStyle(style AssignmentStyle) Option {
+	return func(e *Extractor) { e.assignment = style }
+}
+
+// WithBindVarPrefix renders the parameters as named bind variables :<prefix><N>
+// instead of ?, N is the 1-based index of the parameter in the statement.
+//
+// e.g. WithBindVarPrefix("vtg"): SELECT * FROM t WHERE a = 1 -> SELECT * FROM t WHERE a eq :vtg1
+func WithBindVarPrefix(prefix string) Option {
+	return WithPlaceholder(func(n int) string { return ":" + prefix + strconv.Itoa(n) })
+}
 
-	pool sync.Pool
+// WithStandardOperators renders the standard SQL operators (=, >, <>, AND, -, ~, ...)
+// instead of the eq, gt, ne, and, minus, bitneg tokens, so the template is valid
+// SQL. The default templates are not valid SQL.
+func WithStandardOperators() Option {
+	return func(e *Extractor) { e.standardOps = true }
 }
 
-func NewExtractor() *Extractor {
-	return &Extractor{
-		parser: parser.New(),
-		pool: sync.Pool{
+// WithNamedParams renders the parameters as named placeholders :name, the name
+// is the column the parameter is compared with or assigned to, suffixed with _2,
+// _3, ... when the column is used more than once, or p<N> when there is no such
+// column. The names are returned by ExtractNamed.
+//
+// e.g. SELECT * FROM t WHERE a = 1 AND a < 9 LIMIT 10 -> SELECT * FROM t WHERE a eq :a and a lt :a_2 LIMIT :limit
+func WithNamedParams() Option {
+	return func(e *Extractor) { e.named = true }
+}
+
+// WithParamsCapacity sets the initial params capacity of the pooled visitors for
+// small statements, default 64. Large statements are sized by their length.
+func WithParamsCapacity(capacity int) Option {
+	return func(e *Extractor) {
+		if capacity > 0 {
+			e.paramsCapacity = capacity
+		}
+	}
+}
+
+// stmtTier is the size tier of a statement, which decides the visitor pool.
+type stmtTier int
+
+const (
+	stmtTierSmall stmtTier = iota
+	stmtTierLarge
+	stmtTierCount
+)
+
+// tierOf returns the size tier of the statement by its length.
+func tierOf(stmt ast.StmtNode) stmtTier {
+	if len(stmt.Text()) >= largeStmtLen {
+		return stmtTierLarge
+	}
+
+	return stmtTierSmall
+}
+
+// capacityOf returns the params capacity of the tier.
+func (e *Extractor) capacityOf(tier stmtTier) int {
+	if tier == stmtTierLarge {
+		return max(largeParamsCapacity, e.paramsCapacity)
+	}
+
+	return e.paramsCapacity
+}
+
+// WithCollapsedValues collapses the VALUES of INSERT statements to their first
+// row, so extended INSERTs share the same template whatever their number of
+// rows, and only the parameters of the first row are collected.
+//
+// e.g. INSERT INTO t VALUES (1, 'a'), (2, 'b') -> INSERT INTO t VALUES (?, ?)
+func WithCollapsedValues() Option {
+	return func(e *Extractor) { e.collapse = true }
+}
+
+// WithCollapsedIn collapses the IN lists to their first item, so IN lists of
+// any size share the same template, and only the parameter of the first item
+// is collected.
+//
+// e.g. SELECT * FROM t WHERE a IN (1, 2, 3) -> SELECT * FROM t WHERE a IN (?)
+func WithCollapsedIn() Option {
+	return func(e *Extractor) { e.collapseIn = true }
+}
+
+// WithParamMarkers collects the parameter markers ? of prepared statements as
+// ParamMarker parameters, so the values bound on execution can be placed among
+// the literals of the statement.
+//
+// e.g. SELECT * FROM t WHERE a = ? AND b = 1 -> params: ParamMarker(0), 1
+func WithParamMarkers() Option {
+	return func(e *Extractor) { e.paramMarkers = true }
+}
+
+// WithCollectParams sets whether the parameters are collected, true by default.
+// Without collecting, the literals are replaced by placeholders but their
+// values are neither boxed nor stored, and the parameters are empty, for
+// callers which only need the templates or digests.
+func WithCollectParams(collect bool) Option {
+	return func(e *Extractor) { e.noParams = !collect }
+}
+
+// WithCollectTables sets whether the table infos are collected, true by
+// default. Without collecting, the table infos are empty.
+func WithCollectTables(collect bool) Option {
+	return func(e *Extractor) { e.noTables = !collect }
+}
+
+// WithRawTableNames keeps the original table names instead of templatizing
+// sharded table names, e.g. tb_10 is kept as is instead of tb_?.
+func WithRawTableNames() Option {
+	return func(e *Extractor) { e.rawTableNames = true }
+}
+
+func NewExtractor(opts ...Option) *Extractor {
+	e := &Extractor{
+		parserPool: sync.Pool{
+			New: func() any { return parser.New() },
+		},
+		paramsCapacity: paramsMaxCount,
+		handlers:       make(map[reflect.Type]NodeHandler),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for tier := range stmtTierCount {
+		e.pools[tier] = sync.Pool{
 			New: func() any {
-				return &ExtractVisitor{
-					builder:    &strings.Builder{},
-					params:     make([]any, 0, paramsMaxCount),
-					tableInfos: make([]*models.TableInfo, 0, paramsMaxCount),
-					opType:     models.SQLOperationUnknown,
+				v := &ExtractVisitor{
+					builder:       &templateBuilder{},
+					opType:        models.SQLOperationUnknown,
+					placeholder:   e.placeholder,
+					listHolder:    e.listHolder,
+					standardOps:   e.standardOps,
+					assignment:    e.assignment,
+					rawTableNames: e.rawTableNames,
+					named:         e.named,
+					collapse:      e.collapse,
+					paramMarkers:  e.paramMarkers,
+					foldIdents:    e.foldIdents,
+					quoting:       e.quoting,
+					collapseIn:    e.collapseIn,
+					limitOffset:   e.limitOffset,
+					sqlglot:       e.sqlglot,
+					noParams:      e.noParams,
+					noTables:      e.noTables,
+					maxParams:     e.maxParams,
+
+					maxParamBytes:  e.maxParamBytes,
+					blobParamBytes: e.blobParamBytes,
+
+					wildcardModifiers: e.wildcardModifiers,
+					qualify:           e.qualify,
+					optimizerHints:    e.optimizerHints,
+
+					tenantPattern: e.tenantPattern,
+					tenantSchema:  e.tenantSchema,
+
+					handlers: e.handlers,
+				}
+				if !e.noParams {
+					v.params = make([]any, 0, e.capacityOf(tier))
 				}
+				if !e.noTables {
+					v.tableInfos = make([]*models.TableInfo, 0, paramsMaxCount)
+				}
+
+				return v
 			},
-		},
+		}
 	}
+
+	return e
 }
 
 // Extract returns the templatized SQL, table info, parameters and operation type.
 // It supports multiple SQL statements separated by semicolons.
 func (e *Extractor) Extract(sql string) (
 	[]string, [][]*models.TableInfo, [][]any, []models.SQLOpType, error,
+) {
+	templatedSQL, tableInfos, params, op, _, err := e.ExtractWithWarnings(sql)
+
+	return templatedSQL, tableInfos, params, op, err
+}
+
+// ExtractWithWarnings is Extract, which also returns the warnings reported by
+// the parser, e.g. deprecated syntax.
+func (e *Extractor) ExtractWithWarnings(sql string) (
+	[]string, [][]*models.TableInfo, [][]any, []models.SQLOpType, []string, error,
+) {
+	templatedSQL, tableInfos, params, op, warnings, err := e.extract(sql)
+	if err != nil {
+		e.hooks.error(err)
+	}
+
+	return templatedSQL, tableInfos, params, op, warnings, err
+}
+
+func (e *Extractor) extract(sql string) (
+	[]string, [][]*models.TableInfo, [][]any, []models.SQLOpType, []string, error,
 ) {
 	if sql == "" {
-		return nil, nil, nil, nil, errors.New("empty SQL statement")
+		return nil, nil, nil, nil, nil, errors.New("empty SQL statement")
 	}
 
-	stmts, _, err := e.parser.Parse(sql, "", "")
+	e.hooks.parseStart(len(sql))
+	start := e.hooks.now()
+	stmts, warns, err := e.parseWithWarnings(sql)
+	e.hooks.parseEnd(len(sql), start, err)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 
 	if len(stmts) == 0 {
-		return nil, nil, nil, nil, errors.New("no valid SQL statements found")
+		return nil, nil, nil, nil, nil, errors.New("no valid SQL statements found")
 	}
 
 	// Handle multiple statements
@@ -66,12 +368,23 @@ func (e *Extractor) Extract(sql string) (
 		allParams         = make([][]any, 0, len(stmts))
 		allTableInfos     = make([][]*models.TableInfo, 0, len(stmts))
 		opType            = make([]models.SQLOpType, 0, len(stmts))
+
+		st = newExtractState()
 	)
 
 	for idx := range stmts {
-		templatedSQL, tableInfos, params, op, err := e.extractOneStmt(stmts[idx])
+		start := e.hooks.now()
+		templatedSQL, tableInfos, params, op, err := e.extractStmt(stmts[idx], st)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+		}
+		e.hooks.statementDone(idx, stmts[idx], op, start)
+		st.trackTemporary(stmts[idx], tableInfos)
+
+		if st.truncated > 0 {
+			warns = append(warns, fmt.Sprintf("statement %d: %d parameters truncated to %d (%s)",
+				idx+1, st.truncated, e.maxParams, e.overflow))
+			st.truncated = 0
 		}
 
 		allTemplatizedSQL = append(allTemplatizedSQL, templatedSQL)
@@ -80,51 +393,590 @@ func (e *Extractor) Extract(sql string) (
 		opType = append(opType, op)
 	}
 
-	return allTemplatizedSQL, allTableInfos, allParams, opType, nil
+	return allTemplatizedSQL, allTableInfos, allParams, opType, warns, nil
+}
+
+// parse parses the SQL string with a pooled parser.
+func (e *Extractor) parse(sql string) ([]ast.StmtNode, error) {
+	stmts, _, err := e.parseWithWarnings(sql)
+	return stmts, err
+}
+
+// parseWithWarnings parses the SQL string with a pooled parser, and returns the
+// parser warnings, nil if there is none.
+func (e *Extractor) parseWithWarnings(sql string) ([]ast.StmtNode, []string, error) {
+	p, ok := e.parserPool.Get().(*parser.Parser)
+	if !ok {
+		return nil, nil, errors.New("failed to get Parser from pool")
+	}
+	defer e.parserPool.Put(p)
+
+	if e.postgres {
+		sql = rewritePostgresStrings(sql)
+	}
+	if e.snowflake {
+		sql = rewriteSnowflake(sql)
+	}
+	if e.sqlserver || e.snowflake || e.bigquery {
+		sql = rewriteQualifiedNames(sql, e.bigquery)
+	}
+	if e.bigquery {
+		sql = rewriteBigQuery(sql)
+	}
+	if e.wildcardModifiers {
+		sql = rewriteWildcardModifiers(sql)
+	}
+	if e.qualify {
+		sql = rewriteQualify(sql)
+	}
+	sql = rewriteLateral(sql)
+	if e.spark {
+		sql = rewriteSpark(sql)
+	}
+	if e.postgres {
+		sql = rewriteOnConflict(sql)
+		sql = rewriteCopy(sql)
+	}
+	if e.postgres || e.bigquery {
+		sql = rewriteArrays(sql)
+	}
+	if e.sqlserver || e.postgres || e.oracle {
+		sql = rewritePagination(sql, e.sqlserver)
+	}
+	if e.oracle {
+		sql = rewriteOuterJoins(sql)
+	}
+
+	stmts, warns, err := p.Parse(sql, "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	for idx := range warns {
+		warnings = append(warnings, warns[idx].Error())
+	}
+
+	// 返回的切片会在 parser 下次解析时被复用，需要拷贝
+	return stdslices.Clone(stmts), warnings, nil
 }
 
-// extractOneStmt handles a single SQL statement
-func (e *Extractor) extractOneStmt(stmt ast.StmtNode) (
+// ExtractNamed returns the templatized SQL with named parameters and the named
+// arguments of each statement, the Extractor should be created WithNamedParams.
+func (e *Extractor) ExtractNamed(sql string) ([]string, []map[string]any, error) {
+	if !e.named {
+		return nil, nil, errors.New("extractor is not created with WithNamedParams")
+	}
+
+	if sql == "" {
+		return nil, nil, errors.New("empty SQL statement")
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		templates = make([]string, 0, len(stmts))
+		args      = make([]map[string]any, 0, len(stmts))
+	)
+
+	for idx := range stmts {
+		err := e.visitStmt(stmts[idx], func(v *ExtractVisitor) {
+			named := make(map[string]any, len(v.params))
+			for jdx := range v.params {
+				named[v.paramNames[jdx]] = v.params[jdx]
+			}
+
+			templates = append(templates, v.builder.String())
+			args = append(args, named)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+		}
+	}
+
+	return templates, args, nil
+}
+
+// ParamColumns returns the column of each parameter marker ? of each
+// statement, in order of position: the column the parameter is compared with
+// or assigned to, limit or offset, empty if there is no such column. The
+// Extractor must be created with WithNamedParams and WithParamMarkers.
+//
+// e.g. SELECT * FROM t WHERE a = ? AND b IN (?, ?) LIMIT ? -> [[a b b limit]]
+func (e *Extractor) ParamColumns(sql string) ([][]string, error) {
+	if !e.named || !e.paramMarkers {
+		return nil, errors.New("extractor is not created with WithNamedParams and WithParamMarkers")
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([][]string, 0, len(stmts))
+	for idx := range stmts {
+		err := e.visitStmt(stmts[idx], func(v *ExtractVisitor) {
+			params := make([]any, len(v.params))
+			copy(params, v.params)
+			numberParamMarkers(params)
+
+			var markers int
+			for jdx := range params {
+				if _, ok := params[jdx].(ParamMarker); ok {
+					markers++
+				}
+			}
+
+			cols := make([]string, markers)
+			for jdx := range params {
+				if m, ok := params[jdx].(ParamMarker); ok {
+					cols[m] = v.paramCols[jdx]
+				}
+			}
+			columns = append(columns, cols)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+		}
+	}
+
+	return columns, nil
+}
+
+// ParamNames returns the names of the parameters of each statement, the names
+// of ExtractNamed, in order of the placeholders of the templates. The Extractor
+// must be created with WithNamedParams. Like the parameters, the names beyond
+// the limit of WithMaxParams are dropped.
+//
+// e.g. SELECT * FROM t WHERE a = 1 AND b IN (2, 3) LIMIT 10 -> [[a b b_2 limit]]
+func (e *Extractor) ParamNames(sql string) ([][]string, error) {
+	if !e.named {
+		return nil, errors.New("extractor is not created with WithNamedParams")
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([][]string, 0, len(stmts))
+	for idx := range stmts {
+		err := e.visitStmt(stmts[idx], func(v *ExtractVisitor) {
+			n := len(v.paramNames)
+			if v.maxParams > 0 {
+				n = min(n, v.maxParams)
+			}
+			names = append(names, stdslices.Clone(v.paramNames[:n]))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing statement %d: %w", idx+1, err)
+		}
+	}
+
+	return names, nil
+}
+
+// Split splits the SQL string into its original statements, without trailing
+// semicolons. The statements are slices of the input, not of the SQL rewritten
+// for the parser (e.g. LATERAL derived tables, dialect syntax).
+func (e *Extractor) Split(sql string) ([]string, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	// 改写可能移动语句的内容，按输入中的分号切分；数量不一致时（e.g. psql 的 \copy 以换行结尾）
+	// 使用解析得到的语句文本
+	if texts := splitText(sql, e.postgres); len(texts) == len(stmts) {
+		return texts, nil
+	}
+
+	texts := make([]string, 0, len(stmts))
+	for idx := range stmts {
+		texts = append(texts, strings.TrimRight(strings.TrimSpace(stmts[idx].Text()), "; \t\n"))
+	}
+
+	return texts, nil
+}
+
+// preparedStmt holds the templatized result of a PREPARE statement, so that
+// subsequent EXECUTE statements in the same input can be associated with it.
+type preparedStmt struct {
+	templatizedSQL string
+	tableInfos     []*models.TableInfo
+	opType         models.SQLOpType
+
+	// 按占位符顺序排列的参数，参数标记 ? 为 ParamMarker，EXECUTE 时替换为 USING 变量
+	params []any
+}
+
+// extractStmt dispatches PREPARE / EXECUTE / DEALLOCATE statements, which need
+// state shared across statements, and handles the others by extractOneStmt.
+func (e *Extractor) extractStmt(stmt ast.StmtNode, st *extractState) (
 	string, []*models.TableInfo, []any, models.SQLOpType, error,
 ) {
-	v, ok := e.pool.Get().(*ExtractVisitor)
-	if !ok {
+	switch node := stmt.(type) {
+	case *ast.PrepareStmt:
+		return e.extractPrepareStmt(node, st)
+
+	case *ast.ExecuteStmt:
+		return e.extractExecuteStmt(node, st.prepared)
+
+	case *ast.DeallocateStmt:
+		// 预处理语句名大小写不敏感
+		delete(st.prepared, strings.ToLower(node.Name))
+		return "DEALLOCATE PREPARE " + node.Name, []*models.TableInfo{}, []any{},
+			models.SQLOperationDeallocate, nil
+	}
+
+	return e.extractOneStmt(stmt, st)
+}
+
+// extractPrepareStmt templatizes the SQL text of a PREPARE statement recursively.
+//
+// e.g. PREPARE s FROM 'SELECT * FROM t WHERE id = 1' -> PREPARE s FROM 'SELECT * FROM t WHERE id eq ?'
+func (e *Extractor) extractPrepareStmt(node *ast.PrepareStmt, st *extractState) (
+	string, []*models.TableInfo, []any, models.SQLOpType, error,
+) {
+	// PREPARE s FROM @var: SQL 文本在运行时才确定
+	if node.SQLVar != nil {
+		delete(st.prepared, strings.ToLower(node.Name))
+		return "PREPARE " + node.Name + " FROM @" + node.SQLVar.Name, []*models.TableInfo{}, []any{},
+			models.SQLOperationPrepare, nil
+	}
+
+	stmts, err := e.parse(node.SQLText)
+	if err != nil {
+		return "", nil, nil, models.SQLOperationUnknown, err
+	}
+
+	if len(stmts) != 1 {
 		return "", nil, nil, models.SQLOperationUnknown,
-			errors.New("failed to get ExtractVisitor from pool")
+			errors.New("PREPARE statement should contain exactly one SQL statement")
+	}
+
+	templatedSQL, tableInfos, params, bound, op, err := e.extractBoundStmt(stmts[0], st)
+	if err != nil {
+		return "", nil, nil, models.SQLOperationUnknown, err
+	}
+
+	st.prepared[strings.ToLower(node.Name)] = &preparedStmt{
+		templatizedSQL: templatedSQL,
+		tableInfos:     tableInfos,
+		opType:         op,
+		params:         bound,
+	}
+
+	return "PREPARE " + node.Name + " FROM '" + strings.ReplaceAll(templatedSQL, "'", "''") + "'",
+		tableInfos, params, models.SQLOperationPrepare, nil
+}
+
+// extractExecuteStmt handles EXECUTE statements. If the statement was prepared
+// in the same input, it returns the combined result: the prepared template, its
+// table infos and operation type, with the literals of the prepared statement
+// and the USING variables as parameters, in placeholder order.
+//
+// e.g. PREPARE s FROM 'SELECT * FROM t WHERE a = ? AND b = 1'; EXECUTE s USING @a -> params: "@a", 1
+func (e *Extractor) extractExecuteStmt(node *ast.ExecuteStmt, prepared map[string]*preparedStmt) (
+	string, []*models.TableInfo, []any, models.SQLOpType, error,
+) {
+	params := make([]any, 0, len(node.UsingVars))
+	for idx := range node.UsingVars {
+		if variable, ok := node.UsingVars[idx].(*ast.VariableExpr); ok {
+			params = append(params, "@"+variable.Name)
+		}
+	}
+
+	if ps, ok := prepared[strings.ToLower(node.Name)]; ok {
+		bound := make([]any, len(ps.params))
+		for idx := range ps.params {
+			m, ok := ps.params[idx].(ParamMarker)
+			switch {
+			case !ok:
+				bound[idx] = ps.params[idx]
+			case int(m) < len(params):
+				bound[idx] = params[m]
+			default:
+				bound[idx] = nil // USING 变量少于参数标记，执行时报错
+			}
+		}
+
+		return ps.templatizedSQL, ps.tableInfos, bound, ps.opType, nil
+	}
+
+	var builder strings.Builder
+	builder.WriteString("EXECUTE ")
+	builder.WriteString(node.Name)
+	for idx := range params {
+		if idx == 0 {
+			builder.WriteString(" USING ")
+		} else {
+			builder.WriteString(", ")
+		}
+
+		if e.placeholder == nil {
+			builder.WriteString("?")
+		} else {
+			builder.WriteString(e.placeholder(idx + 1))
+		}
+	}
+
+	return builder.String(), []*models.TableInfo{}, params, models.SQLOperationExecute, nil
+}
+
+// extractOneStmt handles a single SQL statement, st may be nil.
+func (e *Extractor) extractOneStmt(stmt ast.StmtNode, st *extractState) (
+	string, []*models.TableInfo, []any, models.SQLOpType, error,
+) {
+	templatedSQL, tableInfos, params, _, op, err := e.extractBoundStmt(stmt, st)
+	return templatedSQL, tableInfos, params, op, err
+}
+
+// extractBoundStmt 同 extractOneStmt，额外返回按占位符顺序排列、包含参数标记
+// ParamMarker 的参数，供 EXECUTE 将 USING 变量放到参数标记的位置
+func (e *Extractor) extractBoundStmt(stmt ast.StmtNode, st *extractState) (
+	string, []*models.TableInfo, []any, []any, models.SQLOpType, error,
+) {
+	var (
+		templatedSQL string
+		tableInfos   []*models.TableInfo
+		params       []any
+		bound        []any
+		op           = models.SQLOperationUnknown
+		overflow     int
+	)
+
+	err := e.visitStmt(stmt, func(v *ExtractVisitor) {
+		templatedSQL = v.builder.String()
+		tableInfos = uniqTableInfos(v.tableInfos)
+
+		// v.params 会被复用，需要拷贝
+		params = make([]any, len(v.params))
+		copy(params, v.params)
+		if v.paramMarkers {
+			numberParamMarkers(params)
+		}
+		bound = bindParamMarkers(params, v.markers)
+		op = v.opType
+
+		if v.overflowed() {
+			overflow = v.nparams
+		}
+	})
+	if err == nil {
+		err = e.checkOverflow(overflow, st)
+	}
+	if err != nil {
+		return "", nil, nil, nil, models.SQLOperationUnknown, err
+	}
+
+	return templatedSQL, tableInfos, params, bound, op, nil
+}
+
+// bindParamMarkers 将未作为参数收集的参数标记按其在 params 中的位置插入，
+// 返回按占位符顺序排列的参数，参数标记按位置编号为 ParamMarker
+func bindParamMarkers(params []any, markers []markerPos) []any {
+	if len(markers) == 0 {
+		return params
+	}
+
+	bound := make([]any, 0, len(params)+len(markers))
+	next := 0
+	for idx := range params {
+		for ; next < len(markers) && markers[next].at == idx; next++ {
+			bound = append(bound, ParamMarker(markers[next].offset))
+		}
+		bound = append(bound, params[idx])
+	}
+	for ; next < len(markers); next++ {
+		bound = append(bound, ParamMarker(markers[next].offset))
+	}
+	numberParamMarkers(bound)
+
+	return bound
+}
+
+// numberParamMarkers 将参数标记的位置 (Offset) 替换为按位置排序的序号
+func numberParamMarkers(params []any) {
+	var offsets []int
+	for idx := range params {
+		if m, ok := params[idx].(ParamMarker); ok {
+			offsets = append(offsets, int(m))
+		}
+	}
+	stdslices.Sort(offsets)
+
+	for idx := range params {
+		if m, ok := params[idx].(ParamMarker); ok {
+			n, _ := stdslices.BinarySearch(offsets, int(m))
+			params[idx] = ParamMarker(n)
+		}
+	}
+}
+
+// uniqTableInfos 去除重复的表，返回新的切片
+func uniqTableInfos(tableInfos []*models.TableInfo) []*models.TableInfo {
+	return slices.UniqBy(tableInfos, func(t *models.TableInfo) string {
+		if t.Schema() == "" {
+			return t.TableName()
+		}
+
+		return t.Schema() + "." + t.TableName()
+	})
+}
+
+// visitStmt 使用池中的 ExtractVisitor 遍历语句，fn 在 visitor 放回池中之前读取结果
+func (e *Extractor) visitStmt(stmt ast.StmtNode, fn func(v *ExtractVisitor)) error {
+	return e.visit(stmt, visitTemplate, fn)
+}
+
+// visitMode 是 visit 遍历语句的方式
+type visitMode int
+
+const (
+	visitTemplate visitMode = iota // 生成模板
+	visitDigest                    // 遍历时计算模板的摘要，不保留模板
+	visitClauses                   // 生成模板，并记录最外层 SELECT 各子句的位置
+	visitSpans                     // 生成模板，并记录各节点的位置和参数的序号
+)
+
+// reset 重置单条语句的遍历状态
+func (v *ExtractVisitor) reset() {
+	v.builder.Reset()
+	v.params = v.params[:0]
+	v.tableInfos = v.tableInfos[:0]
+	v.inAggrFunc = false
+	v.opType = models.SQLOperationUnknown
+	v.paramColumn = ""
+	v.paramNames = v.paramNames[:0]
+	v.paramCols = v.paramCols[:0]
+	v.rows = 0
+	v.nparams = 0
+	v.segments = v.segments[:0]
+	v.spanStarts = v.spanStarts[:0]
+	v.markers = v.markers[:0]
+}
+
+// visit 同 visitStmt，按 mode 遍历语句
+func (e *Extractor) visit(stmt ast.StmtNode, mode visitMode, fn func(v *ExtractVisitor)) error {
+	tier := tierOf(stmt)
+	v, ok := e.pools[tier].Get().(*ExtractVisitor)
+	if !ok {
+		return errors.New("failed to get ExtractVisitor from pool")
 	}
 
 	defer func() {
-		v.builder.Reset()
-		v.params = v.params[:0]
-		v.tableInfos = v.tableInfos[:0]
-		v.inAggrFunc = false
-		v.opType = models.SQLOperationUnknown
+		v.reset()
+		v.segmented = nil
+		v.spans, v.paramIndex = nil, nil
 
-		e.pool.Put(v)
+		// 不保留超出容量上限的 params，避免池中的 visitor 长期占用大块内存
+		if capacity := e.capacityOf(tier); cap(v.params) > paramsRetainFactor*capacity {
+			v.params = make([]any, 0, capacity)
+		}
+
+		e.pools[tier].Put(v)
 	}()
 
+	switch mode {
+	case visitClauses:
+		v.segmented, _ = stmt.(*ast.SelectStmt)
+	case visitSpans:
+		v.spans, v.paramIndex = make(map[ast.Node]span), make(map[ast.ExprNode]int)
+	}
+	if mode == visitDigest {
+		v.builder.digest, v.builder.discard = sha256.New(), true
+	} else {
+		// 模板化后的 SQL 一般不长于原始 SQL，预先分配避免扩容
+		v.builder.Grow(len(stmt.Text()))
+	}
+	if tier == stmtTierLarge && !v.noParams {
+		v.params = stdslices.Grow(v.params, len(stmt.Text())/bytesPerParam)
+	}
+
 	stmt.Accept(v)
 
-	return v.builder.String(),
-		slices.UniqBy(v.tableInfos, func(t *models.TableInfo) string {
-			if t.Schema() == "" {
-				return t.TableName()
-			}
+	if e.overflow == ParamsCollapse && v.overflowed() {
+		// 参数过多时折叠 IN 列表和 INSERT VALUES 的多行，重新遍历
+		collapse, collapseIn := v.collapse, v.collapseIn
+		defer func() { v.collapse, v.collapseIn = collapse, collapseIn }()
+
+		v.reset()
+		if mode == visitDigest {
+			v.builder.digest, v.builder.discard = sha256.New(), true
+		}
+		v.collapse, v.collapseIn = true, true
+		stmt.Accept(v)
+	}
+
+	fn(v)
 
-			return t.Schema() + "." + t.TableName()
-		}),
-		v.params,
-		v.opType,
-		nil
+	return nil
 }
 
 // ExtractVisitor 实现 ast.Visitor 接口
 type ExtractVisitor struct {
-	builder    *strings.Builder
+	builder    *templateBuilder
 	params     []any
 	inAggrFunc bool
 	tableInfos []*models.TableInfo
 	opType     models.SQLOpType
+
+	placeholder   func(n int) string // 参数占位符，为 nil 时使用 ?
+	listHolder    func(n int) string // 字面量 IN 列表的列表占位符，为 nil 时逐项写入占位符
+	standardOps   bool               // 使用标准 SQL 运算符
+	assignment    AssignmentStyle    // SET 子句中赋值运算符的样式
+	rawTableNames bool               // 不模板化表名
+
+	named       bool     // 使用命名参数占位符 :name
+	paramColumn string   // 当前参数对应的列名，用于生成参数名
+	paramNames  []string // 命名参数名，与 params 一一对应
+	paramCols   []string // 命名参数对应的列名，与 params 一一对应
+
+	collapse     bool              // INSERT VALUES 只保留第一行
+	rows         int               // INSERT VALUES 的行数
+	paramMarkers bool              // 参数标记 ? 作为参数收集
+	markers      []markerPos       // 未作为参数收集的参数标记
+	foldIdents   bool              // 标识符转为小写，仅在必要时加引号
+	quoting      IdentifierQuoting // 标识符的引号
+	sqlglot      bool              // 与 sqlglot 的输出一致，见 WithSQLGlotStyle
+	limitOffset  bool              // LIMIT 写为 LIMIT count OFFSET offset
+
+	noParams   bool // 不收集参数，只写入占位符
+	noTables   bool // 不收集表信息
+	nparams    int  // 已写入的参数占位符个数
+	maxParams  int  // 参数个数上限，超出的参数不收集
+	collapseIn bool // IN 列表只保留第一项
+
+	maxParamBytes  int // 字符串参数的字节数上限，为 0 时不限制
+	blobParamBytes int // 跳过不小于该字节数的二进制字面量，为 0 时不跳过
+
+	wildcardModifiers bool // 支持 * EXCEPT (...)、* REPLACE (...)
+	qualify           bool // 支持 QUALIFY 子句
+	optimizerHints    bool // 保留语句开头的优化器提示
+
+	tenantPattern *regexp.Regexp // 租户 schema 的匹配模式，为 nil 时不映射
+	tenantSchema  string         // 租户 schema 映射到的规范 schema
+
+	handlers map[reflect.Type]NodeHandler // 用户注册的节点处理函数，优先于内置处理
+
+	segmented *ast.SelectStmt // 记录子句位置的 SELECT，为 nil 时不记录
+	segments  []clauseOffset  // segmented 各子句在模板中的位置
+
+	spans      map[ast.Node]span    // 各节点在模板中的位置，为 nil 时不记录
+	spanStarts []int                // 正在遍历的节点的起始位置
+	paramIndex map[ast.ExprNode]int // 字面量和参数标记对应的占位符序号
+
+	scratch [32]byte // 格式化数值的缓冲区，避免内存分配
+}
+
+// markerPos 记录未作为参数收集的参数标记 ?
+type markerPos struct {
+	at     int // 参数标记之前已收集的参数个数
+	offset int // 参数标记在 SQL 中的位置
 }
 
 // 避免重复字符串操作
@@ -143,6 +995,13 @@ func (v *ExtractVisitor) Enter(n ast.Node) (ast.Node, bool) {
 	if n == nil {
 		return n, false
 	}
+	if v.spans != nil {
+		v.spanStarts = append(v.spanStarts, v.builder.Len())
+	}
+
+	if v.handle(n) {
+		return n, true
+	}
 
 	switch node := n.(type) {
 	// 1. 基础表达式层 - 最常用的表达式处理
@@ -150,10 +1009,20 @@ func (v *ExtractVisitor) Enter(n ast.Node) (ast.Node, bool) {
 		v.handleColumnNameExpr(node)
 	case *test_driver.ValueExpr:
 		v.handleValueExpr(node)
+	case *test_driver.ParamMarkerExpr: // e.g. PREPARE 语句中的 ?
+		if v.paramMarkers {
+			v.writeParam(ParamMarker(node.Offset)) // 遍历结束后按位置重新编号
+			v.indexParam(node)
+		} else {
+			v.builder.WriteString("?")
+			v.markers = append(v.markers, markerPos{at: len(v.params), offset: node.Offset})
+		}
 	case *ast.BinaryOperationExpr: // e.g 1+1, and
 		v.handleBinaryOperationExpr(node)
 	case *ast.TableName:
 		v.handleTableName(node)
+	case *ast.VariableExpr:
+		v.handleVariableExpr(node)
 
 	// 2. SQL 语句层
 	case *ast.SelectStmt:
@@ -166,8 +1035,14 @@ func (v *ExtractVisitor) Enter(n ast.Node) (ast.Node, bool) {
 		v.handleDeleteStmt(node)
 	case *ast.ExplainStmt:
 		v.handleExplainStmt(node)
+	case *ast.ExplainForStmt:
+		v.handleExplainForStmt(node)
 	case *ast.ShowStmt:
 		v.handleShowStmt(node)
+	case *ast.CreateTableStmt:
+		v.handleCreateTableStmt(node)
+	case *ast.DropTableStmt:
+		v.handleDropTableStmt(node)
 
 	// 3. 表结构层 - 表引用和连接
 	case *ast.TableSource:
@@ -199,6 +1074,11 @@ func (v *ExtractVisitor) Enter(n ast.Node) (ast.Node, bool) {
 		v.inAggrFunc = true
 		defer func() { v.inAggrFunc = old }()
 		v.handleAggregateFuncExpr(node)
+	case *ast.WindowFuncExpr:
+		old := v.inAggrFunc
+		v.inAggrFunc = true
+		defer func() { v.inAggrFunc = old }()
+		v.handleWindowFuncExpr(node)
 	case *ast.UnaryOperationExpr:
 		v.handleUnaryOperationExpr(node)
 	case *ast.TimeUnitExpr:
@@ -231,7 +1111,6 @@ func (v *ExtractVisitor) Enter(n ast.Node) (ast.Node, bool) {
 		// FIXME PatternRegexpExpr
 		// FIXME PositionExpr
 		// FIXME RowExpr
-		// FIXME VariableExpr
 		// FIXME MatchAgainst
 		// FIXME SetCollationExpr
 		v.logError(fmt.Sprintf("Enter ast.Node type: %T", node))
@@ -243,6 +1122,12 @@ func (v *ExtractVisitor) Enter(n ast.Node) (ast.Node, bool) {
 // Leave 实现 ast.Visitor 接口.
 // Return: n, true - 不继续遍历
 func (v *ExtractVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	if v.spans != nil && len(v.spanStarts) > 0 {
+		last := len(v.spanStarts) - 1
+		v.spans[n] = span{start: v.spanStarts[last], end: v.builder.Len()}
+		v.spanStarts = v.spanStarts[:last]
+	}
+
 	return n, true
 }
 
@@ -250,49 +1135,97 @@ func (v *ExtractVisitor) Leave(n ast.Node) (ast.Node, bool) {
 //
 // nolint:cyclop
 func (v *ExtractVisitor) handleSelectStmt(node *ast.SelectStmt) {
+	if fn := copyCall(node); fn != nil {
+		v.handleCopy(node, fn)
+		return
+	}
+
 	if v.opType == models.SQLOperationUnknown {
 		v.opType = models.SQLOperationSelect
 	}
 
+	start := v.builder.Len()
 	v.builder.WriteString("SELECT ")
+	v.writeHint(node, "SELECT")
 
 	// DISTINCT 关键字
 	if node.Distinct {
 		v.builder.WriteString("DISTINCT ")
 	}
 
-	// 处理 SELECT 列表
+	// TOP 子句，标记位于 SELECT 列表末尾
+	var fields []*ast.SelectField
 	if node.Fields != nil {
-		for idx := range node.Fields.Fields {
-			if idx > 0 {
-				v.builder.WriteString(", ")
+		fields = node.Fields.Fields
+		if fn := topField(fields); fn != nil {
+			v.writeTop(fn)
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	// SQL_NO_CACHE、SQL_CALC_FOUND_ROWS 等选项
+	if opts := selectOptions(node.SelectStmtOpts); opts != 0 {
+		for _, keyword := range opts.Keywords() {
+			v.builder.WriteString(keyword)
+			v.builder.WriteString(" ")
+		}
+	}
+
+	// 处理 SELECT 列表
+	var (
+		qualify *ast.FuncCallExpr
+		spark   []*ast.FuncCallExpr // LATERAL VIEW、DISTRIBUTE BY 等 Spark 子句
+	)
+	for idx := range fields {
+		if fn := sparkField(fields[idx]); fn != nil {
+			spark = append(spark, fn)
+			continue
+		}
+
+		if v.qualify {
+			if fn := qualifyField(fields[idx]); fn != nil {
+				qualify = fn
+				continue
 			}
+		}
 
-			if node.Fields.Fields[idx].WildCard != nil { // *
-				// Schema
-				if node.Fields.Fields[idx].WildCard.Schema.O != "" {
-					v.builder.WriteString(node.Fields.Fields[idx].WildCard.Schema.O)
-					v.builder.WriteString(".")
-				}
+		if v.wildcardModifiers {
+			if fn := wildcardModifier(fields[idx]); fn != nil {
+				v.writeWildcardModifier(fn)
+				continue
+			}
+		}
 
-				if node.Fields.Fields[idx].WildCard.Table.O != "" {
-					v.builder.WriteString(node.Fields.Fields[idx].WildCard.Table.O)
-					v.builder.WriteString(".")
-				}
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
 
-				v.builder.WriteString("*")
-			} else {
-				node.Fields.Fields[idx].Expr.Accept(v)
+		if fields[idx].WildCard != nil { // *
+			// Schema
+			if fields[idx].WildCard.Schema.O != "" {
+				v.writeIdent(fields[idx].WildCard.Schema.O)
+				v.builder.WriteString(".")
+			}
 
-				// 处理 AS
-				if node.Fields.Fields[idx].AsName.String() != "" {
-					v.builder.WriteString(" AS ")
-					v.builder.WriteString(node.Fields.Fields[idx].AsName.String())
-				}
+			if fields[idx].WildCard.Table.O != "" {
+				v.writeIdent(fields[idx].WildCard.Table.O)
+				v.builder.WriteString(".")
+			}
+
+			v.builder.WriteString("*")
+		} else {
+			fields[idx].Expr.Accept(v)
+
+			// 处理 AS
+			if fields[idx].AsName.String() != "" {
+				v.builder.WriteString(" AS ")
+				v.writeIdent(fields[idx].AsName.String())
 			}
 		}
 	}
 
+	start = v.segment(node, models.ClauseSelect, start)
+
 	// FROM 子句
 	if node.From != nil {
 		v.builder.WriteString(" FROM ")
@@ -300,12 +1233,15 @@ func (v *ExtractVisitor) handleSelectStmt(node *ast.SelectStmt) {
 			node.From.TableRefs.Accept(v)
 		}
 	}
+	v.writeSparkClauses(spark, true)
+	start = v.segment(node, models.ClauseFrom, start)
 
 	// WHERE 子句
 	if node.Where != nil {
 		v.builder.WriteString(" WHERE ")
 		node.Where.Accept(v)
 	}
+	start = v.segment(node, models.ClauseWhere, start)
 
 	// GROUP BY 子句
 	if node.GroupBy != nil {
@@ -318,25 +1254,53 @@ func (v *ExtractVisitor) handleSelectStmt(node *ast.SelectStmt) {
 			item.Accept(v)
 		}
 	}
+	start = v.segment(node, models.ClauseGroupBy, start)
 
 	// HAVING 子句
 	if node.Having != nil && node.Having.Expr != nil {
 		v.builder.WriteString(" HAVING ")
+		node.Having.Expr.Accept(v)
+	}
+	v.segment(node, models.ClauseHaving, start)
 
-		switch expr := node.Having.Expr.(type) {
-		case *ast.BinaryOperationExpr:
-			expr.Accept(v)
+	// QUALIFY 子句
+	if qualify != nil {
+		v.builder.WriteString(" QUALIFY ")
+		qualify.Args[0].Accept(v)
+	}
 
-		default:
-			v.logError(fmt.Sprintf("Having.Expr type: %T", expr))
-			expr.Accept(v)
+	// WINDOW 子句
+	for idx := range node.WindowSpecs {
+		if idx == 0 {
+			v.builder.WriteString(" WINDOW ")
+		} else {
+			v.builder.WriteString(", ")
 		}
+
+		v.writeWindowSpec(&node.WindowSpecs[idx])
 	}
 
-	// ORDER BY 子句
+	// DISTRIBUTE BY、CLUSTER BY 子句
+	v.writeSparkClauses(spark, false)
+
+	// ORDER BY 子句，OFFSET FETCH 和 SORT BY 的标记位于末尾
+	start = v.builder.Len()
+	fetch := fetchItem(node.OrderBy)
 	if node.OrderBy != nil {
-		v.builder.WriteString(" ORDER BY ")
-		for idx, item := range node.OrderBy.Items {
+		items := node.OrderBy.Items
+		if fetch != "" {
+			items = items[:len(items)-1]
+		}
+
+		sortBy := sortItem(node.OrderBy)
+		switch {
+		case sortBy:
+			items = items[:len(items)-1]
+			v.builder.WriteString(" SORT BY ")
+		case len(items) > 0:
+			v.builder.WriteString(" ORDER BY ")
+		}
+		for idx, item := range items {
 			if idx > 0 {
 				v.builder.WriteString(", ")
 			}
@@ -344,11 +1308,17 @@ func (v *ExtractVisitor) handleSelectStmt(node *ast.SelectStmt) {
 			item.Accept(v)
 		}
 	}
+	start = v.segment(node, models.ClauseOrderBy, start)
 
 	// LIMIT 子句
 	if node.Limit != nil {
-		node.Limit.Accept(v)
+		if fetch != "" {
+			v.writeFetch(node.Limit, fetch)
+		} else {
+			node.Limit.Accept(v)
+		}
 	}
+	v.segment(node, models.ClauseLimit, start)
 }
 
 // INSERT 语句
@@ -358,16 +1328,28 @@ func (v *ExtractVisitor) handleInsertStmt(node *ast.InsertStmt) {
 	}
 
 	v.builder.WriteString("INSERT ")
-	// INSERT IGNORE
+	v.writeHint(node, "INSERT")
+	// INSERT LOW_PRIORITY | DELAYED | HIGH_PRIORITY IGNORE
+	v.writePriority(node.Priority)
 	if node.IgnoreErr {
 		v.builder.WriteString("IGNORE ")
 	}
-	v.builder.WriteString("INTO ")
+
+	// INSERT OVERWRITE TABLE、INSERT INTO 的分区，改写为 ON DUPLICATE KEY UPDATE 的标记
+	target := insertTarget(node)
+	if target != nil && node.OnDuplicate[0].Column.Name.L == overwriteMarker {
+		v.builder.WriteString("OVERWRITE TABLE ")
+	} else {
+		v.builder.WriteString("INTO ")
+	}
 
 	// TABLE
 	if node.Table.TableRefs != nil {
 		node.Table.TableRefs.Accept(v) // call handleTableSource()
 	}
+	if target != nil {
+		v.writePartition(target)
+	}
 
 	// COLUMNS
 	if len(node.Columns) > 0 {
@@ -377,15 +1359,21 @@ func (v *ExtractVisitor) handleInsertStmt(node *ast.InsertStmt) {
 				v.builder.WriteString(", ")
 			}
 
-			v.builder.WriteString(col.Name.O)
+			v.writeIdent(col.Name.O)
 		}
 		v.builder.WriteString(")")
 	}
 
 	// VALUES
 	if node.Lists != nil {
+		v.rows = len(node.Lists)
+		lists := node.Lists
+		if v.collapse {
+			lists = lists[:min(len(lists), 1)]
+		}
+
 		v.builder.WriteString(" VALUES ")
-		for idx, list := range node.Lists {
+		for idx, list := range lists {
 			if idx > 0 {
 				v.builder.WriteString(", ")
 			}
@@ -396,7 +1384,11 @@ func (v *ExtractVisitor) handleInsertStmt(node *ast.InsertStmt) {
 					v.builder.WriteString(", ")
 				}
 
-				item.Accept(v)
+				column := ""
+				if jdx < len(node.Columns) {
+					column = node.Columns[jdx].Name.O
+				}
+				v.withParamColumn(column, func() { item.Accept(v) })
 			}
 			v.builder.WriteString(")")
 		}
@@ -405,8 +1397,10 @@ func (v *ExtractVisitor) handleInsertStmt(node *ast.InsertStmt) {
 		node.Select.Accept(v)
 	}
 
-	// ON DUPLICATE KEY UPDATE
-	if node.OnDuplicate != nil {
+	// ON DUPLICATE KEY UPDATE, 或改写前的 ON CONFLICT
+	if len(node.OnDuplicate) > 0 && node.OnDuplicate[0].Column.Name.L == conflictMarker {
+		v.writeOnConflict(node.OnDuplicate)
+	} else if node.OnDuplicate != nil && target == nil {
 		v.builder.WriteString(" ON DUPLICATE KEY UPDATE ")
 
 		for idx := range node.OnDuplicate {
@@ -419,6 +1413,16 @@ func (v *ExtractVisitor) handleInsertStmt(node *ast.InsertStmt) {
 	}
 }
 
+// writePriority 写入 DML 语句的优先级修饰符，e.g. LOW_PRIORITY、DELAYED
+func (v *ExtractVisitor) writePriority(priority mysql.PriorityEnum) {
+	if priority == mysql.NoPriority {
+		return
+	}
+
+	v.builder.WriteString(mysql.Priority2Str[priority])
+	v.builder.WriteString(" ")
+}
+
 // UPDATE
 func (v *ExtractVisitor) handleUpdateStmt(node *ast.UpdateStmt) {
 	if v.opType == models.SQLOperationUnknown {
@@ -426,6 +1430,12 @@ func (v *ExtractVisitor) handleUpdateStmt(node *ast.UpdateStmt) {
 	}
 
 	v.builder.WriteString("UPDATE ")
+	v.writeHint(node, "UPDATE")
+	// UPDATE LOW_PRIORITY IGNORE
+	v.writePriority(node.Priority)
+	if node.IgnoreErr {
+		v.builder.WriteString("IGNORE ")
+	}
 
 	if node.TableRefs != nil && node.TableRefs.TableRefs != nil {
 		node.TableRefs.TableRefs.Accept(v) // call handleTableSource()
@@ -472,6 +1482,15 @@ func (v *ExtractVisitor) handleDeleteStmt(node *ast.DeleteStmt) {
 	}
 
 	v.builder.WriteString("DELETE ")
+	v.writeHint(node, "DELETE")
+	// DELETE LOW_PRIORITY QUICK IGNORE
+	v.writePriority(node.Priority)
+	if node.Quick {
+		v.builder.WriteString("QUICK ")
+	}
+	if node.IgnoreErr {
+		v.builder.WriteString("IGNORE ")
+	}
 
 	if node.Tables != nil {
 		for idx := range node.Tables.Tables {
@@ -524,25 +1543,79 @@ func (v *ExtractVisitor) handleExplainStmt(node *ast.ExplainStmt) {
 	if node.Analyze {
 		v.builder.WriteString("ANALYZE ")
 	}
+	if node.Explore {
+		v.builder.WriteString("EXPLORE ")
+	}
 	if node.Format != "" {
 		v.builder.WriteString("FORMAT = ")
 		v.builder.WriteString(node.Format)
 		v.builder.WriteString(" ")
 	}
 
-	// 递归处理被解释的语句
-	if node.Stmt != nil {
-		node.Stmt.Accept(v)
+	// 递归处理被解释的语句
+	if node.Stmt != nil {
+		node.Stmt.Accept(v)
+	} else if node.SQLDigest != "" { // EXPLAIN EXPLORE 'sql_digest'
+		v.writeParam(node.SQLDigest)
+	}
+}
+
+// handleExplainForStmt 处理 EXPLAIN FOR CONNECTION 语句，连接 ID 参数化
+func (v *ExtractVisitor) handleExplainForStmt(node *ast.ExplainForStmt) {
+	if v.opType == models.SQLOperationUnknown {
+		v.opType = models.SQLOperationExplain
+	}
+
+	// 未指定 FORMAT 时解析器默认填充 row，只写出语句中显式指定的 FORMAT
+	v.builder.WriteString("EXPLAIN ")
+	if node.Format != "" && hasFormatKeyword(node.Text()) {
+		v.builder.WriteString("FORMAT = ")
+		v.builder.WriteString(node.Format)
+		v.builder.WriteString(" ")
+	}
+
+	v.builder.WriteString("FOR CONNECTION ")
+	v.writeParam(node.ConnectionID)
+}
+
+// hasFormatKeyword reports whether the FORMAT keyword appears in the SQL, outside
+// of quoted strings and comments.
+func hasFormatKeyword(sql string) bool {
+	const keyword = "format"
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+
+		if (i == 0 || !isIdentByte(sql[i-1])) && len(sql)-i >= len(keyword) &&
+			strings.EqualFold(sql[i:i+len(keyword)], keyword) &&
+			(i+len(keyword) == len(sql) || !isIdentByte(sql[i+len(keyword)])) {
+			return true
+		}
 	}
+
+	return false
 }
 
 // handleTableSource 处理表源
 func (v *ExtractVisitor) handleTableSource(node *ast.TableSource) {
+	// LATERAL 派生表的别名带有改写时加上的前缀
+	alias, lateral := lateralAlias(node.AsName.O)
+
 	switch src := node.Source.(type) {
 	case *ast.TableName:
 		src.Accept(v)
 
 	case *ast.SelectStmt:
+		if fn := unnestCall(src); fn != nil {
+			v.writeUnnest(fn, alias)
+			return
+		}
+
+		if lateral {
+			v.builder.WriteString("LATERAL ")
+		}
 		v.builder.WriteString("(")
 		src.Accept(v)
 		v.builder.WriteString(")")
@@ -555,28 +1628,64 @@ func (v *ExtractVisitor) handleTableSource(node *ast.TableSource) {
 		node.Source.Accept(v)
 	}
 
-	if node.AsName.O != "" {
+	if alias != "" {
 		v.builder.WriteString(" AS ")
-		v.builder.WriteString(node.AsName.O)
+		v.writeIdent(alias)
+	}
+
+	if tn, ok := node.Source.(*ast.TableName); ok && tn.TableSample != nil {
+		v.writeTableSample(tn.TableSample)
 	}
 }
 
 func (v *ExtractVisitor) handleTableName(node *ast.TableName) {
-	v.tableInfos = append(v.tableInfos, models.NewTableInfo())
+	server, catalog, schema := splitQualified(node.Schema.O)
+	for _, part := range []string{server, catalog} {
+		if part != "" {
+			v.writeIdent(part)
+			v.builder.WriteString(".")
+		}
+	}
+	if catalog != "" && schema == "" { // catalog..table
+		v.builder.WriteString(".")
+	}
 
-	if node.Schema.O != "" {
-		TemplizedSchema := v.templateTable(node.Schema.O)
+	var TemplizedSchema string
+	tenant, isTenant := v.tenantOf(schema)
+	if schema != "" {
+		if isTenant {
+			TemplizedSchema = v.ident(v.tenantSchema)
+		} else {
+			TemplizedSchema = v.tableIdent(schema)
+		}
 		v.builder.WriteString(TemplizedSchema)
 		v.builder.WriteString(".")
-
-		v.tableInfos[len(v.tableInfos)-1].SetSchema(node.Schema.O)
-		v.tableInfos[len(v.tableInfos)-1].SetTemplatizedSchema(TemplizedSchema)
 	}
 
-	TemplatizedTable := v.templateTable(node.Name.O)
+	TemplatizedTable := v.tableIdent(node.Name.O)
 	v.builder.WriteString(TemplatizedTable)
-	v.tableInfos[len(v.tableInfos)-1].SetTableName(node.Name.O)
-	v.tableInfos[len(v.tableInfos)-1].SetTemplatizedTableName(TemplatizedTable)
+
+	if v.noTables {
+		return
+	}
+
+	ti := models.NewTableInfo()
+	if server != "" {
+		ti.SetServer(server)
+	}
+	if catalog != "" {
+		ti.SetCatalog(catalog)
+	}
+	if schema != "" {
+		ti.SetSchema(schema)
+		ti.SetTemplatizedSchema(TemplizedSchema)
+	}
+	if isTenant {
+		ti.SetTenant(tenant)
+	}
+	ti.SetTableName(node.Name.O)
+	ti.SetTemplatizedTableName(TemplatizedTable)
+	v.tableInfos = append(v.tableInfos, ti)
 }
 
 // templateTable 模板化 table
@@ -584,7 +1693,7 @@ func (v *ExtractVisitor) handleTableName(node *ast.TableName) {
 // - 如果 table 中包含 _ 且最后一个部分是数字，则认为是分库分表的表名，将最后一个部分替换为若干个 x
 // - 如果 table 中不包含 _ 或最后一个部分不是数字，则返回原值
 func (v *ExtractVisitor) templateTable(table string) string {
-	if table == "" || !strings.Contains(table, "_") {
+	if v.rawTableNames || table == "" || !strings.Contains(table, "_") {
 		return table
 	}
 
@@ -597,6 +1706,12 @@ func (v *ExtractVisitor) templateTable(table string) string {
 }
 
 func (v *ExtractVisitor) handleJoin(node *ast.Join) {
+	// 显式加括号的 JOIN，e.g. (a JOIN b) LEFT JOIN c，保留括号，否则外连接的语义可能改变
+	if node.ExplicitParens && node.Right != nil {
+		v.builder.WriteString("(")
+		defer v.builder.WriteString(")")
+	}
+
 	if node.Left != nil {
 		switch left := node.Left.(type) {
 		// 若左节点是 JOIN，递归处理
@@ -614,15 +1729,11 @@ func (v *ExtractVisitor) handleJoin(node *ast.Join) {
 
 	// 只有存在右节点时，才添加 JOIN 关键字
 	if node.Right != nil {
-		// JOIN Type
-		if joinStr, ok := joinTypeMap[node.Tp]; ok {
-			v.builder.WriteString(joinStr)
-		} else {
-			v.builder.WriteString(" JOIN ")
-		}
+		v.writeJoinType(node)
 
 		switch right := node.Right.(type) {
-		case *ast.TableSource:
+		// 右节点是 JOIN 时，e.g. a LEFT JOIN (b JOIN c)，由 ExplicitParens 加括号
+		case *ast.TableSource, *ast.Join:
 			right.Accept(v)
 
 		default:
@@ -635,23 +1746,74 @@ func (v *ExtractVisitor) handleJoin(node *ast.Join) {
 			v.builder.WriteString(" ON ")
 			node.On.Accept(v)
 		}
+
+		// USING (col, ...)
+		if len(node.Using) > 0 {
+			v.builder.WriteString(" USING (")
+			for idx := range node.Using {
+				if idx > 0 {
+					v.builder.WriteString(", ")
+				}
+
+				v.writeIdent(node.Using[idx].Name.O)
+			}
+			v.builder.WriteString(")")
+		}
+	}
+}
+
+// writeJoinType 写入 JOIN 关键字，保留原始的连接类型：
+//   - NATURAL [LEFT | RIGHT] JOIN
+//   - STRAIGHT_JOIN
+//   - 带 ON、USING 条件的内连接为 INNER JOIN（解析器不区分 JOIN 和 INNER JOIN），sqlglot 风格为 JOIN
+//   - 无条件的内连接（包括逗号连接）为 CROSS JOIN
+func (v *ExtractVisitor) writeJoinType(node *ast.Join) {
+	switch {
+	case node.NaturalJoin:
+		switch node.Tp {
+		case ast.LeftJoin:
+			v.builder.WriteString(" NATURAL LEFT JOIN ")
+		case ast.RightJoin:
+			v.builder.WriteString(" NATURAL RIGHT JOIN ")
+		default:
+			v.builder.WriteString(" NATURAL JOIN ")
+		}
+
+	case node.StraightJoin:
+		v.builder.WriteString(" STRAIGHT_JOIN ")
+
+	case node.Tp == ast.CrossJoin && (node.On != nil || len(node.Using) > 0):
+		if v.sqlglot {
+			v.builder.WriteString(" JOIN ")
+		} else {
+			v.builder.WriteString(" INNER JOIN ")
+		}
+
+	default:
+		if joinStr, ok := joinTypeMap[node.Tp]; ok {
+			v.builder.WriteString(joinStr)
+		} else {
+			v.builder.WriteString(" JOIN ")
+		}
 	}
 }
 
 func (v *ExtractVisitor) handlePatternLikeOrIlikeExpr(node *ast.PatternLikeOrIlikeExpr) {
-	node.Expr.Accept(v)
-	if node.Not {
+	prefixed := v.prefixNot(node.Not)
+	v.writeOperand(node.Expr, precCompare)
+	if node.Not && !prefixed {
 		v.builder.WriteString(" NOT")
 	}
 	v.builder.WriteString(" LIKE ")
 
 	// 处理 LIKE 模式
-	if pattern, ok := node.Pattern.(*test_driver.ValueExpr); ok {
-		v.builder.WriteString("?")
-		v.params = append(v.params, pattern.GetValue())
-	} else {
-		node.Pattern.Accept(v)
-	}
+	v.withParamColumn(columnName(node.Expr), func() {
+		if pattern, ok := node.Pattern.(*test_driver.ValueExpr); ok {
+			v.writeValue(pattern)
+		} else {
+			v.writeOperand(node.Pattern, precCompare+1)
+		}
+	})
 
 	// FIXME 处理 LIKE 模式中的转义字符
 	// if node.Escape != 0 {
@@ -662,25 +1824,52 @@ func (v *ExtractVisitor) handlePatternLikeOrIlikeExpr(node *ast.PatternLikeOrIli
 }
 
 func (v *ExtractVisitor) handlePatternInExpr(node *ast.PatternInExpr) {
-	node.Expr.Accept(v)
-	if node.Not {
+	prefixed := v.prefixNot(node.Not)
+	v.writeOperand(node.Expr, precCompare)
+	if node.Not && !prefixed {
 		v.builder.WriteString(" NOT")
 	}
+
+	if arg := inUnnest(node); arg != nil {
+		v.builder.WriteString(" IN UNNEST(")
+		arg.Accept(v)
+		v.builder.WriteString(")")
+		return
+	}
+	if v.sqlglot && node.Sel != nil { // 子查询自带括号
+		v.builder.WriteString(" IN ")
+		node.Sel.Accept(v)
+		return
+	}
+	if v.listHolder != nil && node.Sel == nil && allValues(node.List) {
+		v.builder.WriteString(" IN ")
+		v.withParamColumn(columnName(node.Expr), func() { v.writeListParam(node.List) })
+		return
+	}
 	v.builder.WriteString(" IN (")
 
-	if node.List != nil {
-		for idx := range node.List {
+	list := node.List
+	if v.collapseIn {
+		list = list[:min(len(list), 1)]
+	}
+
+	v.withParamColumn(columnName(node.Expr), func() {
+		for idx := range list {
 			if idx > 0 {
 				v.builder.WriteString(", ")
 			}
 
-			v.builder.WriteString("?")
 			// 如果是 ValueExpr，保存参数值
-			if valExpr, ok := node.List[idx].(*test_driver.ValueExpr); ok {
-				v.params = append(v.params, valExpr.GetValue())
+			switch item := list[idx].(type) {
+			case *test_driver.ValueExpr:
+				v.writeValue(item)
+			case *test_driver.ParamMarkerExpr:
+				item.Accept(v)
+			default:
+				v.builder.WriteString("?")
 			}
 		}
-	}
+	})
 
 	if node.Sel != nil {
 		node.Sel.Accept(v)
@@ -690,35 +1879,70 @@ func (v *ExtractVisitor) handlePatternInExpr(node *ast.PatternInExpr) {
 }
 
 func (v *ExtractVisitor) handleBinaryOperationExpr(node *ast.BinaryOperationExpr) {
-	node.L.Accept(v)
-	fmt.Fprintf(v.builder, " %s ", node.Op.String())
-	node.R.Accept(v)
+	// 改写前的数组运算符 @>、<@
+	if op, l := containmentOp(node); op != "" {
+		prec := precedence(node)
+		v.withParamColumn(columnName(node.R), func() { v.writeOperand(l, prec) })
+		v.builder.WriteString(" " + op + " ")
+		v.withParamColumn(columnName(l), func() { v.writeOperand(node.R, prec+1) })
+		return
+	}
+
+	// 二元运算符都是左结合的，右操作数的优先级相同时也需要加括号，e.g. a minus (b minus c)
+	prec := precedence(node)
+	v.withParamColumn(columnName(node.R), func() { v.writeOperand(node.L, prec) })
+	v.builder.WriteString(" ")
+	v.writeOp(node.Op)
+	v.builder.WriteString(" ")
+	v.withParamColumn(columnName(node.L), func() { v.writeOperand(node.R, prec+1) })
+}
+
+// columnName 返回列名表达式的列名，不是列名表达式时返回空字符串
+func columnName(expr ast.ExprNode) string {
+	if col, ok := expr.(*ast.ColumnNameExpr); ok {
+		return col.Name.Name.O
+	}
+
+	return ""
 }
 
 func (v *ExtractVisitor) handleBetweenExpr(node *ast.BetweenExpr) {
-	node.Expr.Accept(v)
+	prefixed := v.prefixNot(node.Not)
+	v.writeOperand(node.Expr, precCompare)
 
-	if node.Not {
-		v.builder.WriteString("NOT ")
+	if node.Not && !prefixed {
+		v.builder.WriteString(" NOT")
 	}
 
+	// 上下界不能是比较运算，e.g. a BETWEEN (b eq ?) AND ?
 	v.builder.WriteString(" BETWEEN ")
-	node.Left.Accept(v)
-	v.builder.WriteString(" AND ")
-	node.Right.Accept(v)
+	v.withParamColumn(columnName(node.Expr), func() {
+		v.writeOperand(node.Left, precBitOr)
+		v.builder.WriteString(" AND ")
+		v.writeOperand(node.Right, precBitOr)
+	})
 }
 
 func (v *ExtractVisitor) handleValueExpr(node *test_driver.ValueExpr) {
-	if v.inAggrFunc { // 在聚合函数中，直接输出值
+	if v.inAggrFunc && !v.sqlglot { // 在聚合函数中，直接输出值
+		// 使用 strconv.Append* 写入 scratch，避免 fmt 的内存分配
 		switch val := node.GetValue().(type) {
-		case int64, uint64:
-			fmt.Fprintf(v.builder, "%d", val)
+		case nil:
+			v.builder.WriteString("NULL")
+
+		case int64:
+			v.builder.Write(strconv.AppendInt(v.scratch[:0], val, 10))
+
+		case uint64:
+			v.builder.Write(strconv.AppendUint(v.scratch[:0], val, 10))
 
-		case float64:
-			fmt.Fprintf(v.builder, "%f", val)
+		case float64: // 同 %f
+			v.builder.Write(strconv.AppendFloat(v.scratch[:0], val, 'f', 6, 64))
 
 		case string:
-			fmt.Fprintf(v.builder, "'%s'", val)
+			v.builder.WriteByte('\'')
+			v.builder.WriteString(val)
+			v.builder.WriteByte('\'')
 
 		case *test_driver.MyDecimal:
 			v.builder.WriteString(val.String())
@@ -729,22 +1953,22 @@ func (v *ExtractVisitor) handleValueExpr(node *test_driver.ValueExpr) {
 		}
 	} else {
 		// param -> ?
-		v.builder.WriteString("?")
-		v.params = append(v.params, node.GetValue())
+		v.writeValue(node)
 	}
 }
 
 func (v *ExtractVisitor) handleColumnNameExpr(node *ast.ColumnNameExpr) {
-	var schema, table string
 	if node.Name.Schema.O != "" {
-		schema = node.Name.Schema.O + "."
+		v.writeIdent(node.Name.Schema.O)
+		v.builder.WriteByte('.')
 	}
 
 	if node.Name.Table.O != "" {
-		table = node.Name.Table.O + "."
+		v.writeIdent(node.Name.Table.O)
+		v.builder.WriteByte('.')
 	}
 
-	v.builder.WriteString(schema + table + node.Name.Name.O)
+	v.writeIdent(node.Name.Name.O)
 }
 
 func (v *ExtractVisitor) handleByItem(node *ast.ByItem) {
@@ -768,12 +1992,21 @@ func (v *ExtractVisitor) handleValuesExpr(node *ast.ValuesExpr) {
 func (v *ExtractVisitor) handleLimit(node *ast.Limit) {
 	v.builder.WriteString(" LIMIT ")
 
+	if v.sqlglot || v.limitOffset {
+		v.withParamColumn("limit", func() { node.Count.Accept(v) })
+		if node.Offset != nil {
+			v.builder.WriteString(" OFFSET ")
+			v.withParamColumn("offset", func() { node.Offset.Accept(v) })
+		}
+		return
+	}
+
 	if node.Offset != nil {
-		node.Offset.Accept(v)
+		v.withParamColumn("offset", func() { node.Offset.Accept(v) })
 		v.builder.WriteString(", ")
 	}
 
-	node.Count.Accept(v)
+	v.withParamColumn("limit", func() { node.Count.Accept(v) })
 }
 
 func (v *ExtractVisitor) handleSubqueryExpr(node *ast.SubqueryExpr) {
@@ -789,15 +2022,66 @@ func (v *ExtractVisitor) handleOnCondition(node *ast.OnCondition) {
 // handleAssignment 处理赋值表达式
 func (v *ExtractVisitor) handleAssignment(node *ast.Assignment) {
 	v.handleColumnNameExpr(&ast.ColumnNameExpr{Name: node.Column}) // XXX
-	v.builder.WriteString(" eq ")
-	node.Expr.Accept(v)
+	switch v.assignment {
+	case AssignmentSymbolic:
+		v.builder.WriteString(" = ")
+	case AssignmentWord:
+		v.builder.WriteString(" eq ")
+	default:
+		v.builder.WriteString(" ")
+		v.writeOp(opcode.EQ)
+		v.builder.WriteString(" ")
+	}
+	v.withParamColumn(node.Column.Name.O, func() { node.Expr.Accept(v) })
 }
 
 // handleExprNode 处理表达式节点
 func (v *ExtractVisitor) handleAggregateFuncExpr(node *ast.AggregateFuncExpr) {
-	v.builder.WriteString(node.F)
+	v.writeFuncName(node.F)
 	v.builder.WriteString("(")
 
+	if v.sqlglot && isCountStar(node) {
+		v.builder.WriteString("*)")
+		return
+	}
+
+	if node.Distinct {
+		v.builder.WriteString("DISTINCT ")
+	}
+
+	// GROUP_CONCAT 的最后一个参数为分隔符
+	args := node.Args
+	groupConcat := strings.EqualFold(node.F, ast.AggFuncGroupConcat) && len(args) > 0
+	if groupConcat {
+		args = args[:len(args)-1]
+	}
+
+	for idx := range args {
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
+
+		args[idx].Accept(v)
+	}
+
+	if node.Order != nil {
+		v.builder.WriteString(" ORDER BY ")
+		v.writeByItems(node.Order.Items)
+	}
+
+	if groupConcat {
+		v.builder.WriteString(" SEPARATOR ")
+		node.Args[len(node.Args)-1].Accept(v)
+	}
+	v.builder.WriteString(")")
+}
+
+// handleWindowFuncExpr 处理窗口函数，包括带 OVER 的聚合函数
+//
+// e.g. SUM(a) OVER (PARTITION BY b ORDER BY c ROWS BETWEEN 1 PRECEDING AND CURRENT ROW)
+func (v *ExtractVisitor) handleWindowFuncExpr(node *ast.WindowFuncExpr) {
+	v.builder.WriteString(strings.ToUpper(node.Name))
+	v.builder.WriteString("(")
 	if node.Distinct {
 		v.builder.WriteString("DISTINCT ")
 	}
@@ -810,6 +2094,99 @@ func (v *ExtractVisitor) handleAggregateFuncExpr(node *ast.AggregateFuncExpr) {
 		node.Args[idx].Accept(v)
 	}
 	v.builder.WriteString(")")
+
+	if node.FromLast {
+		v.builder.WriteString(" FROM LAST")
+	}
+	if node.IgnoreNull {
+		v.builder.WriteString(" IGNORE NULLS")
+	}
+
+	v.builder.WriteString(" OVER ")
+	v.writeWindowSpec(&node.Spec)
+}
+
+// writeWindowSpec 写入窗口定义: 窗口名，或 ([ref] [PARTITION BY ...] [ORDER BY ...] [frame])
+func (v *ExtractVisitor) writeWindowSpec(spec *ast.WindowSpec) {
+	if spec.Name.O != "" {
+		v.writeIdent(spec.Name.O)
+		if spec.OnlyAlias {
+			return
+		}
+		v.builder.WriteString(" AS ")
+	}
+
+	v.builder.WriteString("(")
+	sep := ""
+	if spec.Ref.O != "" {
+		v.writeIdent(spec.Ref.O)
+		sep = " "
+	}
+
+	if spec.PartitionBy != nil {
+		v.builder.WriteString(sep)
+		v.builder.WriteString("PARTITION BY ")
+		v.writeByItems(spec.PartitionBy.Items)
+		sep = " "
+	}
+
+	if spec.OrderBy != nil {
+		v.builder.WriteString(sep)
+		v.builder.WriteString("ORDER BY ")
+		v.writeByItems(spec.OrderBy.Items)
+		sep = " "
+	}
+
+	if spec.Frame != nil {
+		v.builder.WriteString(sep)
+		if spec.Frame.Type == ast.Rows {
+			v.builder.WriteString("ROWS BETWEEN ")
+		} else {
+			v.builder.WriteString("RANGE BETWEEN ")
+		}
+		v.writeFrameBound(&spec.Frame.Extent.Start)
+		v.builder.WriteString(" AND ")
+		v.writeFrameBound(&spec.Frame.Extent.End)
+	}
+	v.builder.WriteString(")")
+}
+
+// writeFrameBound 写入窗口框架的边界
+func (v *ExtractVisitor) writeFrameBound(bound *ast.FrameBound) {
+	if bound.Type == ast.CurrentRow {
+		v.builder.WriteString("CURRENT ROW")
+		return
+	}
+
+	if bound.UnBounded {
+		v.builder.WriteString("UNBOUNDED")
+	} else if bound.Expr != nil {
+		if bound.Unit != ast.TimeUnitInvalid {
+			v.builder.WriteString("INTERVAL ")
+		}
+		bound.Expr.Accept(v)
+		if bound.Unit != ast.TimeUnitInvalid {
+			v.builder.WriteString(" ")
+			v.builder.WriteString(bound.Unit.String())
+		}
+	}
+
+	if bound.Type == ast.Preceding {
+		v.builder.WriteString(" PRECEDING")
+	} else {
+		v.builder.WriteString(" FOLLOWING")
+	}
+}
+
+// writeByItems 写入以逗号分隔的 ORDER BY、PARTITION BY 项
+func (v *ExtractVisitor) writeByItems(items []*ast.ByItem) {
+	for idx := range items {
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
+
+		items[idx].Accept(v)
+	}
 }
 
 // handleCaseExpr 处理 CASE 表达式
@@ -852,7 +2229,11 @@ func (v *ExtractVisitor) handleParenthesesExpr(node *ast.ParenthesesExpr) {
 
 // handleFuncCallExpr 处理函数调用表达式
 func (v *ExtractVisitor) handleFuncCallExpr(node *ast.FuncCallExpr) {
-	v.builder.WriteString(node.FnName.String())
+	if v.writeArrayFunc(node) || v.writeOuterJoin(node) || v.writeSnowflakeFunc(node) || v.writeStruct(node) {
+		return
+	}
+
+	v.writeFuncName(node.FnName.String())
 	v.builder.WriteString("(")
 
 	for i := range len(node.Args) {
@@ -877,17 +2258,14 @@ func (v *ExtractVisitor) handleFuncCallExpr(node *ast.FuncCallExpr) {
 
 		// 如果是时间单位表达式，则特殊处理
 		if interval, ok := arg.(*ast.TimeUnitExpr); ok {
-			if i > 0 {
-				// 检查前一个参数是否为值表达式
-				if _, prevIsValue := node.Args[i-1].(*test_driver.ValueExpr); prevIsValue {
-					// 如果前一个参数是值表达式，我们需要将其作为参数
-					if valExpr, ok := node.Args[i-1].(*test_driver.ValueExpr); ok {
-						v.params = append(v.params, valExpr.GetValue())
-					}
-				}
-			}
 			v.builder.WriteString("INTERVAL ")
-			v.builder.WriteString("? ")
+			// 如果前一个参数是值表达式，我们需要将其作为参数
+			if valExpr, ok := prevValueExpr(node.Args, i); ok {
+				v.writeValue(valExpr)
+			} else {
+				v.builder.WriteString("?")
+			}
+			v.builder.WriteString(" ")
 			v.builder.WriteString(interval.Unit.String())
 			continue
 		}
@@ -904,17 +2282,48 @@ func (v *ExtractVisitor) handleFuncCallExpr(node *ast.FuncCallExpr) {
 	v.builder.WriteString(")")
 }
 
+// prevValueExpr 返回 args[i] 的前一个参数，如果它是值表达式
+func prevValueExpr(args []ast.ExprNode, i int) (*test_driver.ValueExpr, bool) {
+	if i == 0 {
+		return nil, false
+	}
+
+	valExpr, ok := args[i-1].(*test_driver.ValueExpr)
+	return valExpr, ok
+}
+
 // handleUnaryOperationExpr 处理一元操作表达式
 func (v *ExtractVisitor) handleUnaryOperationExpr(node *ast.UnaryOperationExpr) {
-	v.builder.WriteString(node.Op.String())
-	v.builder.WriteString(" ")
-	node.V.Accept(v)
+	v.writeOp(node.Op)
+
+	// 单词运算符（e.g. minus、bitneg、not、NOT）之后需要空格，符号运算符紧跟操作数，e.g. -?、!a；
+	// 连续的 - 之间保留空格，避免 -- 被识别为注释。
+	// 默认模式与二元运算符（eq、and）一致写出 opcode 单词，模板不是合法的 SQL，
+	// 只有 WithStandardOperators 的模板可以被再次解析
+	child, nested := node.V.(*ast.UnaryOperationExpr)
+	symbolic := node.Op == opcode.Not2 || (v.standardOps && node.Op != opcode.Not)
+	if !symbolic || (nested && isSign(node.Op) && isSign(child.Op)) {
+		v.builder.WriteString(" ")
+	}
+
+	// 前缀运算符之间没有歧义，e.g. minus minus a
+	if nested {
+		node.V.Accept(v)
+		return
+	}
+	v.writeOperand(node.V, precedence(node))
+}
+
+// isSign reports whether the unary operator is + or -.
+func isSign(op opcode.Op) bool {
+	return op == opcode.Minus || op == opcode.Plus
 }
 
 // handleIsNullExpr 处理 IS NULL 和 IS NOT NULL 表达式
 func (v *ExtractVisitor) handleIsNullExpr(node *ast.IsNullExpr) {
-	node.Expr.Accept(v)
-	if node.Not {
+	prefixed := v.prefixNot(node.Not)
+	v.writeOperand(node.Expr, precCompare)
+	if node.Not && !prefixed {
 		v.builder.WriteString(" IS NOT NULL")
 	} else {
 		v.builder.WriteString(" IS NULL")
@@ -926,6 +2335,11 @@ func (v *ExtractVisitor) handleExistsSubqueryExpr(node *ast.ExistsSubqueryExpr)
 	if node.Not {
 		v.builder.WriteString("NOT ")
 	}
+	if v.sqlglot { // 子查询自带括号，e.g. EXISTS(SELECT ...)
+		v.builder.WriteString("EXISTS")
+		node.Sel.Accept(v)
+		return
+	}
 	v.builder.WriteString("EXISTS (")
 
 	node.Sel.Accept(v)
@@ -951,10 +2365,10 @@ func (v *ExtractVisitor) handleTimeUnitExpr(node *ast.TimeUnitExpr) {
 // handleCompareSubqueryExpr 处理带有比较运算符的子查询表达式
 // 例如: age > ALL(SELECT age FROM users)
 func (v *ExtractVisitor) handleCompareSubqueryExpr(node *ast.CompareSubqueryExpr) {
-	node.L.Accept(v)
+	v.writeOperand(node.L, precCompare)
 
 	v.builder.WriteByte(' ')
-	v.builder.WriteString(node.Op.String())
+	v.writeOp(node.Op)
 
 	// 添加 ALL/ANY 关键字
 	if node.All {
@@ -1104,10 +2518,10 @@ func (v *ExtractVisitor) handleShowWarningsOrErrors(node *ast.ShowStmt) {
 // appendTableName 添加表名到 SQL 字符串
 func (v *ExtractVisitor) appendTableName(table *ast.TableName) {
 	if table.Schema.O != "" {
-		v.builder.WriteString(table.Schema.O)
+		v.writeIdent(table.Schema.O)
 		v.builder.WriteString(".")
 	}
-	v.builder.WriteString(table.Name.O)
+	v.writeIdent(table.Name.O)
 }
 
 // appendPatternAndWhere 添加 LIKE 和 WHERE 子句到 SQL 字符串
@@ -1115,8 +2529,7 @@ func (v *ExtractVisitor) appendPatternAndWhere(node *ast.ShowStmt) {
 	if node.Pattern != nil {
 		v.builder.WriteString(" LIKE ")
 		if valExpr, ok := node.Pattern.Pattern.(*test_driver.ValueExpr); ok {
-			v.builder.WriteString("?")
-			v.params = append(v.params, valExpr.GetValue())
+			v.writeValue(valExpr)
 		} else {
 			node.Pattern.Pattern.Accept(v)
 		}
@@ -1127,6 +2540,135 @@ func (v *ExtractVisitor) appendPatternAndWhere(node *ast.ShowStmt) {
 	}
 }
 
+// writeParam 写入参数占位符并保存参数值
+//
+// 占位符默认为 ?，设置了 placeholder 时由其生成，参数为参数在语句中的序号（从 1 开始）
+func (v *ExtractVisitor) writeParam(val any) {
+	v.nparams++
+	if !v.noParams && (v.maxParams == 0 || v.nparams <= v.maxParams) {
+		v.params = append(v.params, val)
+	}
+
+	if v.named {
+		name := v.paramName()
+		v.paramNames = append(v.paramNames, name)
+		v.paramCols = append(v.paramCols, v.paramColumn)
+		v.builder.WriteString(":")
+		v.builder.WriteString(name)
+
+		return
+	}
+
+	if v.placeholder == nil {
+		v.builder.WriteString("?")
+		return
+	}
+
+	v.builder.WriteString(v.placeholder(v.nparams))
+}
+
+// writeValue 写入字面量的参数占位符，不收集参数时不读取字面量的值，避免装箱
+func (v *ExtractVisitor) writeValue(node *test_driver.ValueExpr) {
+	if v.sqlglot && node.Kind() == test_driver.KindNull { // sqlglot 中 NULL 不是字面量
+		v.builder.WriteString("NULL")
+		return
+	}
+
+	v.writeParam(v.valueParam(node))
+	v.indexParam(node)
+}
+
+// valueParam 返回字面量的参数值，不收集参数时为 nil
+func (v *ExtractVisitor) valueParam(node *test_driver.ValueExpr) any {
+	if v.noParams {
+		return nil
+	}
+	if v.maxParamBytes > 0 || v.blobParamBytes > 0 {
+		if val, ok := v.limitParam(node); ok {
+			return val
+		}
+	}
+
+	return node.GetValue()
+}
+
+// writeListParam 将字面量列表写为一个列表占位符，参数为各字面量的值
+func (v *ExtractVisitor) writeListParam(list []ast.ExprNode) {
+	var values []any
+	if !v.noParams {
+		values = make([]any, len(list))
+		for idx := range list {
+			if item, ok := list[idx].(*test_driver.ValueExpr); ok {
+				values[idx] = v.valueParam(item)
+			}
+		}
+	}
+
+	v.nparams++
+	if !v.noParams && (v.maxParams == 0 || v.nparams <= v.maxParams) {
+		v.params = append(v.params, values)
+	}
+	v.builder.WriteString(v.listHolder(v.nparams))
+
+	for idx := range list {
+		v.indexParam(list[idx])
+	}
+}
+
+// allValues 判断列表是否非空且只包含字面量
+func allValues(list []ast.ExprNode) bool {
+	for idx := range list {
+		if _, ok := list[idx].(*test_driver.ValueExpr); !ok {
+			return false
+		}
+	}
+
+	return len(list) > 0
+}
+
+// paramName 生成当前参数的参数名，在语句中唯一
+func (v *ExtractVisitor) paramName() string {
+	base := v.paramColumn
+	if base == "" {
+		base = "p" + strconv.Itoa(v.nparams)
+	}
+
+	name := base
+	for n := 2; slices.Contains(v.paramNames, name); n++ {
+		name = base + "_" + strconv.Itoa(n)
+	}
+
+	return name
+}
+
+// withParamColumn 在 fn 执行期间将参数对应的列名设置为 column
+func (v *ExtractVisitor) withParamColumn(column string, fn func()) {
+	old := v.paramColumn
+	v.paramColumn = column
+	fn()
+	v.paramColumn = old
+}
+
+// writeOp 写入运算符，默认为 eq、gt 等，设置了 standardOps 时为标准 SQL 运算符
+func (v *ExtractVisitor) writeOp(op opcode.Op) {
+	if !v.standardOps {
+		v.builder.WriteString(op.String())
+		return
+	}
+
+	switch op {
+	case opcode.NE: // literal 为 !=，使用标准的 <>
+		v.builder.WriteString("<>")
+		return
+
+	case opcode.Not: // literal 为 "not "，带有尾随空格
+		v.builder.WriteString("NOT")
+		return
+	}
+
+	_ = op.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, v.builder))
+}
+
 // FIXME logError logs unhandled node type errors during SQL templatization
 func (v *ExtractVisitor) logError(details string) {
 	msg := "[SQL Templatize Error] unhandled node type: " + details
@@ -1,8 +1,16 @@
 package extract
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/kydance/sql-extractor/internal/models"
@@ -757,7 +765,7 @@ func TestTemplatizeSQL_Join(t *testing.T) {
 	template, tableInfos, params, op, err = parser.Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"SELECT t1.*, t2.name FROM schema1.table1 AS t1 LEFT JOIN (SELECT * FROM table2) AS t2 ON t1.id eq t2.id CROSS JOIN table3 AS t3 ON t2.id eq t3.id"},
+		[]string{"SELECT t1.*, t2.name FROM schema1.table1 AS t1 LEFT JOIN (SELECT * FROM table2) AS t2 ON t1.id eq t2.id INNER JOIN table3 AS t3 ON t2.id eq t3.id"},
 		template,
 	)
 	as.Equal(0, len(params[0]))
@@ -776,7 +784,7 @@ func TestTemplatizeSQL_Join(t *testing.T) {
 	template, tableInfos, params, op, err = parser.Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"SELECT t1.*, t2.name FROM schema1.table1 AS t1 LEFT JOIN (SELECT * FROM table2) AS t2 ON t1.id eq t2.id CROSS JOIN table3 AS t3 ON t2.id eq t3.id"},
+		[]string{"SELECT t1.*, t2.name FROM schema1.table1 AS t1 LEFT JOIN (SELECT * FROM table2) AS t2 ON t1.id eq t2.id INNER JOIN table3 AS t3 ON t2.id eq t3.id"},
 		template,
 	)
 	as.Equal(0, len(params[0]))
@@ -808,7 +816,7 @@ func TestTemplatizeSQL_Join(t *testing.T) {
 	template, tableInfos, params, op, err = parser.Extract(sql)
 	as.Nil(err)
 	as.Equal(
-		[]string{"SELECT t1.*, t2.name FROM schema1.table1 AS t1 LEFT JOIN (SELECT * FROM table2) AS t2 ON t1.id eq t2.id CROSS JOIN table3 AS t3 ON t2.id eq t3.id WHERE t1.id eq ? and t2.name eq ? and t3.name eq ? and t3.create_time BETWEEN ? AND ? and t3.age gt ? GROUP BY t1.id HAVING sum(t1.age) gt ? or max(t1.age) lt ? LIMIT ?, ?"},
+		[]string{"SELECT t1.*, t2.name FROM schema1.table1 AS t1 LEFT JOIN (SELECT * FROM table2) AS t2 ON t1.id eq t2.id INNER JOIN table3 AS t3 ON t2.id eq t3.id WHERE t1.id eq ? and t2.name eq ? and t3.name eq ? and t3.create_time BETWEEN ? AND ? and t3.age gt ? GROUP BY t1.id HAVING sum(t1.age) gt ? or max(t1.age) lt ? LIMIT ?, ?"},
 		template,
 	)
 	as.Equal(10, len(params[0]))
@@ -1099,7 +1107,7 @@ func TestTemplatizeSQL_Update(t *testing.T) {
 	template, tableInfos, params, op, err = NewExtractor().Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"UPDATE users AS u1 CROSS JOIN users AS u2 ON u1.manager_id eq u2.id SET u1.name eq u2.name, u1.age eq u2.age, u1.high eq u2.high, u1.weight eq u2.weight, u1.level eq u2.level, u1.create_time eq u2.create_time WHERE u1.id eq ?"},
+		[]string{"UPDATE users AS u1 INNER JOIN users AS u2 ON u1.manager_id eq u2.id SET u1.name eq u2.name, u1.age eq u2.age, u1.high eq u2.high, u1.weight eq u2.weight, u1.level eq u2.level, u1.create_time eq u2.create_time WHERE u1.id eq ?"},
 		template,
 	)
 	as.Equal(1, len(params[0]))
@@ -1127,7 +1135,7 @@ func TestTemplatizeSQL_Update(t *testing.T) {
 	template, tableInfos, params, op, err = NewExtractor().Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"UPDATE users AS u1 CROSS JOIN users AS u2 ON u1.manager_id eq u2.id SET u1.name eq ?, u1.age eq ?, u1.high eq ?, u1.weight eq u2.weight, u1.level eq u2.level, u1.create_time eq u2.create_time WHERE u1.uuid eq ?"},
+		[]string{"UPDATE users AS u1 INNER JOIN users AS u2 ON u1.manager_id eq u2.id SET u1.name eq ?, u1.age eq ?, u1.high eq ?, u1.weight eq u2.weight, u1.level eq u2.level, u1.create_time eq u2.create_time WHERE u1.uuid eq ?"},
 		template,
 	)
 	as.Equal(4, len(params[0]))
@@ -1238,7 +1246,7 @@ func TestTemplatizeSQL_Delete(t *testing.T) {
 	template, tableInfos, params, op, err = NewExtractor().Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"DELETE u FROM users AS u CROSS JOIN roles AS r ON u.id eq r.user_id WHERE u.id eq ?"},
+		[]string{"DELETE u FROM users AS u INNER JOIN roles AS r ON u.id eq r.user_id WHERE u.id eq ?"},
 		template,
 	)
 	as.Equal(1, len(params[0]))
@@ -1295,7 +1303,7 @@ func TestTemplatizeSQL_Delete(t *testing.T) {
 	template, tableInfos, params, op, err = NewExtractor().Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"DELETE u, r FROM users AS u CROSS JOIN roles AS r ON u.id eq r.user_id WHERE u.id eq ?"},
+		[]string{"DELETE u, r FROM users AS u INNER JOIN roles AS r ON u.id eq r.user_id WHERE u.id eq ?"},
 		template,
 	)
 	as.Equal(1, len(params[0]))
@@ -1312,7 +1320,7 @@ func TestTemplatizeSQL_Delete(t *testing.T) {
 	template, tableInfos, params, op, err = NewExtractor().Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"DELETE u FROM users AS u CROSS JOIN roles AS r ON u.id eq r.user_id WHERE u.uuid eq ?"},
+		[]string{"DELETE u FROM users AS u INNER JOIN roles AS r ON u.id eq r.user_id WHERE u.uuid eq ?"},
 		template,
 	)
 	as.Equal(1, len(params[0]))
@@ -1365,8 +1373,8 @@ func TestTemplatizeSQL_MultipleStatements(t *testing.T) {
 	as.Equal(3, len(params))
 	as.Equal("Alice", params[0][0])
 	as.Equal(int64(25), params[0][1])
-	as.Equal("Alice", params[1][0])
-	as.Equal(int64(25), params[1][1])
+	as.Equal(int64(26), params[1][0])
+	as.Equal("Alice", params[1][1])
 	as.Equal("Alice", params[2][0])
 	as.Equal(int64(25), params[2][1])
 	as.Equal([][]*models.TableInfo{
@@ -1403,6 +1411,21 @@ func TestTemplatizeSQL_MultipleStatements(t *testing.T) {
 	as.Equal([]models.SQLOpType{models.SQLOperationInsert, models.SQLOperationInsert}, op)
 }
 
+func TestTemplatizeSQL_ParamsNotShared(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	psr := NewExtractor()
+
+	// 每条语句的参数相互独立，且不会被后续的解析覆盖
+	_, _, params, _, err := psr.Extract("SELECT * FROM t WHERE a = 1; SELECT * FROM t WHERE b = 'x' AND c = 2")
+	as.Nil(err)
+	as.Equal([][]any{{int64(1)}, {"x", int64(2)}}, params)
+
+	_, _, _, _, err = psr.Extract("SELECT * FROM t WHERE a = 3 AND b = 4")
+	as.Nil(err)
+	as.Equal([][]any{{int64(1)}, {"x", int64(2)}}, params)
+}
+
 func TestTemplatizeSQL_Parentheses(t *testing.T) {
 	t.Parallel()
 	as := assert.New(t)
@@ -1999,7 +2022,7 @@ func TestTemplatizeSQL_Explain(t *testing.T) {
 	template, tableInfos, params, op, err = parser.Extract(sql)
 	as.Equal(nil, err)
 	as.Equal(
-		[]string{"EXPLAIN ANALYZE FORMAT = JSON SELECT u.* FROM users AS u CROSS JOIN orders AS o ON u.id eq o.user_id WHERE o.status eq ?"},
+		[]string{"EXPLAIN ANALYZE FORMAT = JSON SELECT u.* FROM users AS u INNER JOIN orders AS o ON u.id eq o.user_id WHERE o.status eq ?"},
 		template)
 	as.Equal(1, len(params))
 	as.Equal(1, len(params[0]))
@@ -2008,6 +2031,58 @@ func TestTemplatizeSQL_Explain(t *testing.T) {
 		models.NewTableInfo("", "orders", "", "orders"),
 	}}, tableInfos)
 	as.Equal([]models.SQLOpType{models.SQLOperationExplain}, op)
+
+	// Test EXPLAIN of DML with quoted FORMAT
+	sql = "EXPLAIN ANALYZE FORMAT = 'brief' UPDATE users SET age = 18 WHERE id = 1"
+	template, tableInfos, params, op, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal(
+		[]string{"EXPLAIN ANALYZE FORMAT = brief UPDATE users SET age eq ? WHERE id eq ?"},
+		template)
+	as.Equal([][]any{{int64(18), int64(1)}}, params)
+	as.Equal([][]*models.TableInfo{{
+		models.NewTableInfo("", "users", "", "users"),
+	}}, tableInfos)
+	as.Equal([]models.SQLOpType{models.SQLOperationExplain}, op)
+
+	// Test EXPLAIN FOR CONNECTION
+	sql = "EXPLAIN FOR CONNECTION 12"
+	template, tableInfos, params, op, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal([]string{"EXPLAIN FOR CONNECTION ?"}, template)
+	as.Equal([][]any{{uint64(12)}}, params)
+	as.Equal([][]*models.TableInfo{{}}, tableInfos)
+	as.Equal([]models.SQLOpType{models.SQLOperationExplain}, op)
+
+	sql = "EXPLAIN FORMAT = JSON FOR CONNECTION 34"
+	template, _, params, _, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal([]string{"EXPLAIN FORMAT = JSON FOR CONNECTION ?"}, template)
+	as.Equal([][]any{{uint64(34)}}, params)
+
+	sql = "EXPLAIN /* format */ format='row' FOR CONNECTION 56"
+	template, _, params, _, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal([]string{"EXPLAIN FORMAT = row FOR CONNECTION ?"}, template)
+	as.Equal([][]any{{uint64(56)}}, params)
+
+	sql = "DESC /* format */ FOR CONNECTION 78"
+	template, _, _, _, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal([]string{"EXPLAIN FOR CONNECTION ?"}, template)
+
+	// Test EXPLAIN EXPLORE
+	sql = "EXPLAIN EXPLORE SELECT * FROM users WHERE id = 1"
+	template, _, params, _, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal([]string{"EXPLAIN EXPLORE SELECT * FROM users WHERE id eq ?"}, template)
+	as.Equal([][]any{{int64(1)}}, params)
+
+	sql = "EXPLAIN EXPLORE 'a1b2c3'"
+	template, _, params, _, err = parser.Extract(sql)
+	as.Equal(nil, err)
+	as.Equal([]string{"EXPLAIN EXPLORE ?"}, template)
+	as.Equal([][]any{{"a1b2c3"}}, params)
 }
 
 func TestTemplatizeSQL_InvalidSQL(t *testing.T) {
@@ -2034,6 +2109,42 @@ func TestTemplatizeSQL_InvalidSQL(t *testing.T) {
 	as.Equal([]models.SQLOpType(nil), op)
 }
 
+func TestTemplatizeSQL_JoinTypes(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	sqlglot := NewExtractor(WithSQLGlotStyle())
+	tests := []struct {
+		sql, want, sqlglot string
+	}{
+		{"SELECT * FROM a JOIN b ON a.id = b.aid", "SELECT * FROM a INNER JOIN b ON a.id eq b.aid", "SELECT * FROM a JOIN b ON a.id = b.aid"},
+		{"SELECT * FROM a INNER JOIN b USING (id)", "SELECT * FROM a INNER JOIN b USING (id)", "SELECT * FROM a JOIN b USING (id)"},
+		{"SELECT * FROM a CROSS JOIN b", "SELECT * FROM a CROSS JOIN b", "SELECT * FROM a CROSS JOIN b"},
+		{"SELECT * FROM a JOIN b", "SELECT * FROM a CROSS JOIN b", "SELECT * FROM a CROSS JOIN b"},
+		{"SELECT * FROM a, b WHERE a.id = b.aid", "SELECT * FROM a CROSS JOIN b WHERE a.id eq b.aid", "SELECT * FROM a CROSS JOIN b WHERE a.id = b.aid"},
+		{"SELECT * FROM a LEFT OUTER JOIN b ON a.id = b.aid", "SELECT * FROM a LEFT JOIN b ON a.id eq b.aid", "SELECT * FROM a LEFT JOIN b ON a.id = b.aid"},
+		{"SELECT * FROM a RIGHT JOIN b USING (id)", "SELECT * FROM a RIGHT JOIN b USING (id)", "SELECT * FROM a RIGHT JOIN b USING (id)"},
+		{"SELECT * FROM a NATURAL JOIN b", "SELECT * FROM a NATURAL JOIN b", "SELECT * FROM a NATURAL JOIN b"},
+		{"SELECT * FROM a NATURAL LEFT OUTER JOIN b", "SELECT * FROM a NATURAL LEFT JOIN b", "SELECT * FROM a NATURAL LEFT JOIN b"},
+		{"SELECT * FROM a NATURAL RIGHT JOIN b", "SELECT * FROM a NATURAL RIGHT JOIN b", "SELECT * FROM a NATURAL RIGHT JOIN b"},
+		{"SELECT * FROM a STRAIGHT_JOIN b ON a.id = b.aid", "SELECT * FROM a STRAIGHT_JOIN b ON a.id eq b.aid", "SELECT * FROM a STRAIGHT_JOIN b ON a.id = b.aid"},
+		{"SELECT * FROM a STRAIGHT_JOIN b", "SELECT * FROM a STRAIGHT_JOIN b", "SELECT * FROM a STRAIGHT_JOIN b"},
+		{"SELECT * FROM a JOIN b ON a.id = b.aid NATURAL JOIN c LEFT JOIN d USING (id)",
+			"SELECT * FROM a INNER JOIN b ON a.id eq b.aid NATURAL JOIN c LEFT JOIN d USING (id)",
+			"SELECT * FROM a JOIN b ON a.id = b.aid NATURAL JOIN c LEFT JOIN d USING (id)"},
+	}
+	for _, tt := range tests {
+		template, _, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, template, tt.sql)
+
+		template, _, _, _, err = sqlglot.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.sqlglot}, template, tt.sql)
+	}
+}
+
 func TestTemplatizeSQL_CrossJoin(t *testing.T) {
 	t.Parallel()
 	as := assert.New(t)
@@ -2058,7 +2169,7 @@ func TestTemplatizeSQL_CrossJoin(t *testing.T) {
 	template, tableInfos, params, op, err = parser.Extract(sql)
 	as.Nil(err)
 	as.Equal([]string{
-		"SELECT * FROM users CROSS JOIN orders ON users.id eq orders.user_id",
+		"SELECT * FROM users INNER JOIN orders ON users.id eq orders.user_id",
 	}, template)
 	as.Equal(1, len(params))
 	as.Equal([][]*models.TableInfo{{
@@ -2839,7 +2950,7 @@ func TestExtractor_ComplexEscapeSequences(t *testing.T) {
 	as.Nil(err)
 	as.Equal([]models.SQLOpType{models.SQLOperationSelect}, op)
 	as.Equal(
-		[]string{"SELECT p.*, c.name AS category_name FROM products AS p CROSS JOIN categories AS c ON p.category_id eq c.id WHERE (p.price gt ? and p.stock gt ?) or (p.name LIKE ? and p.release_date gt ?) ORDER BY p.price DESC LIMIT ?"},
+		[]string{"SELECT p.*, c.name AS category_name FROM products AS p INNER JOIN categories AS c ON p.category_id eq c.id WHERE (p.price gt ? and p.stock gt ?) or (p.name LIKE ? and p.release_date gt ?) ORDER BY p.price DESC LIMIT ?"},
 		template,
 	)
 	as.Equal([][]any{{int64(100), int64(0), "%Limited Edition%", "2025-01-01", uint64(10)}}, params)
@@ -2953,3 +3064,2286 @@ func TestTemplateTable(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplatizeSQL_PrepareExecute(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	// PREPARE + EXECUTE + DEALLOCATE in the same input
+	sql := "PREPARE s FROM 'SELECT * FROM users WHERE id = ? AND age > 18'; EXECUTE s USING @a; DEALLOCATE PREPARE s"
+	template, tableInfos, params, op, err := parser.Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{
+		"PREPARE s FROM 'SELECT * FROM users WHERE id eq ? and age gt ?'",
+		"SELECT * FROM users WHERE id eq ? and age gt ?",
+		"DEALLOCATE PREPARE s",
+	}, template)
+	as.Equal([][]any{{int64(18)}, {"@a", int64(18)}, {}}, params)
+	as.Equal([][]*models.TableInfo{
+		{models.NewTableInfo("", "users", "", "users")},
+		{models.NewTableInfo("", "users", "", "users")},
+		{},
+	}, tableInfos)
+	as.Equal([]models.SQLOpType{
+		models.SQLOperationPrepare,
+		models.SQLOperationSelect,
+		models.SQLOperationDeallocate,
+	}, op)
+
+	// literals and USING variables in placeholder order
+	sql = "PREPARE s FROM 'SELECT * FROM users WHERE age > 18 AND id IN (?, 3, ?) LIMIT ?'; EXECUTE s USING @a, @b, @c"
+	template, _, params, _, err = parser.Extract(sql)
+	as.Nil(err)
+	as.Equal("SELECT * FROM users WHERE age gt ? and id IN (?, ?, ?) LIMIT ?", template[1])
+	as.Equal([]any{int64(18), "@a", int64(3), "@b", "@c"}, params[1])
+
+	// EXECUTE without PREPARE, or after DEALLOCATE
+	sql = "PREPARE s FROM 'DELETE FROM users WHERE id = ?'; DEALLOCATE PREPARE s; EXECUTE s USING @a, @b"
+	template, tableInfos, params, op, err = parser.Extract(sql)
+	as.Nil(err)
+	as.Equal("PREPARE s FROM 'DELETE FROM users WHERE id eq ?'", template[0])
+	as.Equal("EXECUTE s USING ?, ?", template[2])
+	as.Equal([]any{"@a", "@b"}, params[2])
+	as.Equal([]*models.TableInfo{}, tableInfos[2])
+	as.Equal(models.SQLOperationExecute, op[2])
+
+	// PREPARE from user variable
+	sql = "PREPARE s FROM @q"
+	template, _, _, op, err = parser.Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{"PREPARE s FROM @q"}, template)
+	as.Equal([]models.SQLOpType{models.SQLOperationPrepare}, op)
+
+	// invalid inner SQL
+	_, _, _, _, err = parser.Extract("PREPARE s FROM 'SELECT * FROM'")
+	as.NotNil(err)
+}
+
+func TestExtractPredicates(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	sql := "SELECT * FROM users WHERE name = 'kyden' AND 18 < age AND status IN (1, 2) AND nick LIKE 'ky%' AND bio LIKE '%x' ORDER BY id DESC; " +
+		"SELECT * FROM db.users u JOIN orders o ON u.id = o.user_id WHERE u.age BETWEEN 1 AND 2 AND o.status = 1 AND name = 'x'; " +
+		"UPDATE users SET age = 1 WHERE id = 1 ORDER BY created_at"
+	predicates, err := parser.ExtractPredicates(sql)
+	as.Nil(err)
+	as.Equal([][]*models.Predicate{
+		{
+			models.NewPredicate("", "users", "id", models.PredicateOrder),
+			models.NewPredicate("", "users", "name", models.PredicateEqual),
+			models.NewPredicate("", "users", "age", models.PredicateRange),
+			models.NewPredicate("", "users", "status", models.PredicateEqual),
+			models.NewPredicate("", "users", "nick", models.PredicateRange),
+		},
+		{
+			models.NewPredicate("db", "users", "age", models.PredicateRange),
+			models.NewPredicate("", "orders", "status", models.PredicateEqual),
+		},
+		{
+			models.NewPredicate("", "users", "created_at", models.PredicateOrder),
+			models.NewPredicate("", "users", "id", models.PredicateEqual),
+		},
+	}, predicates)
+
+	_, err = parser.ExtractPredicates("SELECT * FROM")
+	as.NotNil(err)
+}
+
+func TestExtractStructureMetrics(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	sql := "SELECT * FROM users; " +
+		"SELECT * FROM (SELECT id FROM users WHERE id IN (SELECT user_id FROM orders)) AS t WHERE EXISTS (SELECT 1 FROM logs); " +
+		"SELECT a FROM t1 UNION SELECT a FROM t2 UNION ALL (SELECT a FROM t3 WHERE a > (SELECT MAX(a) FROM t4)); " +
+		"UPDATE users SET age = 1 WHERE id = (SELECT id FROM admins LIMIT 1)"
+	metrics, err := parser.ExtractStructureMetrics(sql)
+	as.Nil(err)
+	as.Equal([]*models.StructureMetrics{
+		models.NewStructureMetrics(0, 1, 0, 0),
+		models.NewStructureMetrics(2, 3, 1, 0),
+		models.NewStructureMetrics(1, 2, 0, 3),
+		models.NewStructureMetrics(1, 1, 0, 0),
+	}, metrics)
+
+	_, err = parser.ExtractStructureMetrics("SELECT * FROM")
+	as.NotNil(err)
+}
+
+func TestEstimateCost(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	sql := "SELECT * FROM users WHERE id = 1; " +
+		"SELECT * FROM users WHERE age > 18 AND name = ?; " +
+		"SELECT * FROM users WHERE age > 18 OR name LIKE 'k%'; " +
+		"SELECT * FROM users WHERE name LIKE '%den'; " +
+		"SELECT * FROM users LIMIT 10; " +
+		"SELECT * FROM users u JOIN orders o ON u.id = o.uid WHERE DATE(o.created_at) = '2024-05-01' ORDER BY o.id; " +
+		"UPDATE users SET age = 1 WHERE id IN (SELECT uid FROM orders WHERE amount > 10); " +
+		"DELETE FROM logs; " +
+		"INSERT INTO users (id) VALUES (1); " +
+		"SELECT 1"
+	estimates, err := parser.EstimateCost(sql)
+	as.Nil(err)
+	as.Equal([]*models.CostEstimate{
+		models.NewCostEstimate(1, models.SelectivityPoint, false, false, false, 1, models.CostLow),
+		models.NewCostEstimate(1, models.SelectivityPoint, false, false, false, 1, models.CostLow),
+		models.NewCostEstimate(1, models.SelectivityRange, false, false, false, 4, models.CostMedium),
+		models.NewCostEstimate(1, models.SelectivityScan, true, false, false, 32, models.CostHigh),
+		models.NewCostEstimate(1, models.SelectivityRange, false, false, false, 4, models.CostMedium),
+		models.NewCostEstimate(2, models.SelectivityScan, false, true, true, 128, models.CostCritical),
+		models.NewCostEstimate(2, models.SelectivityRange, false, false, false, 8, models.CostMedium),
+		models.NewCostEstimate(1, models.SelectivityScan, false, false, false, 16, models.CostHigh),
+		models.NewCostEstimate(1, models.SelectivityPoint, false, false, false, 1, models.CostLow),
+		models.NewCostEstimate(0, models.SelectivityPoint, false, false, false, 0, models.CostLow),
+	}, estimates)
+	as.Equal("CRITICAL", estimates[5].Tier().String())
+
+	_, err = parser.EstimateCost("SELECT * FROM")
+	as.NotNil(err)
+}
+
+func TestBatchLookup(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	tcs := []struct {
+		sql     string
+		batched string
+		column  string
+		ok      bool
+	}{
+		{"SELECT * FROM users WHERE id = ?", "SELECT * FROM `users` WHERE `id` IN (?,?,?)", "id", true},
+		{"SELECT name FROM shop.users u WHERE (1 = u.id) LIMIT 1", "SELECT `name` FROM `shop`.`users` AS `u` WHERE `u`.`id` IN (?,?,?) LIMIT 1", "id", true},
+		{"SELECT * FROM users WHERE id > ?", "", "", false},
+		{"SELECT * FROM users WHERE id = ? AND tenant = ?", "", "", false},
+		{"SELECT * FROM users u JOIN orders o ON u.id = o.uid WHERE u.id = ?", "", "", false},
+		{"SELECT * FROM (SELECT * FROM users) t WHERE id = ?", "", "", false},
+		{"SELECT * FROM users", "", "", false},
+		{"DELETE FROM users WHERE id = ?", "", "", false},
+		{"SELECT * FROM users WHERE id = 1; SELECT * FROM users WHERE id = 2", "", "", false},
+	}
+	for _, tc := range tcs {
+		batched, column, ok, err := parser.BatchLookup(tc.sql, 3)
+		as.Nil(err, tc.sql)
+		as.Equal(tc.batched, batched, tc.sql)
+		as.Equal(tc.column, column, tc.sql)
+		as.Equal(tc.ok, ok, tc.sql)
+	}
+
+	batched, _, _, err := parser.BatchLookup("SELECT * FROM users WHERE id = ?", 0)
+	as.Nil(err)
+	as.Equal("SELECT * FROM `users` WHERE `id` IN (?)", batched)
+
+	_, _, _, err = parser.BatchLookup("SELEC", 3)
+	as.NotNil(err)
+}
+
+func TestTemplatizeSQL_BindVarPrefix(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor(WithBindVarPrefix("v"))
+
+	sql := "SELECT * FROM users WHERE created_at > DATE_SUB(NOW(), INTERVAL 1 DAY) AND id = 2; EXECUTE s USING @a, @b"
+	template, _, params, _, err := parser.Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{
+		"SELECT * FROM users WHERE created_at gt DATE_SUB(NOW(), INTERVAL :v1 DAY) and id eq :v2",
+		"EXECUTE s USING :v1, :v2",
+	}, template)
+	as.Equal([][]any{{int64(1), int64(2)}, {"@a", "@b"}}, params)
+}
+
+func TestTemplatizeSQL_ListPlaceholder(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor(WithBindVarPrefix("v"), WithListPlaceholder(func(n int) string { return fmt.Sprintf("::v%d", n) }))
+
+	// 只包含字面量的 IN 列表写为一个列表占位符
+	template, _, params, _, err := parser.Extract("SELECT * FROM t WHERE a IN (1, 2) AND b NOT IN (3, c) AND d = 4")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a IN ::v1 and b NOT IN (:v2, ?) and d eq :v3"}, template)
+	as.Equal([][]any{{[]any{int64(1), int64(2)}, int64(3), int64(4)}}, params)
+}
+
+func TestTemplatizeSQL_StandardOperatorsRawTableNames(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor(WithStandardOperators(), WithRawTableNames())
+
+	sql := "UPDATE db.users_01 SET age = age + 1 WHERE name <> 'kyden' AND NOT deleted OR id > ALL (SELECT id FROM admins_02)"
+	template, tableInfos, _, _, err := parser.Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{
+		"UPDATE db.users_01 SET age = age + ? WHERE name <> ? AND NOT deleted OR id > ALL((SELECT id FROM admins_02))",
+	}, template)
+	as.Equal([][]*models.TableInfo{{
+		models.NewTableInfo("db", "users_01", "db", "users_01"),
+		models.NewTableInfo("", "admins_02", "", "admins_02"),
+	}}, tableInfos)
+}
+
+func TestExtractNamed(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor(WithNamedParams())
+
+	sql := "SELECT * FROM users WHERE name = 'kyden' AND 18 < age AND age < 30 AND id IN (1, 2) AND nick LIKE 'k%' AND level BETWEEN 1 AND 9 LIMIT 5, 10; " +
+		"INSERT INTO users (name, age) VALUES ('a', 1), ('b', 2); " +
+		"UPDATE users SET age = 18 WHERE id = 1"
+	templates, args, err := parser.ExtractNamed(sql)
+	as.Nil(err)
+	as.Equal([]string{
+		"SELECT * FROM users WHERE name eq :name and :age lt age and age lt :age_2 and id IN (:id, :id_2) and nick LIKE :nick and level BETWEEN :level AND :level_2 LIMIT :offset, :limit",
+		"INSERT INTO users (name, age) VALUES (:name, :age), (:name_2, :age_2)",
+		"UPDATE users SET age eq :age WHERE id eq :id",
+	}, templates)
+	as.Equal([]map[string]any{
+		{
+			"name": "kyden", "age": int64(18), "age_2": int64(30), "id": int64(1), "id_2": int64(2),
+			"nick": "k%", "level": int64(1), "level_2": int64(9), "offset": uint64(5), "limit": uint64(10),
+		},
+		{"name": "a", "age": int64(1), "name_2": "b", "age_2": int64(2)},
+		{"age": int64(18), "id": int64(1)},
+	}, args)
+
+	// parameter without column
+	templates, args, err = parser.ExtractNamed("SELECT * FROM users WHERE 1 = 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM users WHERE :p1 eq :p2"}, templates)
+	as.Equal([]map[string]any{{"p1": int64(1), "p2": int64(1)}}, args)
+
+	_, _, err = parser.ExtractNamed("")
+	as.NotNil(err)
+	_, _, err = parser.ExtractNamed("SELECT * FROM")
+	as.NotNil(err)
+	_, _, err = NewExtractor().ExtractNamed("SELECT 1")
+	as.NotNil(err)
+}
+
+func TestExtractor_Concurrent(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range 50 {
+				sql := fmt.Sprintf("SELECT * FROM users WHERE id = %d AND age > %d; DELETE FROM orders WHERE id = %d", i, j, i*j)
+				template, _, params, _, err := parser.Extract(sql)
+				as.Nil(err)
+				as.Equal([]string{
+					"SELECT * FROM users WHERE id eq ? and age gt ?",
+					"DELETE FROM orders WHERE id eq ?",
+				}, template)
+				as.Equal([][]any{{int64(i), int64(j)}, {int64(i * j)}}, params)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+const benchmarkSQL = "SELECT u.id, u.name FROM users u JOIN orders o ON u.id = o.user_id WHERE u.age > 18 AND o.status IN (1, 2, 3) ORDER BY o.created_at DESC LIMIT 10"
+
+func BenchmarkExtractor_Extract(b *testing.B) {
+	parser := NewExtractor()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _, _, _, _ = parser.Extract(benchmarkSQL)
+	}
+}
+
+// BenchmarkExtractor_Visit measures the templatization of a parsed statement, without parsing.
+func BenchmarkExtractor_Visit(b *testing.B) {
+	parser := NewExtractor()
+	stmts, err := parser.parse(benchmarkSQL)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _, _, _, _ = parser.extractOneStmt(stmts[0], nil)
+	}
+}
+
+// TestExtractor_AllocationBudget guards the allocation counts of the hot path,
+// most allocations of Extract come from the TiDB parser.
+func TestExtractor_AllocationBudget(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not stable with the race detector")
+	}
+
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	stmts, err := parser.parse(benchmarkSQL)
+	as.Nil(err)
+
+	// builder, params, table infos (2) and the deduplicated table infos
+	visitAllocs := testing.AllocsPerRun(100, func() { _, _, _, _, _ = parser.extractOneStmt(stmts[0], nil) })
+	as.LessOrEqual(visitAllocs, 5.0)
+
+	// literals in aggregate functions are formatted without fmt, only the decimal allocates
+	stmts, err = parser.parse("SELECT COUNT(1), SUM(2.5), MAX('a') FROM users")
+	as.Nil(err)
+	aggrAllocs := testing.AllocsPerRun(100, func() { _, _, _, _, _ = parser.extractOneStmt(stmts[0], nil) })
+	as.LessOrEqual(aggrAllocs, 5.0)
+
+	parseAllocs := testing.AllocsPerRun(100, func() { _, _ = parser.parse(benchmarkSQL) })
+	extractAllocs := testing.AllocsPerRun(100, func() { _, _, _, _, _ = parser.Extract(benchmarkSQL) })
+	as.LessOrEqual(extractAllocs-parseAllocs, 10.0)
+}
+
+// BenchmarkExtractor_ExtractParallel shows the throughput scales with -cpu,
+// since parsers and visitors are pooled instead of shared.
+//
+//	go test -run ^$ -bench ExtractParallel -cpu 1,2,4,8 ./internal/extract
+func BenchmarkExtractor_ExtractParallel(b *testing.B) {
+	parser := NewExtractor()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _, _, _, _ = parser.Extract(benchmarkSQL)
+		}
+	})
+}
+
+func TestExtractor_LargeStatement(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor(WithParamsCapacity(8))
+	as.Equal(8, parser.capacityOf(stmtTierSmall))
+	as.Equal(largeParamsCapacity, parser.capacityOf(stmtTierLarge))
+	as.Equal(paramsMaxCount, NewExtractor(WithParamsCapacity(0)).capacityOf(stmtTierSmall))
+
+	// bulk INSERT with 10k values
+	var builder strings.Builder
+	builder.WriteString("INSERT INTO users (id, name) VALUES ")
+	for i := range 10000 {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		fmt.Fprintf(&builder, "(%d, 'name_%d')", i, i)
+	}
+
+	template, _, params, op, err := parser.Extract(builder.String())
+	as.Nil(err)
+	as.Equal(1, len(template))
+	as.True(strings.HasPrefix(template[0], "INSERT INTO users (id, name) VALUES (?, ?), (?, ?)"))
+	as.Equal(20000, len(params[0]))
+	as.Equal(int64(9999), params[0][19998])
+	as.Equal("name_9999", params[0][19999])
+	as.Equal([]models.SQLOpType{models.SQLOperationInsert}, op)
+
+	stmts, err := parser.parse(builder.String())
+	as.Nil(err)
+	as.Equal(stmtTierLarge, tierOf(stmts[0]))
+
+	stmts, err = parser.parse("SELECT * FROM users WHERE id = 1")
+	as.Nil(err)
+	as.Equal(stmtTierSmall, tierOf(stmts[0]))
+}
+
+func TestExtractor_ParamsRetention(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor(WithParamsCapacity(2))
+
+	// params grown beyond the retain limit are released before the visitor is pooled
+	stmts, err := parser.parse("SELECT * FROM users WHERE id IN (1, 2, 3, 4, 5, 6, 7, 8, 9, 10)")
+	as.Nil(err)
+	as.Nil(parser.visitStmt(stmts[0], func(v *ExtractVisitor) {
+		as.Equal(10, len(v.params))
+	}))
+
+	v, ok := parser.pools[stmtTierSmall].Get().(*ExtractVisitor)
+	as.True(ok)
+	as.Equal(0, len(v.params))
+	as.LessOrEqual(cap(v.params), paramsRetainFactor*2)
+}
+
+func BenchmarkExtractor_BulkInsert(b *testing.B) {
+	parser := NewExtractor()
+
+	var builder strings.Builder
+	builder.WriteString("INSERT INTO users (id, name) VALUES ")
+	for i := range 10000 {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		fmt.Fprintf(&builder, "(%d, 'name_%d')", i, i)
+	}
+	sql := builder.String()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _, _, _, _ = parser.Extract(sql)
+	}
+}
+
+func TestExtractor_Hooks(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var (
+		parseStart []ParseInfo
+		parseEnd   []ParseInfo
+		stmts      []StatementInfo
+		errs       []error
+	)
+	extractor := NewExtractor(WithHooks(&Hooks{
+		OnParseStart:    func(info ParseInfo) { parseStart = append(parseStart, info) },
+		OnParseEnd:      func(info ParseInfo) { parseEnd = append(parseEnd, info) },
+		OnStatementDone: func(info StatementInfo) { stmts = append(stmts, info) },
+		OnError:         func(err error) { errs = append(errs, err) },
+	}))
+
+	sql := "SELECT * FROM users WHERE id = 1; DELETE FROM orders"
+	_, _, _, _, err := extractor.Extract(sql)
+	as.Nil(err)
+	as.Equal([]ParseInfo{{Bytes: len(sql)}}, parseStart)
+	as.Equal(1, len(parseEnd))
+	as.Equal(len(sql), parseEnd[0].Bytes)
+	as.Nil(parseEnd[0].Err)
+	as.Equal(2, len(stmts))
+	as.Equal(0, stmts[0].Index)
+	as.Equal(len("SELECT * FROM users WHERE id = 1;"), stmts[0].Bytes)
+	as.Equal(models.SQLOperationSelect, stmts[0].OpType)
+	as.Greater(stmts[0].NodeCount, stmts[1].NodeCount)
+	as.Equal(1, stmts[1].Index)
+	as.Equal(models.SQLOperationDelete, stmts[1].OpType)
+	as.Empty(errs)
+
+	// parse error
+	_, _, _, _, err = extractor.Extract("SELEC 1")
+	as.NotNil(err)
+	as.Equal(2, len(parseEnd))
+	as.Equal(err, parseEnd[1].Err)
+	as.Equal([]error{err}, errs)
+
+	// empty SQL is not parsed, but still reported
+	_, _, _, _, err = extractor.Extract("")
+	as.NotNil(err)
+	as.Equal(2, len(parseStart))
+	as.Equal(2, len(errs))
+
+	// nil hooks
+	_, _, _, _, err = NewExtractor(WithHooks(&Hooks{})).Extract(sql)
+	as.Nil(err)
+}
+
+func TestExtractor_RegisterNodeHandler(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor()
+	extractor.RegisterNodeHandler((*ast.RowExpr)(nil), func(v *ExtractVisitor, n ast.Node) bool {
+		v.WriteString("(")
+		for idx, expr := range n.(*ast.RowExpr).Values {
+			if idx > 0 {
+				v.WriteString(", ")
+			}
+			v.Visit(expr)
+		}
+		v.WriteString(")")
+
+		return true
+	})
+
+	template, _, params, _, err := extractor.Extract("SELECT * FROM t WHERE (a, b) = (1, 'x')")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE (a, b) eq (?, ?)"}, template)
+	as.Equal([][]any{{int64(1), "x"}}, params)
+
+	// override builtin handling
+	extractor.RegisterNodeHandler((*test_driver.ValueExpr)(nil), func(v *ExtractVisitor, n ast.Node) bool {
+		if s, ok := n.(*test_driver.ValueExpr).GetValue().(string); ok {
+			v.WriteParam("<" + s + ">")
+			return true
+		}
+
+		return false // fall back to builtin handling
+	})
+
+	template, _, params, _, err = extractor.Extract("SELECT * FROM t WHERE a = 'x' AND b = 2")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a eq ? and b eq ?"}, template)
+	as.Equal([][]any{{"<x>", int64(2)}}, params)
+
+	// remove handler
+	extractor.RegisterNodeHandler((*test_driver.ValueExpr)(nil), nil)
+	_, _, params, _, err = extractor.Extract("SELECT * FROM t WHERE a = 'x'")
+	as.Nil(err)
+	as.Equal([][]any{{"x"}}, params)
+}
+
+func TestExtractor_ExtractWithWarnings(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	parser := NewExtractor()
+
+	template, _, _, _, warnings, err := parser.ExtractWithWarnings("SELECT * FROM t WHERE a = 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a eq ?"}, template)
+	as.Nil(warnings)
+
+	// optimizer hint in the wrong position
+	template, _, _, _, warnings, err = parser.ExtractWithWarnings("SELECT * FROM t /*+ USE_INDEX(t, a) */ WHERE a = 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a eq ?"}, template)
+	as.Equal(1, len(warnings))
+
+	_, _, _, _, _, err = parser.ExtractWithWarnings("")
+	as.NotNil(err)
+}
+
+func TestExtractor_ExtractDump(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	stmts, err := NewExtractor(WithCollapsedValues()).ExtractDump(
+		"INSERT INTO `users` VALUES (1,'a'),(2,'b'),(3,'c'); INSERT INTO users VALUES (4,'d'); DELETE FROM users")
+	as.Nil(err)
+	as.Equal([]DumpStmt{
+		{"INSERT INTO users VALUES (?, ?)", []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}, models.SQLOperationInsert, 3},
+		{"INSERT INTO users VALUES (?, ?)", []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}, models.SQLOperationInsert, 1},
+		{"DELETE FROM users", []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}, models.SQLOperationDelete, 0},
+	}, stmts)
+
+	// not collapsed
+	stmts, err = NewExtractor().ExtractDump("INSERT INTO t VALUES (1),(2)")
+	as.Nil(err)
+	as.Equal("INSERT INTO t VALUES (?), (?)", stmts[0].TemplatizedSQL)
+	as.Equal(2, stmts[0].Rows)
+
+	// collapsed params
+	template, _, params, _, err := NewExtractor(WithCollapsedValues()).Extract("INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')")
+	as.Nil(err)
+	as.Equal([]string{"INSERT INTO t (a, b) VALUES (?, ?)"}, template)
+	as.Equal([][]any{{int64(1), "x"}}, params)
+
+	_, err = NewExtractor().ExtractDump("")
+	as.NotNil(err)
+}
+
+func TestExtractor_ParamMarkers(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	template, _, params, _, err := NewExtractor(WithParamMarkers()).Extract("SELECT * FROM t WHERE a = ? AND b = 1 AND c IN (?, ?)")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a eq ? and b eq ? and c IN (?, ?)"}, template)
+	as.Equal([][]any{{ParamMarker(0), int64(1), ParamMarker(1), ParamMarker(2)}}, params)
+
+	// numbered by position, whatever the rendering order
+	_, _, params, _, err = NewExtractor(WithParamMarkers()).Extract("SELECT * FROM t WHERE a IN (?, ?) LIMIT ?, ?")
+	as.Nil(err)
+	as.Equal([][]any{{ParamMarker(0), ParamMarker(1), ParamMarker(2), ParamMarker(3)}}, params)
+
+	// markers are not collected by default
+	_, _, params, _, err = NewExtractor().Extract("SELECT * FROM t WHERE a = ? AND b = 1")
+	as.Nil(err)
+	as.Equal([][]any{{int64(1)}}, params)
+}
+
+func TestExtractor_ParamColumns(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithNamedParams(), WithParamMarkers())
+	columns, err := e.ParamColumns("SELECT * FROM t WHERE a = ? AND b IN (?, ?) AND 1 = 1 AND LOWER(c) = ? LIMIT ?, ?; " +
+		"UPDATE t SET a = ? WHERE b BETWEEN ? AND ?")
+	as.Nil(err)
+	as.Equal([][]string{{"a", "b", "b", "", "offset", "limit"}, {"a", "b", "b"}}, columns)
+
+	_, err = NewExtractor(WithParamMarkers()).ParamColumns("SELECT ?")
+	as.NotNil(err)
+}
+
+func TestExtractor_SQLGlotStyle(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithSQLGlotStyle())
+	templates, _, params, _, err := e.Extract("SELECT count(*), sum(a * 2) FROM t JOIN s USING (id) " +
+		"WHERE a NOT IN (1, 2) AND b IS NOT NULL AND c = NULL AND NOT EXISTS (SELECT 1 FROM r) LIMIT 10, 20")
+	as.Nil(err)
+	as.Equal([]string{"SELECT COUNT(*), SUM(a * ?) FROM t JOIN s USING (id) " +
+		"WHERE NOT a IN (?, ?) AND NOT b IS NULL AND c = NULL AND NOT EXISTS(SELECT ? FROM r) LIMIT ? OFFSET ?"}, templates)
+	as.Equal([][]any{{int64(2), int64(1), int64(2), int64(1), uint64(20), uint64(10)}}, params)
+}
+
+func TestExtractor_ParamNames(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithNamedParams())
+	names, err := e.ParamNames("SELECT * FROM t WHERE a = 1 AND b IN (2, 3) LIMIT 10, 20; UPDATE t SET a = 1 WHERE id = 2")
+	as.Nil(err)
+	as.Equal([][]string{{"a", "b", "b_2", "offset", "limit"}, {"a", "id"}}, names)
+
+	_, args, err := e.ExtractNamed("SELECT * FROM t WHERE a = 1 AND b IN (2, 3) LIMIT 10, 20")
+	as.Nil(err)
+	for idx, name := range names[0] {
+		as.Contains(args[0], name, idx)
+	}
+
+	_, err = NewExtractor().ParamNames("SELECT 1")
+	as.NotNil(err)
+}
+
+func TestExtractor_Translate(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+
+	tests := []struct {
+		sql         string
+		to          string
+		expected    string
+		unsupported []string
+	}{
+		{"SELECT * FROM `users` WHERE id eq ? and name ne ? LIMIT ?, ?", DialectMySQL,
+			"SELECT * FROM `users` WHERE `id`=? AND `name`!=? LIMIT ?,?", nil},
+		{"SELECT * FROM `users` WHERE id eq ? and name ne ? LIMIT ?, ?", DialectPostgreSQL,
+			`SELECT * FROM "users" WHERE "id"=? AND "name"!=? LIMIT ? OFFSET ?`, nil},
+		{"SELECT * FROM `users` WHERE id eq ? LIMIT ?, ?", DialectOracle,
+			`SELECT * FROM "users" WHERE "id"=? OFFSET ? ROWS FETCH NEXT ? ROWS ONLY`, nil},
+		{"SELECT * FROM `users` WHERE id eq ? LIMIT ?, ?", DialectSQLServer,
+			`SELECT * FROM "users" WHERE "id"=? LIMIT ?,?`, []string{"LIMIT without ORDER BY"}},
+		{"SELECT DISTINCT name FROM users WHERE a = 'x\\'y' LIMIT 10", DialectSQLServer,
+			`SELECT DISTINCT TOP (10) "name" FROM "users" WHERE "a"='x''y'`, nil},
+		{"SELECT IFNULL(a, ?) FROM t WHERE id IN (SELECT uid FROM o ORDER BY id LIMIT 5) ORDER BY a LIMIT 5 OFFSET 10", DialectSQLServer,
+			`SELECT COALESCE("a", ?) FROM "t" WHERE "id" IN (SELECT "uid" FROM "o" ORDER BY "id" OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY) ORDER BY "a" OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY`, nil},
+		{"INSERT INTO t (a) VALUES (?) ON DUPLICATE KEY UPDATE a = VALUES(a)", DialectPostgreSQL,
+			`INSERT INTO "t" ("a") VALUES (?) ON DUPLICATE KEY UPDATE "a"=VALUES("a")`, []string{"ON DUPLICATE KEY UPDATE"}},
+		{"UPDATE t SET a = ? WHERE b gt ? LIMIT ?", DialectOracle,
+			`UPDATE "t" SET "a"=? WHERE "b">? LIMIT ?`, []string{"LIMIT in UPDATE"}},
+		{"SELECT GROUP_CONCAT(a) FROM t USE INDEX (i) WHERE a <=> ? FOR UPDATE; SELECT 1 LIMIT 1", DialectSQLServer,
+			`SELECT GROUP_CONCAT("a" SEPARATOR ',') FROM "t" USE INDEX ("i") WHERE "a"<=>? FOR UPDATE; SELECT TOP (1) 1`,
+			[]string{"FOR UPDATE", "GROUP_CONCAT", "index hints", "<=>"}},
+		{"SELECT TOP (?) a FROM t ORDER BY a", DialectPostgreSQL, `SELECT "a" FROM "t" ORDER BY "a" LIMIT ?`, nil},
+		{"SELECT a FROM t ORDER BY a OFFSET ? ROWS FETCH NEXT ? ROWS ONLY", DialectMySQL, "SELECT `a` FROM `t` ORDER BY `a` LIMIT ?,?", nil},
+		{"SELECT a FROM t ORDER BY a OFFSET ? ROWS", DialectPostgreSQL, `SELECT "a" FROM "t" ORDER BY "a" OFFSET ?`, nil},
+		{"SELECT TOP (?) PERCENT a FROM t", DialectSQLServer, `SELECT TOP (?) "a" FROM "t"`, []string{"TOP PERCENT"}},
+		{"SELECT * FROM a STRAIGHT_JOIN b ON a.id eq b.aid", DialectPostgreSQL,
+			`SELECT * FROM "a" STRAIGHT_JOIN "b" ON "a"."id"="b"."aid"`, []string{"STRAIGHT_JOIN"}},
+		{"SELECT * FROM t WHERE d gt NOW() - INTERVAL ? DAY", DialectPostgreSQL,
+			`SELECT * FROM "t" WHERE "d">DATE_SUB(NOW(), INTERVAL ? DAY)`, []string{"INTERVAL"}},
+	}
+
+	for _, test := range tests {
+		translated, unsupported, err := e.Translate(test.sql, test.to)
+		as.Nil(err, test.sql)
+		as.Equal(test.expected, translated, test.sql)
+		as.Equal(test.unsupported, unsupported, test.sql)
+	}
+
+	_, _, err := e.Translate("SELECT 1", "db2")
+	as.NotNil(err)
+	_, _, err = e.Translate("SELEC 1", DialectPostgreSQL)
+	as.NotNil(err)
+	_, _, err = e.Translate("", DialectPostgreSQL)
+	as.NotNil(err)
+
+	// quoted operator names are kept
+	as.Equal("a = 'it''s eq' AND `x``eq` <> \"gt\"", standardizeOps("a eq 'it''s eq' and `x``eq` ne \"gt\""))
+
+	// 与运算符同名的列、表和函数不替换
+	as.Equal("SELECT minus, plus AS eq FROM t AS minus WHERE eq = ? AND `minus` - plus > - ? AND NOT minus AND minus.a = - minus(?)",
+		standardizeOps("SELECT minus, plus AS eq FROM t AS minus WHERE eq eq ? and `minus` minus plus gt minus ? and not minus and minus.a eq minus minus(?)"))
+	// 模板中与运算符同名的标识符加引号，e.g. `minus` minus `plus`
+	sqls, _, _, _, err := e.Extract("SELECT minus, plus AS eq, t.bitneg FROM t WHERE eq = 1 AND minus - plus > -2 AND ~bitneg = 3 ORDER BY minus")
+	as.Nil(err)
+	as.Equal([]string{"SELECT `minus`, `plus` AS `eq`, t.`bitneg` FROM t WHERE `eq` eq ? and `minus` minus `plus` gt minus ? and bitneg `bitneg` eq ? ORDER BY `minus`"}, sqls)
+	translated, unsupported, err := e.Translate(sqls[0], DialectPostgreSQL)
+	as.Nil(err)
+	as.Equal(`SELECT "minus","plus" AS "eq","t"."bitneg" FROM "t" WHERE "eq"=? AND "minus"-"plus">-? AND ~"bitneg"=? ORDER BY "minus"`, translated)
+	as.Nil(unsupported)
+}
+
+func TestExtractor_WithFoldedIdentifiers(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithFoldedIdentifiers())
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM Users", "SELECT * FROM users"},
+		{"select * from `users`", "SELECT * FROM users"},
+		{"SELECT U.Name, `u`.`Age` FROM `Shop`.`Users` AS U WHERE U.Id = 1",
+			"SELECT u.name, u.age FROM shop.users AS u WHERE u.id eq ?"},
+		{"SELECT `select`, `my col` FROM `My Table` WHERE `1` = 1",
+			"SELECT `select`, `my col` FROM `my table` WHERE `1` eq ?"},
+		{"SELECT * FROM TB_10", "SELECT * FROM tb_?"},
+		{"INSERT INTO T (A, `B`) VALUES (1, 2)", "INSERT INTO t (a, b) VALUES (?, ?)"},
+	}
+	for _, tt := range tests {
+		sqls, tableInfos, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, sqls, tt.sql)
+		as.NotEmpty(tableInfos[0], tt.sql)
+	}
+
+	// 表信息保留原始名称
+	_, tableInfos, _, _, err := e.Extract("SELECT * FROM Shop.Users")
+	as.Nil(err)
+	as.Equal("Shop", tableInfos[0][0].Schema())
+	as.Equal("Users", tableInfos[0][0].TableName())
+	as.Equal("users", tableInfos[0][0].TemplatizedTableName())
+}
+
+func TestExtractor_WithIdentifierQuoting(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	tests := []struct {
+		quoting IdentifierQuoting
+		fold    bool
+		want    string
+	}{
+		{QuoteDefault, false, "SELECT order, Name FROM Shop.TB_10 AS T WHERE T.Id eq ?"},
+		{QuoteWhenNeeded, false, "SELECT `order`, Name FROM Shop.TB_? AS T WHERE T.Id eq ?"},
+		{QuoteAlways, false, "SELECT `order`, `Name` FROM `Shop`.`TB_?` AS `T` WHERE `T`.`Id` eq ?"},
+		{QuoteAlways, true, "SELECT `order`, `name` FROM `shop`.`tb_?` AS `t` WHERE `t`.`id` eq ?"},
+	}
+	for _, tt := range tests {
+		opts := []Option{WithIdentifierQuoting(tt.quoting)}
+		if tt.fold {
+			opts = append(opts, WithFoldedIdentifiers())
+		}
+		if tt.quoting == QuoteDefault {
+			opts = append(opts, WithRawTableNames())
+		}
+
+		sqls, _, _, _, err := NewExtractor(opts...).Extract("SELECT `order`, Name FROM Shop.TB_10 AS T WHERE T.Id = 1")
+		as.Nil(err)
+		as.Equal([]string{tt.want}, sqls, tt.quoting)
+	}
+}
+
+func TestExtractor_WithCollapsedIn(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithCollapsedIn(), WithMaxParams(2, ParamsCollapse))
+	sqls, _, params, _, err := e.Extract("SELECT * FROM t WHERE a IN (1, 2, 3) AND b NOT IN ('x'); " +
+		"SELECT * FROM t WHERE a IN (4, 5) AND b = 6 AND c = 7")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a IN (?) and b NOT IN (?)",
+		"SELECT * FROM t WHERE a IN (?) and b eq ? and c eq ?"}, sqls)
+	as.Equal([][]any{{int64(1), "x"}, {int64(4), int64(6)}}, params)
+}
+
+func TestExtractor_Digests(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var large strings.Builder
+	large.WriteString("INSERT INTO tb_1 (id, name) VALUES ")
+	for idx := range 2000 {
+		if idx > 0 {
+			large.WriteString(", ")
+		}
+		fmt.Fprintf(&large, "(%d, 'name_%d')", idx, idx)
+	}
+
+	e := NewExtractor()
+	for _, sql := range []string{
+		"SELECT * FROM users WHERE id = 1",
+		"SELECT a, COUNT(*) FROM t GROUP BY a HAVING COUNT(*) > 1; UPDATE t SET a = 1 WHERE b IN (1, 2)",
+		"PREPARE s FROM 'SELECT * FROM t WHERE id = 1'; EXECUTE s USING @a; DEALLOCATE PREPARE s",
+		large.String(),
+	} {
+		templates, _, _, _, err := e.Extract(sql)
+		as.Nil(err)
+
+		want := make([]string, len(templates))
+		for idx := range templates {
+			sum := sha256.Sum256([]byte(templates[idx]))
+			want[idx] = hex.EncodeToString(sum[:])
+		}
+
+		digests, err := e.Digests(sql)
+		as.Nil(err)
+		as.Equal(want, digests)
+	}
+
+	// visitor 放回池中后不再计算摘要
+	templates, _, _, _, err := e.Extract("SELECT * FROM users WHERE id = 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM users WHERE id eq ?"}, templates)
+
+	_, err = e.Digests("")
+	as.NotNil(err)
+	_, err = e.Digests("SELEC 1")
+	as.NotNil(err)
+}
+
+func TestExtractor_WithCollect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	want, wantTables, _, wantOps, err := NewExtractor().Extract(benchmarkSQL)
+	as.Nil(err)
+
+	templates, tableInfos, params, ops, err := NewExtractor(WithCollectParams(false)).Extract(benchmarkSQL)
+	as.Nil(err)
+	as.Equal(want, templates)
+	as.Equal(wantTables, tableInfos)
+	as.Equal([][]any{{}}, params)
+	as.Equal(wantOps, ops)
+
+	templates, tableInfos, params, _, err = NewExtractor(WithCollectTables(false)).Extract(benchmarkSQL)
+	as.Nil(err)
+	as.Equal(want, templates)
+	as.Empty(tableInfos[0])
+	as.Equal([]any{int64(18), int64(1), int64(2), int64(3), uint64(10)}, params[0])
+
+	// 不收集参数时占位符仍按序号生成
+	templates, _, params, _, err = NewExtractor(WithCollectParams(false), WithBindVarPrefix("v")).
+		Extract("SELECT * FROM t WHERE a = 1 AND b IN (2, 3)")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a eq :v1 and b IN (:v2, :v3)"}, templates)
+	as.Empty(params[0])
+
+	namedSQL := "SELECT * FROM t WHERE a = 1 AND a = 2 AND b = c + 3"
+	want, _, err = NewExtractor(WithNamedParams()).ExtractNamed(namedSQL)
+	as.Nil(err)
+	templates, args, err := NewExtractor(WithCollectParams(false), WithNamedParams()).ExtractNamed(namedSQL)
+	as.Nil(err)
+	as.Equal(want, templates)
+	as.Empty(args[0])
+}
+
+func TestExtractor_WithCollectAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not stable with the race detector")
+	}
+
+	as := assert.New(t)
+	full := NewExtractor()
+	bare := NewExtractor(WithCollectParams(false), WithCollectTables(false))
+
+	stmts, err := full.parse(benchmarkSQL)
+	as.Nil(err)
+
+	fullAllocs := testing.AllocsPerRun(100, func() { _, _, _, _, _ = full.extractOneStmt(stmts[0], nil) })
+	bareAllocs := testing.AllocsPerRun(100, func() { _, _, _, _, _ = bare.extractOneStmt(stmts[0], nil) })
+	as.Less(bareAllocs, fullAllocs)
+}
+
+func BenchmarkExtractor_ExtractWithoutCollect(b *testing.B) {
+	parser := NewExtractor(WithCollectParams(false), WithCollectTables(false))
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _, _, _, _ = parser.Extract(benchmarkSQL)
+	}
+}
+
+func TestExtractor_WithMaxParams(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT * FROM t WHERE a IN (1, 2, 3, 4, 5) AND b = 6; SELECT * FROM t WHERE a = 1"
+
+	// 截断: 模板不变，只保留前 n 个参数，并报告警告
+	templates, _, params, _, warnings, err := NewExtractor(WithMaxParams(3, ParamsTruncate)).ExtractWithWarnings(sql)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a IN (?, ?, ?, ?, ?) and b eq ?", "SELECT * FROM t WHERE a eq ?"}, templates)
+	as.Equal([][]any{{int64(1), int64(2), int64(3)}, {int64(1)}}, params)
+	as.Equal([]string{"statement 1: 6 parameters truncated to 3 (truncate)"}, warnings)
+
+	// 折叠: IN 列表和 INSERT VALUES 只保留第一项
+	templates, _, params, _, warnings, err = NewExtractor(WithMaxParams(3, ParamsCollapse)).ExtractWithWarnings(sql)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a IN (?) and b eq ?", "SELECT * FROM t WHERE a eq ?"}, templates)
+	as.Equal([][]any{{int64(1), int64(6)}, {int64(1)}}, params)
+	as.Empty(warnings)
+
+	templates, _, params, _, err = NewExtractor(WithMaxParams(3, ParamsCollapse)).
+		Extract("INSERT INTO t (a, b) VALUES (1, 2), (3, 4)")
+	as.Nil(err)
+	as.Equal([]string{"INSERT INTO t (a, b) VALUES (?, ?)"}, templates)
+	as.Equal([][]any{{int64(1), int64(2)}}, params)
+
+	// 折叠后仍超出上限时截断
+	_, _, params, _, warnings, err = NewExtractor(WithMaxParams(1, ParamsCollapse)).
+		ExtractWithWarnings("SELECT * FROM t WHERE a IN (1, 2) AND b = 3")
+	as.Nil(err)
+	as.Equal([][]any{{int64(1)}}, params)
+	as.Equal([]string{"statement 1: 2 parameters truncated to 1 (collapse)"}, warnings)
+
+	// 报错
+	e := NewExtractor(WithMaxParams(3, ParamsError))
+	_, _, _, _, err = e.Extract(sql)
+	as.ErrorIs(err, ErrTooManyParams)
+	_, err = e.Digests(sql)
+	as.ErrorIs(err, ErrTooManyParams)
+	_, _, params, _, err = e.Extract("SELECT * FROM t WHERE a IN (1, 2, 3)")
+	as.Nil(err)
+	as.Len(params[0], 3)
+
+	// 折叠后的模板与摘要一致
+	collapse := NewExtractor(WithMaxParams(3, ParamsCollapse))
+	templates, _, _, _, err = collapse.Extract(sql)
+	as.Nil(err)
+	digests, err := collapse.Digests(sql)
+	as.Nil(err)
+	sum := sha256.Sum256([]byte(templates[0]))
+	as.Equal(hex.EncodeToString(sum[:]), digests[0])
+
+	as.Equal("truncate", ParamsTruncate.String())
+	as.Equal("collapse", ParamsCollapse.String())
+	as.Equal("error", ParamsError.String())
+}
+
+func TestExtractor_WithAssignmentStyle(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// INSERT ... SET 被解析器转换为 VALUES 形式
+	sql := "UPDATE t SET a = 1 WHERE b = 2; INSERT INTO t SET a = 1; " +
+		"INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = 2"
+
+	tests := []struct {
+		opts []Option
+		want []string
+	}{
+		{nil, []string{
+			"UPDATE t SET a eq ? WHERE b eq ?",
+			"INSERT INTO t (a) VALUES (?)",
+			"INSERT INTO t (a) VALUES (?) ON DUPLICATE KEY UPDATE a eq ?",
+		}},
+		{[]Option{WithAssignmentStyle(AssignmentSymbolic)}, []string{
+			"UPDATE t SET a = ? WHERE b eq ?",
+			"INSERT INTO t (a) VALUES (?)",
+			"INSERT INTO t (a) VALUES (?) ON DUPLICATE KEY UPDATE a = ?",
+		}},
+		{[]Option{WithStandardOperators()}, []string{
+			"UPDATE t SET a = ? WHERE b = ?",
+			"INSERT INTO t (a) VALUES (?)",
+			"INSERT INTO t (a) VALUES (?) ON DUPLICATE KEY UPDATE a = ?",
+		}},
+		{[]Option{WithStandardOperators(), WithAssignmentStyle(AssignmentWord)}, []string{
+			"UPDATE t SET a eq ? WHERE b = ?",
+			"INSERT INTO t (a) VALUES (?)",
+			"INSERT INTO t (a) VALUES (?) ON DUPLICATE KEY UPDATE a eq ?",
+		}},
+	}
+	for _, tt := range tests {
+		templates, _, _, _, err := NewExtractor(tt.opts...).Extract(sql)
+		as.Nil(err)
+		as.Equal(tt.want, templates)
+	}
+}
+
+func TestExtractor_Having(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT a FROM t GROUP BY a HAVING COUNT(*) IN (1, 2)",
+			"SELECT a FROM t GROUP BY a HAVING COUNT(1) IN (?, ?)", []any{int64(1), int64(2)}},
+		{"SELECT a FROM t GROUP BY a HAVING SUM(b) BETWEEN 1 AND 10",
+			"SELECT a FROM t GROUP BY a HAVING SUM(b) BETWEEN ? AND ?", []any{int64(1), int64(10)}},
+		{"SELECT a FROM t GROUP BY a HAVING NOT (COUNT(*) > 1)",
+			"SELECT a FROM t GROUP BY a HAVING not (COUNT(1) gt ?)", []any{int64(1)}},
+		{"SELECT a FROM t GROUP BY a HAVING ABS(SUM(b)) > 3",
+			"SELECT a FROM t GROUP BY a HAVING ABS(SUM(b)) gt ?", []any{int64(3)}},
+		{"SELECT a FROM t GROUP BY a HAVING GROUP_CONCAT(b) LIKE 'x%'",
+			"SELECT a FROM t GROUP BY a HAVING GROUP_CONCAT(b SEPARATOR ',') LIKE ?", []any{"x%"}},
+		{"SELECT a FROM t GROUP BY a HAVING COUNT(*) IS NOT NULL",
+			"SELECT a FROM t GROUP BY a HAVING COUNT(1) IS NOT NULL", []any{}},
+		{"SELECT a, b FROM t GROUP BY a, b HAVING b",
+			"SELECT a, b FROM t GROUP BY a, b HAVING b", []any{}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+}
+
+func TestExtractor_AggregateOrderAndWindow(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT GROUP_CONCAT(DISTINCT b ORDER BY c DESC, d SEPARATOR ';') FROM t GROUP BY a",
+			"SELECT GROUP_CONCAT(DISTINCT b ORDER BY c DESC, d SEPARATOR ';') FROM t GROUP BY a", []any{}},
+		{"SELECT a, SUM(b) OVER (PARTITION BY a ORDER BY c ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM t WHERE d = 1",
+			"SELECT a, SUM(b) OVER (PARTITION BY a ORDER BY c ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM t WHERE d eq ?",
+			[]any{int64(1)}},
+		{"SELECT row_number() OVER w, LAG(b, 2, 0) OVER (w ORDER BY c) FROM t WINDOW w AS (PARTITION BY a)",
+			"SELECT ROW_NUMBER() OVER w, LAG(b, 2, 0) OVER (w ORDER BY c) FROM t WINDOW w AS (PARTITION BY a)", []any{}},
+		{"SELECT COUNT(*) OVER () FROM t",
+			"SELECT COUNT(1) OVER () FROM t", []any{}},
+		{"SELECT AVG(b) OVER (ORDER BY d RANGE BETWEEN INTERVAL 1 DAY PRECEDING AND UNBOUNDED FOLLOWING) FROM t",
+			"SELECT AVG(b) OVER (ORDER BY d RANGE BETWEEN INTERVAL 1 DAY PRECEDING AND UNBOUNDED FOLLOWING) FROM t", []any{}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+}
+
+func TestExtractor_DistinctAggregates(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT COUNT(DISTINCT a, b) FROM t",
+			"SELECT COUNT(DISTINCT a, b) FROM t", []any{}},
+		{"SELECT COUNT(DISTINCT a, b) / COUNT(*), COUNT(DISTINCT (a)) FROM t WHERE c = 1",
+			"SELECT COUNT(DISTINCT a, b) div COUNT(1), COUNT(DISTINCT (a)) FROM t WHERE c eq ?", []any{int64(1)}},
+		{"SELECT COUNT(DISTINCT a) + 1, SUM(DISTINCT a * 2) FROM t",
+			"SELECT COUNT(DISTINCT a) plus ?, SUM(DISTINCT a mul 2) FROM t", []any{int64(1)}},
+		{"SELECT COUNT(DISTINCT IF(a > 1, b, NULL)) FROM t",
+			"SELECT COUNT(DISTINCT IF(a gt 1, b, NULL)) FROM t", []any{}},
+		{"SELECT AVG(DISTINCT a), MAX(DISTINCT a), GROUP_CONCAT(DISTINCT a, b) FROM t",
+			"SELECT AVG(DISTINCT a), MAX(DISTINCT a), GROUP_CONCAT(DISTINCT a, b SEPARATOR ',') FROM t", []any{}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+}
+
+func TestRewriteWildcardModifiers(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * EXCEPT (a, b) FROM t", "SELECT *, sqlextractor_except(a, b) FROM t"},
+		{"SELECT t.* except(a) REPLACE (f(x, 1) AS b, 'a,b' as c) FROM t",
+			"SELECT t.*, sqlextractor_except(a), sqlextractor_replace(f(x, 1), b, 'a,b', c) FROM t"},
+		{"SELECT * FROM a EXCEPT (SELECT * FROM b)", "SELECT * FROM a EXCEPT (SELECT * FROM b)"},
+		{"SELECT * EXCEPT (SELECT 1)", "SELECT * EXCEPT (SELECT 1)"},
+		{"SELECT '* EXCEPT (a)', `* EXCEPT (b)` FROM t -- * EXCEPT (c)", "SELECT '* EXCEPT (a)', `* EXCEPT (b)` FROM t -- * EXCEPT (c)"},
+		{"SELECT * REPLACE (a) FROM t", "SELECT * REPLACE (a) FROM t"},
+		{"SELECT * EXCEPTION (a) FROM t", "SELECT * EXCEPTION (a) FROM t"},
+		{"SELECT a * 2 FROM t", "SELECT a * 2 FROM t"},
+	}
+	for _, tt := range tests {
+		as.Equal(tt.want, rewriteWildcardModifiers(tt.sql), tt.sql)
+	}
+
+	e := NewExtractor(WithWildcardModifiers())
+	templates, _, params, _, err := e.Extract("SELECT * REPLACE (price * 2 AS price) FROM t WHERE a = 'x'")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * REPLACE (price mul ? AS price) FROM t WHERE a eq ?"}, templates)
+	as.Equal([][]any{{int64(2), "x"}}, params)
+
+	// 未开启时保持 MySQL 语法
+	_, _, _, _, err = NewExtractor().Extract("SELECT * EXCEPT (a) FROM t")
+	as.NotNil(err)
+}
+
+func TestExtractor_DMLModifiers(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"INSERT LOW_PRIORITY INTO t (a) VALUES (1)", "INSERT LOW_PRIORITY INTO t (a) VALUES (?)"},
+		{"INSERT DELAYED IGNORE INTO t (a) VALUES (1)", "INSERT DELAYED IGNORE INTO t (a) VALUES (?)"},
+		{"INSERT HIGH_PRIORITY INTO t (a) VALUES (1)", "INSERT HIGH_PRIORITY INTO t (a) VALUES (?)"},
+		{"INSERT IGNORE INTO t (a) VALUES (1)", "INSERT IGNORE INTO t (a) VALUES (?)"},
+		{"UPDATE IGNORE t SET a = 1 WHERE b = 2", "UPDATE IGNORE t SET a eq ? WHERE b eq ?"},
+		{"UPDATE LOW_PRIORITY IGNORE t SET a = 1", "UPDATE LOW_PRIORITY IGNORE t SET a eq ?"},
+		{"DELETE QUICK FROM t WHERE a = 1", "DELETE QUICK FROM t WHERE a eq ?"},
+		{"DELETE LOW_PRIORITY QUICK IGNORE FROM t WHERE a = 1", "DELETE LOW_PRIORITY QUICK IGNORE FROM t WHERE a eq ?"},
+		{"DELETE FROM t WHERE a = 1", "DELETE FROM t WHERE a eq ?"},
+	}
+	for _, tt := range tests {
+		templates, _, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+	}
+}
+
+func TestExtractor_SelectOptions(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want string
+		opts models.SelectOptions
+	}{
+		{"SELECT SQL_NO_CACHE a FROM t", "SELECT SQL_NO_CACHE a FROM t", models.SelectNoCache},
+		{"SELECT SQL_CALC_FOUND_ROWS a FROM t LIMIT 10", "SELECT SQL_CALC_FOUND_ROWS a FROM t LIMIT ?", models.SelectCalcFoundRows},
+		{"SELECT SQL_SMALL_RESULT a FROM t GROUP BY a", "SELECT SQL_SMALL_RESULT a FROM t GROUP BY a", models.SelectSmallResult},
+		{"SELECT SQL_BIG_RESULT a FROM t GROUP BY a", "SELECT SQL_BIG_RESULT a FROM t GROUP BY a", models.SelectBigResult},
+		{"SELECT a FROM (SELECT SQL_NO_CACHE a FROM s) AS x",
+			"SELECT a FROM (SELECT SQL_NO_CACHE a FROM s) AS x", models.SelectNoCache},
+		{"SELECT SQL_CACHE a FROM t", "SELECT a FROM t", 0},
+	}
+	for _, tt := range tests {
+		templates, _, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+
+		opts, err := e.ExtractSelectOptions(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]models.SelectOptions{tt.opts}, opts, tt.sql)
+	}
+}
+
+func TestExtractor_ParenthesizedJoins(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM ((a JOIN b ON a.x = b.x) LEFT JOIN c ON c.y = a.y) WHERE a.id = 1",
+			"SELECT * FROM ((a INNER JOIN b ON a.x eq b.x) LEFT JOIN c ON c.y eq a.y) WHERE a.id eq ?"},
+		{"SELECT * FROM a LEFT JOIN (b JOIN c ON b.x = c.x) ON a.x = b.x",
+			"SELECT * FROM a LEFT JOIN (b INNER JOIN c ON b.x eq c.x) ON a.x eq b.x"},
+		{"SELECT * FROM (a, b) JOIN c ON c.x = a.x",
+			"SELECT * FROM (a CROSS JOIN b) INNER JOIN c ON c.x eq a.x"},
+		{"SELECT * FROM a JOIN b ON a.x = b.x JOIN c ON c.x = b.x",
+			"SELECT * FROM a INNER JOIN b ON a.x eq b.x INNER JOIN c ON c.x eq b.x"},
+		{"SELECT * FROM (a)", "SELECT * FROM a"},
+	}
+	for _, tt := range tests {
+		templates, _, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+	}
+}
+
+func TestExtractor_JoinUsing(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM users JOIN orders USING (user_id)",
+			"SELECT * FROM users INNER JOIN orders USING (user_id)"},
+		{"SELECT * FROM a LEFT JOIN b USING (x, `y`) WHERE a.z = 1",
+			"SELECT * FROM a LEFT JOIN b USING (x, y) WHERE a.z eq ?"},
+		{"DELETE a FROM a JOIN b USING (id) WHERE b.flag = 1",
+			"DELETE a FROM a INNER JOIN b USING (id) WHERE b.flag eq ?"},
+	}
+	for _, tt := range tests {
+		templates, _, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+	}
+}
+
+func TestExtractJoins(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	joins, err := e.ExtractJoins("SELECT * FROM db.users u JOIN orders o ON u.id = o.user_id AND o.state = 1 " +
+		"LEFT JOIN items i USING (order_id, sku) WHERE u.id = 1; " +
+		"SELECT * FROM a JOIN b ON x = y; SELECT * FROM t")
+	as.Nil(err)
+	as.Len(joins, 3)
+
+	edges := make([]string, 0, len(joins[0]))
+	for _, edge := range joins[0] {
+		edges = append(edges, edge.Left().Schema()+"."+edge.Left().TableName()+"."+edge.LeftColumn()+
+			" = "+edge.Right().TableName()+"."+edge.RightColumn())
+	}
+	as.Equal([]string{
+		"db.users.id = orders.user_id",
+		".orders.order_id = items.order_id",
+		".orders.sku = items.sku",
+	}, edges)
+	as.Empty(joins[1])
+	as.Empty(joins[2])
+}
+
+func TestExtractor_Lateral(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT * FROM t, LATERAL (SELECT a FROM s WHERE s.id = t.id AND s.b > 1) AS x",
+			"SELECT * FROM t CROSS JOIN LATERAL (SELECT a FROM s WHERE s.id eq t.id and s.b gt ?) AS x", []any{int64(1)}},
+		{"SELECT * FROM t LEFT JOIN lateral (SELECT a FROM s WHERE s.id = t.id LIMIT 1) `x` ON true",
+			"SELECT * FROM t LEFT JOIN LATERAL (SELECT a FROM s WHERE s.id eq t.id LIMIT ?) AS x ON ?", []any{uint64(1), int64(1)}},
+		{"SELECT 'LATERAL (1) x', lateral_col FROM t",
+			"SELECT ?, lateral_col FROM t", []any{"LATERAL (1) x"}},
+		{"SELECT * FROM t, (SELECT a FROM s) AS x",
+			"SELECT * FROM t CROSS JOIN (SELECT a FROM s) AS x", []any{}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// Split 返回输入的语句，而不是改写后的语句
+	sql := "SELECT * FROM t, LATERAL (SELECT a FROM s WHERE s.id = t.id) AS x; SELECT 1"
+	texts, err := e.Split(sql)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t, LATERAL (SELECT a FROM s WHERE s.id = t.id) AS x", "SELECT 1"}, texts)
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.Equal([]string{"SELECT 'a;b'", "/* c */ SELECT `x;` FROM t -- d;e", "SELECT 2 /* ; */"},
+		SplitStatements("SELECT 'a;b'; /* c */ SELECT `x;` FROM t -- d;e\n;; SELECT 2 /* ; */; -- done"))
+	as.Equal([]string{"NOT VALID SQL", "SELECT $$a", "b$$"}, SplitStatements("NOT VALID SQL; SELECT $$a;b$$"))
+	as.Empty(SplitStatements(" ; /* */ "))
+
+	// PostgreSQL 的 dollar-quoted 字符串
+	texts, err := NewExtractor(WithPostgres()).Split("SELECT $$a;b$$; SELECT 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT $$a;b$$", "SELECT 1"}, texts)
+}
+
+func TestRewriteLateral(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.Equal("SELECT * FROM t", rewriteLateral("SELECT * FROM t"))
+	as.Equal("SELECT * FROM t, (SELECT 1) AS sqlextractor_lateral_x",
+		rewriteLateral("SELECT * FROM t, LATERAL (SELECT 1) AS x"))
+	as.Equal("SELECT * FROM t, (SELECT * FROM s, (SELECT 1) sqlextractor_lateral_y) sqlextractor_lateral_x",
+		rewriteLateral("SELECT * FROM t, LATERAL (SELECT * FROM s, LATERAL (SELECT 1) y) x"))
+	as.Equal("SELECT * FROM t, (SELECT 1) AS `sqlextractor_lateral_x`",
+		rewriteLateral("SELECT * FROM t, LATERAL (SELECT 1) AS `x`"))
+
+	// 没有别名、引号和注释中的 LATERAL 不改写
+	as.Equal("SELECT * FROM t, LATERAL (SELECT 1)", rewriteLateral("SELECT * FROM t, LATERAL (SELECT 1)"))
+	as.Equal("SELECT 'LATERAL (1) x' /* LATERAL (1) y */", rewriteLateral("SELECT 'LATERAL (1) x' /* LATERAL (1) y */"))
+}
+
+func TestExtractor_NamedWindowAndQualify(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithQualify())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT ROW_NUMBER() OVER w FROM t WINDOW w AS (PARTITION BY a ORDER BY b)",
+			"SELECT ROW_NUMBER() OVER w FROM t WINDOW w AS (PARTITION BY a ORDER BY b)", []any{}},
+		{"SELECT SUM(x) OVER (w ORDER BY c) FROM t WINDOW w AS (PARTITION BY a), w2 AS (w)",
+			"SELECT SUM(x) OVER (w ORDER BY c) FROM t WINDOW w AS (PARTITION BY a), w2 AS (w)", []any{}},
+		{"SELECT a FROM t QUALIFY ROW_NUMBER() OVER (PARTITION BY a ORDER BY b) = 1",
+			"SELECT a FROM t QUALIFY ROW_NUMBER() OVER (PARTITION BY a ORDER BY b) eq ?", []any{int64(1)}},
+		{"SELECT a, COUNT(*) FROM t WHERE b > 2 GROUP BY a HAVING COUNT(*) > 3 QUALIFY RANK() OVER w <= 5 WINDOW w AS (ORDER BY a) ORDER BY a LIMIT 5",
+			"SELECT a, COUNT(1) FROM t WHERE b gt ? GROUP BY a HAVING COUNT(1) gt ? QUALIFY RANK() OVER w le ? WINDOW w AS (ORDER BY a) ORDER BY a LIMIT ?",
+			[]any{int64(2), int64(3), int64(5), uint64(5)}},
+		{"SELECT * FROM (SELECT a FROM t QUALIFY ROW_NUMBER() OVER (ORDER BY a) = 1) AS x WHERE a = 'QUALIFY'",
+			"SELECT * FROM (SELECT a FROM t QUALIFY ROW_NUMBER() OVER (ORDER BY a) eq ?) AS x WHERE a eq ?", []any{int64(1), "QUALIFY"}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	_, _, _, _, err := NewExtractor().Extract("SELECT a FROM t QUALIFY ROW_NUMBER() OVER (ORDER BY a) = 1")
+	as.NotNil(err)
+}
+
+func TestRewriteQualify(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.Equal("SELECT a FROM t", rewriteQualify("SELECT a FROM t"))
+	as.Equal("SELECT a , sqlextractor_qualify(x = 1) FROM t ORDER BY a",
+		rewriteQualify("SELECT a FROM t QUALIFY x = 1 ORDER BY a"))
+	as.Equal("SELECT a , sqlextractor_qualify(x = (SELECT 1)) FROM t ;SELECT 1",
+		rewriteQualify("SELECT a FROM t QUALIFY x = (SELECT 1);SELECT 1"))
+	as.Equal("SELECT (SELECT b , sqlextractor_qualify(y) FROM s ) , sqlextractor_qualify(x) FROM t ",
+		rewriteQualify("SELECT (SELECT b FROM s QUALIFY y) FROM t QUALIFY x"))
+	as.Equal("SELECT 'QUALIFY x' /* QUALIFY y */ FROM t", rewriteQualify("SELECT 'QUALIFY x' /* QUALIFY y */ FROM t"))
+}
+
+func TestExtractor_Precedence(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM t WHERE a OR b AND c", "SELECT * FROM t WHERE a or b and c"},
+		{"SELECT * FROM t WHERE (a OR b) AND c", "SELECT * FROM t WHERE (a or b) and c"},
+		{"SELECT * FROM t WHERE NOT a = 1", "SELECT * FROM t WHERE not a eq ?"},
+		{"SELECT a - (b - c), a - b - c, - - a FROM t", "SELECT a minus (b minus c), a minus b minus c, minus minus a FROM t"},
+		{"SELECT * FROM t WHERE a BETWEEN 1 AND 2 = 0", "SELECT * FROM t WHERE (a BETWEEN ? AND ?) eq ?"},
+		{"SELECT * FROM t WHERE a NOT BETWEEN 1 AND 2", "SELECT * FROM t WHERE a NOT BETWEEN ? AND ?"},
+		{"SELECT * FROM t WHERE a IN (1) = b", "SELECT * FROM t WHERE a IN (?) eq b"},
+	}
+	for _, tt := range tests {
+		templates, _, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+	}
+
+	// 没有括号表达式的语法树，e.g. 由 NodeHandler 改写
+	stmts, err := e.parse("SELECT * FROM t WHERE (a OR b) AND c AND -(d + 1) > 2 AND (e = 1) IS NULL")
+	as.Nil(err)
+	stmts[0].Accept(parenthesesStripper{})
+	templatedSQL, _, _, _, err := e.extractOneStmt(stmts[0], nil)
+	as.Nil(err)
+	as.Equal("SELECT * FROM t WHERE (a or b) and c and minus (d plus ?) gt ? and e eq ? IS NULL", templatedSQL)
+}
+
+// parenthesesStripper removes the parentheses expressions of the operands.
+type parenthesesStripper struct{}
+
+func (parenthesesStripper) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.BinaryOperationExpr:
+		node.L, node.R = unwrapParentheses(node.L), unwrapParentheses(node.R)
+	case *ast.UnaryOperationExpr:
+		node.V = unwrapParentheses(node.V)
+	case *ast.IsNullExpr:
+		node.Expr = unwrapParentheses(node.Expr)
+	}
+
+	return n, false
+}
+
+func (parenthesesStripper) Leave(n ast.Node) (ast.Node, bool) { return n, true }
+
+func TestExtractor_UnaryOperators(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT -5, - - a, -(-a), +a, ~a, !a, NOT a, -(a + 1), !(a = 1) FROM t " +
+		"WHERE a NOT BETWEEN 1 AND 2 AND NOT b AND NOT NOT c AND b = -1"
+
+	templates, _, params, _, err := NewExtractor().Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{"SELECT minus ?, minus minus a, minus (minus a), plus a, bitneg a, !a, not a, minus (a plus ?), !(a eq ?) FROM t " +
+		"WHERE a NOT BETWEEN ? AND ? and not b and not not c and b eq minus ?"}, templates)
+	as.Equal([][]any{{int64(5), int64(1), int64(1), int64(1), int64(2), int64(1)}}, params)
+
+	// 标准运算符的模板可以被再次解析，且结果不变
+	e := NewExtractor(WithStandardOperators())
+	templates, _, _, _, err = e.Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{"SELECT -?, - -a, -(-a), +a, ~a, !a, NOT a, -(a + ?), !(a = ?) FROM t " +
+		"WHERE a NOT BETWEEN ? AND ? AND NOT b AND NOT NOT c AND b = -?"}, templates)
+
+	reparsed, _, _, _, err := e.Extract(templates[0])
+	as.Nil(err)
+	as.Equal(templates, reparsed)
+
+	// 默认模式的模板使用 opcode 单词（minus、bitneg、eq），不能被再次解析；
+	// 但标准运算符的模板在默认模式下得到相同的模板
+	defaults, _, _, _, err := NewExtractor().Extract(sql)
+	as.Nil(err)
+	_, _, _, _, err = NewExtractor().Extract(defaults[0])
+	as.NotNil(err)
+
+	fromStandard, _, _, _, err := NewExtractor().Extract(templates[0])
+	as.Nil(err)
+	as.Equal(defaults, fromStandard)
+}
+
+func TestExtractor_ForceIndex(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	digests, err := e.Digests("SELECT * FROM t1 JOIN s.t2 ON t1.id = t2.id WHERE t1.a = 1")
+	as.Nil(err)
+
+	rules := map[string][]ForceIndexRule{digests[0]: {
+		{Digest: digests[0], Schema: "s", Table: "T2", Indexes: []string{"idx_a", "PRIMARY"}},
+		{Digest: digests[0], Schema: "other", Table: "t1", Indexes: []string{"idx_b"}},
+	}}
+
+	sql, changed, err := e.ForceIndex("SELECT 1; SELECT * FROM t1 JOIN s.t2 IGNORE INDEX (idx_c) ON t1.id = t2.id WHERE t1.a = 2", rules)
+	as.Nil(err)
+	as.True(changed)
+	as.Equal("SELECT 1; SELECT * FROM `t1` JOIN `s`.`t2` FORCE INDEX (`idx_a`, `PRIMARY`) ON `t1`.`id`=`t2`.`id` WHERE `t1`.`a`=2", sql)
+
+	sql, changed, err = e.ForceIndex("SELECT * FROM t1 WHERE a = 1", rules)
+	as.Nil(err)
+	as.False(changed)
+	as.Equal("SELECT * FROM t1 WHERE a = 1", sql)
+
+	sql, changed, err = e.ForceIndex("SELECT * FROM t1 WHERE a = 1", nil)
+	as.Nil(err)
+	as.False(changed)
+	as.Equal("SELECT * FROM t1 WHERE a = 1", sql)
+}
+
+func TestExtractor_ParamBytes(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.True(isBlobLike("iVBORw0KGgoAAAANSUhEUgAA"))
+	as.True(isBlobLike("a\x00b"))
+	as.True(isBlobLike("\xff\xfe"))
+	as.False(isBlobLike("hello world"))
+
+	as.Equal("ab", truncateUTF8("abc", 2))
+	as.Equal("你", truncateUTF8("你好", 5))
+	as.Equal("", truncateUTF8("你好", 2))
+
+	e := NewExtractor(WithMaxParamBytes(3))
+	_, _, params, _, err := e.Extract("SELECT * FROM t WHERE a = 'abcdef' AND b = 'abc' AND c = 1")
+	as.Nil(err)
+	as.Equal([][]any{{TruncatedParam{Prefix: "abc", Size: 6, Truncated: true}, "abc", int64(1)}}, params)
+}
+
+func TestExtractor_ExtractTimeWindows(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	tests := []struct {
+		sql     string
+		windows []string // column: window
+	}{
+		{"SELECT * FROM t WHERE ts > DATE_SUB(CURDATE(), INTERVAL 1 MONTH)", []string{"ts: last 30 days"}},
+		{"SELECT * FROM t WHERE ts BETWEEN NOW() - INTERVAL 2 HOUR AND NOW() - INTERVAL 1 HOUR", []string{"ts: last 1h0m0s"}},
+		{"SELECT * FROM t WHERE ts >= DATE '2024-01-01' AND ts < '2024-02-01 00:00:00'", []string{"ts: 31 days"}},
+		{"SELECT * FROM t WHERE '2024-01-01' <= ts", []string{"ts: unknown width"}},
+		{"SELECT * FROM t WHERE ts <= NOW() AND d >= CURRENT_DATE - INTERVAL '1' DAY", []string{"ts: unbounded", "d: last 1 day"}},
+		{"SELECT * FROM t WHERE a > 1 AND b >= 'x' AND ts = NOW()", []string{}},
+		{"UPDATE t SET a = 1 WHERE ts < (NOW() - INTERVAL 1 YEAR)", []string{"ts: unbounded"}},
+	}
+
+	e := NewExtractor()
+	for _, tt := range tests {
+		windows, err := e.ExtractTimeWindows(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Len(windows, 1, tt.sql)
+
+		got := make([]string, 0, len(windows[0]))
+		for _, w := range windows[0] {
+			got = append(got, w.Column()+": "+w.String())
+		}
+		as.Equal(tt.windows, got, tt.sql)
+	}
+}
+
+func TestExtractor_ExtractColumns(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	format := func(columns []*models.ColumnUsage) []string {
+		got := make([]string, 0, len(columns))
+		for _, c := range columns {
+			got = append(got, c.Table()+"."+c.Column()+" "+c.Usage().String())
+		}
+		return got
+	}
+
+	e := NewExtractor()
+	columns, err := e.ExtractColumns("SELECT u.name, o.total, o.total * 2 FROM users u JOIN orders o ON u.id = o.user_id " +
+		"JOIN items USING (order_id) WHERE u.age > 18 AND o.state = 'paid' AND status = 1 " +
+		"GROUP BY u.name HAVING o.total > 10 ORDER BY o.created_at; " +
+		"UPDATE users SET name = 'x' WHERE id = 1 ORDER BY age; SELECT * FROM t")
+	as.Nil(err)
+	as.Len(columns, 3)
+	as.Equal([]string{
+		"users.name PROJECTION", "orders.total PROJECTION",
+		"orders.order_id JOIN", "items.order_id JOIN",
+		"users.id JOIN", "orders.user_id JOIN",
+		"users.age FILTER", "orders.state FILTER", "orders.total FILTER",
+		"orders.created_at ORDER",
+	}, format(columns[0]))
+	as.Equal([]string{"users.name WRITE", "users.id FILTER", "users.age ORDER"}, format(columns[1]))
+	as.Empty(columns[2])
+
+	columns, err = e.ExtractColumns("INSERT INTO t (a, b) VALUES (1, 2) ON DUPLICATE KEY UPDATE c = 3; INSERT INTO t SET d = 1")
+	as.Nil(err)
+	as.Equal([]string{"t.a WRITE", "t.b WRITE", "t.c WRITE"}, format(columns[0]))
+	as.Equal([]string{"t.d WRITE"}, format(columns[1]))
+
+	// 子查询中的列按其所在子句统计
+	columns, err = e.ExtractColumns("SELECT a FROM t WHERE b IN (SELECT t.c FROM t WHERE t.d = 1)")
+	as.Nil(err)
+	as.Equal([]string{"t.a PROJECTION", "t.b FILTER", "t.c PROJECTION", "t.d FILTER"}, format(columns[0]))
+}
+
+func TestExtractor_WithTenantSchema(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithTenantSchema(regexp.MustCompile(`^tenant_(\d+)$`), "tenant"))
+	templates, tableInfos, _, _, err := e.Extract(
+		"SELECT * FROM tenant_123.orders o JOIN tenant_123.users u ON o.user_id = u.id JOIN db_1.t ON 1 = 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM tenant.orders AS o INNER JOIN tenant.users AS u ON o.user_id eq u.id INNER JOIN db_?.t ON ? eq ?"}, templates)
+	as.Equal([]*models.TableInfo{
+		tenantTable("tenant_123", "orders", "123"),
+		tenantTable("tenant_123", "users", "123"),
+		models.NewTableInfo("db_1", "t", "db_?", "t"),
+	}, tableInfos[0])
+
+	// 没有分组时租户为整个 schema
+	e = NewExtractor(WithTenantSchema(regexp.MustCompile(`^acme$`), "tenant"))
+	_, tableInfos, _, _, err = e.Extract("SELECT * FROM acme.orders")
+	as.Nil(err)
+	as.Equal(tenantTable("acme", "orders", "acme"), tableInfos[0][0])
+}
+
+func tenantTable(schema, table, tenant string) *models.TableInfo {
+	ti := models.NewTableInfo(schema, table, "tenant", table)
+	ti.SetTenant(tenant)
+
+	return ti
+}
+
+func TestExtractor_TemporaryTables(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	templates, tableInfos, params, ops, err := e.Extract("CREATE TEMPORARY TABLE tmp (id INT, name VARCHAR(10), PRIMARY KEY (id)); " +
+		"INSERT INTO tmp SELECT id, name FROM users WHERE age > 18; " +
+		"SELECT * FROM TMP JOIN orders ON orders.user_id = TMP.id; " +
+		"CREATE TEMPORARY TABLE IF NOT EXISTS tmp_2 LIKE users; " +
+		"DROP TEMPORARY TABLE IF EXISTS tmp; " +
+		"SELECT * FROM tmp")
+	as.Nil(err)
+	as.Equal([]string{
+		"CREATE TEMPORARY TABLE tmp (`id` INT, `name` VARCHAR(10), PRIMARY KEY(`id`))",
+		"INSERT INTO tmp SELECT id, name FROM users WHERE age gt ?",
+		"SELECT * FROM TMP INNER JOIN orders ON orders.user_id eq TMP.id",
+		"CREATE TEMPORARY TABLE IF NOT EXISTS tmp_? LIKE users",
+		"DROP TEMPORARY TABLE IF EXISTS tmp",
+		"SELECT * FROM tmp",
+	}, templates)
+	as.Equal([][]any{{}, {int64(18)}, {}, {}, {}, {}}, params)
+	as.Equal([]models.SQLOpType{
+		models.SQLOperationCreate, models.SQLOperationInsert, models.SQLOperationSelect,
+		models.SQLOperationCreate, models.SQLOperationDrop, models.SQLOperationSelect,
+	}, ops)
+
+	temporary := make([][]bool, len(tableInfos))
+	for idx := range tableInfos {
+		for _, ti := range tableInfos[idx] {
+			temporary[idx] = append(temporary[idx], ti.Temporary())
+		}
+	}
+	as.Equal([][]bool{{true}, {true, false}, {true, false}, {true, false}, {true}, {false}}, temporary)
+
+	// 非临时表的 DDL 不处理
+	templates, _, _, ops, err = e.Extract("CREATE TABLE t (id INT)")
+	as.Nil(err)
+	as.Equal([]string{""}, templates)
+	as.Equal([]models.SQLOpType{models.SQLOperationUnknown}, ops)
+}
+
+func TestExtractor_OnConflict(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithPostgres())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"INSERT INTO t (id, hits) VALUES (1, 1) ON CONFLICT (id) DO UPDATE SET hits = t.hits + EXCLUDED.hits, state = 'seen'",
+			"INSERT INTO t (id, hits) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET hits eq t.hits plus EXCLUDED.hits, state eq ?",
+			[]any{int64(1), int64(1), "seen"}},
+		{"insert into t (a, b) values (1, 2) on conflict do nothing",
+			"INSERT INTO t (a, b) VALUES (?, ?) ON CONFLICT DO NOTHING", []any{int64(1), int64(2)}},
+		{"INSERT INTO t (a, b) VALUES (1, 2) ON CONFLICT ON CONSTRAINT t_pkey DO NOTHING",
+			"INSERT INTO t (a, b) VALUES (?, ?) ON CONFLICT ON CONSTRAINT t_pkey DO NOTHING", []any{int64(1), int64(2)}},
+		{"INSERT INTO t (a, b) VALUES (1, 2) ON CONFLICT (a, lower(b)) WHERE deleted = 0 " +
+			"DO UPDATE SET b = EXCLUDED.b WHERE t.version < 3",
+			"INSERT INTO t (a, b) VALUES (?, ?) ON CONFLICT (a, lower(b)) WHERE deleted eq ? " +
+				"DO UPDATE SET b eq EXCLUDED.b WHERE t.version lt ?",
+			[]any{int64(1), int64(2), int64(0), int64(3)}},
+		{"INSERT INTO t (a) SELECT a FROM s WHERE a > 1 ON CONFLICT (a) DO UPDATE SET a = 'ON CONFLICT'",
+			"INSERT INTO t (a) SELECT a FROM s WHERE a gt ? ON CONFLICT (a) DO UPDATE SET a eq ?",
+			[]any{int64(1), "ON CONFLICT"}},
+	}
+	for _, tt := range tests {
+		templates, _, params, ops, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+		as.Equal([]models.SQLOpType{models.SQLOperationInsert}, ops, tt.sql)
+	}
+
+	// 冲突目标不是写入的列
+	columns, err := e.ExtractColumns("INSERT INTO t (a, b) VALUES (1, 2) ON CONFLICT (a) DO UPDATE SET b = EXCLUDED.b")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "t", "a", models.ColumnWrite),
+		models.NewColumnUsage("", "t", "b", models.ColumnWrite),
+	}, columns[0])
+
+	_, _, _, _, err = e.Extract("INSERT INTO t (a) VALUES (1) ON CONFLICT (a) DO")
+	as.NotNil(err)
+
+	// MySQL 不支持 ON CONFLICT
+	_, _, _, _, err = NewExtractor().Extract("INSERT INTO t (a) VALUES (1) ON CONFLICT (a) DO NOTHING")
+	as.NotNil(err)
+}
+
+func TestRewriteOnConflict(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.Equal("INSERT INTO t VALUES (1)", rewriteOnConflict("INSERT INTO t VALUES (1)"))
+	as.Equal("INSERT INTO t VALUES (1) ON DUPLICATE KEY UPDATE sqlextractor_conflict = sqlextractor_conflict(a); SELECT 1",
+		rewriteOnConflict("INSERT INTO t VALUES (1) ON CONFLICT (a) DO NOTHING; SELECT 1"))
+	as.Equal("INSERT INTO t VALUES (1) ON DUPLICATE KEY UPDATE sqlextractor_conflict = sqlextractor_conflict(a), "+
+		"sqlextractor_conflict_where = (b > 0), c = EXCLUDED.c, sqlextractor_update_where = (t.d IN (1, 2))",
+		rewriteOnConflict("INSERT INTO t VALUES (1) ON CONFLICT (a) WHERE b > 0 DO UPDATE SET c = EXCLUDED.c WHERE t.d IN (1, 2)"))
+	as.Equal("SELECT 'ON CONFLICT (a) DO NOTHING' /* ON CONFLICT DO NOTHING */",
+		rewriteOnConflict("SELECT 'ON CONFLICT (a) DO NOTHING' /* ON CONFLICT DO NOTHING */"))
+	as.Equal("INSERT INTO t VALUES (1) ON CONFLICT (a) DO", rewriteOnConflict("INSERT INTO t VALUES (1) ON CONFLICT (a) DO"))
+}
+
+func TestExtractor_Copy(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithPostgres())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+		op     models.SQLOpType
+		tables []*models.TableInfo
+	}{
+		{"COPY users (id, name) FROM STDIN WITH (FORMAT csv, HEADER true)",
+			"COPY users (id, name) FROM STDIN WITH (FORMAT csv, HEADER true)", []any{},
+			models.SQLOperationBulkLoad, []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}},
+		{"copy db_1.events_10 from '/data/events.csv' csv header",
+			"COPY db_?.events_? FROM ? csv header", []any{"/data/events.csv"},
+			models.SQLOperationBulkLoad, []*models.TableInfo{models.NewTableInfo("db_1", "events_10", "db_?", "events_?")}},
+		{"COPY (SELECT id FROM users WHERE age > 18) TO STDOUT WITH (FORMAT csv, DELIMITER ';')",
+			"COPY (SELECT id FROM users WHERE age gt ?) TO STDOUT WITH (FORMAT csv, DELIMITER ';')", []any{int64(18)},
+			models.SQLOperationBulkUnload, []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}},
+		{"COPY users TO PROGRAM 'gzip > /tmp/users.gz'",
+			"COPY users TO PROGRAM ?", []any{"gzip > /tmp/users.gz"},
+			models.SQLOperationBulkUnload, []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}},
+		{"\\copy users FROM 'users.csv' WITH CSV",
+			"\\copy users FROM ? WITH CSV", []any{"users.csv"},
+			models.SQLOperationBulkLoad, []*models.TableInfo{models.NewTableInfo("", "users", "", "users")}},
+	}
+	for _, tt := range tests {
+		templates, tableInfos, params, ops, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+		as.Equal([]models.SQLOpType{tt.op}, ops, tt.sql)
+		as.Equal([][]*models.TableInfo{tt.tables}, tableInfos, tt.sql)
+	}
+
+	// \copy 以行尾结束
+	templates, _, _, ops, err := e.Extract("\\copy t TO 't.csv' CSV\nSELECT * FROM t WHERE a = 1; COPY t FROM STDIN")
+	as.Nil(err)
+	as.Equal([]string{"\\copy t TO ? CSV", "SELECT * FROM t WHERE a eq ?", "COPY t FROM STDIN"}, templates)
+	as.Equal([]models.SQLOpType{
+		models.SQLOperationBulkUnload, models.SQLOperationSelect, models.SQLOperationBulkLoad,
+	}, ops)
+
+	columns, err := e.ExtractColumns("COPY users (id, name) FROM STDIN")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "users", "id", models.ColumnWrite),
+		models.NewColumnUsage("", "users", "name", models.ColumnWrite),
+	}, columns[0])
+
+	// 不在语句开头的 COPY 不改写
+	as.Equal("SELECT 'COPY t FROM STDIN'", rewriteCopy("SELECT 'COPY t FROM STDIN'"))
+	as.Equal("SELECT copy FROM t", rewriteCopy("SELECT copy FROM t"))
+	_, _, _, _, err = e.Extract("COPY users FROM")
+	as.NotNil(err)
+
+	// MySQL 不支持 COPY
+	_, _, _, _, err = NewExtractor().Extract("COPY users FROM STDIN")
+	as.NotNil(err)
+}
+
+func TestExtractor_Arrays(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithPostgres())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT * FROM t WHERE id = ANY($1)", "SELECT * FROM t WHERE id eq ANY($1)", []any{}},
+		{"SELECT * FROM t WHERE id = ANY(ARRAY[1, 2, 3]) AND state <> ALL ('{a,b}')",
+			"SELECT * FROM t WHERE id eq ANY(ARRAY[?, ?, ?]) and state ne ALL(?)", []any{int64(1), int64(2), int64(3), "{a,b}"}},
+		{"SELECT ARRAY[ARRAY[1, 2], ARRAY[a, 3]] FROM t", "SELECT ARRAY[ARRAY[?, ?], ARRAY[a, ?]] FROM t",
+			[]any{int64(1), int64(2), int64(3)}},
+		{"SELECT * FROM t WHERE tags @> ARRAY['go', 'sql'] OR tags <@ (ARRAY['x'])",
+			"SELECT * FROM t WHERE tags @> ARRAY[?, ?] or tags <@ (ARRAY[?])", []any{"go", "sql", "x"}},
+		{"SELECT * FROM t WHERE a + 1 @> b AND c = 2", "SELECT * FROM t WHERE a plus ? @> b and c eq ?", []any{int64(1), int64(2)}},
+		{"SELECT * FROM t WHERE id = ANY (SELECT id FROM s)",
+			"SELECT * FROM t WHERE id eq ANY((SELECT id FROM s))", []any{}},
+		{"SELECT '@> ARRAY[1]' FROM t", "SELECT ? FROM t", []any{"@> ARRAY[1]"}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// MySQL 中 a<@b 是与用户变量的比较
+	as.Equal("SELECT * FROM t WHERE a<@b", rewriteArrays("SELECT * FROM t WHERE a<@b"))
+
+	// 与 IN 列表一样折叠数组
+	e = NewExtractor(WithPostgres(), WithMaxParams(2, ParamsCollapse))
+	templates, _, params, _, err := e.Extract("SELECT * FROM t WHERE id = ANY(ARRAY[1, 2, 3]) AND b IN (4, 5, 6)")
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE id eq ANY(ARRAY[?]) and b IN (?)"}, templates)
+	as.Equal([][]any{{int64(1), int64(4)}}, params)
+
+	// 标记列不是使用的列
+	columns, err := e.ExtractColumns("SELECT * FROM t WHERE tags @> ARRAY['go']")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{models.NewColumnUsage("", "t", "tags", models.ColumnFilter)}, columns[0])
+}
+
+func TestExtractor_Pagination(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithSQLServer())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT TOP 10 * FROM t WHERE a = 1", "SELECT TOP (?) * FROM t WHERE a eq ?", []any{int64(10), int64(1)}},
+		{"SELECT DISTINCT TOP (5) PERCENT WITH TIES a, b FROM t ORDER BY a",
+			"SELECT DISTINCT TOP (?) PERCENT WITH TIES a, b FROM t ORDER BY a", []any{int64(5)}},
+		{"SELECT top FROM t", "SELECT top FROM t", []any{}},
+		{"SELECT a FROM t ORDER BY a DESC OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY",
+			"SELECT a FROM t ORDER BY a DESC OFFSET ? ROWS FETCH NEXT ? ROWS ONLY", []any{uint64(10), uint64(5)}},
+		{"SELECT a FROM t ORDER BY a OFFSET 10 ROWS", "SELECT a FROM t ORDER BY a OFFSET ? ROWS", []any{uint64(10)}},
+		{"SELECT a FROM t FETCH FIRST 3 ROWS ONLY", "SELECT a FROM t FETCH NEXT ? ROWS ONLY", []any{uint64(3)}},
+		{"SELECT TOP 1 (SELECT TOP 2 b FROM s ORDER BY b OFFSET 0 ROWS FETCH NEXT 1 ROW ONLY) FROM t",
+			"SELECT TOP (?) (SELECT TOP (?) b FROM s ORDER BY b OFFSET ? ROWS FETCH NEXT ? ROWS ONLY) FROM t",
+			[]any{int64(1), int64(2), uint64(0), uint64(1)}},
+		{"SELECT a FROM t LIMIT 5 OFFSET 10", "SELECT a FROM t LIMIT ?, ?", []any{uint64(10), uint64(5)}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// 标记列不是使用的列
+	columns, err := e.ExtractColumns("SELECT TOP 1 a FROM t ORDER BY b OFFSET 1 ROWS")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "t", "a", models.ColumnProjection),
+		models.NewColumnUsage("", "t", "b", models.ColumnOrder),
+	}, columns[0])
+
+	// MySQL 不支持 TOP 和 OFFSET FETCH，PostgreSQL 不支持 TOP
+	_, _, _, _, err = NewExtractor().Extract("SELECT TOP (10) a FROM t")
+	as.NotNil(err)
+	_, _, _, _, err = NewExtractor().Extract("SELECT a FROM t ORDER BY a OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY")
+	as.NotNil(err)
+	_, _, _, _, err = NewExtractor(WithPostgres()).Extract("SELECT TOP (10) a FROM t")
+	as.NotNil(err)
+	templates, _, _, _, err := NewExtractor(WithPostgres()).Extract("SELECT a FROM t ORDER BY a OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY")
+	as.Nil(err)
+	as.Equal([]string{"SELECT a FROM t ORDER BY a OFFSET ? ROWS FETCH NEXT ? ROWS ONLY"}, templates)
+}
+
+func TestExtractor_Oracle(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithOracle(), WithOptimizerHints())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT /*+ FULL(t) */ a FROM t WHERE ROWNUM <= 10", "SELECT /*+ FULL(t) */ a FROM t WHERE ROWNUM le ?", []any{int64(10)}},
+		{"/* app */ SELECT /*+ FIRST_ROWS(10) */ a FROM t WHERE id IN (SELECT /*+ NO_UNNEST */ id FROM s)",
+			"SELECT /*+ FIRST_ROWS(10) */ a FROM t WHERE id IN ((SELECT /*+ NO_UNNEST */ id FROM s))", []any{}},
+		{"INSERT /*+ APPEND */ INTO t (a) VALUES (1)", "INSERT /*+ APPEND */ INTO t (a) VALUES (?)", []any{int64(1)}},
+		{"UPDATE /*+ INDEX(t i) */ t SET a = 1", "UPDATE /*+ INDEX(t i) */ t SET a eq ?", []any{int64(1)}},
+		{"DELETE /*+ PARALLEL(t, 4) */ FROM t WHERE a = 1", "DELETE /*+ PARALLEL(t, 4) */ FROM t WHERE a eq ?", []any{int64(1)}},
+		{"SELECT a.x FROM a, b WHERE a.id = b.aid(+) AND b.kind (+) = 'x'",
+			"SELECT a.x FROM a CROSS JOIN b WHERE a.id eq b.aid(+) and b.kind(+) eq ?", []any{"x"}},
+		{"SELECT a FROM t WHERE b = (+1) AND c = '(+)'", "SELECT a FROM t WHERE b eq (plus ?) and c eq ?", []any{int64(1), "(+)"}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// ROWNUM 不是表的列，外连接的条件是连接列
+	sql := "SELECT a.x FROM a, b WHERE a.id = b.aid(+) AND a.y > 1 AND ROWNUM < 5"
+	columns, err := e.ExtractColumns(sql)
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "a", "x", models.ColumnProjection),
+		models.NewColumnUsage("", "a", "id", models.ColumnJoin),
+		models.NewColumnUsage("", "b", "aid", models.ColumnJoin),
+		models.NewColumnUsage("", "a", "y", models.ColumnFilter),
+	}, columns[0])
+
+	predicates, err := e.ExtractPredicates(sql)
+	as.Nil(err)
+	as.Len(predicates[0], 1)
+
+	// 默认不保留优化器提示
+	templates, _, _, _, err := NewExtractor().Extract("SELECT /*+ FULL(t) */ a FROM t")
+	as.Nil(err)
+	as.Equal([]string{"SELECT a FROM t"}, templates)
+
+	// MySQL 不支持 (+)
+	_, _, _, _, err = NewExtractor().Extract(sql)
+	as.NotNil(err)
+}
+
+func TestExtractor_Spark(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithSpark())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+		op     models.SQLOpType
+	}{
+		{"SELECT u.id, tag FROM `db`.`users` u LATERAL VIEW OUTER explode(u.tags) v AS tag WHERE u.age > 18",
+			"SELECT u.id, tag FROM db.users AS u LATERAL VIEW OUTER explode(u.tags) v AS tag WHERE u.age gt ?",
+			[]any{int64(18)}, models.SQLOperationSelect},
+		{"SELECT k, val FROM t LATERAL VIEW explode(map('x', 1)) m AS k, val LATERAL VIEW explode(c) c2 AS cc",
+			"SELECT k, val FROM t LATERAL VIEW explode(map(?, ?)) m AS k, val LATERAL VIEW explode(c) c2 AS cc",
+			[]any{"x", int64(1)}, models.SQLOperationSelect},
+		{"SELECT a, count(*) FROM t GROUP BY a DISTRIBUTE BY a SORT BY a DESC LIMIT 10",
+			"SELECT a, count(1) FROM t GROUP BY a DISTRIBUTE BY a SORT BY a DESC LIMIT ?", []any{uint64(10)}, models.SQLOperationSelect},
+		{"SELECT * FROM (SELECT a FROM t CLUSTER BY a) s", "SELECT * FROM (SELECT a FROM t CLUSTER BY a) AS s", []any{}, models.SQLOperationSelect},
+		{"INSERT OVERWRITE TABLE dw.daily PARTITION (dt = '2024-01-01', hr) SELECT a, hr FROM src WHERE x = 1",
+			"INSERT OVERWRITE TABLE dw.daily PARTITION (dt = ?, hr) SELECT a, hr FROM src WHERE x eq ?",
+			[]any{"2024-01-01", int64(1)}, models.SQLOperationInsert},
+		{"INSERT OVERWRITE t SELECT a FROM s", "INSERT OVERWRITE TABLE t SELECT a FROM s", []any{}, models.SQLOperationInsert},
+		{"INSERT OVERWRITE TABLE t PARTITION (dt = 'x') SELECT a, tag FROM s LATERAL VIEW explode(s.tags) v AS tag DISTRIBUTE BY tag",
+			"INSERT OVERWRITE TABLE t PARTITION (dt = ?) SELECT a, tag FROM s LATERAL VIEW explode(s.tags) v AS tag DISTRIBUTE BY tag",
+			[]any{"x"}, models.SQLOperationInsert},
+		{"INSERT INTO TABLE t PARTITION (dt='x') VALUES (1, 'a')", "INSERT INTO t PARTITION (dt = ?) VALUES (?, ?)",
+			[]any{"x", int64(1), "a"}, models.SQLOperationInsert},
+		{"SELECT sort FROM t WHERE cluster = 1", "SELECT sort FROM t WHERE cluster eq ?", []any{int64(1)}, models.SQLOperationSelect},
+	}
+	for _, tt := range tests {
+		templates, _, params, ops, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+		as.Equal([]models.SQLOpType{tt.op}, ops, tt.sql)
+	}
+
+	// 标记列不是使用的列，静态分区的列是写入的列
+	columns, err := e.ExtractColumns("SELECT a FROM t SORT BY b")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "t", "a", models.ColumnProjection),
+		models.NewColumnUsage("", "t", "b", models.ColumnOrder),
+	}, columns[0])
+
+	columns, err = e.ExtractColumns("INSERT OVERWRITE TABLE t PARTITION (dt = 'x') (a) VALUES (1)")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "t", "a", models.ColumnWrite),
+		models.NewColumnUsage("", "t", "dt", models.ColumnWrite),
+	}, columns[0])
+
+	// MySQL 不支持 CLUSTER BY
+	_, _, _, _, err = NewExtractor().Extract("SELECT a FROM t CLUSTER BY a")
+	as.NotNil(err)
+}
+
+func TestExtractor_Snowflake(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithSnowflake())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{`select src:customer.name::string as name, src:"Items"[0].price::number(10, 2) from raw.events where src:type = 'click'`,
+			`SELECT SRC:customer.name::STRING AS NAME, SRC:"Items"[0].price::NUMBER(10,2) FROM RAW.EVENTS WHERE SRC:type eq ?`,
+			[]any{"click"}},
+		{"SELECT '2024-01-01'::date, (a + 1)::int, parse_json(x):a::varchar FROM t",
+			"SELECT ?::DATE, (A plus ?)::INT, PARSE_JSON(X):a::VARCHAR FROM T", []any{"2024-01-01", int64(1)}},
+		{"SELECT * FROM users u SAMPLE BLOCK (10 ROWS) SEED (42) WHERE u.age > 18",
+			"SELECT * FROM USERS AS U TABLESAMPLE SYSTEM (? ROWS) REPEATABLE (?) WHERE U.AGE gt ?", []any{int64(10), int64(42), int64(18)}},
+		{"SELECT a FROM t TABLESAMPLE ROW (25)", "SELECT A FROM T TABLESAMPLE BERNOULLI (?)", []any{int64(25)}},
+		{"SELECT a FROM t QUALIFY ROW_NUMBER() OVER (PARTITION BY a ORDER BY b) = 1",
+			"SELECT A FROM T QUALIFY ROW_NUMBER() OVER (PARTITION BY A ORDER BY B) eq ?", []any{int64(1)}},
+		{`SELECT "Id", sample FROM sample WHERE "Name" = 'Mixed'`, "SELECT Id, SAMPLE FROM SAMPLE WHERE Name eq ?", []any{"Mixed"}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// 未加引号的标识符不区分大小写
+	digests := make(map[string]bool)
+	for _, sql := range []string{"SELECT id FROM users", "SELECT ID FROM Users", `SELECT "ID" FROM "USERS"`} {
+		templates, tables, _, _, err := e.Extract(sql)
+		as.Nil(err, sql)
+		as.Equal("USERS", tables[0][0].TableName(), sql)
+		digests[templates[0]] = true
+	}
+	as.Len(digests, 1)
+
+	// VARIANT 路径使用的是其所在的列
+	columns, err := e.ExtractColumns("SELECT src:a.b FROM t WHERE t.src:c = 1")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("", "T", "SRC", models.ColumnProjection),
+		models.NewColumnUsage("", "T", "SRC", models.ColumnFilter),
+	}, columns[0])
+}
+
+func TestExtractor_BigQuery(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithBigQuery())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{"SELECT x, off FROM `my-proj.analytics.events` AS e, UNNEST(e.tags) AS x WITH OFFSET AS off WHERE e.id = @id",
+			"SELECT x, off FROM my-proj.analytics.events AS e CROSS JOIN UNNEST(e.tags) AS x WITH OFFSET AS off WHERE e.id eq @id", []any{}},
+		{"SELECT * FROM my-proj.analytics.events e JOIN `ds`.users u ON e.uid = u.id WHERE u.country IN UNNEST(@countries)",
+			"SELECT * FROM my-proj.analytics.events AS e INNER JOIN ds.users AS u ON e.uid eq u.id WHERE u.country IN UNNEST(@countries)", []any{}},
+		{"SELECT STRUCT(1 AS a, 'x' AS b), STRUCT<a INT64, b ARRAY<STRING>>(2, ['p', 'q']) FROM `ds.t`",
+			"SELECT STRUCT(? AS a, ? AS b), STRUCT<a INT64, b ARRAY<STRING>>(?, ARRAY[?, ?]) FROM ds.t",
+			[]any{int64(1), "x", int64(2), "p", "q"}},
+		{"SELECT n FROM UNNEST([1, 2, 3]) AS n", "SELECT n FROM UNNEST(ARRAY[?, ?, ?]) AS n", []any{int64(1), int64(2), int64(3)}},
+		{"SELECT * EXCEPT (a) FROM `example.com:proj.ds.t` QUALIFY ROW_NUMBER() OVER (PARTITION BY b) = 1",
+			"SELECT * EXCEPT (a) FROM example.com:proj.ds.t QUALIFY ROW_NUMBER() OVER (PARTITION BY b) eq ?", []any{int64(1)}},
+		{"INSERT INTO p.d.t (a) VALUES (1)", "INSERT INTO p.d.t (a) VALUES (?)", []any{int64(1)}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// 项目名是表名的 catalog
+	_, tables, _, _, err := e.Extract("SELECT a FROM `example.com:proj.ds.t` JOIN ds.u USING (a)")
+	as.Nil(err)
+	as.Equal("example.com:proj", tables[0][0].Catalog())
+	as.Equal("ds", tables[0][0].Schema())
+	as.Equal("", tables[0][1].Catalog())
+	as.Equal("ds", tables[0][1].Schema())
+
+	// 变量不依赖 BigQuery 选项
+	templates, _, _, _, err := NewExtractor().Extract("SELECT @@global.max_connections, @a := 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT @@GLOBAL.max_connections, @a := ?"}, templates)
+}
+
+func TestExtractor_QualifiedNames(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithSQLServer())
+	tests := []struct {
+		sql    string
+		want   string
+		tables []models.QualifiedName
+	}{
+		{"SELECT a FROM srv.sales.dbo.orders o JOIN [sales].[dbo].[customers] c ON o.cid = c.id",
+			"SELECT a FROM srv.sales.dbo.orders AS o INNER JOIN sales.dbo.customers AS c ON o.cid eq c.id",
+			[]models.QualifiedName{{Server: "srv", Catalog: "sales", Schema: "dbo", Table: "orders"},
+				{Catalog: "sales", Schema: "dbo", Table: "customers"}}},
+		{"SELECT * FROM sales..orders, `db`.t", "SELECT * FROM sales..orders CROSS JOIN db.t",
+			[]models.QualifiedName{{Catalog: "sales", Table: "orders"}, {Schema: "db", Table: "t"}}},
+		{"INSERT INTO db.dbo.t (a) SELECT a FROM s", "INSERT INTO db.dbo.t (a) SELECT a FROM s",
+			[]models.QualifiedName{{Catalog: "db", Schema: "dbo", Table: "t"}, {Table: "s"}}},
+		{`DELETE FROM "db"."dbo"."t" WHERE a = 1`, "DELETE FROM db.dbo.t WHERE a eq ?",
+			[]models.QualifiedName{{Catalog: "db", Schema: "dbo", Table: "t"}}},
+		{"SELECT a FROM `my-proj.analytics.events`", "SELECT a FROM my-proj.analytics.events",
+			[]models.QualifiedName{{Table: "my-proj.analytics.events"}}},
+		// 函数参数中的 FROM 不是表关键字，子查询中的 FROM 是
+		{"SELECT EXTRACT(YEAR FROM db.t.created_at), TRIM(BOTH 'x' FROM a.b.c) FROM db.dbo.t WHERE id IN (SELECT id FROM db.dbo.s)",
+			"SELECT EXTRACT(INTERVAL ? YEAR, db.t.created_at), TRIM(a.b.c, ?, ) FROM db.dbo.t WHERE id IN ((SELECT id FROM db.dbo.s))",
+			[]models.QualifiedName{{Catalog: "db", Schema: "dbo", Table: "t"}, {Catalog: "db", Schema: "dbo", Table: "s"}}},
+	}
+	for _, tt := range tests {
+		templates, tableInfos, _, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+
+		names := make([]models.QualifiedName, len(tableInfos[0]))
+		for idx, ti := range tableInfos[0] {
+			names[idx] = ti.QualifiedName()
+		}
+		as.Equal(tt.tables, names, tt.sql)
+	}
+
+	// 默认不改写，db.t.created_at 是列，不是三段式的表名
+	sql := "SELECT EXTRACT(YEAR FROM db.t.created_at) FROM db.t"
+	templates, tableInfos, _, _, err := NewExtractor().Extract(sql)
+	as.Nil(err)
+	as.Equal([]string{"SELECT EXTRACT(INTERVAL ? YEAR, db.t.created_at) FROM db.t"}, templates)
+	as.Equal([][]*models.TableInfo{{models.NewTableInfo("db", "t", "db", "t")}}, tableInfos)
+
+	texts, err := NewExtractor().Split(sql)
+	as.Nil(err)
+	as.Equal([]string{sql}, texts)
+
+	_, _, _, _, err = NewExtractor().Extract("SELECT * FROM sales.dbo.orders")
+	as.NotNil(err)
+
+	// 列的 schema 带有 catalog
+	columns, err := e.ExtractColumns("SELECT o.a FROM sales.dbo.orders o WHERE o.b = 1")
+	as.Nil(err)
+	as.Equal([]*models.ColumnUsage{
+		models.NewColumnUsage("sales.dbo", "orders", "a", models.ColumnProjection),
+		models.NewColumnUsage("sales.dbo", "orders", "b", models.ColumnFilter),
+	}, columns[0])
+}
+
+func TestExtractor_PostgresStrings(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithPostgres())
+	tests := []struct {
+		sql    string
+		want   string
+		params []any
+	}{
+		{`SELECT * FROM t WHERE body = $$it's a \ test$$`, "SELECT * FROM t WHERE body eq ?", []any{`it's a \ test`}},
+		{"SELECT * FROM t WHERE body = $fn$ SELECT $$x$$; $fn$ AND id = $1",
+			"SELECT * FROM t WHERE body eq ? and id eq $1", []any{" SELECT $$x$$; "}},
+		{`INSERT INTO t (a, b) VALUES (E'it\'s\n', e'\x41\101\u00e9\q''')`,
+			"INSERT INTO t (a, b) VALUES (?, ?)", []any{"it's\n", "AAéq'"}},
+		{"SELECT * FROM t WHERE name = 'E''x' AND a$$b = '$$'", "SELECT * FROM t WHERE name eq ? and a$$b eq ?", []any{"E'x", "$$"}},
+		{"SELECT * FROM t WHERE tags = ANY(ARRAY[$$a$$, E'b'])", "SELECT * FROM t WHERE tags eq ANY(ARRAY[?, ?])", []any{"a", "b"}},
+	}
+	for _, tt := range tests {
+		templates, _, params, _, err := e.Extract(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]string{tt.want}, templates, tt.sql)
+		as.Equal([][]any{tt.params}, params, tt.sql)
+	}
+
+	// 未闭合的字面量保持原样，由解析器报错
+	as.Equal("SELECT $$x", rewritePostgresStrings("SELECT $$x"))
+
+	// MySQL 中 $ 是标识符字符，$tag$ 是列名
+	templates, _, params, _, err := NewExtractor().Extract("SELECT $tag$, a FROM t WHERE $tag$ = 1")
+	as.Nil(err)
+	as.Equal([]string{"SELECT $tag$, a FROM t WHERE $tag$ eq ?"}, templates)
+	as.Equal([][]any{{int64(1)}}, params)
+}
+
+func TestExtractor_ExtractOpSubTypes(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor(WithPostgres(), WithSpark())
+	tests := []struct {
+		sql  string
+		want models.SQLOpSubType
+	}{
+		{"INSERT INTO t (a) VALUES (1)", models.SQLSubTypeInsert},
+		{"INSERT IGNORE INTO t (a) VALUES (1), (2)", models.SQLSubTypeInsert},
+		{"INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = a + 1", models.SQLSubTypeUpsert},
+		{"INSERT INTO t (a) VALUES (1) ON CONFLICT (a) DO NOTHING", models.SQLSubTypeUpsert},
+		{"INSERT INTO t (a) SELECT a FROM s", models.SQLSubTypeInsertSelect},
+		{"REPLACE INTO t (a) VALUES (1)", models.SQLSubTypeReplace},
+		{"UPDATE t SET a = 1", models.SQLSubTypeNone},
+	}
+	for _, tt := range tests {
+		subTypes, err := e.ExtractOpSubTypes(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]models.SQLOpSubType{tt.want}, subTypes, tt.sql)
+	}
+
+	// INSERT OVERWRITE 改写使用 ON DUPLICATE KEY UPDATE 标记，不是 UPSERT
+	subTypes, err := e.ExtractOpSubTypes("INSERT OVERWRITE TABLE t PARTITION (dt = '2024-01-01') SELECT a FROM s")
+	as.Nil(err)
+	as.Equal([]models.SQLOpSubType{models.SQLSubTypeInsertSelect}, subTypes)
+}
+
+func TestExtractor_ExtractClauses(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT a FROM t WHERE id = 1 LIMIT 1", []string{"SELECT a", "FROM t", "WHERE id eq ?", "LIMIT ?"}},
+		{"SELECT * FROM (SELECT a FROM t WHERE b = 1 ORDER BY a) s ORDER BY a",
+			[]string{"SELECT *", "FROM (SELECT a FROM t WHERE b eq ? ORDER BY a) AS s", "ORDER BY a"}},
+		{"SELECT a FROM t UNION SELECT a FROM s", nil},
+		{"DELETE FROM t WHERE id = 1", nil},
+	}
+	for _, tt := range tests {
+		clauses, err := e.ExtractClauses(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Len(clauses, 1, tt.sql)
+
+		var texts []string
+		for _, seg := range clauses[0] {
+			texts = append(texts, seg.Text())
+		}
+		as.Equal(tt.want, texts, tt.sql)
+	}
+}
+
+func TestExtractor_ExtractConditions(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want *Condition
+	}{
+		{"UPDATE t SET a = 1 WHERE id BETWEEN 2 AND 3",
+			&Condition{Op: CondBetween, Column: "id", Params: []int{1, 2}}},
+		{"SELECT * FROM t WHERE u.id <=> 5 AND b NOT IN (1, 2)",
+			&Condition{Op: CondAnd, Children: []*Condition{
+				{Op: CondNullEQ, Column: "u.id", Params: []int{0}},
+				{Op: CondNotIn, Column: "b", Params: []int{1, 2}},
+			}}},
+		{"SELECT * FROM t WHERE a = b OR length(c) > 3",
+			&Condition{Op: CondOr, Children: []*Condition{
+				{Op: CondExpr, Expr: "a eq b"},
+				{Op: CondExpr, Expr: "length(c) gt ?", Params: []int{0}},
+			}}},
+		{"SELECT * FROM t WHERE a IN (SELECT a FROM s WHERE x = 1) AND b IS NOT NULL",
+			&Condition{Op: CondAnd, Children: []*Condition{
+				{Op: CondExpr, Expr: "a IN ((SELECT a FROM s WHERE x eq ?))", Params: []int{0}},
+				{Op: CondIsNotNull, Column: "b"},
+			}}},
+		{"SELECT * FROM t", nil},
+	}
+	for _, tt := range tests {
+		conditions, err := e.ExtractConditions(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([]*Condition{tt.want}, conditions, tt.sql)
+	}
+}
+
+func TestExtractor_ExtractTemporalParams(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	tests := []struct {
+		sql  string
+		want []TemporalParam
+	}{
+		{"SELECT * FROM t WHERE a = 1 AND b > DATE '2024-01-01' AND c < TIMESTAMP '2024-01-01 10:00:00'",
+			[]TemporalParam{{Index: 1, Kind: TemporalDate}, {Index: 2, Kind: TemporalTimestamp}}},
+		{"UPDATE t SET opens = TIME '09:00:00' WHERE id = 1", []TemporalParam{{Index: 0, Kind: TemporalTime}}},
+		{"SELECT * FROM t WHERE d > '2024-01-01'", nil},
+	}
+	for _, tt := range tests {
+		params, err := e.ExtractTemporalParams(tt.sql)
+		as.Nil(err, tt.sql)
+		as.Equal([][]TemporalParam{tt.want}, params, tt.sql)
+	}
+}
+
+func TestExtractor_ExtractListSizes(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor()
+	sizes, err := e.ExtractListSizes("SELECT * FROM t WHERE a IN (1, 2, 3) AND b NOT IN (4) AND c IN (SELECT c FROM s); " +
+		"INSERT INTO t (a) VALUES (1), (2); REPLACE INTO t (a) SELECT a FROM s WHERE a IN (1, 2); DELETE FROM t")
+	as.Nil(err)
+	as.Equal([]ListSizes{
+		{InLists: []int{3, 1}},
+		{Rows: 2},
+		{InLists: []int{2}},
+		{},
+	}, sizes)
+}
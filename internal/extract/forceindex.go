@@ -0,0 +1,115 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+)
+
+// ForceIndexRule forces the indexes of a table in the statements of a digest,
+// e.g. to pin the plan of a regressed query.
+type ForceIndexRule struct {
+	Digest  string   // digest of the statements, see Digests
+	Schema  string   // schema of the table, empty matches any schema
+	Table   string   // table name, case-insensitive
+	Indexes []string // index names, PRIMARY for the primary key
+}
+
+// ForceIndex returns the SQL with the index hints of the tables replaced with
+// FORCE INDEX (idx, ...), for the rules of the digests of its statements. The
+// rules are keyed by digest. Only the matching statements are restored, the
+// others keep their original text, and the SQL is returned as is if no rule
+// matches. Statements with LATERAL derived tables, which can not be restored,
+// are kept as is.
+//
+// Index hints are not part of the templates, so the digest of a rewritten
+// statement is unchanged and the rewrite is idempotent.
+func (e *Extractor) ForceIndex(sql string, rules map[string][]ForceIndexRule) (string, bool, error) {
+	if len(rules) == 0 {
+		return sql, false, nil
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return "", false, err
+	}
+
+	var (
+		texts   = make([]string, len(stmts))
+		changed bool
+		st      = newExtractState()
+	)
+
+	for idx := range stmts {
+		texts[idx] = strings.TrimRight(strings.TrimSpace(stmts[idx].Text()), "; \t\n")
+
+		digest, err := e.digestStmt(stmts[idx], st)
+		if err != nil {
+			return "", false, err
+		}
+
+		matched := rules[digest]
+		if len(matched) == 0 || containsKeyword(texts[idx], "LATERAL") {
+			continue
+		}
+
+		v := &forceIndexVisitor{rules: matched}
+		stmts[idx].Accept(v)
+		if !v.changed {
+			continue
+		}
+
+		var builder strings.Builder
+		err = stmts[idx].Restore(format.NewRestoreCtx(format.DefaultRestoreFlags|format.RestoreStringWithoutCharset, &builder))
+		if err != nil {
+			return "", false, err
+		}
+		texts[idx], changed = builder.String(), true
+	}
+
+	if !changed {
+		return sql, false, nil
+	}
+
+	return strings.Join(texts, "; "), true, nil
+}
+
+// forceIndexVisitor implements ast.Visitor, it replaces the index hints of the
+// tables of the rules.
+type forceIndexVisitor struct {
+	rules   []ForceIndexRule
+	changed bool
+}
+
+// Enter implement ast.Visitor interface.
+func (v *forceIndexVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	node, ok := n.(*ast.TableName)
+	if !ok {
+		return n, false
+	}
+
+	for idx := range v.rules {
+		rule := &v.rules[idx]
+		if !strings.EqualFold(rule.Table, node.Name.O) ||
+			(rule.Schema != "" && !strings.EqualFold(rule.Schema, schemaName(node))) {
+			continue
+		}
+
+		names := make([]ast.CIStr, len(rule.Indexes))
+		for jdx := range rule.Indexes {
+			names[jdx] = ast.NewCIStr(rule.Indexes[jdx])
+		}
+		node.IndexHints = []*ast.IndexHint{{IndexNames: names, HintType: ast.HintForce, HintScope: ast.HintForScan}}
+		v.changed = true
+
+		break
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *forceIndexVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,45 @@
+package extract
+
+import (
+	"reflect"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// NodeHandler handles an AST node during templatization. It reports whether
+// the node is handled; if not, the node falls back to the builtin handling.
+type NodeHandler func(v *ExtractVisitor, n ast.Node) bool
+
+// RegisterNodeHandler adds or overrides the handling of the AST node type of
+// nodeType, e.g. (*ast.RowExpr)(nil). It is not safe for concurrent use with
+// Extract, handlers should be registered before the Extractor is used.
+func (e *Extractor) RegisterNodeHandler(nodeType ast.Node, fn NodeHandler) {
+	if fn == nil {
+		delete(e.handlers, reflect.TypeOf(nodeType))
+		return
+	}
+
+	e.handlers[reflect.TypeOf(nodeType)] = fn
+}
+
+// handle runs the registered handler of the node, if any.
+func (v *ExtractVisitor) handle(n ast.Node) bool {
+	if len(v.handlers) == 0 {
+		return false
+	}
+
+	fn, ok := v.handlers[reflect.TypeOf(n)]
+
+	return ok && fn(v, n)
+}
+
+// WriteString writes s to the templatized SQL.
+func (v *ExtractVisitor) WriteString(s string) { v.builder.WriteString(s) }
+
+// WriteParam writes a placeholder to the templatized SQL and records val as
+// its parameter.
+func (v *ExtractVisitor) WriteParam(val any) { v.writeParam(val) }
+
+// Visit templatizes the node with the builtin and registered handlers, it is
+// used by handlers to templatize the children of their node.
+func (v *ExtractVisitor) Visit(n ast.Node) { n.Accept(v) }
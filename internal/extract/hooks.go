@@ -0,0 +1,130 @@
+package extract
+
+import (
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ParseInfo is the payload of the parse hooks.
+type ParseInfo struct {
+	Bytes    int           // byte size of the SQL string
+	Duration time.Duration // parse latency, zero in OnParseStart
+	Err      error         // parse error, nil in OnParseStart
+}
+
+// StatementInfo is the payload of the OnStatementDone hook.
+type StatementInfo struct {
+	Index     int              // 0-based index of the statement in the SQL string
+	Bytes     int              // byte size of the statement
+	NodeCount int              // number of AST nodes of the statement
+	OpType    models.SQLOpType // operation type of the statement
+	Duration  time.Duration    // templatize latency
+}
+
+// AnomalyKind is the kind of an anomaly of the OnAnomaly hook.
+type AnomalyKind string
+
+// Anomaly kinds.
+const (
+	AnomalyNewDigest AnomalyKind = "new_digest" // digest never seen before
+	AnomalySpike     AnomalyKind = "spike"      // sudden volume spike of a digest
+	AnomalyNewTable  AnomalyKind = "new_table"  // table never accessed before
+)
+
+// AnomalyInfo is the payload of the OnAnomaly hook.
+type AnomalyInfo struct {
+	Kind           AnomalyKind
+	Time           time.Time         // time of the statement
+	Digest         string            // digest of the statement
+	TemplatizedSQL string            // templatized SQL of the statement
+	Table          string            // qualified name of the new table, e.g. shop.users
+	Count          int64             // statements of the digest in the current window, spikes only
+	Baseline       float64           // average statements of the digest per window, spikes only
+	Tags           map[string]string // tags of the statement
+}
+
+// Hooks are callbacks invoked by Extract, so embedders can record latency and
+// error rates without wrapping the whole call. Nil callbacks are skipped, and
+// callbacks may be called concurrently when the Extractor is shared.
+//
+// OnAnomaly is invoked by the anomaly detector instead of Extract.
+type Hooks struct {
+	OnParseStart    func(info ParseInfo)
+	OnParseEnd      func(info ParseInfo)
+	OnStatementDone func(info StatementInfo)
+	OnError         func(err error)
+	OnAnomaly       func(info AnomalyInfo)
+}
+
+// WithHooks sets the hooks invoked by Extract.
+func WithHooks(hooks *Hooks) Option {
+	return func(e *Extractor) { e.hooks = hooks }
+}
+
+func (h *Hooks) parseStart(bytes int) {
+	if h != nil && h.OnParseStart != nil {
+		h.OnParseStart(ParseInfo{Bytes: bytes})
+	}
+}
+
+func (h *Hooks) parseEnd(bytes int, start time.Time, err error) {
+	if h != nil && h.OnParseEnd != nil {
+		h.OnParseEnd(ParseInfo{Bytes: bytes, Duration: time.Since(start), Err: err})
+	}
+}
+
+func (h *Hooks) statementDone(idx int, stmt ast.StmtNode, op models.SQLOpType, start time.Time) {
+	if h != nil && h.OnStatementDone != nil {
+		// 先计算耗时，避免计入 countNodes 额外的 AST 遍历
+		duration := time.Since(start)
+		h.OnStatementDone(StatementInfo{
+			Index:     idx,
+			Bytes:     len(stmt.Text()),
+			NodeCount: countNodes(stmt),
+			OpType:    op,
+			Duration:  duration,
+		})
+	}
+}
+
+func (h *Hooks) error(err error) {
+	if h != nil && h.OnError != nil {
+		h.OnError(err)
+	}
+}
+
+// now returns the current time, or the zero time when there are no hooks.
+func (h *Hooks) now() time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+// countNodes returns the number of AST nodes of the statement.
+func countNodes(stmt ast.StmtNode) int {
+	v := &nodeCounter{}
+	stmt.Accept(v)
+
+	return v.count
+}
+
+// nodeCounter implements ast.Visitor, it counts the AST nodes.
+type nodeCounter struct {
+	count int
+}
+
+// Enter implement ast.Visitor interface.
+func (v *nodeCounter) Enter(n ast.Node) (ast.Node, bool) {
+	v.count++
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *nodeCounter) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,154 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser"
+)
+
+// reservedWords are the reserved keywords, which must be quoted as identifiers.
+var reservedWords = func() map[string]struct{} {
+	words := make(map[string]struct{}, len(parser.Keywords))
+	for _, kw := range parser.Keywords {
+		if kw.Reserved {
+			words[strings.ToLower(kw.Word)] = struct{}{}
+		}
+	}
+
+	return words
+}()
+
+// postgresReservedWords are the reserved keywords of PostgreSQL, which must be
+// quoted as identifiers, e.g. user is not reserved in MySQL.
+var postgresReservedWords = func() map[string]struct{} {
+	words := make(map[string]struct{})
+	for _, word := range strings.Fields(`all analyse analyze and any array as asc asymmetric authorization binary both
+		case cast check collate collation column concurrently constraint create cross current_catalog current_date
+		current_role current_schema current_time current_timestamp current_user default deferrable desc distinct do
+		else end except false fetch for foreign freeze from full grant group having ilike in initially inner intersect
+		into is isnull join lateral leading left like limit localtime localtimestamp natural not notnull null offset on
+		only or order outer overlaps placing primary references returning right select session_user similar some
+		symmetric system_user table tablesample then to trailing true union unique user using variadic verbose when
+		where window with`) {
+		words[word] = struct{}{}
+	}
+
+	return words
+}()
+
+// WithFoldedIdentifiers lowercases the identifiers (schemas, tables, columns
+// and aliases) of the template, and only quotes those which need quoting, so
+// statements differing in identifier case or redundant quoting share the same
+// template, as MySQL with lower_case_table_names.
+//
+// e.g. SELECT * FROM `Users` -> SELECT * FROM users, SELECT `order` FROM t ->
+// SELECT `order` FROM t
+func WithFoldedIdentifiers() Option {
+	return func(e *Extractor) { e.foldIdents = true }
+}
+
+// IdentifierQuoting is the quoting of the identifiers of the templates.
+type IdentifierQuoting int
+
+const (
+	// QuoteDefault writes the identifiers unquoted, or quoted only when needed
+	// with WithFoldedIdentifiers.
+	QuoteDefault IdentifierQuoting = iota
+	// QuoteWhenNeeded quotes the reserved keywords and the names with special
+	// characters, so the templates are executable, e.g. SELECT `order` FROM t.
+	QuoteWhenNeeded
+	// QuoteAlways quotes all the identifiers with backticks, as the digest text
+	// of MySQL, e.g. SELECT `id` FROM `t`.
+	QuoteAlways
+	// QuotePostgres quotes with double quotes the identifiers PostgreSQL needs
+	// quoted: the reserved keywords of MySQL or PostgreSQL, the names with
+	// special characters and those with upper case letters, which PostgreSQL
+	// folds to lower case, e.g. SELECT "user", "userId" FROM t.
+	QuotePostgres
+)
+
+// WithIdentifierQuoting sets the quoting of the identifiers (schemas, tables,
+// columns and aliases) of the templates, QuoteDefault by default.
+func WithIdentifierQuoting(quoting IdentifierQuoting) Option {
+	return func(e *Extractor) { e.quoting = quoting }
+}
+
+// ident returns the identifier as written in the template.
+func (v *ExtractVisitor) ident(name string) string {
+	if !v.foldIdents && v.quoting == QuoteDefault {
+		if v.opName(name) {
+			return "`" + name + "`"
+		}
+		return name
+	}
+
+	lower := strings.ToLower(name)
+	if v.foldIdents {
+		name = lower
+	}
+	if v.quoting == QuotePostgres {
+		if _, ok := postgresReservedWords[lower]; ok || name != lower || needsQuote(lower) {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+
+		return name
+	}
+	if v.quoting == QuoteAlways || needsQuote(lower) || v.opName(name) {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+
+	return name
+}
+
+// opName reports whether the identifier is written as an operator name of
+// the template, e.g. a column named minus, which is quoted so the template
+// reads unambiguously, e.g. `minus` minus ?.
+func (v *ExtractVisitor) opName(name string) bool {
+	if v.standardOps {
+		return false
+	}
+	_, ok := templateOps[name]
+
+	return ok
+}
+
+// tableIdent returns the schema or table name as written in the template,
+// with sharded names templatized, e.g. tb_10 -> tb_?.
+func (v *ExtractVisitor) tableIdent(name string) string {
+	if v.quoting == QuoteAlways { // 先模板化再加引号，e.g. `tb_?`
+		return v.ident(v.templateTable(name))
+	}
+
+	return v.templateTable(v.ident(name))
+}
+
+// writeIdent writes the identifier to the template.
+func (v *ExtractVisitor) writeIdent(name string) {
+	v.builder.WriteString(v.ident(name))
+}
+
+// needsQuote reports whether the lowercase identifier must be quoted: reserved
+// keywords, names with characters other than [0-9a-z_$], and names made of
+// digits only.
+func needsQuote(name string) bool {
+	if name == "" {
+		return true
+	}
+	if _, ok := reservedWords[name]; ok {
+		return true
+	}
+
+	digits := true
+	for idx := range len(name) {
+		c := name[idx]
+		switch {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'z', c == '_', c == '$', c >= 0x80:
+			digits = false
+		default:
+			return true
+		}
+	}
+
+	return digits
+}
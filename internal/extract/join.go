@@ -0,0 +1,132 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ExtractJoins returns the join graph of each statement: the equality join
+// conditions of ON a.x = b.y and USING (x), resolved to their original tables.
+//
+// Unqualified columns of ON conditions are ignored, since they can not be
+// resolved. The columns of USING are joined between the last table of the left
+// operand and the first table of the right operand.
+func (e *Extractor) ExtractJoins(sql string) ([][]*models.JoinEdge, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	joins := make([][]*models.JoinEdge, 0, len(stmts))
+	for idx := range stmts {
+		v := &joinVisitor{tables: make(map[string]*models.TableInfo), edges: []*models.JoinEdge{}}
+		stmts[idx].Accept(v)
+		joins = append(joins, v.edges)
+	}
+
+	return joins, nil
+}
+
+// joinVisitor implements ast.Visitor, it collects the join conditions.
+type joinVisitor struct {
+	tables map[string]*models.TableInfo // lower case table name or alias -> table
+	edges  []*models.JoinEdge
+}
+
+// Enter implement ast.Visitor interface.
+func (v *joinVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	if node, ok := n.(*ast.TableSource); ok {
+		if tn, ok := node.Source.(*ast.TableName); ok {
+			ti := models.NewTableInfo(schemaName(tn), tn.Name.O)
+			v.tables[strings.ToLower(tn.Name.O)] = ti
+			if node.AsName.O != "" {
+				v.tables[strings.ToLower(node.AsName.O)] = ti
+			}
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *joinVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	// 子树的表都已记录后再解析连接条件
+	if node, ok := n.(*ast.Join); ok && node.Right != nil {
+		if node.On != nil {
+			v.addOn(node.On.Expr)
+		}
+		v.addUsing(node)
+	}
+
+	return n, true
+}
+
+// addOn records the `column = column` conditions of the AND operands.
+func (v *joinVisitor) addOn(expr ast.ExprNode) {
+	node, ok := expr.(*ast.BinaryOperationExpr)
+	if !ok {
+		return
+	}
+
+	switch node.Op {
+	case opcode.LogicAnd:
+		v.addOn(node.L)
+		v.addOn(node.R)
+
+	case opcode.EQ, opcode.NullEQ:
+		l, lok := node.L.(*ast.ColumnNameExpr)
+		r, rok := node.R.(*ast.ColumnNameExpr)
+		if !lok || !rok {
+			return
+		}
+
+		left := v.tables[strings.ToLower(l.Name.Table.O)]
+		right := v.tables[strings.ToLower(r.Name.Table.O)]
+		if left == nil || right == nil {
+			return
+		}
+
+		v.edges = append(v.edges, models.NewJoinEdge(left, l.Name.Name.O, right, r.Name.Name.O))
+	}
+}
+
+func (v *joinVisitor) addUsing(node *ast.Join) {
+	if len(node.Using) == 0 {
+		return
+	}
+
+	left, right := edgeTable(node.Left, true), edgeTable(node.Right, false)
+	if left == nil || right == nil {
+		return
+	}
+
+	for idx := range node.Using {
+		column := node.Using[idx].Name.O
+		v.edges = append(v.edges, models.NewJoinEdge(
+			models.NewTableInfo(schemaName(left), left.Name.O), column,
+			models.NewTableInfo(schemaName(right), right.Name.O), column))
+	}
+}
+
+// edgeTable returns the last (or first) table of a join operand, nil if it is
+// a derived table.
+func edgeTable(node ast.ResultSetNode, last bool) *ast.TableName {
+	switch node := node.(type) {
+	case *ast.TableSource:
+		tn, _ := node.Source.(*ast.TableName)
+		return tn
+
+	case *ast.Join:
+		if last && node.Right != nil {
+			return edgeTable(node.Right, last)
+		}
+
+		return edgeTable(node.Left, last)
+	}
+
+	return nil
+}
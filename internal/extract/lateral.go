@@ -0,0 +1,95 @@
+package extract
+
+import "strings"
+
+// LATERAL 派生表改写后的别名前缀，解析后由 visitor 还原
+const lateralMarker = "sqlextractor_lateral_"
+
+// rewriteLateral rewrites the LATERAL derived tables (MySQL 8.0.14+), which
+// the parser rejects, to derived tables with a marker alias:
+//
+//	LATERAL (SELECT ...) AS x   -> (SELECT ...) AS sqlextractor_lateral_x
+//
+// Quoted strings, identifiers and comments are kept as is. A LATERAL which can
+// not be rewritten, e.g. without alias, is kept, so the parser reports the
+// error.
+func rewriteLateral(sql string) string {
+	if !containsKeyword(sql, "LATERAL") {
+		return sql
+	}
+
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "LATERAL") {
+			continue
+		}
+
+		open := skipSpace(sql, i+len("LATERAL"))
+		if open >= len(sql) || sql[open] != '(' {
+			continue
+		}
+
+		closing := matchParen(sql, open)
+		if closing < 0 {
+			continue
+		}
+
+		// [AS] alias
+		alias := skipSpace(sql, closing+1)
+		if hasKeyword(sql[alias:], "AS") {
+			alias = skipSpace(sql, alias+len("AS"))
+		}
+		if alias >= len(sql) || (sql[alias] != '`' && !isIdentChar(sql[alias])) {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 32)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString(rewriteLateral(sql[open:alias])) // 派生表中嵌套的 LATERAL
+		if sql[alias] == '`' {
+			b.WriteByte('`')
+			alias++
+		}
+		b.WriteString(lateralMarker)
+		last, i = alias, alias-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// containsKeyword reports whether sql contains the upper case keyword,
+// case-insensitively.
+func containsKeyword(sql, keyword string) bool {
+	for i := 0; i+len(keyword) <= len(sql); i++ {
+		if sql[i]|0x20 == keyword[0]|0x20 && strings.EqualFold(sql[i:i+len(keyword)], keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lateralAlias returns the original alias of a LATERAL derived table, and
+// whether the alias is a marker alias.
+func lateralAlias(alias string) (string, bool) {
+	if len(alias) < len(lateralMarker) || !strings.EqualFold(alias[:len(lateralMarker)], lateralMarker) {
+		return alias, false
+	}
+
+	return alias[len(lateralMarker):], true
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// ListSizes is the sizes of the value lists of a statement.
+type ListSizes struct {
+	InLists []int // items of each IN list of values, in order of appearance
+	Rows    int   // rows of INSERT / REPLACE VALUES, 0 if none
+}
+
+// ExtractListSizes returns the sizes of the value lists of each statement: the
+// items of the IN lists, without those of subqueries, and the rows of INSERT
+// and REPLACE VALUES, which drive plan instability and packet sizes.
+//
+// e.g. SELECT * FROM t WHERE a IN (1, 2, 3) AND b IN (SELECT b FROM s) -> {[3] 0}
+func (e *Extractor) ExtractListSizes(sql string) ([]ListSizes, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make([]ListSizes, 0, len(stmts))
+	for idx := range stmts {
+		v := &listSizeVisitor{}
+		stmts[idx].Accept(v)
+		sizes = append(sizes, v.sizes)
+	}
+
+	return sizes, nil
+}
+
+// listSizeVisitor implements ast.Visitor, it collects the sizes of the value
+// lists.
+type listSizeVisitor struct {
+	sizes ListSizes
+}
+
+// Enter implement ast.Visitor interface.
+func (v *listSizeVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.PatternInExpr:
+		if node.Sel == nil {
+			v.sizes.InLists = append(v.sizes.InLists, len(node.List))
+		}
+
+	case *ast.InsertStmt:
+		v.sizes.Rows += len(node.Lists)
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *listSizeVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"unicode/utf8"
+
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+// TruncatedParam replaces a string parameter larger than the limit of
+// WithMaxParamBytes, or a blob-like literal skipped by WithSkipBlobParams, so
+// huge payloads do not bloat the results.
+type TruncatedParam struct {
+	Prefix    string `json:"prefix,omitempty"` // first bytes of the value, empty for skipped blobs
+	Size      int    `json:"size"`             // size of the value in bytes
+	Blob      bool   `json:"blob,omitempty"`   // skipped blob-like literal
+	Truncated bool   `json:"truncated"`        // always true, flags the parameter in JSON
+}
+
+// WithMaxParamBytes truncates the string parameters larger than n bytes to a
+// TruncatedParam of their first n bytes, cut at a UTF-8 boundary. n <= 0
+// means no limit, the default.
+func WithMaxParamBytes(n int) Option {
+	return func(e *Extractor) { e.maxParamBytes = max(n, 0) }
+}
+
+// WithSkipBlobParams replaces the blob-like literals of at least minBytes
+// bytes, e.g. hex literals, binary strings or base64 payloads, by a
+// TruncatedParam without their value. minBytes <= 0 disables it, the default.
+func WithSkipBlobParams(minBytes int) Option {
+	return func(e *Extractor) { e.blobParamBytes = max(minBytes, 0) }
+}
+
+// limitParam 按 maxParamBytes 和 blobParamBytes 限制字符串和二进制字面量，
+// 返回 false 时参数不受限制
+func (v *ExtractVisitor) limitParam(node *test_driver.ValueExpr) (any, bool) {
+	var (
+		s    string
+		blob bool
+	)
+
+	switch node.Kind() {
+	case test_driver.KindString:
+		s = node.GetString()
+	case test_driver.KindBytes:
+		s, blob = string(node.GetBytes()), true
+	case test_driver.KindBinaryLiteral, test_driver.KindMysqlBit:
+		s, blob = string(node.GetBinaryLiteral()), true
+	default:
+		return nil, false
+	}
+
+	if v.blobParamBytes > 0 && len(s) >= v.blobParamBytes && (blob || isBlobLike(s)) {
+		return TruncatedParam{Size: len(s), Blob: true, Truncated: true}, true
+	}
+
+	if v.maxParamBytes > 0 && len(s) > v.maxParamBytes && !blob {
+		return TruncatedParam{Prefix: truncateUTF8(s, v.maxParamBytes), Size: len(s), Truncated: true}, true
+	}
+
+	return nil, false
+}
+
+// isBlobLike 判断字符串是否像二进制数据：非 UTF-8、包含 NUL，或只由 base64 字符组成
+func isBlobLike(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+
+	base64 := true
+	for idx := 0; idx < len(s); idx++ {
+		c := s[idx]
+		if c == 0 {
+			return true
+		}
+
+		if !isBase64Char(c) {
+			base64 = false
+		}
+	}
+
+	return base64
+}
+
+// isBase64Char 判断是否为标准或 URL 安全 base64 字符
+func isBase64Char(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '+' || c == '/' || c == '-' || c == '_' || c == '='
+}
+
+// truncateUTF8 截断字符串到不超过 n 字节，不截断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
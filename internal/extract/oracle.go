@@ -0,0 +1,174 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// Oracle 外连接 (+) 改写后的函数名，解析后由 visitor 还原
+const outerJoinMarker = "sqlextractor_outer"
+
+// WithOracle supports the Oracle syntax the parser rejects: the outer join
+// operators (+), whose columns are reported as JOIN columns, and the OFFSET
+// FETCH clauses. The optimizer hints are kept by WithOptimizerHints.
+//
+// e.g. SELECT a.id FROM a, b WHERE a.id = b.aid(+) -> SELECT a.id FROM a CROSS JOIN b WHERE a.id eq b.aid(+)
+func WithOracle() Option {
+	return func(e *Extractor) { e.oracle = true }
+}
+
+// WithOptimizerHints keeps the optimizer hint comment following the leading
+// keyword of a statement, e.g. the Oracle hints, which the parser ignores
+// unless they are TiDB hints. The spaces of the hint are collapsed.
+//
+// e.g. SELECT /*+ INDEX(t idx_a) */ a FROM t WHERE ROWNUM <= 10 -> SELECT /*+ INDEX(t idx_a) */ a FROM t WHERE ROWNUM le ?
+func WithOptimizerHints() Option {
+	return func(e *Extractor) { e.optimizerHints = true }
+}
+
+// rewriteOuterJoins rewrites the Oracle outer join operators (+), which the
+// parser rejects, to marker functions of their columns:
+//
+//	WHERE a.id = b.aid(+) -> WHERE a.id = sqlextractor_outer(b.aid)
+//
+// Quoted strings, identifiers and comments are kept as is.
+func rewriteOuterJoins(sql string) string {
+	if !strings.Contains(sql, "+") {
+		return sql
+	}
+
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if sql[i] != '(' {
+			continue
+		}
+
+		// ( + )
+		j := skipSpace(sql, i+1)
+		if j >= len(sql) || sql[j] != '+' {
+			continue
+		}
+		if j = skipSpace(sql, j+1); j >= len(sql) || sql[j] != ')' {
+			continue
+		}
+
+		// 之前的列名，e.g. b.aid
+		end := i
+		for end > last && isSpace(sql[end-1]) {
+			end--
+		}
+		start := end
+		for start > last && (isIdentChar(sql[start-1]) || sql[start-1] == '.' || sql[start-1] == '`') {
+			start--
+		}
+		if start == end {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 32)
+		}
+		b.WriteString(sql[last:start])
+		b.WriteString(outerJoinMarker)
+		b.WriteByte('(')
+		b.WriteString(sql[start:end])
+		b.WriteByte(')')
+		last, i = j+1, j
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// isOuterJoin reports whether the comparison is an Oracle outer join
+// condition, one of its operands is a rewritten column(+).
+func isOuterJoin(node *ast.BinaryOperationExpr) bool {
+	for _, operand := range []ast.ExprNode{node.L, node.R} {
+		if fn, ok := operand.(*ast.FuncCallExpr); ok && fn.FnName.L == outerJoinMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPseudoColumn reports whether the column is the Oracle ROWNUM pseudo
+// column, which is not a column of the tables.
+func isPseudoColumn(name *ast.ColumnName) bool {
+	return name.Table.L == "" && name.Name.L == "rownum"
+}
+
+// leadingHint returns the optimizer hint comment following the leading keyword
+// of the statement text, e.g. /*+ INDEX(t idx_a) */, empty if there is none.
+// Leading comments and parentheses of subqueries are skipped.
+func leadingHint(text, keyword string) string {
+	i := skipSpace(text, 0)
+	for i < len(text) && !strings.HasPrefix(text[i:], "/*+") {
+		if text[i] == '(' {
+			i = skipSpace(text, i+1)
+			continue
+		}
+
+		j, ok := skipQuotedOrComment(text, i)
+		if !ok || text[i] == '\'' || text[i] == '"' || text[i] == '`' {
+			break
+		}
+		i = skipSpace(text, min(j+1, len(text)))
+	}
+
+	if !hasKeyword(text[i:], keyword) {
+		return ""
+	}
+
+	i = skipSpace(text, i+len(keyword))
+	if !strings.HasPrefix(text[i:], "/*+") {
+		return ""
+	}
+	end := strings.Index(text[i+3:], "*/")
+	if end < 0 {
+		return ""
+	}
+
+	return "/*+ " + strings.Join(strings.Fields(text[i+3:i+3+end]), " ") + " */"
+}
+
+// writeHint 写入语句开头关键字之后的优化器提示，未启用时不写入
+//
+// e.g. SELECT /*+ FULL(t) */ a FROM t -> SELECT /*+ FULL(t) */ a FROM t
+func (v *ExtractVisitor) writeHint(node ast.Node, keyword string) {
+	if !v.optimizerHints {
+		return
+	}
+
+	if hint := leadingHint(node.Text(), keyword); hint != "" {
+		v.builder.WriteString(hint)
+		v.builder.WriteString(" ")
+	}
+}
+
+// writeOuterJoin 写入 Oracle 外连接的列，不是标记函数时返回 false
+//
+// e.g. a.id = b.aid(+) -> a.id eq b.aid(+)
+func (v *ExtractVisitor) writeOuterJoin(node *ast.FuncCallExpr) bool {
+	if node.FnName.L != outerJoinMarker || len(node.Args) != 1 {
+		return false
+	}
+
+	node.Args[0].Accept(v)
+	v.builder.WriteString("(+)")
+
+	return true
+}
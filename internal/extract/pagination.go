@@ -0,0 +1,366 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// TOP 和 OFFSET FETCH 改写后的函数名和标记列名，解析后由 visitor 还原
+const (
+	topMarker    = "sqlextractor_top"
+	fetchMarker  = "sqlextractor_fetch"
+	offsetMarker = "sqlextractor_offset"
+)
+
+// maxLimit is the row count of a rewritten OFFSET clause without FETCH, MySQL
+// requires a count after an offset.
+const maxLimit = "18446744073709551615"
+
+// topTerminators are the keywords ending the select list of a TOP clause.
+var topTerminators = []string{
+	"FROM", "INTO", "WHERE", "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "UNION", "EXCEPT", "INTERSECT", "FOR", "LOCK",
+}
+
+// WithSQLServer supports the SQL Server pagination clauses the parser rejects:
+// TOP (n) [PERCENT] [WITH TIES] and OFFSET n ROWS FETCH NEXT m ROWS ONLY, whose
+// counts are parameterized as those of LIMIT. FETCH FIRST and ROW are
+// rendered as FETCH NEXT ... ROWS ONLY. The three-part and four-part table
+// names are supported too, see rewriteQualifiedNames.
+//
+// e.g. SELECT TOP (10) name FROM users -> SELECT TOP (?) name FROM users
+func WithSQLServer() Option {
+	return func(e *Extractor) { e.sqlserver = true }
+}
+
+// rewritePagination rewrites the OFFSET FETCH clauses of SQL Server,
+// PostgreSQL and Oracle, and the SQL Server TOP clauses if top, which the
+// parser rejects. A TOP clause becomes a marker field appended to the select list, an OFFSET
+// FETCH clause becomes a LIMIT clause led by a marker ORDER BY item:
+//
+//	SELECT TOP (10) PERCENT a FROM t      -> SELECT a , sqlextractor_top(10, 'PERCENT') FROM t
+//	ORDER BY a OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY -> ORDER BY a , sqlextractor_fetch LIMIT 5, 10
+//	OFFSET 5 ROWS                         -> ORDER BY sqlextractor_offset LIMIT 5, 18446744073709551615
+//
+// The counts must be integers or ? placeholders. Quoted strings, identifiers
+// and comments are kept as is. A clause which can not be rewritten is kept, so
+// the parser reports the error.
+func rewritePagination(sql string, top bool) string {
+	if top && containsKeyword(sql, "TOP") {
+		for {
+			rewritten, ok := rewriteFirstTop(sql)
+			if !ok {
+				break
+			}
+			sql = rewritten
+		}
+	}
+	if containsKeyword(sql, "OFFSET") || containsKeyword(sql, "FETCH") {
+		sql = rewriteFetch(sql)
+	}
+
+	return sql
+}
+
+// rewriteFirstTop rewrites the first TOP clause of sql.
+func rewriteFirstTop(sql string) (string, bool) {
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "SELECT") {
+			continue
+		}
+
+		// SELECT [DISTINCT | ALL] TOP
+		start := skipSpace(sql, i+len("SELECT"))
+		for _, keyword := range []string{"DISTINCT", "ALL"} {
+			if hasKeyword(sql[start:], keyword) {
+				start = skipSpace(sql, start+len(keyword))
+			}
+		}
+		if !hasKeyword(sql[start:], "TOP") {
+			continue
+		}
+
+		// TOP (expr) | TOP n
+		j := skipSpace(sql, start+len("TOP"))
+		var count string
+		if j < len(sql) && sql[j] == '(' {
+			closing := matchParen(sql, j)
+			if closing < 0 {
+				continue
+			}
+			count = strings.TrimSpace(sql[j+1 : closing])
+			j = closing + 1
+		} else {
+			count, j = paginationCount(sql, j)
+		}
+		if count == "" {
+			continue
+		}
+
+		// [PERCENT] [WITH TIES]
+		var options []string
+		j = skipSpace(sql, j)
+		if hasKeyword(sql[j:], "PERCENT") {
+			options = append(options, "PERCENT")
+			j = skipSpace(sql, j+len("PERCENT"))
+		}
+		if hasKeyword(sql[j:], "WITH") {
+			k := skipSpace(sql, j+len("WITH"))
+			if !hasKeyword(sql[k:], "TIES") {
+				continue
+			}
+			options = append(options, "WITH TIES")
+			j = skipSpace(sql, k+len("TIES"))
+		}
+
+		// 之后不是 SELECT 列表时，TOP 是列名或函数名，e.g. SELECT top FROM t
+		if j >= len(sql) || strings.IndexByte(",);", sql[j]) >= 0 ||
+			hasKeyword(sql[j:], "FROM") || hasKeyword(sql[j:], "AS") {
+			continue
+		}
+
+		end := clauseEnd(sql, j, topTerminators)
+
+		var b strings.Builder
+		b.Grow(len(sql) + len(topMarker) + 16)
+		b.WriteString(sql[:start])
+		b.WriteString(sql[j:end])
+		b.WriteString(", ")
+		b.WriteString(topMarker)
+		b.WriteByte('(')
+		b.WriteString(count)
+		b.WriteString(", '")
+		b.WriteString(strings.Join(options, " "))
+		b.WriteString("') ")
+		b.WriteString(sql[end:])
+
+		return b.String(), true
+	}
+
+	return sql, false
+}
+
+// rewriteFetch rewrites the OFFSET FETCH clauses of sql.
+func rewriteFetch(sql string) string {
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+
+		// 每层括号中最近的 SELECT 之后是否有 ORDER BY
+		ordered = []bool{false}
+		depth   int
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+
+		switch c := sql[i]; {
+		case c == '(':
+			depth++
+			ordered = append(ordered[:depth], false)
+			continue
+		case c == ')':
+			depth = max(depth-1, 0)
+			continue
+		case !isIdentChar(c) || (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')):
+			continue
+		}
+
+		switch {
+		case hasKeyword(sql[i:], "SELECT"):
+			ordered[depth] = false
+		case hasKeyword(sql[i:], "ORDER"):
+			if hasKeyword(sql[skipSpace(sql, i+len("ORDER")):], "BY") {
+				ordered[depth] = true
+			}
+		case hasKeyword(sql[i:], "OFFSET"), hasKeyword(sql[i:], "FETCH"):
+			clause, end, ok := fetchClause(sql, i, ordered[depth])
+			if !ok {
+				continue
+			}
+
+			if b.Len() == 0 {
+				b.Grow(len(sql) + 64)
+			}
+			b.WriteString(sql[last:i])
+			b.WriteString(clause)
+			last, i = end, end-1
+		}
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// fetchClause returns the LIMIT clause of the OFFSET FETCH clause starting at
+// sql[i], led by the marker ORDER BY item, and the end of the clause.
+func fetchClause(sql string, i int, ordered bool) (string, int, bool) {
+	var offset, count string
+
+	// OFFSET n {ROW | ROWS}
+	if hasKeyword(sql[i:], "OFFSET") {
+		offset, i = paginationCount(sql, skipSpace(sql, i+len("OFFSET")))
+		if offset == "" {
+			return "", 0, false
+		}
+
+		i = skipSpace(sql, i)
+		if i = skipRows(sql, i); i < 0 {
+			return "", 0, false
+		}
+	}
+
+	// FETCH {FIRST | NEXT} n {ROW | ROWS} ONLY
+	if j := skipSpace(sql, i); hasKeyword(sql[j:], "FETCH") {
+		j = skipSpace(sql, j+len("FETCH"))
+		switch {
+		case hasKeyword(sql[j:], "FIRST"):
+			j += len("FIRST")
+		case hasKeyword(sql[j:], "NEXT"):
+			j += len("NEXT")
+		default:
+			return "", 0, false
+		}
+
+		count, j = paginationCount(sql, skipSpace(sql, j))
+		if count == "" {
+			return "", 0, false
+		}
+		if j = skipRows(sql, skipSpace(sql, j)); j < 0 {
+			return "", 0, false
+		}
+		if j = skipSpace(sql, j); !hasKeyword(sql[j:], "ONLY") {
+			return "", 0, false
+		}
+		i = j + len("ONLY")
+	}
+
+	marker := fetchMarker
+	if count == "" {
+		marker, count = offsetMarker, maxLimit
+	}
+
+	var b strings.Builder
+	if ordered {
+		b.WriteString(", ")
+	} else {
+		b.WriteString("ORDER BY ")
+	}
+	b.WriteString(marker)
+	b.WriteString(" LIMIT ")
+	if offset != "" {
+		b.WriteString(offset)
+		b.WriteString(", ")
+	}
+	b.WriteString(count)
+
+	return b.String(), i, true
+}
+
+// paginationCount returns the integer or ? placeholder starting at sql[i], and
+// its end, empty if there is none.
+func paginationCount(sql string, i int) (string, int) {
+	if i < len(sql) && sql[i] == '?' {
+		return "?", i + 1
+	}
+
+	j := i
+	for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+		j++
+	}
+	if j == i || (j < len(sql) && isIdentChar(sql[j])) {
+		return "", i
+	}
+
+	return sql[i:j], j
+}
+
+// skipRows returns the end of the ROW or ROWS keyword at sql[i], or -1.
+func skipRows(sql string, i int) int {
+	switch {
+	case hasKeyword(sql[i:], "ROWS"):
+		return i + len("ROWS")
+	case hasKeyword(sql[i:], "ROW"):
+		return i + len("ROW")
+	}
+
+	return -1
+}
+
+// topField returns the marker function of the last select field, nil if the
+// select statement has no TOP clause.
+func topField(fields []*ast.SelectField) *ast.FuncCallExpr {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	fn, ok := fields[len(fields)-1].Expr.(*ast.FuncCallExpr)
+	if !ok || fn.FnName.L != topMarker || len(fn.Args) != 2 {
+		return nil
+	}
+
+	return fn
+}
+
+// fetchItem returns the marker of the last ORDER BY item, empty if the
+// statement has no OFFSET FETCH clause.
+func fetchItem(orderBy *ast.OrderByClause) string {
+	if orderBy == nil || len(orderBy.Items) == 0 {
+		return ""
+	}
+
+	col, ok := orderBy.Items[len(orderBy.Items)-1].Expr.(*ast.ColumnNameExpr)
+	if !ok || !isFetchMarker(col.Name) {
+		return ""
+	}
+
+	return col.Name.Name.L
+}
+
+// isFetchMarker reports whether the column is a marker of a rewritten OFFSET
+// FETCH clause.
+func isFetchMarker(name *ast.ColumnName) bool {
+	return name.Table.L == "" && (name.Name.L == fetchMarker || name.Name.L == offsetMarker)
+}
+
+// writeTop 写入 TOP 子句，行数与 LIMIT 一样参数化
+//
+// e.g. SELECT TOP (10) WITH TIES a FROM t -> SELECT TOP (?) WITH TIES a FROM t
+func (v *ExtractVisitor) writeTop(fn *ast.FuncCallExpr) {
+	v.builder.WriteString("TOP (")
+	v.withParamColumn("limit", func() { fn.Args[0].Accept(v) })
+	v.builder.WriteString(") ")
+
+	if options := copyArg(fn, 1); options != "" {
+		v.builder.WriteString(options)
+		v.builder.WriteString(" ")
+	}
+}
+
+// writeFetch 写入 OFFSET FETCH 子句，偏移量和行数与 LIMIT 一样参数化
+//
+// e.g. ORDER BY a OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY -> ORDER BY a OFFSET ? ROWS FETCH NEXT ? ROWS ONLY
+func (v *ExtractVisitor) writeFetch(node *ast.Limit, marker string) {
+	if node.Offset != nil {
+		v.builder.WriteString(" OFFSET ")
+		v.withParamColumn("offset", func() { node.Offset.Accept(v) })
+		v.builder.WriteString(" ROWS")
+	}
+
+	if marker == fetchMarker {
+		v.builder.WriteString(" FETCH NEXT ")
+		v.withParamColumn("limit", func() { node.Count.Accept(v) })
+		v.builder.WriteString(" ROWS ONLY")
+	}
+}
@@ -0,0 +1,96 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooManyParams is returned when a statement has more parameters than the
+// limit of WithMaxParams, with the ParamsError policy.
+var ErrTooManyParams = errors.New("too many parameters")
+
+// ParamsOverflow is the policy applied when a statement has more parameters
+// than the limit of WithMaxParams.
+type ParamsOverflow int
+
+const (
+	// ParamsTruncate keeps the first parameters up to the limit, the template
+	// is unchanged, and a warning is reported.
+	ParamsTruncate ParamsOverflow = iota
+
+	// ParamsCollapse templatizes the statement again with IN lists collapsed
+	// to their first item and INSERT VALUES to their first row, e.g.
+	// a IN (?) instead of a IN (?, ?, ...). The parameters of the collapsed
+	// statement are truncated if they still exceed the limit.
+	ParamsCollapse
+
+	// ParamsError fails the statement with ErrTooManyParams.
+	ParamsError
+)
+
+// String returns the name of the policy.
+func (p ParamsOverflow) String() string {
+	switch p {
+	case ParamsTruncate:
+		return "truncate"
+	case ParamsCollapse:
+		return "collapse"
+	case ParamsError:
+		return "error"
+	}
+
+	return fmt.Sprintf("ParamsOverflow(%d)", int(p))
+}
+
+// WithMaxParams limits the parameters collected per statement to n, so that
+// statements with tens of thousands of literals (e.g. giant IN lists or bulk
+// INSERTs) do not grow the memory unbounded. The policy decides what happens
+// to the statements over the limit. n <= 0 means no limit, the default.
+func WithMaxParams(n int, policy ParamsOverflow) Option {
+	return func(e *Extractor) {
+		e.maxParams = max(n, 0)
+		e.overflow = policy
+	}
+}
+
+// extractState is the state shared by the statements of an input.
+type extractState struct {
+	// PREPARE 语句的模板化结果，供同一输入中后续的 EXECUTE 关联
+	prepared map[string]*preparedStmt
+
+	// 上一条语句被截断前的参数个数，为 0 时未截断
+	truncated int
+
+	// 同一输入中已创建且未删除的临时表，key 为小写的 schema.table
+	temporary map[string]struct{}
+}
+
+func newExtractState() *extractState {
+	return &extractState{
+		prepared:  make(map[string]*preparedStmt),
+		temporary: make(map[string]struct{}),
+	}
+}
+
+// overflowed reports whether the statement has more parameters than the limit.
+func (v *ExtractVisitor) overflowed() bool {
+	return v.maxParams > 0 && v.nparams > v.maxParams
+}
+
+// checkOverflow applies the policy to a statement of n parameters, n is 0 if
+// the statement did not overflow.
+func (e *Extractor) checkOverflow(n int, st *extractState) error {
+	if n == 0 {
+		return nil
+	}
+
+	if e.overflow == ParamsError {
+		return fmt.Errorf("%w: %d > %d", ErrTooManyParams, n, e.maxParams)
+	}
+
+	if st != nil {
+		st.truncated = n
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// rewritePostgresStrings rewrites the PostgreSQL dollar-quoted strings and
+// escape strings, which the parser rejects or reads differently, to MySQL
+// string literals of the same value, so they are parameterized as any string:
+//
+//	$$it's$$, $body$...$body$ -> 'it''s', '...'
+//	E'a\tb\x41'               -> 'a<TAB>bA'
+//
+// A dollar quote tag does not start with a digit, so $1 placeholders are kept.
+// Quoted strings, identifiers and comments are kept as is. It runs before the
+// other rewrites, which then skip the bodies as quoted strings.
+func rewritePostgresStrings(sql string) string {
+	if !strings.Contains(sql, "$") && !strings.Contains(sql, "E'") && !strings.Contains(sql, "e'") {
+		return sql
+	}
+
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		if i > 0 && isIdentChar(sql[i-1]) {
+			if j, ok := skipQuotedOrComment(sql, i); ok {
+				i = j
+			}
+			continue
+		}
+
+		var (
+			value string
+			end   int // 字面量之后的位置
+			ok    bool
+		)
+		switch {
+		case c == '$':
+			value, end, ok = dollarQuoted(sql, i)
+		case (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'':
+			value, end, ok = escapeString(sql, i+1)
+		default:
+			if j, quoted := skipQuotedOrComment(sql, i); quoted {
+				i = j
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 16)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString(mysqlString(value))
+		last, i = end, end-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// dollarQuoted returns the body of the dollar-quoted string starting at
+// sql[i], e.g. $$...$$ or $tag$...$tag$, and the end of the string.
+func dollarQuoted(sql string, i int) (string, int, bool) {
+	j := i + 1
+	for j < len(sql) && sql[j] != '$' && isIdentChar(sql[j]) {
+		j++
+	}
+	if j >= len(sql) || sql[j] != '$' || (j > i+1 && sql[i+1] >= '0' && sql[i+1] <= '9') {
+		return "", 0, false
+	}
+
+	tag := sql[i : j+1]
+	closing := strings.Index(sql[j+1:], tag)
+	if closing < 0 {
+		return "", 0, false
+	}
+
+	return sql[j+1 : j+1+closing], j + 1 + closing + len(tag), true
+}
+
+// escapeString returns the value of the escape string whose opening quote is
+// sql[i], e.g. E'a\nb', and the end of the string.
+func escapeString(sql string, i int) (string, int, bool) {
+	closing := skipQuoted(sql, i)
+	if closing >= len(sql) {
+		return "", 0, false
+	}
+
+	var (
+		body = sql[i+1 : closing]
+		b    strings.Builder
+	)
+	b.Grow(len(body))
+	for k := 0; k < len(body); k++ {
+		switch c := body[k]; {
+		case c == '\'': // ''
+			b.WriteByte('\'')
+			k++
+		case c != '\\' || k+1 == len(body):
+			b.WriteByte(c)
+		default:
+			k++
+			k += writeEscape(&b, body[k:]) - 1
+		}
+	}
+
+	return b.String(), closing + 1, true
+}
+
+// writeEscape writes the character of the backslash escape sequence s, without
+// the backslash, and returns the length of the sequence.
+func writeEscape(b *strings.Builder, s string) int {
+	switch s[0] {
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'x': // \xh, \xhh
+		if n := hexDigits(s[1:], 2); n > 0 {
+			v, _ := strconv.ParseUint(s[1:1+n], 16, 8)
+			b.WriteByte(byte(v))
+			return 1 + n
+		}
+		b.WriteByte('x')
+	case 'u', 'U': // \uXXXX, \UXXXXXXXX
+		size := 4
+		if s[0] == 'U' {
+			size = 8
+		}
+		if hexDigits(s[1:], size) == size {
+			v, _ := strconv.ParseUint(s[1:1+size], 16, 32)
+			b.WriteRune(rune(v))
+			return 1 + size
+		}
+		b.WriteByte(s[0])
+	default:
+		if n := octalDigits(s, 3); n > 0 { // \o, \oo, \ooo
+			v, _ := strconv.ParseUint(s[:n], 8, 16)
+			b.WriteByte(byte(v))
+			return n
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		b.WriteRune(r)
+		return size
+	}
+
+	return 1
+}
+
+// hexDigits returns the number of leading hex digits of s, at most n.
+func hexDigits(s string, n int) int {
+	k := 0
+	for k < min(n, len(s)) && strings.IndexByte("0123456789abcdefABCDEF", s[k]) >= 0 {
+		k++
+	}
+
+	return k
+}
+
+// octalDigits returns the number of leading octal digits of s, at most n.
+func octalDigits(s string, n int) int {
+	k := 0
+	for k < min(n, len(s)) && s[k] >= '0' && s[k] <= '7' {
+		k++
+	}
+
+	return k
+}
+
+// mysqlString returns the MySQL string literal of the value.
+func mysqlString(value string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", "''") + "'"
+}
@@ -0,0 +1,12 @@
+package extract
+
+// WithPostgres supports the PostgreSQL syntax the parser rejects or reads
+// differently: INSERT ... ON CONFLICT, COPY and the psql \copy meta-commands,
+// the array syntax, the dollar-quoted strings $$...$$ and the escape strings
+// E'...', and the OFFSET FETCH clauses. $ is an identifier character in MySQL,
+// so the dollar-quoted strings are only rewritten with this option.
+//
+// e.g. SELECT * FROM t WHERE id = ANY(ARRAY[1, 2]) AND s = $$it's$$ -> SELECT * FROM t WHERE id eq ANY(ARRAY[?, ?]) and s eq ?
+func WithPostgres() Option {
+	return func(e *Extractor) { e.postgres = true }
+}
@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+)
+
+// 运算符优先级，数值越大结合越紧，同 MySQL 的运算符优先级
+const (
+	precOr      = iota + 1 // OR, ||
+	precXor                // XOR
+	precAnd                // AND, &&
+	precNot                // NOT
+	precBetween            // BETWEEN
+	precCompare            // =, <=>, >=, >, <=, <, <>, IS, LIKE, REGEXP, IN
+	precBitOr              // |
+	precBitAnd             // &
+	precShift              // <<, >>
+	precAdd                // +, -
+	precMul                // *, /, DIV, %, MOD
+	precBitXor             // ^
+	precUnary              // - (unary minus), ~
+	precNot2               // !
+	precAtom               // 列名、字面值、函数调用、括号表达式等
+)
+
+// binaryPrecedence 二元运算符的优先级
+var binaryPrecedence = map[opcode.Op]int{
+	opcode.LogicOr:    precOr,
+	opcode.LogicXor:   precXor,
+	opcode.LogicAnd:   precAnd,
+	opcode.EQ:         precCompare,
+	opcode.NE:         precCompare,
+	opcode.LT:         precCompare,
+	opcode.LE:         precCompare,
+	opcode.GT:         precCompare,
+	opcode.GE:         precCompare,
+	opcode.NullEQ:     precCompare,
+	opcode.Or:         precBitOr,
+	opcode.And:        precBitAnd,
+	opcode.LeftShift:  precShift,
+	opcode.RightShift: precShift,
+	opcode.Plus:       precAdd,
+	opcode.Minus:      precAdd,
+	opcode.Mul:        precMul,
+	opcode.Div:        precMul,
+	opcode.IntDiv:     precMul,
+	opcode.Mod:        precMul,
+	opcode.Xor:        precBitXor,
+}
+
+// precedence 返回表达式最外层运算符的优先级，不是运算符表达式时为 precAtom
+func precedence(expr ast.ExprNode) int {
+	switch node := expr.(type) {
+	case *ast.BinaryOperationExpr:
+		if prec, ok := binaryPrecedence[node.Op]; ok {
+			return prec
+		}
+
+	case *ast.UnaryOperationExpr:
+		switch node.Op {
+		case opcode.Not:
+			return precNot
+		case opcode.Not2:
+			return precNot2
+		}
+		return precUnary
+
+	case *ast.BetweenExpr:
+		return precBetween
+
+	case *ast.IsNullExpr, *ast.IsTruthExpr, *ast.PatternInExpr, *ast.PatternLikeOrIlikeExpr,
+		*ast.PatternRegexpExpr, *ast.CompareSubqueryExpr:
+		return precCompare
+	}
+
+	return precAtom
+}
+
+// writeOperand 写入运算符的操作数，操作数的优先级低于 prec 时加括号，
+// 使模板与输入的求值顺序一致，e.g. (a BETWEEN ? AND ?) eq ?
+func (v *ExtractVisitor) writeOperand(expr ast.ExprNode, prec int) {
+	if precedence(expr) >= prec {
+		expr.Accept(v)
+		return
+	}
+
+	v.builder.WriteString("(")
+	expr.Accept(v)
+	v.builder.WriteString(")")
+}
@@ -0,0 +1,174 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ExtractPredicates returns the columns used by equality, range and order by
+// predicates of each statement, resolved to their original tables.
+//
+// Columns compared with other columns, and unqualified columns of statements
+// with more than one table are ignored, since they can not be resolved.
+func (e *Extractor) ExtractPredicates(sql string) ([][]*models.Predicate, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	predicates := make([][]*models.Predicate, 0, len(stmts))
+	for idx := range stmts {
+		v := &predicateVisitor{tables: make(map[string]*models.TableInfo)}
+		stmts[idx].Accept(v)
+		predicates = append(predicates, v.resolve())
+	}
+
+	return predicates, nil
+}
+
+// columnRef is a column used by a predicate, before it is resolved to a table.
+type columnRef struct {
+	qualifier string // table name or alias, e.g. u in u.id
+	column    string
+	tp        models.PredicateType
+}
+
+// predicateVisitor implements ast.Visitor, it collects the columns used by
+// predicates and the tables (and aliases) they may refer to.
+type predicateVisitor struct {
+	tables map[string]*models.TableInfo // lower case table name or alias -> table
+	order  []*models.TableInfo          // tables in order of appearance
+	refs   []columnRef
+}
+
+// Enter implement ast.Visitor interface.
+func (v *predicateVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.TableSource:
+		if tn, ok := node.Source.(*ast.TableName); ok {
+			ti := models.NewTableInfo(schemaName(tn), tn.Name.O)
+			v.order = append(v.order, ti)
+			v.tables[strings.ToLower(tn.Name.O)] = ti
+			if node.AsName.O != "" {
+				v.tables[strings.ToLower(node.AsName.O)] = ti
+			}
+		}
+
+	case *ast.BinaryOperationExpr:
+		v.addComparison(node)
+
+	case *ast.PatternInExpr:
+		if col, ok := node.Expr.(*ast.ColumnNameExpr); ok && !node.Not && node.Sel == nil {
+			v.addRef(col, models.PredicateEqual)
+		}
+
+	case *ast.BetweenExpr:
+		if col, ok := node.Expr.(*ast.ColumnNameExpr); ok && !node.Not {
+			v.addRef(col, models.PredicateRange)
+		}
+
+	case *ast.PatternLikeOrIlikeExpr:
+		// 只有前缀匹配才能使用索引, e.g. LIKE 'abc%'
+		col, ok := node.Expr.(*ast.ColumnNameExpr)
+		pattern, isValue := node.Pattern.(*test_driver.ValueExpr)
+		if ok && isValue && !node.Not {
+			if s, isStr := pattern.GetValue().(string); isStr && s != "" && s[0] != '%' && s[0] != '_' {
+				v.addRef(col, models.PredicateRange)
+			}
+		}
+
+	case *ast.SelectStmt:
+		v.addOrderBy(node.OrderBy)
+	case *ast.UpdateStmt:
+		v.addOrderBy(node.Order)
+	case *ast.DeleteStmt:
+		v.addOrderBy(node.Order)
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *predicateVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// addComparison records `column op constant` and `constant op column` comparisons.
+func (v *predicateVisitor) addComparison(node *ast.BinaryOperationExpr) {
+	var tp models.PredicateType
+	switch node.Op {
+	case opcode.EQ, opcode.NullEQ:
+		tp = models.PredicateEqual
+	case opcode.GT, opcode.GE, opcode.LT, opcode.LE:
+		tp = models.PredicateRange
+	default:
+		return
+	}
+
+	if col, ok := node.L.(*ast.ColumnNameExpr); ok && isConstant(node.R) {
+		v.addRef(col, tp)
+	} else if col, ok := node.R.(*ast.ColumnNameExpr); ok && isConstant(node.L) {
+		v.addRef(col, tp)
+	}
+}
+
+func (v *predicateVisitor) addOrderBy(orderBy *ast.OrderByClause) {
+	if orderBy == nil {
+		return
+	}
+
+	for idx := range orderBy.Items {
+		if col, ok := orderBy.Items[idx].Expr.(*ast.ColumnNameExpr); ok {
+			v.addRef(col, models.PredicateOrder)
+		}
+	}
+}
+
+func (v *predicateVisitor) addRef(col *ast.ColumnNameExpr, tp models.PredicateType) {
+	if isMarkerColumn(col.Name) || isPseudoColumn(col.Name) {
+		return
+	}
+
+	v.refs = append(v.refs, columnRef{
+		qualifier: strings.ToLower(col.Name.Table.O),
+		column:    col.Name.Name.O,
+		tp:        tp,
+	})
+}
+
+// resolve resolves the collected columns to their tables.
+func (v *predicateVisitor) resolve() []*models.Predicate {
+	predicates := make([]*models.Predicate, 0, len(v.refs))
+	for idx := range v.refs {
+		var ti *models.TableInfo
+		if v.refs[idx].qualifier != "" {
+			ti = v.tables[v.refs[idx].qualifier]
+		} else if len(v.order) == 1 {
+			ti = v.order[0]
+		}
+
+		if ti == nil {
+			continue
+		}
+
+		predicates = append(predicates,
+			models.NewPredicate(ti.Schema(), ti.TableName(), v.refs[idx].column, v.refs[idx].tp))
+	}
+
+	return predicates
+}
+
+// isConstant reports whether the expression is a literal or a param marker.
+func isConstant(expr ast.ExprNode) bool {
+	switch expr.(type) {
+	case *test_driver.ValueExpr, *test_driver.ParamMarkerExpr:
+		return true
+	}
+
+	return false
+}
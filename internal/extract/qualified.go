@@ -0,0 +1,238 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// qualifiedSep separates the server, catalog and schema parts of a rewritten
+// three-part or four-part table name, which can not be part of an identifier.
+const qualifiedSep = "\x1f"
+
+// tableKeywords are the keywords followed by table names.
+var tableKeywords = []string{"FROM", "JOIN", "INTO", "UPDATE", "TABLE"}
+
+// rewriteQualifiedNames rewrites the three-part and four-part table names
+// following the table keywords and in the FROM lists, which the parser
+// rejects, to two-part names whose schema joins the leading parts, with the
+// SQL Server, Snowflake and BigQuery options only, a.b.c is a column in MySQL:
+//
+//	catalog.schema.table        -> `catalog\x1fschema`.`table`
+//	server.catalog.schema.table -> `server\x1fcatalog\x1fschema`.`table`
+//	catalog..table              -> `catalog\x1f`.`table`, the default schema of SQL Server
+//
+// The parts may be quoted by backticks, double quotes or brackets. With
+// bigquery, the parts may contain hyphens, the names quoted as a whole, e.g.
+// `project.dataset.table`, are split and a name has at most three parts, the
+// leading ones are the project, e.g. example.com:project.
+func rewriteQualifiedNames(sql string, bigquery bool) string {
+	if !strings.Contains(sql, ".") {
+		return sql
+	}
+
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+
+		// 每层括号是否为子查询，函数参数中的 FROM 不是表关键字，e.g. EXTRACT(YEAR FROM a.b.c)
+		queries []bool
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		switch sql[i] {
+		case '(':
+			queries = append(queries, isQueryStart(sql[skipSpace(sql, i+1):]))
+			continue
+		case ')':
+			if len(queries) > 0 {
+				queries = queries[:len(queries)-1]
+			}
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !isIdentChar(sql[i]) {
+			continue
+		}
+		if len(queries) > 0 && !queries[len(queries)-1] {
+			continue
+		}
+
+		keyword := ""
+		for _, k := range tableKeywords {
+			if hasKeyword(sql[i:], k) {
+				keyword = k
+				break
+			}
+		}
+		if keyword == "" {
+			continue
+		}
+
+		// table [[AS] alias], table ...，表值函数 e.g. UNNEST(a) 保持原样
+		j := skipSpace(sql, i+len(keyword))
+		for {
+			name, end, ok := qualifiedName(sql, j, bigquery)
+			if !ok {
+				break
+			}
+			if open := skipSpace(sql, end); open < len(sql) && sql[open] == '(' && (keyword == "FROM" || keyword == "JOIN") {
+				closing := matchParen(sql, open)
+				if closing < 0 {
+					break
+				}
+				name, end = "", closing+1
+			}
+			if name != "" {
+				if b.Len() == 0 {
+					b.Grow(len(sql) + 16)
+				}
+				b.WriteString(sql[last:j])
+				b.WriteString(name)
+				last = end
+			}
+
+			j = skipSpace(sql, end)
+			if hasKeyword(sql[j:], "AS") {
+				j = skipSpace(sql, j+len("AS"))
+			}
+			if k := identEnd(sql, j); k > j && !isClauseKeyword(sql[j:]) {
+				j = skipSpace(sql, k)
+			}
+			if j >= len(sql) || sql[j] != ',' || keyword != "FROM" {
+				break
+			}
+			j = skipSpace(sql, j+1)
+		}
+		i = max(i, j-1)
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// qualifiedName returns the rewritten table name starting at sql[i], empty if
+// it is kept, and its end.
+func qualifiedName(sql string, i int, bigquery bool) (string, int, bool) {
+	var (
+		parts  []string
+		quoted bool // 整体引用的 BigQuery 名称，e.g. `p.d.t`
+		j      = i
+	)
+
+	for j < len(sql) {
+		switch c := sql[j]; {
+		case c == '`' || c == '"' || c == '[':
+			end := skipQuoted(sql, j)
+			if c == '[' {
+				end = strings.IndexByte(sql[j:], ']') + j
+			}
+			if end < j || end >= len(sql) {
+				return "", 0, false
+			}
+
+			name := sql[j+1 : end]
+			if c != '[' {
+				name = strings.ReplaceAll(name, string(c)+string(c), string(c))
+			}
+			if bigquery && c == '`' && strings.Contains(name, ".") {
+				quoted = true
+				parts = append(parts, strings.Split(name, ".")...)
+			} else {
+				parts = append(parts, name)
+			}
+			j = end + 1
+		case c == '.' && !bigquery && len(parts) > 0: // SQL Server 的 catalog..table
+			parts = append(parts, "")
+			j++
+			continue
+		default:
+			k := j
+			for k < len(sql) && (isIdentChar(sql[k]) || (bigquery && sql[k] == '-' && k > j)) {
+				k++
+			}
+			if k == j {
+				return "", 0, false
+			}
+			parts = append(parts, sql[j:k])
+			j = k
+		}
+
+		if j >= len(sql) || sql[j] != '.' {
+			break
+		}
+		j++
+	}
+
+	if bigquery && len(parts) > 3 {
+		parts = append([]string{strings.Join(parts[:len(parts)-2], ".")}, parts[len(parts)-2:]...)
+	}
+	if len(parts) > 4 || parts[len(parts)-1] == "" {
+		return "", 0, false
+	}
+	if len(parts) < 3 && !quoted {
+		return "", j, true
+	}
+
+	table := parts[len(parts)-1]
+	parts = []string{strings.Join(parts[:len(parts)-1], qualifiedSep), table}
+	for idx := range parts {
+		parts[idx] = "`" + strings.ReplaceAll(parts[idx], "`", "``") + "`"
+	}
+
+	return strings.Join(parts, "."), j, true
+}
+
+// isQueryStart reports whether s starts a query, e.g. the body of a subquery
+// in parentheses.
+func isQueryStart(s string) bool {
+	for _, keyword := range []string{"SELECT", "WITH", "TABLE", "VALUES"} {
+		if hasKeyword(s, keyword) {
+			return true
+		}
+	}
+
+	return s != "" && s[0] == '('
+}
+
+// isClauseKeyword reports whether s starts with a keyword following a table
+// reference, rather than an alias.
+func isClauseKeyword(s string) bool {
+	for _, keyword := range []string{
+		"WHERE", "GROUP", "HAVING", "QUALIFY", "WINDOW", "ORDER", "LIMIT", "UNION", "EXCEPT", "INTERSECT",
+		"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "ON", "USING",
+	} {
+		if hasKeyword(s, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitQualified splits the schema of a rewritten table name to the server,
+// the catalog and the schema, the schema as is if it is not rewritten.
+func splitQualified(schema string) (server, catalog, name string) {
+	first, rest, ok := strings.Cut(schema, qualifiedSep)
+	if !ok {
+		return "", "", schema
+	}
+	if second, third, ok := strings.Cut(rest, qualifiedSep); ok {
+		return first, second, third
+	}
+
+	return "", first, rest
+}
+
+// schemaName returns the schema of the table name qualified by its server and
+// catalog, e.g. catalog.schema, for the column usages, predicates and joins.
+func schemaName(tn *ast.TableName) string {
+	return strings.ReplaceAll(tn.Schema.O, qualifiedSep, ".")
+}
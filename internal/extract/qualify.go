@@ -0,0 +1,168 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// QUALIFY 子句改写后的函数名，解析后由 visitor 还原
+const qualifyMarker = "sqlextractor_qualify"
+
+// qualifyTerminators are the keywords ending the expression of a QUALIFY clause.
+var qualifyTerminators = []string{"WINDOW", "ORDER", "LIMIT", "UNION", "EXCEPT", "INTERSECT", "FOR", "LOCK"}
+
+// WithQualify supports the QUALIFY clause of dialects such as BigQuery,
+// Snowflake or DuckDB, which filters the rows by the window functions and the
+// MySQL parser rejects. The clause is kept in the template, after HAVING.
+//
+// e.g. SELECT a FROM t QUALIFY ROW_NUMBER() OVER (PARTITION BY a) = 1 -> SELECT a FROM t QUALIFY ROW_NUMBER() OVER (PARTITION BY a) eq ?
+func WithQualify() Option {
+	return func(e *Extractor) { e.qualify = true }
+}
+
+// rewriteQualify rewrites the QUALIFY clauses to marker fields, appended to
+// the select list, which the MySQL parser accepts:
+//
+//	SELECT a FROM t QUALIFY x = 1 ORDER BY a -> SELECT a , sqlextractor_qualify(x = 1) FROM t ORDER BY a
+//
+// Quoted strings, identifiers and comments are kept as is.
+func rewriteQualify(sql string) string {
+	for {
+		rewritten, ok := rewriteFirstQualify(sql)
+		if !ok {
+			return sql
+		}
+		sql = rewritten
+	}
+}
+
+// rewriteFirstQualify rewrites the first QUALIFY clause of sql.
+func rewriteFirstQualify(sql string) (string, bool) {
+	return moveToSelectList(sql, func(i int) (string, int, bool) {
+		if !hasKeyword(sql[i:], "QUALIFY") {
+			return "", 0, false
+		}
+
+		start := i + len("QUALIFY")
+		end := qualifyEnd(sql, start)
+		expr := strings.TrimSpace(sql[start:end])
+		if expr == "" {
+			return "", 0, false
+		}
+
+		return qualifyMarker + "(" + expr + ")", end, true
+	})
+}
+
+// moveToSelectList moves the first clause of sql matched by clause, which is
+// called at each keyword, to a marker field appended to the select list of its
+// SELECT. clause returns the marker field and the end of the clause.
+func moveToSelectList(sql string, clause func(i int) (string, int, bool)) (string, bool) {
+	// 每层括号中最近的 SELECT 之后第一个 FROM 的位置，即 SELECT 列表的结束位置
+	var (
+		froms = []int{-1}
+		depth int
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+
+		switch c := sql[i]; {
+		case c == '(':
+			depth++
+			froms = append(froms[:depth], -1)
+			continue
+		case c == ')':
+			depth = max(depth-1, 0)
+			continue
+		case !isIdentChar(c) || (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')):
+			continue
+		}
+
+		switch {
+		case hasKeyword(sql[i:], "SELECT"):
+			froms[depth] = -1
+		case hasKeyword(sql[i:], "FROM"):
+			if froms[depth] < 0 {
+				froms[depth] = i
+			}
+		default:
+			field, end, ok := clause(i)
+			if !ok {
+				continue
+			}
+
+			at := froms[depth]
+			if at < 0 { // SELECT 没有 FROM
+				at = i
+			}
+
+			var b strings.Builder
+			b.Grow(len(sql) + len(field) + 4)
+			b.WriteString(sql[:at])
+			b.WriteString(", ")
+			b.WriteString(field)
+			b.WriteString(" ")
+			b.WriteString(sql[at:i])
+			b.WriteString(sql[end:])
+
+			return b.String(), true
+		}
+	}
+
+	return sql, false
+}
+
+// qualifyEnd returns the end of the QUALIFY expression starting at sql[i]: a
+// terminator keyword, a closing parenthesis or a semicolon outside parentheses.
+func qualifyEnd(sql string, i int) int {
+	return clauseEnd(sql, i, qualifyTerminators)
+}
+
+// clauseEnd returns the end of the clause starting at sql[i]: one of the
+// terminator keywords, a closing parenthesis or a semicolon outside
+// parentheses.
+func clauseEnd(sql string, i int, terminators []string) int {
+	depth := 0
+	for ; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+
+		switch c := sql[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case c == ';' && depth == 0:
+			return i
+		case depth == 0 && isIdentChar(c) && (i == 0 || !isIdentChar(sql[i-1])):
+			for _, keyword := range terminators {
+				if hasKeyword(sql[i:], keyword) {
+					return i
+				}
+			}
+		}
+	}
+
+	return len(sql)
+}
+
+// qualifyField returns the marker function of a select field, nil if the field
+// is not a QUALIFY clause.
+func qualifyField(field *ast.SelectField) *ast.FuncCallExpr {
+	fn, ok := field.Expr.(*ast.FuncCallExpr)
+	if !ok || fn.FnName.L != qualifyMarker || len(fn.Args) != 1 {
+		return nil
+	}
+
+	return fn
+}
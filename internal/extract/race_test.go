@@ -0,0 +1,7 @@
+//go:build race
+
+package extract
+
+// raceEnabled reports whether the race detector is enabled, it makes sync.Pool
+// drop objects randomly so allocation counts are not stable.
+const raceEnabled = true
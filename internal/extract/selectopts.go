@@ -0,0 +1,78 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// selectOptions returns the options bitset of a SELECT statement.
+func selectOptions(opts *ast.SelectStmtOpts) models.SelectOptions {
+	if opts == nil {
+		return 0
+	}
+
+	var o models.SelectOptions
+	if opts.Priority == mysql.HighPriority {
+		o |= models.SelectHighPriority
+	}
+	if opts.StraightJoin {
+		o |= models.SelectStraightJoin
+	}
+	if opts.SQLSmallResult {
+		o |= models.SelectSmallResult
+	}
+	if opts.SQLBigResult {
+		o |= models.SelectBigResult
+	}
+	if opts.SQLBufferResult {
+		o |= models.SelectBufferResult
+	}
+	if !opts.SQLCache {
+		o |= models.SelectNoCache
+	}
+	if opts.CalcFoundRows {
+		o |= models.SelectCalcFoundRows
+	}
+
+	return o
+}
+
+// ExtractSelectOptions returns the options bitset of each statement, the union
+// of the options of its SELECT statements, including subqueries and the
+// branches of UNION.
+func (e *Extractor) ExtractSelectOptions(sql string) ([]models.SelectOptions, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]models.SelectOptions, 0, len(stmts))
+	for idx := range stmts {
+		v := &selectOptionsVisitor{}
+		stmts[idx].Accept(v)
+		options = append(options, v.options)
+	}
+
+	return options, nil
+}
+
+// selectOptionsVisitor implements ast.Visitor, it collects the SELECT options.
+type selectOptionsVisitor struct {
+	options models.SelectOptions
+}
+
+// Enter implement ast.Visitor interface.
+func (v *selectOptionsVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	if node, ok := n.(*ast.SelectStmt); ok && node.Kind == ast.SelectStmtKindSelect {
+		v.options |= selectOptions(node.SelectStmtOpts)
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *selectOptionsVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,390 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// Snowflake VARIANT 路径和 :: 类型转换改写后的函数名，解析后由 visitor 还原
+const (
+	variantMarker = "sqlextractor_variant"
+	castMarker    = "sqlextractor_cast"
+)
+
+// sampleKeywords are the keywords which can not precede a SAMPLE clause, SAMPLE
+// following them is a column or a function.
+var sampleKeywords = []string{
+	"SELECT", "DISTINCT", "FROM", "JOIN", "WHERE", "ON", "AND", "OR", "NOT", "BY", "AS", "IN", "IS", "CASE", "WHEN", "THEN", "ELSE",
+}
+
+// WithSnowflake supports the Snowflake syntax the parser rejects: QUALIFY, the
+// SAMPLE clauses, the VARIANT paths src:a.b[0], the :: casts and the
+// database.schema.table names. Identifiers
+// follow the Snowflake case rules: the unquoted identifiers are folded to upper
+// case, the double quoted ones keep their case, so users, USERS and "USERS"
+// are the same table.
+//
+// e.g. select src:name::string from users sample (10) -> SELECT SRC:name::STRING FROM USERS TABLESAMPLE (?)
+func WithSnowflake() Option {
+	return func(e *Extractor) {
+		e.snowflake = true
+		e.qualify = true
+	}
+}
+
+// rewriteSnowflake rewrites the Snowflake syntax to the syntax the parser
+// accepts, then folds the identifiers. The VARIANT paths and the casts become
+// marker functions, SAMPLE becomes TABLESAMPLE:
+//
+//	src:customer."Name"[0]        -> sqlextractor_variant(src, 'customer."Name"[0]')
+//	'2024-01-01'::date            -> sqlextractor_cast('2024-01-01', 'DATE')
+//	t SAMPLE BLOCK (10) SEED (42) -> t TABLESAMPLE SYSTEM (10) REPEATABLE (42)
+//
+// Quoted strings and comments are kept as is.
+func rewriteSnowflake(sql string) string {
+	if strings.Contains(sql, ":") {
+		for {
+			rewritten, ok := rewriteFirstVariant(sql)
+			if !ok {
+				break
+			}
+			sql = rewritten
+		}
+	}
+	if containsKeyword(sql, "SAMPLE") || containsKeyword(sql, "TABLESAMPLE") {
+		sql = rewriteSample(sql)
+	}
+
+	return foldIdentifiers(sql)
+}
+
+// rewriteFirstVariant rewrites the first VARIANT path or :: cast of sql.
+func rewriteFirstVariant(sql string) (string, bool) {
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if sql[i] != ':' {
+			continue
+		}
+
+		// expr :: type [(p, s)]
+		if strings.HasPrefix(sql[i:], "::") {
+			operandEnd := len(strings.TrimRight(sql[:i], " \t\r\n"))
+			start := operandStart(sql[:operandEnd])
+
+			j := skipSpace(sql, i+2)
+			end := identEnd(sql, j)
+			if k := skipSpace(sql, end); end > j && k < len(sql) && sql[k] == '(' {
+				if closing := matchParen(sql, k); closing > 0 {
+					end = closing + 1
+				}
+			}
+			if start < 0 || start == operandEnd || end == j {
+				i++
+				continue
+			}
+
+			typ := strings.ToUpper(strings.Join(strings.Fields(sql[j:end]), ""))
+			return markerCall(sql, start, operandEnd, end, castMarker, typ), true
+		}
+
+		// column:path，: 前后没有空格
+		start, end := operandStart(sql[:i]), variantPath(sql, i+1)
+		if start < 0 || start == i || end == i+1 {
+			continue
+		}
+
+		return markerCall(sql, start, i, end, variantMarker, sql[i+1:end]), true
+	}
+
+	return sql, false
+}
+
+// markerCall replaces sql[start:end] with the marker function of the operand
+// sql[start:operandEnd] and the string argument.
+func markerCall(sql string, start, operandEnd, end int, marker, arg string) string {
+	var b strings.Builder
+	b.Grow(len(sql) + len(marker) + 8)
+	b.WriteString(sql[:start])
+	b.WriteString(marker)
+	b.WriteByte('(')
+	b.WriteString(sql[start:operandEnd])
+	b.WriteString(", ")
+	b.WriteString(mysqlString(arg))
+	b.WriteByte(')')
+	b.WriteString(sql[end:])
+
+	return b.String()
+}
+
+// operandStart returns the start of the operand ending prefix, a column, a
+// literal, a parenthesized expression or a function call, -1 if there is none.
+func operandStart(prefix string) int {
+	i := len(prefix)
+	for i > 0 {
+		switch c := prefix[i-1]; {
+		case c == ')':
+			depth := 0
+			for i--; i >= 0; i-- {
+				if prefix[i] == ')' {
+					depth++
+				} else if prefix[i] == '(' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if i < 0 {
+				return -1
+			}
+		case c == '\'' || c == '"' || c == '`':
+			open := strings.LastIndexByte(prefix[:i-1], c)
+			if open < 0 {
+				return -1
+			}
+			i = open
+		case isIdentChar(c) || c == '.' || c == '?':
+			i--
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// variantPath returns the end of the VARIANT path starting at sql[i], e.g.
+// customer."Name".tags[0], i if there is none.
+func variantPath(sql string, i int) int {
+	if i >= len(sql) || (sql[i] != '"' && !isIdentChar(sql[i])) {
+		return i
+	}
+
+	for j := i; j < len(sql); {
+		switch {
+		case sql[j] == '"':
+			j = skipQuoted(sql, j) + 1
+		case sql[j] == '[':
+			closing := matchBracket(sql, j)
+			if closing < 0 {
+				return j
+			}
+			j = closing + 1
+		case isIdentChar(sql[j]) || (sql[j] == '.' && j > i):
+			j++
+		default:
+			return min(j, len(sql))
+		}
+	}
+
+	return len(sql)
+}
+
+// rewriteSample rewrites the SAMPLE and TABLESAMPLE clauses of Snowflake to
+// the TABLESAMPLE clauses the parser accepts:
+//
+//	SAMPLE [BERNOULLI | ROW | SYSTEM | BLOCK] (n [ROWS]) [REPEATABLE | SEED (n)]
+func rewriteSample(sql string) string {
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) ||
+			(!hasKeyword(sql[i:], "SAMPLE") && !hasKeyword(sql[i:], "TABLESAMPLE")) || !afterTable(sql[last:i]) {
+			continue
+		}
+
+		j := skipSpace(sql, i+len("SAMPLE"))
+		if hasKeyword(sql[i:], "TABLESAMPLE") {
+			j = skipSpace(sql, i+len("TABLESAMPLE"))
+		}
+
+		method := ""
+		for _, m := range [][2]string{{"BERNOULLI", "BERNOULLI"}, {"ROW", "BERNOULLI"}, {"SYSTEM", "SYSTEM"}, {"BLOCK", "SYSTEM"}} {
+			if hasKeyword(sql[j:], m[0]) {
+				method = m[1] + " "
+				j = skipSpace(sql, j+len(m[0]))
+				break
+			}
+		}
+		if j >= len(sql) || sql[j] != '(' {
+			continue
+		}
+		closing := matchParen(sql, j)
+		if closing < 0 {
+			continue
+		}
+		size := sql[j : closing+1]
+		end := closing + 1
+
+		seed := ""
+		if k := skipSpace(sql, end); hasKeyword(sql[k:], "REPEATABLE") || hasKeyword(sql[k:], "SEED") {
+			open := skipSpace(sql, k+len("SEED"))
+			if hasKeyword(sql[k:], "REPEATABLE") {
+				open = skipSpace(sql, k+len("REPEATABLE"))
+			}
+			if open < len(sql) && sql[open] == '(' {
+				if seedEnd := matchParen(sql, open); seedEnd > 0 {
+					seed, end = " REPEATABLE "+sql[open:seedEnd+1], seedEnd+1
+				}
+			}
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 16)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString("TABLESAMPLE ")
+		b.WriteString(method)
+		b.WriteString(size)
+		b.WriteString(seed)
+		last, i = end, end-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// afterTable reports whether the SQL before a SAMPLE keyword ends with a table
+// name or alias, rather than a keyword or an operator.
+func afterTable(before string) bool {
+	before = strings.TrimRight(before, " \t\r\n")
+	if before == "" {
+		return false
+	}
+
+	c := before[len(before)-1]
+	if c == '"' || c == '`' {
+		return true
+	}
+	if !isIdentChar(c) {
+		return false
+	}
+
+	start := len(before)
+	for start > 0 && isIdentChar(before[start-1]) {
+		start--
+	}
+	for _, keyword := range sampleKeywords {
+		if hasKeyword(before[start:], keyword) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// foldIdentifiers folds the unquoted words of sql to upper case, as Snowflake
+// resolves the unquoted identifiers, and quotes the double quoted identifiers
+// by backticks, keeping their case. Strings, comments and numbers are kept.
+func foldIdentifiers(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '"':
+			end := skipQuoted(sql, i)
+			name := strings.ReplaceAll(sql[i+1:min(end, len(sql))], `""`, `"`)
+			b.WriteByte('`')
+			b.WriteString(strings.ReplaceAll(name, "`", "``"))
+			b.WriteByte('`')
+			i = end
+		case c == '\'' || c == '`' || c == '#' || c == '-' || c == '/':
+			j, ok := skipQuotedOrComment(sql, i)
+			if !ok {
+				b.WriteByte(c)
+				continue
+			}
+			j = min(j, len(sql)-1)
+			b.WriteString(sql[i : j+1])
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(sql) && isIdentChar(sql[j]) {
+				j++
+			}
+			if c >= '0' && c <= '9' { // 数字，e.g. 0x1F、1e3
+				b.WriteString(sql[i:j])
+			} else {
+				b.WriteString(strings.ToUpper(sql[i:j]))
+			}
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// writeSnowflakeFunc 写入 Snowflake 的 VARIANT 路径和 :: 类型转换，不是标记函数时返回 false
+//
+// e.g. src:customer.name::string = 'x' -> SRC:customer.name::STRING eq ?
+func (v *ExtractVisitor) writeSnowflakeFunc(node *ast.FuncCallExpr) bool {
+	if len(node.Args) != 2 {
+		return false
+	}
+
+	var sep string
+	switch node.FnName.L {
+	case variantMarker:
+		sep = ":"
+	case castMarker:
+		sep = "::"
+	default:
+		return false
+	}
+
+	node.Args[0].Accept(v)
+	v.builder.WriteString(sep)
+	v.builder.WriteString(copyArg(node, 1))
+
+	return true
+}
+
+// writeTableSample 写入 TABLESAMPLE 子句，采样大小和种子与 LIMIT 一样参数化
+//
+// e.g. FROM t TABLESAMPLE BERNOULLI (10 ROWS) REPEATABLE (42) -> FROM t TABLESAMPLE BERNOULLI (? ROWS) REPEATABLE (?)
+func (v *ExtractVisitor) writeTableSample(node *ast.TableSample) {
+	v.builder.WriteString(" TABLESAMPLE ")
+	switch node.SampleMethod {
+	case ast.SampleMethodTypeBernoulli:
+		v.builder.WriteString("BERNOULLI ")
+	case ast.SampleMethodTypeSystem:
+		v.builder.WriteString("SYSTEM ")
+	case ast.SampleMethodTypeTiDBRegion:
+		v.builder.WriteString("REGIONS ")
+	}
+
+	v.builder.WriteString("(")
+	if node.Expr != nil {
+		v.withParamColumn("sample", func() { node.Expr.Accept(v) })
+	}
+	switch node.SampleClauseUnit {
+	case ast.SampleClauseUnitTypeRow:
+		v.builder.WriteString(" ROWS")
+	case ast.SampleClauseUnitTypePercent:
+		v.builder.WriteString(" PERCENT")
+	}
+	v.builder.WriteString(")")
+
+	if node.RepeatableSeed != nil {
+		v.builder.WriteString(" REPEATABLE (")
+		v.withParamColumn("seed", func() { node.RepeatableSeed.Accept(v) })
+		v.builder.WriteString(")")
+	}
+}
@@ -0,0 +1,446 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+)
+
+// Spark SQL 语法改写后的函数名和标记列名，解析后由 visitor 还原
+const (
+	lateralViewMarker = "sqlextractor_lateral_view"
+	distributeMarker  = "sqlextractor_distribute_by"
+	clusterMarker     = "sqlextractor_cluster_by"
+	sortMarker        = "sqlextractor_sort_by"
+	overwriteMarker   = "sqlextractor_overwrite"
+	intoMarker        = "sqlextractor_into"
+	partitionMarker   = "sqlextractor_partition"
+)
+
+// sparkTerminators are the keywords ending the DISTRIBUTE BY, CLUSTER BY and
+// SORT BY clauses, ON ends the clauses followed by a rewritten INSERT
+// OVERWRITE target.
+var sparkTerminators = []string{"DISTRIBUTE", "CLUSTER", "SORT", "ORDER", "LIMIT", "UNION", "EXCEPT", "INTERSECT", "ON"}
+
+// WithSpark supports the Spark SQL and Hive syntax of batch jobs the parser
+// rejects: LATERAL VIEW [OUTER], DISTRIBUTE BY, SORT BY and CLUSTER BY are kept
+// in the template, the static partition values of INSERT OVERWRITE [TABLE]
+// and INSERT INTO TABLE are parameterized.
+//
+// e.g. SELECT a FROM t DISTRIBUTE BY a SORT BY b -> SELECT a FROM t DISTRIBUTE BY a SORT BY b
+func WithSpark() Option {
+	return func(e *Extractor) { e.spark = true }
+}
+
+// rewriteSpark rewrites the Spark SQL and Hive clauses, which the parser
+// rejects. LATERAL VIEW, DISTRIBUTE BY and CLUSTER BY become marker fields
+// appended to the select list, SORT BY becomes an ORDER BY led by a marker
+// item, and the target of INSERT OVERWRITE becomes a marker assignment of ON
+// DUPLICATE KEY UPDATE:
+//
+//	FROM t LATERAL VIEW OUTER explode(t.tags) v AS tag -> , sqlextractor_lateral_view(explode(t.tags), 'OUTER', 'v', 'tag') FROM t
+//	DISTRIBUTE BY a SORT BY b DESC            -> , sqlextractor_distribute_by(a) ... ORDER BY b DESC, sqlextractor_sort_by
+//	INSERT OVERWRITE TABLE t PARTITION (dt = '2024-01-01') SELECT ...
+//	  -> INSERT INTO t SELECT ... ON DUPLICATE KEY UPDATE sqlextractor_overwrite = sqlextractor_partition(dt = '2024-01-01')
+//
+// Quoted strings, identifiers and comments are kept as is. A clause which can
+// not be rewritten is kept, so the parser reports the error.
+func rewriteSpark(sql string) string {
+	if containsKeyword(sql, "INSERT") {
+		sql = rewriteInsertOverwrite(sql)
+	}
+	if containsKeyword(sql, "SORT") {
+		sql = rewriteSortBy(sql)
+	}
+	if containsKeyword(sql, "VIEW") || containsKeyword(sql, "DISTRIBUTE") || containsKeyword(sql, "CLUSTER") {
+		for {
+			rewritten, ok := moveToSelectList(sql, func(i int) (string, int, bool) { return sparkClause(sql, i) })
+			if !ok {
+				break
+			}
+			sql = rewritten
+		}
+	}
+
+	return sql
+}
+
+// sparkClause returns the marker field of the LATERAL VIEW, DISTRIBUTE BY or
+// CLUSTER BY clause starting at sql[i], and the end of the clause.
+func sparkClause(sql string, i int) (string, int, bool) {
+	var keyword, marker string
+	switch {
+	case hasKeyword(sql[i:], "LATERAL"):
+		return lateralView(sql, i)
+	case hasKeyword(sql[i:], "DISTRIBUTE"):
+		keyword, marker = "DISTRIBUTE", distributeMarker
+	case hasKeyword(sql[i:], "CLUSTER"):
+		keyword, marker = "CLUSTER", clusterMarker
+	default:
+		return "", 0, false
+	}
+
+	j := skipSpace(sql, i+len(keyword))
+	if !hasKeyword(sql[j:], "BY") {
+		return "", 0, false
+	}
+
+	j += len("BY")
+	end := clauseEnd(sql, j, sparkTerminators)
+	items := strings.TrimSpace(sql[j:end])
+	if items == "" {
+		return "", 0, false
+	}
+
+	return marker + "(" + items + ")", end, true
+}
+
+// lateralView returns the marker field of the LATERAL VIEW clause starting at
+// sql[i], and the end of the clause:
+//
+//	LATERAL VIEW [OUTER] generator(expr, ...) alias [AS column, ...]
+func lateralView(sql string, i int) (string, int, bool) {
+	j := skipSpace(sql, i+len("LATERAL"))
+	if !hasKeyword(sql[j:], "VIEW") {
+		return "", 0, false
+	}
+	j = skipSpace(sql, j+len("VIEW"))
+
+	outer := ""
+	if hasKeyword(sql[j:], "OUTER") {
+		outer = "OUTER"
+		j = skipSpace(sql, j+len("OUTER"))
+	}
+
+	// generator(expr, ...)
+	name := identEnd(sql, j)
+	if name == j {
+		return "", 0, false
+	}
+	open := skipSpace(sql, name)
+	if open >= len(sql) || sql[open] != '(' {
+		return "", 0, false
+	}
+	closing := matchParen(sql, open)
+	if closing < 0 {
+		return "", 0, false
+	}
+	generator := sql[j:name] + sql[open:closing+1]
+
+	// alias [AS column, ...]
+	args := []string{generator, "'" + outer + "'"}
+	j = skipSpace(sql, closing+1)
+	if hasKeyword(sql[j:], "AS") {
+		return "", 0, false
+	}
+	end := identEnd(sql, j)
+	if end == j {
+		return "", 0, false
+	}
+	args = append(args, quoteIdent(sql[j:end]))
+
+	if k := skipSpace(sql, end); hasKeyword(sql[k:], "AS") {
+		k += len("AS")
+		for {
+			k = skipSpace(sql, k)
+			column := identEnd(sql, k)
+			if column == k {
+				return "", 0, false
+			}
+			args = append(args, quoteIdent(sql[k:column]))
+			end = column
+
+			k = skipSpace(sql, column)
+			if k >= len(sql) || sql[k] != ',' {
+				break
+			}
+			k++
+		}
+	}
+
+	return lateralViewMarker + "(" + strings.Join(args, ", ") + ")", end, true
+}
+
+// identEnd returns the end of the identifier starting at sql[i], which may
+// be qualified or quoted by backticks, i if there is none.
+func identEnd(sql string, i int) int {
+	j := i
+	for j < len(sql) {
+		switch {
+		case sql[j] == '`':
+			j = skipQuoted(sql, j) + 1
+		case isIdentChar(sql[j]) || (sql[j] == '.' && j > i):
+			j++
+		default:
+			return min(j, len(sql))
+		}
+	}
+
+	return min(j, len(sql))
+}
+
+// quoteIdent returns the string literal of the identifier, without backticks.
+func quoteIdent(ident string) string {
+	return mysqlString(strings.ReplaceAll(strings.Trim(ident, "`"), "``", "`"))
+}
+
+// rewriteSortBy rewrites the SORT BY clauses to ORDER BY clauses led by a
+// marker item.
+func rewriteSortBy(sql string) string {
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "SORT") {
+			continue
+		}
+
+		j := skipSpace(sql, i+len("SORT"))
+		if !hasKeyword(sql[j:], "BY") {
+			continue
+		}
+		j += len("BY")
+		end := clauseEnd(sql, j, sparkTerminators)
+		items := strings.TrimSpace(sql[j:end])
+		if items == "" {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 32)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString("ORDER BY ")
+		b.WriteString(items)
+		b.WriteString(", ")
+		b.WriteString(sortMarker)
+		b.WriteString(" ")
+		last, i = end, end-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// rewriteInsertOverwrite rewrites the INSERT OVERWRITE [TABLE] and INSERT
+// INTO TABLE statements, and their static or dynamic partitions.
+func rewriteInsertOverwrite(sql string) string {
+	var (
+		b    strings.Builder
+		last int // sql[:last] 已写入 b
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if (i > 0 && (isIdentChar(sql[i-1]) || sql[i-1] == '.')) || !hasKeyword(sql[i:], "INSERT") {
+			continue
+		}
+
+		// OVERWRITE [TABLE] | INTO TABLE
+		j := skipSpace(sql, i+len("INSERT"))
+		marker := intoMarker
+		switch {
+		case hasKeyword(sql[j:], "OVERWRITE"):
+			marker = overwriteMarker
+			j = skipSpace(sql, j+len("OVERWRITE"))
+			if hasKeyword(sql[j:], "TABLE") {
+				j = skipSpace(sql, j+len("TABLE"))
+			}
+		case hasKeyword(sql[j:], "INTO"):
+			j = skipSpace(sql, j+len("INTO"))
+			if !hasKeyword(sql[j:], "TABLE") {
+				continue
+			}
+			j = skipSpace(sql, j+len("TABLE"))
+		default:
+			continue
+		}
+
+		table := identEnd(sql, j)
+		if table == j {
+			continue
+		}
+
+		// PARTITION (dt = 'x', hr)
+		partition := ""
+		rest := skipSpace(sql, table)
+		if hasKeyword(sql[rest:], "PARTITION") {
+			open := skipSpace(sql, rest+len("PARTITION"))
+			if open >= len(sql) || sql[open] != '(' {
+				continue
+			}
+			closing := matchParen(sql, open)
+			if closing < 0 {
+				continue
+			}
+			partition = sql[open+1 : closing]
+			rest = closing + 1
+		}
+
+		end := clauseEnd(sql, rest, nil)
+
+		if b.Len() == 0 {
+			b.Grow(len(sql) + 96)
+		}
+		b.WriteString(sql[last:i])
+		b.WriteString("INSERT INTO ")
+		b.WriteString(sql[j:table])
+		b.WriteString(" ")
+		b.WriteString(strings.TrimSpace(sql[rest:end]))
+		if marker == overwriteMarker || partition != "" {
+			b.WriteString(" ON DUPLICATE KEY UPDATE ")
+			b.WriteString(marker)
+			b.WriteString(" = ")
+			b.WriteString(partitionMarker)
+			b.WriteString("(")
+			b.WriteString(partition)
+			b.WriteString(")")
+		}
+		last, i = end, end-1
+	}
+
+	if b.Len() == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// sparkField returns the Spark marker function of a select field, nil if the
+// field is not a Spark clause.
+func sparkField(field *ast.SelectField) *ast.FuncCallExpr {
+	fn, ok := field.Expr.(*ast.FuncCallExpr)
+	if !ok {
+		return nil
+	}
+
+	switch fn.FnName.L {
+	case lateralViewMarker, distributeMarker, clusterMarker:
+		return fn
+	}
+
+	return nil
+}
+
+// sortItem reports whether the last ORDER BY item is the marker of a rewritten
+// SORT BY clause.
+func sortItem(orderBy *ast.OrderByClause) bool {
+	if orderBy == nil || len(orderBy.Items) == 0 {
+		return false
+	}
+
+	col, ok := orderBy.Items[len(orderBy.Items)-1].Expr.(*ast.ColumnNameExpr)
+
+	return ok && isSparkMarker(col.Name)
+}
+
+// isSparkMarker reports whether the column is a marker of a rewritten Spark
+// clause.
+func isSparkMarker(name *ast.ColumnName) bool {
+	switch name.Name.L {
+	case sortMarker, overwriteMarker, intoMarker:
+		return name.Table.L == ""
+	}
+
+	return false
+}
+
+// insertTarget returns the marker assignment of a rewritten INSERT OVERWRITE
+// or INSERT INTO TABLE statement with partitions, nil if there is none.
+func insertTarget(node *ast.InsertStmt) *ast.FuncCallExpr {
+	if len(node.OnDuplicate) != 1 || !isSparkMarker(node.OnDuplicate[0].Column) {
+		return nil
+	}
+
+	fn, ok := node.OnDuplicate[0].Expr.(*ast.FuncCallExpr)
+	if !ok || fn.FnName.L != partitionMarker {
+		return nil
+	}
+
+	return fn
+}
+
+// writeSparkClauses 写入 SELECT 列表末尾标记的 Spark 子句，只写入 LATERAL VIEW
+// 或者 DISTRIBUTE BY、CLUSTER BY
+//
+// e.g. LATERAL VIEW explode(t.tags) v AS tag, DISTRIBUTE BY a
+func (v *ExtractVisitor) writeSparkClauses(fields []*ast.FuncCallExpr, lateral bool) {
+	for _, fn := range fields {
+		switch {
+		case fn.FnName.L == lateralViewMarker && lateral:
+			v.builder.WriteString(" LATERAL VIEW ")
+			if outer := copyArg(fn, 1); outer != "" {
+				v.builder.WriteString(outer)
+				v.builder.WriteString(" ")
+			}
+			fn.Args[0].Accept(v)
+			v.builder.WriteString(" ")
+			v.writeIdent(copyArg(fn, 2))
+			for idx := 3; idx < len(fn.Args); idx++ {
+				if idx == 3 {
+					v.builder.WriteString(" AS ")
+				} else {
+					v.builder.WriteString(", ")
+				}
+				v.writeIdent(copyArg(fn, idx))
+			}
+
+		case fn.FnName.L != lateralViewMarker && !lateral:
+			if fn.FnName.L == distributeMarker {
+				v.builder.WriteString(" DISTRIBUTE BY ")
+			} else {
+				v.builder.WriteString(" CLUSTER BY ")
+			}
+			for idx := range fn.Args {
+				if idx > 0 {
+					v.builder.WriteString(", ")
+				}
+				fn.Args[idx].Accept(v)
+			}
+		}
+	}
+}
+
+// writePartition 写入 INSERT 的分区，静态分区的值参数化
+//
+// e.g. PARTITION (dt = '2024-01-01', hr) -> PARTITION (dt = ?, hr)
+func (v *ExtractVisitor) writePartition(fn *ast.FuncCallExpr) {
+	if len(fn.Args) == 0 {
+		return
+	}
+
+	v.builder.WriteString(" PARTITION (")
+	for idx, arg := range fn.Args {
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
+
+		if eq, ok := arg.(*ast.BinaryOperationExpr); ok && eq.Op == opcode.EQ {
+			column := ""
+			if col, ok := eq.L.(*ast.ColumnNameExpr); ok {
+				column = col.Name.Name.O
+			}
+			eq.L.Accept(v)
+			v.builder.WriteString(" = ")
+			v.withParamColumn(column, func() { eq.R.Accept(v) })
+			continue
+		}
+		arg.Accept(v)
+	}
+	v.builder.WriteString(")")
+}
@@ -0,0 +1,66 @@
+package extract
+
+import (
+	"strings"
+)
+
+// SplitStatements splits the SQL string into its statements at the semicolons
+// outside of quoted strings and comments, without parsing it, so SQL the parser
+// rejects can be split too. The statements are slices of the input without
+// trailing semicolons, the empty and comment-only ones are dropped.
+//
+// e.g. SELECT 'a;b'; SELECT 2 /* ; */; -> [SELECT 'a;b', SELECT 2 /* ; */]
+func SplitStatements(sql string) []string {
+	return splitText(sql, false)
+}
+
+// splitText is SplitStatements, with postgres the dollar-quoted strings are
+// skipped too, e.g. $$a;b$$.
+func splitText(sql string, postgres bool) []string {
+	var (
+		texts []string
+		start int
+		code  bool // sql[start:i] 包含注释之外的内容
+	)
+
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == ';':
+			if code {
+				texts = append(texts, trimStatement(sql[start:i]))
+			}
+			start, code = i+1, false
+			continue
+
+		case postgres && c == '$' && (i == 0 || !isIdentChar(sql[i-1])):
+			if _, end, ok := dollarQuoted(sql, i); ok {
+				i, code = end-1, true
+				continue
+			}
+
+		case isSpace(c):
+			continue
+		}
+
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			if c := sql[i]; c == '\'' || c == '"' || c == '`' {
+				code = true
+			}
+			i = j
+			continue
+		}
+		code = true
+	}
+
+	if code {
+		texts = append(texts, trimStatement(sql[start:]))
+	}
+
+	return texts
+}
+
+// trimStatement trims the spaces and trailing semicolons of a statement, like
+// Split does with the parsed statements.
+func trimStatement(text string) string {
+	return strings.TrimRight(strings.TrimSpace(text), "; \t\n")
+}
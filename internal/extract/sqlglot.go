@@ -0,0 +1,54 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+// WithSQLGlotStyle renders the templates as sqlglot (MySQL dialect) renders
+// the same statement with its literals replaced by placeholders, so that
+// digests computed with sqlglot agree with ours: standard SQL operators,
+// uppercase function names, NULL kept as is, prefix NOT of negated predicates,
+// JOIN instead of INNER JOIN, LIMIT ? OFFSET ?, and literals of aggregate
+// functions as placeholders, except COUNT(*).
+//
+// e.g. SELECT count(*) FROM t JOIN s ON t.id = s.tid WHERE a NOT IN (1, 2) LIMIT 10, 20 ->
+// SELECT COUNT(*) FROM t JOIN s ON t.id = s.tid WHERE NOT a IN (?, ?) LIMIT ? OFFSET ?
+func WithSQLGlotStyle() Option {
+	return func(e *Extractor) {
+		e.sqlglot = true
+		e.standardOps = true
+	}
+}
+
+// prefixNot writes the NOT of a negated predicate before it in the sqlglot
+// style, e.g. NOT a IN (?), and reports whether it was written.
+func (v *ExtractVisitor) prefixNot(not bool) bool {
+	if !not || !v.sqlglot {
+		return false
+	}
+
+	v.builder.WriteString("NOT ")
+	return true
+}
+
+// writeFuncName writes the function name, uppercase in the sqlglot style.
+func (v *ExtractVisitor) writeFuncName(name string) {
+	if v.sqlglot {
+		name = strings.ToUpper(name)
+	}
+	v.builder.WriteString(name)
+}
+
+// isCountStar reports whether the aggregate is COUNT(*), which is parsed as
+// COUNT(1).
+func isCountStar(node *ast.AggregateFuncExpr) bool {
+	if !strings.EqualFold(node.F, ast.AggFuncCount) || node.Distinct || len(node.Args) != 1 {
+		return false
+	}
+
+	val, ok := node.Args[0].(*test_driver.ValueExpr)
+	return ok && val.GetValue() == int64(1)
+}
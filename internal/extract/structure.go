@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ExtractStructureMetrics returns the structure metrics of each statement:
+// subquery count, max nesting depth, derived table count and union branch count.
+func (e *Extractor) ExtractStructureMetrics(sql string) ([]*models.StructureMetrics, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]*models.StructureMetrics, 0, len(stmts))
+	for idx := range stmts {
+		v := &structureVisitor{}
+		stmts[idx].Accept(v)
+		metrics = append(metrics, models.NewStructureMetrics(
+			v.subqueryCount, v.maxDepth, v.derivedTableCount, v.unionBranchCount))
+	}
+
+	return metrics, nil
+}
+
+// structureVisitor implements ast.Visitor, it counts the structure metrics.
+type structureVisitor struct {
+	depth             int
+	maxDepth          int
+	subqueryCount     int
+	derivedTableCount int
+	unionBranchCount  int
+}
+
+// Enter implement ast.Visitor interface.
+func (v *structureVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.SelectStmt:
+		v.depth++
+		v.maxDepth = max(v.maxDepth, v.depth)
+
+	case *ast.SubqueryExpr:
+		v.subqueryCount++
+
+	case *ast.TableSource:
+		switch node.Source.(type) {
+		case *ast.SelectStmt, *ast.SetOprStmt:
+			v.derivedTableCount++
+		}
+
+	case *ast.SetOprSelectList:
+		for idx := range node.Selects {
+			if _, ok := node.Selects[idx].(*ast.SelectStmt); ok {
+				v.unionBranchCount++
+			}
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *structureVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	if _, ok := n.(*ast.SelectStmt); ok {
+		v.depth--
+	}
+
+	return n, true
+}
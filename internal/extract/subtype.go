@@ -0,0 +1,44 @@
+package extract
+
+import (
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ExtractOpSubTypes returns the sub-type of each statement: UPSERT for ON
+// DUPLICATE KEY UPDATE or ON CONFLICT (even with a SELECT source), REPLACE,
+// INSERT_SELECT, or INSERT for the other INSERT statements, and none for the
+// other statements.
+func (e *Extractor) ExtractOpSubTypes(sql string) ([]models.SQLOpSubType, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	subTypes := make([]models.SQLOpSubType, 0, len(stmts))
+	for idx := range stmts {
+		subTypes = append(subTypes, opSubType(stmts[idx]))
+	}
+
+	return subTypes, nil
+}
+
+// opSubType returns the sub-type of a statement.
+func opSubType(stmt ast.StmtNode) models.SQLOpSubType {
+	node, ok := stmt.(*ast.InsertStmt)
+	if !ok {
+		return models.SQLSubTypeNone
+	}
+
+	switch {
+	case len(node.OnDuplicate) > 0 && insertTarget(node) == nil: // 排除 INSERT OVERWRITE 的标记
+		return models.SQLSubTypeUpsert
+	case node.IsReplace:
+		return models.SQLSubTypeReplace
+	case node.Select != nil:
+		return models.SQLSubTypeInsertSelect
+	default:
+		return models.SQLSubTypeInsert
+	}
+}
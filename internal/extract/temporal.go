@@ -0,0 +1,88 @@
+package extract
+
+import (
+	"cmp"
+	stdslices "slices"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// TemporalKind is the type of a temporal literal.
+type TemporalKind string
+
+const (
+	TemporalDate      TemporalKind = "DATE"      // DATE '2024-01-01'
+	TemporalTime      TemporalKind = "TIME"      // TIME '10:00:00'
+	TemporalTimestamp TemporalKind = "TIMESTAMP" // TIMESTAMP '2024-01-01 10:00:00'
+)
+
+// temporalLiterals are the temporal literal functions of the parser.
+var temporalLiterals = map[string]TemporalKind{
+	ast.DateLiteral:      TemporalDate,
+	ast.TimeLiteral:      TemporalTime,
+	ast.TimestampLiteral: TemporalTimestamp,
+}
+
+// TemporalParam is the placeholder of the value of a temporal literal.
+type TemporalParam struct {
+	Index int          // index of the placeholder in the parameters
+	Kind  TemporalKind // type of the literal
+}
+
+// ExtractTemporalParams returns the placeholders of the temporal literals of
+// each statement, in the order of the parameters, so their string values can
+// be typed by the caller.
+//
+// e.g. SELECT * FROM t WHERE a > DATE '2024-01-01' AND b = 1 -> [{0 DATE}]
+func (e *Extractor) ExtractTemporalParams(sql string) ([][]TemporalParam, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([][]TemporalParam, 0, len(stmts))
+	for idx := range stmts {
+		var collector *temporalCollector
+		err := e.visit(stmts[idx], visitSpans, func(v *ExtractVisitor) {
+			collector = &temporalCollector{index: v.paramIndex}
+			stmts[idx].Accept(collector)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		stdslices.SortFunc(collector.params, func(a, b TemporalParam) int { return cmp.Compare(a.Index, b.Index) })
+		params = append(params, collector.params)
+	}
+
+	return params, nil
+}
+
+// temporalCollector implements ast.Visitor, it collects the placeholders of
+// the temporal literals.
+type temporalCollector struct {
+	index  map[ast.ExprNode]int
+	params []TemporalParam
+}
+
+// Enter implement ast.Visitor interface.
+func (c *temporalCollector) Enter(n ast.Node) (ast.Node, bool) {
+	fn, ok := n.(*ast.FuncCallExpr)
+	if !ok || len(fn.Args) != 1 {
+		return n, false
+	}
+
+	if kind, ok := temporalLiterals[fn.FnName.L]; ok {
+		// 参数过多被截断或折叠时没有占位符
+		if idx, found := c.index[fn.Args[0]]; found {
+			c.params = append(c.params, TemporalParam{Index: idx, Kind: kind})
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (c *temporalCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,116 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// handleCreateTableStmt 处理 CREATE TEMPORARY TABLE 语句，列定义和约束按原样保留，
+// 表选项不保留
+//
+// e.g. CREATE TEMPORARY TABLE tmp AS SELECT id FROM t WHERE a = 1 -> CREATE TEMPORARY TABLE tmp AS SELECT id FROM t WHERE a eq ?
+func (v *ExtractVisitor) handleCreateTableStmt(node *ast.CreateTableStmt) {
+	if node.TemporaryKeyword != ast.TemporaryLocal {
+		v.logError(fmt.Sprintf("Enter ast.Node type: %T", node))
+		return
+	}
+
+	if v.opType == models.SQLOperationUnknown {
+		v.opType = models.SQLOperationCreate
+	}
+
+	v.builder.WriteString("CREATE TEMPORARY TABLE ")
+	if node.IfNotExists {
+		v.builder.WriteString("IF NOT EXISTS ")
+	}
+	node.Table.Accept(v)
+
+	if node.ReferTable != nil {
+		v.builder.WriteString(" LIKE ")
+		node.ReferTable.Accept(v)
+		return
+	}
+
+	if len(node.Cols) > 0 || len(node.Constraints) > 0 {
+		ctx := format.NewRestoreCtx(format.DefaultRestoreFlags, v.builder)
+		v.builder.WriteString(" (")
+		for idx := range node.Cols {
+			if idx > 0 {
+				v.builder.WriteString(", ")
+			}
+			_ = node.Cols[idx].Restore(ctx)
+		}
+		for idx := range node.Constraints {
+			if idx > 0 || len(node.Cols) > 0 {
+				v.builder.WriteString(", ")
+			}
+			_ = node.Constraints[idx].Restore(ctx)
+		}
+		v.builder.WriteString(")")
+	}
+
+	if node.Select != nil {
+		v.builder.WriteString(" AS ")
+		node.Select.Accept(v)
+	}
+}
+
+// handleDropTableStmt 处理 DROP TEMPORARY TABLE 语句
+func (v *ExtractVisitor) handleDropTableStmt(node *ast.DropTableStmt) {
+	if node.IsView || node.TemporaryKeyword != ast.TemporaryLocal {
+		v.logError(fmt.Sprintf("Enter ast.Node type: %T", node))
+		return
+	}
+
+	if v.opType == models.SQLOperationUnknown {
+		v.opType = models.SQLOperationDrop
+	}
+
+	v.builder.WriteString("DROP TEMPORARY TABLE ")
+	if node.IfExists {
+		v.builder.WriteString("IF EXISTS ")
+	}
+	for idx := range node.Tables {
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
+		node.Tables[idx].Accept(v)
+	}
+}
+
+// trackTemporary marks the table infos of the temporary tables created by
+// stmt or a previous statement of the input, and forgets the tables dropped
+// by stmt. A plain DROP TABLE also drops the temporary table of the name.
+func (st *extractState) trackTemporary(stmt ast.StmtNode, tableInfos []*models.TableInfo) {
+	if node, ok := stmt.(*ast.CreateTableStmt); ok && node.TemporaryKeyword == ast.TemporaryLocal {
+		_, _, schema := splitQualified(node.Table.Schema.O)
+		st.temporary[temporaryKey(schema, node.Table.Name.O)] = struct{}{}
+	}
+
+	if len(st.temporary) == 0 {
+		return
+	}
+
+	for _, ti := range tableInfos {
+		if _, ok := st.temporary[temporaryKey(ti.Schema(), ti.TableName())]; ok {
+			ti.SetTemporary(true)
+		}
+	}
+
+	if node, ok := stmt.(*ast.DropTableStmt); ok && !node.IsView {
+		for _, tn := range node.Tables {
+			_, _, schema := splitQualified(tn.Schema.O)
+			delete(st.temporary, temporaryKey(schema, tn.Name.O))
+		}
+	}
+}
+
+// temporaryKey 返回临时表的 key，表名大小写不敏感
+func temporaryKey(schema, table string) string {
+	return strings.ToLower(schema) + "." + strings.ToLower(table)
+}
@@ -0,0 +1,36 @@
+package extract
+
+import (
+	"regexp"
+)
+
+// WithTenantSchema maps the per-tenant schemas matching pattern to the
+// canonical schema in the templates and the templatized schemas of the table
+// infos, so the statements of all the tenants share the same digest. The
+// tenant of the table infos is the first submatch of pattern, or the whole
+// schema if pattern has no group.
+//
+// e.g. WithTenantSchema(regexp.MustCompile(`^tenant_(\w+)$`), "tenant"): SELECT * FROM tenant_123.orders -> SELECT * FROM tenant.orders, tenant 123
+func WithTenantSchema(pattern *regexp.Regexp, schema string) Option {
+	return func(e *Extractor) {
+		e.tenantPattern = pattern
+		e.tenantSchema = schema
+	}
+}
+
+// tenantOf 返回 schema 对应的租户，schema 不是租户 schema 时返回 false
+func (v *ExtractVisitor) tenantOf(schema string) (string, bool) {
+	if v.tenantPattern == nil || schema == "" {
+		return "", false
+	}
+
+	match := v.tenantPattern.FindStringSubmatch(schema)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+
+	return match[0], true
+}
@@ -0,0 +1,261 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// ExtractTimeWindows returns the time windows queried by the temporal range
+// predicates of each statement, one per column, in the order of the columns.
+//
+// The predicates compare a column with a temporal expression: the current time
+// (NOW(), CURDATE(), ...), optionally shifted by INTERVAL or DATE_SUB, or a
+// date literal. The predicates of a column are combined whatever the logical
+// operators. Months and years count as 30 and 365 days.
+func (e *Extractor) ExtractTimeWindows(sql string) ([][]*models.TimeWindow, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := make([][]*models.TimeWindow, 0, len(stmts))
+	for idx := range stmts {
+		v := &timeWindowVisitor{bounds: make(map[string]*timeRange)}
+		stmts[idx].Accept(v)
+		windows = append(windows, v.windows())
+	}
+
+	return windows, nil
+}
+
+// timeBound is a bound of a time range, relative to the current time or fixed.
+type timeBound struct {
+	relative bool
+	offset   time.Duration // 相对当前时间的偏移，relative 时有效
+	at       time.Time     // 固定时间，非 relative 时有效
+}
+
+// timeRange is the bounds of the predicates of a column, nil if missing.
+type timeRange struct {
+	lower, upper *timeBound
+}
+
+// timeWindowVisitor implements ast.Visitor, it collects the bounds of the
+// temporal range predicates.
+type timeWindowVisitor struct {
+	columns []string // 按出现顺序
+	bounds  map[string]*timeRange
+}
+
+// Enter implement ast.Visitor interface.
+func (v *timeWindowVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.BinaryOperationExpr:
+		v.addComparison(node)
+
+	case *ast.BetweenExpr:
+		if col, ok := node.Expr.(*ast.ColumnNameExpr); ok && !node.Not {
+			v.addBound(col, node.Left, true)
+			v.addBound(col, node.Right, false)
+		}
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *timeWindowVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// addComparison records the bound of col >= x, col < x, x <= col, etc.
+func (v *timeWindowVisitor) addComparison(node *ast.BinaryOperationExpr) {
+	var lower bool
+	switch node.Op {
+	case opcode.GE, opcode.GT:
+		lower = true
+	case opcode.LE, opcode.LT:
+	default:
+		return
+	}
+
+	if col, ok := node.L.(*ast.ColumnNameExpr); ok {
+		v.addBound(col, node.R, lower)
+	} else if col, ok := node.R.(*ast.ColumnNameExpr); ok {
+		v.addBound(col, node.L, !lower)
+	}
+}
+
+// addBound records the bound of the column if the expression is temporal.
+func (v *timeWindowVisitor) addBound(col *ast.ColumnNameExpr, expr ast.ExprNode, lower bool) {
+	bound, ok := evalTime(expr)
+	if !ok {
+		return
+	}
+
+	name := col.Name.Name.O
+	if col.Name.Table.O != "" {
+		name = col.Name.Table.O + "." + name
+	}
+
+	r, ok := v.bounds[name]
+	if !ok {
+		r = &timeRange{}
+		v.bounds[name] = r
+		v.columns = append(v.columns, name)
+	}
+
+	if lower {
+		r.lower = &bound
+	} else {
+		r.upper = &bound
+	}
+}
+
+// windows returns the time windows of the columns.
+func (v *timeWindowVisitor) windows() []*models.TimeWindow {
+	windows := make([]*models.TimeWindow, 0, len(v.columns))
+	for _, name := range v.columns {
+		r := v.bounds[name]
+		if r.lower == nil {
+			windows = append(windows, models.NewTimeWindow(name, 0, false, true))
+			continue
+		}
+
+		// 没有上界时上界为当前时间
+		upper := r.upper
+		if upper == nil {
+			upper = &timeBound{relative: true}
+		}
+
+		var width time.Duration
+		switch {
+		case r.lower.relative && upper.relative:
+			width = upper.offset - r.lower.offset
+		case !r.lower.relative && !upper.relative:
+			width = upper.at.Sub(r.lower.at)
+		}
+
+		windows = append(windows, models.NewTimeWindow(name, max(width, 0), r.lower.relative, false))
+	}
+
+	return windows
+}
+
+// dateLayouts are the layouts of the date literals.
+var dateLayouts = []string{time.DateOnly, time.DateTime, "2006-01-02 15:04:05.999999", "2006-01-02T15:04:05"}
+
+// evalTime evaluates a temporal expression to a time bound.
+func evalTime(expr ast.ExprNode) (timeBound, bool) {
+	switch node := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return evalTime(node.Expr)
+
+	case *test_driver.ValueExpr:
+		s, ok := node.GetValue().(string)
+		if !ok {
+			return timeBound{}, false
+		}
+
+		for _, layout := range dateLayouts {
+			if at, err := time.Parse(layout, s); err == nil {
+				return timeBound{at: at}, true
+			}
+		}
+
+	case *ast.FuncCallExpr:
+		return evalTimeFunc(node)
+	}
+
+	return timeBound{}, false
+}
+
+// evalTimeFunc evaluates the current time functions, the date literals and
+// the interval arithmetic.
+func evalTimeFunc(node *ast.FuncCallExpr) (timeBound, bool) {
+	switch node.FnName.L {
+	case ast.Now, ast.CurrentTimestamp, ast.LocalTime, ast.LocalTimestamp, ast.Sysdate,
+		ast.Curdate, ast.CurrentDate, ast.UTCTimestamp, ast.UTCDate:
+		return timeBound{relative: true}, true
+
+	case ast.DateLiteral, ast.TimestampLiteral, ast.Date:
+		if len(node.Args) == 1 {
+			return evalTime(node.Args[0])
+		}
+
+	case ast.DateSub, ast.SubDate, ast.DateAdd, ast.AddDate:
+		if len(node.Args) != 3 {
+			break
+		}
+
+		base, ok := evalTime(node.Args[0])
+		if !ok {
+			break
+		}
+
+		d, ok := intervalDuration(node.Args[1], node.Args[2])
+		if !ok {
+			break
+		}
+
+		if node.FnName.L == ast.DateSub || node.FnName.L == ast.SubDate {
+			d = -d
+		}
+		base.offset += d
+		base.at = base.at.Add(d)
+
+		return base, true
+	}
+
+	return timeBound{}, false
+}
+
+// intervalUnits are the durations of the interval units.
+var intervalUnits = map[ast.TimeUnitType]time.Duration{
+	ast.TimeUnitMicrosecond: time.Microsecond,
+	ast.TimeUnitSecond:      time.Second,
+	ast.TimeUnitMinute:      time.Minute,
+	ast.TimeUnitHour:        time.Hour,
+	ast.TimeUnitDay:         24 * time.Hour,
+	ast.TimeUnitWeek:        7 * 24 * time.Hour,
+	ast.TimeUnitMonth:       30 * 24 * time.Hour,
+	ast.TimeUnitQuarter:     90 * 24 * time.Hour,
+	ast.TimeUnitYear:        365 * 24 * time.Hour,
+}
+
+// intervalDuration returns the duration of INTERVAL n unit.
+func intervalDuration(n, unit ast.ExprNode) (time.Duration, bool) {
+	u, ok := unit.(*ast.TimeUnitExpr)
+	if !ok {
+		return 0, false
+	}
+	d, ok := intervalUnits[u.Unit]
+	if !ok {
+		return 0, false
+	}
+
+	val, ok := n.(*test_driver.ValueExpr)
+	if !ok {
+		return 0, false
+	}
+
+	switch x := val.GetValue().(type) {
+	case int64:
+		return time.Duration(x) * d, true
+	case uint64:
+		return time.Duration(x) * d, true
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64); err == nil {
+			return time.Duration(i) * d, true
+		}
+	}
+
+	return 0, false
+}
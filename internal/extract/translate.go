@@ -0,0 +1,555 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+)
+
+// Dialects supported by Translate.
+const (
+	DialectMySQL      = "mysql"
+	DialectPostgreSQL = "postgresql"
+	DialectSQLServer  = "sqlserver"
+	DialectOracle     = "oracle"
+)
+
+// standardQuotes are the restore flags of the dialects with double quoted
+// names.
+const standardQuotes = format.RestoreStringSingleQuotes | format.RestoreKeyWordUppercase |
+	format.RestoreNameDoubleQuotes | format.RestoreStringWithoutCharset
+
+// translateFlags are the restore flags of each target dialect.
+var translateFlags = map[string]format.RestoreFlags{
+	DialectMySQL:      format.DefaultRestoreFlags | format.RestoreStringWithoutCharset,
+	DialectPostgreSQL: standardQuotes,
+	DialectSQLServer:  standardQuotes,
+	DialectOracle:     standardQuotes,
+}
+
+// templateOps maps the operator names written in templates (eq, gt, and, ...)
+// to the SQL operators.
+var templateOps = func() map[string]string {
+	ops := make(map[string]string)
+	for op := opcode.LogicAnd; op <= opcode.IntDiv; op++ {
+		var builder strings.Builder
+		if err := op.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &builder)); err != nil {
+			continue
+		}
+		ops[op.String()] = strings.TrimSpace(builder.String())
+	}
+	ops[opcode.LogicXor.String()] = "XOR"
+	ops[opcode.NullEQ.String()] = "<=>"
+	ops[opcode.NE.String()] = "<>"
+
+	return ops
+}()
+
+// unaryOps are the operator names of templates which are also prefix
+// operators, e.g. minus ?.
+var unaryOps = map[string]bool{
+	opcode.Minus.String(): true, opcode.Plus.String(): true, opcode.BitNeg.String(): true, opcode.Not.String(): true,
+}
+
+// operandKeywords are the reserved keywords which are operands, e.g. NULL,
+// or end one, e.g. CASE ... END.
+var operandKeywords = map[string]bool{"null": true, "true": true, "false": true, "end": true}
+
+// operandStartKeywords are the reserved keywords which start an operand.
+var operandStartKeywords = map[string]bool{
+	"null": true, "true": true, "false": true, "case": true, "not": true, "exists": true, "interval": true, "binary": true,
+}
+
+// standardizeOps replaces the operator names of a template, e.g. id eq ?, with
+// the SQL operators. A name is an operator only where the grammar expects one:
+// after an operand for the binary operators, e.g. a minus ?, and before an
+// operand for the prefix ones, e.g. eq minus ?. Columns, tables and functions
+// named after an operator, e.g. SELECT minus FROM t, quoted strings and quoted
+// names are kept as is. The templates of Extract quote the names written as
+// operator names, e.g. `minus` minus ?, which read as prefix operators
+// otherwise.
+func standardizeOps(template string) string {
+	var builder strings.Builder
+	builder.Grow(len(template))
+
+	// afterOperand 表示前一个 token 是操作数（或以操作数结尾），之后的运算符名是二元运算符
+	afterOperand := false
+	for i := 0; i < len(template); {
+		c := template[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			j := skipQuotedName(template, i)
+			builder.WriteString(template[i:j])
+			afterOperand = true
+			i = j
+
+		case isIdentByte(c):
+			j := i
+			for j < len(template) && isIdentByte(template[j]) {
+				j++
+			}
+			word := template[i:j]
+			if op, ok := templateOps[word]; ok && isOperator(template, i, j, afterOperand) {
+				builder.WriteString(op)
+				afterOperand = false
+			} else {
+				builder.WriteString(word)
+				afterOperand = isOperand(word)
+			}
+			i = j
+
+		default:
+			builder.WriteByte(c)
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				afterOperand = c == '?' || c == ')'
+			}
+			i++
+		}
+	}
+
+	return builder.String()
+}
+
+// skipQuotedName returns the end of the quoted string or name at i.
+func skipQuotedName(template string, i int) int {
+	c := template[i]
+	j := i + 1
+	for j < len(template) {
+		if template[j] == '\\' && c != '`' {
+			j += 2
+			continue
+		}
+		if template[j] == c {
+			// 两个连续的引号表示转义
+			if j+1 < len(template) && template[j+1] == c {
+				j += 2
+				continue
+			}
+			break
+		}
+		j++
+	}
+
+	return min(j+1, len(template))
+}
+
+// isOperator reports whether the operator name template[i:j] is an operator,
+// not a name, e.g. t.minus or minus(a).
+func isOperator(template string, i, j int, afterOperand bool) bool {
+	if i > 0 && template[i-1] == '.' || j < len(template) && (template[j] == '.' || template[j] == '(') {
+		return false
+	}
+	if afterOperand {
+		return true
+	}
+	if !unaryOps[template[i:j]] {
+		return false
+	}
+
+	// 前缀运算符之后必须是操作数，e.g. eq minus ?；minus FROM、minus, 中的 minus 是列名
+	k := j
+	for k < len(template) && (template[k] == ' ' || template[k] == '\t' || template[k] == '\n' || template[k] == '\r') {
+		k++
+	}
+	if k == len(template) {
+		return false
+	}
+	switch c := template[k]; {
+	case c == '?' || c == '(' || c == ':' || c == '\'' || c == '"' || c == '`':
+		return true
+	case isIdentByte(c):
+		l := k
+		for l < len(template) && isIdentByte(template[l]) {
+			l++
+		}
+		next := template[k:l]
+		if _, ok := templateOps[next]; ok {
+			return unaryOps[next]
+		}
+		if _, ok := reservedWords[strings.ToLower(next)]; ok {
+			return operandStartKeywords[strings.ToLower(next)]
+		}
+		return true
+	}
+
+	return false
+}
+
+// isOperand reports whether the unquoted word of a template is an operand or
+// ends one: a name, a number, a parameter or NULL, but not a keyword.
+func isOperand(word string) bool {
+	lower := strings.ToLower(word)
+	if _, ok := reservedWords[lower]; ok {
+		return operandKeywords[lower]
+	}
+
+	return true
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// intervalFuncs are the functions of the MySQL INTERVAL expressions, e.g.
+// DATE_ADD(d, INTERVAL 1 DAY) and d - INTERVAL 1 DAY.
+var intervalFuncs = map[string]bool{"date_add": true, "date_sub": true, "adddate": true, "subdate": true}
+
+// limitClause is a LIMIT clause replaced by a marker during the restore.
+type limitClause struct {
+	marker        string
+	count, offset string // restored expressions, offset is empty if not set
+	owner         string // SELECT, UPDATE or DELETE
+	orderBy       bool   // the statement has ORDER BY
+	topLevel      bool   // the clause belongs to the statement, not a subquery
+}
+
+// translator rewrites the statement for the target dialect, and collects the
+// constructs which can not be translated.
+type translator struct {
+	to          string
+	flags       format.RestoreFlags
+	root        ast.Node
+	limits      []*limitClause
+	unsupported []string
+	err         error
+}
+
+func (t *translator) report(construct string) {
+	for _, u := range t.unsupported {
+		if u == construct {
+			return
+		}
+	}
+	t.unsupported = append(t.unsupported, construct)
+}
+
+func (t *translator) restore(n ast.Node) (string, error) {
+	var builder strings.Builder
+	err := n.Restore(format.NewRestoreCtx(t.flags, &builder))
+
+	return builder.String(), err
+}
+
+// limit replaces the LIMIT clause with a marker, which is replaced with the
+// clause of the target dialect after the restore.
+func (t *translator) limit(limit **ast.Limit, owner ast.Node, kind string, orderBy bool) {
+	l := &limitClause{
+		marker:   "__sql_extractor_limit_" + strconv.Itoa(len(t.limits)),
+		owner:    kind,
+		orderBy:  orderBy,
+		topLevel: owner == t.root,
+	}
+
+	var err error
+	if l.count, err = t.restore((*limit).Count); err != nil {
+		t.err = err
+		return
+	}
+	if (*limit).Offset != nil {
+		if l.offset, err = t.restore((*limit).Offset); err != nil {
+			t.err = err
+			return
+		}
+	}
+
+	*limit = &ast.Limit{Count: &ast.ColumnNameExpr{Name: &ast.ColumnName{Name: ast.NewCIStr(l.marker)}}}
+	t.limits = append(t.limits, l)
+}
+
+// pagination removes the markers of a rewritten TOP or OFFSET FETCH clause,
+// the TOP clause becomes the LIMIT clause. It reports whether the clause is an
+// OFFSET without FETCH, whose count is not a part of the statement.
+func (t *translator) pagination(node *ast.SelectStmt) bool {
+	if node.Fields != nil {
+		if fn := topField(node.Fields.Fields); fn != nil {
+			node.Fields.Fields = node.Fields.Fields[:len(node.Fields.Fields)-1]
+			if node.Limit == nil {
+				node.Limit = &ast.Limit{Count: fn.Args[0]}
+			}
+			if options := copyArg(fn, 1); options != "" {
+				t.report("TOP " + options)
+			}
+		}
+	}
+
+	marker := fetchItem(node.OrderBy)
+	if marker == "" {
+		return false
+	}
+
+	node.OrderBy.Items = node.OrderBy.Items[:len(node.OrderBy.Items)-1]
+	if len(node.OrderBy.Items) == 0 {
+		node.OrderBy = nil
+	}
+
+	return marker == offsetMarker
+}
+
+// Enter implements the ast.Visitor interface.
+//
+//nolint:gocyclo,cyclop
+func (t *translator) Enter(n ast.Node) (ast.Node, bool) {
+	mysql := t.to == DialectMySQL
+
+	switch node := n.(type) {
+	case *ast.SelectStmt:
+		offsetOnly := t.pagination(node)
+		if node.Limit != nil {
+			t.limit(&node.Limit, node, "SELECT", node.OrderBy != nil)
+			if offsetOnly && t.err == nil {
+				t.limits[len(t.limits)-1].count = ""
+			}
+		}
+		if !mysql && node.SelectStmtOpts != nil && node.SelectStmtOpts.StraightJoin {
+			t.report("STRAIGHT_JOIN")
+		}
+		if node.LockInfo != nil && node.LockInfo.LockType != ast.SelectLockNone {
+			if t.to == DialectSQLServer ||
+				t.to == DialectOracle && node.LockInfo.LockType != ast.SelectLockForUpdate {
+				t.report(strings.ToUpper(node.LockInfo.LockType.String()))
+			}
+		}
+
+	case *ast.SetOprStmt:
+		if node.Limit != nil {
+			t.limit(&node.Limit, node, "SELECT", node.OrderBy != nil)
+		}
+
+	case *ast.UpdateStmt:
+		if node.Limit != nil {
+			t.limit(&node.Limit, node, "UPDATE", node.Order != nil)
+		}
+		if !mysql && node.IgnoreErr {
+			t.report("UPDATE IGNORE")
+		}
+
+	case *ast.DeleteStmt:
+		if node.Limit != nil {
+			t.limit(&node.Limit, node, "DELETE", node.Order != nil)
+		}
+		if !mysql && node.IgnoreErr {
+			t.report("DELETE IGNORE")
+		}
+
+	case *ast.InsertStmt:
+		if !mysql {
+			if node.IsReplace {
+				t.report("REPLACE")
+			}
+			if node.IgnoreErr {
+				t.report("INSERT IGNORE")
+			}
+			if len(node.OnDuplicate) > 0 {
+				t.report("ON DUPLICATE KEY UPDATE")
+			}
+		}
+
+	case *ast.Join:
+		if !mysql && node.StraightJoin {
+			t.report("STRAIGHT_JOIN")
+		}
+
+	case *ast.TableName:
+		if !mysql && len(node.IndexHints) > 0 {
+			t.report("index hints")
+		}
+
+	case *ast.FuncCallExpr:
+		if !mysql && node.FnName.L == "ifnull" {
+			node.FnName = ast.NewCIStr("COALESCE")
+		}
+		if !mysql && intervalFuncs[node.FnName.L] {
+			t.report("INTERVAL")
+		}
+
+	case *ast.AggregateFuncExpr:
+		if !mysql && strings.EqualFold(node.F, ast.AggFuncGroupConcat) {
+			t.report("GROUP_CONCAT")
+		}
+
+	case *ast.BinaryOperationExpr:
+		if !mysql && node.Op == opcode.NullEQ {
+			t.report("<=>")
+		}
+
+	case *ast.UnaryOperationExpr:
+		if !mysql && node.Op == opcode.Not2 {
+			t.report("!")
+		}
+	}
+
+	return n, t.err != nil
+}
+
+// Leave implements the ast.Visitor interface.
+func (t *translator) Leave(n ast.Node) (ast.Node, bool) {
+	return n, t.err == nil
+}
+
+// clause returns the LIMIT clause in the target dialect, and whether it is a
+// TOP clause, which is written after SELECT.
+func (t *translator) clause(l *limitClause) (string, bool) {
+	if l.count == "" { // OFFSET 没有 FETCH
+		return t.offsetClause(l), false
+	}
+
+	mysqlClause := "LIMIT " + l.count
+	if l.offset != "" {
+		mysqlClause = "LIMIT " + l.offset + "," + l.count
+	}
+	if t.to == DialectMySQL {
+		return mysqlClause, false
+	}
+
+	if l.owner != "SELECT" {
+		t.report("LIMIT in " + l.owner)
+		return mysqlClause, false
+	}
+
+	fetch := "FETCH NEXT " + l.count + " ROWS ONLY"
+	if l.offset != "" {
+		fetch = "OFFSET " + l.offset + " ROWS " + fetch
+	}
+
+	switch t.to {
+	case DialectPostgreSQL:
+		if l.offset != "" {
+			return "LIMIT " + l.count + " OFFSET " + l.offset, false
+		}
+		return "LIMIT " + l.count, false
+
+	case DialectOracle:
+		return fetch, false
+
+	case DialectSQLServer:
+		// OFFSET FETCH 需要 ORDER BY，否则只能在顶层 SELECT 使用 TOP
+		if l.orderBy {
+			if l.offset == "" {
+				fetch = "OFFSET 0 ROWS " + fetch
+			}
+			return fetch, false
+		}
+		if l.offset == "" && l.topLevel {
+			return "TOP (" + l.count + ")", true
+		}
+
+		t.report("LIMIT without ORDER BY")
+		return mysqlClause, false
+	}
+
+	return mysqlClause, false
+}
+
+// offsetClause returns the OFFSET clause without a row count in the target
+// dialect.
+func (t *translator) offsetClause(l *limitClause) string {
+	switch t.to {
+	case DialectMySQL:
+		return "LIMIT " + l.offset + "," + maxLimit
+	case DialectPostgreSQL:
+		return "OFFSET " + l.offset
+	case DialectSQLServer:
+		if !l.orderBy {
+			t.report("OFFSET without ORDER BY")
+		}
+	}
+
+	return "OFFSET " + l.offset + " ROWS"
+}
+
+// Unsupported returns the constructs of the statements of the SQL which can
+// not be translated into the target dialect, see Translate. Unlike Translate,
+// the SQL is parsed as is, it should not be a template.
+func (e *Extractor) Unsupported(sql, to string) ([]string, error) {
+	flags, ok := translateFlags[to]
+	if !ok {
+		return nil, fmt.Errorf("unsupported dialect: %s", to)
+	}
+
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var unsupported []string
+	for _, stmt := range stmts {
+		t := &translator{to: to, flags: flags, root: stmt, unsupported: unsupported}
+		stmt.Accept(t)
+		if t.err != nil {
+			return nil, t.err
+		}
+		unsupported = t.unsupported
+	}
+
+	return unsupported, nil
+}
+
+// Translate translates the statements of the SQL, e.g. a template of Extract,
+// into the target dialect: LIMIT is rewritten as LIMIT OFFSET, FETCH or TOP,
+// names are double quoted and IFNULL becomes COALESCE. The constructs which
+// can not be translated, e.g. ON DUPLICATE KEY UPDATE, are kept as is and
+// reported. Operator names of templates (eq, gt, and, ...) are replaced with
+// the SQL operators, and their TOP and OFFSET FETCH clauses are translated as
+// LIMIT.
+func (e *Extractor) Translate(sql, to string) (string, []string, error) {
+	flags, ok := translateFlags[to]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported dialect: %s", to)
+	}
+
+	// 模板中的 TOP 和 OFFSET FETCH 不依赖 Extractor 的方言选项
+	stmts, err := e.parse(rewritePagination(standardizeOps(sql), true))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(stmts) == 0 {
+		return "", nil, errors.New("empty SQL statement")
+	}
+
+	var (
+		translated  = make([]string, len(stmts))
+		unsupported []string
+	)
+	for idx, stmt := range stmts {
+		t := &translator{to: to, flags: flags, root: stmt, unsupported: unsupported}
+		stmt.Accept(t)
+		if t.err != nil {
+			return "", nil, t.err
+		}
+
+		s, err := t.restore(stmt)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, l := range t.limits {
+			marker, err := t.restore(&ast.ColumnName{Name: ast.NewCIStr(l.marker)})
+			if err != nil {
+				return "", nil, err
+			}
+
+			clause, top := t.clause(l)
+			if !top {
+				s = strings.Replace(s, "LIMIT "+marker, clause, 1)
+				continue
+			}
+
+			s = strings.Replace(s, " LIMIT "+marker, "", 1)
+			if rest, ok := strings.CutPrefix(s, "SELECT DISTINCT "); ok {
+				s = "SELECT DISTINCT " + clause + " " + rest
+			} else {
+				s = "SELECT " + clause + " " + strings.TrimPrefix(s, "SELECT ")
+			}
+		}
+
+		translated[idx] = s
+		unsupported = t.unsupported
+	}
+
+	return strings.Join(translated, "; "), unsupported, nil
+}
@@ -0,0 +1,390 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// 通配符修饰符改写后的函数名，解析后由 visitor 还原
+const (
+	exceptMarker  = "sqlextractor_except"
+	replaceMarker = "sqlextractor_replace"
+)
+
+// WithWildcardModifiers supports the wildcard modifiers of dialects such as
+// ClickHouse, BigQuery or DuckDB, which the MySQL parser rejects: the columns
+// of * EXCEPT (a, b) are kept in the template, and the expressions of
+// * REPLACE (expr AS c) are templatized.
+//
+// e.g. SELECT * EXCEPT (password) FROM users WHERE id = 1 -> SELECT * EXCEPT (password) FROM users WHERE id eq ?
+func WithWildcardModifiers() Option {
+	return func(e *Extractor) { e.wildcardModifiers = true }
+}
+
+// rewriteWildcardModifiers rewrites the wildcard modifiers to marker fields
+// which the MySQL parser accepts:
+//
+//	t.* EXCEPT (a, b)        -> t.*, sqlextractor_except(a, b)
+//	* REPLACE (x + 1 AS a)   -> *, sqlextractor_replace(x + 1, a)
+//
+// Quoted strings, identifiers and comments are kept as is. A modifier which
+// can not be rewritten is kept, so the parser reports the error.
+func rewriteWildcardModifiers(sql string) string {
+	var (
+		b     strings.Builder
+		last  int // sql[:last] 已写入 b
+		found bool
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if j, ok := skipQuotedOrComment(sql, i); ok {
+			i = j
+			continue
+		}
+		if sql[i] != '*' {
+			continue
+		}
+
+		// * 之后的修饰符，可以有多个，e.g. * EXCEPT (a) REPLACE (b AS c)
+		end := i + 1
+		for {
+			marker, open, ok := modifierAt(sql, end)
+			if !ok {
+				break
+			}
+
+			closing := matchParen(sql, open)
+			if closing < 0 {
+				break
+			}
+
+			args, ok := modifierArgs(marker, sql[open+1:closing])
+			if !ok {
+				break
+			}
+
+			if !found {
+				b.Grow(len(sql) + 32)
+				found = true
+			}
+			b.WriteString(sql[last:end])
+			b.WriteString(", ")
+			b.WriteString(marker)
+			b.WriteByte('(')
+			b.WriteString(args)
+			b.WriteByte(')')
+			last, end = closing+1, closing+1
+		}
+		i = end - 1
+	}
+
+	if !found {
+		return sql
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// modifierAt returns the marker of the EXCEPT or REPLACE modifier at sql[i:],
+// after whitespace, and the index of its opening parenthesis.
+func modifierAt(sql string, i int) (string, int, bool) {
+	i = skipSpace(sql, i)
+
+	var marker string
+	switch {
+	case hasKeyword(sql[i:], "EXCEPT"):
+		marker, i = exceptMarker, i+len("EXCEPT")
+	case hasKeyword(sql[i:], "REPLACE"):
+		marker, i = replaceMarker, i+len("REPLACE")
+	default:
+		return "", 0, false
+	}
+
+	i = skipSpace(sql, i)
+	if i >= len(sql) || sql[i] != '(' {
+		return "", 0, false
+	}
+
+	// * EXCEPT (SELECT ...) 不是修饰符
+	if hasKeyword(sql[skipSpace(sql, i+1):], "SELECT") {
+		return "", 0, false
+	}
+
+	return marker, i, true
+}
+
+// modifierArgs returns the arguments of the marker function of the modifier
+// list: the columns of EXCEPT, and expression, column pairs of REPLACE.
+func modifierArgs(marker, list string) (string, bool) {
+	if marker == exceptMarker {
+		return list, strings.TrimSpace(list) != ""
+	}
+
+	items := splitTopLevel(list)
+	args := make([]string, 0, 2*len(items))
+	for _, item := range items {
+		idx := lastTopLevelAs(item)
+		if idx < 0 {
+			return "", false
+		}
+		args = append(args, strings.TrimSpace(item[:idx]), strings.TrimSpace(item[idx+len(" AS "):]))
+	}
+
+	return strings.Join(args, ", "), true
+}
+
+// splitTopLevel splits the list by the commas outside parentheses, brackets
+// and quotes.
+func splitTopLevel(list string) []string {
+	var (
+		items []string
+		depth int
+		start int
+	)
+
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(list, i)
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(items, list[start:])
+}
+
+// lastTopLevelAs returns the index of the last " AS " outside parentheses and
+// quotes, or -1.
+func lastTopLevelAs(item string) int {
+	var (
+		depth int
+		found = -1
+	)
+
+	for i := 0; i < len(item); i++ {
+		switch c := item[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(item, i)
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isSpace(c) && len(item) > i+3 &&
+			strings.EqualFold(item[i+1:i+3], "AS") && isSpace(item[i+3]):
+			found = i
+		}
+	}
+
+	return found
+}
+
+// matchParen returns the index of the parenthesis closing sql[open], or -1.
+func matchParen(sql string, open int) int {
+	depth := 0
+	for i := open; i < len(sql); i++ {
+		switch sql[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(sql, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// skipQuotedOrComment returns the index of the last byte of the quoted string,
+// quoted identifier or comment starting at sql[i], if any.
+func skipQuotedOrComment(sql string, i int) (int, bool) {
+	switch c := sql[i]; {
+	case c == '\'' || c == '"' || c == '`':
+		return skipQuoted(sql, i), true
+	case c == '#' || (c == '-' && strings.HasPrefix(sql[i:], "-- ")):
+		if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+			return i + j, true
+		}
+
+		return len(sql), true
+	case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+		if j := strings.Index(sql[i+2:], "*/"); j >= 0 {
+			return i + j + 3, true
+		}
+
+		return len(sql), true
+	}
+
+	return i, false
+}
+
+// skipQuoted returns the index of the quote closing sql[i].
+func skipQuoted(sql string, i int) int {
+	quote := sql[i]
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case sql[j] == '\\' && quote != '`':
+			j++
+		case sql[j] == quote:
+			if j+1 < len(sql) && sql[j+1] == quote { // 转义的引号 '' 或 ``
+				j++
+				continue
+			}
+
+			return j
+		}
+	}
+
+	return len(sql)
+}
+
+func skipSpace(sql string, i int) int {
+	for i < len(sql) && isSpace(sql[i]) {
+		i++
+	}
+
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// hasKeyword reports whether s starts with the keyword, case-insensitively,
+// as a whole word.
+func hasKeyword(s, keyword string) bool {
+	if len(s) < len(keyword) || !strings.EqualFold(s[:len(keyword)], keyword) {
+		return false
+	}
+
+	if len(s) == len(keyword) {
+		return true
+	}
+
+	return !isIdentChar(s[len(keyword)])
+}
+
+// isIdentChar reports whether c may be part of an unquoted identifier.
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// wildcardModifier returns the marker function of a select field, nil if the
+// field is not a wildcard modifier.
+func wildcardModifier(field *ast.SelectField) *ast.FuncCallExpr {
+	fn, ok := field.Expr.(*ast.FuncCallExpr)
+	if !ok || (fn.FnName.L != exceptMarker && fn.FnName.L != replaceMarker) {
+		return nil
+	}
+
+	return fn
+}
+
+// writeWildcardModifier 写入通配符修饰符，e.g. EXCEPT (a, b)、REPLACE (x plus ? AS a)
+func (v *ExtractVisitor) writeWildcardModifier(fn *ast.FuncCallExpr) {
+	if fn.FnName.L == exceptMarker {
+		v.builder.WriteString(" EXCEPT (")
+		for idx := range fn.Args {
+			if idx > 0 {
+				v.builder.WriteString(", ")
+			}
+			fn.Args[idx].Accept(v)
+		}
+		v.builder.WriteString(")")
+
+		return
+	}
+
+	v.builder.WriteString(" REPLACE (")
+	for idx := 0; idx+1 < len(fn.Args); idx += 2 {
+		if idx > 0 {
+			v.builder.WriteString(", ")
+		}
+
+		column := columnName(fn.Args[idx+1])
+		v.withParamColumn(column, func() { fn.Args[idx].Accept(v) })
+		v.builder.WriteString(" AS ")
+		v.writeIdent(column)
+	}
+	v.builder.WriteString(")")
+}
+
+// ExtractWildcards returns the wildcard projections of each statement, with
+// the columns of their modifiers if the extractor is created with
+// WithWildcardModifiers.
+func (e *Extractor) ExtractWildcards(sql string) ([][]*models.Wildcard, error) {
+	stmts, err := e.parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	wildcards := make([][]*models.Wildcard, 0, len(stmts))
+	for idx := range stmts {
+		v := &wildcardVisitor{wildcards: []*models.Wildcard{}}
+		stmts[idx].Accept(v)
+		wildcards = append(wildcards, v.wildcards)
+	}
+
+	return wildcards, nil
+}
+
+// wildcardVisitor implements ast.Visitor, it collects the wildcard fields.
+type wildcardVisitor struct {
+	wildcards []*models.Wildcard
+}
+
+// Enter implement ast.Visitor interface.
+func (v *wildcardVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	list, ok := n.(*ast.FieldList)
+	if !ok {
+		return n, false
+	}
+
+	for idx := 0; idx < len(list.Fields); idx++ {
+		wild := list.Fields[idx].WildCard
+		if wild == nil {
+			continue
+		}
+
+		var except, replace []string
+		for idx+1 < len(list.Fields) {
+			fn := wildcardModifier(list.Fields[idx+1])
+			if fn == nil {
+				break
+			}
+			idx++
+
+			for jdx := range fn.Args {
+				if fn.FnName.L == exceptMarker {
+					except = append(except, columnName(fn.Args[jdx]))
+				} else if jdx%2 == 1 {
+					replace = append(replace, columnName(fn.Args[jdx]))
+				}
+			}
+		}
+
+		v.wildcards = append(v.wildcards, models.NewWildcard(wild.Schema.O, wild.Table.O, except, replace))
+	}
+
+	return n, false
+}
+
+// Leave implement ast.Visitor interface.
+func (v *wildcardVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
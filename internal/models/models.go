@@ -1,5 +1,11 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // SQLOpType represents the type of SQL operation
 type SQLOpType string
 
@@ -14,14 +20,45 @@ const (
 	SQLOperationDelete  SQLOpType = "DELETE"
 	SQLOperationExplain SQLOpType = "EXPLAIN"
 	SQLOperationShow    SQLOpType = "SHOW"
+
+	SQLOperationPrepare    SQLOpType = "PREPARE"
+	SQLOperationExecute    SQLOpType = "EXECUTE"
+	SQLOperationDeallocate SQLOpType = "DEALLOCATE"
+
+	SQLOperationCreate SQLOpType = "CREATE" // CREATE TEMPORARY TABLE
+	SQLOperationDrop   SQLOpType = "DROP"   // DROP TEMPORARY TABLE
+
+	SQLOperationBulkLoad   SQLOpType = "BULK_LOAD"   // COPY ... FROM
+	SQLOperationBulkUnload SQLOpType = "BULK_UNLOAD" // COPY ... TO
+)
+
+// SQLOpSubType refines the SQLOpType of the writes whose operational
+// characteristics differ, e.g. upserts lock the duplicate keys and INSERT ...
+// SELECT reads its source tables.
+type SQLOpSubType string
+
+// String returns the string representation of the SQLOpSubType.
+func (s SQLOpSubType) String() string { return string(s) }
+
+const (
+	SQLSubTypeNone         SQLOpSubType = ""              // not an INSERT statement
+	SQLSubTypeInsert       SQLOpSubType = "INSERT"        // INSERT ... VALUES
+	SQLSubTypeUpsert       SQLOpSubType = "UPSERT"        // ON DUPLICATE KEY UPDATE, ON CONFLICT
+	SQLSubTypeInsertSelect SQLOpSubType = "INSERT_SELECT" // INSERT ... SELECT
+	SQLSubTypeReplace      SQLOpSubType = "REPLACE"       // REPLACE INTO
 )
 
 type TableInfo struct {
 	templatizedSchema    string // templated schema, e.g. db_?
 	templatizedTableName string // templated table name, e.g. tb_?
 
+	server    string // linked server of a four-part name
+	catalog   string // catalog of a three-part name, e.g. the BigQuery project
 	schema    string // original schema, e.g. db_23
 	tableName string // original table name, e.g. tb_10
+
+	tenant    string // tenant of a per-tenant schema, e.g. 123 of tenant_123
+	temporary bool   // temporary table created by a previous statement of the input
 }
 
 // NewTableInfo creates a new TableInfo object.
@@ -59,13 +96,17 @@ func NewTableInfo(args ...string) *TableInfo {
 	)
 }
 
-// TableNameWithSchema returns the table name with schema.
+// TableNameWithSchema returns the table name with schema, qualified by the
+// server and the catalog if any, see QualifiedName.
 // If the schema is empty, it returns the table name without schema.
 //
 // Returns:
 //   - string: the table name with schema, or the table name if the schema is empty
 //   - bool: whether the schema is empty
 func (t *TableInfo) TableNameWithSchema() (string, bool) {
+	if t.server != "" || t.catalog != "" {
+		return t.QualifiedName().String(), t.schema != ""
+	}
 	if t.schema != "" {
 		return t.schema + "." + t.tableName, true
 	}
@@ -87,3 +128,444 @@ func (t *TableInfo) SetTemplatizedTableName(tableName string) { t.templatizedTab
 func (t *TableInfo) TemplatizedTableName() string             { return t.templatizedTableName }
 func (t *TableInfo) SetTemplatizedSchema(schema string)       { t.templatizedSchema = schema }
 func (t *TableInfo) TemplatizedSchema() string                { return t.templatizedSchema }
+
+// Catalog returns the catalog of a three-part table name, e.g. the project of
+// a BigQuery project.dataset.table name, empty if the name has no catalog.
+func (t *TableInfo) Catalog() string { return t.catalog }
+
+// SetCatalog sets the catalog of the table name.
+func (t *TableInfo) SetCatalog(catalog string) { t.catalog = catalog }
+
+// Server returns the linked server of a four-part table name, e.g. the SQL
+// Server server.catalog.schema.table, empty if the name has no server.
+func (t *TableInfo) Server() string { return t.server }
+
+// SetServer sets the linked server of the table name.
+func (t *TableInfo) SetServer(server string) { t.server = server }
+
+// QualifiedName returns all the parts of the table name.
+func (t *TableInfo) QualifiedName() QualifiedName {
+	return QualifiedName{Server: t.server, Catalog: t.catalog, Schema: t.schema, Table: t.tableName}
+}
+
+// QualifiedName is a table name of up to four parts, e.g. the SQL Server
+// server.catalog.schema.table, the BigQuery project.dataset.table or the MySQL
+// schema.table. The leading parts are empty if not qualified.
+type QualifiedName struct {
+	Server  string
+	Catalog string
+	Schema  string
+	Table   string
+}
+
+// Parts returns the parts of the name from the first non-empty one, e.g.
+// [catalog, "", table] for the SQL Server catalog..table.
+func (n QualifiedName) Parts() []string {
+	parts := []string{n.Server, n.Catalog, n.Schema, n.Table}
+	for len(parts) > 1 && parts[0] == "" {
+		parts = parts[1:]
+	}
+
+	return parts
+}
+
+// String returns the dot separated parts of the name, e.g. db..t.
+func (n QualifiedName) String() string {
+	return strings.Join(n.Parts(), ".")
+}
+
+// Tenant returns the tenant of the per-tenant schema mapped to a canonical
+// schema, empty if the schema is not mapped.
+func (t *TableInfo) Tenant() string { return t.tenant }
+
+// SetTenant sets the tenant of the per-tenant schema.
+func (t *TableInfo) SetTenant(tenant string) { t.tenant = tenant }
+
+// Temporary reports whether the table is a temporary table, created by CREATE
+// TEMPORARY TABLE in the same input.
+func (t *TableInfo) Temporary() bool { return t.temporary }
+
+// SetTemporary sets whether the table is a temporary table.
+func (t *TableInfo) SetTemporary(temporary bool) { t.temporary = temporary }
+
+// PlanSummary is the summary of one row of the EXPLAIN output.
+type PlanSummary struct {
+	table      string // table accessed, e.g. users
+	accessType string // access type, e.g. ALL, ref, range, const
+	key        string // index actually used, empty if none
+	rows       int64  // estimated rows to be examined
+}
+
+// NewPlanSummary creates a new PlanSummary object.
+func NewPlanSummary(table, accessType, key string, rows int64) *PlanSummary {
+	return &PlanSummary{
+		table:      table,
+		accessType: accessType,
+		key:        key,
+		rows:       rows,
+	}
+}
+
+func (p *PlanSummary) Table() string      { return p.table }
+func (p *PlanSummary) AccessType() string { return p.accessType }
+func (p *PlanSummary) Key() string        { return p.key }
+func (p *PlanSummary) Rows() int64        { return p.rows }
+
+// PredicateType represents how a column is used by a predicate.
+type PredicateType string
+
+// String returns the string representation of the PredicateType.
+func (p PredicateType) String() string { return string(p) }
+
+const (
+	PredicateEqual PredicateType = "EQUAL" // e.g. a = ?, a IN (?, ?)
+	PredicateRange PredicateType = "RANGE" // e.g. a > ?, a BETWEEN ? AND ?, a LIKE 'x%'
+	PredicateOrder PredicateType = "ORDER" // e.g. ORDER BY a
+)
+
+// Predicate is a column used by an equality, range or order by predicate.
+type Predicate struct {
+	schema string        // original schema of the table, e.g. db_23
+	table  string        // original table name, e.g. tb_10
+	column string        // column name
+	tp     PredicateType // predicate type
+}
+
+// NewPredicate creates a new Predicate object.
+func NewPredicate(schema, table, column string, tp PredicateType) *Predicate {
+	return &Predicate{
+		schema: schema,
+		table:  table,
+		column: column,
+		tp:     tp,
+	}
+}
+
+func (p *Predicate) Schema() string      { return p.schema }
+func (p *Predicate) Table() string       { return p.table }
+func (p *Predicate) Column() string      { return p.column }
+func (p *Predicate) Type() PredicateType { return p.tp }
+
+// IndexRecommendation is a candidate composite index of a table.
+type IndexRecommendation struct {
+	schema  string   // original schema of the table
+	table   string   // original table name
+	columns []string // index columns, in index order
+}
+
+// NewIndexRecommendation creates a new IndexRecommendation object.
+func NewIndexRecommendation(schema, table string, columns []string) *IndexRecommendation {
+	return &IndexRecommendation{
+		schema:  schema,
+		table:   table,
+		columns: columns,
+	}
+}
+
+func (r *IndexRecommendation) Schema() string    { return r.schema }
+func (r *IndexRecommendation) Table() string     { return r.table }
+func (r *IndexRecommendation) Columns() []string { return r.columns }
+
+// StructureMetrics is the structure metrics of a SQL statement.
+type StructureMetrics struct {
+	subqueryCount     int // subqueries in expressions, e.g. IN (SELECT ...), EXISTS (SELECT ...)
+	maxNestingDepth   int // max nesting depth of SELECT, 1 for a SELECT without subquery
+	derivedTableCount int // subqueries in FROM, e.g. FROM (SELECT ...) AS t
+	unionBranchCount  int // SELECT branches of UNION / EXCEPT / INTERSECT, 0 if none
+}
+
+// NewStructureMetrics creates a new StructureMetrics object.
+func NewStructureMetrics(subqueryCount, maxNestingDepth, derivedTableCount, unionBranchCount int) *StructureMetrics {
+	return &StructureMetrics{
+		subqueryCount:     subqueryCount,
+		maxNestingDepth:   maxNestingDepth,
+		derivedTableCount: derivedTableCount,
+		unionBranchCount:  unionBranchCount,
+	}
+}
+
+func (m *StructureMetrics) SubqueryCount() int     { return m.subqueryCount }
+func (m *StructureMetrics) MaxNestingDepth() int   { return m.maxNestingDepth }
+func (m *StructureMetrics) DerivedTableCount() int { return m.derivedTableCount }
+func (m *StructureMetrics) UnionBranchCount() int  { return m.unionBranchCount }
+
+// Selectivity is the coarse selectivity class of the predicates of a
+// statement, the worst class of its query blocks.
+type Selectivity string
+
+const (
+	SelectivityPoint Selectivity = "POINT" // equality predicates, e.g. id = ?, id IN (?, ?)
+	SelectivityRange Selectivity = "RANGE" // range predicates, e.g. a > ?, a LIKE 'x%', LIMIT without ORDER BY
+	SelectivityScan  Selectivity = "SCAN"  // no usable predicate, e.g. no WHERE, a LIKE '%x', f(a) = ?
+)
+
+// CostTier is the coarse cost tier of a statement, in increasing cost.
+type CostTier int
+
+const (
+	CostLow      CostTier = iota // e.g. point lookups
+	CostMedium                   // e.g. range scans, joins of point lookups
+	CostHigh                     // e.g. full scans of a table
+	CostCritical                 // e.g. full scans of joined tables, sorts of full scans
+)
+
+// String returns the name of the tier, e.g. HIGH.
+func (t CostTier) String() string {
+	switch t {
+	case CostLow:
+		return "LOW"
+	case CostMedium:
+		return "MEDIUM"
+	case CostHigh:
+		return "HIGH"
+	case CostCritical:
+		return "CRITICAL"
+	}
+
+	return "UNKNOWN"
+}
+
+// CostEstimate is the static cost estimate of a SQL statement, from its
+// structure only.
+type CostEstimate struct {
+	tables           int         // table references, including derived tables
+	selectivity      Selectivity // selectivity class of the predicates
+	leadingWildcard  bool        // LIKE '%...' or LIKE '_...'
+	functionOnColumn bool        // function of a column in a predicate, e.g. DATE(a) = ?
+	unboundedSort    bool        // ORDER BY without LIMIT
+	score            int         // tables x selectivity x penalties
+	tier             CostTier
+}
+
+// NewCostEstimate creates a new CostEstimate object.
+func NewCostEstimate(tables int, selectivity Selectivity, leadingWildcard, functionOnColumn, unboundedSort bool,
+	score int, tier CostTier,
+) *CostEstimate {
+	return &CostEstimate{
+		tables:           tables,
+		selectivity:      selectivity,
+		leadingWildcard:  leadingWildcard,
+		functionOnColumn: functionOnColumn,
+		unboundedSort:    unboundedSort,
+		score:            score,
+		tier:             tier,
+	}
+}
+
+func (c *CostEstimate) Tables() int              { return c.tables }
+func (c *CostEstimate) Selectivity() Selectivity { return c.selectivity }
+func (c *CostEstimate) LeadingWildcard() bool    { return c.leadingWildcard }
+func (c *CostEstimate) FunctionOnColumn() bool   { return c.functionOnColumn }
+func (c *CostEstimate) UnboundedSort() bool      { return c.unboundedSort }
+func (c *CostEstimate) Score() int               { return c.score }
+func (c *CostEstimate) Tier() CostTier           { return c.tier }
+
+// Wildcard is a wildcard projection of a SELECT statement, * or t.*, with the
+// columns of its modifiers: * EXCEPT (a, b) excludes a and b, and
+// * REPLACE (expr AS c) replaces c.
+type Wildcard struct {
+	schema  string   // schema of the qualifier, e.g. s of s.t.*
+	table   string   // table of the qualifier, empty for *
+	except  []string // columns excluded by EXCEPT
+	replace []string // columns replaced by REPLACE
+}
+
+// NewWildcard creates a new Wildcard object.
+func NewWildcard(schema, table string, except, replace []string) *Wildcard {
+	return &Wildcard{schema: schema, table: table, except: except, replace: replace}
+}
+
+func (w *Wildcard) Schema() string    { return w.schema }
+func (w *Wildcard) Table() string     { return w.table }
+func (w *Wildcard) Except() []string  { return w.except }
+func (w *Wildcard) Replace() []string { return w.replace }
+
+// SelectOptions is the bitset of the options of the SELECT statements of a
+// SQL statement, e.g. SQL_NO_CACHE or SQL_CALC_FOUND_ROWS.
+type SelectOptions uint16
+
+const (
+	SelectHighPriority  SelectOptions = 1 << iota // HIGH_PRIORITY
+	SelectStraightJoin                            // STRAIGHT_JOIN
+	SelectSmallResult                             // SQL_SMALL_RESULT
+	SelectBigResult                               // SQL_BIG_RESULT
+	SelectBufferResult                            // SQL_BUFFER_RESULT
+	SelectNoCache                                 // SQL_NO_CACHE
+	SelectCalcFoundRows                           // SQL_CALC_FOUND_ROWS
+)
+
+// selectOptionNames is the keywords of the options, in the order of the
+// SELECT syntax.
+var selectOptionNames = []struct {
+	opt  SelectOptions
+	name string
+}{
+	{SelectHighPriority, "HIGH_PRIORITY"},
+	{SelectStraightJoin, "STRAIGHT_JOIN"},
+	{SelectSmallResult, "SQL_SMALL_RESULT"},
+	{SelectBigResult, "SQL_BIG_RESULT"},
+	{SelectBufferResult, "SQL_BUFFER_RESULT"},
+	{SelectNoCache, "SQL_NO_CACHE"},
+	{SelectCalcFoundRows, "SQL_CALC_FOUND_ROWS"},
+}
+
+// Has reports whether all the options of opt are set.
+func (o SelectOptions) Has(opt SelectOptions) bool { return o&opt == opt }
+
+// Keywords returns the keywords of the set options, in the order of the
+// SELECT syntax.
+func (o SelectOptions) Keywords() []string {
+	keywords := make([]string, 0, len(selectOptionNames))
+	for _, item := range selectOptionNames {
+		if o.Has(item.opt) {
+			keywords = append(keywords, item.name)
+		}
+	}
+
+	return keywords
+}
+
+// String returns the keywords of the set options separated by spaces, e.g.
+// SQL_NO_CACHE SQL_CALC_FOUND_ROWS.
+func (o SelectOptions) String() string { return strings.Join(o.Keywords(), " ") }
+
+// ColumnUsageType represents the clause a column is used in.
+type ColumnUsageType string
+
+// String returns the string representation of the ColumnUsageType.
+func (c ColumnUsageType) String() string { return string(c) }
+
+const (
+	ColumnFilter     ColumnUsageType = "FILTER"     // e.g. WHERE a = ?, HAVING a > ?
+	ColumnJoin       ColumnUsageType = "JOIN"       // e.g. ON a.id = b.id, USING (id)
+	ColumnProjection ColumnUsageType = "PROJECTION" // e.g. SELECT a
+	ColumnOrder      ColumnUsageType = "ORDER"      // e.g. ORDER BY a
+	ColumnWrite      ColumnUsageType = "WRITE"      // e.g. UPDATE t SET a = ?, INSERT INTO t (a)
+)
+
+// ColumnUsage is a column used by a clause of a statement.
+type ColumnUsage struct {
+	schema string          // schema of the table, may be empty
+	table  string          // original table name
+	column string          // column name
+	usage  ColumnUsageType // clause
+}
+
+// NewColumnUsage creates a new ColumnUsage object.
+func NewColumnUsage(schema, table, column string, usage ColumnUsageType) *ColumnUsage {
+	return &ColumnUsage{schema: schema, table: table, column: column, usage: usage}
+}
+
+func (c *ColumnUsage) Schema() string         { return c.schema }
+func (c *ColumnUsage) Table() string          { return c.table }
+func (c *ColumnUsage) Column() string         { return c.column }
+func (c *ColumnUsage) Usage() ColumnUsageType { return c.usage }
+
+// JoinEdge is an equality join condition between the columns of two tables,
+// e.g. ON u.id = o.user_id, or USING (user_id).
+type JoinEdge struct {
+	left        *TableInfo
+	leftColumn  string
+	right       *TableInfo
+	rightColumn string
+}
+
+// NewJoinEdge creates a new JoinEdge object.
+func NewJoinEdge(left *TableInfo, leftColumn string, right *TableInfo, rightColumn string) *JoinEdge {
+	return &JoinEdge{left: left, leftColumn: leftColumn, right: right, rightColumn: rightColumn}
+}
+
+func (j *JoinEdge) Left() *TableInfo    { return j.left }
+func (j *JoinEdge) LeftColumn() string  { return j.leftColumn }
+func (j *JoinEdge) Right() *TableInfo   { return j.right }
+func (j *JoinEdge) RightColumn() string { return j.rightColumn }
+
+// TimeWindow is the time window queried by the temporal range predicates of a
+// column, e.g. created_at >= NOW() - INTERVAL 7 DAY is the last 7 days.
+type TimeWindow struct {
+	column    string
+	width     time.Duration
+	relative  bool
+	unbounded bool
+}
+
+// NewTimeWindow creates a new TimeWindow object.
+func NewTimeWindow(column string, width time.Duration, relative, unbounded bool) *TimeWindow {
+	return &TimeWindow{column: column, width: width, relative: relative, unbounded: unbounded}
+}
+
+// Column returns the column as written, e.g. o.created_at.
+func (w *TimeWindow) Column() string { return w.column }
+
+// Width returns the width of the window, 0 if unknown, e.g. when the lower
+// bound is a fixed date and there is no upper bound.
+func (w *TimeWindow) Width() time.Duration { return w.width }
+
+// Relative reports whether the lower bound is relative to the current time.
+func (w *TimeWindow) Relative() bool { return w.relative }
+
+// Unbounded reports whether the window has no lower bound, i.e. the whole
+// history before its upper bound is queried.
+func (w *TimeWindow) Unbounded() bool { return w.unbounded }
+
+// String returns the window in a human readable form, e.g. "last 7 days".
+func (w *TimeWindow) String() string {
+	switch {
+	case w.unbounded:
+		return "unbounded"
+	case w.width == 0:
+		return "unknown width"
+	case w.relative:
+		return "last " + formatWidth(w.width)
+	}
+
+	return formatWidth(w.width)
+}
+
+// formatWidth formats the width in days if it is a whole number of days.
+func formatWidth(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day != 0 {
+		return d.String()
+	}
+
+	if n := d / day; n != 1 {
+		return fmt.Sprintf("%d days", n)
+	}
+
+	return "1 day"
+}
+
+// Clause is a clause of a SELECT statement.
+type Clause string
+
+// String returns the string representation of the Clause.
+func (c Clause) String() string { return string(c) }
+
+const (
+	ClauseSelect  Clause = "SELECT" // select list, with DISTINCT and hints
+	ClauseFrom    Clause = "FROM"
+	ClauseWhere   Clause = "WHERE"
+	ClauseGroupBy Clause = "GROUP BY"
+	ClauseHaving  Clause = "HAVING"
+	ClauseOrderBy Clause = "ORDER BY" // ORDER BY or SORT BY
+	ClauseLimit   Clause = "LIMIT"    // LIMIT or OFFSET FETCH
+)
+
+// ClauseSegment is the segment of a template written for a clause, keyword
+// included, e.g. WHERE id eq ?.
+type ClauseSegment struct {
+	clause Clause
+	text   string
+}
+
+// NewClauseSegment creates a ClauseSegment.
+func NewClauseSegment(clause Clause, text string) *ClauseSegment {
+	return &ClauseSegment{clause: clause, text: text}
+}
+
+// Clause returns the clause of the segment.
+func (s *ClauseSegment) Clause() Clause { return s.clause }
+
+// Text returns the text of the segment.
+func (s *ClauseSegment) Text() string { return s.text }
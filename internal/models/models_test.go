@@ -109,4 +109,38 @@ func TestTableInfo_Methods(t *testing.T) {
 	tName, tHasSchema = ti2.TemplatizedTableNameWithSchema()
 	a.False(tHasSchema)
 	a.Equal("{{products}}", tName)
+
+	// Test with catalog
+	ti3 := NewTableInfo("analytics", "events")
+	ti3.SetCatalog("my-project")
+	a.Equal("my-project", ti3.Catalog())
+	name, hasSchema = ti3.TableNameWithSchema()
+	a.True(hasSchema)
+	a.Equal("my-project.analytics.events", name)
+
+	// Test with server and the default schema
+	ti4 := NewTableInfo("", "orders")
+	ti4.SetServer("srv")
+	ti4.SetCatalog("sales")
+	a.Equal(QualifiedName{Server: "srv", Catalog: "sales", Table: "orders"}, ti4.QualifiedName())
+	a.Equal([]string{"srv", "sales", "", "orders"}, ti4.QualifiedName().Parts())
+	name, hasSchema = ti4.TableNameWithSchema()
+	a.False(hasSchema)
+	a.Equal("srv.sales..orders", name)
+	a.Equal("orders", QualifiedName{Table: "orders"}.String())
+}
+
+func TestSelectOptions(t *testing.T) {
+	a := assert.New(t)
+
+	var opts SelectOptions
+	a.Equal("", opts.String())
+	a.Empty(opts.Keywords())
+
+	opts = SelectCalcFoundRows | SelectNoCache
+	a.True(opts.Has(SelectNoCache))
+	a.True(opts.Has(SelectNoCache | SelectCalcFoundRows))
+	a.False(opts.Has(SelectNoCache | SelectStraightJoin))
+	a.Equal([]string{"SQL_NO_CACHE", "SQL_CALC_FOUND_ROWS"}, opts.Keywords())
+	a.Equal("SQL_NO_CACHE SQL_CALC_FOUND_ROWS", opts.String())
 }
@@ -0,0 +1,266 @@
+// Package obfuscate provides a token based SQL obfuscator, which matches the
+// obfuscation performed by common APM agents (e.g. Datadog, New Relic):
+//
+//   - string, number, hex and bit literals are replaced with ?
+//   - IN and VALUES lists of literals are collapsed, e.g. IN (?, ?, ?) -> IN (?),
+//     the other lists, e.g. select lists and function arguments, are kept
+//   - comments are removed, whitespace is collapsed to a single space
+//   - keywords and identifiers are kept as is, no case change
+//
+// Unlike package extract, it does not parse the SQL, so it also works on SQL
+// which can not be parsed.
+package obfuscate
+
+import (
+	"strings"
+)
+
+// Obfuscate obfuscates the SQL string.
+//
+// e.g. SELECT  *  FROM users WHERE id IN (1, 2) AND name = 'kyden' -> SELECT * FROM users WHERE id IN (?) AND name = ?
+func Obfuscate(sql string) string {
+	var (
+		builder strings.Builder
+		space   bool // pending whitespace
+	)
+	builder.Grow(len(sql))
+
+	write := func(s string) {
+		if space && builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		space = false
+		builder.WriteString(s)
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-', c == '#':
+			// -- comment, # comment
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			space = true
+
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 4
+			}
+			space = true
+
+		case c == '\'' || c == '"':
+			i = skipQuoted(sql, i, c)
+			write("?")
+
+		case c == '`':
+			start := i
+			i = skipQuoted(sql, i, c)
+			write(sql[start:i])
+
+		case (c == 'x' || c == 'X' || c == 'b' || c == 'B') && i+1 < len(sql) && sql[i+1] == '\'':
+			// X'1F', B'0101'
+			i = skipQuoted(sql, i+1, '\'')
+			write("?")
+
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			i = skipNumber(sql, i)
+			write("?")
+
+		case isIdentChar(c):
+			start := i
+			for i < len(sql) && isIdentChar(sql[i]) {
+				i++
+			}
+			write(sql[start:i])
+
+		default:
+			write(string(c))
+			i++
+		}
+	}
+
+	return collapseLists(builder.String())
+}
+
+// skipQuoted returns the index after the quoted string starting at sql[start],
+// both doubled quotes and backslash escapes are supported.
+func skipQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+
+			return i + 1
+		}
+	}
+
+	return len(sql)
+}
+
+// skipNumber returns the index after the number starting at sql[start],
+// e.g. 1, 1.5, .5, 1e10, 1.5E-3, 0x1F, 0b01
+func skipNumber(sql string, start int) int {
+	i := start
+	if sql[i] == '0' && i+1 < len(sql) && (sql[i+1] == 'x' || sql[i+1] == 'X' || sql[i+1] == 'b' || sql[i+1] == 'B') {
+		i += 2
+		for i < len(sql) && isIdentChar(sql[i]) {
+			i++
+		}
+
+		return i
+	}
+
+	for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+		i++
+	}
+
+	if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+		j := i + 1
+		if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+			j++
+		}
+
+		if j < len(sql) && isDigit(sql[j]) {
+			i = j
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+		}
+	}
+
+	return i
+}
+
+// collapseLists collapses the IN lists and VALUES rows of placeholders, e.g.
+// IN (?, ?, ?) -> IN (?), VALUES (?, ?), (?, ?) -> VALUES (?), (?)
+func collapseLists(s string) string {
+	var (
+		builder strings.Builder
+		last    int  // s[:last] 已写入 builder
+		values  bool // 位于 VALUES 的行列表中
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '`':
+			i = skipQuoted(s, i, '`') - 1
+			values = false
+
+		case '(':
+			word := prevWord(s, i)
+			values = strings.EqualFold(word, "VALUES") || strings.EqualFold(word, "VALUE") ||
+				values && prevByte(s, i) == ','
+			if !values && !strings.EqualFold(word, "IN") {
+				continue
+			}
+
+			end := closingParen(s, i)
+			if end < 0 {
+				return builder.String() + s[last:]
+			}
+
+			list := s[i+1 : end]
+			if isPlaceholderList(list) {
+				builder.WriteString(s[last : i+1])
+				builder.WriteString(list[:len(list)-len(strings.TrimLeft(list, " "))])
+				builder.WriteString("?")
+				builder.WriteString(list[len(strings.TrimRight(list, " ")):])
+				last = end
+				i = end
+			} else if values { // 跳过整行，继续匹配下一行
+				i = end
+			}
+
+		case ' ', ',':
+
+		default:
+			values = false
+		}
+	}
+	builder.WriteString(s[last:])
+
+	return builder.String()
+}
+
+// closingParen returns the index of the parenthesis closing s[open], -1 if
+// there is none.
+func closingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '`':
+			i = skipQuoted(s, i, '`') - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// isPlaceholderList reports whether s is a list of placeholders, e.g. ?, ?
+func isPlaceholderList(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) != "?" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// prevWord returns the word before s[i], skipping spaces.
+func prevWord(s string, i int) string {
+	end := i
+	for end > 0 && s[end-1] == ' ' {
+		end--
+	}
+
+	start := end
+	for start > 0 && isIdentChar(s[start-1]) {
+		start--
+	}
+
+	return s[start:end]
+}
+
+// prevByte returns the byte before s[i], skipping spaces, 0 if there is none.
+func prevByte(s string, i int) byte {
+	for i > 0 && s[i-1] == ' ' {
+		i--
+	}
+	if i == 0 {
+		return 0
+	}
+
+	return s[i-1]
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
@@ -0,0 +1,35 @@
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscate(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"SELECT * FROM users", "SELECT * FROM users"},
+		{"select *\n  from users_01\twhere id = 1", "select * from users_01 where id = ?"},
+		{"SELECT * FROM users WHERE name = 'ky''den' AND nick = \"it\\'s\"", "SELECT * FROM users WHERE name = ? AND nick = ?"},
+		{"SELECT * FROM users WHERE id IN (1, 2, 3) AND age > -18.5e3", "SELECT * FROM users WHERE id IN (?) AND age > -?"},
+		{"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')", "INSERT INTO t (a, b) VALUES (?), (?)"},
+		{"SELECT a FROM t WHERE b = 0x1F OR c = X'1F' OR d = b'01' OR e = .5", "SELECT a FROM t WHERE b = ? OR c = ? OR d = ? OR e = ?"},
+		{"SELECT `col1` FROM `t2` /* comment */ WHERE a = 1 -- tail\n AND b = 2 # hash", "SELECT `col1` FROM `t2` WHERE a = ? AND b = ?"},
+		{"SELECT COUNT(*) FROM t1 LIMIT 10, 20", "SELECT COUNT(*) FROM t1 LIMIT ?, ?"},
+		{"SELECT 1, 2, COALESCE(a, 3, 4) FROM t WHERE b in ( 5,6 ) AND c NOT IN (SELECT 7, 8)",
+			"SELECT ?, ?, COALESCE(a, ?, ?) FROM t WHERE b in ( ? ) AND c NOT IN (SELECT ?, ?)"},
+		{"INSERT INTO t VALUES (1, NOW()), (2, 3) ON DUPLICATE KEY UPDATE a = VALUES(a), b = (4, 5)",
+			"INSERT INTO t VALUES (?, NOW()), (?) ON DUPLICATE KEY UPDATE a = VALUES(a), b = (?, ?)"},
+		{"SELECT 'unterminated", "SELECT ?"},
+	}
+
+	for _, tt := range tests {
+		as.Equal(tt.expected, Obfuscate(tt.sql), tt.sql)
+	}
+}
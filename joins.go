@@ -0,0 +1,15 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+// Joins returns the join graph of each statement: the equality join
+// conditions of ON a.x = b.y and USING (x), resolved to their original tables.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users u JOIN orders o USING (user_id)")
+//	joins, err := extractor.Joins()
+//	// users.user_id = orders.user_id
+func (e *Extractor) Joins() ([][]*models.JoinEdge, error) {
+	return e.internal().ExtractJoins(e.rawSQL)
+}
@@ -0,0 +1,39 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_Joins(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users u JOIN orders o USING (user_id) WHERE u.id = 1")
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT * FROM users AS u INNER JOIN orders AS o USING (user_id) WHERE u.id eq ?"},
+		extractor.TemplatizedSQL())
+
+	joins, err := extractor.Joins()
+	as.Nil(err)
+	as.Len(joins, 1)
+	as.Len(joins[0], 1)
+	as.Equal("users", joins[0][0].Left().TableName())
+	as.Equal("user_id", joins[0][0].LeftColumn())
+	as.Equal("orders", joins[0][0].Right().TableName())
+	as.Equal("user_id", joins[0][0].RightColumn())
+}
+
+func TestExtractor_Joins_Dialect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 使用 Extractor 的方言选项解析
+	joins, err := NewExtractor("SELECT TOP (10) * FROM users u JOIN orders o ON o.user_id = u.id", WithSQLServer()).Joins()
+	as.Nil(err)
+	as.Len(joins, 1)
+	as.Len(joins[0], 1)
+	as.Equal("orders", joins[0][0].Left().TableName())
+	as.Equal("users", joins[0][0].Right().TableName())
+}
@@ -0,0 +1,379 @@
+// Package library persists the template library, the digests seen by an
+// agent with their templates, first/last seen times and counts, in an
+// embedded bbolt database, so it survives agent restarts.
+//
+// It is a separate package so the bbolt dependency is not linked into
+// programs which only extract, e.g. the WebAssembly build.
+package library
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// templatesBucket is the bucket of the templates, keyed by digest.
+var templatesBucket = []byte("templates")
+
+// DefaultOpenTimeout is how long Open waits for the lock of a database opened
+// by another process.
+const DefaultOpenTimeout = time.Second
+
+// Template is a template of the library.
+type Template struct {
+	Digest         string    `json:"digest"`          // sha256 of the templatized SQL, hex encoded
+	TemplatizedSQL string    `json:"templatized_sql"` // templatized SQL
+	OpType         string    `json:"op_type"`         // operation type, e.g. SELECT
+	Tables         []string  `json:"tables"`          // qualified names of the tables, e.g. shop.users
+	FirstSeen      time.Time `json:"first_seen"`      // when the digest was first recorded
+	LastSeen       time.Time `json:"last_seen"`       // when the digest was last recorded
+	Count          int64     `json:"count"`           // number of statements
+}
+
+// Library is a template library stored in a bbolt database file. It is safe
+// for concurrent use, but a database file can only be opened by one Library.
+type Library struct {
+	mu sync.RWMutex // Compact 替换 db 时持有写锁
+
+	path    string
+	timeout time.Duration
+	now     func() time.Time
+
+	db *bbolt.DB
+}
+
+// Option configures the Library.
+type Option func(*Library)
+
+// WithOpenTimeout sets how long Open waits for the lock of a database opened
+// by another process, 0 waits indefinitely. Default is DefaultOpenTimeout.
+func WithOpenTimeout(d time.Duration) Option {
+	return func(l *Library) { l.timeout = max(d, 0) }
+}
+
+// Open opens the library of the database file at path, creating it if it does
+// not exist.
+func Open(path string, opts ...Option) (*Library, error) {
+	l := &Library{
+		path:    path,
+		timeout: DefaultOpenTimeout,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	db, err := l.open(path)
+	if err != nil {
+		return nil, err
+	}
+	l.db = db
+
+	return l, nil
+}
+
+// open opens the database file at path and creates the templates bucket.
+func (l *Library) open(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0o644, &bbolt.Options{Timeout: l.timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(templatesBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Close closes the database file.
+func (l *Library) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.db.Close()
+}
+
+// Record merges the aggregates, e.g. of Aggregator.Stats before its Reset,
+// into the library in a single transaction. The aggregates of the same digest
+// with different tags are merged. The seen time is the time bucket of the
+// aggregate, or the current time without time buckets.
+func (l *Library) Record(stats []sqlextractor.DigestStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	now := l.now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(templatesBucket)
+
+		for idx := range stats {
+			s := &stats[idx]
+
+			seen := now
+			if !s.Bucket.IsZero() {
+				seen = s.Bucket
+			}
+
+			t, err := get(b, s.Digest)
+			if err != nil {
+				return err
+			}
+			if t == nil {
+				t = &Template{
+					Digest:         s.Digest,
+					TemplatizedSQL: s.TemplatizedSQL,
+					OpType:         s.OpType.String(),
+					Tables:         make([]string, len(s.TableInfos)),
+					FirstSeen:      seen,
+					LastSeen:       seen,
+				}
+				for idx, ti := range s.TableInfos {
+					t.Tables[idx], _ = ti.TableNameWithSchema()
+				}
+			}
+
+			if seen.Before(t.FirstSeen) {
+				t.FirstSeen = seen
+			}
+			if seen.After(t.LastSeen) {
+				t.LastSeen = seen
+			}
+			t.Count += s.Count
+
+			if err := put(b, t); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Get returns the template of the digest, false if it is not in the library.
+func (l *Library) Get(digest string) (Template, bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var t *Template
+	err := l.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		t, err = get(tx.Bucket(templatesBucket), digest)
+		return err
+	})
+	if err != nil || t == nil {
+		return Template{}, false, err
+	}
+
+	return *t, true, nil
+}
+
+// Templates returns the templates of the library, in order of digest.
+func (l *Library) Templates() ([]Template, error) {
+	var templates []Template
+	err := l.each(func(t *Template) error {
+		templates = append(templates, *t)
+		return nil
+	})
+
+	return templates, err
+}
+
+// Len returns the number of templates of the library.
+func (l *Library) Len() (int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var n int
+	err := l.db.View(func(tx *bbolt.Tx) error {
+		n = tx.Bucket(templatesBucket).Stats().KeyN
+		return nil
+	})
+
+	return n, err
+}
+
+// Export writes the templates to w as JSON lines, in order of digest.
+func (l *Library) Export(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := l.each(func(t *Template) error { return enc.Encode(t) }); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Import merges the templates of the JSON lines written by Export, e.g. of
+// another agent, into the library: the counts are added, the first and last
+// seen times widened.
+func (l *Library) Import(r io.Reader) error {
+	var templates []Template
+	dec := json.NewDecoder(r)
+	for {
+		var t Template
+		if err := dec.Decode(&t); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		templates = append(templates, t)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(templatesBucket)
+
+		for idx := range templates {
+			t := &templates[idx]
+
+			old, err := get(b, t.Digest)
+			if err != nil {
+				return err
+			}
+			if old != nil {
+				if old.FirstSeen.Before(t.FirstSeen) {
+					t.FirstSeen = old.FirstSeen
+				}
+				if old.LastSeen.After(t.LastSeen) {
+					t.LastSeen = old.LastSeen
+				}
+				t.Count += old.Count
+			}
+
+			if err := put(b, t); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Compact removes the templates last seen before the time, a zero time keeps
+// all of them, and rewrites the database file to reclaim the space of the
+// removed and updated templates. It returns the number of removed templates.
+func (l *Library) Compact(before time.Time) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var removed int
+	if !before.IsZero() {
+		err := l.db.Update(func(tx *bbolt.Tx) error {
+			c := tx.Bucket(templatesBucket).Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				var t Template
+				if err := json.Unmarshal(v, &t); err != nil {
+					return err
+				}
+				if !t.LastSeen.Before(before) {
+					continue
+				}
+
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				removed++
+			}
+
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// bbolt 不会缩小文件，复制到新文件后替换
+	tmp := l.path + ".compact"
+	_ = os.Remove(tmp)
+	dst, err := bbolt.Open(tmp, 0o644, &bbolt.Options{Timeout: l.timeout})
+	if err != nil {
+		return removed, err
+	}
+	if err := bbolt.Compact(dst, l.db, 0); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return removed, err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return removed, err
+	}
+
+	if err := l.db.Close(); err != nil {
+		return removed, err
+	}
+	renameErr := os.Rename(tmp, l.path)
+	if renameErr != nil {
+		_ = os.Remove(tmp)
+	}
+
+	// 替换失败时重新打开原文件
+	db, err := l.open(l.path)
+	if err != nil {
+		return removed, err
+	}
+	l.db = db
+
+	return removed, renameErr
+}
+
+// each calls fn with the templates of the library, in order of digest.
+func (l *Library) each(fn func(t *Template) error) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(templatesBucket).ForEach(func(_, v []byte) error {
+			var t Template
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+
+			return fn(&t)
+		})
+	})
+}
+
+// get returns the template of the digest, nil if it is not in the bucket.
+func get(b *bbolt.Bucket, digest string) (*Template, error) {
+	v := b.Get([]byte(digest))
+	if v == nil {
+		return nil, nil
+	}
+
+	var t Template
+	if err := json.Unmarshal(v, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// put stores the template by its digest.
+func put(b *bbolt.Bucket, t *Template) error {
+	v, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte(t.Digest), v)
+}
@@ -0,0 +1,122 @@
+package library
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+func TestLibrary(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	path := filepath.Join(t.TempDir(), "library.db")
+
+	l, err := Open(path)
+	as.Nil(err)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	a := sqlextractor.NewAggregator()
+	as.Nil(a.AddSQL("SELECT * FROM shop.users WHERE id = 1", sqlextractor.Tags{"service": "billing"}))
+	as.Nil(a.AddSQL("SELECT * FROM shop.users WHERE id = 2", sqlextractor.Tags{"service": "api"}))
+	as.Nil(a.AddSQL("DELETE FROM orders WHERE id = 1", nil))
+	stats := a.Stats()
+	as.Nil(l.Record(stats))
+
+	// 重启后累加
+	as.Nil(l.Close())
+	l, err = Open(path)
+	as.Nil(err)
+	defer l.Close()
+	later := now.Add(time.Hour)
+	l.now = func() time.Time { return later }
+	as.Nil(l.Record(stats[:1]))
+
+	users, ok, err := l.Get(stats[0].Digest)
+	as.Nil(err)
+	as.True(ok)
+	as.Equal(Template{
+		Digest:         stats[0].Digest,
+		TemplatizedSQL: "SELECT * FROM shop.users WHERE id eq ?",
+		OpType:         "SELECT",
+		Tables:         []string{"shop.users"},
+		FirstSeen:      now,
+		LastSeen:       later,
+		Count:          3,
+	}, users)
+
+	_, ok, err = l.Get("unknown")
+	as.Nil(err)
+	as.False(ok)
+
+	n, err := l.Len()
+	as.Nil(err)
+	as.Equal(2, n)
+
+	// 导出后导入到另一个库
+	var buf bytes.Buffer
+	as.Nil(l.Export(&buf))
+	as.Equal(2, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	other, err := Open(filepath.Join(t.TempDir(), "other.db"))
+	as.Nil(err)
+	defer other.Close()
+	as.Nil(other.Import(bytes.NewReader(buf.Bytes())))
+	as.Nil(other.Import(bytes.NewReader(buf.Bytes())))
+	templates, err := other.Templates()
+	as.Nil(err)
+	as.Equal(2, len(templates))
+	for _, tmpl := range templates {
+		if tmpl.Digest == users.Digest {
+			as.Equal(int64(6), tmpl.Count)
+			as.Equal(now, tmpl.FirstSeen.UTC())
+		}
+	}
+
+	// 删除过期模板
+	removed, err := l.Compact(later)
+	as.Nil(err)
+	as.Equal(1, removed)
+	templates, err = l.Templates()
+	as.Nil(err)
+	as.Equal(1, len(templates))
+	as.Equal(users.Digest, templates[0].Digest)
+
+	removed, err = l.Compact(time.Time{})
+	as.Nil(err)
+	as.Equal(0, removed)
+	n, err = l.Len()
+	as.Nil(err)
+	as.Equal(1, n)
+}
+
+func TestLibrary_TimeBucket(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	l, err := Open(filepath.Join(t.TempDir(), "library.db"))
+	as.Nil(err)
+	defer l.Close()
+
+	a := sqlextractor.NewAggregator()
+	as.Nil(a.AddSQL("SELECT 1", nil))
+	stats := a.Stats()
+
+	bucket := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	stats[0].Bucket = bucket.Add(time.Minute)
+	as.Nil(l.Record(stats))
+	stats[0].Bucket = bucket
+	as.Nil(l.Record(stats))
+
+	tmpl, ok, err := l.Get(stats[0].Digest)
+	as.Nil(err)
+	as.True(ok)
+	as.Equal(bucket, tmpl.FirstSeen)
+	as.Equal(bucket.Add(time.Minute), tmpl.LastSeen)
+	as.Equal(int64(2), tmpl.Count)
+}
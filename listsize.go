@@ -0,0 +1,210 @@
+package sqlextractor
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// DefaultListSizeBounds are the default upper bounds of the buckets of the
+// list size histograms.
+var DefaultListSizeBounds = []int{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// SizeHistogram is the distribution of the sizes of value lists, see
+// WithListSizeHistograms.
+type SizeHistogram struct {
+	Bounds []int   `json:"bounds"` // inclusive upper bounds of the buckets, ascending
+	Counts []int64 `json:"counts"` // observations per bucket, the last one is above the last bound
+	Count  int64   `json:"count"`  // number of observations
+	Sum    int64   `json:"sum"`    // sum of the sizes
+	Max    int     `json:"max"`    // largest size
+}
+
+// newSizeHistogram creates an empty histogram with the bounds.
+func newSizeHistogram(bounds []int) *SizeHistogram {
+	return &SizeHistogram{Bounds: bounds, Counts: make([]int64, len(bounds)+1)}
+}
+
+// observe adds a size to the histogram.
+func (h *SizeHistogram) observe(size int) {
+	idx, _ := slices.BinarySearch(h.Bounds, size)
+	h.Counts[idx]++
+	h.Count++
+	h.Sum += int64(size)
+	h.Max = max(h.Max, size)
+}
+
+// merge adds the observations of o, of the same bounds, to the histogram.
+func (h *SizeHistogram) merge(o *SizeHistogram) {
+	for idx := range o.Counts {
+		h.Counts[idx] += o.Counts[idx]
+	}
+	h.Count += o.Count
+	h.Sum += o.Sum
+	h.Max = max(h.Max, o.Max)
+}
+
+// clone returns a copy of the histogram, nil if h is nil.
+func (h *SizeHistogram) clone() *SizeHistogram {
+	if h == nil {
+		return nil
+	}
+
+	c := *h
+	c.Counts = slices.Clone(h.Counts)
+
+	return &c
+}
+
+// WithListSizeHistograms tracks the distributions of the IN list lengths and
+// of the INSERT VALUES row counts of each aggregate, see
+// DigestStats.InListSizes and ValuesRows, and WriteMetrics, since they drive
+// plan instability and packet sizes. The bounds are the inclusive upper
+// bounds of the buckets, DefaultListSizeBounds if empty.
+//
+// The sizes are those of the RawSQL of the statement results, which is parsed
+// again. The lists of different sizes have different digests unless the
+// templates collapse them, e.g. with WithPreset(PresetMySQLDigest).
+func WithListSizeHistograms(bounds ...int) AggregatorOption {
+	return func(a *Aggregator) {
+		if len(bounds) == 0 {
+			bounds = DefaultListSizeBounds
+		}
+
+		a.listBounds = slices.Compact(slices.Sorted(slices.Values(bounds)))
+	}
+}
+
+// listSizesOf returns the list sizes of the statement of the result, false if
+// they are unknown.
+func listSizesOf(r StatementResult) (extract.ListSizes, bool) {
+	if r.RawSQL == "" {
+		return extract.ListSizes{}, false
+	}
+
+	sizes, err := defaultExtractor.ExtractListSizes(r.RawSQL)
+	if err != nil || len(sizes) == 0 {
+		return extract.ListSizes{}, false
+	}
+
+	// RawSQL 是单条语句，或者是整个 SQL（e.g. tap 的多语句查询）
+	switch {
+	case len(sizes) == 1:
+		return sizes[0], true
+	case r.Index < len(sizes):
+		return sizes[r.Index], true
+	}
+
+	return extract.ListSizes{}, false
+}
+
+// addListSizes adds the list sizes to the histograms of an aggregate. The
+// caller holds the lock.
+func (a *Aggregator) addListSizes(s *DigestStats, sizes extract.ListSizes) {
+	for _, size := range sizes.InLists {
+		if s.InListSizes == nil {
+			s.InListSizes = newSizeHistogram(a.listBounds)
+		}
+		s.InListSizes.observe(size)
+	}
+
+	if sizes.Rows > 0 {
+		if s.ValuesRows == nil {
+			s.ValuesRows = newSizeHistogram(a.listBounds)
+		}
+		s.ValuesRows.observe(sizes.Rows)
+	}
+}
+
+// WriteMetrics writes the list size histograms of the aggregates in the
+// OpenMetrics text format, for Prometheus, labeled with the digest. The
+// aggregates of the same digest are merged whatever their tags and buckets.
+// Nothing but the EOF marker is written without WithListSizeHistograms.
+//
+// Example:
+//
+//	http.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+//	  w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+//	  _ = agg.WriteMetrics(w)
+//	})
+func (a *Aggregator) WriteMetrics(w io.Writer) error {
+	type digestHistograms struct {
+		digest        string
+		inLists, rows *SizeHistogram
+	}
+
+	a.mu.Lock()
+	var (
+		histograms []*digestHistograms
+		byDigest   = make(map[string]*digestHistograms)
+	)
+	for _, s := range a.order {
+		if s.InListSizes == nil && s.ValuesRows == nil {
+			continue
+		}
+
+		h, ok := byDigest[s.Digest]
+		if !ok {
+			h = &digestHistograms{digest: s.Digest}
+			byDigest[s.Digest] = h
+			histograms = append(histograms, h)
+		}
+		h.inLists = mergeHistogram(h.inLists, s.InListSizes)
+		h.rows = mergeHistogram(h.rows, s.ValuesRows)
+	}
+	a.mu.Unlock()
+
+	slices.SortFunc(histograms, func(x, y *digestHistograms) int { return cmp.Compare(x.digest, y.digest) })
+
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	writeHistogram := func(name, help string, get func(*digestHistograms) *SizeHistogram) {
+		printf("# TYPE %s histogram\n# HELP %s %s\n", name, name, help)
+		for _, dh := range histograms {
+			h := get(dh)
+			if h == nil {
+				continue
+			}
+
+			var cumulative int64
+			for idx, bound := range h.Bounds {
+				cumulative += h.Counts[idx]
+				printf("%s_bucket{digest=%q,le=\"%d\"} %d\n", name, dh.digest, bound, cumulative)
+			}
+			printf("%s_bucket{digest=%q,le=\"+Inf\"} %d\n", name, dh.digest, h.Count)
+			printf("%s_sum{digest=%q} %d\n", name, dh.digest, h.Sum)
+			printf("%s_count{digest=%q} %d\n", name, dh.digest, h.Count)
+		}
+	}
+
+	if len(histograms) > 0 {
+		writeHistogram("sql_extractor_in_list_size", "Items of the IN lists of values.",
+			func(h *digestHistograms) *SizeHistogram { return h.inLists })
+		writeHistogram("sql_extractor_values_rows", "Rows of the INSERT VALUES.",
+			func(h *digestHistograms) *SizeHistogram { return h.rows })
+	}
+	printf("# EOF\n")
+
+	return err
+}
+
+// mergeHistogram returns the merge of the histograms, which may be nil.
+func mergeHistogram(h, o *SizeHistogram) *SizeHistogram {
+	if o == nil {
+		return h
+	}
+	if h == nil {
+		return o.clone()
+	}
+
+	h.merge(o)
+
+	return h
+}
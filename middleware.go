@@ -0,0 +1,136 @@
+package sqlextractor
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/charset"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// StatementResult is the extraction result of a single statement, passed
+// through the middleware chain after templatization.
+type StatementResult struct {
+	Index          int                 // 0-based index of the statement in the raw SQL
+	TemplatizedSQL string              // templatized SQL
+	TableInfos     []*models.TableInfo // table infos: Schema, Tablename
+	Params         []any               // parameters
+	OpType         models.SQLOpType    // operation type
+	Tags           Tags                // caller metadata, see SetTags
+	Hash           string              // hash of the templatized SQL, only set by Results
+
+	// RawSQL is the original statement, and Err its extraction error. They are
+	// only set by ExtractStream.
+	RawSQL string
+	Err    error
+
+	// Drop removes the statement from the results. Note that the results are
+	// no longer aligned with the raw statements, so Explain reports a mismatch.
+	Drop bool
+}
+
+// Middleware post-processes the result of a statement, e.g. custom
+// normalization, annotation or filtering.
+type Middleware func(StatementResult) StatementResult
+
+// Use appends middleware to the chain, which is run in order on each statement
+// by Extract, before the hash is computed.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; SHOW TABLES")
+//	extractor.Use(func(r StatementResult) StatementResult {
+//	  r.Drop = r.OpType == models.SQLOperationShow
+//	  return r
+//	})
+func (e *Extractor) Use(mw ...Middleware) {
+	e.middleware = append(e.middleware, mw...)
+}
+
+// applyMiddleware runs the middleware chain on each statement.
+func (e *Extractor) applyMiddleware() {
+	if len(e.middleware) == 0 {
+		return
+	}
+
+	n := 0
+	for idx := range e.templatedSQL {
+		result := StatementResult{
+			Index:          idx,
+			TemplatizedSQL: e.templatedSQL[idx],
+			TableInfos:     e.tableInfos[idx],
+			Params:         e.params[idx],
+			OpType:         e.opType[idx],
+			Tags:           e.tags,
+		}
+		for _, mw := range e.middleware {
+			if result = mw(result); result.Drop {
+				break
+			}
+		}
+
+		if result.Drop {
+			continue
+		}
+
+		e.templatedSQL[n] = result.TemplatizedSQL
+		e.tableInfos[n] = result.TableInfos
+		e.params[n] = result.Params
+		e.opType[n] = result.OpType
+		n++
+	}
+
+	e.templatedSQL = e.templatedSQL[:n]
+	e.tableInfos = e.tableInfos[:n]
+	e.params = e.params[:n]
+	e.opType = e.opType[:n]
+}
+
+// DefaultSchema returns the middleware setting the schema of the unqualified
+// tables, e.g. the current database of the connection of a logged statement
+// (USE shop), so the tables of the statements of different databases are not
+// mixed up. The templates are unchanged.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM orders")
+//	extractor.Use(DefaultSchema("shop"))
+//	_ = extractor.Extract() // tables: shop.orders
+func DefaultSchema(schema string) Middleware {
+	return func(r StatementResult) StatementResult {
+		r.TableInfos = withDefaultSchema(r.TableInfos, schema)
+		return r
+	}
+}
+
+// ConnectionCharset returns the middleware decoding the string params from
+// the character set of the connection, e.g. SET NAMES gbk, to UTF-8, so the
+// params of logged statements are readable. The params of UTF-8, latin1 and
+// binary connections, and the params which are not valid in the character
+// set, are unchanged.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE name = '\xd6\xd0\xce\xc4'")
+//	extractor.Use(ConnectionCharset("gbk"))
+//	_ = extractor.Extract() // params: [中文]
+func ConnectionCharset(name string) Middleware {
+	enc := charset.FindEncoding(strings.ToLower(name))
+	return func(r StatementResult) StatementResult {
+		if enc.Tp() == charset.EncodingTpUTF8 || enc.Tp() == charset.EncodingTpBin {
+			return r
+		}
+
+		for idx, param := range r.Params {
+			s, ok := param.(string)
+			if !ok {
+				continue
+			}
+			if decoded, err := enc.Transform(nil, []byte(s), charset.OpDecode); err == nil {
+				r.Params[idx] = string(decoded)
+			}
+		}
+
+		return r
+	}
+}
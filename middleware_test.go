@@ -0,0 +1,89 @@
+package sqlextractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestExtractor_Use(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var order []string
+	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; SHOW TABLES; DELETE FROM orders WHERE id = 2")
+	extractor.Use(
+		func(r StatementResult) StatementResult {
+			order = append(order, "filter")
+			r.Drop = r.OpType == models.SQLOperationShow
+			return r
+		},
+		func(r StatementResult) StatementResult {
+			order = append(order, "lower")
+			r.TemplatizedSQL = strings.ToLower(r.TemplatizedSQL)
+			return r
+		},
+	)
+	extractor.Use(func(r StatementResult) StatementResult {
+		order = append(order, "annotate")
+		r.TemplatizedSQL += " /* stmt */"
+		return r
+	})
+
+	as.Nil(extractor.Extract())
+	as.Equal([]string{
+		"select * from users where id eq ? /* stmt */",
+		"delete from orders where id eq ? /* stmt */",
+	}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{int64(1)}, {int64(2)}}, extractor.Params())
+	as.Equal([]models.SQLOpType{models.SQLOperationSelect, models.SQLOperationDelete}, extractor.OpType())
+	as.Equal(2, len(extractor.TableInfos()))
+	as.Equal("orders", extractor.TableInfos()[1][0].TableName())
+
+	// dropped statements skip the rest of the chain
+	as.Equal([]string{"filter", "lower", "annotate", "filter", "filter", "lower", "annotate"}, order)
+
+	// hash is computed after the middleware
+	as.Equal(defaultHash([]byte("select * from users where id eq ? /* stmt */")), extractor.TemplatizedSQLHash()[0])
+}
+
+func TestDefaultSchema(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM orders o JOIN billing.invoices i ON o.id = i.order_id")
+	extractor.Use(DefaultSchema("shop"))
+	as.Nil(extractor.Extract())
+
+	tables := extractor.TableInfos()[0]
+	as.Equal("shop", tables[0].Schema())
+	as.Equal("", tables[0].TemplatizedSchema())
+	as.Equal("billing", tables[1].Schema())
+	as.Equal("SELECT * FROM orders AS o INNER JOIN billing.invoices AS i ON o.id eq i.order_id", extractor.TemplatizedSQL()[0])
+
+	aggregator := NewAggregator()
+	as.Nil(aggregator.AddSQLWith("SELECT * FROM orders WHERE id = 1", nil, DefaultSchema("shop")))
+	as.Equal("shop", aggregator.Stats()[0].TableInfos[0].Schema())
+}
+
+func TestConnectionCharset(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT * FROM users WHERE name = '\xd6\xd0\xce\xc4' AND id = 1 AND note = 'ok'"
+	extractor := NewExtractor(sql)
+	extractor.Use(ConnectionCharset("GBK"))
+	as.Nil(extractor.Extract())
+	as.Equal([][]any{{"中文", int64(1), "ok"}}, extractor.Params())
+
+	// UTF-8 连接和未知字符集不转换
+	for _, name := range []string{"utf8mb4", "latin1", "unknown"} {
+		extractor = NewExtractor(sql)
+		extractor.Use(ConnectionCharset(name))
+		as.Nil(extractor.Extract())
+		as.Equal([][]any{{"\xd6\xd0\xce\xc4", int64(1), "ok"}}, extractor.Params(), name)
+	}
+}
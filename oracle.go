@@ -0,0 +1,11 @@
+package sqlextractor
+
+// WithOracle supports the Oracle syntax the MySQL parser rejects, e.g. for a
+// Dialect wrapping the Extractor: the outer join operators (+), whose columns
+// are reported as JOIN columns by ExtractColumns, and the OFFSET FETCH
+// clauses. The optimizer hints are kept, as WithOptimizerHints.
+//
+// e.g. SELECT /*+ INDEX(o idx_a) */ o.id FROM orders o, customers c WHERE o.cid = c.id(+) -> SELECT /*+ INDEX(o idx_a) */ o.id FROM orders AS o CROSS JOIN customers AS c WHERE o.cid eq c.id(+)
+func WithOracle() ExtractorOption {
+	return func(e *Extractor) { e.options.oracle, e.options.optimizerHints = true, true }
+}
@@ -0,0 +1,115 @@
+package sqlextractor
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// maxParamValues is the maximum number of distinct values tracked per
+// placeholder, so statements with unique values, e.g. primary keys, do not
+// grow the memory unbounded.
+const maxParamValues = 1000
+
+// ParamValue is a masked value of a placeholder, with its count.
+type ParamValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// ParamStats is the statistics of the values of a placeholder of a template,
+// see WithParamStats.
+type ParamStats struct {
+	Index int `json:"index"` // index of the placeholder in the params
+	// Distinct is the number of distinct masked values, a lower bound when
+	// Truncated: the values beyond the first 1000 distinct ones are not
+	// tracked.
+	Distinct  int          `json:"distinct"`
+	Truncated bool         `json:"truncated,omitempty"`
+	Top       []ParamValue `json:"top"` // most frequent values, by count then value
+}
+
+// WithParamStats tracks the values of each placeholder of the templates, see
+// DigestStats.Params: their cardinality and the topK most frequent ones, so
+// analysts can see e.g. that param 2 of a hot template is almost always the
+// same tenant. The values are masked by mask before they are tracked, by
+// default they are replaced by a hash, which keeps the statistics without
+// storing sensitive values. topK <= 0 disables the statistics, the default.
+//
+// Example:
+//
+//	a := NewAggregator(WithParamStats(5, func(param any) string { return fmt.Sprint(param) }))
+func WithParamStats(topK int, mask func(param any) string) AggregatorOption {
+	return func(a *Aggregator) {
+		a.paramTopK, a.paramMask = max(topK, 0), mask
+		if a.paramMask == nil {
+			a.paramMask = hashParam
+		}
+	}
+}
+
+// hashParam masks the values but NULL by a hash.
+func hashParam(param any) string {
+	if param == nil {
+		return "NULL"
+	}
+
+	return defaultHash([]byte(fmt.Sprint(param)))[:16]
+}
+
+// paramSlot is the values of a placeholder of an aggregate.
+type paramSlot struct {
+	counts    map[string]int64
+	truncated bool
+}
+
+// addParams counts the masked values of the placeholders of an aggregate.
+// The caller holds the lock.
+func (a *Aggregator) addParams(s *DigestStats, masked []string) {
+	slots := a.paramSlots[s]
+	for len(slots) < len(masked) {
+		slots = append(slots, &paramSlot{counts: make(map[string]int64)})
+	}
+	a.paramSlots[s] = slots
+
+	for idx, value := range masked {
+		slot := slots[idx]
+		if _, ok := slot.counts[value]; !ok && len(slot.counts) >= maxParamValues {
+			slot.truncated = true
+			continue
+		}
+		slot.counts[value]++
+	}
+}
+
+// paramStats returns the statistics of the placeholders of an aggregate. The
+// caller holds the lock.
+func (a *Aggregator) paramStats(s *DigestStats) []ParamStats {
+	slots := a.paramSlots[s]
+	if len(slots) == 0 {
+		return nil
+	}
+
+	stats := make([]ParamStats, len(slots))
+	for idx, slot := range slots {
+		top := make([]ParamValue, 0, len(slot.counts))
+		for value, count := range slot.counts {
+			top = append(top, ParamValue{Value: value, Count: count})
+		}
+		slices.SortFunc(top, func(x, y ParamValue) int {
+			if c := cmp.Compare(y.Count, x.Count); c != 0 {
+				return c
+			}
+			return cmp.Compare(x.Value, y.Value)
+		})
+
+		stats[idx] = ParamStats{
+			Index:     idx,
+			Distinct:  len(slot.counts),
+			Truncated: slot.truncated,
+			Top:       top[:min(len(top), a.paramTopK)],
+		}
+	}
+
+	return stats
+}
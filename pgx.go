@@ -0,0 +1,66 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// pgxExtractor renders $1..$n placeholders, standard SQL operators, LIMIT
+// OFFSET, original table names and double quoted identifiers.
+var pgxExtractor = packageExtractor(
+	extract.WithPlaceholder(func(n int) string { return "$" + strconv.Itoa(n) }),
+	extract.WithStandardOperators(),
+	extract.WithLimitOffset(),
+	extract.WithRawTableNames(),
+	extract.WithIdentifierQuoting(extract.QuotePostgres),
+)
+
+// PgxTemplates returns the template of each statement with $1..$n placeholders,
+// standard SQL operators, LIMIT count OFFSET offset and original table names,
+// and the args converted to pgx-compatible types, so the templates can be
+// executed directly via pgx. The identifiers PostgreSQL needs quoted, e.g.
+// reserved keywords and mixed case names, are double quoted. The SQL with
+// MySQL-only constructs PostgreSQL rejects, e.g. INTERVAL 1 DAY, ON DUPLICATE
+// KEY UPDATE, <=> or !, is an error, see Translate.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id = 1 AND name = 'kyden'")
+//	templates, args, err := extractor.PgxTemplates()
+//	// templates: ["SELECT * FROM users WHERE id = $1 AND name = $2"]
+//	// args:      [[1, "kyden"]]
+//	rows, err := conn.Query(ctx, templates[0], args[0]...)
+func (e *Extractor) PgxTemplates() ([]string, [][]any, error) {
+	templates, _, params, _, err := pgxExtractor.Extract(e.rawSQL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unsupported, err := pgxExtractor.Unsupported(e.rawSQL, DialectPostgreSQL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(unsupported) > 0 {
+		return nil, nil, fmt.Errorf("unsupported by %s: %s", DialectPostgreSQL, strings.Join(unsupported, ", "))
+	}
+
+	args := make([][]any, len(params))
+	for idx := range params {
+		args[idx] = make([]any, len(params[idx]))
+		for jdx := range params[idx] {
+			args[idx][jdx] = pgxArg(params[idx][jdx])
+		}
+	}
+
+	return templates, args, nil
+}
+
+// pgxArg converts the parameter to a pgx-compatible type, see driverValue.
+// The parameters which can not be converted are passed as is.
+func pgxArg(param any) any {
+	val, _ := driverValue(param)
+	return val
+}
@@ -0,0 +1,60 @@
+package sqlextractor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_PgxTemplates(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users_01 WHERE id IN (1, 2) AND name != 'kyden' OR age >= 1.5 LIMIT 10; UPDATE users SET age = 18 WHERE id = 3")
+	templates, args, err := extractor.PgxTemplates()
+	as.Nil(err)
+	as.Equal([]string{
+		"SELECT * FROM users_01 WHERE id IN ($1, $2) AND name <> $3 OR age >= $4 LIMIT $5",
+		"UPDATE users SET age = $1 WHERE id = $2",
+	}, templates)
+	as.Equal([][]any{
+		{int64(1), int64(2), "kyden", "1.5", int64(10)},
+		{int64(18), int64(3)},
+	}, args)
+
+	// LIMIT offset, count
+	templates, args, err = NewExtractor("SELECT * FROM users ORDER BY id LIMIT 20, 10").PgxTemplates()
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM users ORDER BY id LIMIT $1 OFFSET $2"}, templates)
+	as.Equal([][]any{{int64(10), int64(20)}}, args)
+
+	// PostgreSQL 不支持的 MySQL 语法
+	_, _, err = NewExtractor("SELECT * FROM users WHERE created_at > NOW() - INTERVAL 1 DAY").PgxTemplates()
+	as.EqualError(err, "unsupported by postgresql: INTERVAL")
+	_, _, err = NewExtractor("INSERT INTO users (id) VALUES (1) ON DUPLICATE KEY UPDATE id = 1").PgxTemplates()
+	as.EqualError(err, "unsupported by postgresql: ON DUPLICATE KEY UPDATE")
+	_, _, err = NewExtractor("SELECT * FROM users WHERE !deleted").PgxTemplates()
+	as.EqualError(err, "unsupported by postgresql: !")
+
+	// PostgreSQL 需要引号的标识符：保留字、大小写混合和特殊字符
+	templates, _, err = NewExtractor("SELECT `order`, userId, u.`user` FROM `My Table` AS u WHERE `select` = 1 AND deleted = 0").PgxTemplates()
+	as.Nil(err)
+	as.Equal([]string{`SELECT "order", "userId", u."user" FROM "My Table" AS u WHERE "select" = $1 AND deleted = $2`}, templates)
+
+	_, _, err = NewExtractor("").PgxTemplates()
+	as.NotNil(err)
+}
+
+func TestPgxArg(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	as.Equal(int64(1), pgxArg(uint64(1)))
+	as.Equal("18446744073709551615", pgxArg(uint64(math.MaxUint64)))
+	as.Equal(float64(1.5), pgxArg(float32(1.5)))
+	as.Equal([]byte{0x1f}, pgxArg(test_driver.BinaryLiteral{0x1f}))
+	as.Equal("kyden", pgxArg("kyden"))
+	as.Nil(pgxArg(nil))
+}
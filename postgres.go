@@ -0,0 +1,13 @@
+package sqlextractor
+
+// WithPostgres supports the PostgreSQL syntax the MySQL parser rejects or
+// reads differently, e.g. for a Dialect wrapping the Extractor: INSERT ... ON
+// CONFLICT, COPY and the psql \copy meta-commands, the array syntax ANY(...),
+// ARRAY[...], @> and <@, the dollar-quoted strings $$...$$ and the escape
+// strings E'...', and the OFFSET FETCH clauses. Without it, $tag$ is a MySQL
+// identifier and kept as is.
+//
+// e.g. INSERT INTO t (id) VALUES (1) ON CONFLICT (id) DO NOTHING -> INSERT INTO t (id) VALUES (?) ON CONFLICT (id) DO NOTHING
+func WithPostgres() ExtractorOption {
+	return func(e *Extractor) { e.options.postgres = true }
+}
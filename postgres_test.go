@@ -0,0 +1,27 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithPostgres(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "INSERT INTO notes (id, body) VALUES (1, $$it's done$$) ON CONFLICT (id) DO UPDATE SET body = EXCLUDED.body"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithPostgres())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"INSERT INTO notes (id, body) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET body eq EXCLUDED.body"},
+		extractor.TemplatizedSQL())
+	as.Equal([][]any{{int64(1), "it's done"}}, extractor.Params())
+
+	// MySQL 中 $tag$ 是标识符
+	extractor = NewExtractor("SELECT $tag$ FROM t")
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT $tag$ FROM t"}, extractor.TemplatizedSQL())
+}
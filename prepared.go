@@ -0,0 +1,77 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DigestVersion is the version of the templates and their digests. It is
+// bumped when the template of the same SQL changes, e.g. a literal is
+// normalized differently, so keys of an older version are not reused.
+const DigestVersion = 1
+
+// CacheKey returns the key of each statement for client-side prepared
+// statement caches, the dialect, the DigestVersion and the hash of the
+// template, e.g. mysql:v1:9f86d0..., so statements of the same template share
+// a prepared statement, whatever their params. It should be called after
+// Extract, the hash is that of TemplatizedSQLHash with the default function.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id = 1")
+//	_ = extractor.Extract()
+//	key := extractor.CacheKey()[0]
+//	stmt, ok := cache[key]
+func (e *Extractor) CacheKey() []string {
+	hash := e.TemplatizedSQLHash()
+	prefix := e.Dialect() + ":v" + strconv.Itoa(DigestVersion) + ":"
+
+	keys := make([]string, len(hash))
+	for idx := range hash {
+		keys[idx] = prefix + hash[idx]
+	}
+
+	return keys
+}
+
+// ParamNames returns the names of the params of each statement, in positional
+// binding order of the ? placeholders of the templates: the names of
+// SqlxNamed, the columns the params are compared with or assigned to,
+// suffixed with _2, _3, ... when a column is used more than once, or p<N> when
+// there is no such column.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE age > 18 AND id IN (1, 2) LIMIT 10")
+//	names, err := extractor.ParamNames()
+//	// names: [["age" "id" "id_2" "limit"]]
+func (e *Extractor) ParamNames() ([][]string, error) {
+	// 与 Params 使用相同的选项，参数个数上限等选项下名称与参数一一对应
+	opts := e.options
+	opts.named = true
+
+	return sharedExtractor(opts).ParamNames(e.rawSQL)
+}
+
+// BindNamed returns the named args, e.g. of SqlxNamed, in the positional
+// binding order of the names, e.g. of ParamNames, so they can be passed to a
+// prepared statement of the template. It returns an error if an arg is
+// missing.
+//
+// Example:
+//
+//	args, err := sqlextractor.BindNamed([]string{"age", "id"}, map[string]any{"id": 1, "age": 18})
+//	// args: [18 1]
+//	rows, err := stmt.QueryContext(ctx, args...)
+func BindNamed(names []string, args map[string]any) ([]any, error) {
+	bound := make([]any, len(names))
+	for idx, name := range names {
+		arg, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("missing arg %s of param %d", name, idx+1)
+		}
+		bound[idx] = arg
+	}
+
+	return bound, nil
+}
@@ -0,0 +1,63 @@
+package sqlextractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_CacheKey(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor("SELECT * FROM users WHERE id = 1; SELECT * FROM users WHERE id = 2; DELETE FROM users")
+	as.Nil(e.Extract())
+
+	keys := e.CacheKey()
+	as.Equal(3, len(keys))
+	as.Equal("mysql:v1:"+defaultHash([]byte("SELECT * FROM users WHERE id eq ?")), keys[0])
+	as.Equal(keys[0], keys[1])
+	as.NotEqual(keys[0], keys[2])
+
+	e = NewExtractor("SELECT * FROM users WHERE id = 1")
+	as.Nil(e.ExtractAutoDialect())
+	as.True(strings.HasPrefix(e.CacheKey()[0], DialectMySQL+":v1:"))
+
+	as.Empty(NewExtractor("SELECT 1").CacheKey())
+}
+
+func TestBindNamed(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor("SELECT * FROM users WHERE age > 18 AND id IN (1, 2) LIMIT 10")
+	names, err := e.ParamNames()
+	as.Nil(err)
+	as.Equal([][]string{{"age", "id", "id_2", "limit"}}, names)
+
+	_, args, err := e.SqlxNamed()
+	as.Nil(err)
+	bound, err := BindNamed(names[0], args[0])
+	as.Nil(err)
+
+	// same order as the params of the template
+	as.Nil(e.Extract())
+	as.Equal(e.Params()[0], bound)
+
+	_, err = BindNamed([]string{"age", "name"}, map[string]any{"age": 18})
+	as.ErrorContains(err, "missing arg name of param 2")
+
+	_, err = NewExtractor("SELEC").ParamNames()
+	as.NotNil(err)
+
+	// 与 Params 使用相同的选项
+	for _, overflow := range []ParamsOverflow{ParamsCollapse, ParamsTruncate} {
+		e = NewExtractor("SELECT * FROM users WHERE age > 18 AND id IN (1, 2, 3) LIMIT 10", WithMaxParams(2, overflow))
+		as.Nil(e.Extract())
+		names, err = e.ParamNames()
+		as.Nil(err)
+		as.Equal([][]string{{"age", "id"}}, names)
+		as.Len(e.Params()[0], 2)
+	}
+}
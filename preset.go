@@ -0,0 +1,77 @@
+package sqlextractor
+
+import (
+	"fmt"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// Preset is a named output preset of the templates, which bundles the
+// operator style, identifier quoting, IN-list collapsing and placeholder
+// style, see WithPreset. Keywords are uppercase in all presets.
+type Preset string
+
+// String returns the name of the Preset.
+func (p Preset) String() string { return string(p) }
+
+const (
+	// PresetMySQLDigest renders the templates close to the digest text of
+	// MySQL: standard operators, all identifiers quoted, IN lists collapsed to
+	// their first item and ? placeholders, e.g.
+	// SELECT * FROM `users` WHERE `id` IN (?). The templates are not the
+	// DIGEST_TEXT of performance_schema, which writes IN (...) and its own
+	// spacing, so they do not match it byte for byte.
+	PresetMySQLDigest Preset = "mysql-digest"
+
+	// PresetExecutable renders executable templates: standard operators,
+	// identifiers quoted when needed, original table names and ?
+	// placeholders, so they run with the params, e.g.
+	// SELECT `order` FROM orders_01 WHERE id IN (?, ?).
+	PresetExecutable Preset = "executable"
+
+	// PresetHuman renders readable templates: standard operators, identifiers
+	// quoted when needed, original table names, IN lists collapsed to their
+	// first item and named placeholders, e.g.
+	// SELECT * FROM users WHERE id IN (:id) AND age > :age.
+	PresetHuman Preset = "human"
+)
+
+// Presets returns the names of the presets.
+func Presets() []Preset {
+	return []Preset{PresetMySQLDigest, PresetExecutable, PresetHuman}
+}
+
+// ParsePreset returns the preset of the name, e.g. of a configuration file.
+func ParsePreset(name string) (Preset, error) {
+	for _, p := range Presets() {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown preset: %s", name)
+}
+
+// WithPreset sets the output options of the preset, replacing those of the
+// previous presets, so teams get consistent templates with one option. The
+// templates, and so the digests, of a preset differ from the default ones.
+// Unknown presets are ignored, see ParsePreset.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id IN (1, 2, 3)", WithPreset(PresetMySQLDigest))
+//	_ = extractor.Extract()
+//	fmt.Println(extractor.TemplatizedSQL()) // [SELECT * FROM `users` WHERE `id` IN (?)]
+func WithPreset(p Preset) ExtractorOption {
+	return func(e *Extractor) {
+		o := &e.options
+		switch p {
+		case PresetMySQLDigest:
+			o.standardOps, o.quoting, o.collapseIn, o.rawTableNames, o.named = true, extract.QuoteAlways, true, false, false
+		case PresetExecutable:
+			o.standardOps, o.quoting, o.collapseIn, o.rawTableNames, o.named = true, extract.QuoteWhenNeeded, false, true, false
+		case PresetHuman:
+			o.standardOps, o.quoting, o.collapseIn, o.rawTableNames, o.named = true, extract.QuoteWhenNeeded, true, true, true
+		}
+	}
+}
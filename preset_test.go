@@ -0,0 +1,53 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPreset(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	const sql = "SELECT `order`, name FROM shop.orders_01 WHERE id IN (1, 2, 3) AND age > 18; UPDATE users SET name = 'x' WHERE id = 1"
+	tests := []struct {
+		preset Preset
+		want   []string
+	}{
+		{PresetMySQLDigest, []string{
+			"SELECT `order`, `name` FROM `shop`.`orders_?` WHERE `id` IN (?) AND `age` > ?",
+			"UPDATE `users` SET `name` = ? WHERE `id` = ?",
+		}},
+		{PresetExecutable, []string{
+			"SELECT `order`, name FROM shop.orders_01 WHERE id IN (?, ?, ?) AND age > ?",
+			"UPDATE users SET name = ? WHERE id = ?",
+		}},
+		{PresetHuman, []string{
+			"SELECT `order`, name FROM shop.orders_01 WHERE id IN (:id) AND age > :age",
+			"UPDATE users SET name = :name WHERE id = :id",
+		}},
+	}
+	for _, tt := range tests {
+		e := NewExtractor(sql, WithPreset(tt.preset))
+		as.Nil(e.Extract(), tt.preset)
+		as.Equal(tt.want, e.TemplatizedSQL(), tt.preset)
+	}
+
+	// the params of the executable preset run with its templates
+	e := NewExtractor(sql, WithPreset(PresetExecutable))
+	as.Nil(e.Extract())
+	as.Equal([]any{int64(1), int64(2), int64(3), int64(18)}, e.Params()[0])
+
+	// the last preset wins, unknown presets are ignored
+	e = NewExtractor("SELECT * FROM users WHERE id = 1", WithPreset(PresetHuman), WithPreset(PresetMySQLDigest), WithPreset("unknown"))
+	as.Nil(e.Extract())
+	as.Equal([]string{"SELECT * FROM `users` WHERE `id` = ?"}, e.TemplatizedSQL())
+
+	p, err := ParsePreset("executable")
+	as.Nil(err)
+	as.Equal(PresetExecutable, p)
+	_, err = ParsePreset("pretty")
+	as.NotNil(err)
+	as.Equal(3, len(Presets()))
+}
@@ -0,0 +1,133 @@
+package sqlextractor
+
+import (
+	"fmt"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+	"github.com/kydance/sql-extractor/internal/obfuscate"
+)
+
+// Profile is a normalization profile, which decides how the SQL is normalized
+// before the digest is computed.
+type Profile string
+
+// String returns the string representation of the Profile.
+func (p Profile) String() string { return string(p) }
+
+const (
+	// ProfileDefault is the AST based templatization, same as TemplatizedSQL.
+	ProfileDefault Profile = "default"
+
+	// ProfileAPM matches the obfuscation performed by common APM agents (e.g.
+	// Datadog, New Relic): literals are replaced with ?, whitespace is collapsed,
+	// keywords case is not changed. It is token based, so the SQL the parser
+	// rejects is normalized too.
+	ProfileAPM Profile = "apm"
+
+	// ProfileCaseInsensitive is the default templatization with identifiers
+	// lowercased and quoted only when needed, matching MySQL's case-insensitive
+	// table names on common platforms (lower_case_table_names=1 or 2): e.g.
+	// SELECT * FROM Users and select * from `users` share the same digest.
+	ProfileCaseInsensitive Profile = "case-insensitive"
+
+	// ProfileSQLGlot matches the canonicalization of sqlglot (MySQL dialect)
+	// with the literals replaced by placeholders and the identifiers unquoted,
+	// so digests computed in Python agree with ours, see
+	// testdata/sqlglot/conformance.py: e.g. SELECT count(*) FROM t WHERE a NOT
+	// IN (1, 2) LIMIT 10, 20 gives SELECT COUNT(*) FROM t WHERE NOT a IN (?, ?)
+	// LIMIT ? OFFSET ?. Table names are not templatized.
+	ProfileSQLGlot Profile = "sqlglot"
+)
+
+var (
+	// foldExtractor is the extractor of ProfileCaseInsensitive.
+	foldExtractor = packageExtractor(extract.WithFoldedIdentifiers())
+
+	// sqlglotExtractor is the extractor of ProfileSQLGlot.
+	sqlglotExtractor = packageExtractor(
+		extract.WithSQLGlotStyle(),
+		extract.WithRawTableNames(),
+		extract.WithCollectParams(false),
+	)
+)
+
+// NormalizedSQL returns the normalized SQL of each statement by the profile.
+// It should be called after Extract.
+func (e *Extractor) NormalizedSQL(profile Profile) ([]string, error) {
+	switch profile {
+	case ProfileDefault:
+		return e.templatedSQL, nil
+
+	case ProfileAPM:
+		// 混淆基于 token，不依赖解析，无法解析的 SQL 同样可以归一化
+		stmts := extract.SplitStatements(e.rawSQL)
+		normalized := make([]string, len(stmts))
+		for idx := range stmts {
+			normalized[idx] = obfuscate.Obfuscate(stmts[idx])
+		}
+
+		return normalized, nil
+
+	case ProfileCaseInsensitive:
+		normalized, _, _, _, err := foldExtractor.Extract(e.rawSQL)
+		if err != nil {
+			return nil, err
+		}
+
+		return normalized, nil
+
+	case ProfileSQLGlot:
+		normalized, _, _, _, err := sqlglotExtractor.Extract(e.rawSQL)
+		if err != nil {
+			return nil, err
+		}
+
+		return normalized, nil
+	}
+
+	return nil, fmt.Errorf("unknown profile: %s", profile)
+}
+
+// NormalizeForDigest returns the canonical string of each statement of sql,
+// which is hashed as its digest (TemplatizedSQLHash of an Extractor with the
+// default options and no middleware). It only depends on the syntax tree of
+// the statement, so whitespace, comments, keywords case and trailing
+// semicolons of the input do not change it, e.g.
+//
+//	SELECT * FROM t WHERE a = 1
+//	select *  from t -- comment
+//	  where a=1;
+//
+// both give SELECT * FROM t WHERE a eq ?. Middleware of an Extractor is not
+// applied.
+func NormalizeForDigest(sql string) ([]string, error) {
+	normalized, _, _, _, err := defaultExtractor.Extract(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// NormalizedSQLHash returns the hash of the normalized SQL of each statement
+// by the profile, so digests line up with groupings computed elsewhere.
+//
+// Default hash function is sha256.
+func (e *Extractor) NormalizedSQLHash(profile Profile, fn ...func([]byte) string) ([]string, error) {
+	normalized, err := e.NormalizedSQL(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	hashFn := defaultHash
+	if len(fn) > 0 {
+		hashFn = fn[0]
+	}
+
+	hash := make([]string, len(normalized))
+	for idx := range normalized {
+		hash[idx] = hashFn([]byte(normalized[idx]))
+	}
+
+	return hash, nil
+}
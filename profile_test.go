@@ -0,0 +1,158 @@
+package sqlextractor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_NormalizedSQL(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("select *  FROM users WHERE id IN (1, 2) AND name = 'kyden';\nSELECT 1")
+	as.Nil(extractor.Extract())
+
+	normalized, err := extractor.NormalizedSQL(ProfileDefault)
+	as.Nil(err)
+	as.Equal(extractor.TemplatizedSQL(), normalized)
+
+	normalized, err = extractor.NormalizedSQL(ProfileAPM)
+	as.Nil(err)
+	as.Equal([]string{"select * FROM users WHERE id IN (?) AND name = ?", "SELECT ?"}, normalized)
+
+	hash, err := extractor.NormalizedSQLHash(ProfileAPM, func(b []byte) string { return string(b) })
+	as.Nil(err)
+	as.Equal(normalized, hash)
+
+	hash, err = extractor.NormalizedSQLHash(ProfileAPM)
+	as.Nil(err)
+	as.Equal(defaultHash([]byte("SELECT ?")), hash[1])
+
+	// 改写前的语句，e.g. LATERAL 保留
+	lateral := NewExtractor("SELECT * FROM t, LATERAL (SELECT a FROM s WHERE s.id = t.id) AS x")
+	as.Nil(lateral.Extract())
+	normalized, err = lateral.NormalizedSQL(ProfileAPM)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t, LATERAL (SELECT a FROM s WHERE s.id = t.id) AS x"}, normalized)
+
+	// 无法解析的 SQL 同样可以归一化
+	invalid := NewExtractor("SELECT * FROM t WHERE a = 1 QUALIFY x > 2; UPSERT t SET b = 'x'")
+	as.NotNil(invalid.Extract())
+	normalized, err = invalid.NormalizedSQL(ProfileAPM)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM t WHERE a = ? QUALIFY x > ?", "UPSERT t SET b = ?"}, normalized)
+
+	_, err = extractor.NormalizedSQL(Profile("unknown"))
+	as.NotNil(err)
+	_, err = extractor.NormalizedSQLHash(Profile("unknown"))
+	as.NotNil(err)
+}
+
+func TestExtractor_NormalizedSQL_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	upper := NewExtractor("SELECT * FROM Users WHERE Id = 1")
+	as.Nil(upper.Extract())
+	quoted := NewExtractor("select * from `users` where `id` = 2")
+	as.Nil(quoted.Extract())
+
+	normalized, err := upper.NormalizedSQL(ProfileCaseInsensitive)
+	as.Nil(err)
+	as.Equal([]string{"SELECT * FROM users WHERE id eq ?"}, normalized)
+
+	upperHash, err := upper.NormalizedSQLHash(ProfileCaseInsensitive)
+	as.Nil(err)
+	quotedHash, err := quoted.NormalizedSQLHash(ProfileCaseInsensitive)
+	as.Nil(err)
+	as.Equal(upperHash, quotedHash)
+	as.NotEqual(upper.TemplatizedSQLHash(), quoted.TemplatizedSQLHash())
+
+	// 必要的引号保留
+	reserved := NewExtractor("SELECT `Order`, `My Col` FROM `My Table`")
+	as.Nil(reserved.Extract())
+	normalized, err = reserved.NormalizedSQL(ProfileCaseInsensitive)
+	as.Nil(err)
+	as.Equal([]string{"SELECT `order`, `my col` FROM `my table`"}, normalized)
+}
+
+func TestProfileSQLGlot_Conformance(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	corpus, err := os.ReadFile("testdata/sqlglot/conformance.tsv")
+	as.Nil(err)
+
+	for _, line := range strings.Split(strings.TrimSpace(string(corpus)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sql, expected, ok := strings.Cut(line, "\t")
+		as.True(ok, line)
+
+		normalized, err := NewExtractor(sql).NormalizedSQL(ProfileSQLGlot)
+		as.Nil(err, sql)
+		as.Equal([]string{expected}, normalized, sql)
+	}
+
+	// the digest is the sha256 of the canonical form, as digest() of conformance.py
+	hash, err := NewExtractor("select * from users where id = 1").NormalizedSQLHash(ProfileSQLGlot)
+	as.Nil(err)
+	as.Equal([]string{defaultHash([]byte("SELECT * FROM users WHERE id = ?"))}, hash)
+
+	// the other profiles are unchanged
+	e := NewExtractor("select count(*) from t where a not in (1, 2) limit 10, 20")
+	as.Nil(e.Extract())
+	as.Equal([]string{"SELECT count(1) FROM t WHERE a NOT IN (?, ?) LIMIT ?, ?"}, e.TemplatizedSQL())
+}
+
+func TestNormalizeForDigest(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	want := []string{"SELECT * FROM t WHERE a eq ?"}
+	digest := defaultHash([]byte(want[0]))
+
+	for _, sql := range []string{
+		"SELECT * FROM t WHERE a = 1",
+		"  select *\n\tfrom t   where a=1 ;",
+		"SELECT /* c */ * FROM t -- x\n WHERE a = 1;;",
+		"# hi\nSELECT * FROM t WHERE a = 1 /* end */;",
+		"SELECT /*+ MAX_EXECUTION_TIME(1) */ * FROM t WHERE a = 2",
+	} {
+		normalized, err := NormalizeForDigest(sql)
+		as.Nil(err, sql)
+		as.Equal(want, normalized, sql)
+
+		extractor := NewExtractor(sql)
+		as.Nil(extractor.Extract(), sql)
+		as.Equal([]string{digest}, extractor.TemplatizedSQLHash(), sql)
+	}
+
+	normalized, err := NormalizeForDigest("SELECT 1;\n\nSELECT 2 ;")
+	as.Nil(err)
+	as.Equal([]string{"SELECT ?", "SELECT ?"}, normalized)
+
+	_, err = NormalizeForDigest("SELEC * FROM t")
+	as.NotNil(err)
+}
+
+func TestDigestOnly(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT * FROM users WHERE id = 1; INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')"
+	extractor := NewExtractor(sql)
+	as.Nil(extractor.Extract())
+
+	digests, err := DigestOnly(sql)
+	as.Nil(err)
+	as.Equal(extractor.TemplatizedSQLHash(), digests)
+
+	_, err = DigestOnly("SELEC 1")
+	as.NotNil(err)
+}
@@ -0,0 +1,8 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+// QualifiedName is a table name of up to four parts, returned by
+// TableInfo.QualifiedName, e.g. the SQL Server server.catalog.schema.table or
+// the BigQuery project.dataset.table.
+type QualifiedName = models.QualifiedName
@@ -0,0 +1,11 @@
+package sqlextractor
+
+// WithQualify supports the QUALIFY clause of dialects such as BigQuery,
+// Snowflake or DuckDB, which filters the rows by the window functions and the
+// MySQL parser rejects, e.g. for a Dialect wrapping the Extractor. The clause
+// is kept in the template, after HAVING.
+//
+// e.g. SELECT a FROM t QUALIFY ROW_NUMBER() OVER w = 1 WINDOW w AS (PARTITION BY a) -> SELECT a FROM t QUALIFY ROW_NUMBER() OVER w eq ? WINDOW w AS (PARTITION BY a)
+func WithQualify() ExtractorOption {
+	return func(e *Extractor) { e.options.qualify = true }
+}
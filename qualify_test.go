@@ -0,0 +1,24 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithQualify(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT user_id, ts FROM events WHERE day = '2024-01-01' " +
+		"QUALIFY ROW_NUMBER() OVER w = 1 WINDOW w AS (PARTITION BY user_id ORDER BY ts DESC) LIMIT 10"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithQualify())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT user_id, ts FROM events WHERE day eq ? " +
+		"QUALIFY ROW_NUMBER() OVER w eq ? WINDOW w AS (PARTITION BY user_id ORDER BY ts DESC) LIMIT ?"},
+		extractor.TemplatizedSQL())
+	as.Equal([][]any{{"2024-01-01", int64(1), uint64(10)}}, extractor.Params())
+}
@@ -0,0 +1,342 @@
+// Package replay executes captured templates with their params, or
+// interpolated SQL, against a target database with configurable concurrency
+// and pacing, and records the latencies per digest, for load testing with
+// production-shaped traffic.
+//
+// The target is a database/sql handle, the driver of the DSN is registered by
+// the caller, e.g. by importing github.com/go-sql-driver/mysql.
+package replay
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// Query is a query of the replay.
+type Query struct {
+	SQL    string        // executable SQL, with ? placeholders for the Args
+	Args   []any         // database/sql driver values of the placeholders
+	Digest string        // digest the latency is recorded by, the hash of SQL if empty
+	At     time.Duration // offset from the start of the replay, zero runs as soon as possible
+}
+
+// FromTemplate returns the query of a stored template, e.g. a TemplatizedSQL,
+// and its params, converted to driver values, e.g. by Extractor.DriverParams.
+// The template is rendered with SQL operators and ? placeholders by Translate,
+// the digest is the hash of the template, the same as TemplatizedSQLHash.
+func FromTemplate(template string, params []any) (Query, error) {
+	rendered, _, err := sqlextractor.Translate(template, sqlextractor.DialectMySQL, sqlextractor.DialectMySQL)
+	if err != nil {
+		return Query{}, err
+	}
+
+	return Query{SQL: rendered, Args: params, Digest: hash(template)}, nil
+}
+
+// FromSQL returns a query per statement of the interpolated SQL, executed as
+// is, with the digest of its template.
+func FromSQL(sql string) ([]Query, error) {
+	var queries []Query
+	err := sqlextractor.ExtractStream(strings.NewReader(sql), func(r sqlextractor.StatementResult) error {
+		if r.Err != nil {
+			return r.Err
+		}
+
+		queries = append(queries, Query{SQL: r.RawSQL, Digest: hash(r.TemplatizedSQL)})
+		return nil
+	})
+
+	return queries, err
+}
+
+// hash returns the digest of a template, sha256 hex encoded.
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// DigestLatency is the latency of the queries of a digest.
+type DigestLatency struct {
+	Digest string
+	SQL    string // SQL of the first query
+	Count  int64  // number of executed queries, including the failed ones
+	Errors int64  // number of failed queries
+	Err    error  // first error
+
+	Total         time.Duration
+	Min, Max      time.Duration
+	P50, P95, P99 time.Duration
+}
+
+// Report is the result of a replay.
+type Report struct {
+	Duration time.Duration   // wall time of the replay
+	Queries  int64           // number of executed queries
+	Errors   int64           // number of failed queries
+	Digests  []DigestLatency // in order of the first query of the digest
+}
+
+// Replayer executes queries against a database. It is safe for concurrent use.
+type Replayer struct {
+	db          *sql.DB
+	owned       bool // db 由 Open 打开，由 Close 关闭
+	concurrency int
+	rate        float64
+	speed       float64
+	timeout     time.Duration
+}
+
+// Option configures the Replayer.
+type Option func(*Replayer)
+
+// WithConcurrency executes up to n queries concurrently, default is 1.
+func WithConcurrency(n int) Option {
+	return func(r *Replayer) { r.concurrency = max(n, 1) }
+}
+
+// WithRate starts at most perSecond queries per second. A perSecond <= 0 means
+// unlimited, which is the default.
+func WithRate(perSecond float64) Option {
+	return func(r *Replayer) { r.rate = max(perSecond, 0) }
+}
+
+// WithSpeed scales the offsets of the queries, e.g. 2 replays twice as fast
+// as captured. Default is 1.
+func WithSpeed(factor float64) Option {
+	return func(r *Replayer) {
+		if factor > 0 {
+			r.speed = factor
+		}
+	}
+}
+
+// WithQueryTimeout cancels the queries running longer than d, 0 means no
+// timeout, which is the default.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(r *Replayer) { r.timeout = max(d, 0) }
+}
+
+// New creates a new Replayer executing the queries through db.
+func New(db *sql.DB, opts ...Option) *Replayer {
+	r := &Replayer{db: db, concurrency: 1, speed: 1}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Open opens the target DSN with the registered driver, and creates a new
+// Replayer of it. The database is closed by Close.
+func Open(driverName, dsn string, opts ...Option) (*Replayer, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	r := New(db, opts...)
+	r.owned = true
+	db.SetMaxOpenConns(r.concurrency)
+
+	return r, nil
+}
+
+// Close closes the database opened by Open.
+func (r *Replayer) Close() error {
+	if !r.owned {
+		return nil
+	}
+
+	return r.db.Close()
+}
+
+// Run executes the queries, in order of their offsets, and returns the
+// latencies per digest. Failed queries are recorded, not returned; the error
+// is that of ctx if it is done before all the queries are started.
+//
+// Example:
+//
+//	r, err := replay.Open("mysql", "user:pass@tcp(staging:3306)/shop", replay.WithConcurrency(16), replay.WithRate(500))
+//	if err != nil {
+//	  // handle error
+//	}
+//	defer r.Close()
+//
+//	report, err := r.Run(ctx, queries)
+func (r *Replayer) Run(ctx context.Context, queries []Query) (*Report, error) {
+	queries = slices.Clone(queries)
+	slices.SortStableFunc(queries, func(a, b Query) int { return cmp.Compare(a.At, b.At) })
+
+	rec := newRecorder(queries)
+	start := time.Now()
+
+	var (
+		wg   sync.WaitGroup
+		work = make(chan *Query)
+	)
+	for range r.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range work {
+				latency, err := r.exec(ctx, q)
+				rec.record(q, latency, err)
+			}
+		}()
+	}
+
+	err := r.dispatch(ctx, queries, start, work)
+	close(work)
+	wg.Wait()
+
+	report := rec.report()
+	report.Duration = time.Since(start)
+
+	return report, err
+}
+
+// dispatch sends the queries to the workers, paced by their offsets and the
+// rate.
+func (r *Replayer) dispatch(ctx context.Context, queries []Query, start time.Time, work chan<- *Query) error {
+	var interval time.Duration
+	if r.rate > 0 {
+		interval = time.Duration(float64(time.Second) / r.rate)
+	}
+
+	next := start // 按速率允许的下一个开始时间
+	for idx := range queries {
+		q := &queries[idx]
+
+		at := start.Add(time.Duration(float64(q.At) / r.speed))
+		if interval > 0 {
+			if at.Before(next) {
+				at = next
+			}
+			next = at.Add(interval)
+		}
+		if wait := time.Until(at); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case work <- q:
+		}
+	}
+
+	return nil
+}
+
+// exec executes the query and reads its rows, and returns its latency.
+func (r *Replayer) exec(ctx context.Context, q *Query) (time.Duration, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	rows, err := r.db.QueryContext(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return time.Since(start), err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// 读取所有行，计入延迟
+	}
+	err = rows.Err()
+
+	return time.Since(start), err
+}
+
+// recorder records the latencies per digest.
+type recorder struct {
+	mu sync.Mutex
+
+	digests map[string]*digestRecord
+	order   []*digestRecord // in order of first appearance
+}
+
+type digestRecord struct {
+	DigestLatency
+	latencies []time.Duration
+}
+
+// newRecorder creates a recorder of the digests of the queries, so they are
+// reported in order of the queries instead of their completion.
+func newRecorder(queries []Query) *recorder {
+	rec := &recorder{digests: make(map[string]*digestRecord)}
+	for idx := range queries {
+		q := &queries[idx]
+		if q.Digest == "" {
+			q.Digest = hash(q.SQL)
+		}
+
+		if _, ok := rec.digests[q.Digest]; !ok {
+			d := &digestRecord{DigestLatency: DigestLatency{Digest: q.Digest, SQL: q.SQL}}
+			rec.digests[q.Digest] = d
+			rec.order = append(rec.order, d)
+		}
+	}
+
+	return rec
+}
+
+func (rec *recorder) record(q *Query, latency time.Duration, err error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	d := rec.digests[q.Digest]
+	d.Count++
+	if err != nil {
+		d.Errors++
+		if d.Err == nil {
+			d.Err = fmt.Errorf("%s: %w", q.SQL, err)
+		}
+	}
+	d.Total += latency
+	d.latencies = append(d.latencies, latency)
+}
+
+func (rec *recorder) report() *Report {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	report := &Report{Digests: make([]DigestLatency, 0, len(rec.order))}
+	for _, d := range rec.order {
+		if d.Count == 0 { // 取消前未执行
+			continue
+		}
+
+		slices.Sort(d.latencies)
+		percentile := func(p float64) time.Duration {
+			return d.latencies[int(p*float64(len(d.latencies)-1))]
+		}
+
+		d.Min, d.Max = d.latencies[0], d.latencies[len(d.latencies)-1]
+		d.P50, d.P95, d.P99 = percentile(0.5), percentile(0.95), percentile(0.99)
+		report.Digests = append(report.Digests, d.DigestLatency)
+		report.Queries += d.Count
+		report.Errors += d.Errors
+	}
+
+	return report
+}
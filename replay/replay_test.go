@@ -0,0 +1,203 @@
+package replay
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+// replayDriver is a fake driver which records the executed queries, and fails
+// the queries of the missing table.
+type replayDriver struct {
+	mu      sync.Mutex
+	queries []string
+	args    [][]driver.Value
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (d *replayDriver) Open(string) (driver.Conn, error) { return &replayConn{d}, nil }
+
+type replayConn struct{ d *replayDriver }
+
+func (c *replayConn) Prepare(query string) (driver.Stmt, error) { return &replayStmt{c.d, query}, nil }
+func (*replayConn) Close() error                                { return nil }
+func (*replayConn) Begin() (driver.Tx, error)                   { return nil, errors.New("not supported") }
+
+type replayStmt struct {
+	d     *replayDriver
+	query string
+}
+
+func (*replayStmt) Close() error  { return nil }
+func (*replayStmt) NumInput() int { return -1 }
+
+func (*replayStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *replayStmt) Query(args []driver.Value) (driver.Rows, error) {
+	running := s.d.running.Add(1)
+	defer s.d.running.Add(-1)
+	for peak := s.d.peak.Load(); running > peak && !s.d.peak.CompareAndSwap(peak, running); peak = s.d.peak.Load() {
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	s.d.mu.Lock()
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	s.d.mu.Unlock()
+
+	if strings.Contains(s.query, "missing") {
+		return nil, errors.New("table doesn't exist")
+	}
+
+	return &replayRows{n: 2}, nil
+}
+
+type replayRows struct{ n int }
+
+func (*replayRows) Columns() []string { return []string{"id"} }
+func (*replayRows) Close() error      { return nil }
+
+func (r *replayRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	r.n--
+	dest[0] = int64(r.n)
+
+	return nil
+}
+
+var (
+	fakeDriver = &replayDriver{}
+	driverOnce sync.Once
+)
+
+func openFake(t *testing.T) *replayDriver {
+	t.Helper()
+	driverOnce.Do(func() { sql.Register("replay-fake", fakeDriver) })
+
+	fakeDriver.mu.Lock()
+	defer fakeDriver.mu.Unlock()
+	fakeDriver.queries, fakeDriver.args = nil, nil
+	fakeDriver.peak.Store(0)
+
+	return fakeDriver
+}
+
+func TestFromTemplate(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := sqlextractor.NewExtractor("SELECT * FROM users WHERE id = 1 AND name IN ('a', 'b')")
+	as.Nil(e.Extract())
+	params, err := e.DriverParams()
+	as.Nil(err)
+
+	q, err := FromTemplate(e.TemplatizedSQL()[0], params[0])
+	as.Nil(err)
+	as.Equal("SELECT * FROM `users` WHERE `id`=? AND `name` IN (?,?)", q.SQL)
+	as.Equal([]any{int64(1), "a", "b"}, q.Args)
+	as.Equal(e.TemplatizedSQLHash()[0], q.Digest)
+
+	_, err = FromTemplate("SELEC", nil)
+	as.NotNil(err)
+
+	queries, err := FromSQL("SELECT * FROM users WHERE id = 1; SELECT * FROM users WHERE id = 2")
+	as.Nil(err)
+	as.Equal(2, len(queries))
+	as.Equal("SELECT * FROM users WHERE id = 2", queries[1].SQL)
+	as.Equal(queries[0].Digest, queries[1].Digest)
+
+	_, err = FromSQL("SELEC")
+	as.NotNil(err)
+}
+
+// The fake driver is shared, so the replay tests are not parallel.
+func TestReplayer(t *testing.T) {
+	as := assert.New(t)
+	d := openFake(t)
+
+	r, err := Open("replay-fake", "", WithConcurrency(4))
+	as.Nil(err)
+	defer r.Close()
+
+	users, err := FromTemplate("SELECT * FROM users WHERE id eq ?", []any{int64(1)})
+	as.Nil(err)
+	queries := []Query{users, {SQL: "SELECT * FROM missing"}}
+	for range 7 {
+		queries = append(queries, users)
+	}
+
+	report, err := r.Run(context.Background(), queries)
+	as.Nil(err)
+	as.Equal(int64(9), report.Queries)
+	as.Equal(int64(1), report.Errors)
+	as.Equal(2, len(report.Digests))
+
+	latency := report.Digests[0]
+	as.Equal(users.Digest, latency.Digest)
+	as.Equal("SELECT * FROM `users` WHERE `id`=?", latency.SQL)
+	as.Equal(int64(8), latency.Count)
+	as.Nil(latency.Err)
+	as.GreaterOrEqual(latency.Min, 5*time.Millisecond)
+	as.LessOrEqual(latency.Min, latency.P50)
+	as.LessOrEqual(latency.P99, latency.Max)
+	as.GreaterOrEqual(latency.Total, 8*latency.Min)
+
+	missing := report.Digests[1]
+	as.Equal(int64(1), missing.Errors)
+	as.ErrorContains(missing.Err, "doesn't exist")
+
+	as.Greater(d.peak.Load(), int32(1))
+	as.LessOrEqual(d.peak.Load(), int32(4))
+	as.Contains(d.args, []driver.Value{int64(1)})
+}
+
+func TestReplayer_Pacing(t *testing.T) {
+	as := assert.New(t)
+	openFake(t)
+
+	db, err := sql.Open("replay-fake", "")
+	as.Nil(err)
+	defer db.Close()
+
+	// offsets are replayed twice as fast
+	r := New(db, WithSpeed(2), WithConcurrency(2))
+	report, err := r.Run(context.Background(), []Query{
+		{SQL: "SELECT 2", At: 100 * time.Millisecond},
+		{SQL: "SELECT 1"},
+	})
+	as.Nil(err)
+	as.Equal("SELECT 1", report.Digests[0].SQL)
+	as.GreaterOrEqual(report.Duration, 50*time.Millisecond)
+	as.Less(report.Duration, 100*time.Millisecond)
+
+	// rate limit
+	r = New(db, WithRate(50), WithConcurrency(4))
+	report, err = r.Run(context.Background(), []Query{{SQL: "SELECT 1"}, {SQL: "SELECT 1"}, {SQL: "SELECT 1"}})
+	as.Nil(err)
+	as.Equal(int64(3), report.Queries)
+	as.GreaterOrEqual(report.Duration, 40*time.Millisecond)
+
+	// cancellation stops the dispatch
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err = r.Run(ctx, []Query{{SQL: "SELECT 1"}, {SQL: "SELECT 1", At: time.Hour}})
+	as.ErrorIs(err, context.DeadlineExceeded)
+	as.Equal(int64(1), report.Queries)
+	as.Nil(r.Close())
+}
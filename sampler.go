@@ -0,0 +1,90 @@
+package sqlextractor
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Sampler decides which queries of a stream are extracted, so high-QPS
+// deployments can bound the extraction CPU. Queries are first sampled at the
+// sample rate, then limited by a token bucket. It is safe for concurrent use.
+//
+// Sampling is uniform, so digest counts can be estimated by dividing the
+// observed counts by the sample rate.
+type Sampler struct {
+	mu sync.Mutex
+
+	rate   float64 // sample rate in [0, 1]
+	limit  float64 // tokens added per second, 0 means unlimited
+	burst  float64 // bucket size
+	tokens float64
+	last   time.Time
+
+	now    func() time.Time
+	random func() float64
+}
+
+// SamplerOption configures the Sampler.
+type SamplerOption func(*Sampler)
+
+// WithSampleRate extracts the fraction rate of the queries, e.g. 0.1 extracts
+// one query out of ten on average. It is clamped to [0, 1], default is 1.
+func WithSampleRate(rate float64) SamplerOption {
+	return func(s *Sampler) { s.rate = min(max(rate, 0), 1) }
+}
+
+// WithRateLimit extracts at most perSecond queries per second on average, with
+// bursts of up to burst queries. A perSecond <= 0 means unlimited.
+func WithRateLimit(perSecond float64, burst int) SamplerOption {
+	return func(s *Sampler) {
+		s.limit = max(perSecond, 0)
+		s.burst = float64(max(burst, 1))
+	}
+}
+
+// NewSampler creates a new Sampler, which allows all queries by default.
+func NewSampler(opts ...SamplerOption) *Sampler {
+	s := &Sampler{
+		rate:   1,
+		burst:  1,
+		now:    time.Now,
+		random: rand.Float64,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.tokens = s.burst
+
+	return s
+}
+
+// SampleRate returns the sample rate.
+func (s *Sampler) SampleRate() float64 { return s.rate }
+
+// Allow reports whether the next query should be extracted.
+func (s *Sampler) Allow() bool {
+	if s.rate < 1 && s.random() >= s.rate {
+		return false
+	}
+
+	if s.limit == 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if !s.last.IsZero() {
+		s.tokens = min(s.burst, s.tokens+now.Sub(s.last).Seconds()*s.limit)
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+
+	return true
+}
@@ -0,0 +1,55 @@
+package sqlextractor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// default allows all
+	s := NewSampler()
+	as.Equal(1.0, s.SampleRate())
+	for range 100 {
+		as.True(s.Allow())
+	}
+
+	// sample rate
+	as.Equal(0.0, NewSampler(WithSampleRate(-1)).SampleRate())
+	as.Equal(1.0, NewSampler(WithSampleRate(2)).SampleRate())
+
+	s = NewSampler(WithSampleRate(0.1))
+	values := []float64{0.05, 0.1, 0.5, 0.09}
+	s.random = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+	as.True(s.Allow())
+	as.False(s.Allow())
+	as.False(s.Allow())
+	as.True(s.Allow())
+
+	// rate limit
+	now := time.Unix(0, 0)
+	s = NewSampler(WithRateLimit(2, 3))
+	s.now = func() time.Time { return now }
+	as.True(s.Allow())
+	as.True(s.Allow())
+	as.True(s.Allow()) // burst
+	as.False(s.Allow())
+
+	now = now.Add(500 * time.Millisecond) // 1 token
+	as.True(s.Allow())
+	as.False(s.Allow())
+
+	now = now.Add(time.Hour) // bucket is full, up to burst
+	as.True(s.Allow())
+	as.True(s.Allow())
+	as.True(s.Allow())
+	as.False(s.Allow())
+}
@@ -0,0 +1,25 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+// SelectOptions is the bitset of the options of the SELECT statements of a
+// SQL statement, e.g. SQL_NO_CACHE or SQL_CALC_FOUND_ROWS.
+type SelectOptions = models.SelectOptions
+
+const (
+	SelectHighPriority  = models.SelectHighPriority  // HIGH_PRIORITY
+	SelectStraightJoin  = models.SelectStraightJoin  // STRAIGHT_JOIN
+	SelectSmallResult   = models.SelectSmallResult   // SQL_SMALL_RESULT
+	SelectBigResult     = models.SelectBigResult     // SQL_BIG_RESULT
+	SelectBufferResult  = models.SelectBufferResult  // SQL_BUFFER_RESULT
+	SelectNoCache       = models.SelectNoCache       // SQL_NO_CACHE
+	SelectCalcFoundRows = models.SelectCalcFoundRows // SQL_CALC_FOUND_ROWS
+)
+
+// SelectOptions returns the options bitset of each statement, the union of
+// the options of its SELECT statements, so that the usage of deprecated
+// options such as SQL_NO_CACHE or SQL_CALC_FOUND_ROWS can be tracked. The
+// options are also kept in the templates.
+func (e *Extractor) SelectOptions() ([]SelectOptions, error) {
+	return e.internal().ExtractSelectOptions(e.rawSQL)
+}
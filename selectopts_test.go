@@ -0,0 +1,35 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_SelectOptions(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT SQL_NO_CACHE SQL_CALC_FOUND_ROWS a FROM t WHERE id = 1; " +
+		"SELECT DISTINCT STRAIGHT_JOIN a FROM t; " +
+		"SELECT a FROM t WHERE b IN (SELECT HIGH_PRIORITY SQL_BUFFER_RESULT b FROM s); " +
+		"SELECT a FROM t")
+	as.Nil(extractor.Extract())
+	as.Equal([]string{
+		"SELECT SQL_NO_CACHE SQL_CALC_FOUND_ROWS a FROM t WHERE id eq ?",
+		"SELECT DISTINCT STRAIGHT_JOIN a FROM t",
+		"SELECT a FROM t WHERE b IN ((SELECT HIGH_PRIORITY SQL_BUFFER_RESULT b FROM s))",
+		"SELECT a FROM t",
+	}, extractor.TemplatizedSQL())
+
+	options, err := extractor.SelectOptions()
+	as.Nil(err)
+	as.Equal([]SelectOptions{
+		SelectNoCache | SelectCalcFoundRows,
+		SelectStraightJoin,
+		SelectHighPriority | SelectBufferResult,
+		0,
+	}, options)
+	as.True(options[0].Has(SelectCalcFoundRows))
+	as.False(options[3].Has(SelectNoCache))
+}
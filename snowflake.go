@@ -0,0 +1,13 @@
+package sqlextractor
+
+// WithSnowflake supports the Snowflake syntax the MySQL parser rejects, e.g.
+// for a Dialect wrapping the Extractor: QUALIFY, the SAMPLE clauses, the
+// VARIANT paths src:a.b[0], the :: casts and the database.schema.table names.
+// The identifiers follow the
+// Snowflake case rules, the unquoted ones are folded to upper case and the
+// double quoted ones keep their case, so users and "USERS" share a digest.
+//
+// e.g. select src:name::string from users sample (10) -> SELECT SRC:name::STRING FROM USERS TABLESAMPLE (?)
+func WithSnowflake() ExtractorOption {
+	return func(e *Extractor) { e.options.snowflake = true }
+}
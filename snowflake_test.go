@@ -0,0 +1,25 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithSnowflake(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := `SELECT src:customer."Name"::string, count(*) FROM raw.events SAMPLE (10) ` +
+		`WHERE src:type = 'click' GROUP BY src:customer."Name" QUALIFY ROW_NUMBER() OVER (ORDER BY ts) = 1`
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithSnowflake())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{`SELECT SRC:customer."Name"::STRING, COUNT(1) FROM RAW.EVENTS TABLESAMPLE (?) ` +
+		`WHERE SRC:type eq ? GROUP BY SRC:customer."Name" QUALIFY ROW_NUMBER() OVER (ORDER BY TS) eq ?`},
+		extractor.TemplatizedSQL())
+	as.Equal([][]any{{int64(10), "click", int64(1)}}, extractor.Params())
+	as.Equal("EVENTS", extractor.TableInfos()[0][0].TableName())
+}
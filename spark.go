@@ -0,0 +1,11 @@
+package sqlextractor
+
+// WithSpark supports the Spark SQL and Hive syntax of batch jobs the MySQL
+// parser rejects: LATERAL VIEW [OUTER], DISTRIBUTE BY, SORT BY and CLUSTER BY
+// are kept in the template, the static partition values of INSERT OVERWRITE
+// [TABLE] and INSERT INTO TABLE are parameterized.
+//
+// e.g. INSERT OVERWRITE TABLE t PARTITION (dt = '2024-01-01') SELECT a FROM s -> INSERT OVERWRITE TABLE t PARTITION (dt = ?) SELECT a FROM s
+func WithSpark() ExtractorOption {
+	return func(e *Extractor) { e.options.spark = true }
+}
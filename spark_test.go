@@ -0,0 +1,24 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithSpark(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "INSERT OVERWRITE TABLE dw.daily PARTITION (dt = '2024-01-01') " +
+		"SELECT u.id, tag FROM users u LATERAL VIEW explode(u.tags) v AS tag DISTRIBUTE BY tag"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithSpark())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"INSERT OVERWRITE TABLE dw.daily PARTITION (dt = ?) " +
+		"SELECT u.id, tag FROM users AS u LATERAL VIEW explode(u.tags) v AS tag DISTRIBUTE BY tag"},
+		extractor.TemplatizedSQL())
+	as.Equal([][]any{{"2024-01-01"}}, extractor.Params())
+}
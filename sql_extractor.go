@@ -8,21 +8,31 @@ import (
 	"github.com/kydance/sql-extractor/internal/models"
 )
 
+// defaultExtractor is shared by all Extractors, it is safe for concurrent use.
+var defaultExtractor = packageExtractor()
+
 // Extractor is a struct that holds the raw SQL, templatized SQL, operation type,
 // parameters and table information. It is used to extract information from a
 // SQL string.
 type Extractor struct {
-	rawSQL       string                // raw SQL which needs to be extracted
-	templatedSQL []string              // templatized SQL
-	opType       []models.SQLOpType    // operation type: SELECT, INSERT, UPDATE, DELETE
-	params       [][]any               // parameters: where conditions, order by, limit, offset
-	tableInfos   [][]*models.TableInfo // table infos: Schema, Tablename
-	hash         []string              // hash of the templatized SQL
+	rawSQL       string                  // raw SQL which needs to be extracted
+	templatedSQL []string                // templatized SQL
+	opType       []models.SQLOpType      // operation type: SELECT, INSERT, UPDATE, DELETE
+	params       [][]any                 // parameters: where conditions, order by, limit, offset
+	tableInfos   [][]*models.TableInfo   // table infos: Schema, Tablename
+	hash         []string                // hash of the templatized SQL
+	plans        [][]*models.PlanSummary // plan summaries attached by Explain
+	extractor    *extract.Extractor      // internal extractor with hooks, nil means defaultExtractor
+	middleware   []Middleware            // post-processing middleware, run in order
+	warnings     []string                // parser warnings
+	tags         Tags                    // caller metadata, passed through unchanged
+	dialect      string                  // dialect detected by ExtractAutoDialect, empty means MySQL
+	options      extractorOptions        // options of the internal extractor
 }
 
 // NewExtractor creates a new Extractor. It requires a raw SQL string.
-func NewExtractor(sql string) *Extractor {
-	return &Extractor{
+func NewExtractor(sql string, opts ...ExtractorOption) *Extractor {
+	e := &Extractor{
 		rawSQL:       sql,
 		templatedSQL: []string{},
 		opType:       []models.SQLOpType{},
@@ -30,6 +40,11 @@ func NewExtractor(sql string) *Extractor {
 		tableInfos:   [][]*models.TableInfo{},
 		hash:         []string{},
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
 }
 
 // RawSQL returns the raw SQL.
@@ -50,15 +65,54 @@ func (e *Extractor) TableInfos() [][]*models.TableInfo { return e.tableInfos }
 // OpType returns the operation type.
 func (e *Extractor) OpType() []models.SQLOpType { return e.opType }
 
+// Results returns the result of each statement, which bundles its template,
+// params, table infos, operation type and hash, instead of the parallel slices
+// of TemplatizedSQL, Params, TableInfos and OpType which are easy to
+// misalign. The hash is that of the last TemplatizedSQLHash, sha256 by
+// default. It should be called after Extract.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; DELETE FROM t")
+//	_ = extractor.Extract()
+//	for _, r := range extractor.Results() {
+//	  fmt.Println(r.OpType, r.TemplatizedSQL, r.Params, r.Hash)
+//	}
+func (e *Extractor) Results() []StatementResult {
+	results := make([]StatementResult, len(e.templatedSQL))
+	for idx := range e.templatedSQL {
+		results[idx] = StatementResult{
+			Index:          idx,
+			TemplatizedSQL: e.templatedSQL[idx],
+			TableInfos:     e.tableInfos[idx],
+			Params:         e.params[idx],
+			OpType:         e.opType[idx],
+			Tags:           e.tags,
+		}
+		if idx < len(e.hash) {
+			results[idx].Hash = e.hash[idx]
+		}
+	}
+
+	return results
+}
+
+// Warnings returns the warnings reported by the parser, e.g. optimizer hints in
+// the wrong position.
+func (e *Extractor) Warnings() []string { return e.warnings }
+
+// defaultHash is the default hash function: sha256.
+func defaultHash(s []byte) string {
+	hash := sha256.Sum256(s)
+	return hex.EncodeToString(hash[:])
+}
+
 // doHash calculates the hash of the templatized SQL.
 func (e *Extractor) doHash(fn ...func([]byte) string) {
 	e.hash = make([]string, len(e.templatedSQL))
 
 	if len(fn) == 0 {
-		fn = []func([]byte) string{func(s []byte) string {
-			hash := sha256.Sum256(s)
-			return hex.EncodeToString(hash[:])
-		}}
+		fn = []func([]byte) string{defaultHash}
 	}
 
 	for i := range e.templatedSQL {
@@ -66,7 +120,12 @@ func (e *Extractor) doHash(fn ...func([]byte) string) {
 	}
 }
 
-// TemplatizedSQLHash returns the hash of the templatized SQL.
+// TemplatizedSQLHash returns the hash of the templatized SQL, as returned by
+// TemplatizedSQL, so the options and middleware changing the templates change
+// the hash too. The templates are rendered from the syntax tree, so the hash
+// is independent of whitespace, comments and trailing semicolons. With the
+// default options and no middleware, the hashed string is the one returned by
+// NormalizeForDigest.
 //
 // Default hash function is sha256.
 func (e *Extractor) TemplatizedSQLHash(fn ...func([]byte) string) []string {
@@ -74,6 +133,15 @@ func (e *Extractor) TemplatizedSQLHash(fn ...func([]byte) string) []string {
 	return e.hash
 }
 
+// DigestOnly returns the digest of each statement of sql, the same as
+// TemplatizedSQLHash with the default hash function and no middleware. The
+// digest is computed while the statement is templatized, without keeping the
+// templatized SQL, parameters and table infos, so it is cheaper than Extract
+// for callers which only group statements, especially for big statements.
+func DigestOnly(sql string) ([]string, error) {
+	return defaultExtractor.Digests(sql)
+}
+
 // Extract extracts information from the raw SQL string. It extracts the templatized
 // SQL, parameters, table information, and operation type.
 //
@@ -86,10 +154,19 @@ func (e *Extractor) TemplatizedSQLHash(fn ...func([]byte) string) []string {
 //	}
 //	fmt.Println(extractor.TemplatizeSQL())
 func (e *Extractor) Extract() (err error) {
-	if e.templatedSQL, e.tableInfos, e.params, e.opType, err = extract.NewExtractor().Extract(e.rawSQL); err != nil {
+	if e.templatedSQL, e.tableInfos, e.params, e.opType, e.warnings, err = e.internal().ExtractWithWarnings(e.rawSQL); err != nil {
 		return err
 	}
+	e.plans = nil
+	e.dialect = DialectMySQL
+	e.applyMiddleware()
 	e.doHash()
 
 	return nil
 }
+
+// StructureMetrics returns the structure metrics of each statement: subquery
+// count, max nesting depth, derived table count and union branch count.
+func (e *Extractor) StructureMetrics() ([]*models.StructureMetrics, error) {
+	return e.internal().ExtractStructureMetrics(e.rawSQL)
+}
@@ -95,7 +95,7 @@ func TestExtractor_TableInfos(t *testing.T) {
 	err = extractor.Extract()
 	as.Nil(err)
 	as.Equal([]models.SQLOpType{models.SQLOperationSelect}, extractor.OpType())
-	as.Equal([]string{"SELECT * FROM users AS u CROSS JOIN orders AS o ON u.id eq o.user_id WHERE u.name eq ?"}, extractor.TemplatizedSQL())
+	as.Equal([]string{"SELECT * FROM users AS u INNER JOIN orders AS o ON u.id eq o.user_id WHERE u.name eq ?"}, extractor.TemplatizedSQL())
 	as.Equal([][]any{{"kyden"}}, extractor.Params())
 	as.Equal([][]*models.TableInfo{
 		{
@@ -176,6 +176,32 @@ func TestExtractor_TemplatizedSQLHash(t *testing.T) {
 	}
 }
 
+func TestExtractor_Results(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users WHERE id = 1; DELETE FROM logs")
+	as.Empty(extractor.Results())
+
+	extractor.SetTags(Tags{"service": "billing"})
+	as.Nil(extractor.Extract())
+
+	results := extractor.Results()
+	as.Len(results, 2)
+	as.Equal(StatementResult{
+		Index:          0,
+		TemplatizedSQL: "SELECT * FROM users WHERE id eq ?",
+		TableInfos:     []*models.TableInfo{models.NewTableInfo("", "users", "", "users")},
+		Params:         []any{int64(1)},
+		OpType:         models.SQLOperationSelect,
+		Tags:           Tags{"service": "billing"},
+		Hash:           extractor.TemplatizedSQLHash()[0],
+	}, results[0])
+	as.Equal(1, results[1].Index)
+	as.Equal(models.SQLOperationDelete, results[1].OpType)
+	as.Equal(extractor.TemplatizedSQLHash()[1], results[1].Hash)
+}
+
 func TestExtractor_ComplexQueries(t *testing.T) {
 	t.Parallel()
 	as := assert.New(t)
@@ -188,7 +214,7 @@ func TestExtractor_ComplexQueries(t *testing.T) {
 	as.Nil(err)
 	as.Equal([]models.SQLOpType{models.SQLOperationSelect}, extractor.OpType())
 	as.Equal(
-		[]string{"SELECT u.name, o.order_id FROM users AS u CROSS JOIN orders AS o ON u.id eq o.user_id WHERE u.age gt ? and o.amount gt ?"},
+		[]string{"SELECT u.name, o.order_id FROM users AS u INNER JOIN orders AS o ON u.id eq o.user_id WHERE u.age gt ? and o.amount gt ?"},
 		extractor.TemplatizedSQL(),
 	)
 	as.Equal(2, len(extractor.Params()[0]))
@@ -230,3 +256,19 @@ func TestExtractor_0(t *testing.T) {
 		extractor.TemplatizedSQL(),
 	)
 }
+
+func TestExtractor_StructureMetrics(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)")
+	metrics, err := extractor.StructureMetrics()
+	as.Nil(err)
+	as.Equal([]*models.StructureMetrics{models.NewStructureMetrics(1, 2, 0, 0)}, metrics)
+
+	// 使用 Extractor 的方言选项解析
+	extractor = NewExtractor("SELECT TOP 5 * FROM users WHERE id IN (SELECT user_id FROM orders)", WithSQLServer())
+	metrics, err = extractor.StructureMetrics()
+	as.Nil(err)
+	as.Equal([]*models.StructureMetrics{models.NewStructureMetrics(1, 2, 0, 0)}, metrics)
+}
@@ -0,0 +1,12 @@
+package sqlextractor
+
+// WithSQLServer supports the SQL Server pagination clauses the MySQL parser
+// rejects, e.g. for a Dialect wrapping the Extractor: TOP (n) [PERCENT] [WITH
+// TIES] and OFFSET n ROWS FETCH NEXT m ROWS ONLY, whose counts are
+// parameterized as those of LIMIT, and the three-part and four-part table
+// names, e.g. server.catalog.schema.table and catalog..table.
+//
+// e.g. SELECT TOP (10) name FROM users -> SELECT TOP (?) name FROM users
+func WithSQLServer() ExtractorOption {
+	return func(e *Extractor) { e.options.sqlserver = true }
+}
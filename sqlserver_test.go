@@ -0,0 +1,24 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithSQLServer(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT TOP (10) name FROM users; SELECT name FROM users ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithSQLServer())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{
+		"SELECT TOP (?) name FROM users",
+		"SELECT name FROM users ORDER BY id OFFSET ? ROWS FETCH NEXT ? ROWS ONLY",
+	}, extractor.TemplatizedSQL())
+	as.Equal([][]any{{int64(10)}, {uint64(20), uint64(10)}}, extractor.Params())
+}
@@ -0,0 +1,31 @@
+package sqlextractor
+
+import (
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// sqlxExtractor renders named placeholders, standard SQL operators and original table names.
+var sqlxExtractor = packageExtractor(
+	extract.WithNamedParams(),
+	extract.WithStandardOperators(),
+	extract.WithRawTableNames(),
+)
+
+// SqlxNamed returns the sqlx-compatible named template (:param_name) of each
+// statement, with standard SQL operators and original table names, and the
+// named arguments, so the captured SQL can be passed to sqlx NamedExec directly.
+//
+// Parameter names are the columns the parameters are compared with or assigned
+// to, suffixed with _2, _3, ... when a column is used more than once, or p<N>
+// when there is no such column.
+//
+// Example:
+//
+//	extractor := NewExtractor("UPDATE users SET age = 18 WHERE id = 1")
+//	templates, args, err := extractor.SqlxNamed()
+//	// templates: ["UPDATE users SET age = :age WHERE id = :id"]
+//	// args:      [{"age": 18, "id": 1}]
+//	_, err = db.NamedExec(templates[0], args[0])
+func (e *Extractor) SqlxNamed() ([]string, []map[string]any, error) {
+	return sqlxExtractor.ExtractNamed(e.rawSQL)
+}
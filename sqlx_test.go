@@ -0,0 +1,21 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_SqlxNamed(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("UPDATE users_01 SET age = 18 WHERE id = 1 AND name != 'kyden'")
+	templates, args, err := extractor.SqlxNamed()
+	as.Nil(err)
+	as.Equal([]string{"UPDATE users_01 SET age = :age WHERE id = :id AND name <> :name"}, templates)
+	as.Equal([]map[string]any{{"age": int64(18), "id": int64(1), "name": "kyden"}}, args)
+
+	_, _, err = NewExtractor("").SqlxNamed()
+	as.NotNil(err)
+}
@@ -0,0 +1,213 @@
+package sqlextractor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ExtractStream reads the statements of a SQL script or dump (e.g. mysqldump)
+// from r, and calls fn with the result of each statement, one at a time. Only
+// one statement is held in memory, so it is suitable for large dumps.
+//
+// Statements are separated by the delimiter, ; by default, which can be
+// changed by DELIMITER commands. Statements which can not be extracted are
+// passed to fn with Err set. ExtractStream stops at the first error returned
+// by fn, and returns it.
+//
+// Example:
+//
+//	f, _ := os.Open("dump.sql")
+//	err := sqlextractor.ExtractStream(f, func(r sqlextractor.StatementResult) error {
+//	  if r.Err != nil {
+//	    return nil // skip
+//	  }
+//	  fmt.Println(r.TemplatizedSQL)
+//	  return nil
+//	})
+func ExtractStream(r io.Reader, fn func(StatementResult) error) error {
+	s := newStmtScanner(r)
+
+	idx := 0
+	for {
+		stmt, err := s.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		e := NewExtractor(stmt)
+		if err := e.Extract(); err != nil {
+			if err := fn(StatementResult{Index: idx, RawSQL: stmt, Err: err}); err != nil {
+				return err
+			}
+			idx++
+
+			continue
+		}
+
+		for i := range e.templatedSQL {
+			err := fn(StatementResult{
+				Index:          idx,
+				RawSQL:         stmt,
+				TemplatizedSQL: e.templatedSQL[i],
+				TableInfos:     e.tableInfos[i],
+				Params:         e.params[i],
+				OpType:         e.opType[i],
+			})
+			if err != nil {
+				return err
+			}
+			idx++
+		}
+	}
+}
+
+// scanState is the lexical state of the stmtScanner.
+type scanState int
+
+const (
+	scanNormal scanState = iota
+	scanQuoted
+	scanLineComment
+	scanBlockComment
+)
+
+// stmtScanner splits a SQL script into statements. Quoted strings and
+// identifiers are kept as is, comments are dropped except for executable
+// comments (/*! ... */) and optimizer hints (/*+ ... */).
+type stmtScanner struct {
+	r     *bufio.Reader
+	delim string
+	buf   strings.Builder
+}
+
+func newStmtScanner(r io.Reader) *stmtScanner {
+	return &stmtScanner{r: bufio.NewReaderSize(r, 64<<10), delim: ";"}
+}
+
+// delimiterCmd is the client command which changes the delimiter.
+const delimiterCmd = "DELIMITER "
+
+// next returns the next non-empty statement without its delimiter, or io.EOF.
+//
+//nolint:gocyclo,cyclop
+func (s *stmtScanner) next() (string, error) {
+	var (
+		state scanState
+		quote byte
+	)
+
+	s.buf.Reset()
+	for {
+		if state == scanNormal && strings.TrimSpace(s.buf.String()) == "" {
+			if ok, err := s.readDelimiterCmd(); err != nil {
+				return "", err
+			} else if ok {
+				s.buf.Reset()
+				continue
+			}
+		}
+
+		c, err := s.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			if stmt := strings.TrimSpace(s.buf.String()); stmt != "" {
+				return stmt, nil
+			}
+
+			return "", io.EOF
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch state {
+		case scanQuoted:
+			s.buf.WriteByte(c)
+			if c == '\\' && quote != '`' {
+				if c, err = s.r.ReadByte(); err == nil {
+					s.buf.WriteByte(c)
+				}
+			} else if c == quote {
+				state = scanNormal
+			}
+
+			continue
+
+		case scanLineComment:
+			if c == '\n' {
+				state = scanNormal
+				s.buf.WriteByte(c)
+			}
+
+			continue
+
+		case scanBlockComment:
+			if c == '*' && s.peek("/") {
+				_, _ = s.r.ReadByte()
+				state = scanNormal
+				s.buf.WriteByte(' ')
+			}
+
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			state, quote = scanQuoted, c
+		case c == '#' || (c == '-' && (s.peek("- ") || s.peek("-\t") || s.peek("-\n") || s.peek("-\r"))):
+			state = scanLineComment
+			continue
+		case c == '/' && s.peek("*") && !s.peek("*!") && !s.peek("*+"):
+			_, _ = s.r.ReadByte()
+			state = scanBlockComment
+			continue
+		}
+
+		s.buf.WriteByte(c)
+		if c == s.delim[len(s.delim)-1] && strings.HasSuffix(s.buf.String(), s.delim) {
+			stmt := strings.TrimSpace(strings.TrimSuffix(s.buf.String(), s.delim))
+			if stmt != "" {
+				return stmt, nil
+			}
+			s.buf.Reset()
+		}
+	}
+}
+
+// peek reports whether the next bytes are prefix.
+func (s *stmtScanner) peek(prefix string) bool {
+	b, _ := s.r.Peek(len(prefix))
+	return string(b) == prefix
+}
+
+// readDelimiterCmd reads a DELIMITER command, and changes the delimiter.
+func (s *stmtScanner) readDelimiterCmd() (bool, error) {
+	// 跳过空白，DELIMITER 命令之前只能有空白
+	for {
+		b, err := s.r.Peek(1)
+		if err != nil || (b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n') {
+			break
+		}
+		_, _ = s.r.ReadByte()
+	}
+
+	b, _ := s.r.Peek(len(delimiterCmd))
+	if !strings.EqualFold(string(b), delimiterCmd) {
+		return false, nil
+	}
+
+	line, err := s.r.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	if delim := strings.TrimSpace(line[len(delimiterCmd):]); delim != "" {
+		s.delim = delim
+	}
+
+	return true, nil
+}
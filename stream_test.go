@@ -0,0 +1,96 @@
+package sqlextractor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+const dump = `-- MySQL dump 10.13  Distrib 8.0.36
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+
+--
+-- Table structure for table ` + "`users`" + `
+--
+
+DROP TABLE IF EXISTS ` + "`users`" + `;
+/* a comment; with a semicolon */
+LOCK TABLES ` + "`users`" + ` WRITE;
+INSERT INTO ` + "`users`" + ` VALUES (1,'a;b'),(2,'it\'s'),(3,"x"";y");
+UNLOCK TABLES;
+# trailing comment
+DELIMITER ;;
+CREATE TRIGGER trg BEFORE INSERT ON users FOR EACH ROW BEGIN SET NEW.a = 1; END ;;
+DELIMITER ;
+SELECT * FROM users WHERE id = 7
+`
+
+func TestStmtScanner(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	s := newStmtScanner(strings.NewReader(dump))
+	var stmts []string
+	for {
+		stmt, err := s.next()
+		if err != nil {
+			break
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	as.Equal([]string{
+		"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */",
+		"DROP TABLE IF EXISTS `users`",
+		"LOCK TABLES `users` WRITE",
+		`INSERT INTO ` + "`users`" + ` VALUES (1,'a;b'),(2,'it\'s'),(3,"x"";y")`,
+		"UNLOCK TABLES",
+		"CREATE TRIGGER trg BEFORE INSERT ON users FOR EACH ROW BEGIN SET NEW.a = 1; END",
+		"SELECT * FROM users WHERE id = 7",
+	}, stmts)
+}
+
+func TestExtractStream(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	var results []StatementResult
+	as.Nil(ExtractStream(strings.NewReader(dump), func(r StatementResult) error {
+		results = append(results, r)
+		return nil
+	}))
+	as.Equal(7, len(results))
+
+	as.Equal(3, results[3].Index)
+	as.Equal("INSERT INTO users VALUES (?, ?), (?, ?), (?, ?)", results[3].TemplatizedSQL)
+	as.Equal([]any{int64(1), "a;b", int64(2), "it's", int64(3), `x";y`}, results[3].Params)
+	as.Equal(models.SQLOperationInsert, results[3].OpType)
+
+	as.Equal("SELECT * FROM users WHERE id eq ?", results[6].TemplatizedSQL)
+	as.Equal("SELECT * FROM users WHERE id = 7", results[6].RawSQL)
+
+	// callback error stops the stream
+	stop := errors.New("stop")
+	n := 0
+	as.Equal(stop, ExtractStream(strings.NewReader(dump), func(StatementResult) error {
+		n++
+		return stop
+	}))
+	as.Equal(1, n)
+
+	// extraction errors are passed to the callback
+	results = results[:0]
+	as.Nil(ExtractStream(strings.NewReader("SELEC 1; SELECT 2"), func(r StatementResult) error {
+		results = append(results, r)
+		return nil
+	}))
+	as.Equal(2, len(results))
+	as.NotNil(results[0].Err)
+	as.Equal("SELEC 1", results[0].RawSQL)
+	as.Nil(results[1].Err)
+	as.Equal(1, results[1].Index)
+}
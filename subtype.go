@@ -0,0 +1,21 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+// SQLOpSubType refines the operation type of the INSERT statements.
+type SQLOpSubType = models.SQLOpSubType
+
+const (
+	SQLSubTypeNone         = models.SQLSubTypeNone         // not an INSERT statement
+	SQLSubTypeInsert       = models.SQLSubTypeInsert       // INSERT ... VALUES
+	SQLSubTypeUpsert       = models.SQLSubTypeUpsert       // ON DUPLICATE KEY UPDATE, ON CONFLICT
+	SQLSubTypeInsertSelect = models.SQLSubTypeInsertSelect // INSERT ... SELECT
+	SQLSubTypeReplace      = models.SQLSubTypeReplace      // REPLACE INTO
+)
+
+// OpSubTypes returns the sub-type of each statement, so that plain inserts,
+// upserts and INSERT ... SELECT, all of OpType INSERT, can be told apart. The
+// sub-type of the statements other than INSERT is SQLSubTypeNone.
+func (e *Extractor) OpSubTypes() ([]SQLOpSubType, error) {
+	return e.internal().ExtractOpSubTypes(e.rawSQL)
+}
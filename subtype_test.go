@@ -0,0 +1,32 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_OpSubTypes(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("INSERT INTO users (id, name) VALUES (1, 'a'); " +
+		"INSERT INTO users (id, name) VALUES (1, 'a') ON DUPLICATE KEY UPDATE name = VALUES(name); " +
+		"INSERT INTO archive SELECT * FROM users WHERE id < 10; " +
+		"INSERT INTO archive SELECT * FROM users ON DUPLICATE KEY UPDATE name = users.name; " +
+		"REPLACE INTO users (id, name) VALUES (1, 'a'); " +
+		"SELECT * FROM users")
+	as.Nil(extractor.Extract())
+
+	subTypes, err := extractor.OpSubTypes()
+	as.Nil(err)
+	as.Equal([]SQLOpSubType{
+		SQLSubTypeInsert,
+		SQLSubTypeUpsert,
+		SQLSubTypeInsertSelect,
+		SQLSubTypeUpsert,
+		SQLSubTypeReplace,
+		SQLSubTypeNone,
+	}, subTypes)
+	as.Equal("INSERT_SELECT", subTypes[2].String())
+}
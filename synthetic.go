@@ -0,0 +1,217 @@
+package sqlextractor
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// syntheticExtractor returns the columns of the ? placeholders of templates.
+var syntheticExtractor = packageExtractor(
+	extract.WithNamedParams(),
+	extract.WithParamMarkers(),
+)
+
+// SyntheticOption configures SyntheticParams.
+type SyntheticOption func(*syntheticConfig)
+
+type syntheticConfig struct {
+	types  map[string]string // 小写列名 -> 小写 SQL 类型
+	seed   uint64
+	now    time.Time
+	random *rand.Rand
+}
+
+// WithColumnTypes sets the SQL types of the columns, e.g. {"id": "BIGINT",
+// "status": "ENUM('new','paid')"}, matched case-insensitively with the columns
+// the placeholders are compared with or assigned to. The types of the other
+// columns are guessed from their names.
+func WithColumnTypes(types map[string]string) SyntheticOption {
+	return func(c *syntheticConfig) {
+		for column, typ := range types {
+			c.types[strings.ToLower(column)] = strings.ToLower(strings.TrimSpace(typ))
+		}
+	}
+}
+
+// WithSyntheticSeed sets the seed of the synthetic values, which are the same
+// for the same seed. Default is 1.
+func WithSyntheticSeed(seed uint64) SyntheticOption {
+	return func(c *syntheticConfig) { c.seed = seed }
+}
+
+// SyntheticParams returns the template, e.g. a TemplatizedSQL whose params
+// were redacted, rendered with SQL operators and ? placeholders by Translate,
+// and type-appropriate synthetic values of the placeholders, converted to
+// database/sql driver values, so the template can still be replayed or
+// explained without real data.
+//
+// The type of a placeholder is the type of its column given by
+// WithColumnTypes, or guessed from the column name: ids, counts and LIMIT are
+// integers, *_at and *date* are times, prices and amounts are floats, and
+// strings otherwise.
+//
+// Example:
+//
+//	sql, params, err := sqlextractor.SyntheticParams("SELECT * FROM orders WHERE user_id eq ? AND created_at gt ? LIMIT ?")
+//	// sql:    SELECT * FROM `orders` WHERE `user_id`=? AND `created_at`>? LIMIT ?
+//	// params: [int64(417) time.Time(...) int64(10)]
+//	rows, err := db.QueryContext(ctx, "EXPLAIN "+sql, params...)
+func SyntheticParams(template string, opts ...SyntheticOption) (string, []any, error) {
+	c := &syntheticConfig{types: make(map[string]string), seed: 1, now: time.Now().UTC().Truncate(time.Second)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.random = rand.New(rand.NewPCG(c.seed, c.seed))
+
+	sql, _, err := Translate(template, DialectMySQL, DialectMySQL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	columns, err := syntheticExtractor.ParamColumns(sql)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var params []any
+	for _, cols := range columns {
+		for _, column := range cols {
+			params = append(params, c.value(strings.ToLower(column)))
+		}
+	}
+
+	return sql, params, nil
+}
+
+// value returns a synthetic value of the column.
+func (c *syntheticConfig) value(column string) any {
+	if typ, ok := c.types[column]; ok {
+		return c.typedValue(typ)
+	}
+
+	switch {
+	case column == "limit":
+		return int64(10)
+	case column == "offset":
+		return int64(0)
+	case column == "id" || strings.HasSuffix(column, "_id"):
+		return c.random.Int64N(1000) + 1
+	case strings.HasSuffix(column, "uuid") || strings.HasSuffix(column, "guid"):
+		return c.uuid()
+	case strings.HasSuffix(column, "_at") || strings.HasSuffix(column, "_on") ||
+		strings.Contains(column, "date") || strings.Contains(column, "time"):
+		return c.time()
+	case strings.HasPrefix(column, "is_") || strings.HasPrefix(column, "has_") ||
+		column == "enabled" || column == "active" || column == "deleted":
+		return c.random.Int64N(2)
+	case strings.Contains(column, "price") || strings.Contains(column, "amount") || strings.Contains(column, "total") ||
+		strings.HasSuffix(column, "rate") || strings.HasSuffix(column, "ratio") || strings.HasSuffix(column, "score"):
+		return float64(c.random.IntN(100000)) / 100
+	case strings.Contains(column, "count") || strings.HasPrefix(column, "num") || column == "age" ||
+		strings.Contains(column, "qty") || strings.Contains(column, "quantity") || column == "year" || column == "size":
+		return c.random.Int64N(100)
+	case strings.Contains(column, "email"):
+		return "user" + strconv.Itoa(c.random.IntN(1000)) + "@example.com"
+	}
+
+	return c.text(8)
+}
+
+// typedValue returns a synthetic value of the SQL type, e.g. varchar(32).
+func (c *syntheticConfig) typedValue(typ string) any {
+	base, args, _ := strings.Cut(typ, "(")
+	args = strings.TrimSuffix(strings.TrimSpace(args), ")")
+	base = strings.TrimSpace(strings.TrimSuffix(base, " unsigned"))
+
+	switch base {
+	case "bool", "boolean", "bit":
+		return c.random.Int64N(2)
+	case "tinyint":
+		return c.random.Int64N(100)
+	case "int", "integer", "smallint", "mediumint", "bigint", "serial":
+		return c.random.Int64N(1000) + 1
+	case "year":
+		return int64(c.now.Year())
+	case "decimal", "numeric", "float", "double", "real":
+		return float64(c.random.IntN(100000)) / 100
+	case "date":
+		return c.time().Truncate(24 * time.Hour)
+	case "datetime", "timestamp":
+		return c.time()
+	case "time":
+		return c.time().Format(time.TimeOnly)
+	case "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob":
+		return []byte(c.text(8))
+	case "json":
+		return "{}"
+	case "uuid", "uniqueidentifier":
+		return c.uuid()
+	case "enum", "set":
+		if values := splitEnumValues(args); len(values) > 0 {
+			return values[c.random.IntN(len(values))]
+		}
+	case "char", "varchar", "nchar", "nvarchar":
+		if n, err := strconv.Atoi(args); err == nil && n > 0 {
+			return c.text(min(n, 8))
+		}
+	}
+
+	return c.text(8)
+}
+
+// time returns a time within the last 30 days.
+func (c *syntheticConfig) time() time.Time {
+	return c.now.Add(-time.Duration(c.random.Int64N(30*24*3600)) * time.Second)
+}
+
+// text returns a string of n lowercase letters and digits.
+func (c *syntheticConfig) text(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, n)
+	for idx := range b {
+		b[idx] = chars[c.random.IntN(len(chars))]
+	}
+
+	return string(b)
+}
+
+// uuid returns a random version 4 UUID.
+func (c *syntheticConfig) uuid() string {
+	var b [16]byte
+	for idx := range b {
+		b[idx] = byte(c.random.UintN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	const hex = "0123456789abcdef"
+	var builder strings.Builder
+	for idx, v := range b {
+		if idx == 4 || idx == 6 || idx == 8 || idx == 10 {
+			builder.WriteByte('-')
+		}
+		builder.WriteByte(hex[v>>4])
+		builder.WriteByte(hex[v&0x0f])
+	}
+
+	return builder.String()
+}
+
+// splitEnumValues returns the values of the ENUM or SET arguments, e.g.
+// 'new','paid'.
+func splitEnumValues(args string) []string {
+	var values []string
+	for _, v := range strings.Split(args, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+			values = append(values, strings.ReplaceAll(v[1:len(v)-1], "''", "'"))
+		}
+	}
+
+	return values
+}
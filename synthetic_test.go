@@ -0,0 +1,50 @@
+package sqlextractor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntheticParams(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql, params, err := SyntheticParams("SELECT * FROM orders WHERE user_id eq ? AND created_at gt ? AND " +
+		"price lt ? AND note like ? AND is_paid eq ? LIMIT ?, ?")
+	as.Nil(err)
+	as.Equal("SELECT * FROM `orders` WHERE `user_id`=? AND `created_at`>? AND `price`<? AND `note` LIKE ? AND `is_paid`=? LIMIT ?,?", sql)
+	as.Equal(7, len(params))
+	as.IsType(int64(0), params[0])
+	as.IsType(time.Time{}, params[1])
+	as.IsType(float64(0), params[2])
+	as.IsType("", params[3])
+	as.Contains([]any{int64(0), int64(1)}, params[4])
+	as.Equal([]any{int64(0), int64(10)}, params[5:])
+
+	// the same seed gives the same values
+	_, again, err := SyntheticParams("SELECT * FROM orders WHERE user_id eq ? AND note like ?")
+	as.Nil(err)
+	as.Equal(params[0], again[0])
+	_, other, err := SyntheticParams("SELECT * FROM orders WHERE user_id eq ? AND note like ?", WithSyntheticSeed(42))
+	as.Nil(err)
+	as.Equal(2, len(other))
+
+	// column type hints
+	_, params, err = SyntheticParams("INSERT INTO orders (id, status, code, paid_on, raw, uid) VALUES (?, ?, ?, ?, ?, ?)",
+		WithColumnTypes(map[string]string{
+			"ID": "bigint unsigned", "status": "ENUM('new','paid')", "code": "CHAR(2)",
+			"paid_on": "DATE", "raw": "VARBINARY(16)", "uid": "UUID",
+		}))
+	as.Nil(err)
+	as.IsType(int64(0), params[0])
+	as.Contains([]any{"new", "paid"}, params[1])
+	as.Len(params[2], 2)
+	as.Equal(params[3].(time.Time).Truncate(24*time.Hour), params[3])
+	as.IsType([]byte{}, params[4])
+	as.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, params[5])
+
+	_, _, err = SyntheticParams("SELEC")
+	as.NotNil(err)
+}
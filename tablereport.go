@@ -0,0 +1,209 @@
+package sqlextractor
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// TableAccess is the access pattern of a table, see TableReport.
+type TableAccess struct {
+	Table      string  `json:"table"`      // templatized table name with schema, e.g. db_?.orders
+	Statements int64   `json:"statements"` // statements using the table
+	Reads      int64   `json:"reads"`      // SELECT statements
+	Writes     int64   `json:"writes"`     // INSERT, UPDATE and DELETE statements
+	ReadRatio  float64 `json:"read_ratio"` // Reads / (Reads + Writes), 0 without reads and writes
+
+	// Predicates are the predicates on the columns of the table, most used
+	// first.
+	Predicates []PredicateUsage `json:"predicates"`
+
+	// WhereColumns are the columns of the equality and range predicates, and
+	// OrderColumns of ORDER BY, most used first.
+	WhereColumns []string `json:"where_columns"`
+	OrderColumns []string `json:"order_columns"`
+
+	// Digests are the digests of the statements using the table, most
+	// executed first.
+	Digests []string `json:"digests"`
+}
+
+// PredicateUsage is a predicate on a column, and the number of statements
+// using it.
+type PredicateUsage struct {
+	Column string               `json:"column"`
+	Type   models.PredicateType `json:"type"`
+	Count  int64                `json:"count"`
+}
+
+// TableReport returns the access pattern of each table of the aggregates, for
+// capacity planning: the read/write ratio, the most common predicates, the
+// columns used in WHERE and ORDER BY, and the digests. The tables are sorted
+// by statements, most used first.
+//
+// The predicates are resolved from the first example of each aggregate, so
+// they are missing for the aggregates without examples, see WithMaxExamples.
+//
+// Example:
+//
+//	aggregator := sqlextractor.NewAggregator()
+//	_ = aggregator.AddSQL("SELECT * FROM orders WHERE user_id = 1 ORDER BY created_at", nil)
+//	report := sqlextractor.TableReport(aggregator.Stats())
+//	// orders: 1 read, where (user_id), order by (created_at)
+func TableReport(stats []DigestStats) []TableAccess {
+	var (
+		tables = make(map[string]*tableAccess)
+		order  []*tableAccess
+	)
+
+	for idx := range stats {
+		s := &stats[idx]
+
+		names := templatizedNames(s)
+		seen := make(map[string]bool, len(s.TableInfos))
+		for _, ti := range s.TableInfos {
+			name, _ := ti.TemplatizedTableNameWithSchema()
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			t, ok := tables[name]
+			if !ok {
+				t = newTableAccess(name)
+				tables[name] = t
+				order = append(order, t)
+			}
+			t.add(s)
+		}
+
+		for _, p := range examplePredicates(s) {
+			original, _ := models.NewTableInfo(p.Schema(), p.Table()).TableNameWithSchema()
+			if t, ok := tables[names[original]]; ok {
+				t.predicates[predicateKey{column: p.Column(), tp: p.Type()}] += s.Count
+			}
+		}
+	}
+
+	report := make([]TableAccess, len(order))
+	for idx, t := range order {
+		report[idx] = t.report()
+	}
+	slices.SortStableFunc(report, func(a, b TableAccess) int { return cmp.Compare(b.Statements, a.Statements) })
+
+	return report
+}
+
+// TableReport returns the access pattern of each table of the aggregates, see
+// TableReport.
+func (a *Aggregator) TableReport() []TableAccess {
+	return TableReport(a.Stats())
+}
+
+// templatizedNames returns the templatized names of the tables of the
+// aggregate by their original names, to attribute the columns of its example
+// to the tables of the reports.
+func templatizedNames(s *DigestStats) map[string]string {
+	names := make(map[string]string, len(s.TableInfos))
+	for _, ti := range s.TableInfos {
+		name, _ := ti.TemplatizedTableNameWithSchema()
+		original, _ := ti.TableNameWithSchema()
+		names[original] = name
+		if ti.TemplatizedSchema() == "" && ti.Schema() != "" {
+			names[ti.TableName()] = name // 默认 schema，例如日志中连接的当前数据库
+		}
+	}
+
+	return names
+}
+
+// examplePredicates returns the predicates of the first example of the
+// aggregate, nil if it has none or can not be parsed, e.g. truncated.
+func examplePredicates(s *DigestStats) []*models.Predicate {
+	if len(s.Examples) == 0 {
+		return nil
+	}
+
+	predicates, err := defaultExtractor.ExtractPredicates(s.Examples[0])
+	if err != nil || len(predicates) != 1 {
+		return nil
+	}
+
+	return predicates[0]
+}
+
+type predicateKey struct {
+	column string
+	tp     models.PredicateType
+}
+
+// tableAccess accumulates the access pattern of a table.
+type tableAccess struct {
+	TableAccess
+	predicates map[predicateKey]int64
+	digests    map[string]int64
+}
+
+func newTableAccess(name string) *tableAccess {
+	return &tableAccess{
+		TableAccess: TableAccess{Table: name},
+		predicates:  make(map[predicateKey]int64),
+		digests:     make(map[string]int64),
+	}
+}
+
+// add accumulates the statements of the aggregate.
+func (t *tableAccess) add(s *DigestStats) {
+	t.Statements += s.Count
+	t.digests[s.Digest] += s.Count
+
+	switch s.OpType {
+	case models.SQLOperationSelect, models.SQLOperationBulkUnload:
+		t.Reads += s.Count
+	case models.SQLOperationInsert, models.SQLOperationUpdate, models.SQLOperationDelete, models.SQLOperationBulkLoad:
+		t.Writes += s.Count
+	}
+}
+
+// report returns the access pattern, with the predicates, columns and digests
+// sorted by count, then by name.
+func (t *tableAccess) report() TableAccess {
+	r := t.TableAccess
+	if r.Reads+r.Writes > 0 {
+		r.ReadRatio = float64(r.Reads) / float64(r.Reads+r.Writes)
+	}
+
+	r.Predicates = make([]PredicateUsage, 0, len(t.predicates))
+	where, orderBy := make(map[string]int64), make(map[string]int64)
+	for key, count := range t.predicates {
+		r.Predicates = append(r.Predicates, PredicateUsage{Column: key.column, Type: key.tp, Count: count})
+		if key.tp == models.PredicateOrder {
+			orderBy[key.column] += count
+		} else {
+			where[key.column] += count
+		}
+	}
+	slices.SortFunc(r.Predicates, func(a, b PredicateUsage) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Column, b.Column), cmp.Compare(a.Type, b.Type))
+	})
+
+	r.WhereColumns = sortedByCount(where)
+	r.OrderColumns = sortedByCount(orderBy)
+	r.Digests = sortedByCount(t.digests)
+
+	return r
+}
+
+// sortedByCount returns the keys sorted by count, most first, then by key.
+func sortedByCount(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	return keys
+}
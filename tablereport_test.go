@@ -0,0 +1,58 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+func TestTableReport(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	aggregator := NewAggregator()
+	as.Nil(aggregator.AddSQL("SELECT * FROM orders o JOIN users u ON u.id = o.user_id "+
+		"WHERE o.user_id = 1 AND o.created_at > '2024-01-01' ORDER BY o.created_at", nil))
+	as.Nil(aggregator.AddSQL("SELECT * FROM orders o JOIN users u ON u.id = o.user_id "+
+		"WHERE o.user_id = 2 AND o.created_at > '2024-02-01' ORDER BY o.created_at", nil))
+	as.Nil(aggregator.AddSQL("UPDATE orders SET state = 'paid' WHERE id = 3", nil))
+	as.Nil(aggregator.AddSQL("SELECT name FROM users WHERE id IN (1, 2)", nil))
+	as.Nil(aggregator.AddSQL("SELECT name FROM users WHERE id IN (3, 4)", nil))
+	as.Nil(aggregator.AddSQL("SELECT * FROM db_1.tb_23 WHERE k = 1; SELECT * FROM db_2.tb_7 WHERE k = 2", nil))
+
+	stats := aggregator.Stats()
+	digest := func(idx int) string { return stats[idx].Digest }
+
+	report := aggregator.TableReport()
+	as.Len(report, 3)
+
+	as.Equal(TableAccess{
+		Table: "users", Statements: 4, Reads: 4, ReadRatio: 1,
+		Predicates:   []PredicateUsage{{Column: "id", Type: models.PredicateEqual, Count: 2}},
+		WhereColumns: []string{"id"},
+		OrderColumns: []string{},
+		Digests:      []string{min(digest(0), digest(2)), max(digest(0), digest(2))}, // 次数相同时按 digest 排序
+	}, report[0])
+
+	as.Equal(TableAccess{
+		Table: "orders", Statements: 3, Reads: 2, Writes: 1, ReadRatio: 2.0 / 3,
+		Predicates: []PredicateUsage{
+			{Column: "created_at", Type: models.PredicateOrder, Count: 2},
+			{Column: "created_at", Type: models.PredicateRange, Count: 2},
+			{Column: "user_id", Type: models.PredicateEqual, Count: 2},
+			{Column: "id", Type: models.PredicateEqual, Count: 1},
+		},
+		WhereColumns: []string{"created_at", "user_id", "id"},
+		OrderColumns: []string{"created_at"},
+		Digests:      []string{digest(0), digest(1)},
+	}, report[1])
+
+	// 分表按模板化表名汇总
+	as.Equal("db_?.tb_?", report[2].Table)
+	as.Equal(int64(2), report[2].Statements)
+	as.Equal([]string{"k"}, report[2].WhereColumns)
+
+	as.Empty(TableReport(nil))
+}
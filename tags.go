@@ -0,0 +1,19 @@
+package sqlextractor
+
+// Tags is caller metadata attached to an extraction, e.g. service, endpoint or
+// user. Tags are not interpreted, they flow unchanged into the statement
+// results, the Envelope and the CLI output, so pipelines need no parallel
+// bookkeeping.
+type Tags map[string]string
+
+// SetTags attaches the tags to the results of the extractor. The tags are not
+// copied, they should not be modified afterward.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id = 1")
+//	extractor.SetTags(Tags{"service": "billing", "endpoint": "/invoices"})
+func (e *Extractor) SetTags(tags Tags) { e.tags = tags }
+
+// Tags returns the tags attached by SetTags.
+func (e *Extractor) Tags() Tags { return e.tags }
@@ -0,0 +1,32 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_Tags(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	tags := Tags{"service": "billing", "endpoint": "/invoices"}
+
+	e := NewExtractor("SELECT * FROM users WHERE id = 1; SHOW TABLES")
+	e.SetTags(tags)
+	as.Equal(tags, e.Tags())
+
+	var seen []Tags
+	e.Use(func(r StatementResult) StatementResult {
+		seen = append(seen, r.Tags)
+		return r
+	})
+	as.Nil(e.Extract())
+	as.Equal([]Tags{tags, tags}, seen)
+
+	env := e.Envelope()
+	as.Equal(tags, env.Tags)
+	as.Contains(string(env.JSON()), `"tags":{"endpoint":"/invoices","service":"billing"}`)
+
+	as.NotContains(string(ExtractEnvelope("SELECT 1").JSON()), `"tags"`)
+}
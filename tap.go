@@ -0,0 +1,534 @@
+package sqlextractor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// tapExtractor collects the parameter markers of prepared statements, so the
+// values bound by COM_STMT_EXECUTE can be placed among the literals.
+var tapExtractor = packageExtractor(extract.WithParamMarkers())
+
+// MySQL client/server protocol constants.
+const (
+	comQuery        = 0x03
+	comStmtPrepare  = 0x16
+	comStmtExecute  = 0x17
+	comStmtClose    = 0x19
+	maxPacketLength = 1<<24 - 1
+	packetHeaderLen = 4
+	unsignedFlag    = 0x80
+)
+
+// MySQL column types of binary protocol values.
+const (
+	typeTiny       = 1
+	typeShort      = 2
+	typeLong       = 3
+	typeFloat      = 4
+	typeDouble     = 5
+	typeNull       = 6
+	typeTimestamp  = 7
+	typeLongLong   = 8
+	typeInt24      = 9
+	typeDate       = 10
+	typeTime       = 11
+	typeDateTime   = 12
+	typeYear       = 13
+	typeNewDecimal = 0xf6
+	typeVarString  = 0xfd
+)
+
+// ProtocolTap decodes the MySQL client/server protocol of captured
+// connections, e.g. TCP streams reassembled from a pcap or mirrored by a
+// proxy such as ProxySQL, and extracts the statements of COM_QUERY and
+// COM_STMT_EXECUTE commands. Prepared statements are correlated by the
+// statement id returned by the server for COM_STMT_PREPARE, so both
+// directions of the connections should be fed.
+//
+// Each direction must be fed in order, from the first command after the
+// handshake; chunks may split packets anywhere. Compressed and TLS connections
+// are not supported. It is safe for concurrent use.
+type ProtocolTap struct {
+	mu    sync.Mutex
+	conns map[string]*tapConn
+}
+
+// tapConn is the state of a captured connection.
+type tapConn struct {
+	client, server []byte // partial packets
+
+	pending  []pendingCmd // commands waiting for their response, in order
+	prepared map[uint32]*tapStmt
+}
+
+// pendingCmd is a command waiting for its response.
+type pendingCmd struct {
+	cmd  byte
+	stmt *tapStmt // COM_STMT_PREPARE only
+}
+
+// tapStmt is a prepared statement of a connection.
+type tapStmt struct {
+	sql    string
+	result StatementResult // template, params are ParamMarker placeholders
+	params int
+	types  []byte // parameter types, 2 bytes each, bound by the last execute
+}
+
+// NewProtocolTap creates a new ProtocolTap.
+func NewProtocolTap() *ProtocolTap {
+	return &ProtocolTap{conns: make(map[string]*tapConn)}
+}
+
+func (t *ProtocolTap) conn(id string) *tapConn {
+	c, ok := t.conns[id]
+	if !ok {
+		c = &tapConn{prepared: make(map[uint32]*tapStmt)}
+		t.conns[id] = c
+	}
+
+	return c
+}
+
+// Close forgets the state of the connection.
+func (t *ProtocolTap) Close(conn string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.conns, conn)
+}
+
+// ClientData feeds data sent by the client of the connection, and returns the
+// results of the executed statements. Statements which can not be extracted
+// are returned with Err set.
+func (t *ProtocolTap) ClientData(conn string, data []byte) ([]StatementResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.conn(conn)
+
+	var (
+		results []StatementResult
+		payload []byte
+		seq     byte
+		err     error
+	)
+	c.client = append(c.client, data...)
+	for {
+		if payload, seq, c.client, err = nextPacket(c.client); err != nil || payload == nil {
+			return results, err
+		}
+
+		// 只处理命令，忽略握手等其他报文
+		if seq != 0 || len(payload) == 0 {
+			continue
+		}
+
+		switch payload[0] {
+		case comQuery:
+			c.pending = append(c.pending, pendingCmd{cmd: comQuery})
+			results = append(results, extractQueryResults(string(payload[1:]))...)
+
+		case comStmtPrepare:
+			stmt := &tapStmt{sql: string(payload[1:])}
+			stmt.result = prepareResult(stmt.sql)
+			c.pending = append(c.pending, pendingCmd{cmd: comStmtPrepare, stmt: stmt})
+
+		case comStmtExecute:
+			c.pending = append(c.pending, pendingCmd{cmd: comStmtExecute})
+			if res, ok := c.execute(payload); ok {
+				results = append(results, res)
+			}
+
+		case comStmtClose:
+			if len(payload) >= 5 {
+				delete(c.prepared, binary.LittleEndian.Uint32(payload[1:]))
+			}
+
+		default:
+			// 其他命令 (COM_PING, COM_INIT_DB, ...) 都有响应
+			c.pending = append(c.pending, pendingCmd{cmd: payload[0]})
+		}
+	}
+}
+
+// ServerData feeds data sent by the server of the connection, it is used to
+// correlate prepared statements with their statement ids.
+func (t *ProtocolTap) ServerData(conn string, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.conn(conn)
+
+	var (
+		payload []byte
+		seq     byte
+		err     error
+	)
+	c.server = append(c.server, data...)
+	for {
+		if payload, seq, c.server, err = nextPacket(c.server); err != nil || payload == nil {
+			return err
+		}
+
+		// 响应的第一个报文序号为 1，后续的报文属于同一个响应
+		if seq != 1 || len(c.pending) == 0 {
+			continue
+		}
+
+		cmd := c.pending[0]
+		c.pending = c.pending[1:]
+
+		// COM_STMT_PREPARE_OK: 0x00, statement id, columns, params
+		if cmd.cmd == comStmtPrepare && len(payload) >= 9 && payload[0] == 0x00 {
+			cmd.stmt.params = int(binary.LittleEndian.Uint16(payload[7:]))
+			c.prepared[binary.LittleEndian.Uint32(payload[1:])] = cmd.stmt
+		}
+	}
+}
+
+// nextPacket returns the payload and sequence id of the next complete packet,
+// and the remaining data. The payload is nil if the packet is not complete.
+func nextPacket(data []byte) (payload []byte, seq byte, rest []byte, err error) {
+	var buf []byte
+	for off := 0; ; {
+		if len(data)-off < packetHeaderLen {
+			return nil, 0, data, nil
+		}
+
+		n := int(data[off]) | int(data[off+1])<<8 | int(data[off+2])<<16
+		if len(data)-off-packetHeaderLen < n {
+			return nil, 0, data, nil
+		}
+
+		if off == 0 {
+			seq = data[3]
+		}
+		buf = append(buf, data[off+packetHeaderLen:off+packetHeaderLen+n]...)
+		off += packetHeaderLen + n
+
+		// 长度为 0xffffff 的报文后面还有后续报文
+		if n < maxPacketLength {
+			return buf, seq, data[off:], nil
+		}
+	}
+}
+
+// extractQueryResults extracts the statements of a text query.
+func extractQueryResults(sql string) []StatementResult {
+	templatedSQL, tableInfos, params, opType, err := defaultExtractor.Extract(sql)
+	if err != nil {
+		return []StatementResult{{RawSQL: sql, Err: err}}
+	}
+
+	results := make([]StatementResult, len(templatedSQL))
+	for idx := range templatedSQL {
+		results[idx] = StatementResult{
+			Index:          idx,
+			RawSQL:         sql,
+			TemplatizedSQL: templatedSQL[idx],
+			TableInfos:     tableInfos[idx],
+			Params:         params[idx],
+			OpType:         opType[idx],
+		}
+	}
+
+	return results
+}
+
+// prepareResult extracts the template of a prepared statement.
+func prepareResult(sql string) StatementResult {
+	templatedSQL, tableInfos, params, opType, err := tapExtractor.Extract(sql)
+	if err != nil {
+		return StatementResult{RawSQL: sql, Err: err}
+	}
+	if len(templatedSQL) != 1 {
+		return StatementResult{RawSQL: sql, Err: errors.New("prepared statement should be a single statement")}
+	}
+
+	return StatementResult{
+		RawSQL:         sql,
+		TemplatizedSQL: templatedSQL[0],
+		TableInfos:     tableInfos[0],
+		Params:         params[0],
+		OpType:         opType[0],
+	}
+}
+
+// execute returns the result of a COM_STMT_EXECUTE, with the bound values
+// placed among the literals of the prepared statement. It is false if the
+// statement is unknown, e.g. prepared before the capture started.
+func (c *tapConn) execute(payload []byte) (StatementResult, bool) {
+	if len(payload) < 10 {
+		return StatementResult{}, false
+	}
+
+	stmt, ok := c.prepared[binary.LittleEndian.Uint32(payload[1:])]
+	if !ok {
+		return StatementResult{}, false
+	}
+
+	res := stmt.result
+	if res.Err != nil {
+		return res, true
+	}
+
+	values, err := stmt.decodeParams(payload[10:])
+	if err != nil {
+		res.Err = err
+		return res, true
+	}
+
+	res.Params = make([]any, len(stmt.result.Params))
+	for idx, p := range stmt.result.Params {
+		if m, ok := p.(extract.ParamMarker); ok && int(m) < len(values) {
+			p = values[m]
+		}
+		res.Params[idx] = p
+	}
+
+	return res, true
+}
+
+// decodeParams decodes the parameter values of a COM_STMT_EXECUTE, after the
+// statement id, flags and iteration count.
+func (s *tapStmt) decodeParams(b []byte) ([]any, error) {
+	if s.params == 0 {
+		return nil, nil
+	}
+
+	nullBitmap := (s.params + 7) / 8
+	if len(b) < nullBitmap+1 {
+		return nil, errors.New("malformed COM_STMT_EXECUTE")
+	}
+	nulls, b := b[:nullBitmap], b[nullBitmap:]
+
+	// new-params-bound-flag: 类型只在第一次执行 (或类型变化) 时发送
+	bound, b := b[0], b[1:]
+	if bound == 1 {
+		if len(b) < 2*s.params {
+			return nil, errors.New("malformed COM_STMT_EXECUTE")
+		}
+		s.types, b = append(s.types[:0], b[:2*s.params]...), b[2*s.params:]
+	}
+	if len(s.types) != 2*s.params {
+		return nil, errors.New("COM_STMT_EXECUTE without parameter types")
+	}
+
+	values := make([]any, s.params)
+	for idx := range values {
+		if nulls[idx/8]&(1<<(idx%8)) != 0 {
+			continue
+		}
+
+		var err error
+		if values[idx], b, err = decodeValue(s.types[2*idx], s.types[2*idx+1]&unsignedFlag != 0, b); err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", idx+1, err)
+		}
+	}
+
+	return values, nil
+}
+
+var errShortValue = errors.New("malformed binary value")
+
+// decodeValue decodes a binary protocol value, integers are int64 (uint64 if
+// unsigned), dates and times are formatted as in SQL text.
+//
+//nolint:gocyclo,cyclop
+func decodeValue(tp byte, unsigned bool, b []byte) (any, []byte, error) {
+	fixed := func(n int) ([]byte, []byte, error) {
+		if len(b) < n {
+			return nil, nil, errShortValue
+		}
+
+		return b[:n], b[n:], nil
+	}
+
+	integer := func(u uint64, signed int64) any {
+		if unsigned {
+			return u
+		}
+
+		return signed
+	}
+
+	switch tp {
+	case typeNull:
+		return nil, b, nil
+
+	case typeTiny:
+		v, rest, err := fixed(1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return integer(uint64(v[0]), int64(int8(v[0]))), rest, nil
+
+	case typeShort, typeYear:
+		v, rest, err := fixed(2)
+		if err != nil {
+			return nil, nil, err
+		}
+		u := binary.LittleEndian.Uint16(v)
+
+		return integer(uint64(u), int64(int16(u))), rest, nil
+
+	case typeLong, typeInt24:
+		v, rest, err := fixed(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		u := binary.LittleEndian.Uint32(v)
+
+		return integer(uint64(u), int64(int32(u))), rest, nil
+
+	case typeLongLong:
+		v, rest, err := fixed(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		u := binary.LittleEndian.Uint64(v)
+
+		return integer(u, int64(u)), rest, nil
+
+	case typeFloat:
+		v, rest, err := fixed(4)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(v))), rest, nil
+
+	case typeDouble:
+		v, rest, err := fixed(8)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return math.Float64frombits(binary.LittleEndian.Uint64(v)), rest, nil
+
+	case typeDate, typeDateTime, typeTimestamp:
+		n, rest, err := fixed(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		b = rest
+		v, rest, err := fixed(int(n[0]))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return formatDateTime(tp, v), rest, nil
+
+	case typeTime:
+		n, rest, err := fixed(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		b = rest
+		v, rest, err := fixed(int(n[0]))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return formatTime(v), rest, nil
+	}
+
+	// 其余类型 (字符串、DECIMAL、BLOB、JSON, ...) 都是 length-encoded string
+	n, rest, ok := lengthEncodedInt(b)
+	if !ok || uint64(len(rest)) < n {
+		return nil, nil, errShortValue
+	}
+
+	return string(rest[:n]), rest[n:], nil
+}
+
+// lengthEncodedInt decodes a length-encoded integer.
+func lengthEncodedInt(b []byte) (uint64, []byte, bool) {
+	if len(b) == 0 {
+		return 0, nil, false
+	}
+
+	var n int
+	switch b[0] {
+	case 0xfc:
+		n = 2
+	case 0xfd:
+		n = 3
+	case 0xfe:
+		n = 8
+	default:
+		return uint64(b[0]), b[1:], b[0] < 0xfb
+	}
+
+	if len(b) < 1+n {
+		return 0, nil, false
+	}
+
+	var v uint64
+	for idx := range n {
+		v |= uint64(b[1+idx]) << (8 * idx)
+	}
+
+	return v, b[1+n:], true
+}
+
+// formatDateTime formats a binary DATE, DATETIME or TIMESTAMP value.
+func formatDateTime(tp byte, v []byte) string {
+	var year, month, day, hour, minute, second, micro int
+	if len(v) >= 4 {
+		year, month, day = int(binary.LittleEndian.Uint16(v)), int(v[2]), int(v[3])
+	}
+	if len(v) >= 7 {
+		hour, minute, second = int(v[4]), int(v[5]), int(v[6])
+	}
+	if len(v) >= 11 {
+		micro = int(binary.LittleEndian.Uint32(v[7:]))
+	}
+
+	s := fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+	if tp == typeDate {
+		return s
+	}
+
+	s += fmt.Sprintf(" %02d:%02d:%02d", hour, minute, second)
+	if micro > 0 {
+		s += "." + fmt.Sprintf("%06d", micro)
+	}
+
+	return s
+}
+
+// formatTime formats a binary TIME value.
+func formatTime(v []byte) string {
+	var (
+		negative                   bool
+		days, hour, minute, second int
+		micro                      int
+	)
+	if len(v) >= 8 {
+		negative = v[0] == 1
+		days = int(binary.LittleEndian.Uint32(v[1:]))
+		hour, minute, second = int(v[5]), int(v[6]), int(v[7])
+	}
+	if len(v) >= 12 {
+		micro = int(binary.LittleEndian.Uint32(v[8:]))
+	}
+
+	s := fmt.Sprintf("%02d:%02d:%02d", days*24+hour, minute, second)
+	if micro > 0 {
+		s += "." + fmt.Sprintf("%06d", micro)
+	}
+	if negative {
+		s = "-" + s
+	}
+
+	return s
+}
@@ -0,0 +1,165 @@
+package sqlextractor
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// packet frames a MySQL protocol payload.
+func packet(seq byte, payload ...byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+func command(cmd byte, arg string) []byte {
+	return packet(0, append([]byte{cmd}, arg...)...)
+}
+
+func prepareOK(stmtID uint32, params uint16) []byte {
+	b := []byte{0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(b[1:], stmtID)
+	binary.LittleEndian.PutUint16(b[7:], params)
+
+	return packet(1, b...)
+}
+
+func TestProtocolTap_Query(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	tap := NewProtocolTap()
+
+	// handshake response is ignored
+	results, err := tap.ClientData("c1", packet(1, 0x8d, 0xa6, 0x0f, 0x00))
+	as.Nil(err)
+	as.Empty(results)
+
+	data := command(comQuery, "SELECT * FROM users WHERE id = 1")
+	results, err = tap.ClientData("c1", data[:5])
+	as.Nil(err)
+	as.Empty(results)
+
+	results, err = tap.ClientData("c1", data[5:])
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.Equal("SELECT * FROM users WHERE id eq ?", results[0].TemplatizedSQL)
+	as.Equal([]any{int64(1)}, results[0].Params)
+	as.Equal(models.SQLOperationSelect, results[0].OpType)
+
+	results, err = tap.ClientData("c1", command(comQuery, "SELEC 1"))
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.NotNil(results[0].Err)
+	as.Equal("SELEC 1", results[0].RawSQL)
+}
+
+func TestProtocolTap_PrepareExecute(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	tap := NewProtocolTap()
+
+	results, err := tap.ClientData("c1", command(comStmtPrepare,
+		"SELECT * FROM users WHERE status = 1 AND id IN (?, ?) LIMIT ?"))
+	as.Nil(err)
+	as.Empty(results)
+	as.Nil(tap.ServerData("c1", prepareOK(7, 3)))
+
+	exec := []byte{comStmtExecute, 7, 0, 0, 0, 0, 1, 0, 0, 0}
+	exec = append(exec, 0x00, 1) // null bitmap, new params bound
+	exec = append(exec, typeLongLong, 0, typeVarString, 0, typeLong, unsignedFlag)
+	exec = append(exec, 42, 0, 0, 0, 0, 0, 0, 0)
+	exec = append(exec, 3, 'a', 'b', 'c')
+	exec = append(exec, 10, 0, 0, 0)
+
+	results, err = tap.ClientData("c1", packet(0, exec...))
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.Nil(results[0].Err)
+	as.Equal("SELECT * FROM users WHERE status eq ? and id IN (?, ?) LIMIT ?", results[0].TemplatizedSQL)
+	as.Equal([]any{int64(1), int64(42), "abc", uint64(10)}, results[0].Params)
+
+	// types are only sent with the first execute, the second param is NULL
+	exec = []byte{comStmtExecute, 7, 0, 0, 0, 0, 1, 0, 0, 0}
+	exec = append(exec, 0x02, 0)
+	exec = append(exec, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	exec = append(exec, 5, 0, 0, 0)
+
+	results, err = tap.ClientData("c1", packet(0, exec...))
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.Equal([]any{int64(1), int64(-1), nil, uint64(5)}, results[0].Params)
+
+	// other connections do not share statements
+	results, err = tap.ClientData("c2", packet(0, exec...))
+	as.Nil(err)
+	as.Empty(results)
+
+	// closed statements are forgotten
+	results, err = tap.ClientData("c1", append(
+		packet(0, comStmtClose, 7, 0, 0, 0),
+		packet(0, exec...)...))
+	as.Nil(err)
+	as.Empty(results)
+}
+
+func TestProtocolTap_PipelinedResponses(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+	tap := NewProtocolTap()
+
+	_, err := tap.ClientData("c1", command(comQuery, "SELECT 1"))
+	as.Nil(err)
+	_, err = tap.ClientData("c1", command(comStmtPrepare, "DELETE FROM users WHERE id = ?"))
+	as.Nil(err)
+
+	// OK of the query, then PREPARE_OK split in two chunks
+	as.Nil(tap.ServerData("c1", packet(1, 0x00, 0, 0, 2, 0, 0, 0)))
+	ok := prepareOK(3, 1)
+	as.Nil(tap.ServerData("c1", ok[:6]))
+	as.Nil(tap.ServerData("c1", ok[6:]))
+
+	exec := []byte{comStmtExecute, 3, 0, 0, 0, 0, 1, 0, 0, 0, 0x00, 1, typeTiny, 0, 9}
+	results, err := tap.ClientData("c1", packet(0, exec...))
+	as.Nil(err)
+	as.Equal(1, len(results))
+	as.Equal("DELETE FROM users WHERE id eq ?", results[0].TemplatizedSQL)
+	as.Equal([]any{int64(9)}, results[0].Params)
+	as.Equal(models.SQLOperationDelete, results[0].OpType)
+}
+
+func TestDecodeValue(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	tests := []struct {
+		tp       byte
+		unsigned bool
+		data     []byte
+		expected any
+	}{
+		{typeTiny, false, []byte{0xff}, int64(-1)},
+		{typeTiny, true, []byte{0xff}, uint64(255)},
+		{typeShort, false, []byte{0x01, 0x01}, int64(257)},
+		{typeDouble, false, []byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f}, 1.5},
+		{typeDate, false, []byte{4, 0xe8, 0x07, 2, 29}, "2024-02-29"},
+		{typeDateTime, false, []byte{7, 0xe8, 0x07, 2, 29, 13, 5, 9}, "2024-02-29 13:05:09"},
+		{typeDateTime, false, []byte{0}, "0000-00-00 00:00:00"},
+		{typeTime, false, []byte{8, 1, 1, 0, 0, 0, 2, 3, 4}, "-26:03:04"},
+		{typeNewDecimal, false, []byte{4, '1', '.', '2', '5'}, "1.25"},
+	}
+
+	for _, test := range tests {
+		v, rest, err := decodeValue(test.tp, test.unsigned, test.data)
+		as.Nil(err)
+		as.Empty(rest)
+		as.Equal(test.expected, v)
+	}
+
+	_, _, err := decodeValue(typeLongLong, false, []byte{1, 2})
+	as.NotNil(err)
+	_, _, err = decodeValue(typeVarString, false, []byte{5, 'a'})
+	as.NotNil(err)
+}
@@ -0,0 +1,221 @@
+package sqlextractor
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kydance/sql-extractor/internal/models"
+)
+
+// changedTemplateSimilarity is the minimum similarity of the tokens of a
+// removed and an added template to report them as a changed template.
+const changedTemplateSimilarity = 0.5
+
+// TemplateEntry is a template of a corpus, see DiffTemplates.
+type TemplateEntry struct {
+	Digest         string           `json:"digest"`
+	TemplatizedSQL string           `json:"templatized_sql"`
+	OpType         models.SQLOpType `json:"op_type"`
+	Tables         []string         `json:"tables"` // templatized table names with schema, sorted
+	Count          int64            `json:"count"`  // statements of the template in the corpus
+}
+
+// TemplateChange is a template of the old corpus replaced by a similar one in
+// the new corpus.
+type TemplateChange struct {
+	Old TemplateEntry `json:"old"`
+	New TemplateEntry `json:"new"`
+}
+
+// TemplateDiff is the differences between the templates of two corpora, see
+// DiffTemplates. The templates are sorted by digest.
+type TemplateDiff struct {
+	Added   []TemplateEntry  `json:"added"`
+	Removed []TemplateEntry  `json:"removed"`
+	Changed []TemplateChange `json:"changed"`
+}
+
+// Empty reports whether the corpora have the same templates.
+func (d TemplateDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns the differences, one per line.
+func (d TemplateDiff) String() string {
+	var b strings.Builder
+	for _, e := range d.Added {
+		fmt.Fprintf(&b, "+ %s\n", e.TemplatizedSQL)
+	}
+	for _, e := range d.Removed {
+		fmt.Fprintf(&b, "- %s\n", e.TemplatizedSQL)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s\n  -> %s\n", c.Old.TemplatizedSQL, c.New.TemplatizedSQL)
+	}
+
+	return b.String()
+}
+
+// DiffTemplates compares the digests of the aggregates of two corpora, e.g.
+// the integration test logs of two releases, and reports the added, removed
+// and changed templates, so unreviewed query changes are caught before
+// rollout. The aggregates of the same digest are merged whatever their tags
+// and buckets.
+//
+// A removed and an added template with the same operation type and tables
+// are reported as changed when their tokens are similar enough (Jaccard
+// similarity of at least 0.5), the most similar first.
+//
+// Example:
+//
+//	diff := sqlextractor.DiffTemplates(releaseA.Stats(), releaseB.Stats())
+//	if !diff.Empty() {
+//	  fmt.Print(diff)
+//	}
+func DiffTemplates(oldStats, newStats []DigestStats) TemplateDiff {
+	var (
+		oldEntries = templateEntries(oldStats)
+		newEntries = templateEntries(newStats)
+		diff       TemplateDiff
+	)
+
+	for digest, e := range newEntries {
+		if _, ok := oldEntries[digest]; !ok {
+			diff.Added = append(diff.Added, e)
+		}
+	}
+	for digest, e := range oldEntries {
+		if _, ok := newEntries[digest]; !ok {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+
+	byDigest := func(a, b TemplateEntry) int { return cmp.Compare(a.Digest, b.Digest) }
+	slices.SortFunc(diff.Added, byDigest)
+	slices.SortFunc(diff.Removed, byDigest)
+
+	diff.pairChanges()
+
+	return diff
+}
+
+// pairChanges moves the similar removed and added templates to Changed.
+func (d *TemplateDiff) pairChanges() {
+	type candidate struct {
+		removed, added int
+		similarity     float64
+	}
+
+	var candidates []candidate
+	for i := range d.Removed {
+		for j := range d.Added {
+			if !sameShape(&d.Removed[i], &d.Added[j]) {
+				continue
+			}
+
+			s := tokenSimilarity(d.Removed[i].TemplatizedSQL, d.Added[j].TemplatizedSQL)
+			if s >= changedTemplateSimilarity {
+				candidates = append(candidates, candidate{removed: i, added: j, similarity: s})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	// 相似度最高的优先配对，相同时按 digest 顺序，保证结果稳定
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return cmp.Compare(b.similarity, a.similarity) })
+
+	var (
+		removed = make([]bool, len(d.Removed))
+		added   = make([]bool, len(d.Added))
+	)
+	for _, c := range candidates {
+		if removed[c.removed] || added[c.added] {
+			continue
+		}
+
+		removed[c.removed], added[c.added] = true, true
+		d.Changed = append(d.Changed, TemplateChange{Old: d.Removed[c.removed], New: d.Added[c.added]})
+	}
+
+	slices.SortFunc(d.Changed, func(a, b TemplateChange) int { return cmp.Compare(a.Old.Digest, b.Old.Digest) })
+	d.Removed = unpaired(d.Removed, removed)
+	d.Added = unpaired(d.Added, added)
+}
+
+// unpaired returns the entries which are not paired.
+func unpaired(entries []TemplateEntry, paired []bool) []TemplateEntry {
+	kept := entries[:0]
+	for idx := range entries {
+		if !paired[idx] {
+			kept = append(kept, entries[idx])
+		}
+	}
+
+	return kept
+}
+
+// templateEntries merges the aggregates by digest.
+func templateEntries(stats []DigestStats) map[string]TemplateEntry {
+	entries := make(map[string]TemplateEntry, len(stats))
+	for idx := range stats {
+		s := &stats[idx]
+		if e, ok := entries[s.Digest]; ok {
+			e.Count += s.Count
+			entries[s.Digest] = e
+			continue
+		}
+
+		tables := make([]string, 0, len(s.TableInfos))
+		for _, ti := range s.TableInfos {
+			name, _ := ti.TemplatizedTableNameWithSchema()
+			tables = append(tables, name)
+		}
+		slices.Sort(tables)
+
+		entries[s.Digest] = TemplateEntry{
+			Digest:         s.Digest,
+			TemplatizedSQL: s.TemplatizedSQL,
+			OpType:         s.OpType,
+			Tables:         slices.Compact(tables),
+			Count:          s.Count,
+		}
+	}
+
+	return entries
+}
+
+// sameShape reports whether the templates have the same operation type and
+// tables.
+func sameShape(a, b *TemplateEntry) bool {
+	return a.OpType == b.OpType && slices.Equal(a.Tables, b.Tables)
+}
+
+// tokenSimilarity returns the Jaccard similarity of the sets of the space
+// separated tokens of the templates.
+func tokenSimilarity(a, b string) float64 {
+	ta, tb := make(map[string]struct{}), make(map[string]struct{})
+	for _, t := range strings.Fields(a) {
+		ta[t] = struct{}{}
+	}
+	for _, t := range strings.Fields(b) {
+		tb[t] = struct{}{}
+	}
+
+	intersection := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			intersection++
+		}
+	}
+
+	union := len(ta) + len(tb) - intersection
+	if union == 0 {
+		return 1
+	}
+
+	return float64(intersection) / float64(union)
+}
@@ -0,0 +1,46 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTemplates(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	releaseA := NewAggregator()
+	as.Nil(releaseA.AddSQL("SELECT * FROM users WHERE id = 1", Tags{"host": "a"}))
+	as.Nil(releaseA.AddSQL("SELECT * FROM users WHERE id = 2", Tags{"host": "b"}))
+	as.Nil(releaseA.AddSQL("SELECT name FROM orders WHERE user_id = 1 AND status = 'paid'", nil))
+	as.Nil(releaseA.AddSQL("DELETE FROM sessions WHERE expired_at < NOW()", nil))
+
+	releaseB := NewAggregator()
+	as.Nil(releaseB.AddSQL("SELECT * FROM users WHERE id = 3", nil))
+	as.Nil(releaseB.AddSQL("SELECT name FROM orders WHERE user_id = 1 AND status = 'paid' AND deleted = 0", nil))
+	as.Nil(releaseB.AddSQL("INSERT INTO audit (msg) VALUES ('x')", nil))
+
+	diff := DiffTemplates(releaseA.Stats(), releaseB.Stats())
+	as.False(diff.Empty())
+
+	as.Len(diff.Added, 1)
+	as.Equal("INSERT INTO audit (msg) VALUES (?)", diff.Added[0].TemplatizedSQL)
+	as.Equal([]string{"audit"}, diff.Added[0].Tables)
+
+	as.Len(diff.Removed, 1)
+	as.Equal("DELETE FROM sessions WHERE expired_at lt NOW()", diff.Removed[0].TemplatizedSQL)
+
+	as.Len(diff.Changed, 1)
+	as.Equal("SELECT name FROM orders WHERE user_id eq ? and status eq ?", diff.Changed[0].Old.TemplatizedSQL)
+	as.Equal("SELECT name FROM orders WHERE user_id eq ? and status eq ? and deleted eq ?",
+		diff.Changed[0].New.TemplatizedSQL)
+
+	as.Equal("+ INSERT INTO audit (msg) VALUES (?)\n"+
+		"- DELETE FROM sessions WHERE expired_at lt NOW()\n"+
+		"~ SELECT name FROM orders WHERE user_id eq ? and status eq ?\n"+
+		"  -> SELECT name FROM orders WHERE user_id eq ? and status eq ? and deleted eq ?\n", diff.String())
+
+	same := DiffTemplates(releaseA.Stats(), releaseA.Stats())
+	as.True(same.Empty())
+}
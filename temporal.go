@@ -0,0 +1,128 @@
+package sqlextractor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// TemporalKind is the type of a temporal literal.
+type TemporalKind = extract.TemporalKind
+
+const (
+	TemporalDate      = extract.TemporalDate
+	TemporalTime      = extract.TemporalTime
+	TemporalTimestamp = extract.TemporalTimestamp
+)
+
+// TemporalParam is the placeholder of the value of a temporal literal, see
+// TemporalParams.
+type TemporalParam = extract.TemporalParam
+
+// timestampLayouts are the layouts of the values of the DATE and TIMESTAMP
+// literals, those with an offset are not interpreted in the session time zone.
+var timestampLayouts = []string{
+	time.DateOnly,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+}
+
+// TemporalParams returns the params of each statement with the values of the
+// DATE and TIMESTAMP literals typed as time.Time, interpreted in the session
+// time zone loc, UTC if nil, so the same log gives the same instants whatever
+// the region it is processed in. The values of TIME literals, a time of day,
+// are kept as strings. It should be called after Extract.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM orders WHERE created_at > TIMESTAMP '2024-01-01 08:00:00'")
+//	_ = extractor.Extract()
+//	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+//	params, err := extractor.TemporalParams(shanghai)
+//	// params: [[2024-01-01 08:00:00 +0800 CST]]
+func (e *Extractor) TemporalParams(loc *time.Location) ([][]any, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	temporals, err := e.internal().ExtractTemporalParams(e.rawSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([][]any, len(e.params))
+	for idx := range e.params {
+		params[idx] = e.params[idx]
+		if idx >= len(temporals) || len(temporals[idx]) == 0 {
+			continue
+		}
+
+		params[idx] = append([]any(nil), e.params[idx]...)
+		for _, tp := range temporals[idx] {
+			if tp.Kind == TemporalTime || tp.Index >= len(params[idx]) {
+				continue
+			}
+
+			s, ok := params[idx][tp.Index].(string)
+			if !ok { // e.g. TruncatedParam
+				continue
+			}
+
+			t, err := parseTimestamp(s, loc)
+			if err != nil {
+				return nil, fmt.Errorf("statement %d: param %d: %s literal %q: %w", idx+1, tp.Index+1, tp.Kind, s, err)
+			}
+			params[idx][tp.Index] = t
+		}
+	}
+
+	return params, nil
+}
+
+// parseTimestamp parses the value of a DATE or TIMESTAMP literal in loc.
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	var err error
+	for _, layout := range timestampLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// ParseTimeZone returns the location of a MySQL time_zone value, e.g. of SET
+// time_zone statements: SYSTEM for the local time zone, an offset such as
+// +08:00, or a named time zone such as Asia/Shanghai.
+func ParseTimeZone(tz string) (*time.Location, error) {
+	tz = strings.Trim(strings.TrimSpace(tz), `'"`)
+	if strings.EqualFold(tz, "SYSTEM") {
+		return time.Local, nil
+	}
+
+	if tz != "" && (tz[0] == '+' || tz[0] == '-') {
+		hours, minutes, ok := strings.Cut(tz[1:], ":")
+		h, herr := strconv.Atoi(hours)
+		m, merr := strconv.Atoi(minutes)
+		if !ok || herr != nil || merr != nil || h < 0 || h > 14 || m < 0 || m > 59 {
+			return nil, fmt.Errorf("invalid time zone offset %q", tz)
+		}
+
+		offset := h*3600 + m*60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+
+		return time.FixedZone(tz, offset), nil
+	}
+
+	return time.LoadLocation(tz)
+}
@@ -0,0 +1,65 @@
+package sqlextractor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_TemporalParams(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM orders WHERE id = 1 AND created_at > TIMESTAMP '2024-01-01 08:00:00' " +
+		"AND day = DATE '2024-01-01' AND opens = TIME '09:00:00' AND note = '2024-01-01'; " +
+		"SELECT * FROM t WHERE at < TIMESTAMP '2024-01-01 08:00:00.5+02:00'")
+	as.Nil(extractor.Extract())
+
+	shanghai := time.FixedZone("+08:00", 8*3600)
+	params, err := extractor.TemporalParams(shanghai)
+	as.Nil(err)
+	as.Equal([][]any{
+		{int64(1), time.Date(2024, 1, 1, 8, 0, 0, 0, shanghai), time.Date(2024, 1, 1, 0, 0, 0, 0, shanghai),
+			"09:00:00", "2024-01-01"},
+		{time.Date(2024, 1, 1, 6, 0, 0, 500000000, time.UTC)},
+	}, [][]any{params[0], {params[1][0].(time.Time).UTC()}})
+	as.Equal("2024-01-01 08:00:00", extractor.Params()[0][1], "the params are unchanged")
+
+	params, err = extractor.TemporalParams(nil)
+	as.Nil(err)
+	as.Equal(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), params[0][1])
+
+	extractor = NewExtractor("SELECT * FROM t WHERE d = DATE '2024-13-01'")
+	as.Nil(extractor.Extract())
+	_, err = extractor.TemporalParams(time.UTC)
+	as.ErrorContains(err, `statement 1: param 1: DATE literal "2024-13-01"`)
+}
+
+func TestParseTimeZone(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	loc, err := ParseTimeZone("'+08:00'")
+	as.Nil(err)
+	_, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	as.Equal(8*3600, offset)
+
+	loc, err = ParseTimeZone("-05:30")
+	as.Nil(err)
+	_, offset = time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	as.Equal(-(5*3600 + 30*60), offset)
+
+	loc, err = ParseTimeZone("system")
+	as.Nil(err)
+	as.Equal(time.Local, loc)
+
+	loc, err = ParseTimeZone("UTC")
+	as.Nil(err)
+	as.Equal(time.UTC, loc)
+
+	_, err = ParseTimeZone("+8")
+	as.EqualError(err, `invalid time zone offset "+8"`)
+	_, err = ParseTimeZone("Mars/Olympus")
+	as.NotNil(err)
+}
@@ -0,0 +1,37 @@
+package sqlextractor
+
+import "regexp"
+
+// WithTenantSchema maps the per-tenant schemas matching pattern to the
+// canonical schema in the templates and the templatized schemas of
+// TableInfos, so the digests of the statements aggregate across tenants. The
+// tenant, returned by Tenants and the Tenant of TableInfos, is the first
+// submatch of pattern, or the whole schema if pattern has no group. It panics
+// if pattern is not a valid regular expression.
+//
+// e.g. WithTenantSchema(`^tenant_(\d+)$`, "tenant"): SELECT * FROM tenant_123.orders -> SELECT * FROM tenant.orders, tenant 123
+func WithTenantSchema(pattern, schema string) ExtractorOption {
+	regexp.MustCompile(pattern)
+
+	return func(e *Extractor) {
+		e.options.tenantPattern = pattern
+		e.options.tenantSchema = schema
+	}
+}
+
+// Tenants returns the tenant of each statement, the tenant of its first table
+// in a per-tenant schema, see WithTenantSchema, or empty if there is none. It
+// should be called after Extract.
+func (e *Extractor) Tenants() []string {
+	tenants := make([]string, len(e.tableInfos))
+	for idx := range e.tableInfos {
+		for _, ti := range e.tableInfos[idx] {
+			if ti.Tenant() != "" {
+				tenants[idx] = ti.Tenant()
+				break
+			}
+		}
+	}
+
+	return tenants
+}
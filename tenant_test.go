@@ -0,0 +1,38 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithTenantSchema(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	opt := WithTenantSchema(`^tenant_(\w+)$`, "tenant")
+
+	a := NewExtractor("SELECT * FROM tenant_123.orders WHERE id = 1", opt)
+	as.Nil(a.Extract())
+	b := NewExtractor("SELECT * FROM tenant_acme.orders WHERE id = 2", opt)
+	as.Nil(b.Extract())
+
+	as.Equal([]string{"SELECT * FROM tenant.orders WHERE id eq ?"}, a.TemplatizedSQL())
+	as.Equal(a.TemplatizedSQLHash(), b.TemplatizedSQLHash())
+	as.Equal([]string{"123"}, a.Tenants())
+	as.Equal([]string{"acme"}, b.Tenants())
+
+	ti := a.TableInfos()[0][0]
+	as.Equal("tenant_123", ti.Schema())
+	as.Equal("tenant", ti.TemplatizedSchema())
+	as.Equal("123", ti.Tenant())
+	as.Contains(string(a.Envelope().JSON()), `"tenant":"123"`)
+
+	// 非租户 schema 不受影响
+	c := NewExtractor("SELECT * FROM db_1.orders; SELECT 1", opt)
+	as.Nil(c.Extract())
+	as.Equal([]string{"SELECT * FROM db_?.orders", "SELECT ?"}, c.TemplatizedSQL())
+	as.Equal([]string{"", ""}, c.Tenants())
+
+	as.Panics(func() { WithTenantSchema("(", "tenant") })
+}
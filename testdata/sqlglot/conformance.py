@@ -0,0 +1,51 @@
+"""Checks the sqlglot side of the ProfileSQLGlot conformance corpus.
+
+Usage: pip install sqlglot && python conformance.py [conformance.tsv]
+
+digest() is the recipe to compute, in Python, the digests computed in Go by
+Extractor.NormalizedSQLHash(ProfileSQLGlot).
+"""
+
+import hashlib
+import pathlib
+import sys
+
+import sqlglot
+from sqlglot import exp
+
+
+def canonical(sql: str) -> str:
+    def transform(node: exp.Expression) -> exp.Expression:
+        if isinstance(node, exp.Literal):
+            return exp.Placeholder()
+        if isinstance(node, exp.Identifier):
+            node.set("quoted", False)
+        return node
+
+    tree = sqlglot.parse_one(sql, read="mysql")
+    return tree.transform(transform).sql(dialect="mysql", comments=False)
+
+
+def digest(sql: str) -> str:
+    return hashlib.sha256(canonical(sql).encode()).hexdigest()
+
+
+def main() -> int:
+    path = pathlib.Path(sys.argv[1] if len(sys.argv) > 1 else pathlib.Path(__file__).with_name("conformance.tsv"))
+
+    failures = 0
+    for lineno, line in enumerate(path.read_text().splitlines(), 1):
+        if not line or line.startswith("#"):
+            continue
+
+        sql, expected = line.split("\t")
+        actual = canonical(sql)
+        if actual != expected:
+            failures += 1
+            print(f"{path}:{lineno}: {sql}\n  expected: {expected}\n  actual:   {actual}")
+
+    return 1 if failures else 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
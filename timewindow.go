@@ -0,0 +1,18 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+// TimeWindows returns the time windows queried by the temporal range
+// predicates of each statement, one per column, e.g. to detect dashboards
+// querying unbounded history. A column compared with the current time, an
+// interval from it or a date literal is temporal, so statements without such
+// predicates have no windows.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM events WHERE created_at >= NOW() - INTERVAL 7 DAY")
+//	windows, err := extractor.TimeWindows()
+//	// created_at: last 7 days
+func (e *Extractor) TimeWindows() ([][]*models.TimeWindow, error) {
+	return e.internal().ExtractTimeWindows(e.rawSQL)
+}
@@ -0,0 +1,43 @@
+package sqlextractor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_TimeWindows(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM events e WHERE e.created_at >= NOW() - INTERVAL 7 DAY AND e.type = 'click'; " +
+		"SELECT count(*) FROM events WHERE created_at < '2024-01-01'")
+
+	windows, err := extractor.TimeWindows()
+	as.Nil(err)
+	as.Len(windows, 2)
+
+	as.Len(windows[0], 1)
+	as.Equal("e.created_at", windows[0][0].Column())
+	as.Equal(7*24*time.Hour, windows[0][0].Width())
+	as.True(windows[0][0].Relative())
+	as.Equal("last 7 days", windows[0][0].String())
+
+	as.Len(windows[1], 1)
+	as.True(windows[1][0].Unbounded())
+	as.Equal("unbounded", windows[1][0].String())
+}
+
+func TestExtractor_TimeWindows_Dialect(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	// 使用 Extractor 的方言选项解析
+	windows, err := NewExtractor("SELECT * FROM events WHERE created_at >= NOW() - INTERVAL 7 DAY "+
+		"OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY", WithPostgres()).TimeWindows()
+	as.Nil(err)
+	as.Len(windows, 1)
+	as.Len(windows[0], 1)
+	as.Equal("last 7 days", windows[0][0].String())
+}
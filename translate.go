@@ -0,0 +1,39 @@
+package sqlextractor
+
+import (
+	"fmt"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// Target dialects of Translate, besides DialectMySQL.
+const (
+	DialectPostgreSQL = extract.DialectPostgreSQL
+	DialectSQLServer  = extract.DialectSQLServer
+	DialectOracle     = extract.DialectOracle
+)
+
+// Translate converts the template, e.g. a TemplatizedSQL, from one dialect to
+// another where possible: LIMIT is rewritten as LIMIT OFFSET (PostgreSQL),
+// OFFSET FETCH (Oracle, SQL Server) or TOP (SQL Server), backtick quoted names
+// are double quoted and IFNULL becomes COALESCE. Operator names of templates
+// (eq, gt, and, ...) are replaced with the SQL operators, the ? placeholders
+// are kept.
+//
+// Constructs which can not be translated, e.g. ON DUPLICATE KEY UPDATE or
+// index hints, are kept as is and returned, so the caller can decide whether
+// the translation is usable. Only templates of the built-in MySQL dialect can
+// be parsed, from must be DialectMySQL.
+//
+// Example:
+//
+//	sql, unsupported, err := sqlextractor.Translate(
+//	  "SELECT * FROM users WHERE id eq ? LIMIT ?, ?", sqlextractor.DialectMySQL, sqlextractor.DialectPostgreSQL)
+//	// sql: SELECT * FROM "users" WHERE "id"=? LIMIT ? OFFSET ?
+func Translate(template, from, to string) (string, []string, error) {
+	if from != DialectMySQL {
+		return "", nil, fmt.Errorf("unsupported source dialect: %s, only %s templates can be parsed", from, DialectMySQL)
+	}
+
+	return defaultExtractor.Translate(template, to)
+}
@@ -0,0 +1,28 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	e := NewExtractor("SELECT * FROM users WHERE id = 1 AND name = 'kyden' ORDER BY id LIMIT 20, 10")
+	as.Nil(e.Extract())
+
+	sql, unsupported, err := Translate(e.TemplatizedSQL()[0], DialectMySQL, DialectSQLServer)
+	as.Nil(err)
+	as.Empty(unsupported)
+	as.Equal(`SELECT * FROM "users" WHERE "id"=? AND "name"=? ORDER BY "id" OFFSET ? ROWS FETCH NEXT ? ROWS ONLY`, sql)
+
+	sql, unsupported, err = Translate("REPLACE INTO users (id) VALUES (?)", DialectMySQL, DialectPostgreSQL)
+	as.Nil(err)
+	as.Equal(`REPLACE INTO "users" ("id") VALUES (?)`, sql)
+	as.Equal([]string{"REPLACE"}, unsupported)
+
+	_, _, err = Translate("SELECT 1", DialectPostgreSQL, DialectMySQL)
+	as.NotNil(err)
+}
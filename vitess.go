@@ -0,0 +1,47 @@
+package sqlextractor
+
+import (
+	"strconv"
+
+	"github.com/kydance/sql-extractor/internal/extract"
+)
+
+// vitessBindVarPrefix is the bind variable prefix used by the Vitess normalizer.
+const vitessBindVarPrefix = "vtg"
+
+// vitessExtractor renders valid SQL with :vtg1, :vtg2, ... bind variables, and
+// ::vtg1 list bind variables for the IN lists of literals.
+var vitessExtractor = packageExtractor(
+	extract.WithBindVarPrefix(vitessBindVarPrefix),
+	extract.WithListPlaceholder(func(n int) string { return "::" + vitessBindVarPrefix + strconv.Itoa(n) }),
+	extract.WithStandardOperators(),
+)
+
+// VitessNormalized returns the Vitess-compatible normalized SQL of each
+// statement, with the standard SQL operators and the literals replaced by
+// :vtg1, :vtg2, ... bind variables, and the bind variable map (without the
+// leading colons) of each statement. An IN list of literals is replaced by a
+// ::vtg1 list bind variable, whose value is the []any of the literals.
+//
+// Example:
+//
+//	extractor := NewExtractor("SELECT * FROM users WHERE id IN (1, 2) AND name = 'kyden'")
+//	normalized, bindVars, err := extractor.VitessNormalized()
+//	// normalized: ["SELECT * FROM users WHERE id IN ::vtg1 AND name = :vtg2"]
+//	// bindVars:   [{"vtg1": [1 2], "vtg2": "kyden"}]
+func (e *Extractor) VitessNormalized() ([]string, []map[string]any, error) {
+	normalized, _, params, _, err := vitessExtractor.Extract(e.rawSQL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bindVars := make([]map[string]any, len(params))
+	for idx := range params {
+		bindVars[idx] = make(map[string]any, len(params[idx]))
+		for jdx := range params[idx] {
+			bindVars[idx][vitessBindVarPrefix+strconv.Itoa(jdx+1)] = params[idx][jdx]
+		}
+	}
+
+	return normalized, bindVars, nil
+}
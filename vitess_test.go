@@ -0,0 +1,28 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_VitessNormalized(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	extractor := NewExtractor("SELECT * FROM users WHERE id IN (1, 2) AND name LIKE 'ky%' LIMIT 10; " +
+		"UPDATE users SET age = 18 WHERE id = 3 AND role NOT IN (4, 5)")
+	normalized, bindVars, err := extractor.VitessNormalized()
+	as.Nil(err)
+	as.Equal([]string{
+		"SELECT * FROM users WHERE id IN ::vtg1 AND name LIKE :vtg2 LIMIT :vtg3",
+		"UPDATE users SET age = :vtg1 WHERE id = :vtg2 AND role NOT IN ::vtg3",
+	}, normalized)
+	as.Equal([]map[string]any{
+		{"vtg1": []any{int64(1), int64(2)}, "vtg2": "ky%", "vtg3": uint64(10)},
+		{"vtg1": int64(18), "vtg2": int64(3), "vtg3": []any{int64(4), int64(5)}},
+	}, bindVars)
+
+	_, _, err = NewExtractor("").VitessNormalized()
+	as.NotNil(err)
+}
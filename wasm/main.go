@@ -0,0 +1,39 @@
+//go:build js && wasm
+
+// Command wasm exposes the templatizer to JavaScript, so browser based query
+// review UIs can templatize SQL client side.
+//
+// Build with `make wasm` (or `make wasm-tinygo`), then from JavaScript:
+//
+//	const result = sqlExtractor.templatize("SELECT * FROM users WHERE id = 1");
+//	// result.statements[0].templatized_sql: "SELECT * FROM users WHERE id eq ?"
+//	// result.statements[0].params: [1]
+//	// result.error: set if the SQL can not be parsed
+//
+// The result is the versioned envelope described by schema/envelope.v1.json.
+package main
+
+import (
+	"syscall/js"
+
+	sqlextractor "github.com/kydance/sql-extractor"
+)
+
+func main() {
+	js.Global().Set("sqlExtractor", js.ValueOf(map[string]any{
+		"templatize": js.FuncOf(templatize),
+	}))
+
+	select {} // keep the functions alive
+}
+
+// templatize is the JavaScript binding of sqlextractor.ExtractEnvelope.
+func templatize(_ js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return map[string]any{"error": "templatize expects a SQL string"}
+	}
+
+	env := sqlextractor.ExtractEnvelope(args[0].String())
+
+	return js.Global().Get("JSON").Call("parse", string(env.JSON()))
+}
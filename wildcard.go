@@ -0,0 +1,21 @@
+package sqlextractor
+
+import "github.com/kydance/sql-extractor/internal/models"
+
+// WithWildcardModifiers supports the wildcard modifiers of dialects such as
+// ClickHouse, BigQuery or DuckDB, which the MySQL parser rejects, e.g. for a
+// Dialect wrapping the Extractor: the columns of * EXCEPT (a, b) are kept in
+// the template, the expressions of * REPLACE (expr AS c) are templatized, and
+// the columns are listed by Wildcards.
+//
+// e.g. SELECT * EXCEPT (password) FROM users WHERE id = 1 -> SELECT * EXCEPT (password) FROM users WHERE id eq ?
+func WithWildcardModifiers() ExtractorOption {
+	return func(e *Extractor) { e.options.wildcardModifiers = true }
+}
+
+// Wildcards returns the wildcard projections (* or t.*) of each statement,
+// with the columns excluded by EXCEPT and replaced by REPLACE when the
+// Extractor is created with WithWildcardModifiers.
+func (e *Extractor) Wildcards() ([][]*models.Wildcard, error) {
+	return e.internal().ExtractWildcards(e.rawSQL)
+}
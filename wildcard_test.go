@@ -0,0 +1,37 @@
+package sqlextractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor_WithWildcardModifiers(t *testing.T) {
+	t.Parallel()
+	as := assert.New(t)
+
+	sql := "SELECT u.* EXCEPT (password, `salt`) REPLACE (UPPER(name) AS name), o.* FROM users u, orders o WHERE u.id = 1"
+
+	as.NotNil(NewExtractor(sql).Extract())
+
+	extractor := NewExtractor(sql, WithWildcardModifiers())
+	as.Nil(extractor.Extract())
+	as.Equal([]string{"SELECT u.* EXCEPT (password, salt) REPLACE (UPPER(name) AS name), o.* FROM users AS u CROSS JOIN orders AS o WHERE u.id eq ?"},
+		extractor.TemplatizedSQL())
+	as.Equal([][]any{{int64(1)}}, extractor.Params())
+
+	wildcards, err := extractor.Wildcards()
+	as.Nil(err)
+	as.Len(wildcards, 1)
+	as.Len(wildcards[0], 2)
+	as.Equal("u", wildcards[0][0].Table())
+	as.Equal([]string{"password", "salt"}, wildcards[0][0].Except())
+	as.Equal([]string{"name"}, wildcards[0][0].Replace())
+	as.Equal("o", wildcards[0][1].Table())
+	as.Empty(wildcards[0][1].Except())
+
+	// 字符串和注释中的修饰符不改写
+	extractor = NewExtractor("SELECT '* EXCEPT (a)' FROM t /* * EXCEPT (b) */", WithWildcardModifiers())
+	as.Nil(extractor.Extract())
+	as.Equal([][]any{{"* EXCEPT (a)"}}, extractor.Params())
+}